@@ -0,0 +1,103 @@
+package cbdcconfig
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// CapellaAuthBasic authenticates with a username and password, which
+	// are exchanged for a session token.
+	CapellaAuthBasic = "basic"
+
+	// CapellaAuthToken authenticates with an access key and secret key.
+	CapellaAuthToken = "token"
+)
+
+// CapellaEnvironment describes a Capella control plane.  Credentials which
+// are not specified by the environment are taken from the capella section.
+type CapellaEnvironment struct {
+	Name string `yaml:"-"`
+
+	Endpoint  string `yaml:"endpoint"`
+	AuthStyle string `yaml:"auth-style,omitempty"`
+	UIUrl     string `yaml:"ui-url,omitempty"`
+
+	Username       string `yaml:"username,omitempty"`
+	Password       string `yaml:"password,omitempty"`
+	AccessKey      string `yaml:"access-key,omitempty"`
+	SecretKey      string `yaml:"secret-key,omitempty"`
+	OrganizationID string `yaml:"organization-id,omitempty"`
+}
+
+var builtinCapellaEnvironments = map[string]*CapellaEnvironment{
+	"prod": {
+		Endpoint:  DEFAULT_CAPELLA_ENDPOINT,
+		AuthStyle: CapellaAuthBasic,
+		UIUrl:     "https://cloud.couchbase.com",
+	},
+}
+
+// CapellaEnvironmentNames returns the names of the builtin and configured
+// environments, sorted.
+func (c *Config_Capella) CapellaEnvironmentNames() []string {
+	var names []string
+	for name := range builtinCapellaEnvironments {
+		if _, ok := c.Environments[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveEnvironment finds the environment to use, which is the named one
+// if specified, otherwise the one selected in the config.  If neither
+// specifies one, the endpoint of the capella section is used.
+func (c *Config_Capella) ResolveEnvironment(name string) (*CapellaEnvironment, error) {
+	if name == "" {
+		name = c.Environment
+	}
+
+	var env CapellaEnvironment
+	if name == "" {
+		env = CapellaEnvironment{
+			Endpoint:  c.Endpoint,
+			AuthStyle: CapellaAuthBasic,
+		}
+
+		for _, builtinEnv := range builtinCapellaEnvironments {
+			if builtinEnv.Endpoint == c.Endpoint {
+				env.UIUrl = builtinEnv.UIUrl
+			}
+		}
+	} else if configEnv := c.Environments[name]; configEnv != nil {
+		env = *configEnv
+	} else if builtinEnv := builtinCapellaEnvironments[name]; builtinEnv != nil {
+		env = *builtinEnv
+	} else {
+		return nil, fmt.Errorf("unknown capella environment `%s`", name)
+	}
+
+	env.Name = name
+
+	if env.AuthStyle == "" {
+		env.AuthStyle = CapellaAuthBasic
+	}
+	if env.AuthStyle != CapellaAuthBasic && env.AuthStyle != CapellaAuthToken {
+		return nil, fmt.Errorf("capella environment `%s` has unknown auth style `%s`", name, env.AuthStyle)
+	}
+
+	if env.Username == "" && env.Password == "" {
+		env.Username = c.Username
+		env.Password = c.Password
+	}
+	if env.OrganizationID == "" {
+		env.OrganizationID = c.OrganizationID
+	}
+
+	return &env, nil
+}
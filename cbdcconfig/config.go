@@ -2,15 +2,17 @@ package cbdcconfig
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path"
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
-const Version = 6
+const Version = 7
 
 type StringBool string
 
@@ -52,8 +54,30 @@ type Config struct {
 	Azure   Config_Azure   `yaml:"azure"`
 	Capella Config_Capella `yaml:"capella"`
 
-	DefaultDeployer string        `yaml:"default-deployer"`
-	DefaultExpiry   time.Duration `yaml:"default-expiry"`
+	Registry Config_Registry `yaml:"registry"`
+
+	DefaultDeployer   string        `yaml:"default-deployer"`
+	DefaultExpiry     time.Duration `yaml:"default-expiry"`
+	ExpiryGracePeriod time.Duration `yaml:"expiry-grace-period"`
+	ExpiryHook        string        `yaml:"expiry-hook"`
+
+	// OrgDefaults is the path to a file of Defaults shared by an
+	// organization, such as one kept in a shared repository.
+	OrgDefaults string `yaml:"org-defaults,omitempty"`
+
+	// VersionsLock is the path of the versions lock file which maps names
+	// such as stable to exact server versions.  When it is empty, the file
+	// is looked for in the working directory and its parents.
+	VersionsLock string `yaml:"versions-lock,omitempty"`
+
+	// Profiles are named layers of Defaults which can be selected when
+	// allocating, and DefaultProfile is the one used when none is selected.
+	Profiles       map[string]*Defaults `yaml:"profiles,omitempty"`
+	DefaultProfile string               `yaml:"default-profile,omitempty"`
+
+	// ReadOnly restricts the CLI to the commands which only inspect
+	// clusters, for dashboards and people inspecting shared environments.
+	ReadOnly StringBool `yaml:"read-only"`
 
 	_DefaultCloud string `yaml:"default-cloud"`
 }
@@ -63,6 +87,87 @@ type Config_Docker struct {
 	Host        string     `yaml:"host"`
 	Network     string     `yaml:"network"`
 	ForwardOnly StringBool `yaml:"forward-only"`
+
+	// ClientNetwork is an additional network which nodes are attached to
+	// and which clients connect through, keeping client traffic separate
+	// from the inter-node traffic of Network.
+	ClientNetwork string `yaml:"client-network"`
+
+	// Context selects a docker cli context to connect with, in which
+	// case Host is ignored.
+	Context string `yaml:"context"`
+
+	// CertPath is a directory containing ca.pem, cert.pem and key.pem
+	// for connecting to a tcp:// host with TLS.
+	CertPath      string     `yaml:"cert-path"`
+	TLSSkipVerify StringBool `yaml:"tls-skip-verify"`
+
+	// AdvertiseAddress is the address of a remote docker host, as
+	// reachable from this machine, which node ports are published on.
+	AdvertiseAddress string `yaml:"advertise-address"`
+
+	// AcceptLicense and LicenseFile provide license material to every
+	// server node, unless overridden by the cluster definition.
+	AcceptLicense StringBool `yaml:"accept-license"`
+	LicenseFile   string     `yaml:"license-file"`
+
+	// ImageManifest is a file listing the trusted digests of images, which
+	// images are pinned to and verified against.
+	ImageManifest string `yaml:"image-manifest"`
+
+	// ImageProviders is an ordered chain of sources which server images are
+	// fetched from, falling through to the next source when one cannot
+	// provide an image.  Images are fetched from dockerhub or ghcr when it
+	// is empty.
+	ImageProviders []Config_DockerImageProvider `yaml:"image-providers,omitempty"`
+
+	// EncryptNodeState encrypts the state stored within nodes with a key
+	// kept on this machine, and keeps sensitive values out of the labels
+	// of containers, which are visible to all users of a docker host.
+	EncryptNodeState StringBool `yaml:"encrypt-node-state"`
+
+	// SnapshotRegistry is the image repository which golden snapshots are
+	// published to and resolved from by name, such as a shared registry of
+	// the organization.  Snapshots are only kept locally when it is empty.
+	SnapshotRegistry string `yaml:"snapshot-registry"`
+
+	// Hosts lists additional docker hosts which cluster nodes are spread
+	// across.  The network must be shared by all hosts, for instance as an
+	// attachable swarm overlay network or with routed subnets.
+	Hosts []Config_DockerHost `yaml:"hosts,omitempty"`
+}
+
+// DockerImageProviderTypes are the types of source which can be used in the
+// image provider chain.
+var DockerImageProviderTypes = []string{"local", "ghcr", "dockerhub", "package", "registry"}
+
+type Config_DockerImageProvider struct {
+	Type string `yaml:"type"`
+
+	// PackageURL is where packages are downloaded from by the package type,
+	// with {version}, {build}, {edition} and {arch} replaced.
+	PackageURL string `yaml:"package-url,omitempty"`
+
+	// RegistryURL is the image reference used by the registry type, with
+	// {version}, {build} and {edition} replaced, and RegistryUsername and
+	// RegistryPassword are the credentials of that registry.
+	RegistryURL      string `yaml:"registry-url,omitempty"`
+	RegistryUsername string `yaml:"registry-username,omitempty"`
+	RegistryPassword string `yaml:"registry-password,omitempty"`
+
+	// Allow and Deny are glob patterns of the versions which may or may not
+	// be fetched from this source, such as 7.6.* or 8.0.0-*.
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+type Config_DockerHost struct {
+	Name             string     `yaml:"name"`
+	Host             string     `yaml:"host"`
+	Context          string     `yaml:"context"`
+	CertPath         string     `yaml:"cert-path"`
+	TLSSkipVerify    StringBool `yaml:"tls-skip-verify"`
+	AdvertiseAddress string     `yaml:"advertise-address"`
 }
 
 type Config_K8s struct {
@@ -117,6 +222,55 @@ type Config_Capella struct {
 	DefaultGcpRegion   string `yaml:"default-gcp-region"`
 
 	UploadServerLogsHostName string `yaml:"upload-server-logs-host-name"`
+
+	// Environment selects the control plane to use from Environments or the
+	// builtin environments, instead of Endpoint.
+	Environment  string                         `yaml:"environment,omitempty"`
+	Environments map[string]*CapellaEnvironment `yaml:"environments,omitempty"`
+
+	// RequestsPerSecond and RequestBurst limit the rate of requests sent to
+	// Capella, and MaxConcurrentRequests the number in flight at once.  Unset
+	// values use the defaults, and negative values disable the limit.
+	RequestsPerSecond     float64 `yaml:"requests-per-second"`
+	RequestBurst          int     `yaml:"request-burst"`
+	MaxConcurrentRequests int     `yaml:"max-concurrent-requests"`
+
+	// ProxyUrl, CaBundle and TLSSkipVerify configure how the control plane
+	// is reached, for corporate networks and pre-production environments.
+	ProxyUrl      string     `yaml:"proxy-url"`
+	CaBundle      string     `yaml:"ca-bundle"`
+	TLSSkipVerify StringBool `yaml:"tls-skip-verify"`
+
+	// DnsResolvers are the DNS servers the connection string records of new
+	// clusters are checked against, and DnsWaitTimeout how long to wait for
+	// them to resolve consistently before giving up.
+	DnsResolvers   []string      `yaml:"dns-resolvers,omitempty"`
+	DnsWaitTimeout time.Duration `yaml:"dns-wait-timeout,omitempty"`
+
+	// Presets defines additional named sizing presets, overriding any
+	// builtin presets of the same name.
+	Presets map[string]*clusterdef.CloudPreset `yaml:"presets,omitempty"`
+}
+
+// Config_Registry configures a registry of allocated clusters which is
+// shared with other machines, such as those of teammates or CI runners.
+type Config_Registry struct {
+	// Type selects the registry backend, either "s3" or "http".  The
+	// registry is disabled when no type is set.
+	Type string `yaml:"type"`
+
+	// Endpoint is the URL of the http registry service, or an optional
+	// endpoint override for S3 compatible stores.
+	Endpoint string `yaml:"endpoint"`
+	Token    string `yaml:"token"`
+
+	Bucket string `yaml:"bucket"`
+	Prefix string `yaml:"prefix"`
+	Region string `yaml:"region"`
+
+	// Owner identifies the clusters allocated from this machine, and
+	// defaults to user@hostname.
+	Owner string `yaml:"owner"`
 }
 
 func DefaultConfigPath() (string, error) {
@@ -161,9 +315,81 @@ func Upgrade(config *Config) *Config {
 		config.Version = 6
 	}
 
+	if config.Version < 7 {
+		config.ExpiryGracePeriod = 0
+		config.ExpiryHook = ""
+		config.Version = 7
+	}
+
 	return config
 }
 
+// ErrConfigTooNew indicates the config file was written by a newer version
+// of cbdinocluster, which we cannot safely read or rewrite.
+var ErrConfigTooNew = errors.New("config file is newer than this version of cbdinocluster supports")
+
+func parse(configBytes []byte) (*Config, error) {
+	var config *Config
+	err := yaml.Unmarshal(configBytes, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse config file")
+	}
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.Version > Version {
+		return nil, errors.Wrapf(ErrConfigTooNew,
+			"config file is version %d but only up to version %d is supported, please update cbdinocluster",
+			config.Version, Version)
+	}
+
+	return config, nil
+}
+
+// BackupPath returns the path the config file is backed up to before it is
+// migrated from the specified version.
+func BackupPath(configPath string, fromVersion int) string {
+	return fmt.Sprintf("%s.v%d.bak", configPath, fromVersion)
+}
+
+type MigrateResult struct {
+	FromVersion int
+	ToVersion   int
+
+	// BackupPath is the path the original config file was copied to, which
+	// is empty if no migration was needed.
+	BackupPath string
+}
+
+func migrate(ctx context.Context, configPath string, configBytes []byte, config *Config) (*MigrateResult, error) {
+	result := &MigrateResult{
+		FromVersion: config.Version,
+		ToVersion:   Version,
+	}
+
+	if config.Version == Version {
+		return result, nil
+	}
+
+	// the original file is kept so that users can recover it if they need
+	// to go back to an older version of cbdinocluster
+	result.BackupPath = BackupPath(configPath, config.Version)
+	err := os.WriteFile(result.BackupPath, configBytes, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to backup config file")
+	}
+
+	Upgrade(config)
+
+	err = Save(ctx, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to save upgraded configuration")
+	}
+
+	return result, nil
+}
+
 func Load(ctx context.Context) (*Config, error) {
 	configPath, err := DefaultConfigPath()
 	if err != nil {
@@ -175,24 +401,40 @@ func Load(ctx context.Context) (*Config, error) {
 		return nil, errors.Wrap(err, "failed to read config file")
 	}
 
-	var config *Config
-	err = yaml.Unmarshal(configBytes, &config)
+	config, err := parse(configBytes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse config file")
+		return nil, err
 	}
 
-	if config.Version != Version {
-		config = Upgrade(config)
-
-		err := Save(ctx, config)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to save upgraded configuration")
-		}
+	_, err = migrate(ctx, configPath, configBytes, config)
+	if err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// Migrate explicitly migrates the config file to the latest version.  This
+// normally happens automatically when the config is loaded.
+func Migrate(ctx context.Context) (*MigrateResult, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find default config path")
+	}
+
+	configBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	config, err := parse(configBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate(ctx, configPath, configBytes, config)
+}
+
 func Save(ctx context.Context, config *Config) error {
 	configPath, err := DefaultConfigPath()
 	if err != nil {
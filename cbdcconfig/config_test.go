@@ -0,0 +1,205 @@
+package cbdcconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, contents string) string {
+	homePath := t.TempDir()
+	t.Setenv("HOME", homePath)
+
+	configPath := filepath.Join(homePath, ".cbdinocluster")
+	require.NoError(t, os.WriteFile(configPath, []byte(contents), 0600))
+	return configPath
+}
+
+func TestMigrateBacksUpOldConfig(t *testing.T) {
+	ctx := context.Background()
+	oldConfig := "version: 6\ndefault-deployer: docker\n"
+	configPath := writeTestConfig(t, oldConfig)
+
+	result, err := Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.FromVersion)
+	assert.Equal(t, Version, result.ToVersion)
+	assert.Equal(t, configPath+".v6.bak", result.BackupPath)
+
+	backupBytes, err := os.ReadFile(result.BackupPath)
+	require.NoError(t, err)
+	assert.Equal(t, oldConfig, string(backupBytes))
+
+	config, err := Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Version, config.Version)
+	assert.Equal(t, "docker", config.DefaultDeployer)
+
+	result, err = Migrate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.BackupPath)
+}
+
+func TestLoadRejectsNewerConfig(t *testing.T) {
+	newConfig := "version: 999\nsome-future-setting: true\n"
+	configPath := writeTestConfig(t, newConfig)
+
+	_, err := Load(context.Background())
+	assert.ErrorIs(t, err, ErrConfigTooNew)
+
+	// the file must not be rewritten, or the unknown settings would be lost
+	configBytes, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, newConfig, string(configBytes))
+}
+
+func TestDoctor(t *testing.T) {
+	writeTestConfig(t, "version: 7\ndefault-deployer: cloud\ndocker:\n  enabled: \"true\"\n  netwrok: bridge\n")
+
+	result, err := Doctor(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.HasErrors())
+
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Message)
+	}
+	assert.Contains(t, messages, "default deployer `cloud` is not enabled")
+	assert.Contains(t, messages, "docker is enabled but no network is configured")
+	assert.Contains(t, messages, "line 5: field netwrok not found in type cbdcconfig.Config_Docker")
+}
+
+func TestDoctorImageProviders(t *testing.T) {
+	writeTestConfig(t, "version: 7\ndocker:\n  image-providers:\n"+
+		"  - type: local\n  - type: quay\n  - type: package\n    allow: [\"7.6.[\"]\n  - type: registry\n")
+
+	result, err := Doctor(context.Background())
+	require.NoError(t, err)
+
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Message)
+	}
+	assert.Contains(t, messages, "docker image provider type `quay` is not one of local, ghcr, dockerhub, package, registry")
+	assert.Contains(t, messages, "docker image provider `package` has an invalid version pattern `7.6.[`")
+	assert.Contains(t, messages, "docker image provider `registry` is missing a registry-url")
+	assert.NotContains(t, messages, "docker image provider type `local` is not one of local, ghcr, dockerhub, package, registry")
+}
+
+func TestLoadStateKey(t *testing.T) {
+	writeTestConfig(t, "version: 7\n")
+
+	key, err := LoadStateKey(false)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	key, err = LoadStateKey(true)
+	require.NoError(t, err)
+	assert.Len(t, key, StateKeySize)
+
+	keyPath, err := StateKeyPath()
+	require.NoError(t, err)
+	keyInfo, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), keyInfo.Mode().Perm())
+
+	loadedKey, err := LoadStateKey(true)
+	require.NoError(t, err)
+	assert.Equal(t, key, loadedKey)
+}
+
+func TestResolveCapellaEnvironment(t *testing.T) {
+	config := &Config_Capella{
+		Endpoint:       "https://api.example.com",
+		Username:       "user",
+		Password:       "pass",
+		OrganizationID: "org",
+		Environments: map[string]*CapellaEnvironment{
+			"stage": {
+				Endpoint:       "https://api.stage.example.com",
+				Username:       "stage-user",
+				Password:       "stage-pass",
+				OrganizationID: "stage-org",
+			},
+		},
+	}
+
+	env, err := config.ResolveEnvironment("")
+	require.NoError(t, err)
+	require.Equal(t, "https://api.example.com", env.Endpoint)
+	require.Equal(t, CapellaAuthBasic, env.AuthStyle)
+
+	env, err = config.ResolveEnvironment("prod")
+	require.NoError(t, err)
+	require.Equal(t, DEFAULT_CAPELLA_ENDPOINT, env.Endpoint)
+	require.Equal(t, "user", env.Username)
+	require.Equal(t, "org", env.OrganizationID)
+
+	config.Environment = "stage"
+	env, err = config.ResolveEnvironment("")
+	require.NoError(t, err)
+	require.Equal(t, "stage", env.Name)
+	require.Equal(t, "stage-user", env.Username)
+	require.Equal(t, "stage-org", env.OrganizationID)
+
+	_, err = config.ResolveEnvironment("missing")
+	require.Error(t, err)
+
+	require.Equal(t, []string{"prod", "stage"}, config.CapellaEnvironmentNames())
+}
+
+func TestResolveDefaults(t *testing.T) {
+	orgPath := filepath.Join(t.TempDir(), "org-defaults.yaml")
+	require.NoError(t, os.WriteFile(orgPath,
+		[]byte("version: 7.2.0\npurpose: testing\nexpiry: 4h\nmax-expiry: 24h\n"), 0600))
+
+	config := &Config{
+		DefaultDeployer: "docker",
+		DefaultExpiry:   time.Hour,
+		OrgDefaults:     orgPath,
+		Profiles: map[string]*Defaults{
+			"perf": {
+				Version:   "7.6.0",
+				MaxExpiry: 48 * time.Hour,
+			},
+		},
+	}
+
+	layers, err := config.DefaultsLayers("perf")
+	require.NoError(t, err)
+	defaults := ResolveDefaults(layers)
+	assert.Equal(t, "docker", defaults.Deployer)
+	assert.Equal(t, "7.6.0", defaults.Version)
+	assert.Equal(t, "testing", defaults.Purpose)
+	assert.Equal(t, time.Hour, defaults.Expiry)
+	assert.Equal(t, 24*time.Hour, defaults.MaxExpiry)
+	assert.Equal(t, "profile perf", defaults.Sources["version"])
+	assert.Equal(t, "config", defaults.Sources["expiry"])
+	assert.Equal(t, "org", defaults.Sources["max-expiry"])
+
+	def := &clusterdef.Cluster{
+		Purpose: "mine",
+		NodeGroups: []*clusterdef.NodeGroup{
+			{Count: 3},
+			{Count: 1, Version: "7.1.0"},
+		},
+	}
+	require.NoError(t, defaults.Apply(def))
+	assert.Equal(t, "docker", def.Deployer)
+	assert.Equal(t, "mine", def.Purpose)
+	assert.Equal(t, time.Hour, def.Expiry)
+	assert.Equal(t, "7.6.0", def.NodeGroups[0].Version)
+	assert.Equal(t, "7.1.0", def.NodeGroups[1].Version)
+
+	def = &clusterdef.Cluster{Expiry: 72 * time.Hour}
+	assert.ErrorIs(t, defaults.Apply(def), ErrExpiryTooLong)
+
+	_, err = config.DefaultsLayers("missing")
+	assert.Error(t, err)
+}
@@ -1,9 +1,13 @@
 package cbdcconfig
 
+import "time"
+
 const (
 	DEFAULT_AWS_REGION       = "us-west-2"
 	DEFAULT_AZURE_REGION     = "westus2"
 	DEFAULT_GCP_REGION       = "us-west1"
 	DEFAULT_CAPELLA_ENDPOINT = "https://api.cloud.couchbase.com"
 	DEFAULT_CAPELLA_PROVIDER = "aws"
+
+	DEFAULT_EXPIRY_GRACE_PERIOD = 30 * time.Minute
 )
@@ -0,0 +1,258 @@
+package cbdcconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type DoctorSeverity string
+
+const (
+	DoctorSeverityInfo    DoctorSeverity = "info"
+	DoctorSeverityWarning DoctorSeverity = "warning"
+	DoctorSeverityError   DoctorSeverity = "error"
+)
+
+type DoctorIssue struct {
+	Severity DoctorSeverity
+	Message  string
+}
+
+type DoctorResult struct {
+	ConfigPath string
+	Version    int
+	Issues     []DoctorIssue
+}
+
+func (r *DoctorResult) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == DoctorSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *DoctorResult) add(severity DoctorSeverity, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, DoctorIssue{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Doctor inspects the config file for problems without modifying it.
+func Doctor(ctx context.Context) (*DoctorResult, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find default config path")
+	}
+
+	configBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	result := &DoctorResult{
+		ConfigPath: configPath,
+	}
+
+	fileInfo, err := os.Stat(configPath)
+	if err == nil && fileInfo.Mode().Perm()&0077 != 0 {
+		result.add(DoctorSeverityWarning,
+			"config file may contain credentials but is accessible by other users (mode %04o), it should be 0600",
+			fileInfo.Mode().Perm())
+	}
+
+	var config *Config
+	err = yaml.Unmarshal(configBytes, &config)
+	if err != nil {
+		result.add(DoctorSeverityError, "config file could not be parsed: %s", err)
+		return result, nil
+	}
+	if config == nil {
+		config = &Config{}
+	}
+
+	result.Version = config.Version
+	if config.Version > Version {
+		result.add(DoctorSeverityError,
+			"config file is version %d but only up to version %d is supported, please update cbdinocluster",
+			config.Version, Version)
+		return result, nil
+	} else if config.Version < Version {
+		result.add(DoctorSeverityInfo,
+			"config file is version %d and will be migrated to version %d, use `config migrate` to do this now",
+			config.Version, Version)
+	}
+
+	// unknown keys are usually typos, or settings from older versions which
+	// will be dropped the next time the config is saved.
+	decoder := yaml.NewDecoder(bytes.NewReader(configBytes))
+	decoder.KnownFields(true)
+	var strictConfig Config
+	err = decoder.Decode(&strictConfig)
+	if err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, msg := range typeErr.Errors {
+				result.add(DoctorSeverityWarning, "%s", msg)
+			}
+		} else {
+			result.add(DoctorSeverityWarning, "config file failed strict parsing: %s", err)
+		}
+	}
+
+	Upgrade(config)
+	checkConfig(config, result)
+
+	return result, nil
+}
+
+func checkConfig(config *Config, result *DoctorResult) {
+	deployerEnabled := map[string]bool{
+		"docker": config.Docker.Enabled.Value(),
+		"cao":    config.K8s.Enabled.Value(),
+		"cloud":  config.Capella.Enabled.Value(),
+	}
+
+	anyEnabled := false
+	for _, enabled := range deployerEnabled {
+		anyEnabled = anyEnabled || enabled
+	}
+	if !anyEnabled {
+		result.add(DoctorSeverityWarning, "no deployers are enabled, run `init` to configure one")
+	}
+
+	if config.DefaultDeployer != "" {
+		enabled, known := deployerEnabled[config.DefaultDeployer]
+		if !known {
+			result.add(DoctorSeverityError,
+				"default deployer `%s` is not a known deployer", config.DefaultDeployer)
+		} else if !enabled {
+			result.add(DoctorSeverityError,
+				"default deployer `%s` is not enabled", config.DefaultDeployer)
+		}
+	}
+
+	if config.OrgDefaults != "" {
+		if _, err := LoadOrgDefaults(config.OrgDefaults); err != nil {
+			result.add(DoctorSeverityError,
+				"org defaults `%s` cannot be loaded: %s", config.OrgDefaults, err)
+		}
+	}
+
+	if config.DefaultProfile != "" {
+		if _, ok := config.Profiles[config.DefaultProfile]; !ok {
+			result.add(DoctorSeverityError,
+				"default profile `%s` is not a configured profile", config.DefaultProfile)
+		}
+	}
+
+	if config.Docker.Enabled.Value() && config.Docker.Network == "" {
+		result.add(DoctorSeverityError, "docker is enabled but no network is configured")
+	}
+
+	if config.Docker.ClientNetwork != "" && config.Docker.ClientNetwork == config.Docker.Network {
+		result.add(DoctorSeverityWarning,
+			"docker client network `%s` is the same as the cluster network, so client traffic is not separated",
+			config.Docker.ClientNetwork)
+	}
+
+	if config.Docker.LicenseFile != "" {
+		if _, err := os.Stat(config.Docker.LicenseFile); err != nil {
+			result.add(DoctorSeverityError,
+				"docker license file `%s` cannot be read: %s", config.Docker.LicenseFile, err)
+		}
+	}
+
+	if config.Docker.ImageManifest != "" {
+		if _, err := os.Stat(config.Docker.ImageManifest); err != nil {
+			result.add(DoctorSeverityError,
+				"docker image manifest `%s` cannot be read: %s", config.Docker.ImageManifest, err)
+		}
+	}
+
+	for _, provider := range config.Docker.ImageProviders {
+		if !slices.Contains(DockerImageProviderTypes, provider.Type) {
+			result.add(DoctorSeverityError,
+				"docker image provider type `%s` is not one of %s",
+				provider.Type, strings.Join(DockerImageProviderTypes, ", "))
+		}
+
+		if provider.Type == "registry" && provider.RegistryURL == "" {
+			result.add(DoctorSeverityError, "docker image provider `registry` is missing a registry-url")
+		}
+
+		for _, pattern := range append(slices.Clone(provider.Allow), provider.Deny...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				result.add(DoctorSeverityError,
+					"docker image provider `%s` has an invalid version pattern `%s`", provider.Type, pattern)
+			}
+		}
+	}
+
+	for _, host := range config.Docker.Hosts {
+		if host.Name == "" {
+			result.add(DoctorSeverityError, "a docker host is missing a name")
+		} else if host.Host == "" && host.Context == "" {
+			result.add(DoctorSeverityError,
+				"docker host `%s` has neither a host nor a context", host.Name)
+		}
+	}
+
+	if config.Capella.Enabled.Value() {
+		capellaEnv, err := config.Capella.ResolveEnvironment("")
+		if err != nil {
+			result.add(DoctorSeverityError, "%s", err)
+		} else {
+			if capellaEnv.Endpoint == "" {
+				result.add(DoctorSeverityError, "capella is enabled but no endpoint is configured")
+			}
+			if capellaEnv.AuthStyle == CapellaAuthToken {
+				if capellaEnv.AccessKey == "" || capellaEnv.SecretKey == "" {
+					result.add(DoctorSeverityError, "capella environment uses token auth but no keys are configured")
+				}
+			} else if config.Capella.OverrideToken == "" &&
+				(capellaEnv.Username == "" || capellaEnv.Password == "") {
+				result.add(DoctorSeverityError, "capella is enabled but no credentials are configured")
+			}
+			if capellaEnv.OrganizationID == "" {
+				result.add(DoctorSeverityInfo,
+					"no default capella organization is set, use `config set-default-org` to set one")
+			}
+			if capellaEnv.Endpoint != "" && !strings.HasPrefix(capellaEnv.Endpoint, "https://") {
+				result.add(DoctorSeverityWarning,
+					"capella endpoint `%s` does not use https", capellaEnv.Endpoint)
+			}
+		}
+
+		for name, env := range config.Capella.Environments {
+			if env == nil || env.Endpoint == "" {
+				result.add(DoctorSeverityError, "capella environment `%s` has no endpoint", name)
+			}
+		}
+	}
+
+	switch config.Registry.Type {
+	case "":
+	case "http":
+		if config.Registry.Endpoint == "" {
+			result.add(DoctorSeverityError, "the http registry requires an endpoint")
+		}
+	case "s3":
+		if config.Registry.Bucket == "" || config.Registry.Region == "" {
+			result.add(DoctorSeverityError, "the s3 registry requires a bucket and region")
+		}
+	default:
+		result.add(DoctorSeverityError,
+			"registry type `%s` is not supported, expected `s3` or `http`", config.Registry.Type)
+	}
+}
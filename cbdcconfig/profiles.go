@@ -0,0 +1,175 @@
+package cbdcconfig
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrExpiryTooLong indicates a cluster definition asks for a longer expiry
+// than one of the layers of defaults allows.
+var ErrExpiryTooLong = errors.New("expiry exceeds the maximum allowed")
+
+// Defaults are values applied to cluster definitions which do not specify
+// them.  They are layered from the org defaults, to the config file, to the
+// selected profile, with each layer overriding the ones before it and the
+// definition itself overriding all of them.
+type Defaults struct {
+	Deployer      string        `yaml:"deployer,omitempty"`
+	Version       string        `yaml:"version,omitempty"`
+	Purpose       string        `yaml:"purpose,omitempty"`
+	Expiry        time.Duration `yaml:"expiry,omitempty"`
+	CloudProvider string        `yaml:"cloud-provider,omitempty"`
+
+	// MaxExpiry caps the expiry of new clusters.  Unlike the other values,
+	// later layers can only lower the cap and never raise it.
+	MaxExpiry time.Duration `yaml:"max-expiry,omitempty"`
+}
+
+type DefaultsLayer struct {
+	Name     string
+	Defaults *Defaults
+}
+
+// EffectiveDefaults are the result of merging layers of defaults.  Sources
+// holds the name of the layer each value was taken from, keyed by the name
+// the value has in the config file.
+type EffectiveDefaults struct {
+	Defaults
+	Sources map[string]string
+}
+
+// LoadOrgDefaults reads a file of defaults shared by an organization.
+func LoadOrgDefaults(path string) (*Defaults, error) {
+	defaultsBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read org defaults file")
+	}
+
+	var defaults Defaults
+	err = yaml.Unmarshal(defaultsBytes, &defaults)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse org defaults file")
+	}
+
+	return &defaults, nil
+}
+
+// DefaultsLayers returns the layers of defaults which apply to a new cluster
+// using the named profile, from the lowest to the highest precedence.  The
+// default profile is used if no profile is named.
+func (c *Config) DefaultsLayers(profileName string) ([]DefaultsLayer, error) {
+	var layers []DefaultsLayer
+
+	if c.OrgDefaults != "" {
+		orgDefaults, err := LoadOrgDefaults(c.OrgDefaults)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, DefaultsLayer{Name: "org", Defaults: orgDefaults})
+	}
+
+	layers = append(layers, DefaultsLayer{
+		Name: "config",
+		Defaults: &Defaults{
+			Deployer: c.DefaultDeployer,
+			Expiry:   c.DefaultExpiry,
+		},
+	})
+
+	if profileName == "" {
+		profileName = c.DefaultProfile
+	}
+	if profileName != "" {
+		profile, ok := c.Profiles[profileName]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile `%s`", profileName)
+		}
+		if profile == nil {
+			profile = &Defaults{}
+		}
+
+		layers = append(layers, DefaultsLayer{Name: "profile " + profileName, Defaults: profile})
+	}
+
+	return layers, nil
+}
+
+// ResolveDefaults merges layers of defaults, from the lowest to the highest
+// precedence.
+func ResolveDefaults(layers []DefaultsLayer) *EffectiveDefaults {
+	result := &EffectiveDefaults{
+		Sources: make(map[string]string),
+	}
+
+	for _, layer := range layers {
+		defaults := layer.Defaults
+
+		if defaults.Deployer != "" {
+			result.Deployer = defaults.Deployer
+			result.Sources["deployer"] = layer.Name
+		}
+		if defaults.Version != "" {
+			result.Version = defaults.Version
+			result.Sources["version"] = layer.Name
+		}
+		if defaults.Purpose != "" {
+			result.Purpose = defaults.Purpose
+			result.Sources["purpose"] = layer.Name
+		}
+		if defaults.Expiry != 0 {
+			result.Expiry = defaults.Expiry
+			result.Sources["expiry"] = layer.Name
+		}
+		if defaults.CloudProvider != "" {
+			result.CloudProvider = defaults.CloudProvider
+			result.Sources["cloud-provider"] = layer.Name
+		}
+		if defaults.MaxExpiry != 0 && (result.MaxExpiry == 0 || defaults.MaxExpiry < result.MaxExpiry) {
+			result.MaxExpiry = defaults.MaxExpiry
+			result.Sources["max-expiry"] = layer.Name
+		}
+	}
+
+	return result
+}
+
+// Apply fills in the values which the definition does not specify, and
+// checks the expiry of the definition against the cap.  Clusters which
+// would otherwise never expire are given the maximum expiry.
+func (d *EffectiveDefaults) Apply(def *clusterdef.Cluster) error {
+	if def.Deployer == "" {
+		def.Deployer = d.Deployer
+	}
+	if def.Purpose == "" {
+		def.Purpose = d.Purpose
+	}
+	if def.Expiry == 0 {
+		def.Expiry = d.Expiry
+	}
+	if def.Cloud.CloudProvider == "" {
+		def.Cloud.CloudProvider = d.CloudProvider
+	}
+	for _, nodeGrp := range def.NodeGroups {
+		if nodeGrp.Version == "" {
+			nodeGrp.Version = d.Version
+		}
+	}
+
+	if d.MaxExpiry > 0 {
+		if def.Expiry == 0 {
+			def.Expiry = d.MaxExpiry
+		} else if def.Expiry > d.MaxExpiry {
+			return errors.Wrapf(ErrExpiryTooLong,
+				"expiry of %s is longer than the maximum of %s set by the %s defaults",
+				def.Expiry, d.MaxExpiry, d.Sources["max-expiry"])
+		}
+	}
+
+	return nil
+}
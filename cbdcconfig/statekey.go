@@ -0,0 +1,72 @@
+package cbdcconfig
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// StateKeySize is the size of the machine key, which is an AES-256 key.
+const StateKeySize = 32
+
+// StateKeyPath returns the path of the machine key used to encrypt the
+// state which is stored within nodes.
+func StateKeyPath() (string, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return configPath + ".key", nil
+}
+
+// LoadStateKey loads the machine key, returning nil if there is none.  If
+// create is specified, a new key is generated when there is none.
+func LoadStateKey(create bool) ([]byte, error) {
+	keyPath, err := StateKeyPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find state key path")
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != StateKeySize {
+			return nil, fmt.Errorf("state key at %s is invalid", keyPath)
+		}
+
+		return key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "failed to read state key")
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	key = make([]byte, StateKeySize)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate state key")
+	}
+
+	// O_EXCL avoids replacing a key created concurrently by another process,
+	// which would make the state encrypted with it unreadable.
+	keyFile, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return LoadStateKey(false)
+		}
+
+		return nil, errors.Wrap(err, "failed to create state key")
+	}
+	defer keyFile.Close()
+
+	_, err = keyFile.Write(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write state key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,143 @@
+package clusterdef
+
+import "golang.org/x/exp/maps"
+
+// CloudPreset is a named Capella sizing specification which can be applied to
+// a cluster definition, avoiding the need to repeat the full node specs.
+type CloudPreset struct {
+	CloudProvider string       `yaml:"cloud-provider,omitempty"`
+	Region        string       `yaml:"region,omitempty"`
+	NodeGroups    []*NodeGroup `yaml:"nodes,omitempty"`
+}
+
+func newCloudPresetNodeGroup(count int, cloud CloudNodeGroup) *NodeGroup {
+	return &NodeGroup{
+		Count: count,
+		Services: []Service{
+			KvService,
+			IndexService,
+			QueryService,
+			SearchService,
+		},
+		Cloud: cloud,
+	}
+}
+
+var awsPresetSpec = CloudNodeGroup{
+	InstanceType: "m5.xlarge",
+	Cpu:          4,
+	Memory:       16,
+	DiskType:     "gp3",
+	DiskSize:     50,
+	DiskIops:     3000,
+}
+
+var awsIo2PresetSpec = CloudNodeGroup{
+	InstanceType: "m5.2xlarge",
+	Cpu:          8,
+	Memory:       32,
+	DiskType:     "io2",
+	DiskSize:     100,
+	DiskIops:     5000,
+}
+
+var azurePresetSpec = CloudNodeGroup{
+	InstanceType: "Standard_D4s_v5",
+	Cpu:          4,
+	Memory:       16,
+	DiskType:     "P6",
+	DiskSize:     64,
+	DiskIops:     240,
+}
+
+var gcpPresetSpec = CloudNodeGroup{
+	InstanceType: "n2-standard-4",
+	Cpu:          4,
+	Memory:       16,
+	DiskType:     "pd-ssd",
+	DiskSize:     50,
+}
+
+// BuiltinCloudPresets are the presets available without any configuration.
+// Presets of the same name in the users configuration take precedence.
+var BuiltinCloudPresets = map[string]*CloudPreset{
+	"tiny-aws": {
+		CloudProvider: "aws",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(1, awsPresetSpec)},
+	},
+	"3node-aws": {
+		CloudProvider: "aws",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(3, awsPresetSpec)},
+	},
+	"3node-aws-io2": {
+		CloudProvider: "aws",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(3, awsIo2PresetSpec)},
+	},
+	"tiny-azure": {
+		CloudProvider: "azure",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(1, azurePresetSpec)},
+	},
+	"3node-azure": {
+		CloudProvider: "azure",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(3, azurePresetSpec)},
+	},
+	"tiny-gcp": {
+		CloudProvider: "gcp",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(1, gcpPresetSpec)},
+	},
+	"3node-gcp": {
+		CloudProvider: "gcp",
+		NodeGroups:    []*NodeGroup{newCloudPresetNodeGroup(3, gcpPresetSpec)},
+	},
+}
+
+// ResolveCloudPreset finds a preset by name, preferring the user provided
+// presets over the builtin ones.
+func ResolveCloudPreset(userPresets map[string]*CloudPreset, name string) *CloudPreset {
+	if preset, ok := userPresets[name]; ok {
+		return preset
+	}
+	return BuiltinCloudPresets[name]
+}
+
+// CloudPresetNames returns the names of all available presets.
+func CloudPresetNames(userPresets map[string]*CloudPreset) []string {
+	names := maps.Keys(BuiltinCloudPresets)
+	for name := range userPresets {
+		if _, ok := BuiltinCloudPresets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Apply replaces the node groups of the definition with the presets node
+// groups.  The server version of the definitions first node group is kept,
+// and the cloud provider and region are only applied if not already set.
+func (p *CloudPreset) Apply(def *Cluster) {
+	version := ""
+	if len(def.NodeGroups) > 0 {
+		version = def.NodeGroups[0].Version
+	}
+
+	var nodeGroups []*NodeGroup
+	for _, presetGrp := range p.NodeGroups {
+		nodeGrp := *presetGrp
+		nodeGrp.Services = append([]Service{}, presetGrp.Services...)
+		if nodeGrp.Version == "" {
+			nodeGrp.Version = version
+		}
+		nodeGroups = append(nodeGroups, &nodeGrp)
+	}
+	def.NodeGroups = nodeGroups
+
+	if def.Deployer == "" {
+		def.Deployer = "cloud"
+	}
+	if def.Cloud.CloudProvider == "" {
+		def.Cloud.CloudProvider = p.CloudProvider
+	}
+	if def.Cloud.Region == "" {
+		def.Cloud.Region = p.Region
+	}
+}
@@ -0,0 +1,47 @@
+package clusterdef
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinCloudPresetSizes(t *testing.T) {
+	// these pin each preset to the published size of its instance type, so
+	// a changed instance type without a matching size change gets caught.
+	tests := []struct {
+		preset       string
+		provider     string
+		count        int
+		instanceType string
+		cpu          int
+		memory       int
+	}{
+		{"tiny-aws", "aws", 1, "m5.xlarge", 4, 16},
+		{"3node-aws", "aws", 3, "m5.xlarge", 4, 16},
+		{"3node-aws-io2", "aws", 3, "m5.2xlarge", 8, 32},
+		{"tiny-azure", "azure", 1, "Standard_D4s_v5", 4, 16},
+		{"3node-azure", "azure", 3, "Standard_D4s_v5", 4, 16},
+		{"tiny-gcp", "gcp", 1, "n2-standard-4", 4, 16},
+		{"3node-gcp", "gcp", 3, "n2-standard-4", 4, 16},
+	}
+
+	assert.Len(t, BuiltinCloudPresets, len(tests))
+
+	for _, test := range tests {
+		t.Run(test.preset, func(t *testing.T) {
+			preset := BuiltinCloudPresets[test.preset]
+			require.NotNil(t, preset)
+
+			assert.Equal(t, test.provider, preset.CloudProvider)
+			require.Len(t, preset.NodeGroups, 1)
+
+			nodeGrp := preset.NodeGroups[0]
+			assert.Equal(t, test.count, nodeGrp.Count)
+			assert.Equal(t, test.instanceType, nodeGrp.Cloud.InstanceType)
+			assert.Equal(t, test.cpu, nodeGrp.Cloud.Cpu)
+			assert.Equal(t, test.memory, nodeGrp.Cloud.Memory)
+		})
+	}
+}
@@ -14,6 +14,43 @@ type Cluster struct {
 	Docker DockerCluster `yaml:"docker,omitempty"`
 	Cao    CaoCluster    `yaml:"cao,omitempty"`
 	Cloud  CloudCluster  `yaml:"cloud,omitempty"`
+
+	// PostProvision lists steps which are run in order against the cluster
+	// once it has been allocated, such as loading datasets or creating
+	// indexes.
+	PostProvision []PostProvisionHook `yaml:"post-provision,omitempty"`
+}
+
+// PostProvisionHook is a single step run against a newly allocated cluster.
+// Exactly one of Query, Rest or Script must be specified.
+type PostProvisionHook struct {
+	// Name identifies the step in logs and errors.
+	Name string `yaml:"name,omitempty"`
+
+	// Query is a SQL++ statement to execute.
+	Query string `yaml:"query,omitempty"`
+
+	// Rest is a request made to the management endpoint of the cluster.
+	Rest *PostProvisionRest `yaml:"rest,omitempty"`
+
+	// Script is a shell script run on this machine, with the details needed
+	// to connect to the cluster in CBDC_CLUSTER_ID, CBDC_CONNSTR,
+	// CBDC_CONNSTR_TLS, CBDC_MGMT, CBDC_MGMT_TLS, CBDC_USERNAME and
+	// CBDC_PASSWORD.
+	Script string `yaml:"script,omitempty"`
+}
+
+type PostProvisionRest struct {
+	// Method defaults to POST.
+	Method string `yaml:"method,omitempty"`
+
+	// Path is the path of the request on the management endpoint, such as
+	// /settings/indexes.
+	Path string `yaml:"path"`
+
+	// Body is sent as form data unless ContentType says otherwise.
+	Body        string `yaml:"body,omitempty"`
+	ContentType string `yaml:"content-type,omitempty"`
 }
 
 type DockerCluster struct {
@@ -27,12 +64,88 @@ type DockerCluster struct {
 	EventingMemoryMB int `yaml:"eventing-memory,omitempty"`
 
 	Analytics AnalyticsSettings `yaml:"analytics,omitempty"`
+
+	Mobile MobileSettings `yaml:"mobile,omitempty"`
+
+	License DockerLicense `yaml:"license,omitempty"`
+
+	Serverless ServerlessSettings `yaml:"serverless,omitempty"`
+
+	// Snapshot is the name of a golden snapshot, or the full reference of
+	// its image, whose buckets, data and indexes are restored into the
+	// cluster once it has been initialized.
+	Snapshot string `yaml:"snapshot,omitempty"`
+}
+
+// ServerlessSettings configures clusters whose nodes run the serverless
+// profile (versions ending in `-serverless`), which emulate the multi-tenant
+// dataplane of Capella serverless.  Limits which are not specified keep the
+// server defaults.
+type ServerlessSettings struct {
+	// EnforceLimits enables enforcement of the throttle and storage limits,
+	// which are otherwise only reported.
+	EnforceLimits bool `yaml:"enforce-limits,omitempty"`
+
+	// DefaultLimits are the limits applied to newly created buckets.
+	DefaultLimits ServerlessLimits `yaml:"default-limits,omitempty"`
+}
+
+// ServerlessLimits are the throttle limits, in units per second, and storage
+// limits, in GB, of the services of a serverless bucket.
+type ServerlessLimits struct {
+	DataThrottleLimit   int `yaml:"data-throttle,omitempty"`
+	IndexThrottleLimit  int `yaml:"index-throttle,omitempty"`
+	SearchThrottleLimit int `yaml:"search-throttle,omitempty"`
+	QueryThrottleLimit  int `yaml:"query-throttle,omitempty"`
+	DataStorageLimit    int `yaml:"data-storage,omitempty"`
+	IndexStorageLimit   int `yaml:"index-storage,omitempty"`
+	SearchStorageLimit  int `yaml:"search-storage,omitempty"`
+}
+
+func (l ServerlessLimits) IsEmpty() bool {
+	return l == ServerlessLimits{}
+}
+
+// DockerLicense provides license material to the server nodes when they are
+// started, for images such as mirrored or test builds which require the
+// license to be explicitly accepted or supplied.
+type DockerLicense struct {
+	// Accept sets COUCHBASE_ACCEPT_LICENSE=true in the node containers.
+	Accept bool `yaml:"accept,omitempty"`
+
+	// File is the path to a license file on this machine, which is copied
+	// into each node with COUCHBASE_LICENSE_FILE pointing at it.
+	File string `yaml:"file,omitempty"`
+}
+
+// MobileSettings describes the mobile stack deployed alongside a cluster,
+// which is a Sync Gateway wired to a bucket of the cluster and optionally
+// a test app container which is pointed at the Sync Gateway.
+type MobileSettings struct {
+	// SyncGatewayVersion enables the mobile stack when specified.
+	SyncGatewayVersion string `yaml:"sync-gateway-version,omitempty"`
+
+	// Bucket is the bucket the Sync Gateway database is backed by, which
+	// is created if it does not already exist.  Defaults to `mobile`.
+	Bucket string `yaml:"bucket,omitempty"`
+
+	// TestAppImage is the image of a test app to run, which receives the
+	// websocket endpoint of the Sync Gateway database in SYNC_GATEWAY_URL.
+	TestAppImage string `yaml:"test-app-image,omitempty"`
 }
 
 type AnalyticsSettings struct {
 	BlobStorage AnalyticsBlobStorageSettings `yaml:"blob-storage,omitempty"`
 }
 
+const (
+	BlobStorageProviderS3Mock = "s3mock"
+	BlobStorageProviderMinio  = "minio"
+)
+
+// AnalyticsBlobStorageSettings configures the blob storage used by the
+// analytics service of columnar clusters.  When no endpoint is specified,
+// the deployer launches the storage alongside the cluster using Provider.
 type AnalyticsBlobStorageSettings struct {
 	Region        string `yaml:"region,omitempty"`
 	Prefix        string `yaml:"prefix,omitempty"`
@@ -40,6 +153,15 @@ type AnalyticsBlobStorageSettings struct {
 	Scheme        string `yaml:"scheme,omitempty"`
 	Endpoint      string `yaml:"endpoint,omitempty"`
 	AnonymousAuth bool   `yaml:"anonymous-auth,omitempty"`
+
+	// Provider is either `s3mock` (the default) or `minio`.  Unlike s3mock,
+	// MinIO validates the credentials of requests.
+	Provider string `yaml:"provider,omitempty"`
+
+	// AccessKey and SecretKey are the credentials the nodes use for the
+	// storage, and the root credentials of a deployed MinIO.
+	AccessKey string `yaml:"access-key,omitempty"`
+	SecretKey string `yaml:"secret-key,omitempty"`
 }
 
 type CaoCluster struct {
@@ -50,6 +172,19 @@ type CaoCluster struct {
 	GatewayVersion  string `yaml:"gateway-version,omitempty"`
 
 	GatewayLogLevel string `yaml:"gateway-log-level,omitempty"`
+
+	// BackupVersion enables operator managed backups using this version of
+	// the operator backup image.
+	BackupVersion string `yaml:"backup-version,omitempty"`
+
+	// ServerGroups lists the availability zones which the operator spreads
+	// the pods of the cluster across, keyed by the zone label of the k8s
+	// nodes.  Each zone becomes a server group of the cluster.
+	ServerGroups []string `yaml:"server-groups,omitempty"`
+
+	// AntiAffinity prevents two pods of the cluster from being scheduled on
+	// the same k8s node.
+	AntiAffinity bool `yaml:"anti-affinity,omitempty"`
 }
 
 type CloudCluster struct {
@@ -1,6 +1,11 @@
 package clusterdef
 
 type NodeGroup struct {
+	// Name optionally names the group, which is recorded on its nodes so
+	// that they can later be targeted as a group, for instance with the
+	// `group:analytics` node identifier.
+	Name string `yaml:"name,omitempty"`
+
 	// Count specifies the number of nodes of this type to create.
 	Count int `yaml:"count,omitempty"`
 
@@ -16,11 +21,45 @@ type NodeGroup struct {
 
 	Docker DockerNodeGroup `yaml:"docker,omitempty"`
 	Cloud  CloudNodeGroup  `yaml:"cloud,omitempty"`
+	Cao    CaoNodeGroup    `yaml:"cao,omitempty"`
 }
 
 type DockerNodeGroup struct {
 	Image   string            `yaml:"image,omitempty"`
 	EnvVars map[string]string `yaml:"env,omitempty"`
+
+	// Ulimits overrides the resource limits applied to the node containers,
+	// for instance, setting `core` to -1 to enable core dumps.
+	Ulimits []DockerUlimit `yaml:"ulimits,omitempty"`
+
+	// Sysctls specifies namespaced kernel parameters to set in the container.
+	Sysctls map[string]string `yaml:"sysctls,omitempty"`
+
+	// Volumes specifies extra volumes to mount in `source:target[:mode]` form.
+	Volumes []string `yaml:"volumes,omitempty"`
+
+	// Args specifies the arguments to pass to the container entrypoint.
+	Args []string `yaml:"args,omitempty"`
+
+	// OsTuning applies the OS settings recommended for production, such as
+	// disabling transparent huge pages, lowering swappiness and raising the
+	// process limits.  Kernel settings are only applied where the docker
+	// host permits it, and what was applied is recorded on each node.
+	OsTuning bool `yaml:"os-tuning,omitempty"`
+
+	// MemoryLimitMB limits the memory of the node containers, including
+	// swap.  Processes of the node are killed when it is exceeded.
+	MemoryLimitMB int `yaml:"memory-limit,omitempty"`
+
+	// Host places the nodes on a specific configured docker host rather
+	// than spreading them across all the hosts.
+	Host string `yaml:"host,omitempty"`
+}
+
+type DockerUlimit struct {
+	Name string `yaml:"name"`
+	Soft int64  `yaml:"soft"`
+	Hard int64  `yaml:"hard"`
 }
 
 type CloudNodeGroup struct {
@@ -32,3 +71,25 @@ type CloudNodeGroup struct {
 	DiskSize     int    `yaml:"disk-size,omitempty"`
 	DiskIops     int    `yaml:"disk-iops,omitempty"`
 }
+
+type CaoNodeGroup struct {
+	// Zones pins the pods of this node group to specific availability
+	// zones, rather than spreading them across all the server groups of
+	// the cluster.
+	Zones []string `yaml:"zones,omitempty"`
+
+	// NodeSelector restricts the pods of this node group to k8s nodes with
+	// matching labels.
+	NodeSelector map[string]string `yaml:"node-selector,omitempty"`
+
+	// Tolerations allows the pods of this node group to be scheduled on
+	// tainted k8s nodes.
+	Tolerations []CaoToleration `yaml:"tolerations,omitempty"`
+}
+
+type CaoToleration struct {
+	Key      string `yaml:"key,omitempty"`
+	Operator string `yaml:"operator,omitempty"`
+	Value    string `yaml:"value,omitempty"`
+	Effect   string `yaml:"effect,omitempty"`
+}
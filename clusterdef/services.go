@@ -2,6 +2,9 @@ package clusterdef
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
 
 	"golang.org/x/exp/slices"
@@ -133,3 +136,40 @@ func ServicesToCaoServices(services []Service) ([]string, error) {
 	}
 	return out, nil
 }
+
+// ParseServices parses a comma separated list of services, such as from the
+// command line.  Both the ns_server names and the friendlier names used by
+// the operator (`data`, `query`, `search`, etc...) are accepted.
+func ParseServices(servicesStr string) ([]Service, error) {
+	var out []Service
+	for _, serviceStr := range strings.Split(servicesStr, ",") {
+		serviceStr = strings.TrimSpace(serviceStr)
+		if serviceStr == "" {
+			continue
+		}
+
+		service, err := CaoServiceToService(serviceStr)
+		if err != nil {
+			service = Service(serviceStr)
+		}
+
+		switch service {
+		case KvService, QueryService, IndexService, SearchService,
+			AnalyticsService, EventingService, BackupService:
+		default:
+			return nil, fmt.Errorf("unknown service `%s`", serviceStr)
+		}
+
+		if slices.Contains(out, service) {
+			return nil, fmt.Errorf("duplicate service `%s`", serviceStr)
+		}
+
+		out = append(out, service)
+	}
+
+	if len(out) == 0 {
+		return nil, errors.New("at least one service must be specified")
+	}
+
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt [flags] identifier",
+	Short: "Adopts a cluster which was created outside of cbdinocluster",
+	Long: "Adopts a cluster which was created outside of cbdinocluster, so that it can be\n" +
+		"used with the other commands and is removed once it expires.  For docker, the\n" +
+		"identifier is a cluster ID, container ID or node name of containers created by\n" +
+		"cbdyncluster.  For capella, it is the Capella cluster ID, and its project is\n" +
+		"renamed, which requires that the cluster is the only one in its project.",
+	Example: "adopt --deployer docker 2a1e5f4c\nadopt --deployer capella 7b5c1d9e-4f1a-4c8e-9d3b-0a2f6e8c1b4d",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+		config := helper.GetConfig(ctx)
+
+		deployerName, _ := cmd.Flags().GetString("deployer")
+		purpose, _ := cmd.Flags().GetString("purpose")
+		expiry, _ := cmd.Flags().GetDuration("expiry")
+		expiryIsSet := cmd.Flags().Changed("expiry")
+
+		if !expiryIsSet {
+			expiry = config.DefaultExpiry
+		}
+
+		// a zero expiry keeps the cluster until it is removed
+		var expiryTime time.Time
+		if expiry > 0 {
+			expiryTime = time.Now().Add(expiry)
+		}
+
+		// capella clusters are managed by the cloud deployer
+		if deployerName == "capella" {
+			deployerName = "cloud"
+		}
+
+		deployer := helper.GetDeployerByName(ctx, deployerName)
+
+		var cluster deployment.ClusterInfo
+		var err error
+		switch deployer := deployer.(type) {
+		case *dockerdeploy.Deployer:
+			if purpose != "" {
+				logger.Warn("docker clusters keep the purpose they were created with")
+			}
+
+			cluster, err = deployer.AdoptCluster(ctx, &dockerdeploy.AdoptClusterOptions{
+				Identifier: args[0],
+				Expiry:     expiryTime,
+			})
+		case *clouddeploy.Deployer:
+			cluster, err = deployer.AdoptCluster(ctx, &clouddeploy.AdoptClusterOptions{
+				CloudClusterID: args[0],
+				Expiry:         expiryTime,
+				Purpose:        purpose,
+			})
+		default:
+			logger.Fatal("adopting clusters is only supported for the docker and capella deployers",
+				zap.String("deployer", deployerName))
+		}
+		if err != nil {
+			logger.Fatal("failed to adopt cluster", zap.Error(err))
+		}
+
+		logger.Info("cluster adopted",
+			zap.String("cluster", cluster.GetID()),
+			zap.Time("expiry", expiryTime))
+
+		if !helper.IsDryRun() {
+			helper.RegisterCluster(ctx, deployerName, cluster, nil)
+		}
+
+		fmt.Printf("%s\n", cluster.GetID())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().String("deployer", "docker", "The deployer which can reach the cluster (docker, capella)")
+	adoptCmd.Flags().String("purpose", "", "The purpose for the cluster, for capella clusters")
+	adoptCmd.Flags().Duration("expiry", 0, "The time to keep this cluster for, before it is removed")
+}
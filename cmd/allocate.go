@@ -5,6 +5,10 @@ import (
 
 	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/cioutput"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -13,7 +17,8 @@ var allocateCmd = &cobra.Command{
 	Use:     "allocate [flags] [definition-tag | --def | --def-file]",
 	Aliases: []string{"alloc", "create"},
 	Short:   "Allocates a cluster",
-	Example: "allocate simple:7.0.0\nallocate single:7.2.0",
+	Example: "allocate simple:7.0.0\nallocate single:7.2.0\nallocate --profile quick simple:7.2.0\nallocate --profile small simple:7.6.0\nallocate --preset 3node-aws single:7.6.0\n" +
+		"allocate --version 7.6.2 --num-nodes 3 --services kv,n1ql,index --bucket default\nallocate --version stable",
 	Run: func(cmd *cobra.Command, args []string) {
 		helper := CmdHelper{}
 		logger := helper.GetLogger()
@@ -28,6 +33,15 @@ var allocateCmd = &cobra.Command{
 		expiryIsSet := cmd.Flags().Changed("expiry")
 		deployerName, _ := cmd.Flags().GetString("deployer")
 		cloudProvider, _ := cmd.Flags().GetString("cloud-provider")
+		profile, _ := cmd.Flags().GetString("profile")
+		presetName, _ := cmd.Flags().GetString("preset")
+		version, _ := cmd.Flags().GetString("version")
+		numNodes, _ := cmd.Flags().GetInt("num-nodes")
+		numNodesIsSet := cmd.Flags().Changed("num-nodes")
+		servicesStr, _ := cmd.Flags().GetString("services")
+		servicesIsSet := cmd.Flags().Changed("services")
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		skipDnsWait, _ := cmd.Flags().GetBool("skip-dns-wait")
 
 		var def *clusterdef.Cluster
 
@@ -36,9 +50,91 @@ var allocateCmd = &cobra.Command{
 			simpleDefStr = args[0]
 		}
 
-		def, err := helper.FetchClusterDef(simpleDefStr, defStr, defFile)
-		if err != nil {
-			logger.Fatal("failed to get definition", zap.Error(err))
+		// profiles other than the builtin deployment profiles select a
+		// layer of defaults from the config
+		isQuickProfile := false
+		isSmallProfile := false
+		defaultsProfile := ""
+		switch profile {
+		case "", dockerdeploy.ProfileStandard:
+		case dockerdeploy.ProfileQuick:
+			isQuickProfile = true
+		case dockerdeploy.ProfileSmall:
+			isSmallProfile = true
+		default:
+			defaultsProfile = profile
+		}
+
+		defaults := helper.GetEffectiveDefaults(ctx, defaultsProfile)
+
+		hasDef := simpleDefStr != "" || defStr != "" || defFile != ""
+		if version == "" && presetName == "" && !hasDef {
+			version = defaults.Version
+		}
+
+		var err error
+		if version != "" {
+			if hasDef {
+				logger.Fatal("--version cannot be combined with another form of cluster definition")
+			}
+
+			// the node count and services are applied below
+			def = &clusterdef.Cluster{
+				NodeGroups: []*clusterdef.NodeGroup{
+					{
+						Count:   3,
+						Version: version,
+						Services: []clusterdef.Service{
+							clusterdef.KvService,
+							clusterdef.QueryService,
+							clusterdef.IndexService,
+							clusterdef.SearchService,
+						},
+					},
+				},
+			}
+		} else if presetName != "" && !hasDef {
+			// presets fully describe the topology, so a definition is optional
+			def = &clusterdef.Cluster{}
+		} else {
+			def, err = helper.FetchClusterDef(simpleDefStr, defStr, defFile)
+			if err != nil {
+				logger.Fatal("failed to get definition", zap.Error(err))
+			}
+		}
+
+		if presetName != "" {
+			preset := clusterdef.ResolveCloudPreset(config.Capella.Presets, presetName)
+			if preset == nil {
+				logger.Fatal("failed to find preset",
+					zap.String("preset", presetName),
+					zap.Strings("availablePresets", clusterdef.CloudPresetNames(config.Capella.Presets)))
+			}
+
+			preset.Apply(def)
+		}
+
+		if numNodesIsSet || servicesIsSet {
+			if len(def.NodeGroups) != 1 {
+				logger.Fatal("--num-nodes and --services can only be used with definitions with a single node group")
+			}
+
+			if numNodesIsSet {
+				if numNodes <= 0 {
+					logger.Fatal("--num-nodes must be at least 1")
+				}
+
+				def.NodeGroups[0].Count = numNodes
+			}
+
+			if servicesIsSet {
+				services, err := clusterdef.ParseServices(servicesStr)
+				if err != nil {
+					logger.Fatal("failed to parse services", zap.Error(err))
+				}
+
+				def.NodeGroups[0].Services = services
+			}
 		}
 
 		if purpose != "" {
@@ -46,8 +142,6 @@ var allocateCmd = &cobra.Command{
 		}
 		if expiryIsSet {
 			def.Expiry = expiry
-		} else if def.Expiry == 0 {
-			def.Expiry = config.DefaultExpiry
 		}
 		if deployerName != "" {
 			def.Deployer = deployerName
@@ -56,12 +150,88 @@ var allocateCmd = &cobra.Command{
 			def.Cloud.CloudProvider = cloudProvider
 		}
 
+		if isQuickProfile || isSmallProfile {
+			if def.Deployer != "" && def.Deployer != "docker" {
+				logger.Fatal("the profile is only supported by the docker deployer",
+					zap.String("profile", profile),
+					zap.String("deployer", def.Deployer))
+			}
+
+			// this stops a default deployer from being applied below
+			def.Deployer = "docker"
+		}
+
+		err = defaults.Apply(def)
+		if err != nil {
+			logger.Fatal("failed to apply defaults", zap.Error(err))
+		}
+
+		helper.ResolveVersionNames(ctx, def)
+
+		if isQuickProfile {
+			var collapsed bool
+			def, collapsed, err = dockerdeploy.QuickProfileDefinition(def)
+			if err != nil {
+				logger.Fatal("failed to apply quick profile", zap.Error(err))
+			}
+
+			if collapsed {
+				logger.Warn("quick profile is collapsing the cluster into a single node")
+			}
+		}
+
+		if isSmallProfile {
+			var removedServices []clusterdef.Service
+			def, removedServices, err = dockerdeploy.SmallProfileDefinition(def)
+			if err != nil {
+				logger.Fatal("failed to apply small profile", zap.Error(err))
+			}
+
+			if len(removedServices) > 0 {
+				logger.Warn("small profile removed services which do not fit within its memory limits",
+					zap.Any("services", removedServices))
+			}
+		}
+
 		logger.Info("deploying definition", zap.Any("def", def))
 
+		if def.Deployer == "cloud" || (def.Deployer == "" && config.DefaultDeployer == "cloud") {
+			cloudDeployer := helper.GetCloudDeployer(ctx)
+			hourlyCost, err := cloudDeployer.EstimateDefinitionCost(ctx, def)
+			if err != nil {
+				logger.Warn("failed to estimate cluster cost", zap.Error(err))
+			} else {
+				costFields := []zap.Field{
+					zap.String("hourly", fmt.Sprintf("$%.2f", hourlyCost)),
+				}
+				if def.Expiry > 0 {
+					costFields = append(costFields,
+						zap.String("untilExpiry", fmt.Sprintf("$%.2f", hourlyCost*def.Expiry.Hours())))
+				}
+				logger.Info("estimated cluster cost", costFields...)
+			}
+		}
+
 		if dryRun {
 			return
 		}
 
+		resolvedDeployerName := def.Deployer
+		if resolvedDeployerName == "" {
+			resolvedDeployerName = config.DefaultDeployer
+		}
+
+		ciOutput := helper.GetCIOutput()
+		if ciOutput != nil {
+			ciOutput.StartGroup("allocating cluster")
+		}
+
+		progressCtx, finishProgress := helper.StartProgress(ctx,
+			"allocating cluster", "allocate-"+resolvedDeployerName)
+		if skipDnsWait {
+			progressCtx = clouddeploy.WithSkipDnsWait(progressCtx)
+		}
+
 		var deployer deployment.Deployer
 		if def.Deployer == "" {
 			deployer = helper.GetDefaultDeployer(ctx)
@@ -69,8 +239,24 @@ var allocateCmd = &cobra.Command{
 			deployer = helper.GetDeployerByName(ctx, def.Deployer)
 		}
 
-		cluster, err := deployer.NewCluster(ctx, def)
+		trackedCtx, finishPartials := helper.TrackPartialClusters(progressCtx, resolvedDeployerName, deployer)
+		cluster, err := deployer.NewCluster(trackedCtx, def)
+		finishPartials(err)
+		finishProgress(err == nil)
+		if ciOutput != nil {
+			ciOutput.EndGroup()
+		}
 		if err != nil {
+			helper.FatalIfInterrupted(err)
+
+			switch {
+			case errors.Is(err, deployment.ErrVersionUnavailable):
+				logger.Fatal("cluster deployment failed, the requested version is not available", zap.Error(err))
+			case errors.Is(err, deployment.ErrQuotaExceeded):
+				logger.Fatal("cluster deployment failed, a resource quota has been exceeded", zap.Error(err))
+			case errors.Is(err, deployment.ErrProvisionTimeout):
+				logger.Fatal("cluster deployment timed out", zap.Error(err))
+			}
 			logger.Fatal("cluster deployment failed", zap.Error(err))
 		}
 
@@ -82,18 +268,68 @@ var allocateCmd = &cobra.Command{
 				zap.String("connstr", connectInfo.ConnStr))
 		}
 
-		fmt.Printf("%s\n", cluster.GetID())
+		helper.RegisterCluster(ctx, resolvedDeployerName, cluster, connectInfo)
+
+		// the cluster exists from here on, so its id is reported even if the
+		// rest of its setup fails, so that the caller can still remove it.
+		outputCluster := func() {
+			ciOutputs := []cioutput.Output{
+				{Name: "cluster_id", Value: cluster.GetID()},
+			}
+			if connectInfo != nil {
+				ciOutputs = append(ciOutputs,
+					cioutput.Output{Name: "connstr", Value: connectInfo.ConnStr},
+					cioutput.Output{Name: "connstr_tls", Value: connectInfo.ConnStrTls},
+					cioutput.Output{Name: "mgmt", Value: connectInfo.Mgmt})
+			}
+			helper.SetCIOutputs(ciOutput, ciOutputs)
+
+			fmt.Printf("%s\n", cluster.GetID())
+		}
+
+		if bucketName != "" {
+			err := deployer.CreateBucket(ctx, cluster.GetID(), &deployment.CreateBucketOptions{
+				Name: bucketName,
+			})
+			if err != nil {
+				outputCluster()
+				logger.Fatal("failed to create bucket",
+					zap.Error(err),
+					zap.String("cluster", cluster.GetID()))
+			}
+		}
+
+		err = deployment.RunPostProvisionHooks(ctx, deployer, &deployment.RunPostProvisionHooksOptions{
+			Logger:    logger,
+			ClusterID: cluster.GetID(),
+			Hooks:     def.PostProvision,
+		})
+		if err != nil {
+			outputCluster()
+			helper.FatalIfInterrupted(err)
+			logger.Fatal("failed to run post-provision hooks",
+				zap.Error(err),
+				zap.String("cluster", cluster.GetID()))
+		}
+
+		outputCluster()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(allocateCmd)
 
-	allocateCmd.Flags().Bool("dry-run", false, "Disables the actual allocate and simply does a dry-run.")
 	allocateCmd.Flags().String("def", "", "The cluster definition you wish to provision.")
 	allocateCmd.Flags().String("def-file", "", "The path to a file containing a cluster definition to provision.")
 	allocateCmd.Flags().String("purpose", "", "The purpose for allocating this cluster")
 	allocateCmd.Flags().Duration("expiry", 0, "The time to keep this cluster allocated for")
 	allocateCmd.Flags().String("deployer", "", "The name of the deployer to use")
 	allocateCmd.Flags().String("cloud-provider", "", "The cloud provider to use for this cluster")
+	allocateCmd.Flags().String("profile", "", "The deployment profile to use (standard, quick, small), or the name of a profile of defaults from the config")
+	allocateCmd.Flags().String("preset", "", "The name of a Capella sizing preset to apply to the definition")
+	allocateCmd.Flags().String("version", "", "The server version of a cluster to allocate without a definition")
+	allocateCmd.Flags().Int("num-nodes", 3, "The number of nodes in the cluster")
+	allocateCmd.Flags().String("services", "kv,n1ql,index,fts", "The comma separated services of the nodes in the cluster")
+	allocateCmd.Flags().String("bucket", "", "The name of a bucket to create once the cluster is allocated")
+	allocateCmd.Flags().Bool("skip-dns-wait", false, "Returns Capella clusters without waiting for their DNS records to propagate")
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var backupsCreateCmd = &cobra.Command{
+	Use:   "create [cluster] [backup-name]",
+	Short: "Creates an operator managed backup of a cao cluster",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		strategy, _ := cmd.Flags().GetString("strategy")
+		fullSchedule, _ := cmd.Flags().GetString("full-schedule")
+		incrementalSchedule, _ := cmd.Flags().GetString("incremental-schedule")
+		size, _ := cmd.Flags().GetString("size")
+		backupVersion, _ := cmd.Flags().GetString("backup-version")
+		wait, _ := cmd.Flags().GetBool("wait")
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		err := deployer.CreateBackup(ctx, clusterID, &caodeploy.CreateBackupOptions{
+			Name:                args[1],
+			Strategy:            strategy,
+			FullSchedule:        fullSchedule,
+			IncrementalSchedule: incrementalSchedule,
+			Size:                size,
+			BackupVersion:       backupVersion,
+		})
+		if err != nil {
+			logger.Fatal("failed to create backup", zap.Error(err))
+		}
+
+		if wait {
+			job, err := deployer.WaitBackupJob(ctx, clusterID, args[1])
+			if err != nil {
+				logger.Fatal("backup did not succeed", zap.Error(err))
+			}
+
+			fmt.Printf("Backup job %s succeeded\n", job.Name)
+		}
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsCreateCmd)
+
+	backupsCreateCmd.Flags().String("strategy", "", "The backup strategy, one of immediate_full (default), immediate_incremental, full_only or full_incremental")
+	backupsCreateCmd.Flags().String("full-schedule", "", "The cron schedule of full backups")
+	backupsCreateCmd.Flags().String("incremental-schedule", "", "The cron schedule of incremental backups")
+	backupsCreateCmd.Flags().String("size", "", "The size of the backup volume, such as 5Gi")
+	backupsCreateCmd.Flags().String("backup-version", "", "The operator backup image version, needed when the cluster does not have backups enabled yet")
+	backupsCreateCmd.Flags().Bool("wait", false, "Wait for the first backup job to complete")
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type BackupsJobsOutput []BackupsJobsOutput_Item
+
+type BackupsJobsOutput_Item struct {
+	Name           string     `json:"name"`
+	OwnerKind      string     `json:"ownerKind"`
+	OwnerName      string     `json:"ownerName"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Active         int        `json:"active"`
+	Succeeded      int        `json:"succeeded"`
+	Failed         int        `json:"failed"`
+}
+
+var backupsJobsCmd = &cobra.Command{
+	Use:   "jobs [cluster] [backup-or-restore-name]",
+	Short: "Lists the jobs run for the backups and restores of a cao cluster",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		ownerName := ""
+		if len(args) >= 2 {
+			ownerName = args[1]
+		}
+
+		jobs, err := deployer.ListBackupJobs(ctx, clusterID, ownerName)
+		if err != nil {
+			logger.Fatal("failed to list backup jobs", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Jobs:\n")
+			for _, job := range jobs {
+				state := "running"
+				if job.Succeeded > 0 {
+					state = "succeeded"
+				} else if job.Failed > 0 && job.Active == 0 {
+					state = "failed"
+				}
+
+				fmt.Printf("  %s [%s: %s, State: %s, Started: %s]\n",
+					job.Name,
+					job.OwnerKind,
+					job.OwnerName,
+					state,
+					job.StartTime.Format(time.RFC3339))
+			}
+		} else {
+			out := BackupsJobsOutput{}
+			for _, job := range jobs {
+				out = append(out, BackupsJobsOutput_Item{
+					Name:           job.Name,
+					OwnerKind:      job.OwnerKind,
+					OwnerName:      job.OwnerName,
+					StartTime:      backupTimePtr(job.StartTime),
+					CompletionTime: backupTimePtr(job.CompletionTime),
+					Active:         job.Active,
+					Succeeded:      job.Succeeded,
+					Failed:         job.Failed,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsJobsCmd)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type BackupsListOutput struct {
+	Backups  []BackupsListOutput_Backup  `json:"backups"`
+	Restores []BackupsListOutput_Restore `json:"restores"`
+}
+
+type BackupsListOutput_Backup struct {
+	Name        string                   `json:"name"`
+	Strategy    string                   `json:"strategy"`
+	Running     bool                     `json:"running"`
+	Failed      bool                     `json:"failed"`
+	Repo        string                   `json:"repo,omitempty"`
+	LastRun     *time.Time               `json:"lastRun,omitempty"`
+	LastSuccess *time.Time               `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time               `json:"lastFailure,omitempty"`
+	Repos       []BackupsListOutput_Repo `json:"repos,omitempty"`
+}
+
+type BackupsListOutput_Repo struct {
+	Name         string   `json:"name"`
+	Full         string   `json:"full,omitempty"`
+	Incrementals []string `json:"incrementals,omitempty"`
+}
+
+type BackupsListOutput_Restore struct {
+	Name        string     `json:"name"`
+	Backup      string     `json:"backup"`
+	Repo        string     `json:"repo"`
+	Running     bool       `json:"running"`
+	Failed      bool       `json:"failed"`
+	LastRun     *time.Time `json:"lastRun,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+}
+
+func backupTimePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func backupStateStr(running, failed bool) string {
+	if running {
+		return "running"
+	} else if failed {
+		return "failed"
+	}
+	return "idle"
+}
+
+var backupsListCmd = &cobra.Command{
+	Use:   "list [cluster]",
+	Short: "Lists the operator managed backups and restores of a cao cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		backups, err := deployer.ListBackups(ctx, clusterID)
+		if err != nil {
+			logger.Fatal("failed to list backups", zap.Error(err))
+		}
+
+		restores, err := deployer.ListRestores(ctx, clusterID)
+		if err != nil {
+			logger.Fatal("failed to list restores", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Backups:\n")
+			for _, backup := range backups {
+				fmt.Printf("  %s [Strategy: %s, State: %s]\n",
+					backup.Name,
+					backup.Strategy,
+					backupStateStr(backup.Running, backup.Failed))
+				if !backup.LastSuccess.IsZero() {
+					fmt.Printf("    Last Success: %s\n", backup.LastSuccess.Format(time.RFC3339))
+				}
+				if !backup.LastFailure.IsZero() {
+					fmt.Printf("    Last Failure: %s\n", backup.LastFailure.Format(time.RFC3339))
+				}
+				for _, repo := range backup.Backups {
+					fmt.Printf("    Repo: %s [Full: %s, Incrementals: %s]\n",
+						repo.Name,
+						repo.Full,
+						strings.Join(repo.Incrementals, ","))
+				}
+			}
+
+			fmt.Printf("Restores:\n")
+			for _, restore := range restores {
+				fmt.Printf("  %s [Backup: %s, Repo: %s, State: %s]\n",
+					restore.Name,
+					restore.BackupName,
+					restore.Repo,
+					backupStateStr(restore.Running, restore.Failed))
+			}
+		} else {
+			out := BackupsListOutput{
+				Backups:  []BackupsListOutput_Backup{},
+				Restores: []BackupsListOutput_Restore{},
+			}
+			for _, backup := range backups {
+				backupOut := BackupsListOutput_Backup{
+					Name:        backup.Name,
+					Strategy:    backup.Strategy,
+					Running:     backup.Running,
+					Failed:      backup.Failed,
+					Repo:        backup.Repo,
+					LastRun:     backupTimePtr(backup.LastRun),
+					LastSuccess: backupTimePtr(backup.LastSuccess),
+					LastFailure: backupTimePtr(backup.LastFailure),
+				}
+				for _, repo := range backup.Backups {
+					backupOut.Repos = append(backupOut.Repos, BackupsListOutput_Repo{
+						Name:         repo.Name,
+						Full:         repo.Full,
+						Incrementals: repo.Incrementals,
+					})
+				}
+				out.Backups = append(out.Backups, backupOut)
+			}
+			for _, restore := range restores {
+				out.Restores = append(out.Restores, BackupsListOutput_Restore{
+					Name:        restore.Name,
+					Backup:      restore.BackupName,
+					Repo:        restore.Repo,
+					Running:     restore.Running,
+					Failed:      restore.Failed,
+					LastRun:     backupTimePtr(restore.LastRun),
+					LastSuccess: backupTimePtr(restore.LastSuccess),
+					LastFailure: backupTimePtr(restore.LastFailure),
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsListCmd)
+}
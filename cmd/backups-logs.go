@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var backupsLogsCmd = &cobra.Command{
+	Use:   "logs [cluster] [job-name]",
+	Short: "Prints the output of a backup or restore job",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		logs, err := deployer.GetBackupJobLogs(ctx, clusterID, args[1])
+		if err != nil {
+			logger.Fatal("failed to get job logs", zap.Error(err))
+		}
+
+		fmt.Print(logs)
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsLogsCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var backupsRemoveCmd = &cobra.Command{
+	Use:   "remove [cluster] [backup-name]",
+	Short: "Removes an operator managed backup along with its jobs",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		err := deployer.DeleteBackup(ctx, clusterID, args[1])
+		if err != nil {
+			logger.Fatal("failed to remove backup", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsRemoveCmd)
+}
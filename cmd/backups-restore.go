@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var backupsRestoreCmd = &cobra.Command{
+	Use:   "restore [cluster] [backup-name] [restore-name]",
+	Short: "Restores an operator managed backup into the cluster",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		repo, _ := cmd.Flags().GetString("repo")
+		wait, _ := cmd.Flags().GetBool("wait")
+
+		deployer, clusterID := identifyCaoBackupCluster(&helper, args)
+
+		err := deployer.CreateRestore(ctx, clusterID, &caodeploy.CreateRestoreOptions{
+			Name:       args[2],
+			BackupName: args[1],
+			Repo:       repo,
+		})
+		if err != nil {
+			logger.Fatal("failed to create restore", zap.Error(err))
+		}
+
+		if wait {
+			job, err := deployer.WaitBackupJob(ctx, clusterID, args[2])
+			if err != nil {
+				logger.Fatal("restore did not succeed", zap.Error(err))
+			}
+
+			fmt.Printf("Restore job %s succeeded\n", job.Name)
+		}
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsRestoreCmd)
+
+	backupsRestoreCmd.Flags().String("repo", "", "The backup repository to restore, defaulting to the current repository of the backup")
+	backupsRestoreCmd.Flags().Bool("wait", false, "Wait for the restore job to complete")
+}
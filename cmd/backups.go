@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Provides access to operator managed backups of cao clusters",
+	Run:   nil,
+}
+
+// identifyCaoBackupCluster identifies a cluster for the backup commands,
+// which are only supported by the cao deployer.
+func identifyCaoBackupCluster(helper *CmdHelper, args []string) (*caodeploy.Deployer, string) {
+	logger := helper.GetLogger()
+	ctx := helper.GetContext()
+
+	_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+	caoDeployer, ok := deployer.(*caodeploy.Deployer)
+	if !ok {
+		logger.Fatal("backups are only supported for cao clusters",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	return caoDeployer, cluster.GetID()
+}
+
+func init() {
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchFile is a list of commands which are run as a single unit.
+type BatchFile struct {
+	// Vars are substituted into the args of steps as ${NAME}, falling back
+	// to environment variables for names which are not listed.
+	Vars map[string]string `yaml:"vars"`
+
+	// ContinueOnError runs the remaining steps after a step fails, rather
+	// than stopping at the first failure.
+	ContinueOnError bool `yaml:"continue-on-error"`
+
+	Steps []BatchFile_Step `yaml:"steps"`
+}
+
+type BatchFile_Step struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"`
+
+	// ContinueOnError overrides the batch-wide setting for this step.
+	ContinueOnError *bool `yaml:"continue-on-error"`
+}
+
+type BatchRunOutput struct {
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Skipped   int                   `json:"skipped"`
+	Steps     []BatchRunOutput_Step `json:"steps"`
+}
+
+type BatchRunOutput_Step struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Status   string   `json:"status"`
+	ExitCode int      `json:"exit-code"`
+	Duration string   `json:"duration"`
+}
+
+// batchFlagState is the state of a flag, which is restored between steps so
+// that the flags of one step do not leak into the next.
+type batchFlagState struct {
+	value   string
+	slice   []string
+	changed bool
+}
+
+func visitAllFlags(cmd *cobra.Command, fn func(flag *pflag.Flag)) {
+	cmd.Flags().VisitAll(fn)
+	cmd.PersistentFlags().VisitAll(fn)
+	for _, subCmd := range cmd.Commands() {
+		visitAllFlags(subCmd, fn)
+	}
+}
+
+func saveFlagStates() map[*pflag.Flag]batchFlagState {
+	states := make(map[*pflag.Flag]batchFlagState)
+	visitAllFlags(rootCmd, func(flag *pflag.Flag) {
+		state := batchFlagState{
+			value:   flag.Value.String(),
+			changed: flag.Changed,
+		}
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			state.slice = sliceValue.GetSlice()
+		}
+		states[flag] = state
+	})
+	return states
+}
+
+func restoreFlagStates(states map[*pflag.Flag]batchFlagState) {
+	visitAllFlags(rootCmd, func(flag *pflag.Flag) {
+		state, ok := states[flag]
+		if !ok {
+			// flags such as help are only created once they are needed
+			state = batchFlagState{
+				value: flag.DefValue,
+			}
+			if _, ok := flag.Value.(pflag.SliceValue); ok {
+				defValue := strings.Trim(flag.DefValue, "[]")
+				if defValue != "" {
+					state.slice = strings.Split(defValue, ",")
+				}
+			}
+		}
+
+		if flag.Value.String() != state.value {
+			if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+				_ = sliceValue.Replace(state.slice)
+			} else {
+				_ = flag.Value.Set(state.value)
+			}
+		}
+		flag.Changed = state.changed
+	})
+}
+
+// runBatchStep runs a single command of a batch within this process and
+// returns its exit code.
+func runBatchStep(args []string) (exitCode int) {
+	flagStates := saveFlagStates()
+	defer restoreFlagStates(flagStates)
+
+	inBatchStep = true
+	defer func() {
+		inBatchStep = false
+
+		if r := recover(); r != nil {
+			stepExit, ok := r.(batchStepExit)
+			if !ok {
+				panic(r)
+			}
+
+			exitCode = stepExit.ExitCode
+		}
+	}()
+
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	if err != nil {
+		return ExitUsage
+	}
+
+	return ExitSuccess
+}
+
+var batchRunCmd = &cobra.Command{
+	Use:   "run <batch-file>",
+	Short: "Runs the commands of a batch file as a single unit",
+	Long: `Runs the commands of a batch file as a single unit, within a single process.
+
+The batch file lists the steps to run, each being the arguments of a command,
+with ${NAME} being substituted from the vars of the batch file, the --var flag
+or the environment:
+
+  vars:
+    CLUSTER: 0a1b2c3d
+  steps:
+    - name: create bucket
+      args: [buckets, add, "${CLUSTER}", default, --ram-quota-mb, "256"]
+    - name: create user
+      args: [users, add, "${CLUSTER}", app, --password, password]
+      continue-on-error: true
+
+The batch stops at the first step which fails unless continue-on-error is set,
+and exits with the exit code of the first step which failed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		vars, _ := cmd.Flags().GetStringToString("var")
+		continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+
+		batchBytes, err := os.ReadFile(args[0])
+		if err != nil {
+			logger.Fatal("failed to read batch file", zap.Error(err))
+		}
+
+		var batch BatchFile
+		err = yaml.Unmarshal(batchBytes, &batch)
+		if err != nil {
+			logger.Fatal("failed to parse batch file", zap.Error(err))
+		}
+
+		if cmd.Flags().Changed("continue-on-error") {
+			batch.ContinueOnError = continueOnError
+		}
+
+		expandVar := func(name string) string {
+			if value, ok := vars[name]; ok {
+				return value
+			}
+			if value, ok := batch.Vars[name]; ok {
+				return value
+			}
+			return os.Getenv(name)
+		}
+
+		for stepIdx, step := range batch.Steps {
+			if len(step.Args) == 0 {
+				logger.Fatal("batch step has no args",
+					zap.Int("step", stepIdx+1),
+					zap.Error(errors.New("every step must specify the args of a command")))
+			}
+			if step.Args[0] == "batch" {
+				logger.Fatal("batch step runs another batch",
+					zap.Int("step", stepIdx+1),
+					zap.Error(errors.New("batches cannot be nested")))
+			}
+		}
+
+		out := BatchRunOutput{}
+		firstExitCode := ExitSuccess
+		stopped := false
+		for stepIdx, step := range batch.Steps {
+			stepName := step.Name
+			if stepName == "" {
+				stepName = fmt.Sprintf("step %d", stepIdx+1)
+			}
+
+			var stepArgs []string
+			for _, arg := range step.Args {
+				stepArgs = append(stepArgs, os.Expand(arg, expandVar))
+			}
+
+			if stopped {
+				out.Skipped++
+				out.Steps = append(out.Steps, BatchRunOutput_Step{
+					Name:   stepName,
+					Args:   stepArgs,
+					Status: "skipped",
+				})
+				continue
+			}
+
+			logger.Info("running batch step",
+				zap.String("name", stepName),
+				zap.Strings("args", stepArgs))
+
+			stepStart := time.Now()
+			exitCode := runBatchStep(stepArgs)
+			stepDuration := time.Since(stepStart)
+
+			status := "succeeded"
+			if exitCode != ExitSuccess {
+				status = "failed"
+				out.Failed++
+
+				logger.Warn("batch step failed",
+					zap.String("name", stepName),
+					zap.Int("exitCode", exitCode))
+
+				if firstExitCode == ExitSuccess {
+					firstExitCode = exitCode
+				}
+
+				stepContinueOnError := batch.ContinueOnError
+				if step.ContinueOnError != nil {
+					stepContinueOnError = *step.ContinueOnError
+				}
+				if !stepContinueOnError {
+					stopped = true
+				}
+			} else {
+				out.Succeeded++
+			}
+
+			out.Steps = append(out.Steps, BatchRunOutput_Step{
+				Name:     stepName,
+				Args:     stepArgs,
+				Status:   status,
+				ExitCode: exitCode,
+				Duration: stepDuration.Round(time.Millisecond).String(),
+			})
+		}
+
+		if !outputJson {
+			fmt.Printf("Batch Steps:\n")
+			for _, step := range out.Steps {
+				if step.Status == "skipped" {
+					fmt.Printf("  %s [Status: %s]\n", step.Name, step.Status)
+				} else {
+					fmt.Printf("  %s [Status: %s, Exit Code: %d, Duration: %s]\n",
+						step.Name, step.Status, step.ExitCode, step.Duration)
+				}
+			}
+			fmt.Printf("Succeeded: %d, Failed: %d, Skipped: %d\n",
+				out.Succeeded, out.Failed, out.Skipped)
+		} else {
+			helper.OutputJson(out)
+		}
+
+		if firstExitCode != ExitSuccess {
+			exitWithCode(firstExitCode)
+		}
+	},
+}
+
+func init() {
+	batchCmd.AddCommand(batchRunCmd)
+
+	batchRunCmd.Flags().StringToString("var", nil, "Sets a variable of the batch file, in NAME=VALUE form")
+	batchRunCmd.Flags().Bool("continue-on-error", false, "Runs the remaining steps after a step fails, overriding the batch file")
+}
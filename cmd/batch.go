@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Provides the ability to run many commands as a single unit",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+}
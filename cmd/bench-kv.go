@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/kvbench"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type BenchKvOutput_Bucket struct {
+	UpperBoundUs int64 `json:"upper_bound_us"`
+	Count        int   `json:"count"`
+}
+
+type BenchKvOutput_Op struct {
+	Type       string                 `json:"type"`
+	Ops        int                    `json:"ops"`
+	OpsPerSec  float64                `json:"ops_per_sec"`
+	Errors     int                    `json:"errors"`
+	FirstError string                 `json:"first_error,omitempty"`
+	MinUs      int64                  `json:"min_us"`
+	MeanUs     int64                  `json:"mean_us"`
+	P50Us      int64                  `json:"p50_us"`
+	P90Us      int64                  `json:"p90_us"`
+	P99Us      int64                  `json:"p99_us"`
+	P999Us     int64                  `json:"p999_us"`
+	MaxUs      int64                  `json:"max_us"`
+	Histogram  []BenchKvOutput_Bucket `json:"histogram"`
+}
+
+type BenchKvOutput struct {
+	DurationSecs float64            `json:"duration_secs"`
+	Ops          []BenchKvOutput_Op `json:"ops"`
+}
+
+// benchHistogramWidth is the width of the largest bar of a printed histogram.
+const benchHistogramWidth = 40
+
+var benchKvCmd = &cobra.Command{
+	Use:   "kv [cluster]",
+	Short: "Runs a lightweight kv and query load against a cluster and reports its latencies",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		username, _ := cmd.Flags().GetString("username")
+		password, _ := cmd.Flags().GetString("password")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		workers, _ := cmd.Flags().GetInt("workers")
+		numKeys, _ := cmd.Flags().GetInt("keys")
+		docSize, _ := cmd.Flags().GetInt("doc-size")
+		readRatio, _ := cmd.Flags().GetFloat64("read-ratio")
+		queryRatio, _ := cmd.Flags().GetFloat64("query-ratio")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		var connectInfo *deployment.ConnectInfo
+		var err error
+		if username == "" {
+			connectInfo, err = deployment.GetConnectInfoWithCredentials(ctx, deployer, cluster.GetID(),
+				&deployment.ConnectCredentialsOptions{
+					Buckets: []string{bucketName},
+				})
+			if err != nil {
+				logger.Warn("failed to create dedicated credentials, using the default credentials",
+					zap.Error(err))
+
+				connectInfo = nil
+				username = "Administrator"
+				password = "password"
+			} else {
+				username = connectInfo.Username
+				password = connectInfo.Password
+			}
+		}
+		if connectInfo == nil {
+			connectInfo, err = deployer.GetConnectInfo(ctx, cluster.GetID())
+			if err != nil {
+				logger.Fatal("failed to get connect info", zap.Error(err))
+			}
+		}
+
+		result, err := kvbench.Run(ctx, &kvbench.Options{
+			Logger:     logger,
+			ConnStr:    toolsConnStr(connectInfo),
+			Username:   username,
+			Password:   password,
+			BucketName: bucketName,
+			Duration:   duration,
+			Workers:    workers,
+			NumKeys:    numKeys,
+			DocSize:    docSize,
+			ReadRatio:  readRatio,
+			QueryRatio: queryRatio,
+		})
+		if err != nil {
+			helper.FatalIfInterrupted(err)
+			logger.Fatal("failed to run benchmark", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Ran for %s\n", result.Duration.Round(time.Millisecond))
+
+			for _, op := range result.Ops {
+				latencies := op.Latencies
+				fmt.Printf("\n%s:\n", op.Type)
+				fmt.Printf("  Ops: %d (%.1f/s)\n",
+					latencies.Count(),
+					float64(latencies.Count())/result.Duration.Seconds())
+				fmt.Printf("  Errors: %d\n", op.Errors)
+				if op.FirstError != "" {
+					fmt.Printf("  First Error: %s\n", op.FirstError)
+				}
+				if latencies.Count() == 0 {
+					continue
+				}
+
+				fmt.Printf("  Latency: min %s, mean %s, max %s\n",
+					latencies.Min(), latencies.Mean(), latencies.Max())
+				fmt.Printf("  Percentiles: p50 %s, p90 %s, p99 %s, p99.9 %s\n",
+					latencies.Percentile(50),
+					latencies.Percentile(90),
+					latencies.Percentile(99),
+					latencies.Percentile(99.9))
+
+				buckets := latencies.Buckets()
+				maxCount := 0
+				for _, bucket := range buckets {
+					maxCount = max(maxCount, bucket.Count)
+				}
+
+				fmt.Printf("  Histogram:\n")
+				for _, bucket := range buckets {
+					barWidth := max(bucket.Count*benchHistogramWidth/maxCount, 1)
+					fmt.Printf("    < %-10s %8d %s\n",
+						bucket.UpperBound.Round(time.Microsecond),
+						bucket.Count,
+						strings.Repeat("#", barWidth))
+				}
+			}
+		} else {
+			var out BenchKvOutput
+			out.DurationSecs = result.Duration.Seconds()
+			for _, op := range result.Ops {
+				latencies := op.Latencies
+
+				opOut := BenchKvOutput_Op{
+					Type:       string(op.Type),
+					Ops:        latencies.Count(),
+					OpsPerSec:  float64(latencies.Count()) / result.Duration.Seconds(),
+					Errors:     op.Errors,
+					FirstError: op.FirstError,
+					MinUs:      latencies.Min().Microseconds(),
+					MeanUs:     latencies.Mean().Microseconds(),
+					P50Us:      latencies.Percentile(50).Microseconds(),
+					P90Us:      latencies.Percentile(90).Microseconds(),
+					P99Us:      latencies.Percentile(99).Microseconds(),
+					P999Us:     latencies.Percentile(99.9).Microseconds(),
+					MaxUs:      latencies.Max().Microseconds(),
+					Histogram:  []BenchKvOutput_Bucket{},
+				}
+				for _, bucket := range latencies.Buckets() {
+					opOut.Histogram = append(opOut.Histogram, BenchKvOutput_Bucket{
+						UpperBoundUs: bucket.UpperBound.Microseconds(),
+						Count:        bucket.Count,
+					})
+				}
+
+				out.Ops = append(out.Ops, opOut)
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	benchCmd.AddCommand(benchKvCmd)
+
+	benchKvCmd.Flags().String("bucket", "default", "The bucket to run the load against")
+	benchKvCmd.Flags().String("username", "", "The username to connect with, instead of creating dedicated credentials")
+	benchKvCmd.Flags().String("password", "", "The password to connect with, instead of creating dedicated credentials")
+	benchKvCmd.Flags().Duration("duration", 60*time.Second, "How long to run the load for")
+	benchKvCmd.Flags().Int("workers", 8, "The number of operations to run concurrently")
+	benchKvCmd.Flags().Int("keys", 1000, "The number of documents to read and write")
+	benchKvCmd.Flags().Int("doc-size", 256, "The approximate size of each document in bytes")
+	benchKvCmd.Flags().Float64("read-ratio", 0.8, "The fraction of kv operations which are reads rather than writes")
+	benchKvCmd.Flags().Float64("query-ratio", 0.1, "The fraction of all operations which are queries")
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Provides access to benchmarks which can be run against a cluster",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+}
@@ -21,6 +21,21 @@ var bucketsAddCmd = &cobra.Command{
 		ramQuotaMB, _ := cmd.Flags().GetInt("ram-quota-mb")
 		flushEnabled, _ := cmd.Flags().GetBool("flush-enabled")
 		numReplicas, _ := cmd.Flags().GetInt("num-replicas")
+		numReplicasIsSet := cmd.Flags().Changed("num-replicas")
+		bucketType, _ := cmd.Flags().GetString("bucket-type")
+		evictionPolicy, _ := cmd.Flags().GetString("eviction-policy")
+		storageBackend, _ := cmd.Flags().GetString("storage-backend")
+		historyRetentionBytes, _ := cmd.Flags().GetInt("history-retention-bytes")
+		historyRetentionDuration, _ := cmd.Flags().GetDuration("history-retention-duration")
+
+		if storageBackend != "" && storageBackend != "couchstore" && storageBackend != "magma" {
+			logger.Fatal("unexpected storage backend", zap.String("storageBackend", storageBackend))
+		}
+
+		// memcached buckets have no replicas, so the default is not applied
+		if bucketType == string(deployment.BucketTypeMemcached) && !numReplicasIsSet {
+			numReplicas = 0
+		}
 
 		_, deployer, cluster := helper.IdentifyCluster(ctx, clusterID)
 
@@ -29,6 +44,13 @@ var bucketsAddCmd = &cobra.Command{
 			RamQuotaMB:   ramQuotaMB,
 			FlushEnabled: flushEnabled,
 			NumReplicas:  numReplicas,
+
+			BucketType:     deployment.BucketType(bucketType),
+			EvictionPolicy: evictionPolicy,
+			StorageBackend: storageBackend,
+
+			HistoryRetentionBytes:    historyRetentionBytes,
+			HistoryRetentionDuration: historyRetentionDuration,
 		})
 		if err != nil {
 			logger.Fatal("failed to create bucket", zap.Error(err))
@@ -42,4 +64,9 @@ func init() {
 	bucketsAddCmd.Flags().Int("ram-quota-mb", 0, "The amount of RAM to provide for the bucket.")
 	bucketsAddCmd.Flags().Bool("flush-enabled", false, "Whether flush is enabled on the bucket.")
 	bucketsAddCmd.Flags().Int("num-replicas", 1, "The number of replicas for the bucket.")
+	bucketsAddCmd.Flags().String("bucket-type", "", "The type of the bucket (couchbase, ephemeral, memcached).")
+	bucketsAddCmd.Flags().String("eviction-policy", "", "The eviction policy of the bucket (valueOnly, fullEviction for couchbase, noEviction, nruEviction for ephemeral).")
+	bucketsAddCmd.Flags().String("storage-backend", "", "The storage backend for the bucket (couchstore, magma).")
+	bucketsAddCmd.Flags().Int("history-retention-bytes", 0, "The maximum size of the change history of the bucket (requires magma).")
+	bucketsAddCmd.Flags().Duration("history-retention-duration", 0, "The maximum age of the change history of the bucket (requires magma).")
 }
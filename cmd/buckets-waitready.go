@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var bucketsWaitReadyCmd = &cobra.Command{
+	Use:   "wait-ready",
+	Short: "Waits for all buckets to finish warming up, such as after restarting nodes",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("waiting for bucket warmup is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		err := dockerDeployer.WaitForBucketsReady(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to wait for buckets", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	bucketsCmd.AddCommand(bucketsWaitReadyCmd)
+
+	bucketsWaitReadyCmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for the buckets")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaEnvsOutput []CapellaEnvsOutput_Item
+
+type CapellaEnvsOutput_Item struct {
+	Name      string `json:"name"`
+	Endpoint  string `json:"endpoint"`
+	AuthStyle string `json:"auth_style"`
+	UIUrl     string `json:"ui_url,omitempty"`
+	IsCurrent bool   `json:"is_current"`
+}
+
+var capellaEnvsCmd = &cobra.Command{
+	Use:   "envs",
+	Short: "Lists the Capella environments which can be selected",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+		config := helper.GetConfig(ctx)
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		currentEnv := helper.GetCapellaEnvironment(ctx)
+
+		out := CapellaEnvsOutput{}
+		for _, name := range config.Capella.CapellaEnvironmentNames() {
+			env, err := config.Capella.ResolveEnvironment(name)
+			if err != nil {
+				logger.Warn("failed to resolve capella environment", zap.Error(err))
+				continue
+			}
+
+			out = append(out, CapellaEnvsOutput_Item{
+				Name:      env.Name,
+				Endpoint:  env.Endpoint,
+				AuthStyle: env.AuthStyle,
+				UIUrl:     env.UIUrl,
+				IsCurrent: env.Name == currentEnv.Name,
+			})
+		}
+
+		if currentEnv.Name == "" {
+			out = append(out, CapellaEnvsOutput_Item{
+				Name:      "",
+				Endpoint:  currentEnv.Endpoint,
+				AuthStyle: currentEnv.AuthStyle,
+				UIUrl:     currentEnv.UIUrl,
+				IsCurrent: true,
+			})
+		}
+
+		if !outputJson {
+			fmt.Printf("Environments:\n")
+			for _, env := range out {
+				currentMarker := " "
+				if env.IsCurrent {
+					currentMarker = "*"
+				}
+
+				name := env.Name
+				if name == "" {
+					name = "(endpoint)"
+				}
+
+				fmt.Printf(" %s %s  %s [%s]\n", currentMarker, name, env.Endpoint, env.AuthStyle)
+				if env.UIUrl != "" {
+					fmt.Printf("     ui: %s\n", env.UIUrl)
+				}
+			}
+		} else {
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaEnvsCmd)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaJanitorOutput []CapellaJanitorOutput_Item
+
+type CapellaJanitorOutput_Item struct {
+	Type       string     `json:"type"`
+	ProjectID  string     `json:"project_id"`
+	ResourceID string     `json:"resource_id"`
+	Name       string     `json:"name"`
+	Expiry     *time.Time `json:"expiry,omitempty"`
+	Reason     string     `json:"reason"`
+}
+
+var capellaJanitorCmd = &cobra.Command{
+	Use:   "janitor",
+	Short: "Removes expired dyncluster resources across the entire Capella tenant",
+	Long: "Scans the whole Capella tenant for dyncluster clusters, columnars and projects " +
+		"which have exceeded their expiry and removes them, regardless of which machine created them. " +
+		"Use --dry-run to only list what would be removed.",
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		maxAge, _ := cmd.Flags().GetDuration("max-age")
+
+		deployer := helper.GetCloudDeployer(ctx)
+
+		report := &deployment.CleanupReport{}
+		resources, err := deployer.RunJanitor(deployment.WithCleanupReport(ctx, report, "janitor"), &clouddeploy.JanitorOptions{
+			MaxAge: maxAge,
+		})
+
+		// the report is written even on failure, so that what was removed
+		// prior to the failure is not lost
+		writeCleanupReportFile(cmd, &helper, report.Actions())
+
+		if err != nil {
+			logger.Fatal("failed to run janitor", zap.Error(err))
+		}
+
+		if !outputJson {
+			if helper.IsDryRun() {
+				fmt.Printf("Would Remove Resources:\n")
+			} else {
+				fmt.Printf("Removed Resources:\n")
+			}
+			for _, resource := range resources {
+				expiryStr := "none"
+				if !resource.Expiry.IsZero() {
+					expiryStr = resource.Expiry.Format(time.RFC3339)
+				}
+
+				fmt.Printf("  %-10s %-40s %s [Expiry: %s, Reason: %s]\n",
+					resource.Type,
+					resource.ResourceID,
+					resource.Name,
+					expiryStr,
+					resource.Reason)
+			}
+		} else {
+			out := CapellaJanitorOutput{}
+			for _, resource := range resources {
+				item := CapellaJanitorOutput_Item{
+					Type:       string(resource.Type),
+					ProjectID:  resource.ProjectID,
+					ResourceID: resource.ResourceID,
+					Name:       resource.Name,
+					Reason:     resource.Reason,
+				}
+				if !resource.Expiry.IsZero() {
+					expiry := resource.Expiry
+					item.Expiry = &expiry
+				}
+				out = append(out, item)
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaJanitorCmd)
+
+	capellaJanitorCmd.Flags().Duration("max-age", 0, "Also remove dyncluster resources without expiry meta-data once older than this")
+	addCleanupReportFlags(capellaJanitorCmd)
+}
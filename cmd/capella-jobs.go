@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaJobsOutput []CapellaJobsOutput_Item
+
+type CapellaJobsOutput_Item struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	StartTime   time.Time `json:"start_time"`
+	Progress    int       `json:"progress"`
+	CurrentStep string    `json:"current_step"`
+	InitiatedBy string    `json:"initiated_by"`
+}
+
+var capellaJobsCmd = &cobra.Command{
+	Use:   "jobs [cluster]",
+	Short: "Lists the Capella jobs of a cluster and their progress",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		cloudDeployer, ok := deployer.(*clouddeploy.Deployer)
+		if !ok {
+			logger.Fatal("jobs are only supported for cloud deployer")
+		}
+
+		jobs, err := cloudDeployer.ListClusterJobs(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to list jobs", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Jobs:\n")
+			for _, job := range jobs {
+				fmt.Printf("  %s %s [Progress: %d%%, Step: %s, Started: %s, By: %s]\n",
+					job.ID,
+					job.JobType,
+					job.CompletionPercentage,
+					job.CurrentStep,
+					job.StartTime.Format(time.RFC3339),
+					job.InitiatedBy)
+			}
+		} else {
+			out := CapellaJobsOutput{}
+			for _, job := range jobs {
+				out = append(out, CapellaJobsOutput_Item{
+					ID:          job.ID,
+					Type:        job.JobType,
+					StartTime:   job.StartTime,
+					Progress:    job.CompletionPercentage,
+					CurrentStep: job.CurrentStep,
+					InitiatedBy: job.InitiatedBy,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaJobsCmd)
+}
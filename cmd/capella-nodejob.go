@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaNodeJobCmd = &cobra.Command{
+	Use:   "node-job [cluster] [node-hostname] [replace/repair]",
+	Short: "Triggers a Capella node replacement or repair job",
+	Long: "Triggers a Capella-side job which replaces or repairs a node of the cluster, " +
+		"allowing SDK behaviour during node replacement to be tested.  This requires an " +
+		"internal support token to be configured.",
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		noWait, _ := cmd.Flags().GetBool("no-wait")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		cloudDeployer, ok := deployer.(*clouddeploy.Deployer)
+		if !ok {
+			logger.Fatal("node jobs are only supported for cloud deployer")
+		}
+
+		var action clouddeploy.NodeMaintenanceAction
+		switch args[2] {
+		case "replace":
+			action = clouddeploy.NodeMaintenanceReplace
+		case "repair":
+			action = clouddeploy.NodeMaintenanceRepair
+		default:
+			logger.Fatal("unexpected node job type",
+				zap.String("type", args[2]))
+		}
+
+		err := cloudDeployer.StartNodeMaintenance(ctx, cluster.GetID(), args[1], action)
+		if err != nil {
+			logger.Fatal("failed to start node job", zap.Error(err))
+		}
+
+		if noWait || helper.IsDryRun() {
+			return
+		}
+
+		logger.Info("waiting for node job to complete")
+
+		err = cloudDeployer.WaitForClusterJobs(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to wait for node job", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaNodeJobCmd)
+
+	capellaNodeJobCmd.Flags().Bool("no-wait", false, "Do not wait for the job to complete")
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaOrgsListOutput []CapellaOrgsListOutput_Item
+
+type CapellaOrgsListOutput_Item struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default"`
+}
+
+func listCapellaOrgs(ctx context.Context, client *capellacontrol.Controller) ([]*capellacontrol.OrganizationInfo, error) {
+	resp, err := client.ListOrganizations(ctx, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list organizations")
+	}
+
+	var orgs []*capellacontrol.OrganizationInfo
+	for _, org := range resp.Data {
+		orgs = append(orgs, org.Data)
+	}
+
+	return orgs, nil
+}
+
+// findCapellaOrg finds an organization by its exact id, or otherwise by a
+// case-insensitive match of its name.
+func findCapellaOrg(orgs []*capellacontrol.OrganizationInfo, userInput string) (*capellacontrol.OrganizationInfo, error) {
+	for _, org := range orgs {
+		if org.ID == userInput {
+			return org, nil
+		}
+	}
+
+	var foundOrg *capellacontrol.OrganizationInfo
+	for _, org := range orgs {
+		if strings.EqualFold(org.Name, userInput) {
+			if foundOrg != nil {
+				return nil, fmt.Errorf("multiple organizations are named `%s`, use its id instead", userInput)
+			}
+			foundOrg = org
+		}
+	}
+	if foundOrg == nil {
+		return nil, fmt.Errorf("failed to find organization `%s`", userInput)
+	}
+
+	return foundOrg, nil
+}
+
+var capellaOrgsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the Capella organizations available to your credentials",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+		capellaEnv := helper.GetCapellaEnvironment(ctx)
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		client := helper.GetCapellaController(ctx)
+
+		orgs, err := listCapellaOrgs(ctx, client)
+		if err != nil {
+			logger.Fatal("failed to list organizations", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Organizations:\n")
+			for _, org := range orgs {
+				defaultMarker := " "
+				if org.ID == capellaEnv.OrganizationID {
+					defaultMarker = "*"
+				}
+
+				fmt.Printf(" %s %s  %s\n",
+					defaultMarker,
+					org.ID,
+					org.Name)
+			}
+		} else {
+			out := CapellaOrgsListOutput{}
+			for _, org := range orgs {
+				out = append(out, CapellaOrgsListOutput_Item{
+					ID:        org.ID,
+					Name:      org.Name,
+					IsDefault: org.ID == capellaEnv.OrganizationID,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaOrgsCmd.AddCommand(capellaOrgsListCmd)
+}
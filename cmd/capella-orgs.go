@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var capellaOrgsCmd = &cobra.Command{
+	Use:   "orgs",
+	Short: "Provides tools for the Capella organizations available to you",
+	Run:   nil,
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaOrgsCmd)
+}
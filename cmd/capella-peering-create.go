@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaPeeringCreateCmd = &cobra.Command{
+	Use:   "create [cluster] [name]",
+	Short: "Requests a VPC peering between a Capella cluster and another network",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		cidr, _ := cmd.Flags().GetString("cidr")
+		accountID, _ := cmd.Flags().GetString("aws-account-id")
+		vpcID, _ := cmd.Flags().GetString("aws-vpc-id")
+		region, _ := cmd.Flags().GetString("aws-region")
+		projectID, _ := cmd.Flags().GetString("gcp-project-id")
+		networkName, _ := cmd.Flags().GetString("gcp-network")
+		serviceAccount, _ := cmd.Flags().GetString("gcp-service-account")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, clusterID := identifyPeeringCluster(&helper, args[0])
+
+		peer, err := deployer.CreateNetworkPeer(ctx, clusterID, &clouddeploy.CreateNetworkPeerOptions{
+			Name:           args[1],
+			Cidr:           cidr,
+			AccountID:      accountID,
+			VpcID:          vpcID,
+			Region:         region,
+			ProjectID:      projectID,
+			NetworkName:    networkName,
+			ServiceAccount: serviceAccount,
+		})
+		if err != nil {
+			logger.Fatal("failed to create network peer", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("%s\n", peer.ID)
+		} else {
+			helper.OutputJson(CapellaPeeringListOutput_Item{
+				ID:           peer.ID,
+				Name:         peer.Name,
+				ProviderType: peer.ProviderType,
+				Cidr:         peer.Cidr,
+				Network:      peer.Network,
+				State:        peer.State,
+				Reasoning:    peer.Reasoning,
+				ProviderID:   peer.ProviderID,
+				HostedZoneID: peer.HostedZoneID,
+				CreatedAt:    peer.CreatedAt,
+			})
+		}
+	},
+}
+
+func init() {
+	capellaPeeringCmd.AddCommand(capellaPeeringCreateCmd)
+
+	capellaPeeringCreateCmd.Flags().String("cidr", "", "The CIDR of the peered network")
+	capellaPeeringCreateCmd.Flags().String("aws-account-id", "", "The AWS account of the peered VPC")
+	capellaPeeringCreateCmd.Flags().String("aws-vpc-id", "", "The id of the peered AWS VPC")
+	capellaPeeringCreateCmd.Flags().String("aws-region", "", "The region of the peered AWS VPC, defaulting to the region of the cluster")
+	capellaPeeringCreateCmd.Flags().String("gcp-project-id", "", "The GCP project of the peered network")
+	capellaPeeringCreateCmd.Flags().String("gcp-network", "", "The name of the peered GCP network")
+	capellaPeeringCreateCmd.Flags().String("gcp-service-account", "", "The GCP service account Capella uses to set up the peering")
+}
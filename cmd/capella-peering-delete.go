@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaPeeringDeleteCmd = &cobra.Command{
+	Use:   "delete [cluster] [peer]",
+	Short: "Deletes a network peer of a Capella cluster by its id or name",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer, clusterID := identifyPeeringCluster(&helper, args[0])
+
+		err := deployer.DeleteNetworkPeer(ctx, clusterID, args[1])
+		if err != nil {
+			logger.Fatal("failed to delete network peer", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	capellaPeeringCmd.AddCommand(capellaPeeringDeleteCmd)
+}
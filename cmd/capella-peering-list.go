@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaPeeringListOutput []CapellaPeeringListOutput_Item
+
+type CapellaPeeringListOutput_Item struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ProviderType string    `json:"provider_type"`
+	Cidr         string    `json:"cidr"`
+	Network      string    `json:"network"`
+	State        string    `json:"state"`
+	Reasoning    string    `json:"reasoning,omitempty"`
+	ProviderID   string    `json:"provider_id,omitempty"`
+	HostedZoneID string    `json:"hosted_zone_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+var capellaPeeringListCmd = &cobra.Command{
+	Use:   "list [cluster]",
+	Short: "Lists the network peers of a Capella cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, clusterID := identifyPeeringCluster(&helper, args[0])
+
+		peers, err := deployer.ListNetworkPeers(ctx, clusterID)
+		if err != nil {
+			logger.Fatal("failed to list network peers", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Network Peers:\n")
+			for _, peer := range peers {
+				fmt.Printf("  %s %s [Provider: %s, Network: %s, CIDR: %s, State: %s]\n",
+					peer.ID,
+					peer.Name,
+					peer.ProviderType,
+					peer.Network,
+					peer.Cidr,
+					peer.State)
+				if peer.Reasoning != "" {
+					fmt.Printf("    %s\n", peer.Reasoning)
+				}
+			}
+		} else {
+			out := CapellaPeeringListOutput{}
+			for _, peer := range peers {
+				out = append(out, CapellaPeeringListOutput_Item{
+					ID:           peer.ID,
+					Name:         peer.Name,
+					ProviderType: peer.ProviderType,
+					Cidr:         peer.Cidr,
+					Network:      peer.Network,
+					State:        peer.State,
+					Reasoning:    peer.Reasoning,
+					ProviderID:   peer.ProviderID,
+					HostedZoneID: peer.HostedZoneID,
+					CreatedAt:    peer.CreatedAt,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaPeeringCmd.AddCommand(capellaPeeringListCmd)
+}
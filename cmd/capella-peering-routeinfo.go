@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaPeeringRouteInfoOutput struct {
+	ProviderID   string `json:"provider_id"`
+	HostedZoneID string `json:"hosted_zone_id,omitempty"`
+	Command      string `json:"command"`
+}
+
+var capellaPeeringRouteInfoCmd = &cobra.Command{
+	Use:   "route-info [cluster] [peer]",
+	Short: "Prints how to accept a network peering and route to the cluster from the peered network",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, clusterID := identifyPeeringCluster(&helper, args[0])
+
+		info, err := deployer.GetNetworkPeerRouteInfo(ctx, clusterID, args[1])
+		if err != nil {
+			logger.Fatal("failed to get network peer route info", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Peering Connection: %s\n", info.ProviderID)
+			if info.HostedZoneID != "" {
+				fmt.Printf("Hosted Zone: %s\n", info.HostedZoneID)
+			}
+			fmt.Printf("%s\n", info.Command)
+		} else {
+			helper.OutputJson(CapellaPeeringRouteInfoOutput{
+				ProviderID:   info.ProviderID,
+				HostedZoneID: info.HostedZoneID,
+				Command:      info.Command,
+			})
+		}
+	},
+}
+
+func init() {
+	capellaPeeringCmd.AddCommand(capellaPeeringRouteInfoCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaPeeringCmd = &cobra.Command{
+	Use:   "peering",
+	Short: "Provides access to VPC peering of Capella clusters",
+	Run:   nil,
+}
+
+// identifyPeeringCluster identifies a cluster for the peering commands,
+// which are only supported by the cloud deployer.
+func identifyPeeringCluster(helper *CmdHelper, clusterArg string) (*clouddeploy.Deployer, string) {
+	logger := helper.GetLogger()
+	ctx := helper.GetContext()
+
+	_, deployer, cluster := helper.IdentifyCluster(ctx, clusterArg)
+
+	cloudDeployer, ok := deployer.(*clouddeploy.Deployer)
+	if !ok {
+		logger.Fatal("network peering is only supported for cloud deployer",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	return cloudDeployer, cluster.GetID()
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaPeeringCmd)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaProjectsCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Creates a project in the Capella organization",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer := helper.GetCloudDeployer(ctx)
+
+		project, err := deployer.CreateProject(ctx, args[0])
+		if err != nil {
+			logger.Fatal("failed to create project", zap.Error(err))
+		}
+
+		fmt.Printf("%s\n", project.ID)
+	},
+}
+
+func init() {
+	capellaProjectsCmd.AddCommand(capellaProjectsCreateCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var capellaProjectsDeleteCmd = &cobra.Command{
+	Use:   "delete [project]",
+	Short: "Deletes a project of the Capella organization by its id or name",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer := helper.GetCloudDeployer(ctx)
+
+		err := deployer.DeleteProject(ctx, args[0])
+		if err != nil {
+			logger.Fatal("failed to delete project", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	capellaProjectsCmd.AddCommand(capellaProjectsDeleteCmd)
+}
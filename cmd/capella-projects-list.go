@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CapellaProjectsListOutput []CapellaProjectsListOutput_Item
+
+type CapellaProjectsListOutput_Item struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ClusterCount int       `json:"cluster_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	ClusterID    string    `json:"cluster_id,omitempty"`
+}
+
+var capellaProjectsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the projects of the Capella organization",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer := helper.GetCloudDeployer(ctx)
+
+		projects, err := deployer.ListProjects(ctx)
+		if err != nil {
+			logger.Fatal("failed to list projects", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Projects:\n")
+			for _, project := range projects {
+				ownerStr := ""
+				if project.ClusterID != "" {
+					ownerStr = ", Cluster: " + project.ClusterID
+				}
+
+				fmt.Printf("  %s %s [Clusters: %d%s]\n",
+					project.ID,
+					project.Name,
+					project.ClusterCount,
+					ownerStr)
+			}
+		} else {
+			out := CapellaProjectsListOutput{}
+			for _, project := range projects {
+				out = append(out, CapellaProjectsListOutput_Item{
+					ID:           project.ID,
+					Name:         project.Name,
+					ClusterCount: project.ClusterCount,
+					CreatedAt:    project.CreatedAt,
+					ClusterID:    project.ClusterID,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	capellaProjectsCmd.AddCommand(capellaProjectsListCmd)
+}
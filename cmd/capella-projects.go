@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var capellaProjectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Provides tools for the projects of the Capella organization",
+	Run:   nil,
+}
+
+func init() {
+	capellaCmd.AddCommand(capellaProjectsCmd)
+}
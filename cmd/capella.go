@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var capellaCmd = &cobra.Command{
+	Use:   "capella",
+	Short: "Provides Capella specific management tools",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(capellaCmd)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type CertificatesStatusOutput []CertificatesStatusOutput_Node
+
+type CertificatesStatusOutput_Node struct {
+	NodeID    string    `json:"nodeId"`
+	Address   string    `json:"address"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	Expired   bool      `json:"expired"`
+}
+
+var certificatesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Checks the validity of the certificate presented by each node",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("certificate status is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		statuses, err := dockerDeployer.GetNodeCertificates(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get node certificates", zap.Error(err))
+		}
+
+		if !outputJson {
+			for _, status := range statuses {
+				validity := "valid"
+				if status.Expired {
+					validity = "expired"
+				}
+
+				fmt.Printf("%s [%s] %s\n", status.NodeID, status.Address, validity)
+				fmt.Printf("  Subject: %s\n", status.Subject)
+				fmt.Printf("  Issuer: %s\n", status.Issuer)
+				fmt.Printf("  Not After: %s (%s)\n",
+					status.NotAfter.Format(time.RFC3339),
+					time.Until(status.NotAfter).Round(time.Second))
+			}
+		} else {
+			out := CertificatesStatusOutput{}
+			for _, status := range statuses {
+				out = append(out, CertificatesStatusOutput_Node{
+					NodeID:    status.NodeID,
+					Address:   status.Address,
+					Subject:   status.Subject,
+					Issuer:    status.Issuer,
+					NotBefore: status.NotBefore,
+					NotAfter:  status.NotAfter,
+					Expired:   status.Expired,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	certificatesCmd.AddCommand(certificatesStatusCmd)
+}
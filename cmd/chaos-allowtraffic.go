@@ -15,11 +15,13 @@ var chaosAllowTrafficCmd = &cobra.Command{
 		ctx := helper.GetContext()
 
 		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
-		node := helper.IdentifyNode(ctx, cluster, args[1])
+		nodes := helper.IdentifyNodes(ctx, cluster, args[1])
 
-		err := deployer.AllowNodeTraffic(ctx, cluster.GetID(), node.GetID())
-		if err != nil {
-			logger.Fatal("failed to allow node traffic", zap.Error(err))
+		for _, node := range nodes {
+			err := deployer.AllowNodeTraffic(ctx, cluster.GetID(), node.GetID())
+			if err != nil {
+				logger.Fatal("failed to allow node traffic", zap.Error(err))
+			}
 		}
 	},
 }
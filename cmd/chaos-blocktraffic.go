@@ -1,22 +1,70 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
+// trafficPortAliases are the ports of each service which can be blocked by
+// name, including both their plain and TLS ports.
+var trafficPortAliases = map[string][]int{
+	"kv":        {11210, 11207},
+	"mgmt":      {8091, 18091},
+	"query":     {8093, 18093},
+	"index":     {9102, 19102},
+	"search":    {8094, 18094},
+	"analytics": {8095, 18095},
+	"eventing":  {8096, 18096},
+	"cluster":   {21100, 21150},
+}
+
+func parseTrafficPorts(portStrs []string) ([]int, error) {
+	var ports []int
+	for _, portStr := range portStrs {
+		if aliasPorts, ok := trafficPortAliases[strings.ToLower(portStr)]; ok {
+			ports = append(ports, aliasPorts...)
+			continue
+		}
+
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port `%s`", portStr)
+		}
+
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
 var chaosBlockTrafficCmd = &cobra.Command{
 	Use:   "block-traffic [nodes/clients/all]",
 	Short: "Blocks a type of traffic to a specific node",
-	Args:  cobra.MinimumNArgs(2),
+	Long: "Blocks a type of traffic to a specific node.  With --ports, only traffic to and from those " +
+		"ports is blocked, for example `--ports kv` makes the data service unreachable while leaving " +
+		"management reachable, and `--ports cluster` only blocks inter-node cluster traffic.  Ports " +
+		"are numbers or one of the service names: kv, mgmt, query, index, search, analytics, " +
+		"eventing or cluster.",
+	Args: cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		helper := CmdHelper{}
 		logger := helper.GetLogger()
 		ctx := helper.GetContext()
 
+		portStrs, _ := cmd.Flags().GetStringSlice("ports")
+
+		ports, err := parseTrafficPorts(portStrs)
+		if err != nil {
+			logger.Fatal("failed to parse ports", zap.Error(err))
+		}
+
 		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
-		node := helper.IdentifyNode(ctx, cluster, args[1])
+		nodes := helper.IdentifyNodes(ctx, cluster, args[1])
 
 		blockType := deployment.BlockNodeTrafficNodes
 		if len(args) > 2 {
@@ -33,13 +81,32 @@ var chaosBlockTrafficCmd = &cobra.Command{
 			}
 		}
 
-		err := deployer.BlockNodeTraffic(ctx, cluster.GetID(), node.GetID(), blockType)
-		if err != nil {
-			logger.Fatal("failed to block node traffic", zap.Error(err))
+		var dockerDeployer *dockerdeploy.Deployer
+		if len(ports) > 0 {
+			var ok bool
+			dockerDeployer, ok = deployer.(*dockerdeploy.Deployer)
+			if !ok {
+				logger.Fatal("blocking specific ports is only supported for docker clusters",
+					zap.Error(deployment.ErrFeatureUnsupported))
+			}
+		}
+
+		for _, node := range nodes {
+			var err error
+			if dockerDeployer != nil {
+				err = dockerDeployer.BlockNodePortTraffic(ctx, cluster.GetID(), node.GetID(), blockType, ports)
+			} else {
+				err = deployer.BlockNodeTraffic(ctx, cluster.GetID(), node.GetID(), blockType)
+			}
+			if err != nil {
+				logger.Fatal("failed to block node traffic", zap.Error(err))
+			}
 		}
 	},
 }
 
 func init() {
 	chaosCmd.AddCommand(chaosBlockTrafficCmd)
+
+	chaosBlockTrafficCmd.Flags().StringSlice("ports", nil, "Only blocks traffic of these ports or services (docker only)")
 }
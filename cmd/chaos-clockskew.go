@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var chaosClockSkewCmd = &cobra.Command{
+	Use:   "clock-skew [cluster] [node] [offset]",
+	Short: "Shifts the clock of a specific node by an offset (e.g. 5m, -90s, 0 to reset)",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		nodes := helper.IdentifyNodes(ctx, cluster, args[1])
+
+		skew, err := time.ParseDuration(args[2])
+		if err != nil {
+			logger.Fatal("failed to parse clock offset", zap.Error(err))
+		}
+
+		for _, node := range nodes {
+			err := deployer.SetNodeClockSkew(ctx, cluster.GetID(), node.GetID(), skew)
+			if err != nil {
+				logger.Fatal("failed to set node clock skew", zap.Error(err))
+			}
+		}
+	},
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosClockSkewCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var chaosKillServiceCmd = &cobra.Command{
+	Use:   "kill-service [cluster] [node] [process]",
+	Short: "Kills a service process within a node (e.g. memcached, indexer, cbq-engine, cbft)",
+	Long: "Kills a service process within a node, leaving the rest of the node running.  The process " +
+		"is normally restarted by the node shortly afterwards, with --hold-down it is killed again " +
+		"every time it restarts until the duration has elapsed.  Processes can also be identified " +
+		"by their service: kv, index, query, search or eventing.",
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		holdDown, _ := cmd.Flags().GetDuration("hold-down")
+
+		processName, err := dockerdeploy.ServiceProcessName(args[2])
+		if err != nil {
+			logger.Fatal("failed to identify service process", zap.Error(err))
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		nodes := helper.IdentifyNodes(ctx, cluster, args[1])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("killing service processes is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		for _, node := range nodes {
+			err := dockerDeployer.KillNodeServiceProcess(ctx, cluster.GetID(), node.GetID(), processName, holdDown)
+			if err != nil {
+				logger.Fatal("failed to kill service process", zap.Error(err))
+			}
+		}
+	},
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosKillServiceCmd)
+
+	chaosKillServiceCmd.Flags().Duration("hold-down", 0, "Keeps killing the process when it restarts for this long")
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/faultproxy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var chaosProxyCmd = &cobra.Command{
+	Use:   "proxy [cluster] [node]",
+	Short: "Runs fault-injecting proxies in front of a nodes service ports",
+	Long: "Runs a TCP proxy for each of the service ports of a node. " +
+		"Faults (delays, drops or resets) can then be injected per-port at runtime " +
+		"using the proxy-fault command, without requiring any privileges on the node.\n" +
+		"While running, the proxy ports are registered as the external alternate " +
+		"addresses of the node, and any other nodes without alternate addresses are " +
+		"given their own, so that SDKs which bootstrap through the proxy using " +
+		"network=external send all of their traffic for the node through it.  The " +
+		"alternate addresses the nodes had before are restored when the proxy exits.",
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		portOffset, _ := cmd.Flags().GetInt("port-offset")
+		bindAddress, _ := cmd.Flags().GetString("bind-address")
+		advertiseAddress, _ := cmd.Flags().GetString("advertise-address")
+		controlAddress, _ := cmd.Flags().GetString("control-address")
+
+		if advertiseAddress == "" {
+			bindIP := net.ParseIP(bindAddress)
+			if bindIP != nil && bindIP.IsUnspecified() {
+				logger.Fatal("an advertise address must be specified when binding to all interfaces")
+			}
+			advertiseAddress = bindAddress
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		node := helper.IdentifyNode(ctx, cluster, args[1])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("fault proxies are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		targetAddrs, err := dockerDeployer.NodeServiceAddresses(ctx, cluster.GetID(), node.GetID())
+		if err != nil {
+			logger.Fatal("failed to get node addresses", zap.Error(err))
+		}
+
+		control := &faultproxy.ControlServer{}
+		var proxies []*faultproxy.Proxy
+		proxyPorts := make(map[string]int)
+		for portName, port := range clustercontrol.AlternateAddressPorts {
+			proxy := faultproxy.NewProxy(&faultproxy.ProxyOptions{
+				Logger:        logger,
+				ListenAddress: net.JoinHostPort(bindAddress, strconv.Itoa(port+portOffset)),
+				TargetAddress: targetAddrs[port],
+			})
+
+			err := proxy.Listen()
+			if err != nil {
+				logger.Fatal("failed to start proxy",
+					zap.Int("port", port),
+					zap.Error(err))
+			}
+
+			control.AddProxy(strconv.Itoa(port), proxy)
+			proxies = append(proxies, proxy)
+			proxyPorts[portName] = port + portOffset
+		}
+
+		controlListener, err := net.Listen("tcp", controlAddress)
+		if err != nil {
+			logger.Fatal("failed to start control server", zap.Error(err))
+		}
+
+		restoreAltAddrs, err := dockerDeployer.OverrideAlternateAddresses(ctx, cluster.GetID(),
+			map[string]*clustercontrol.SetupAlternateAddressesOptions{
+				node.GetID(): {
+					Hostname: advertiseAddress,
+					Ports:    proxyPorts,
+				},
+			})
+		if err != nil {
+			logger.Fatal("failed to setup alternate addresses", zap.Error(err))
+		}
+
+		fmt.Printf("Control server listening on %s\n", controlListener.Addr().String())
+		for _, proxyInfo := range control.ListProxies() {
+			fmt.Printf("  %s -> %s\n", proxyInfo.ListenAddress, proxyInfo.TargetAddress)
+		}
+		fmt.Printf("Connect SDKs using couchbase://%s?network=external\n",
+			net.JoinHostPort(advertiseAddress, strconv.Itoa(proxyPorts["kv"])))
+
+		// a failure of any proxy stops all of them, so that the alternate
+		// addresses are always restored before we exit
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		controlServer := &http.Server{Handler: control}
+		go func() {
+			<-runCtx.Done()
+			controlServer.Close()
+		}()
+
+		var wg sync.WaitGroup
+		proxyErrs := make(chan error, len(proxies))
+		for _, proxy := range proxies {
+			wg.Add(1)
+			go func(proxy *faultproxy.Proxy) {
+				defer wg.Done()
+
+				err := proxy.Run(runCtx)
+				if err != nil {
+					proxyErrs <- err
+					cancel()
+				}
+			}(proxy)
+		}
+
+		serveErr := controlServer.Serve(controlListener)
+		if serveErr == http.ErrServerClosed {
+			serveErr = nil
+		}
+		cancel()
+		wg.Wait()
+		close(proxyErrs)
+
+		// the command context has usually been cancelled by the time we get here
+		restoreCtx, restoreCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer restoreCancel()
+		err = restoreAltAddrs(restoreCtx)
+		if err != nil {
+			logger.Warn("failed to restore alternate addresses", zap.Error(err))
+		}
+
+		if serveErr != nil {
+			logger.Fatal("control server failed", zap.Error(serveErr))
+		}
+		for err := range proxyErrs {
+			logger.Fatal("proxy failed", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosProxyCmd)
+
+	chaosProxyCmd.Flags().Int("port-offset", 20000, "The offset added to each service port to form the proxy port")
+	chaosProxyCmd.Flags().String("bind-address", "127.0.0.1", "The address the proxies listen on")
+	chaosProxyCmd.Flags().String("advertise-address", "", "The address SDKs reach the proxies on, defaults to the bind address")
+	chaosProxyCmd.Flags().String("control-address", "127.0.0.1:19999", "The address the fault control server listens on")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/faultproxy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var chaosProxyFaultCmd = &cobra.Command{
+	Use:     "proxy-fault [port] [none/delay/drop/reset]",
+	Short:   "Sets the fault applied by a running chaos proxy",
+	Example: "proxy-fault 11210 delay --delay 500ms",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		delay, _ := cmd.Flags().GetDuration("delay")
+		controlAddress, _ := cmd.Flags().GetString("control-address")
+
+		fault := faultproxy.Fault{
+			Type: faultproxy.FaultType(args[1]),
+		}
+		if fault.Type == faultproxy.FaultTypeDelay {
+			fault.Delay = delay
+		}
+		err := fault.Validate()
+		if err != nil {
+			logger.Fatal("invalid fault", zap.Error(err))
+		}
+
+		faultBytes, _ := json.Marshal(fault)
+
+		reqUri := fmt.Sprintf("http://%s/proxies/%s", controlAddress, args[0])
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, reqUri, bytes.NewReader(faultBytes))
+		if err != nil {
+			logger.Fatal("failed to create request", zap.Error(err))
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			logger.Fatal("failed to contact proxy control server", zap.Error(err))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			logger.Fatal("failed to set proxy fault",
+				zap.Int("status", resp.StatusCode),
+				zap.String("body", string(bytes.TrimSpace(respBody))))
+		}
+	},
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosProxyFaultCmd)
+
+	chaosProxyFaultCmd.Flags().Duration("delay", 100*time.Millisecond, "The delay to apply for delay faults")
+	chaosProxyFaultCmd.Flags().String("control-address", "127.0.0.1:19999", "The address of the proxy control server")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var chaosReleaseServiceCmd = &cobra.Command{
+	Use:   "release-service [cluster] [node] [process]",
+	Short: "Ends the hold down of a service process started by kill-service, allowing it to restart",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		processName, err := dockerdeploy.ServiceProcessName(args[2])
+		if err != nil {
+			logger.Fatal("failed to identify service process", zap.Error(err))
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		nodes := helper.IdentifyNodes(ctx, cluster, args[1])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("releasing service processes is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		for _, node := range nodes {
+			err := dockerDeployer.ReleaseNodeServiceProcess(ctx, cluster.GetID(), node.GetID(), processName)
+			if err != nil {
+				logger.Fatal("failed to release service process", zap.Error(err))
+			}
+		}
+	},
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosReleaseServiceCmd)
+}
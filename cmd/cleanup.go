@@ -2,9 +2,15 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
 	"github.com/couchbaselabs/cbdinocluster/utils/awscontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/azurecontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/resourcetags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
@@ -23,6 +29,21 @@ var cleanupCmd = &cobra.Command{
 		ctx := helper.GetContext()
 		config := helper.GetConfig(ctx)
 
+		outputJson, _ := cmd.Flags().GetBool("json")
+		allOwners, _ := cmd.Flags().GetBool("all-owners")
+
+		report := &deployment.CleanupReport{}
+		writeReport := func() {
+			if outputJson {
+				err := deployment.WriteCleanupReport(os.Stdout, deployment.CleanupReportFormatJson, report.Actions(), time.Now())
+				if err != nil {
+					logger.Warn("failed to output cleanup report", zap.Error(err))
+				}
+			}
+
+			writeCleanupReportFile(cmd, &helper, report.Actions())
+		}
+
 		cleaners := make(map[string]cleanableTarget)
 
 		// put all the registered deployers into the cleaners list
@@ -31,6 +52,16 @@ var cleanupCmd = &cobra.Command{
 			cleaners[deployerName] = deployer
 		}
 
+		// the private endpoint resources are only removed on expiry once the
+		// cloud cluster they were created for is gone, as its expiry may have
+		// been extended after they were tagged.
+		var clusterExists resourcetags.ClusterExistsFunc
+		if cloudDeployer := deployers["cloud"]; cloudDeployer != nil {
+			clusterExists = func(ctx context.Context, clusterID string) (bool, error) {
+				return cloudClusterExists(ctx, cloudDeployer, clusterID)
+			}
+		}
+
 		// add special AWS target for private links
 		// removable if we add an actual AWS deployer
 		if deployers["aws"] != nil {
@@ -42,6 +73,9 @@ var cleanupCmd = &cobra.Command{
 				Logger:      logger,
 				Region:      config.AWS.Region,
 				Credentials: awsCreds,
+				GracePeriod: config.ExpiryGracePeriod,
+
+				ClusterExists: clusterExists,
 			}
 
 			cleaners["aws"] = peCtrl
@@ -55,11 +89,14 @@ var cleanupCmd = &cobra.Command{
 		if config.Azure.Enabled.Value() {
 			azureCreds := helper.GetAzureCredentials(ctx)
 			peCtrl := &azurecontrol.PrivateEndpointsController{
-				Logger: logger,
-				Region: config.Azure.Region,
-				Creds:  azureCreds,
-				SubID:  config.Azure.SubID,
-				RgName: config.Azure.RGName,
+				Logger:      logger,
+				Region:      config.Azure.Region,
+				Creds:       azureCreds,
+				SubID:       config.Azure.SubID,
+				RgName:      config.Azure.RGName,
+				GracePeriod: config.ExpiryGracePeriod,
+
+				ClusterExists: clusterExists,
 			}
 
 			cleaners["azure"] = peCtrl
@@ -96,20 +133,260 @@ var cleanupCmd = &cobra.Command{
 		logger.Info("identified cleaners and order",
 			zap.Strings("cleaners", finalCleanupOrder))
 
+		cleanupInterruptedClusters(deployment.WithCleanupReport(ctx, report, "interrupted"),
+			&helper, deployers, finalCleanupOrder)
+
 		for _, cleanerName := range finalCleanupOrder {
 			cleaner := cleaners[cleanerName]
 
+			// only the deployers understand dry-run, so we skip the
+			// special private endpoint cleaners entirely in that case.
+			if helper.IsDryRun() && deployers[cleanerName] == nil {
+				logger.Info("dry-run: skipping cleaner",
+					zap.String("cleaner", cleanerName))
+				continue
+			}
+
 			logger.Info("running cleanup",
 				zap.String("cleaner", cleanerName))
 
-			err := cleaner.Cleanup(ctx)
+			err := cleaner.Cleanup(deployment.WithCleanupReport(ctx, report, cleanerName))
 			if err != nil {
+				// the report is still written, so that what was removed
+				// prior to the failure is not lost
+				writeReport()
 				logger.Fatal("failed to cleanup resources", zap.Error(err))
 			}
 		}
+
+		if allOwners {
+			cleanupRegisteredClusters(deployment.WithCleanupReport(ctx, report, "registry"), &helper, deployers)
+		}
+
+		writeReport()
 	},
 }
 
+// cleanupInterruptedClusters retries removing the clusters which interrupted
+// commands failed to remove, for the deployers being cleaned up.
+func cleanupInterruptedClusters(
+	ctx context.Context,
+	helper *CmdHelper,
+	deployers map[string]deployment.Deployer,
+	cleanerNames []string,
+) {
+	logger := helper.GetLogger()
+
+	entries, err := helper.LoadInterruptedClusters()
+	if err != nil {
+		logger.Warn("failed to load clusters left by interrupted commands", zap.Error(err))
+		return
+	}
+
+	var remaining []*InterruptedCluster
+	for _, entry := range entries {
+		deployer := deployers[entry.Deployer]
+		if deployer == nil || !slices.Contains(cleanerNames, entry.Deployer) {
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		action := &deployment.CleanupAction{
+			Source:    entry.Deployer,
+			Type:      "cluster",
+			ID:        entry.ClusterID,
+			ClusterID: entry.ClusterID,
+			Reason:    "left by interrupted command",
+			DryRun:    helper.IsDryRun(),
+		}
+
+		if helper.IsDryRun() {
+			logger.Info("dry-run: skipping removing cluster left by interrupted command",
+				zap.String("cluster", entry.ClusterID))
+			deployment.RecordCleanupAction(ctx, action)
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		logger.Info("removing cluster left by interrupted command",
+			zap.String("cluster", entry.ClusterID),
+			zap.String("command", entry.Command))
+
+		err := deployer.RemoveCluster(ctx, entry.ClusterID)
+		if err != nil && !errors.Is(err, deployment.ErrClusterNotFound) {
+			logger.Warn("failed to remove cluster left by interrupted command",
+				zap.String("cluster", entry.ClusterID),
+				zap.Error(err))
+			action.Error = err.Error()
+			deployment.RecordCleanupAction(ctx, action)
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		deployment.RecordCleanupAction(ctx, action)
+		helper.UnregisterCluster(ctx, entry.ClusterID)
+	}
+
+	if len(remaining) == len(entries) {
+		return
+	}
+
+	err = helper.SaveInterruptedClusters(remaining)
+	if err != nil {
+		logger.Warn("failed to save clusters left by interrupted commands", zap.Error(err))
+	}
+}
+
+// cleanupRegisteredClusters removes expired clusters of any owner which are
+// reachable by our deployers, such as Capella clusters, and then removes
+// registry entries for clusters which no longer exist.
+func cleanupRegisteredClusters(ctx context.Context, helper *CmdHelper, deployers map[string]deployment.Deployer) {
+	logger := helper.GetLogger()
+	config := helper.GetConfig(ctx)
+
+	registry := helper.GetRegistry(ctx)
+	if registry == nil {
+		logger.Fatal("cleaning up clusters of all owners requires a shared registry to be configured")
+	}
+
+	entries, err := registry.List(ctx)
+	if err != nil {
+		logger.Fatal("failed to list shared registry", zap.Error(err))
+	}
+
+	// entries of deployers whose clusters could not be listed are skipped,
+	// since we cannot tell whether those clusters still exist.
+	visibleClusters := make(map[string]map[string]bool)
+	for deployerName, deployer := range deployers {
+		clusters, err := deployer.ListClusters(ctx)
+		if err != nil {
+			logger.Warn("failed to list clusters",
+				zap.String("deployer", deployerName),
+				zap.Error(err))
+			continue
+		}
+
+		clusterIDs := make(map[string]bool)
+		for _, cluster := range clusters {
+			clusterIDs[cluster.GetID()] = true
+		}
+		visibleClusters[deployerName] = clusterIDs
+	}
+
+	owner := helper.GetRegistryOwner(ctx)
+	now := time.Now()
+	for _, entry := range entries {
+		isExpired := entry.IsExpired(now, config.ExpiryGracePeriod)
+		deployerClusters, canList := visibleClusters[entry.Deployer]
+
+		newEntryAction := func(actionType string, reason string) *deployment.CleanupAction {
+			return &deployment.CleanupAction{
+				Type:      actionType,
+				ID:        entry.ClusterID,
+				ClusterID: entry.ClusterID,
+				Owner:     entry.Owner,
+				CreatedAt: entry.CreatedAt,
+				Expiry:    entry.Expiry,
+				Reason:    reason,
+				DryRun:    helper.IsDryRun(),
+			}
+		}
+
+		unregisterReason := ""
+		switch {
+		case canList && deployerClusters[entry.ClusterID]:
+			if !isExpired {
+				continue
+			}
+
+			action := newEntryAction("cluster", "expired")
+			action.Source = entry.Deployer
+
+			if helper.IsDryRun() {
+				logger.Info("dry-run: skipping removing expired cluster",
+					zap.String("cluster", entry.ClusterID),
+					zap.String("owner", entry.Owner))
+				deployment.RecordCleanupAction(ctx, action)
+				continue
+			}
+
+			logger.Info("removing expired cluster",
+				zap.String("cluster", entry.ClusterID),
+				zap.String("owner", entry.Owner))
+
+			err := deployers[entry.Deployer].RemoveCluster(ctx, entry.ClusterID)
+			if err != nil {
+				logger.Warn("failed to remove expired cluster",
+					zap.String("cluster", entry.ClusterID),
+					zap.Error(err))
+				action.Error = err.Error()
+				deployment.RecordCleanupAction(ctx, action)
+				continue
+			}
+
+			deployment.RecordCleanupAction(ctx, action)
+			unregisterReason = "cluster was removed"
+		case canList && entry.Owner == owner:
+			// our own cluster was removed without being unregistered
+			unregisterReason = "cluster no longer exists"
+		case isExpired:
+			// the cluster belongs to a machine we cannot reach, but its
+			// owner should have removed it by now
+			unregisterReason = "expired on an unreachable machine"
+		default:
+			continue
+		}
+
+		action := newEntryAction("registry-entry", unregisterReason)
+
+		if helper.IsDryRun() {
+			logger.Info("dry-run: skipping unregistering cluster",
+				zap.String("cluster", entry.ClusterID),
+				zap.String("owner", entry.Owner))
+			deployment.RecordCleanupAction(ctx, action)
+			continue
+		}
+
+		logger.Info("unregistering cluster",
+			zap.String("cluster", entry.ClusterID),
+			zap.String("owner", entry.Owner))
+
+		err := registry.Unregister(ctx, entry.ClusterID)
+		if err != nil {
+			logger.Warn("failed to unregister cluster",
+				zap.String("cluster", entry.ClusterID),
+				zap.Error(err))
+			action.Error = err.Error()
+		}
+		deployment.RecordCleanupAction(ctx, action)
+	}
+}
+
+// cloudClusterExists checks for a cloud cluster by either its cbdc id or its
+// Capella cluster id, as private endpoint resources are tagged with either.
+func cloudClusterExists(ctx context.Context, deployer deployment.Deployer, clusterID string) (bool, error) {
+	clusters, err := deployer.ListClusters(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list cloud clusters")
+	}
+
+	for _, cluster := range clusters {
+		if cluster.GetID() == clusterID {
+			return true, nil
+		}
+
+		cloudCluster, ok := cluster.(*clouddeploy.ClusterInfo)
+		if ok && cloudCluster.CloudClusterID == clusterID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func init() {
 	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().Bool("all-owners", false, "Also cleanup expired clusters of other owners using the shared registry")
+	addCleanupReportFlags(cleanupCmd)
 }
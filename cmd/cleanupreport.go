@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func addCleanupReportFlags(cmd *cobra.Command) {
+	cmd.Flags().String("report-file", "", "Writes a report of the removed resources to this file")
+	cmd.Flags().String("report-format", "", "The format of the report file (json, csv, junit), defaulting to the file extension")
+}
+
+// cleanupReportFormatForPath picks the report format from the extension of
+// the report file, defaulting to json.
+func cleanupReportFormatForPath(path string) deployment.CleanupReportFormat {
+	switch filepath.Ext(path) {
+	case ".csv":
+		return deployment.CleanupReportFormatCsv
+	case ".xml":
+		return deployment.CleanupReportFormatJUnit
+	}
+	return deployment.CleanupReportFormatJson
+}
+
+// writeCleanupReportFile writes the report requested by the report flags of
+// a command, if one was requested.
+func writeCleanupReportFile(cmd *cobra.Command, helper *CmdHelper, actions []*deployment.CleanupAction) {
+	logger := helper.GetLogger()
+
+	reportFile, _ := cmd.Flags().GetString("report-file")
+	reportFormat, _ := cmd.Flags().GetString("report-format")
+
+	if reportFile == "" {
+		return
+	}
+
+	format := deployment.CleanupReportFormat(reportFormat)
+	if format == "" {
+		format = cleanupReportFormatForPath(reportFile)
+	}
+
+	file, err := os.Create(reportFile)
+	if err != nil {
+		logger.Fatal("failed to create report file", zap.Error(err))
+	}
+	defer file.Close()
+
+	err = deployment.WriteCleanupReport(file, format, actions, time.Now())
+	if err != nil {
+		logger.Fatal("failed to write report", zap.Error(err))
+	}
+
+	logger.Info("wrote cleanup report",
+		zap.String("path", reportFile),
+		zap.String("format", string(format)))
+}
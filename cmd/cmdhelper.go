@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
@@ -22,9 +26,17 @@ import (
 	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
 	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/cioutput"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/clusterlock"
+	"github.com/couchbaselabs/cbdinocluster/utils/clusterregistry"
+	"github.com/couchbaselabs/cbdinocluster/utils/dockerhost"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/couchbaselabs/cbdinocluster/utils/versionlock"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/exp/maps"
 )
 
@@ -32,35 +44,259 @@ type CmdHelper struct {
 	logger *zap.Logger
 
 	config *cbdcconfig.Config
+
+	capellaLimiter *capellacontrol.RateLimiter
 }
 
+// GetContext returns the context for commands to use, which is cancelled
+// once we are interrupted.
 func (h *CmdHelper) GetContext() context.Context {
-	return context.Background()
+	return getInterruptContext(h.GetLogger())
 }
 
 func (h *CmdHelper) GetLogger() *zap.Logger {
 	if h.logger == nil {
 		verbose, _ := rootCmd.Flags().GetBool("verbose")
+		verboseHttp := h.IsVerboseHttp()
 
 		logConfig := zap.NewDevelopmentConfig()
-		if !verbose {
+		if h.IsQuiet() {
+			// only failures are logged, so that the output is just the result
+			logConfig.Level.SetLevel(zap.ErrorLevel)
+			logConfig.DisableCaller = true
+		} else if !verbose && !verboseHttp {
 			logConfig.Level.SetLevel(zap.InfoLevel)
 			logConfig.DisableCaller = true
 		}
 
-		logger, err := logConfig.Build()
+		// logs are written through the progress writer, so that they do not
+		// collide with any progress being shown on the terminal
+		logger, err := logConfig.Build(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return zapcore.NewCore(
+				zapcore.NewConsoleEncoder(logConfig.EncoderConfig),
+				progress.Stderr,
+				logConfig.Level)
+		}), zap.WithFatalHook(exitCodeFatalHook{}))
 		if err != nil {
 			log.Fatalf("failed to initialize verbose logger: %s", err)
 		}
 
 		logger.Info("logger initialized")
 
+		if verboseHttp {
+			clustercontrol.EnableHttpLogging(logger.Named("http"))
+		}
+
 		h.logger = logger
 	}
 
 	return h.logger
 }
 
+// IsQuiet indicates that only the primary result of a command should be
+// output, along with any failures.
+func (h *CmdHelper) IsQuiet() bool {
+	quiet, _ := rootCmd.Flags().GetBool("quiet")
+	return quiet
+}
+
+func (h *CmdHelper) IsVerboseHttp() bool {
+	verboseHttp, _ := rootCmd.Flags().GetBool("verbose-http")
+	return verboseHttp
+}
+
+// capellaCacheTTL is how long Capella lookups are cached for between
+// invocations.  This is kept short since changes made by other clients
+// are not observed until the cached lookups expire.
+const capellaCacheTTL = 30 * time.Second
+
+func (h *CmdHelper) getCapellaCache() *capellacontrol.ResponseCache {
+	logger := h.GetLogger()
+
+	noCache, _ := rootCmd.Flags().GetBool("no-cache")
+	if noCache {
+		return nil
+	}
+
+	cacheBasePath, err := os.UserCacheDir()
+	if err != nil {
+		logger.Debug("failed to find user cache path, disabling cache", zap.Error(err))
+		return nil
+	}
+
+	return &capellacontrol.ResponseCache{
+		Path: filepath.Join(cacheBasePath, "cbdinocluster", "capella"),
+		TTL:  capellaCacheTTL,
+	}
+}
+
+// getClusterLocker returns the locker used to prevent concurrent invocations
+// from modifying the same cluster at the same time.
+func (h *CmdHelper) getClusterLocker() *clusterlock.Locker {
+	logger := h.GetLogger()
+
+	noLock, _ := rootCmd.Flags().GetBool("no-lock")
+	if noLock {
+		return nil
+	}
+
+	cacheBasePath, err := os.UserCacheDir()
+	if err != nil {
+		logger.Debug("failed to find user cache path, disabling cluster locking", zap.Error(err))
+		return nil
+	}
+
+	locker, err := clusterlock.NewLocker(&clusterlock.LockerOptions{
+		Logger: logger,
+		Path:   filepath.Join(cacheBasePath, "cbdinocluster", "locks"),
+	})
+	if err != nil {
+		logger.Debug("failed to create cluster locker, disabling cluster locking", zap.Error(err))
+		return nil
+	}
+
+	return locker
+}
+
+// getCapellaRateLimiter returns the rate limiter shared by every Capella
+// controller created by this command.
+func (h *CmdHelper) getCapellaRateLimiter(ctx context.Context) *capellacontrol.RateLimiter {
+	if h.capellaLimiter == nil {
+		config := h.GetConfig(ctx)
+
+		limiterOpts := &capellacontrol.RateLimiterOptions{
+			RequestsPerSecond: capellacontrol.DefaultRequestsPerSecond,
+			Burst:             capellacontrol.DefaultRequestBurst,
+			MaxConcurrent:     capellacontrol.DefaultMaxConcurrentRequests,
+		}
+		if config.Capella.RequestsPerSecond != 0 {
+			limiterOpts.RequestsPerSecond = max(config.Capella.RequestsPerSecond, 0)
+		}
+		if config.Capella.RequestBurst != 0 {
+			limiterOpts.Burst = max(config.Capella.RequestBurst, 0)
+		}
+		if config.Capella.MaxConcurrentRequests != 0 {
+			limiterOpts.MaxConcurrent = max(config.Capella.MaxConcurrentRequests, 0)
+		}
+
+		h.capellaLimiter = capellacontrol.NewRateLimiter(limiterOpts)
+	}
+
+	return h.capellaLimiter
+}
+
+// StartProgress starts reporting the progress of a long running operation,
+// returning a context which the deployer reports steps through and a
+// function to call once the operation has completed.  The history key
+// identifies the kind of operation, and how long it previously took is
+// used to estimate the time remaining.
+func (h *CmdHelper) StartProgress(ctx context.Context, title string, historyKey string) (context.Context, func(success bool)) {
+	logger := h.GetLogger()
+
+	if h.IsQuiet() {
+		return ctx, func(success bool) {}
+	}
+
+	var history *progress.History
+	cacheBasePath, err := os.UserCacheDir()
+	if err != nil {
+		logger.Debug("failed to find user cache path, disabling progress history", zap.Error(err))
+	} else {
+		history = &progress.History{
+			Path: filepath.Join(cacheBasePath, "cbdinocluster", "progress.json"),
+		}
+	}
+
+	var expected time.Duration
+	if history != nil {
+		expected = history.Expected(historyKey)
+	}
+
+	reporter := progress.NewReporter(&progress.ReporterOptions{
+		Logger:   logger,
+		Writer:   progress.Stderr,
+		Title:    title,
+		Expected: expected,
+	})
+
+	return progress.WithReporter(ctx, reporter), func(success bool) {
+		duration := reporter.Finish()
+		if success && history != nil {
+			err := history.Record(historyKey, duration)
+			if err != nil {
+				logger.Debug("failed to record progress history", zap.Error(err))
+			}
+		}
+	}
+}
+
+// GetCIOutput returns the writer for CI outputs, or nil if CI outputs were
+// not requested.
+func (h *CmdHelper) GetCIOutput() *cioutput.Writer {
+	logger := h.GetLogger()
+
+	ciMode, _ := rootCmd.Flags().GetString("ci-output")
+	ciOutputFile, _ := rootCmd.Flags().GetString("ci-output-file")
+
+	if ciMode == "" {
+		return nil
+	}
+
+	writer, err := cioutput.NewWriter(&cioutput.WriterOptions{
+		Mode:       cioutput.Mode(ciMode),
+		OutputPath: ciOutputFile,
+	})
+	if err != nil {
+		logger.Fatal("failed to setup ci output", zap.Error(err))
+	}
+
+	return writer
+}
+
+// SetCIOutputs writes outputs to the CI system, if CI outputs were requested.
+func (h *CmdHelper) SetCIOutputs(ciOutput *cioutput.Writer, outputs []cioutput.Output) {
+	logger := h.GetLogger()
+
+	if ciOutput == nil {
+		return
+	}
+
+	err := ciOutput.SetOutputs(outputs)
+	if err != nil {
+		logger.Fatal("failed to write ci outputs", zap.Error(err))
+	}
+}
+
+func (h *CmdHelper) IsDryRun() bool {
+	dryRun, _ := rootCmd.Flags().GetBool("dry-run")
+	return dryRun
+}
+
+// GetCapellaEnvironment returns the Capella control plane to use, which is
+// selected by the --capella-env flag or otherwise by the config.
+func (h *CmdHelper) GetCapellaEnvironment(ctx context.Context) *cbdcconfig.CapellaEnvironment {
+	logger := h.GetLogger()
+
+	capellaEnv, err := h.getCapellaEnvironment(ctx)
+	if err != nil {
+		logger.Fatal("failed to select capella environment", zap.Error(err))
+	}
+
+	return capellaEnv
+}
+
+func (h *CmdHelper) getCapellaEnvironment(ctx context.Context) (*cbdcconfig.CapellaEnvironment, error) {
+	config := h.GetConfig(ctx)
+
+	envName, _ := rootCmd.Flags().GetString("capella-env")
+	return config.Capella.ResolveEnvironment(envName)
+}
+
+func (h *CmdHelper) GetDiagnosticsPath() string {
+	diagnosticsPath, _ := rootCmd.Flags().GetString("diagnostics-dir")
+	return diagnosticsPath
+}
+
 func (h *CmdHelper) GetConfig(ctx context.Context) *cbdcconfig.Config {
 	logger := h.GetLogger()
 
@@ -82,6 +318,174 @@ func (h *CmdHelper) GetConfig(ctx context.Context) *cbdcconfig.Config {
 	return h.config
 }
 
+// GetEffectiveDefaults merges the org, config and profile defaults which
+// apply to new clusters allocated with the named profile.
+func (h *CmdHelper) GetEffectiveDefaults(ctx context.Context, profileName string) *cbdcconfig.EffectiveDefaults {
+	logger := h.GetLogger()
+	config := h.GetConfig(ctx)
+
+	layers, err := config.DefaultsLayers(profileName)
+	if err != nil {
+		logger.Fatal("failed to load defaults", zap.Error(err))
+	}
+
+	return cbdcconfig.ResolveDefaults(layers)
+}
+
+// GetVersionsLockPath returns the path of the versions lock file, which is
+// empty if there is no lock file to use.
+func (h *CmdHelper) GetVersionsLockPath(ctx context.Context) string {
+	logger := h.GetLogger()
+	config := h.GetConfig(ctx)
+
+	if config.VersionsLock != "" {
+		return config.VersionsLock
+	}
+
+	lockPath, err := versionlock.Find(".")
+	if err != nil {
+		logger.Fatal("failed to find versions lock", zap.Error(err))
+	}
+
+	return lockPath
+}
+
+// ResolveVersionNames replaces the versions of a definition which are names
+// pinned in the versions lock file with the exact versions they refer to.
+func (h *CmdHelper) ResolveVersionNames(ctx context.Context, def *clusterdef.Cluster) {
+	logger := h.GetLogger()
+
+	lockPath := h.GetVersionsLockPath(ctx)
+	if lockPath == "" {
+		return
+	}
+
+	lock, err := versionlock.Load(lockPath)
+	if err != nil {
+		logger.Fatal("failed to load versions lock", zap.Error(err))
+	}
+
+	for _, nodeGrp := range def.NodeGroups {
+		resolved := lock.Resolve(nodeGrp.Version)
+		if resolved != nodeGrp.Version {
+			logger.Info("resolved pinned version",
+				zap.String("name", nodeGrp.Version),
+				zap.String("version", resolved),
+				zap.String("lock", lockPath))
+			nodeGrp.Version = resolved
+		}
+	}
+}
+
+func (h *CmdHelper) getExpiringHook(ctx context.Context) deployment.ExpiringHook {
+	logger := h.GetLogger()
+	config := h.GetConfig(ctx)
+
+	if config.ExpiryHook == "" {
+		return nil
+	}
+
+	// the cloud and k8s deployers notify on every cleanup, so which clusters
+	// were already notified is recorded here
+	var notifications *deployment.ExpiryNotifications
+	cacheBasePath, err := os.UserCacheDir()
+	if err != nil {
+		logger.Debug("failed to find user cache path, expiry hook may run repeatedly", zap.Error(err))
+	} else {
+		notifications = &deployment.ExpiryNotifications{
+			Path:        filepath.Join(cacheBasePath, "cbdinocluster", "expiry-notified.json"),
+			GracePeriod: config.ExpiryGracePeriod,
+		}
+	}
+
+	return func(ctx context.Context, clusterID string, expiry time.Time) {
+		if notifications != nil {
+			marked, err := notifications.Mark(clusterID, expiry, time.Now())
+			if err != nil {
+				logger.Warn("failed to record expiry notification, skipping expiry hook",
+					zap.String("cluster", clusterID),
+					zap.Error(err))
+				return
+			}
+			if !marked {
+				return
+			}
+		}
+
+		hookCmd := exec.CommandContext(ctx, "sh", "-c", config.ExpiryHook)
+		hookCmd.Env = append(os.Environ(),
+			"CBDC_CLUSTER_ID="+clusterID,
+			"CBDC_CLUSTER_EXPIRY="+expiry.Format(time.RFC3339))
+		hookCmd.Stdout = os.Stderr
+		hookCmd.Stderr = os.Stderr
+
+		err := hookCmd.Run()
+		if err != nil {
+			logger.Warn("expiry hook failed",
+				zap.String("cluster", clusterID),
+				zap.Error(err))
+		}
+	}
+}
+
+// newDockerClient creates a docker client for the configured docker host,
+// supporting docker contexts, ssh:// hosts and tcp:// hosts with TLS.
+func newDockerClient(dockerConfig *cbdcconfig.Config_Docker) (*client.Client, error) {
+	var endpoint *dockerhost.Endpoint
+	if dockerConfig.Context != "" {
+		contextEndpoint, err := dockerhost.ResolveContext(dockerConfig.Context)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = contextEndpoint
+	} else {
+		endpoint = dockerhost.EndpointFromCertPath(
+			dockerConfig.Host,
+			dockerConfig.CertPath,
+			dockerConfig.TLSSkipVerify.Value())
+	}
+
+	clientOpts := []client.Opt{
+		client.WithAPIVersionNegotiation(),
+	}
+
+	if dockerhost.IsSSHHost(endpoint.Host) {
+		dialer, err := dockerhost.NewSSHDialer(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		// the host is a placeholder, all connections go through the dialer
+		clientOpts = append(clientOpts,
+			client.WithHost("http://docker.example.com"),
+			client.WithDialContext(dialer))
+	} else {
+		if endpoint.HasTLS() {
+			tlsConfig, err := endpoint.TLSConfig()
+			if err != nil {
+				return nil, err
+			}
+
+			clientOpts = append(clientOpts, client.WithHTTPClient(&http.Client{
+				Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			}))
+		}
+
+		clientOpts = append(clientOpts, client.WithHost(endpoint.Host))
+	}
+
+	return client.NewClientWithOpts(clientOpts...)
+}
+
+// toolsConnStr picks the connection string that tools connecting directly
+// to a cluster should use, which is the non-TLS one where there is one.
+func toolsConnStr(connectInfo *deployment.ConnectInfo) string {
+	if connectInfo.ConnStr != "" {
+		return connectInfo.ConnStr
+	}
+	return connectInfo.ConnStrTls
+}
+
 func (h *CmdHelper) getDockerDeployer(ctx context.Context) (*dockerdeploy.Deployer, error) {
 	logger := h.GetLogger()
 	config := h.GetConfig(ctx)
@@ -92,23 +496,88 @@ func (h *CmdHelper) getDockerDeployer(ctx context.Context) (*dockerdeploy.Deploy
 
 	githubToken := config.GitHub.Token
 	githubUser := config.GitHub.User
-	dockerHost := config.Docker.Host
 	dockerNetwork := config.Docker.Network
 
-	dockerCli, err := client.NewClientWithOpts(
-		client.WithHost(dockerHost),
-		client.WithAPIVersionNegotiation(),
-	)
+	dockerCli, err := newDockerClient(&config.Docker)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect to docker")
 	}
 
+	var extraHosts []*dockerdeploy.HostOptions
+	for _, hostConfig := range config.Docker.Hosts {
+		hostDockerCli, err := newDockerClient(&cbdcconfig.Config_Docker{
+			Host:          hostConfig.Host,
+			Context:       hostConfig.Context,
+			CertPath:      hostConfig.CertPath,
+			TLSSkipVerify: hostConfig.TLSSkipVerify,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to docker host `%s`", hostConfig.Name)
+		}
+
+		extraHosts = append(extraHosts, &dockerdeploy.HostOptions{
+			Name:             hostConfig.Name,
+			DockerCli:        hostDockerCli,
+			AdvertiseAddress: hostConfig.AdvertiseAddress,
+		})
+	}
+
+	var imageManifest *dockerdeploy.ImageManifest
+	if config.Docker.ImageManifest != "" {
+		imageManifest, err = dockerdeploy.LoadImageManifest(config.Docker.ImageManifest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load docker image manifest")
+		}
+	}
+
+	// the key is always loaded if it exists, so that node state encrypted
+	// before encryption was disabled can still be read.
+	encryptNodeState := config.Docker.EncryptNodeState.Value()
+	nodeStateKey, err := cbdcconfig.LoadStateKey(encryptNodeState)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load node state key")
+	}
+
+	var imageSources []dockerdeploy.ImageSourceOptions
+	for _, provider := range config.Docker.ImageProviders {
+		imageSources = append(imageSources, dockerdeploy.ImageSourceOptions{
+			Type:             provider.Type,
+			PackageURL:       provider.PackageURL,
+			RegistryURL:      provider.RegistryURL,
+			RegistryUsername: provider.RegistryUsername,
+			RegistryPassword: provider.RegistryPassword,
+			Allow:            provider.Allow,
+			Deny:             provider.Deny,
+		})
+	}
+
 	deployer, err := dockerdeploy.NewDeployer(&dockerdeploy.DeployerOptions{
-		Logger:       logger,
-		DockerCli:    dockerCli,
-		NetworkName:  dockerNetwork,
-		GhcrUsername: githubUser,
-		GhcrPassword: githubToken,
+		Logger:           logger,
+		DockerCli:        dockerCli,
+		NetworkName:      dockerNetwork,
+		GhcrUsername:     githubUser,
+		GhcrPassword:     githubToken,
+		AdvertiseAddress: config.Docker.AdvertiseAddress,
+		ExtraHosts:       extraHosts,
+		DryRun:           h.IsDryRun(),
+
+		ClientNetworkName: config.Docker.ClientNetwork,
+
+		ExpiryGracePeriod: config.ExpiryGracePeriod,
+		OnClusterExpiring: h.getExpiringHook(ctx),
+		DiagnosticsPath:   h.GetDiagnosticsPath(),
+
+		AcceptLicense: config.Docker.AcceptLicense.Value(),
+		LicenseFile:   config.Docker.LicenseFile,
+		ImageManifest: imageManifest,
+		ImageSources:  imageSources,
+
+		NodeStateKey:     nodeStateKey,
+		EncryptNodeState: encryptNodeState,
+
+		SnapshotRegistry: config.Docker.SnapshotRegistry,
+
+		ClusterLocker: h.getClusterLocker(),
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to initializer deployer")
@@ -140,6 +609,10 @@ func (h *CmdHelper) getCaoDeployer(ctx context.Context) (*caodeploy.Deployer, er
 	deployer, err := caodeploy.NewDeployer(&caodeploy.NewDeployerOptions{
 		Logger: logger,
 		Client: caoCtrl,
+		DryRun: h.IsDryRun(),
+
+		ExpiryGracePeriod: config.ExpiryGracePeriod,
+		OnClusterExpiring: h.getExpiringHook(ctx),
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to initializer deployer")
@@ -148,6 +621,64 @@ func (h *CmdHelper) getCaoDeployer(ctx context.Context) (*caodeploy.Deployer, er
 	return deployer, nil
 }
 
+func (h *CmdHelper) getCapellaController(ctx context.Context) (*capellacontrol.Controller, error) {
+	logger := h.GetLogger()
+	config := h.GetConfig(ctx)
+
+	capellaEnv, err := h.getCapellaEnvironment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth capellacontrol.Credentials
+	switch capellaEnv.AuthStyle {
+	case cbdcconfig.CapellaAuthToken:
+		auth = &capellacontrol.TokenCredentials{
+			AccessKey: capellaEnv.AccessKey,
+			SecretKey: capellaEnv.SecretKey,
+		}
+	default:
+		auth = &capellacontrol.BasicCredentials{
+			Username: capellaEnv.Username,
+			Password: capellaEnv.Password,
+		}
+	}
+
+	client, err := capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
+		Logger:      logger,
+		Endpoint:    capellaEnv.Endpoint,
+		Auth:        auth,
+		LogHttp:     h.IsVerboseHttp(),
+		Cache:       h.getCapellaCache(),
+		RateLimiter: h.getCapellaRateLimiter(ctx),
+
+		ProxyUrl:      config.Capella.ProxyUrl,
+		CaBundlePath:  config.Capella.CaBundle,
+		TLSSkipVerify: config.Capella.TLSSkipVerify.Value(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create controller")
+	}
+
+	return client, nil
+}
+
+func (h *CmdHelper) GetCapellaController(ctx context.Context) *capellacontrol.Controller {
+	logger := h.GetLogger()
+	config := h.GetConfig(ctx)
+
+	if !config.Capella.Enabled.Value() {
+		logger.Fatal("capella is not enabled")
+	}
+
+	client, err := h.getCapellaController(ctx)
+	if err != nil {
+		logger.Fatal("failed to get capella controller", zap.Error(err))
+	}
+
+	return client
+}
+
 func (h *CmdHelper) getCloudDeployer(ctx context.Context) (*clouddeploy.Deployer, error) {
 	logger := h.GetLogger()
 	config := h.GetConfig(ctx)
@@ -156,24 +687,19 @@ func (h *CmdHelper) getCloudDeployer(ctx context.Context) (*clouddeploy.Deployer
 		return nil, nil
 	}
 
-	capellaEndpoint := config.Capella.Endpoint
-	capellaUser := config.Capella.Username
-	capellaPass := config.Capella.Password
-	capellaOid := config.Capella.OrganizationID
+	capellaEnv, err := h.getCapellaEnvironment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	capellaOid := capellaEnv.OrganizationID
 	capellaOverrideToken := config.Capella.OverrideToken
 	capellaInternalSupportToken := config.Capella.InternalSupportToken
 	uploadServerLogsHostName := config.Capella.UploadServerLogsHostName
 
-	client, err := capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
-		Logger:   logger,
-		Endpoint: capellaEndpoint,
-		Auth: &capellacontrol.BasicCredentials{
-			Username: capellaUser,
-			Password: capellaPass,
-		},
-	})
+	client, err := h.getCapellaController(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create controller")
+		return nil, err
 	}
 
 	defaultCloud := config.Capella.DefaultCloud
@@ -192,6 +718,13 @@ func (h *CmdHelper) getCloudDeployer(ctx context.Context) (*clouddeploy.Deployer
 		DefaultAzureRegion:       defaultAzureRegion,
 		DefaultGcpRegion:         defaultGcpRegion,
 		UploadServerLogsHostName: uploadServerLogsHostName,
+		DryRun:                   h.IsDryRun(),
+		ExpiryGracePeriod:        config.ExpiryGracePeriod,
+		OnClusterExpiring:        h.getExpiringHook(ctx),
+		DiagnosticsPath:          h.GetDiagnosticsPath(),
+		ClusterLocker:            h.getClusterLocker(),
+		DnsResolvers:             config.Capella.DnsResolvers,
+		DnsWaitTimeout:           config.Capella.DnsWaitTimeout,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create deployer")
@@ -369,6 +902,210 @@ func (h *CmdHelper) IdentifyCurrentUser() string {
 	return osUser.Username
 }
 
+func (h *CmdHelper) getRegistry(ctx context.Context) (clusterregistry.Registry, error) {
+	logger := h.GetLogger()
+	cbdcConfig := h.GetConfig(ctx)
+	registryConfig := cbdcConfig.Registry
+
+	switch registryConfig.Type {
+	case "":
+		return nil, nil
+	case "http":
+		return clusterregistry.NewHttpRegistry(&clusterregistry.HttpRegistryOptions{
+			Logger:   logger,
+			Endpoint: registryConfig.Endpoint,
+			Token:    registryConfig.Token,
+		})
+	case "s3":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load AWS config")
+		}
+
+		creds, err := cfg.Credentials.Retrieve(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to retreive AWS credentials")
+		}
+
+		return clusterregistry.NewS3Registry(&clusterregistry.S3RegistryOptions{
+			Logger:      logger,
+			Bucket:      registryConfig.Bucket,
+			Prefix:      registryConfig.Prefix,
+			Region:      registryConfig.Region,
+			Endpoint:    registryConfig.Endpoint,
+			Credentials: creds,
+		})
+	}
+
+	return nil, fmt.Errorf("unsupported registry type `%s`", registryConfig.Type)
+}
+
+// GetRegistry returns the shared cluster registry, or nil if no registry
+// has been configured.
+func (h *CmdHelper) GetRegistry(ctx context.Context) clusterregistry.Registry {
+	logger := h.GetLogger()
+
+	registry, err := h.getRegistry(ctx)
+	if err != nil {
+		logger.Fatal("failed to get cluster registry", zap.Error(err))
+	}
+
+	return registry
+}
+
+// getRegistryOrWarn is used for best-effort registry updates, where any
+// failure to reach the registry should not fail the command.
+func (h *CmdHelper) getRegistryOrWarn(ctx context.Context) clusterregistry.Registry {
+	logger := h.GetLogger()
+
+	registry, err := h.getRegistry(ctx)
+	if err != nil {
+		logger.Warn("failed to get cluster registry", zap.Error(err))
+		return nil
+	}
+
+	return registry
+}
+
+func (h *CmdHelper) GetRegistryOwner(ctx context.Context) string {
+	cbdcConfig := h.GetConfig(ctx)
+
+	if cbdcConfig.Registry.Owner != "" {
+		return cbdcConfig.Registry.Owner
+	}
+
+	hostname, _ := os.Hostname()
+	return h.IdentifyCurrentUser() + "@" + hostname
+}
+
+// RegisterCluster records a newly allocated cluster in the shared registry,
+// if one is configured.  Failures are only logged since the cluster itself
+// was still allocated successfully.
+func (h *CmdHelper) RegisterCluster(
+	ctx context.Context,
+	deployerName string,
+	cluster deployment.ClusterInfo,
+	connectInfo *deployment.ConnectInfo,
+) {
+	logger := h.GetLogger()
+
+	registry := h.getRegistryOrWarn(ctx)
+	if registry == nil {
+		return
+	}
+
+	entry := &clusterregistry.Entry{
+		ClusterID: cluster.GetID(),
+		Deployer:  deployerName,
+		Owner:     h.GetRegistryOwner(ctx),
+		Purpose:   cluster.GetPurpose(),
+		CreatedAt: time.Now(),
+		Expiry:    cluster.GetExpiry(),
+	}
+	if connectInfo != nil {
+		entry.ConnStr = connectInfo.ConnStr
+		entry.Mgmt = connectInfo.Mgmt
+	}
+
+	err := registry.Register(ctx, entry)
+	if err != nil {
+		logger.Warn("failed to register cluster in shared registry",
+			zap.String("cluster", cluster.GetID()),
+			zap.Error(err))
+	}
+}
+
+// UnregisterCluster removes a cluster from the shared registry, if one is
+// configured.  Failures are only logged, leaving the stale entry for the
+// cleanup command to remove later.
+func (h *CmdHelper) UnregisterCluster(ctx context.Context, clusterID string) {
+	logger := h.GetLogger()
+
+	if h.IsDryRun() {
+		logger.Info("dry-run: skipping unregistering cluster",
+			zap.String("cluster", clusterID))
+		return
+	}
+
+	registry := h.getRegistryOrWarn(ctx)
+	if registry == nil {
+		return
+	}
+
+	err := registry.Unregister(ctx, clusterID)
+	if err != nil {
+		logger.Warn("failed to unregister cluster from shared registry",
+			zap.String("cluster", clusterID),
+			zap.Error(err))
+	}
+}
+
+// UnregisterOwnedClusters removes all of the clusters allocated from this
+// machine using a particular deployer from the shared registry.
+func (h *CmdHelper) UnregisterOwnedClusters(ctx context.Context, deployerName string) {
+	logger := h.GetLogger()
+
+	if h.IsDryRun() {
+		return
+	}
+
+	registry := h.getRegistryOrWarn(ctx)
+	if registry == nil {
+		return
+	}
+
+	entries, err := registry.List(ctx)
+	if err != nil {
+		logger.Warn("failed to list shared registry", zap.Error(err))
+		return
+	}
+
+	owner := h.GetRegistryOwner(ctx)
+	for _, entry := range entries {
+		if entry.Owner != owner || entry.Deployer != deployerName {
+			continue
+		}
+
+		err := registry.Unregister(ctx, entry.ClusterID)
+		if err != nil {
+			logger.Warn("failed to unregister cluster from shared registry",
+				zap.String("cluster", entry.ClusterID),
+				zap.Error(err))
+		}
+	}
+}
+
+// UpdateRegisteredExpiry updates the expiry of a cluster in the shared
+// registry, if one is configured and the cluster is registered.
+func (h *CmdHelper) UpdateRegisteredExpiry(ctx context.Context, clusterID string, expiry time.Time) {
+	logger := h.GetLogger()
+
+	registry := h.getRegistryOrWarn(ctx)
+	if registry == nil {
+		return
+	}
+
+	entries, err := registry.List(ctx)
+	if err != nil {
+		logger.Warn("failed to list shared registry", zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.ClusterID != clusterID {
+			continue
+		}
+
+		entry.Expiry = expiry
+		err = registry.Register(ctx, entry)
+		if err != nil {
+			logger.Warn("failed to update cluster in shared registry",
+				zap.String("cluster", clusterID),
+				zap.Error(err))
+		}
+	}
+}
+
 func (h *CmdHelper) IdentifyCluster(ctx context.Context, userInput string) (string, deployment.Deployer, deployment.ClusterInfo) {
 	logger := h.GetLogger()
 	logger.Info("attempting to identify cluster", zap.String("input", userInput))
@@ -431,15 +1168,59 @@ func (h *CmdHelper) IdentifyCluster(ctx context.Context, userInput string) (stri
 
 	cancel()
 	logger.Fatal("failed to identify cluster using specified identifier",
-		zap.String("identifier", userInput))
+		zap.String("identifier", userInput),
+		zap.Error(deployment.ErrClusterNotFound))
 	return "", nil, nil
 }
 
+// nodeGroupPrefix is the prefix of node identifiers which identify the
+// nodes of a named node group, for instance `group:analytics`.
+const nodeGroupPrefix = "group:"
+
+// IdentifyNodes identifies the nodes targeted by the user input, which is
+// either a single node or all the nodes of a node group.
+func (h *CmdHelper) IdentifyNodes(
+	ctx context.Context,
+	cluster deployment.ClusterInfo,
+	userInput string,
+) []deployment.ClusterNodeInfo {
+	groupName, isGroup := strings.CutPrefix(userInput, nodeGroupPrefix)
+	if !isGroup {
+		return []deployment.ClusterNodeInfo{
+			h.IdentifyNode(ctx, cluster, userInput),
+		}
+	}
+
+	logger := h.GetLogger()
+	logger.Info("attempting to identify node group",
+		zap.String("clusterId", cluster.GetID()),
+		zap.String("group", groupName))
+
+	var nodes []deployment.ClusterNodeInfo
+	for _, node := range cluster.GetNodes() {
+		if node.IsClusterNode() && node.GetNodeGroup() == groupName {
+			nodes = append(nodes, node)
+		}
+	}
+
+	if len(nodes) == 0 {
+		logger.Fatal("failed to find any nodes in the specified node group",
+			zap.String("group", groupName))
+	}
+
+	return nodes
+}
+
 func (h *CmdHelper) IdentifyNode(
 	ctx context.Context,
 	cluster deployment.ClusterInfo,
 	userInput string,
 ) deployment.ClusterNodeInfo {
+	// commands which target a single node use the first node of a group
+	if strings.HasPrefix(userInput, nodeGroupPrefix) {
+		return h.IdentifyNodes(ctx, cluster, userInput)[0]
+	}
+
 	logger := h.GetLogger()
 	logger.Info("attempting to identify node",
 		zap.String("clusterId", cluster.GetID()),
@@ -482,6 +1263,28 @@ func (h *CmdHelper) IdentifyNode(
 		}
 	}
 
+	// the identity of docker nodes within the cluster is only known by asking
+	// the nodes, so this is only done once the other identifiers do not match
+	dockerCluster, ok := cluster.(*dockerdeploy.ClusterInfo)
+	if ok {
+		h.GetDockerDeployer(ctx).PopulateNodeIdentity(ctx, dockerCluster)
+		nodes = cluster.GetNodes()
+	}
+
+	// check if we have an otp node or node uuid exact match
+	for _, node := range nodes {
+		if node.GetOTPNode() == userInput || node.GetNodeUUID() == userInput {
+			return node
+		}
+	}
+
+	// check if we have a node uuid partial match
+	for _, node := range nodes {
+		if node.GetNodeUUID() != "" && strings.HasPrefix(node.GetNodeUUID(), userInput) {
+			return node
+		}
+	}
+
 	logger.Fatal("failed to identify node using specified identifier",
 		zap.String("identifier", userInput))
 	return nil
@@ -504,7 +1307,8 @@ func (h *CmdHelper) FetchClusterDef(
 
 		shortDef, err := clusterdef.FromShortString(simpleStr)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to parse definition short string")
+			return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+				errors.Wrap(err, "failed to parse definition short string"))
 		}
 
 		return shortDef, nil
@@ -515,7 +1319,8 @@ func (h *CmdHelper) FetchClusterDef(
 
 		parsedDef, err := clusterdef.Parse([]byte(defStr))
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to parse cluster definition")
+			return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+				errors.Wrap(err, "failed to parse cluster definition"))
 		}
 
 		return parsedDef, nil
@@ -531,7 +1336,8 @@ func (h *CmdHelper) FetchClusterDef(
 
 		parsedDef, err := clusterdef.Parse(defFileBytes)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to parse cluster definition from file")
+			return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+				errors.Wrap(err, "failed to parse cluster definition from file"))
 		}
 
 		return parsedDef, nil
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -19,9 +20,19 @@ var collectionsAddCmd = &cobra.Command{
 		scopeName := args[2]
 		collectionName := args[3]
 
+		maxTTL, _ := cmd.Flags().GetDuration("max-ttl")
+
+		opts := &deployment.CreateCollectionOptions{
+			MaxTTL: maxTTL,
+		}
+		if cmd.Flags().Changed("history") {
+			history, _ := cmd.Flags().GetBool("history")
+			opts.History = &history
+		}
+
 		_, deployer, cluster := helper.IdentifyCluster(ctx, clusterID)
 
-		err := deployer.CreateCollection(ctx, cluster.GetID(), bucketName, scopeName, collectionName)
+		err := deployer.CreateCollection(ctx, cluster.GetID(), bucketName, scopeName, collectionName, opts)
 		if err != nil {
 			logger.Fatal("failed to create collection", zap.Error(err))
 		}
@@ -30,4 +41,7 @@ var collectionsAddCmd = &cobra.Command{
 
 func init() {
 	collectionsCmd.AddCommand(collectionsAddCmd)
+
+	collectionsAddCmd.Flags().Duration("max-ttl", 0, "The maximum expiry of documents in the collection, or negative for no expiry.")
+	collectionsAddCmd.Flags().Bool("history", false, "Whether the change history of the collection is retained (requires magma).")
 }
@@ -9,6 +9,22 @@ import (
 
 type CollectionsListOutput map[string][]string
 
+type CollectionsListDetailsOutput struct {
+	UID    uint64                                 `json:"uid"`
+	Scopes map[string]CollectionsListOutput_Scope `json:"scopes"`
+}
+
+type CollectionsListOutput_Scope struct {
+	UID         uint64                                      `json:"uid"`
+	Collections map[string]CollectionsListOutput_Collection `json:"collections"`
+}
+
+type CollectionsListOutput_Collection struct {
+	UID     uint64 `json:"uid"`
+	MaxTTL  int    `json:"maxTTL"`
+	History bool   `json:"history"`
+}
+
 var collectionsListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
@@ -20,31 +36,61 @@ var collectionsListCmd = &cobra.Command{
 		ctx := helper.GetContext()
 
 		outputJson, _ := cmd.Flags().GetBool("json")
+		showDetails, _ := cmd.Flags().GetBool("details")
 
 		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
 
-		scopes, err := deployer.ListCollections(ctx, cluster.GetID(), args[1])
+		manifest, err := deployer.GetCollectionManifest(ctx, cluster.GetID(), args[1])
 		if err != nil {
 			logger.Fatal("failed to list collections", zap.Error(err))
 		}
 
 		if !outputJson {
+			fmt.Printf("Manifest UID: %d\n", manifest.UID)
+
 			fmt.Printf("Scopes:\n")
-			for _, scope := range scopes {
-				fmt.Printf("  %s\n",
-					scope.Name)
+			for _, scope := range manifest.Scopes {
+				fmt.Printf("  %s (uid: %d)\n",
+					scope.Name, scope.UID)
 			}
 
 			fmt.Printf("Collections:\n")
-			for _, scope := range scopes {
+			for _, scope := range manifest.Scopes {
 				for _, collection := range scope.Collections {
-					fmt.Printf("  %s/%s\n",
-						scope.Name, collection.Name)
+					maxTTL := "bucket"
+					if collection.MaxTTL < 0 {
+						maxTTL = "none"
+					} else if collection.MaxTTL > 0 {
+						maxTTL = collection.MaxTTL.String()
+					}
+
+					fmt.Printf("  %s/%s (uid: %d, max-ttl: %s, history: %t)\n",
+						scope.Name, collection.Name, collection.UID, maxTTL, collection.History)
 				}
 			}
+		} else if showDetails {
+			out := CollectionsListDetailsOutput{
+				UID:    manifest.UID,
+				Scopes: make(map[string]CollectionsListOutput_Scope),
+			}
+			for _, scope := range manifest.Scopes {
+				scopeOut := CollectionsListOutput_Scope{
+					UID:         scope.UID,
+					Collections: make(map[string]CollectionsListOutput_Collection),
+				}
+				for _, collection := range scope.Collections {
+					scopeOut.Collections[collection.Name] = CollectionsListOutput_Collection{
+						UID:     collection.UID,
+						MaxTTL:  int(collection.MaxTTL.Seconds()),
+						History: collection.History,
+					}
+				}
+				out.Scopes[scope.Name] = scopeOut
+			}
+			helper.OutputJson(out)
 		} else {
 			out := make(CollectionsListOutput)
-			for _, scope := range scopes {
+			for _, scope := range manifest.Scopes {
 				var collections []string
 				for _, collection := range scope.Collections {
 					collections = append(collections, collection.Name)
@@ -58,4 +104,6 @@ var collectionsListCmd = &cobra.Command{
 
 func init() {
 	collectionsCmd.AddCommand(collectionsListCmd)
+
+	collectionsListCmd.Flags().Bool("details", false, "Include uids and collection settings in the json output")
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var collectionsUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Changes the settings of a collection",
+	Args:  cobra.MinimumNArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		clusterID := args[0]
+		bucketName := args[1]
+		scopeName := args[2]
+		collectionName := args[3]
+
+		opts := &deployment.UpdateCollectionOptions{}
+		if cmd.Flags().Changed("max-ttl") {
+			maxTTL, _ := cmd.Flags().GetDuration("max-ttl")
+			opts.MaxTTL = &maxTTL
+		}
+		if cmd.Flags().Changed("history") {
+			history, _ := cmd.Flags().GetBool("history")
+			opts.History = &history
+		}
+
+		if opts.MaxTTL == nil && opts.History == nil {
+			logger.Fatal("no settings were specified to update")
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, clusterID)
+
+		err := deployer.UpdateCollection(ctx, cluster.GetID(), bucketName, scopeName, collectionName, opts)
+		if err != nil {
+			logger.Fatal("failed to update collection", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	collectionsCmd.AddCommand(collectionsUpdateCmd)
+
+	collectionsUpdateCmd.Flags().Duration("max-ttl", 0, "The maximum expiry of documents in the collection, or negative for no expiry.")
+	collectionsUpdateCmd.Flags().Bool("history", false, "Whether the change history of the collection is retained (requires magma).")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var collectionsWaitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Waits for every node to have at least the specified collection manifest",
+	Args:  cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		minUid, err := strconv.ParseUint(args[2], 0, 64)
+		if err != nil {
+			logger.Fatal("failed to parse manifest uid", zap.Error(err))
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		err = deployer.WaitForCollectionManifest(ctx, cluster.GetID(), args[1], minUid)
+		if err != nil {
+			logger.Fatal("failed to wait for collection manifest", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	collectionsCmd.AddCommand(collectionsWaitCmd)
+
+	collectionsWaitCmd.Flags().Duration("timeout", 1*time.Minute, "How long to wait for the manifest, or forever if zero")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/cbdcconfig"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ConfigDoctorOutput []ConfigDoctorOutput_Item
+
+type ConfigDoctorOutput_Item struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+var configDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the config file for problems",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		result, err := cbdcconfig.Doctor(ctx)
+		if err != nil {
+			logger.Fatal("failed to check config", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Config: %s (version %d)\n", result.ConfigPath, result.Version)
+			if len(result.Issues) == 0 {
+				fmt.Printf("No problems found.\n")
+			} else {
+				fmt.Printf("Issues:\n")
+				for _, issue := range result.Issues {
+					fmt.Printf("  [%s] %s\n", issue.Severity, issue.Message)
+				}
+			}
+		} else {
+			out := ConfigDoctorOutput{}
+			for _, issue := range result.Issues {
+				out = append(out, ConfigDoctorOutput_Item{
+					Severity: string(issue.Severity),
+					Message:  issue.Message,
+				})
+			}
+			helper.OutputJson(out)
+		}
+
+		if result.HasErrors() {
+			exitWithCode(ExitFailure)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configDoctorCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ConfigEffectiveOutput struct {
+	Defaults   []ConfigEffectiveOutput_Item `json:"defaults"`
+	Definition string                       `json:"definition,omitempty"`
+}
+
+type ConfigEffectiveOutput_Item struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+var configEffectiveCmd = &cobra.Command{
+	Use:   "effective [flags] [definition-tag | --def | --def-file]",
+	Short: "Prints the merged defaults, and the definition they produce, for an allocate",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		defStr, _ := cmd.Flags().GetString("def")
+		defFile, _ := cmd.Flags().GetString("def-file")
+		profile, _ := cmd.Flags().GetString("profile")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		simpleDefStr := ""
+		if len(args) >= 1 {
+			simpleDefStr = args[0]
+		}
+
+		// the builtin deployment profiles do not select any defaults
+		if profile == dockerdeploy.ProfileStandard || profile == dockerdeploy.ProfileQuick ||
+			profile == dockerdeploy.ProfileSmall {
+			profile = ""
+		}
+
+		defaults := helper.GetEffectiveDefaults(ctx, profile)
+
+		values := []ConfigEffectiveOutput_Item{
+			{Name: "deployer", Value: defaults.Deployer},
+			{Name: "version", Value: defaults.Version},
+			{Name: "purpose", Value: defaults.Purpose},
+			{Name: "expiry", Value: defaults.Expiry.String()},
+			{Name: "max-expiry", Value: defaults.MaxExpiry.String()},
+			{Name: "cloud-provider", Value: defaults.CloudProvider},
+		}
+		for i := range values {
+			values[i].Source = defaults.Sources[values[i].Name]
+			if values[i].Source == "" {
+				values[i].Value = ""
+			}
+		}
+
+		var effectiveDefStr string
+		if simpleDefStr != "" || defStr != "" || defFile != "" {
+			def, err := helper.FetchClusterDef(simpleDefStr, defStr, defFile)
+			if err != nil {
+				logger.Fatal("failed to get definition", zap.Error(err))
+			}
+
+			err = defaults.Apply(def)
+			if err != nil {
+				logger.Fatal("failed to apply defaults", zap.Error(err))
+			}
+
+			effectiveDefStr, err = clusterdef.Stringify(def)
+			if err != nil {
+				logger.Fatal("failed to generate definition output", zap.Error(err))
+			}
+		}
+
+		if !outputJson {
+			fmt.Printf("Defaults:\n")
+			for _, value := range values {
+				if value.Source == "" {
+					fmt.Printf("  %s: (unset)\n", value.Name)
+				} else {
+					fmt.Printf("  %s: %s (from %s)\n", value.Name, value.Value, value.Source)
+				}
+			}
+
+			if effectiveDefStr != "" {
+				fmt.Printf("Definition:\n")
+				fmt.Printf("%s\n", effectiveDefStr)
+			}
+		} else {
+			helper.OutputJson(ConfigEffectiveOutput{
+				Defaults:   values,
+				Definition: effectiveDefStr,
+			})
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEffectiveCmd)
+
+	configEffectiveCmd.Flags().String("def", "", "The cluster definition to apply the defaults to.")
+	configEffectiveCmd.Flags().String("def-file", "", "The path to a file containing a cluster definition to apply the defaults to.")
+	configEffectiveCmd.Flags().String("profile", "", "The profile of defaults to use, instead of the default profile")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/cbdcconfig"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrates the config file to the latest version",
+	Long: `Migrates the config file to the latest version, keeping a backup of the
+original file.  This normally happens automatically whenever the config file
+is loaded, but can be useful to do ahead of time.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		result, err := cbdcconfig.Migrate(ctx)
+		if err != nil {
+			logger.Fatal("failed to migrate config", zap.Error(err))
+		}
+
+		if result.BackupPath == "" {
+			fmt.Printf("Config is already at version %d.\n", result.ToVersion)
+			return
+		}
+
+		fmt.Printf("Config migrated from version %d to %d.\n", result.FromVersion, result.ToVersion)
+		fmt.Printf("The original config was saved to %s\n", result.BackupPath)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/cbdcconfig"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var configSetDefaultOrgCmd = &cobra.Command{
+	Use:   "set-default-org [org-id | org-name]",
+	Short: "Sets the Capella organization to use by default",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+		config := helper.GetConfig(ctx)
+
+		client := helper.GetCapellaController(ctx)
+
+		orgs, err := listCapellaOrgs(ctx, client)
+		if err != nil {
+			logger.Fatal("failed to list organizations", zap.Error(err))
+		}
+
+		org, err := findCapellaOrg(orgs, args[0])
+		if err != nil {
+			logger.Fatal("failed to identify organization", zap.Error(err))
+		}
+
+		config.Capella.OrganizationID = org.ID
+
+		err = cbdcconfig.Save(ctx, config)
+		if err != nil {
+			logger.Fatal("failed to save config", zap.Error(err))
+		}
+
+		logger.Info("default organization updated",
+			zap.String("id", org.ID),
+			zap.String("name", org.Name))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetDefaultOrgCmd)
+}
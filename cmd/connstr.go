@@ -3,10 +3,18 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/cioutput"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
+type ConnstrOutput struct {
+	ConnStr  string `json:"connstr"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
 var connstrCmd = &cobra.Command{
 	Use:     "connstr [flags] cluster",
 	Aliases: []string{"conn-str"},
@@ -20,16 +28,40 @@ var connstrCmd = &cobra.Command{
 		useTLS, _ := cmd.Flags().GetBool("tls")
 		noTLS, _ := cmd.Flags().GetBool("no-tls")
 		useCb2, _ := cmd.Flags().GetBool("couchbase2")
+		useSgw, _ := cmd.Flags().GetBool("sync-gateway")
+		newUser, _ := cmd.Flags().GetBool("new-user")
+		buckets, _ := cmd.Flags().GetStringSlice("bucket")
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		if !newUser && (len(buckets) > 0 || readOnly) {
+			logger.Fatal("--bucket and --read-only can only be used with --new-user")
+		}
 
 		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
 
-		connectInfo, err := deployer.GetConnectInfo(ctx, cluster.GetID())
+		var connectInfo *deployment.ConnectInfo
+		var err error
+		if newUser {
+			connectInfo, err = deployment.GetConnectInfoWithCredentials(ctx, deployer, cluster.GetID(),
+				&deployment.ConnectCredentialsOptions{
+					Buckets:  buckets,
+					ReadOnly: readOnly,
+				})
+		} else {
+			connectInfo, err = deployer.GetConnectInfo(ctx, cluster.GetID())
+		}
 		if err != nil {
 			logger.Fatal("failed to get connect info", zap.Error(err))
 		}
 
 		var connStr string
-		if useCb2 {
+		if useSgw {
+			connStr = connectInfo.SyncGateway
+			if connStr == "" {
+				logger.Fatal("sync gateway endpoint is unavailable")
+			}
+		} else if useCb2 {
 			if noTLS {
 				logger.Fatal("cannot request non-TLS for couchbase2")
 			}
@@ -62,7 +94,27 @@ var connstrCmd = &cobra.Command{
 			}
 		}
 
-		fmt.Printf("%s\n", connStr)
+		// secrets are masked before they are output anywhere
+		ciOutput := helper.GetCIOutput()
+		helper.SetCIOutputs(ciOutput, []cioutput.Output{
+			{Name: "connstr", Value: connStr},
+			{Name: "username", Value: connectInfo.Username},
+			{Name: "password", Value: connectInfo.Password, Secret: true},
+		})
+
+		if !outputJson {
+			fmt.Printf("%s\n", connStr)
+			if connectInfo.Username != "" {
+				fmt.Printf("Username: %s\n", connectInfo.Username)
+				fmt.Printf("Password: %s\n", connectInfo.Password)
+			}
+		} else {
+			helper.OutputJson(ConnstrOutput{
+				ConnStr:  connStr,
+				Username: connectInfo.Username,
+				Password: connectInfo.Password,
+			})
+		}
 	},
 }
 
@@ -72,4 +124,8 @@ func init() {
 	connstrCmd.PersistentFlags().Bool("couchbase2", false, "Requests a couchbase2 connstr")
 	connstrCmd.PersistentFlags().Bool("tls", false, "Explicitly requests a TLS endpoint")
 	connstrCmd.PersistentFlags().Bool("no-tls", false, "Explicitly requests non-TLS endpoint")
+	connstrCmd.PersistentFlags().Bool("sync-gateway", false, "Requests the sync gateway websocket endpoint")
+	connstrCmd.PersistentFlags().Bool("new-user", false, "Creates a dedicated user with a random password to connect with")
+	connstrCmd.PersistentFlags().StringSlice("bucket", nil, "Restricts the new user to these buckets")
+	connstrCmd.PersistentFlags().Bool("read-only", false, "Prevents the new user from writing data")
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"os"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var dataExportCmd = &cobra.Command{
+	Use:   "export [cluster] [bucket]",
+	Short: "Exports the documents of a bucket to a gzipped JSON lines file",
+	Long: "Exports all documents of a bucket, including their scope and collection, using cbexport " +
+		"in a docker container.  Clusters other than docker clusters (such as Capella) require the " +
+		"credentials of a database user to be passed.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outPath, _ := cmd.Flags().GetString("out")
+		username, _ := cmd.Flags().GetString("username")
+		password, _ := cmd.Flags().GetString("password")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		connectInfo, err := deployer.GetConnectInfo(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get connect info", zap.Error(err))
+		}
+
+		dockerDeployer := helper.GetDockerDeployer(ctx)
+
+		outFile, err := os.Create(outPath)
+		if err != nil {
+			logger.Fatal("failed to create output file", zap.Error(err))
+		}
+		defer outFile.Close()
+
+		gzipWrt := gzip.NewWriter(outFile)
+
+		err = dockerDeployer.ExportBucketData(ctx, &dockerdeploy.BucketDataOptions{
+			ConnStr:    toolsConnStr(connectInfo),
+			Username:   username,
+			Password:   password,
+			BucketName: args[1],
+		}, gzipWrt)
+		if err != nil {
+			logger.Fatal("failed to export bucket data", zap.Error(err))
+		}
+
+		err = gzipWrt.Close()
+		if err != nil {
+			logger.Fatal("failed to finish writing output file", zap.Error(err))
+		}
+
+		logger.Info("exported bucket data", zap.String("path", outPath))
+	},
+}
+
+func init() {
+	dataCmd.AddCommand(dataExportCmd)
+
+	dataExportCmd.Flags().String("out", "data.jsonl.gz", "The path of the file to write")
+	dataExportCmd.Flags().String("username", "Administrator", "The username to connect to the cluster with")
+	dataExportCmd.Flags().String("password", "password", "The password to connect to the cluster with")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"os"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var dataImportCmd = &cobra.Command{
+	Use:   "import [cluster] [bucket]",
+	Short: "Imports documents from a gzipped JSON lines file written by data export",
+	Long: "Imports documents into a bucket using cbimport in a docker container.  The scopes and " +
+		"collections referenced by the data must already exist in the bucket.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		inPath, _ := cmd.Flags().GetString("in")
+		username, _ := cmd.Flags().GetString("username")
+		password, _ := cmd.Flags().GetString("password")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		connectInfo, err := deployer.GetConnectInfo(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get connect info", zap.Error(err))
+		}
+
+		dockerDeployer := helper.GetDockerDeployer(ctx)
+
+		inFile, err := os.Open(inPath)
+		if err != nil {
+			logger.Fatal("failed to open input file", zap.Error(err))
+		}
+		defer inFile.Close()
+
+		gzipRdr, err := gzip.NewReader(inFile)
+		if err != nil {
+			logger.Fatal("failed to read input file", zap.Error(err))
+		}
+
+		err = dockerDeployer.ImportBucketData(ctx, &dockerdeploy.BucketDataOptions{
+			ConnStr:    toolsConnStr(connectInfo),
+			Username:   username,
+			Password:   password,
+			BucketName: args[1],
+		}, gzipRdr)
+		if err != nil {
+			logger.Fatal("failed to import bucket data", zap.Error(err))
+		}
+
+		logger.Info("imported bucket data", zap.String("path", inPath))
+	},
+}
+
+func init() {
+	dataCmd.AddCommand(dataImportCmd)
+
+	dataImportCmd.Flags().String("in", "data.jsonl.gz", "The path of the file to read")
+	dataImportCmd.Flags().String("username", "Administrator", "The username to connect to the cluster with")
+	dataImportCmd.Flags().String("password", "password", "The password to connect to the cluster with")
+}
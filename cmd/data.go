@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var dataCmd = &cobra.Command{
+	Use:   "data",
+	Short: "Provides the ability to move bucket data between clusters",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(dataCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/settingsdump"
+	"github.com/spf13/cobra"
+)
+
+type DebugSettingsDiffOutput []settingsdump.Difference
+
+var debugSettingsDiffCmd = &cobra.Command{
+	Use:   "settings-diff",
+	Short: "Compares the settings of two clusters or settings dumps",
+	Long: "Compares the settings of two clusters or settings dumps.  Each argument is\n" +
+		"either the path to a file written by settings-dump, or a cluster id.",
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		left := helper.loadSettingsDump(ctx, args[0])
+		right := helper.loadSettingsDump(ctx, args[1])
+
+		diffs := settingsdump.Diff(left, right)
+
+		if !outputJson {
+			if len(diffs) == 0 {
+				fmt.Printf("No differences found\n")
+				return
+			}
+
+			fmt.Printf("Differences:\n")
+			for _, diff := range diffs {
+				leftValue := diff.Left
+				if leftValue == "" {
+					leftValue = "(missing)"
+				}
+				rightValue := diff.Right
+				if rightValue == "" {
+					rightValue = "(missing)"
+				}
+
+				fmt.Printf("  %s: %s -> %s\n",
+					diff.Path, leftValue, rightValue)
+			}
+		} else {
+			out := DebugSettingsDiffOutput(diffs)
+			if out == nil {
+				out = DebugSettingsDiffOutput{}
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	debugCmd.AddCommand(debugSettingsDiffCmd)
+}
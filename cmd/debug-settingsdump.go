@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var debugSettingsDumpCmd = &cobra.Command{
+	Use:   "settings-dump",
+	Short: "Dumps the normalized settings of a cluster",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputPath, _ := cmd.Flags().GetString("output")
+
+		dump := helper.captureSettingsDump(ctx, args[0])
+
+		dumpBytes, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			logger.Fatal("failed to marshal settings dump", zap.Error(err))
+		}
+
+		if outputPath == "" {
+			fmt.Printf("%s\n", dumpBytes)
+			return
+		}
+
+		err = os.WriteFile(outputPath, append(dumpBytes, '\n'), 0644)
+		if err != nil {
+			logger.Fatal("failed to write settings dump", zap.Error(err))
+		}
+
+		logger.Info("wrote settings dump", zap.String("path", outputPath))
+	},
+}
+
+func init() {
+	debugCmd.AddCommand(debugSettingsDumpCmd)
+
+	debugSettingsDumpCmd.Flags().String("output", "", "A file to write the dump to rather than stdout")
+}
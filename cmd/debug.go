@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/settingsdump"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Provides tools for triaging differences between clusters",
+	Run:   nil,
+}
+
+// managementJsonGetter is implemented by deployers which can read arbitrary
+// endpoints of the cluster management API.
+type managementJsonGetter interface {
+	GetManagementJson(ctx context.Context, clusterID string, path string) (json.RawMessage, error)
+}
+
+func (h *CmdHelper) captureSettingsDump(ctx context.Context, userInput string) *settingsdump.Dump {
+	logger := h.GetLogger()
+
+	_, deployer, cluster := h.IdentifyCluster(ctx, userInput)
+
+	getter, ok := deployer.(managementJsonGetter)
+	if !ok {
+		logger.Fatal("settings dumps are not supported by this deployer",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	dump, err := settingsdump.Capture(ctx, func(ctx context.Context, path string) (json.RawMessage, error) {
+		return getter.GetManagementJson(ctx, cluster.GetID(), path)
+	})
+	if err != nil {
+		logger.Fatal("failed to capture settings dump", zap.Error(err))
+	}
+
+	return dump
+}
+
+// loadSettingsDump loads a settings dump from a file if one exists at the
+// specified path, otherwise captures one from the identified cluster.
+func (h *CmdHelper) loadSettingsDump(ctx context.Context, userInput string) *settingsdump.Dump {
+	logger := h.GetLogger()
+
+	if _, err := os.Stat(userInput); err == nil {
+		dump, err := settingsdump.Load(userInput)
+		if err != nil {
+			logger.Fatal("failed to load settings dump", zap.Error(err))
+		}
+
+		return dump
+	}
+
+	return h.captureSettingsDump(ctx, userInput)
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"go.uber.org/zap/zapcore"
+)
+
+// These are the exit codes of the tool, which scripts can rely on.  Any
+// failure which does not fall into one of the specific categories exits
+// with ExitFailure.
+const (
+	ExitSuccess            = 0
+	ExitFailure            = 1
+	ExitUsage              = 2
+	ExitNotFound           = 3
+	ExitTimeout            = 4
+	ExitQuotaExceeded      = 5
+	ExitInvalidDefinition  = 6
+	ExitVersionUnavailable = 7
+	ExitFeatureUnsupported = 8
+	ExitImageUntrusted     = 9
+	ExitReadOnly           = 10
+	ExitVersionDrift       = 11
+)
+
+// exitCodeForError maps the kind of an error to the exit code to use.
+func exitCodeForError(err error) int {
+	switch {
+	case err == nil:
+		return ExitFailure
+	case errors.Is(err, deployment.ErrClusterNotFound):
+		return ExitNotFound
+	case errors.Is(err, deployment.ErrProvisionTimeout):
+		return ExitTimeout
+	case errors.Is(err, deployment.ErrQuotaExceeded):
+		return ExitQuotaExceeded
+	case errors.Is(err, deployment.ErrInvalidDefinition):
+		return ExitInvalidDefinition
+	case errors.Is(err, deployment.ErrVersionUnavailable):
+		return ExitVersionUnavailable
+	case errors.Is(err, deployment.ErrFeatureUnsupported):
+		return ExitFeatureUnsupported
+	case errors.Is(err, deployment.ErrImageUntrusted):
+		return ExitImageUntrusted
+	case errors.Is(err, errReadOnly):
+		return ExitReadOnly
+	case errors.Is(err, errVersionDrift):
+		return ExitVersionDrift
+	}
+	return ExitFailure
+}
+
+// exitCodeFatalHook is used for fatal logs, exiting with the code matching
+// the error attached to the log entry, if there is one.
+type exitCodeFatalHook struct{}
+
+var _ zapcore.CheckWriteHook = exitCodeFatalHook{}
+
+func (exitCodeFatalHook) OnWrite(entry *zapcore.CheckedEntry, fields []zapcore.Field) {
+	var err error
+	for _, field := range fields {
+		if field.Type != zapcore.ErrorType {
+			continue
+		}
+		if fieldErr, ok := field.Interface.(error); ok {
+			err = fieldErr
+		}
+	}
+
+	exitWithCode(exitCodeForError(err))
+}
+
+// batchStepExit is panicked with to end the current step of a batch, rather
+// than exiting the tool.
+type batchStepExit struct {
+	ExitCode int
+}
+
+// inBatchStep indicates that a step of a batch is currently running.
+var inBatchStep bool
+
+// exitWithCode exits the tool with an exit code, or when a batch is running,
+// ends only the current step of the batch.
+func exitWithCode(code int) {
+	if inBatchStep {
+		panic(batchStepExit{ExitCode: code})
+	}
+
+	os.Exit(code)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -25,8 +26,15 @@ var imagesListCmd = &cobra.Command{
 		ctx := helper.GetContext()
 
 		outputJson, _ := cmd.Flags().GetBool("json")
+		deployerName, _ := cmd.Flags().GetString("deployer")
+
+		var deployer deployment.Deployer
+		if deployerName == "" {
+			deployer = helper.GetDeployer(ctx)
+		} else {
+			deployer = helper.GetDeployerByName(ctx, deployerName)
+		}
 
-		deployer := helper.GetDeployer(ctx)
 		images, err := deployer.ListImages(ctx)
 		if err != nil {
 			logger.Fatal("failed to list images", zap.Error(err))
@@ -57,4 +65,6 @@ var imagesListCmd = &cobra.Command{
 
 func init() {
 	imagesCmd.AddCommand(imagesListCmd)
+
+	imagesListCmd.Flags().String("deployer", "", "The name of the deployer to list images for")
 }
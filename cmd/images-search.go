@@ -30,8 +30,15 @@ var imagesSearchCmd = &cobra.Command{
 
 		outputJson, _ := cmd.Flags().GetBool("json")
 		limit, _ := cmd.Flags().GetInt("limit")
+		deployerName, _ := cmd.Flags().GetString("deployer")
+
+		var deployer deployment.Deployer
+		if deployerName == "" {
+			deployer = helper.GetDeployer(ctx)
+		} else {
+			deployer = helper.GetDeployerByName(ctx, deployerName)
+		}
 
-		deployer := helper.GetDeployer(ctx)
 		images, err := deployer.SearchImages(ctx, args[0])
 		if err != nil {
 			logger.Fatal("failed to search images", zap.Error(err))
@@ -90,4 +97,5 @@ func init() {
 	imagesCmd.AddCommand(imagesSearchCmd)
 
 	imagesSearchCmd.Flags().Int("limit", 10, "The maximum number of results to return")
+	imagesSearchCmd.Flags().String("deployer", "", "The name of the deployer to search images for")
 }
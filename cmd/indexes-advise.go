@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type IndexesAdviseOutput struct {
+	CurrentIndexes     []string `json:"currentIndexes"`
+	RecommendedIndexes []string `json:"recommendedIndexes"`
+	CoveringIndexes    []string `json:"coveringIndexes"`
+}
+
+var indexesAdviseCmd = &cobra.Command{
+	Use:   "advise [cluster] [statement]",
+	Short: "Asks the index advisor which indexes a query statement would benefit from",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		advice, err := deployer.AdviseIndexes(ctx, cluster.GetID(), args[1])
+		if err != nil {
+			logger.Fatal("failed to run index advisor", zap.Error(err))
+		}
+
+		if !outputJson {
+			printStatements := func(title string, statements []string) {
+				fmt.Printf("%s:\n", title)
+				if len(statements) == 0 {
+					fmt.Printf("  none\n")
+				}
+				for _, statement := range statements {
+					fmt.Printf("  %s\n", statement)
+				}
+			}
+			printStatements("Current Indexes", advice.CurrentIndexes)
+			printStatements("Recommended Indexes", advice.RecommendedIndexes)
+			printStatements("Covering Indexes", advice.CoveringIndexes)
+		} else {
+			helper.OutputJson(IndexesAdviseOutput{
+				CurrentIndexes:     advice.CurrentIndexes,
+				RecommendedIndexes: advice.RecommendedIndexes,
+				CoveringIndexes:    advice.CoveringIndexes,
+			})
+		}
+	},
+}
+
+func init() {
+	indexesCmd.AddCommand(indexesAdviseCmd)
+}
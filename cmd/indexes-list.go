@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type IndexesListOutput []IndexesListOutput_Item
+
+type IndexesListOutput_Item struct {
+	Bucket     string   `json:"bucket"`
+	Scope      string   `json:"scope"`
+	Collection string   `json:"collection"`
+	Name       string   `json:"name"`
+	ReplicaID  int      `json:"replicaId"`
+	Status     string   `json:"status"`
+	Progress   int      `json:"progress"`
+	Definition string   `json:"definition"`
+	Hosts      []string `json:"hosts"`
+}
+
+var indexesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Lists all the GSI indexes of a cluster",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		bucketName, _ := cmd.Flags().GetString("bucket")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		indexes, err := deployer.ListIndexes(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to list indexes", zap.Error(err))
+		}
+
+		out := IndexesListOutput{}
+		for _, index := range indexes {
+			if bucketName != "" && index.BucketName != bucketName {
+				continue
+			}
+
+			out = append(out, IndexesListOutput_Item{
+				Bucket:     index.BucketName,
+				Scope:      index.ScopeName,
+				Collection: index.CollectionName,
+				Name:       index.Name,
+				ReplicaID:  index.ReplicaID,
+				Status:     index.Status,
+				Progress:   index.Progress,
+				Definition: index.Definition,
+				Hosts:      index.Hosts,
+			})
+		}
+
+		if !outputJson {
+			fmt.Printf("Indexes:\n")
+			for _, index := range out {
+				name := index.Name
+				if index.ReplicaID > 0 {
+					name = fmt.Sprintf("%s (replica %d)", name, index.ReplicaID)
+				}
+
+				fmt.Printf("  %s/%s/%s %s [Status: %s, Progress: %d%%, Hosts: %s]\n",
+					index.Bucket, index.Scope, index.Collection, name,
+					index.Status, index.Progress, strings.Join(index.Hosts, ","))
+				fmt.Printf("    %s\n", index.Definition)
+			}
+		} else {
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	indexesCmd.AddCommand(indexesListCmd)
+
+	indexesListCmd.Flags().String("bucket", "", "Only lists the indexes of this bucket")
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var indexesCmd = &cobra.Command{
+	Use:   "indexes",
+	Short: "Provides the ability to inspect the GSI indexes of a system",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(indexesCmd)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -24,9 +25,9 @@ import (
 	"github.com/couchbaselabs/cbdinocluster/utils/azurecontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/cloudinstancecontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/dockerhost"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/client"
 	"github.com/google/go-github/v53/github"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -60,7 +61,12 @@ var initCmd = &cobra.Command{
 			fmt.Printf("Failed to identify user home directory (%s)\n", err)
 		}
 
-		curConfig, _ := cbdcconfig.Load(ctx)
+		curConfig, err := cbdcconfig.Load(ctx)
+		if errors.Is(err, cbdcconfig.ErrConfigTooNew) {
+			// re-initializing would overwrite settings we do not understand
+			fmt.Printf("Failed to load existing config (%s)\n", err)
+			exitWithCode(ExitFailure)
+		}
 		if curConfig == nil {
 			curConfig = &cbdcconfig.Config{
 				Version: cbdcconfig.Version,
@@ -148,7 +154,7 @@ var initCmd = &cobra.Command{
 			err := cbdcconfig.Save(ctx, curConfig)
 			if err != nil {
 				fmt.Printf("failed to write updated config: %s\n", err)
-				os.Exit(1)
+				exitWithCode(ExitFailure)
 			}
 		}
 
@@ -371,6 +377,8 @@ var initCmd = &cobra.Command{
 			fmt.Printf("  Host: %s\n", curConfig.Docker.Host)
 			fmt.Printf("  Network: %s\n", curConfig.Docker.Network)
 			fmt.Printf("  Forward Only: %t\n", curConfig.Docker.ForwardOnly.Value())
+			fmt.Printf("  Context: %s\n", curConfig.Docker.Context)
+			fmt.Printf("  Advertise Address: %s\n", curConfig.Docker.AdvertiseAddress)
 		}
 		{
 			fmt.Printf("-- Docker Configuration\n")
@@ -378,11 +386,15 @@ var initCmd = &cobra.Command{
 			flagDisableDocker, _ := cmd.Flags().GetBool("disable-docker")
 			flagDockerHost, _ := cmd.Flags().GetString("docker-host")
 			flagDockerNetwork, _ := cmd.Flags().GetString("docker-network")
+			flagDockerContext, _ := cmd.Flags().GetString("docker-context")
+			flagDockerAdvertiseAddress, _ := cmd.Flags().GetString("docker-advertise-address")
 			envDockerHost := os.Getenv("DOCKER_HOST")
 
 			dockerEnabled := curConfig.Docker.Enabled.ValueOr(true)
 			dockerHost := curConfig.Docker.Host
 			dockerNetwork := curConfig.Docker.Network
+			dockerContext := curConfig.Docker.Context
+			dockerAdvertiseAddress := curConfig.Docker.AdvertiseAddress
 
 			for {
 				if flagDisableDocker {
@@ -398,9 +410,21 @@ var initCmd = &cobra.Command{
 					break
 				}
 
-				if flagDockerHost != "" {
+				if flagDockerContext != "" {
+					fmt.Printf("Docker context specified via flags:\n  %s\n", flagDockerContext)
+					dockerContext = flagDockerContext
+
+					contextEndpoint, err := dockerhost.ResolveContext(dockerContext)
+					if err != nil {
+						fmt.Printf("Failed to read docker context:\n  %s\n", err)
+						dockerEnabled = false
+						continue
+					}
+					dockerHost = contextEndpoint.Host
+				} else if flagDockerHost != "" {
 					fmt.Printf("Docker host specified via flags:\n  %s\n", flagDockerHost)
 					dockerHost = flagDockerHost
+					dockerContext = ""
 				} else {
 					colimaDockerHost := getColimaDockerHost()
 					dockerDockerHost := getDockerDockerHost()
@@ -429,10 +453,12 @@ var initCmd = &cobra.Command{
 				}
 
 				fmt.Printf("Pinging the docker host to confirm it works...\n")
-				dockerCli, err := client.NewClientWithOpts(
-					client.WithHost(dockerHost),
-					client.WithAPIVersionNegotiation(),
-				)
+				dockerCli, err := newDockerClient(&cbdcconfig.Config_Docker{
+					Host:          dockerHost,
+					Context:       dockerContext,
+					CertPath:      curConfig.Docker.CertPath,
+					TLSSkipVerify: curConfig.Docker.TLSSkipVerify,
+				})
 				if err != nil {
 					fmt.Printf("Failed to setup docker client:\n  %s\n", err)
 					dockerEnabled = false
@@ -536,6 +562,25 @@ var initCmd = &cobra.Command{
 					continue
 				}
 
+				if flagDockerAdvertiseAddress != "" {
+					fmt.Printf("Docker advertise address specified via flags:\n  %s\n", flagDockerAdvertiseAddress)
+					dockerAdvertiseAddress = flagDockerAdvertiseAddress
+				} else if remoteAddress := dockerhost.HostAddress(dockerHost); remoteAddress != "" {
+					fmt.Printf("This appears to be a remote docker host.  If its container network is not\n")
+					fmt.Printf("routable from this machine, node ports can be published on the host instead.\n")
+
+					if dockerAdvertiseAddress == "" {
+						dockerAdvertiseAddress = remoteAddress
+					}
+
+					dockerAdvertiseAddress = readString(
+						"What address should be used to reach published ports (`none` to disable)?",
+						dockerAdvertiseAddress, false)
+					if dockerAdvertiseAddress == "none" {
+						dockerAdvertiseAddress = ""
+					}
+				}
+
 				break
 			}
 
@@ -543,6 +588,8 @@ var initCmd = &cobra.Command{
 			curConfig.Docker.Host = dockerHost
 			curConfig.Docker.Network = dockerNetwork
 			curConfig.Docker.ForwardOnly.Set(false)
+			curConfig.Docker.Context = dockerContext
+			curConfig.Docker.AdvertiseAddress = dockerAdvertiseAddress
 			saveConfig()
 		}
 
@@ -1285,6 +1332,8 @@ var initCmd = &cobra.Command{
 		printBaseConfig := func() {
 			fmt.Printf("  Default Deployer: %s\n", curConfig.DefaultDeployer)
 			fmt.Printf("  Default Expiry: %s\n", curConfig.DefaultExpiry.String())
+			fmt.Printf("  Expiry Grace Period: %s\n", curConfig.ExpiryGracePeriod.String())
+			fmt.Printf("  Expiry Hook: %s\n", curConfig.ExpiryHook)
 		}
 		{
 			fmt.Printf("-- Base Configuration\n")
@@ -1318,6 +1367,19 @@ var initCmd = &cobra.Command{
 				curConfig.DefaultExpiry = defaultExpiry
 			}
 
+			{
+				expiryGracePeriod := curConfig.ExpiryGracePeriod
+				if expiryGracePeriod == 0 && !ciConfig {
+					expiryGracePeriod = cbdcconfig.DEFAULT_EXPIRY_GRACE_PERIOD
+				}
+
+				expiryGracePeriod = readDuration(
+					"How long should expired clusters be kept before removal?",
+					expiryGracePeriod)
+
+				curConfig.ExpiryGracePeriod = expiryGracePeriod
+			}
+
 			saveConfig()
 		}
 
@@ -1357,6 +1419,8 @@ func init() {
 	initCmd.Flags().Bool("disable-docker", false, "Disable Docker")
 	initCmd.Flags().String("docker-host", "", "Docker host address to use")
 	initCmd.Flags().String("docker-network", "", "Docker network to use")
+	initCmd.Flags().String("docker-context", "", "Docker cli context to use instead of a docker host")
+	initCmd.Flags().String("docker-advertise-address", "", "Address of a remote docker host to reach published node ports through")
 	initCmd.Flags().Bool("disable-k8s", false, "Disable K8s")
 	initCmd.Flags().String("cao-tools", "", "CAO tools path to use")
 	initCmd.Flags().String("kube-config", "", "Kubeconfig file to use")
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	interruptOnce      sync.Once
+	interruptCtx       context.Context
+	interruptSignal    os.Signal
+	forceExitSuspended int
+	interruptLock      sync.Mutex
+)
+
+// getInterruptContext returns a context which is cancelled once we receive
+// SIGINT or SIGTERM, giving long operations the chance to clean up after
+// themselves.  A second signal exits immediately, unless this has been
+// suspended with SuspendForceExit.
+func getInterruptContext(logger *zap.Logger) context.Context {
+	interruptOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		interruptCtx = ctx
+
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+		go func() {
+			sig := <-signals
+
+			interruptLock.Lock()
+			interruptSignal = sig
+			interruptLock.Unlock()
+
+			logger.Warn("interrupted, cleaning up (interrupt again to exit immediately)",
+				zap.String("signal", sig.String()))
+			cancel()
+
+			for sig := range signals {
+				interruptLock.Lock()
+				suspended := forceExitSuspended > 0
+				interruptLock.Unlock()
+
+				if suspended {
+					logger.Warn("interrupted again, but still cleaning up, please wait",
+						zap.String("signal", sig.String()))
+					continue
+				}
+
+				logger.Warn("interrupted again, exiting without cleaning up",
+					zap.String("signal", sig.String()))
+				os.Exit(exitCodeForSignal(sig))
+			}
+		}()
+	})
+
+	return interruptCtx
+}
+
+// SuspendForceExit stops further signals from exiting immediately until the
+// returned function is called, for commands which must not be abandoned
+// part way through, such as while tearing down a cluster.
+func (h *CmdHelper) SuspendForceExit() func() {
+	interruptLock.Lock()
+	forceExitSuspended++
+	interruptLock.Unlock()
+
+	var resumeOnce sync.Once
+	return func() {
+		resumeOnce.Do(func() {
+			interruptLock.Lock()
+			forceExitSuspended--
+			interruptLock.Unlock()
+		})
+	}
+}
+
+// getInterruptSignal returns the signal which interrupted us, if any.
+func getInterruptSignal() os.Signal {
+	interruptLock.Lock()
+	defer interruptLock.Unlock()
+
+	return interruptSignal
+}
+
+// IsInterrupted indicates that we received SIGINT or SIGTERM.
+func (h *CmdHelper) IsInterrupted() bool {
+	return getInterruptSignal() != nil
+}
+
+// FatalIfInterrupted exits with the exit code of the signal which
+// interrupted us, if we were interrupted.
+func (h *CmdHelper) FatalIfInterrupted(err error) {
+	logger := h.GetLogger()
+
+	sig := getInterruptSignal()
+	if sig == nil {
+		return
+	}
+
+	logger.Error("operation was interrupted", zap.Error(err))
+	exitWithCode(exitCodeForSignal(sig))
+}
+
+// exitCodeForSignal returns the exit code shells use for a process which
+// was terminated by a signal.
+func exitCodeForSignal(sig os.Signal) int {
+	if sysSig, ok := sig.(syscall.Signal); ok {
+		return 128 + int(sysSig)
+	}
+	return ExitFailure
+}
+
+// InterruptedCluster is a cluster which was left behind by an interrupted
+// command, because it could not be removed at the time.
+type InterruptedCluster struct {
+	Deployer      string    `json:"deployer"`
+	ClusterID     string    `json:"cluster_id"`
+	Command       string    `json:"command"`
+	InterruptedAt time.Time `json:"interrupted_at"`
+}
+
+func getInterruptedStatePath() (string, error) {
+	cacheBasePath, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find user cache path")
+	}
+
+	return filepath.Join(cacheBasePath, "cbdinocluster", "interrupted.json"), nil
+}
+
+// LoadInterruptedClusters loads the clusters which interrupted commands
+// failed to remove, which the cleanup command retries removing.
+func (h *CmdHelper) LoadInterruptedClusters() ([]*InterruptedCluster, error) {
+	statePath, err := getInterruptedStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	stateBytes, err := os.ReadFile(statePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read interrupted state")
+	}
+
+	var clusters []*InterruptedCluster
+	err = json.Unmarshal(stateBytes, &clusters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse interrupted state")
+	}
+
+	return clusters, nil
+}
+
+// SaveInterruptedClusters replaces the persisted interrupted clusters.
+func (h *CmdHelper) SaveInterruptedClusters(clusters []*InterruptedCluster) error {
+	statePath, err := getInterruptedStatePath()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		err := os.Remove(statePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrap(err, "failed to remove interrupted state")
+		}
+		return nil
+	}
+
+	stateBytes, err := json.MarshalIndent(clusters, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal interrupted state")
+	}
+
+	err = os.MkdirAll(filepath.Dir(statePath), 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create interrupted state directory")
+	}
+
+	err = os.WriteFile(statePath, stateBytes, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write interrupted state")
+	}
+
+	return nil
+}
+
+// TrackPartialClusters returns a context through which deployers report the
+// clusters they start to create, and a function to call with the result of
+// the operation once it has finished.  If the operation failed because it
+// was interrupted, the partially created clusters are removed, and any which
+// cannot be removed are persisted so that the cleanup command can retry
+// removing them.  Clusters from an operation which completed are kept, even
+// if the interrupt arrived just afterwards.
+func (h *CmdHelper) TrackPartialClusters(
+	ctx context.Context,
+	deployerName string,
+	deployer deployment.Deployer,
+) (context.Context, func(opErr error)) {
+	logger := h.GetLogger()
+
+	partials := &deployment.PartialClusters{}
+	trackedCtx := deployment.WithPartialClusters(ctx, partials)
+
+	return trackedCtx, func(opErr error) {
+		if opErr == nil || !h.IsInterrupted() {
+			return
+		}
+
+		// the removal must not be cancelled by the interruption itself
+		removeCtx := context.WithoutCancel(ctx)
+
+		var leftovers []*InterruptedCluster
+		for _, clusterID := range partials.ClusterIDs() {
+			logger.Info("removing partially created cluster",
+				zap.String("cluster", clusterID))
+
+			err := deployer.RemoveCluster(removeCtx, clusterID)
+			if err != nil && !errors.Is(err, deployment.ErrClusterNotFound) {
+				logger.Warn("failed to remove partially created cluster",
+					zap.String("cluster", clusterID),
+					zap.Error(err))
+
+				leftovers = append(leftovers, &InterruptedCluster{
+					Deployer:      deployerName,
+					ClusterID:     clusterID,
+					Command:       strings.Join(os.Args, " "),
+					InterruptedAt: time.Now(),
+				})
+				continue
+			}
+
+			h.UnregisterCluster(removeCtx, clusterID)
+		}
+
+		if len(leftovers) == 0 {
+			return
+		}
+
+		existing, err := h.LoadInterruptedClusters()
+		if err != nil {
+			logger.Warn("failed to load interrupted state", zap.Error(err))
+		}
+
+		err = h.SaveInterruptedClusters(append(existing, leftovers...))
+		if err != nil {
+			logger.Warn("failed to persist clusters left behind, they will be removed once they expire",
+				zap.Error(err))
+			return
+		}
+
+		logger.Warn("some clusters were left behind, run `cleanup` to retry removing them",
+			zap.Int("count", len(leftovers)))
+	}
+}
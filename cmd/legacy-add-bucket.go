@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var legacyAddBucketCmd = &cobra.Command{
+	Use:   "add-bucket [flags] cluster",
+	Short: "Adds a bucket, like cbdyncluster add-bucket",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("name")
+		ramQuotaMB, _ := cmd.Flags().GetInt("ram-quota")
+		replicaCount, _ := cmd.Flags().GetInt("replica-count")
+		bucketType, _ := cmd.Flags().GetString("bucket-type")
+		evictionPolicy, _ := cmd.Flags().GetString("eviction-policy")
+		storageBackend, _ := cmd.Flags().GetString("storage-backend")
+
+		if bucketName == "" {
+			logger.Fatal("--name must be specified")
+		}
+		if bucketType == string(deployment.BucketTypeMemcached) && !cmd.Flags().Changed("replica-count") {
+			replicaCount = 0
+		}
+		if storageBackend != "" && storageBackend != "couchstore" && storageBackend != "magma" {
+			logger.Fatal("unexpected storage backend", zap.String("storageBackend", storageBackend))
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		err := deployer.CreateBucket(ctx, cluster.GetID(), &deployment.CreateBucketOptions{
+			Name:           bucketName,
+			RamQuotaMB:     ramQuotaMB,
+			NumReplicas:    replicaCount,
+			BucketType:     deployment.BucketType(bucketType),
+			EvictionPolicy: evictionPolicy,
+			StorageBackend: storageBackend,
+		})
+		if err != nil {
+			logger.Fatal("failed to create bucket", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	legacyCmd.AddCommand(legacyAddBucketCmd)
+
+	legacyAddBucketCmd.Flags().String("name", "", "The name of the bucket")
+	legacyAddBucketCmd.Flags().Int("ram-quota", 256, "The RAM quota of the bucket in MB")
+	legacyAddBucketCmd.Flags().Int("replica-count", 1, "The number of replicas of the bucket")
+	legacyAddBucketCmd.Flags().String("bucket-type", "couchbase", "The type of the bucket (couchbase, ephemeral, memcached)")
+	legacyAddBucketCmd.Flags().String("eviction-policy", "", "The eviction policy of the bucket")
+	legacyAddBucketCmd.Flags().String("storage-backend", "", "The storage backend of the bucket (couchstore, magma)")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var legacyAllocateCmd = &cobra.Command{
+	Use:   "allocate",
+	Short: "Allocates a cluster, like cbdyncluster allocate",
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		numNodes, _ := cmd.Flags().GetInt("num-nodes")
+		serverVersion, _ := cmd.Flags().GetString("server-version")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		platform, _ := cmd.Flags().GetString("platform")
+
+		if serverVersion == "" {
+			logger.Fatal("--server-version must be specified")
+		}
+		if numNodes <= 0 {
+			logger.Fatal("--num-nodes must be at least 1")
+		}
+
+		deployerName := ""
+		switch platform {
+		case "", "docker":
+			deployerName = "docker"
+		case "cloud", "capella":
+			deployerName = "cloud"
+		default:
+			logger.Fatal("unsupported platform", zap.String("platform", platform))
+		}
+
+		// cbdyncluster clusters were set up separately, ours come up with the
+		// services that are usually set up for them.
+		def := &clusterdef.Cluster{
+			Deployer: deployerName,
+			Expiry:   timeout,
+			NodeGroups: []*clusterdef.NodeGroup{
+				{
+					Count:   numNodes,
+					Version: serverVersion,
+					Services: []clusterdef.Service{
+						clusterdef.KvService,
+						clusterdef.QueryService,
+						clusterdef.IndexService,
+						clusterdef.SearchService,
+					},
+				},
+			},
+		}
+
+		helper.ResolveVersionNames(ctx, def)
+
+		deployer := helper.GetDeployerByName(ctx, deployerName)
+
+		progressCtx, finishProgress := helper.StartProgress(ctx,
+			"allocating cluster", "allocate-"+deployerName)
+		trackedCtx, finishPartials := helper.TrackPartialClusters(progressCtx, deployerName, deployer)
+		cluster, err := deployer.NewCluster(trackedCtx, def)
+		finishPartials(err)
+		finishProgress(err == nil)
+		if err != nil {
+			helper.FatalIfInterrupted(err)
+			logger.Fatal("cluster deployment failed", zap.Error(err))
+		}
+
+		helper.RegisterCluster(ctx, deployerName, cluster, nil)
+
+		fmt.Printf("%s\n", cluster.GetID())
+	},
+}
+
+func init() {
+	legacyCmd.AddCommand(legacyAllocateCmd)
+
+	legacyAllocateCmd.Flags().Int("num-nodes", 3, "The number of nodes to allocate")
+	legacyAllocateCmd.Flags().String("server-version", "", "The server version to allocate")
+	legacyAllocateCmd.Flags().Duration("timeout", 1*time.Hour, "The time to keep this cluster allocated for")
+	legacyAllocateCmd.Flags().String("platform", "docker", "The platform to allocate on (docker, capella)")
+}
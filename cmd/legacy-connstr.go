@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var legacyConnstrCmd = &cobra.Command{
+	Use:   "connstr [flags] cluster",
+	Short: "Gets a connection string, like cbdyncluster connstr",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		useSSL, _ := cmd.Flags().GetBool("ssl")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		connectInfo, err := deployer.GetConnectInfo(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get connect info", zap.Error(err))
+		}
+
+		connStr := connectInfo.ConnStr
+		if useSSL {
+			connStr = connectInfo.ConnStrTls
+		}
+		if connStr == "" {
+			logger.Fatal("endpoint is unavailable", zap.Bool("ssl", useSSL))
+		}
+
+		fmt.Printf("%s\n", connStr)
+	},
+}
+
+func init() {
+	legacyCmd.AddCommand(legacyConnstrCmd)
+
+	legacyConnstrCmd.Flags().Bool("ssl", false, "Requests a TLS connection string")
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var legacyPsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "Lists clusters, like cbdyncluster ps",
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		var clusters []deployment.ClusterInfo
+		for deployerName, deployer := range helper.GetAllDeployers(ctx) {
+			deployerClusters, err := deployer.ListClusters(ctx)
+			if err != nil {
+				logger.Warn("failed to list clusters",
+					zap.String("deployer", deployerName),
+					zap.Error(err))
+				continue
+			}
+
+			clusters = append(clusters, deployerClusters...)
+		}
+
+		sort.Slice(clusters, func(i, j int) bool {
+			return clusters[i].GetID() < clusters[j].GetID()
+		})
+
+		fmt.Printf("Clusters:\n")
+		for _, cluster := range clusters {
+			expiryStr := "none"
+			if expiry := cluster.GetExpiry(); !expiry.IsZero() {
+				expiryStr = fmt.Sprintf("%s (%s)",
+					expiry.Format(time.RFC3339),
+					time.Until(expiry).Round(time.Second))
+			}
+
+			fmt.Printf("  %s [Purpose: %s, Expiry: %s]\n",
+				cluster.GetID(), cluster.GetPurpose(), expiryStr)
+			for _, node := range cluster.GetNodes() {
+				fmt.Printf("    %-16s %-20s %s\n",
+					node.GetResourceID(), node.GetName(), node.GetIPAddress())
+			}
+		}
+	},
+}
+
+func init() {
+	legacyCmd.AddCommand(legacyPsCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var legacyRmCmd = &cobra.Command{
+	Use:   "rm [cluster...]",
+	Short: "Removes clusters, like cbdyncluster rm",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		for _, clusterID := range args {
+			deployerName, deployer, cluster := helper.IdentifyCluster(ctx, clusterID)
+
+			progressCtx, finishProgress := helper.StartProgress(ctx,
+				"removing cluster", "remove-"+deployerName)
+			err := deployer.RemoveCluster(progressCtx, cluster.GetID())
+			finishProgress(err == nil)
+			if err != nil {
+				logger.Fatal("failed to remove cluster",
+					zap.String("cluster", cluster.GetID()),
+					zap.Error(err))
+			}
+
+			helper.UnregisterCluster(ctx, cluster.GetID())
+		}
+	},
+}
+
+func init() {
+	legacyCmd.AddCommand(legacyRmCmd)
+}
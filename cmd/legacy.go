@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// legacyBinaryName is the name of the original cbdyncluster binary.  When we
+// are invoked under this name, the legacy commands are used directly.
+const legacyBinaryName = "cbdyncluster"
+
+var legacyCmd = &cobra.Command{
+	Use:   "legacy",
+	Short: "Provides the commands of the original cbdyncluster, for existing scripts",
+	Long: "Provides the commands of the original cbdyncluster with the same flags, so that\n" +
+		"existing scripts can migrate by swapping the binary.  These commands are used\n" +
+		"directly when cbdinocluster is invoked as `" + legacyBinaryName + "`.",
+	Run: nil,
+}
+
+func init() {
+	rootCmd.AddCommand(legacyCmd)
+}
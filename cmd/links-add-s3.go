@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -21,19 +22,50 @@ var linksS3Cmd = &cobra.Command{
 		endpoint, _ := cmd.Flags().GetString("endpoint")
 		accessKey, _ := cmd.Flags().GetString("access-key")
 		secretKey, _ := cmd.Flags().GetString("secret-key")
+		useMinio, _ := cmd.Flags().GetBool("minio")
 
 		if linkName == "" {
 			logger.Fatal("you must give the link a name")
 		}
 
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		if dockerDeployer, ok := deployer.(*dockerdeploy.Deployer); ok {
+			if useMinio {
+				minio, err := dockerDeployer.GetMinio(ctx, cluster.GetID())
+				if err != nil {
+					logger.Fatal("failed to get minio", zap.Error(err))
+				}
+
+				region = minio.Region
+				endpoint = minio.Endpoint
+				accessKey = minio.AccessKey
+				secretKey = minio.SecretKey
+			}
+
+			if region == "" {
+				logger.Fatal("you must specify a region")
+			}
+
+			err := dockerDeployer.CreateS3Link(ctx, cluster.GetID(), linkName, region, endpoint, accessKey, secretKey)
+			if err != nil {
+				logger.Fatal("failed to setup S3 link", zap.Error(err))
+			}
+
+			return
+		}
+
 		if region == "" {
 			logger.Fatal("you must specify an AWS region")
 		}
-		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
 
 		cloudDeployer, ok := deployer.(*clouddeploy.Deployer)
 		if !ok {
-			logger.Fatal("links s3 is only supported for cloud deployments")
+			logger.Fatal("links s3 is only supported for cloud and docker deployments")
+		}
+
+		if useMinio {
+			logger.Fatal("minio links are only supported for docker deployments")
 		}
 
 		if accessKey == "" && secretKey == "" {
@@ -57,4 +89,5 @@ func init() {
 	linksS3Cmd.Flags().String("endpoint", "", "The S3 endpoint. Optional.")
 	linksS3Cmd.Flags().String("access-key", "", "AWS AccessKeyId to use. Will use the cbdino config values if not flag not provided.")
 	linksS3Cmd.Flags().String("secret-key", "", "AWS SecretKey to use. Will use the cbdino config values if not flag not provided.")
+	linksS3Cmd.Flags().Bool("minio", false, "Links the MinIO deployed with `tools minio deploy` to a docker cluster.")
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -17,12 +18,14 @@ var linksDropCmd = &cobra.Command{
 		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
 		linkName := args[1]
 
-		cloudDeployer, ok := deployer.(*clouddeploy.Deployer)
-		if !ok {
-			logger.Fatal("links is only supported for cloud deployments")
+		var err error
+		if dockerDeployer, ok := deployer.(*dockerdeploy.Deployer); ok {
+			err = dockerDeployer.DropLink(ctx, cluster.GetID(), linkName)
+		} else if cloudDeployer, ok := deployer.(*clouddeploy.Deployer); ok {
+			err = cloudDeployer.DropLink(ctx, cluster.GetID(), linkName)
+		} else {
+			logger.Fatal("links is only supported for cloud and docker deployments")
 		}
-
-		err := cloudDeployer.DropLink(ctx, cluster.GetID(), linkName)
 		if err != nil {
 			logger.Fatal("failed to drop link", zap.Error(err))
 		}
@@ -2,12 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/clusterregistry"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 )
 
 type deployerCluster struct {
@@ -23,7 +31,42 @@ type ClusterListOutput_Item struct {
 	State    string                   `json:"state"`
 	Expiry   *time.Time               `json:"expiry,omitempty"`
 	Deployer string                   `json:"deployer"`
+	Owner    string                   `json:"owner,omitempty"`
 	Nodes    []ClusterListOutput_Node `json:"nodes"`
+
+	EstimatedHourlyCost *float64 `json:"estimated_hourly_cost,omitempty"`
+	EstimatedCost       *float64 `json:"estimated_cost,omitempty"`
+
+	Jobs []ClusterListOutput_Job `json:"jobs,omitempty"`
+
+	MemoryQuotas  *ClusterListOutput_MemoryQuotas  `json:"memory_quotas,omitempty"`
+	ServiceGroups []ClusterListOutput_ServiceGroup `json:"service_groups,omitempty"`
+}
+
+type ClusterListOutput_MemoryQuotas struct {
+	KvMB       int `json:"kv_mb"`
+	IndexMB    int `json:"index_mb"`
+	FtsMB      int `json:"fts_mb"`
+	CbasMB     int `json:"cbas_mb"`
+	EventingMB int `json:"eventing_mb"`
+}
+
+type ClusterListOutput_ServiceGroup struct {
+	Count        int            `json:"count"`
+	Services     []string       `json:"services"`
+	InstanceType string         `json:"instance_type,omitempty"`
+	Cpu          int            `json:"cpu,omitempty"`
+	MemoryGB     int            `json:"memory_gb,omitempty"`
+	DiskType     string         `json:"disk_type,omitempty"`
+	DiskSizeGB   int            `json:"disk_size_gb,omitempty"`
+	QuotasMB     map[string]int `json:"quotas_mb,omitempty"`
+}
+
+type ClusterListOutput_Job struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Progress    int    `json:"progress"`
+	CurrentStep string `json:"current_step"`
 }
 
 type ClusterListOutput_Node struct {
@@ -32,6 +75,79 @@ type ClusterListOutput_Node struct {
 	IPAddress     string `json:"ip_address"`
 	ResourceID    string `json:"resource_id"`
 	IsClusterNode bool   `json:"is_cluster_node"`
+	Health        string `json:"health,omitempty"`
+	OTPNode       string `json:"otp_node,omitempty"`
+	NodeUUID      string `json:"node_uuid,omitempty"`
+	NodeGroup     string `json:"node_group,omitempty"`
+
+	Services    []string `json:"services,omitempty"`
+	ServerGroup string   `json:"server_group,omitempty"`
+
+	Resources *ClusterListOutput_NodeResources `json:"resources,omitempty"`
+}
+
+type ClusterListOutput_NodeResources struct {
+	CpuPercent      float64 `json:"cpu_percent"`
+	CpuLimit        float64 `json:"cpu_limit"`
+	MemUsedBytes    uint64  `json:"mem_used_bytes"`
+	MemLimitBytes   uint64  `json:"mem_limit_bytes"`
+	DiskUsedBytes   int64   `json:"disk_used_bytes"`
+	VolumeUsedBytes int64   `json:"volume_used_bytes"`
+}
+
+func formatBytes(numBytes uint64) string {
+	const unit = 1024
+	if numBytes < unit {
+		return fmt.Sprintf("%dB", numBytes)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := numBytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(numBytes)/float64(div), "KMGTPE"[exp])
+}
+
+func formatServices(services []clusterdef.Service) string {
+	return strings.Join(lo.Map(services, func(service clusterdef.Service, _ int) string {
+		return string(service)
+	}), ",")
+}
+
+func formatMemoryQuotas(quotas *clustercontrol.MemoryQuotas) string {
+	var quotaStrs []string
+	addQuota := func(service clusterdef.Service, quotaMB int) {
+		if quotaMB > 0 {
+			quotaStrs = append(quotaStrs, fmt.Sprintf("%s %dMB", service, quotaMB))
+		}
+	}
+	addQuota(clusterdef.KvService, quotas.KvMemoryQuotaMB)
+	addQuota(clusterdef.IndexService, quotas.IndexMemoryQuotaMB)
+	addQuota(clusterdef.SearchService, quotas.FtsMemoryQuotaMB)
+	addQuota(clusterdef.AnalyticsService, quotas.CbasMemoryQuotaMB)
+	addQuota(clusterdef.EventingService, quotas.EventingMemoryQuotaMB)
+	return strings.Join(quotaStrs, ", ")
+}
+
+func formatServiceGroup(group clouddeploy.ServiceGroupInfo) string {
+	var serviceStrs []string
+	for _, service := range group.Services {
+		if quotaMB := group.ServiceQuotasMB[service]; quotaMB > 0 {
+			serviceStrs = append(serviceStrs, fmt.Sprintf("%s %dMB", service, quotaMB))
+		} else {
+			serviceStrs = append(serviceStrs, string(service))
+		}
+	}
+
+	return fmt.Sprintf("%dx %s (%d cpu, %dGB), Disk: %s %dGB, Services: %s",
+		group.Count,
+		group.InstanceType,
+		group.Cpu,
+		group.MemoryGB,
+		group.DiskType,
+		group.DiskSizeGB,
+		strings.Join(serviceStrs, ", "))
 }
 
 var listCmd = &cobra.Command{
@@ -44,6 +160,8 @@ var listCmd = &cobra.Command{
 		ctx := helper.GetContext()
 
 		outputJson, _ := cmd.Flags().GetBool("json")
+		allOwners, _ := cmd.Flags().GetBool("all-owners")
+		verbose, _ := cmd.Flags().GetBool("verbose")
 
 		var wg sync.WaitGroup
 		clustersCh := make(chan *deployerCluster, 1024)
@@ -58,6 +176,26 @@ var listCmd = &cobra.Command{
 				}
 
 				for _, cluster := range deployerClusters {
+					// sampling usage takes a moment, so it is only done when asked for
+					dockerDeployer, isDocker := deployer.(*dockerdeploy.Deployer)
+					dockerCluster, isDockerCluster := cluster.(*dockerdeploy.ClusterInfo)
+					if verbose && isDocker && isDockerCluster {
+						dockerDeployer.PopulateResourceUsage(ctx, dockerCluster)
+						dockerDeployer.PopulateNodeIdentity(ctx, dockerCluster)
+						dockerDeployer.PopulateMemoryQuotas(ctx, dockerCluster)
+					}
+
+					cloudDeployer, isCloud := deployer.(*clouddeploy.Deployer)
+					cloudCluster, isCloudCluster := cluster.(*clouddeploy.ClusterInfo)
+					if isCloud && isCloudCluster {
+						err := cloudDeployer.PopulateActiveJobs(ctx, cloudCluster)
+						if err != nil {
+							logger.Warn("failed to list cluster jobs",
+								zap.String("cluster", cloudCluster.ClusterID),
+								zap.Error(err))
+						}
+					}
+
 					clustersCh <- &deployerCluster{
 						DeployerName: deployerName,
 						Info:         cluster,
@@ -78,6 +216,35 @@ var listCmd = &cobra.Command{
 			clusters = append(clusters, clusterInfo)
 		}
 
+		// with --all-owners, the shared registry provides the owners of our
+		// clusters, as well as the clusters of other machines we cannot see.
+		registeredClusters := make(map[string]*clusterregistry.Entry)
+		var otherClusters []*clusterregistry.Entry
+		if allOwners {
+			registry := helper.GetRegistry(ctx)
+			if registry == nil {
+				logger.Fatal("listing clusters of all owners requires a shared registry to be configured")
+			}
+
+			entries, err := registry.List(ctx)
+			if err != nil {
+				logger.Fatal("failed to list shared registry", zap.Error(err))
+			}
+
+			for _, entry := range entries {
+				registeredClusters[entry.ClusterID] = entry
+			}
+
+			for _, entry := range entries {
+				isLocal := slices.ContainsFunc(clusters, func(cluster *deployerCluster) bool {
+					return cluster.Info.GetID() == entry.ClusterID
+				})
+				if !isLocal {
+					otherClusters = append(otherClusters, entry)
+				}
+			}
+		}
+
 		if !outputJson {
 			fmt.Printf("Clusters:\n")
 			for _, clusterInfo := range clusters {
@@ -90,23 +257,111 @@ var listCmd = &cobra.Command{
 					expiryStr = time.Until(cluster.GetExpiry()).Round(time.Second).String()
 				}
 
-				fmt.Printf("  %s [Type: %s, State: %s, Timeout: %s, Deployer: %s]\n",
+				ownerStr := ""
+				if entry := registeredClusters[cluster.GetID()]; entry != nil {
+					ownerStr = ", Owner: " + entry.Owner
+				}
+
+				fmt.Printf("  %s [Type: %s, State: %s, Timeout: %s, Deployer: %s%s]\n",
 					cluster.GetID(),
 					cluster.GetType(),
 					cluster.GetState(),
 					expiryStr,
-					deployerName)
+					deployerName,
+					ownerStr)
+
+				cloudCluster, ok := cluster.(*clouddeploy.ClusterInfo)
+				if ok && cloudCluster.EstimatedHourlyCost > 0 {
+					fmt.Printf("    Estimated Cost: $%.2f/hour, $%.2f so far\n",
+						cloudCluster.EstimatedHourlyCost,
+						cloudCluster.EstimatedCost())
+				}
+				if ok {
+					for _, job := range cloudCluster.ActiveJobs {
+						fmt.Printf("    Job: %s [Progress: %d%%, Step: %s]\n",
+							job.JobType,
+							job.CompletionPercentage,
+							job.CurrentStep)
+					}
+				}
+				if ok && verbose {
+					for _, group := range cloudCluster.ServiceGroups {
+						fmt.Printf("    Service Group: %s\n", formatServiceGroup(group))
+					}
+				}
+
+				dockerCluster, ok := cluster.(*dockerdeploy.ClusterInfo)
+				if ok && dockerCluster.MemoryQuotas != nil {
+					fmt.Printf("    Memory Quotas: %s\n", formatMemoryQuotas(dockerCluster.MemoryQuotas))
+				}
+
 				for _, node := range cluster.GetNodes() {
 					printId := node.GetID()
 					if !node.IsClusterNode() {
 						printId = "[UTIL] " + printId
 					}
 
-					fmt.Printf("    %-40s %-20s %-20s %s\n",
+					healthStr := ""
+					dockerNode, ok := node.(*dockerdeploy.ClusterNodeInfo)
+					if ok && dockerNode.Health != "" {
+						healthStr = " [" + dockerNode.Health + "]"
+					}
+
+					fmt.Printf("    %-40s %-20s %-20s %s%s\n",
 						printId,
 						node.GetName(),
 						node.GetIPAddress(),
-						node.GetResourceID())
+						node.GetResourceID(),
+						healthStr)
+
+					if node.GetNodeGroup() != "" {
+						fmt.Printf("      [Node Group: %s]\n", node.GetNodeGroup())
+					}
+
+					if node.GetOTPNode() != "" {
+						fmt.Printf("      [OTP Node: %s, UUID: %s]\n",
+							node.GetOTPNode(),
+							node.GetNodeUUID())
+					}
+
+					if ok && len(dockerNode.Services) > 0 {
+						fmt.Printf("      [Services: %s, Server Group: %s]\n",
+							formatServices(dockerNode.Services),
+							dockerNode.ServerGroup)
+					}
+
+					if ok && dockerNode.Usage != nil {
+						usage := dockerNode.Usage
+						fmt.Printf("      [CPU: %.1f%% of %.1f cpus, Mem: %s / %s, Disk: %s, Data Volume: %s]\n",
+							usage.CpuPercent,
+							usage.CpuLimit,
+							formatBytes(usage.MemUsedBytes),
+							formatBytes(usage.MemLimitBytes),
+							formatBytes(uint64(usage.DiskUsedBytes)),
+							formatBytes(uint64(usage.VolumeUsedBytes)))
+					}
+				}
+			}
+
+			if allOwners {
+				fmt.Printf("Other Owners' Clusters:\n")
+				for _, entry := range otherClusters {
+					expiryStr := "none"
+					if !entry.Expiry.IsZero() {
+						expiryStr = time.Until(entry.Expiry).Round(time.Second).String()
+					}
+
+					fmt.Printf("  %s [Owner: %s, Timeout: %s, Deployer: %s]\n",
+						entry.ClusterID,
+						entry.Owner,
+						expiryStr,
+						entry.Deployer)
+					if entry.Purpose != "" {
+						fmt.Printf("    Purpose: %s\n", entry.Purpose)
+					}
+					if entry.ConnStr != "" {
+						fmt.Printf("    %s\n", entry.ConnStr)
+					}
 				}
 			}
 		} else {
@@ -119,22 +374,121 @@ var listCmd = &cobra.Command{
 					Deployer: cluster.DeployerName,
 				}
 
+				if entry := registeredClusters[cluster.Info.GetID()]; entry != nil {
+					clusterItem.Owner = entry.Owner
+				}
+
 				expiry := cluster.Info.GetExpiry()
 				if !expiry.IsZero() {
 					clusterItem.Expiry = &expiry
 				}
 
+				cloudCluster, ok := cluster.Info.(*clouddeploy.ClusterInfo)
+				if ok && cloudCluster.EstimatedHourlyCost > 0 {
+					hourlyCost := cloudCluster.EstimatedHourlyCost
+					totalCost := cloudCluster.EstimatedCost()
+					clusterItem.EstimatedHourlyCost = &hourlyCost
+					clusterItem.EstimatedCost = &totalCost
+				}
+				if ok {
+					for _, job := range cloudCluster.ActiveJobs {
+						clusterItem.Jobs = append(clusterItem.Jobs, ClusterListOutput_Job{
+							ID:          job.ID,
+							Type:        job.JobType,
+							Progress:    job.CompletionPercentage,
+							CurrentStep: job.CurrentStep,
+						})
+					}
+				}
+				if ok && verbose {
+					for _, group := range cloudCluster.ServiceGroups {
+						groupItem := ClusterListOutput_ServiceGroup{
+							Count:        group.Count,
+							InstanceType: group.InstanceType,
+							Cpu:          group.Cpu,
+							MemoryGB:     group.MemoryGB,
+							DiskType:     group.DiskType,
+							DiskSizeGB:   group.DiskSizeGB,
+							QuotasMB:     make(map[string]int),
+						}
+						for _, service := range group.Services {
+							groupItem.Services = append(groupItem.Services, string(service))
+						}
+						for service, quotaMB := range group.ServiceQuotasMB {
+							groupItem.QuotasMB[string(service)] = quotaMB
+						}
+						clusterItem.ServiceGroups = append(clusterItem.ServiceGroups, groupItem)
+					}
+				}
+
+				dockerCluster, ok := cluster.Info.(*dockerdeploy.ClusterInfo)
+				if ok && dockerCluster.MemoryQuotas != nil {
+					quotas := dockerCluster.MemoryQuotas
+					clusterItem.MemoryQuotas = &ClusterListOutput_MemoryQuotas{
+						KvMB:       quotas.KvMemoryQuotaMB,
+						IndexMB:    quotas.IndexMemoryQuotaMB,
+						FtsMB:      quotas.FtsMemoryQuotaMB,
+						CbasMB:     quotas.CbasMemoryQuotaMB,
+						EventingMB: quotas.EventingMemoryQuotaMB,
+					}
+				}
+
 				for _, node := range cluster.Info.GetNodes() {
-					clusterItem.Nodes = append(clusterItem.Nodes, ClusterListOutput_Node{
+					nodeItem := ClusterListOutput_Node{
 						ID:            node.GetID(),
 						Name:          node.GetName(),
 						IPAddress:     node.GetIPAddress(),
 						ResourceID:    node.GetResourceID(),
 						IsClusterNode: node.IsClusterNode(),
-					})
+						OTPNode:       node.GetOTPNode(),
+						NodeUUID:      node.GetNodeUUID(),
+						NodeGroup:     node.GetNodeGroup(),
+					}
+
+					dockerNode, ok := node.(*dockerdeploy.ClusterNodeInfo)
+					if ok {
+						nodeItem.Health = dockerNode.Health
+						nodeItem.ServerGroup = dockerNode.ServerGroup
+						for _, service := range dockerNode.Services {
+							nodeItem.Services = append(nodeItem.Services, string(service))
+						}
+
+						if dockerNode.Usage != nil {
+							nodeItem.Resources = &ClusterListOutput_NodeResources{
+								CpuPercent:      dockerNode.Usage.CpuPercent,
+								CpuLimit:        dockerNode.Usage.CpuLimit,
+								MemUsedBytes:    dockerNode.Usage.MemUsedBytes,
+								MemLimitBytes:   dockerNode.Usage.MemLimitBytes,
+								DiskUsedBytes:   dockerNode.Usage.DiskUsedBytes,
+								VolumeUsedBytes: dockerNode.Usage.VolumeUsedBytes,
+							}
+						}
+					}
+
+					clusterItem.Nodes = append(clusterItem.Nodes, nodeItem)
 				}
 				out = append(out, clusterItem)
 			}
+
+			// clusters of other owners are not visible to our deployers,
+			// so we only know what was recorded in the registry.
+			for _, entry := range otherClusters {
+				clusterItem := ClusterListOutput_Item{
+					ID:       entry.ClusterID,
+					Type:     string(deployment.ClusterTypeUnknown),
+					State:    "unknown",
+					Deployer: entry.Deployer,
+					Owner:    entry.Owner,
+				}
+
+				if !entry.Expiry.IsZero() {
+					expiry := entry.Expiry
+					clusterItem.Expiry = &expiry
+				}
+
+				out = append(out, clusterItem)
+			}
+
 			helper.OutputJson(out)
 		}
 	},
@@ -142,4 +496,6 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().Bool("all-owners", false, "Also list clusters of other owners from the shared registry")
 }
@@ -34,7 +34,10 @@ var modifyCmd = &cobra.Command{
 			logger.Fatal("cannot update the deployer for a cluster")
 		}
 
-		err = deployer.ModifyCluster(ctx, cluster.GetID(), def)
+		progressCtx, finishProgress := helper.StartProgress(ctx,
+			"modifying cluster", "modify-"+deployerName)
+		err = deployer.ModifyCluster(progressCtx, cluster.GetID(), def)
+		finishProgress(err == nil)
 		if err != nil {
 			logger.Fatal("failed to update cluster", zap.Error(err))
 		}
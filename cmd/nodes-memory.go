@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type NodesMemoryOutput []NodesMemoryOutput_Item
+
+type NodesMemoryOutput_Item struct {
+	NodeID    string `json:"nodeId"`
+	IPAddress string `json:"ipAddress"`
+	LimitMB   int    `json:"limitMb,omitempty"`
+	OomKills  int    `json:"oomKills"`
+}
+
+var nodesMemoryCmd = &cobra.Command{
+	Use:   "memory [flags] cluster",
+	Short: "Shows the memory limits of the nodes and how many of their processes were killed for exceeding them",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("node memory is only supported for docker clusters")
+		}
+
+		nodeMemories, err := dockerDeployer.GetNodeMemory(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get node memory", zap.Error(err))
+		}
+
+		if !outputJson {
+			for _, nodeMemory := range nodeMemories {
+				fmt.Printf("%s [%s]\n", nodeMemory.NodeID, nodeMemory.IPAddress)
+				if nodeMemory.LimitMB > 0 {
+					fmt.Printf("  limit: %dMB\n", nodeMemory.LimitMB)
+				} else {
+					fmt.Printf("  limit: none\n")
+				}
+				fmt.Printf("  oom kills: %d\n", nodeMemory.OomKills)
+			}
+		} else {
+			var out NodesMemoryOutput
+			for _, nodeMemory := range nodeMemories {
+				out = append(out, NodesMemoryOutput_Item{
+					NodeID:    nodeMemory.NodeID,
+					IPAddress: nodeMemory.IPAddress,
+					LimitMB:   nodeMemory.LimitMB,
+					OomKills:  nodeMemory.OomKills,
+				})
+			}
+			helper.OutputJson(out)
+		}
+
+		for _, nodeMemory := range nodeMemories {
+			if nodeMemory.OomKills > 0 {
+				logger.Warn("processes of node were killed for exceeding its memory limit",
+					zap.String("node", nodeMemory.NodeID),
+					zap.Int("oomKills", nodeMemory.OomKills))
+			}
+		}
+	},
+}
+
+func init() {
+	nodesCmd.AddCommand(nodesMemoryCmd)
+}
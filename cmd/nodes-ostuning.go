@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type NodesOsTuningOutput []NodesOsTuningOutput_Item
+
+type NodesOsTuningOutput_Item struct {
+	NodeID    string                         `json:"nodeId"`
+	IPAddress string                         `json:"ipAddress"`
+	Tuned     bool                           `json:"tuned"`
+	Results   []*dockerdeploy.OsTuningResult `json:"results,omitempty"`
+}
+
+var nodesOsTuningCmd = &cobra.Command{
+	Use:   "os-tuning [flags] cluster",
+	Short: "Shows the OS settings which were applied to the nodes",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("os tuning is only supported for docker clusters")
+		}
+
+		nodeTunings, err := dockerDeployer.GetOsTuning(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get os tuning", zap.Error(err))
+		}
+
+		if !outputJson {
+			for _, nodeTuning := range nodeTunings {
+				fmt.Printf("%s [%s]\n", nodeTuning.NodeID, nodeTuning.IPAddress)
+				if nodeTuning.State == nil {
+					fmt.Printf("  not tuned\n")
+					continue
+				}
+
+				for _, result := range nodeTuning.State.Results {
+					if result.Applied {
+						fmt.Printf("  %s: %s (applied)\n", result.Name, result.Value)
+					} else {
+						fmt.Printf("  %s: %s (not applied, actual: %s)\n", result.Name, result.Value, result.Actual)
+					}
+				}
+			}
+		} else {
+			var out NodesOsTuningOutput
+			for _, nodeTuning := range nodeTunings {
+				outItem := NodesOsTuningOutput_Item{
+					NodeID:    nodeTuning.NodeID,
+					IPAddress: nodeTuning.IPAddress,
+					Tuned:     nodeTuning.State != nil,
+				}
+				if nodeTuning.State != nil {
+					outItem.Results = nodeTuning.State.Results
+				}
+				out = append(out, outItem)
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	nodesCmd.AddCommand(nodesOsTuningCmd)
+}
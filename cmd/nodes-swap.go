@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var nodesSwapCmd = &cobra.Command{
+	Use:   "swap",
+	Short: "Replaces a node with a new node using a swap rebalance",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		newVersion, _ := cmd.Flags().GetString("version")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		node := helper.IdentifyNode(ctx, cluster, args[1])
+
+		nodeID, err := deployer.SwapNode(ctx, cluster.GetID(), node.GetID(), newVersion)
+		if err != nil {
+			logger.Fatal("failed to swap node", zap.Error(err))
+		}
+
+		fmt.Printf("%s\n", nodeID)
+	},
+}
+
+func init() {
+	nodesCmd.AddCommand(nodesSwapCmd)
+
+	nodesSwapCmd.Flags().String("version", "", "The version of the replacement node, defaulting to the version of the old node")
+}
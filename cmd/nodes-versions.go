@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var errVersionDrift = errors.New("version drift")
+
+type NodesVersionsOutput []NodesVersionsOutput_Item
+
+type NodesVersionsOutput_Item struct {
+	NodeID          string `json:"nodeId"`
+	Name            string `json:"name"`
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
+	RunningVersion  string `json:"runningVersion"`
+	Drift           string `json:"drift,omitempty"`
+}
+
+var nodesVersionsCmd = &cobra.Command{
+	Use:   "versions [flags] cluster",
+	Short: "Shows the server version each node is running, and any drift from the version it was deployed with",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		failOnDrift, _ := cmd.Flags().GetBool("fail-on-drift")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		nodeVersions, err := deployer.GetNodeVersions(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get node versions", zap.Error(err))
+		}
+
+		numDrifted := 0
+		var out NodesVersionsOutput
+		for _, nodeVersion := range nodeVersions {
+			drift := nodeVersion.Drift()
+			if drift != "" {
+				numDrifted++
+			}
+
+			out = append(out, NodesVersionsOutput_Item{
+				NodeID:          nodeVersion.NodeID,
+				Name:            nodeVersion.Name,
+				ExpectedVersion: nodeVersion.ExpectedVersion,
+				RunningVersion:  nodeVersion.RunningVersion,
+				Drift:           drift,
+			})
+		}
+
+		if !outputJson {
+			for _, item := range out {
+				fmt.Printf("%s [%s]\n", item.NodeID, item.Name)
+				fmt.Printf("  running: %s\n", item.RunningVersion)
+				if item.ExpectedVersion != "" {
+					fmt.Printf("  deployed with: %s\n", item.ExpectedVersion)
+				}
+				if item.Drift != "" {
+					fmt.Printf("  drift: %s\n", item.Drift)
+				}
+			}
+		} else {
+			helper.OutputJson(out)
+		}
+
+		if numDrifted > 0 {
+			if failOnDrift {
+				logger.Fatal("nodes are not running the version they were deployed with",
+					zap.Error(fmt.Errorf("%w: %d nodes drifted", errVersionDrift, numDrifted)))
+			}
+
+			logger.Warn("nodes are not running the version they were deployed with",
+				zap.Int("numDrifted", numDrifted))
+		}
+	},
+}
+
+func init() {
+	nodesCmd.AddCommand(nodesVersionsCmd)
+
+	nodesVersionsCmd.Flags().Bool("fail-on-drift", false, "Exits with an error if any node has drifted from the version it was deployed with")
+}
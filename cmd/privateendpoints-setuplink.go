@@ -68,6 +68,11 @@ var privateEndpointsSetupLinkCmd = &cobra.Command{
 			logger.Fatal("failed to get private endpoint info", zap.Error(err))
 		}
 
+		// the created resources are tagged with who created them and when they
+		// expire so that cleanup can find them if this run is interrupted
+		owner := helper.GetRegistryOwner(ctx)
+		expiry := cloudCluster.GetExpiry()
+
 		logger.Info("private endpoint details",
 			zap.String("service-name", pe.ServiceName),
 			zap.String("private-dns", pe.PrivateDNS))
@@ -89,6 +94,8 @@ var privateEndpointsSetupLinkCmd = &cobra.Command{
 				ClusterID:   cloudCluster.CloudClusterID,
 				ServiceName: pe.ServiceName,
 				InstanceID:  instanceId,
+				Owner:       owner,
+				Expiry:      expiry,
 			})
 			if err != nil {
 				logger.Fatal("failed to create vpc endpoint", zap.Error(err))
@@ -124,6 +131,8 @@ var privateEndpointsSetupLinkCmd = &cobra.Command{
 				ClusterID:    cloudCluster.ClusterID,
 				ServiceID:    pe.ServiceName,
 				VmResourceID: vmId,
+				Owner:        owner,
+				Expiry:       expiry,
 			})
 			if err != nil {
 				logger.Fatal("failed to create private endpoint", zap.Error(err))
@@ -138,6 +147,8 @@ var privateEndpointsSetupLinkCmd = &cobra.Command{
 				ClusterID:    cloudCluster.ClusterID,
 				PeResourceID: peData.PeResourceID,
 				DnsName:      pe.PrivateDNS,
+				Owner:        owner,
+				Expiry:       expiry,
 			})
 			if err != nil {
 				logger.Fatal("failed to enable private dns", zap.Error(err))
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/cbdcconfig"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var errReadOnly = errors.New("read-only mode")
+
+// readOnlyCommands are the commands which may be run in read-only mode,
+// along with any of their flags which cause them to modify something.
+// Commands which are not listed are refused, so that new commands are
+// only permitted once they are known to be safe.
+var readOnlyCommands = map[string][]string{
+	"list":                           nil,
+	"watch":                          nil,
+	"version":                        nil,
+	"connstr":                        {"new-user"},
+	"get-definition":                 nil,
+	"ip":                             nil,
+	"mgmt":                           nil,
+	"collect-logs":                   nil,
+	"record-stats":                   nil,
+	"validate":                       nil,
+	"def print":                      nil,
+	"images list":                    nil,
+	"images search":                  nil,
+	"versions list":                  nil,
+	"buckets list":                   nil,
+	"nodes versions":                 nil,
+	"nodes memory":                   nil,
+	"collections list":               nil,
+	"users list":                     nil,
+	"udfs libraries list":            nil,
+	"indexes list":                   nil,
+	"indexes advise":                 nil,
+	"xdcr list":                      nil,
+	"transactions get-settings":      nil,
+	"transactions list-atrs":         nil,
+	"allow-list list":                nil,
+	"certificates get-ca":            nil,
+	"certificates get-gateway-ca":    nil,
+	"certificates status":            nil,
+	"backups list":                   nil,
+	"backups jobs":                   nil,
+	"backups logs":                   nil,
+	"snapshots list":                 nil,
+	"ingresses connstr":              nil,
+	"ingresses mgmt":                 nil,
+	"private-endpoints connstr":      nil,
+	"private-endpoints mgmt":         nil,
+	"private-endpoints service-name": nil,
+	"capella envs":                   nil,
+	"capella jobs":                   nil,
+	"capella orgs list":              nil,
+	"capella projects list":          nil,
+	"capella peering list":           nil,
+	"capella peering route-info":     nil,
+	"data export":                    nil,
+	"debug settings-dump":            nil,
+	"debug settings-diff":            nil,
+	"config doctor":                  nil,
+	"config effective":               nil,
+	"tools cao events":               nil,
+	"tools cao diagnostics":          nil,
+	"tools minio info":               nil,
+	"tools self-ident":               nil,
+	"help":                           nil,
+	"legacy ps":                      nil,
+	"legacy connstr":                 nil,
+	// the steps of a batch are each checked as they are run
+	"batch run":             nil,
+	"completion":            nil,
+	"completion bash":       nil,
+	"completion zsh":        nil,
+	"completion fish":       nil,
+	"completion powershell": nil,
+	"__complete":            nil,
+}
+
+func isReadOnly() bool {
+	readOnly, _ := rootCmd.Flags().GetBool("read-only")
+	if readOnly {
+		return true
+	}
+
+	// the config is loaded directly, as commands such as `init` must work
+	// before there is any config
+	config, err := cbdcconfig.Load(context.Background())
+	if err != nil || config == nil {
+		return false
+	}
+
+	return config.ReadOnly.Value()
+}
+
+// checkReadOnly refuses to run commands which may modify clusters when the
+// CLI is in read-only mode.
+func checkReadOnly(cmd *cobra.Command) {
+	if !isReadOnly() {
+		return
+	}
+
+	cmdPath := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+
+	modifyingFlags, ok := readOnlyCommands[cmdPath]
+	if ok {
+		for _, flagName := range modifyingFlags {
+			if cmd.Flags().Changed(flagName) {
+				ok = false
+			}
+		}
+	}
+	if ok {
+		return
+	}
+
+	helper := CmdHelper{}
+	logger := helper.GetLogger()
+	logger.Fatal("command is not permitted in read-only mode",
+		zap.String("command", cmdPath),
+		zap.Error(fmt.Errorf("%w: `%s` may modify clusters", errReadOnly, cmdPath)))
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type RecordStatsOutput_Sample struct {
+	Time  time.Time                `json:"time"`
+	Nodes []RecordStatsOutput_Node `json:"nodes"`
+}
+
+type RecordStatsOutput_Node struct {
+	NodeID            string  `json:"node_id"`
+	Name              string  `json:"name"`
+	CpuPercent        float64 `json:"cpu_percent"`
+	MemUsedBytes      uint64  `json:"mem_used_bytes"`
+	MemLimitBytes     uint64  `json:"mem_limit_bytes"`
+	DiskUsedBytes     int64   `json:"disk_used_bytes"`
+	ItemCount         int64   `json:"item_count"`
+	OpsPerSec         float64 `json:"ops_per_sec"`
+	DataMemUsedBytes  int64   `json:"data_mem_used_bytes"`
+	DataDiskUsedBytes int64   `json:"data_disk_used_bytes"`
+}
+
+var recordStatsCsvHeader = []string{
+	"time", "node_id", "name",
+	"cpu_percent", "mem_used_bytes", "mem_limit_bytes", "disk_used_bytes",
+	"item_count", "ops_per_sec", "data_mem_used_bytes", "data_disk_used_bytes",
+}
+
+var recordStatsCmd = &cobra.Command{
+	Use:   "record-stats [flags] cluster out-path",
+	Short: "Records the resource usage of a cluster until it is removed or interrupted",
+	Long: "Periodically samples the cpu, memory and disk usage of the containers of a cluster " +
+		"along with some basic couchbase stats, writing them to a CSV or JSON report.  " +
+		"Recording stops when the cluster is removed or the command is interrupted.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		format, _ := cmd.Flags().GetString("format")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		outPath := args[1]
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("recording stats is only supported for docker clusters")
+		}
+
+		if format == "" {
+			format = "csv"
+			if filepath.Ext(outPath) == ".json" {
+				format = "json"
+			}
+		}
+		if format != "csv" && format != "json" {
+			logger.Fatal("unsupported report format", zap.String("format", format))
+		}
+
+		outFile, err := os.Create(outPath)
+		if err != nil {
+			logger.Fatal("failed to create report file", zap.Error(err))
+		}
+		defer outFile.Close()
+
+		csvWriter := csv.NewWriter(outFile)
+		if format == "csv" {
+			err := csvWriter.Write(recordStatsCsvHeader)
+			if err != nil {
+				logger.Fatal("failed to write report header", zap.Error(err))
+			}
+			csvWriter.Flush()
+		}
+
+		ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+		defer cancel()
+
+		logger.Info("recording stats",
+			zap.String("cluster", cluster.GetID()),
+			zap.Duration("interval", interval))
+
+		var samples []RecordStatsOutput_Sample
+		for {
+			sample, err := dockerDeployer.SampleResourceUsage(ctx, cluster.GetID())
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+
+				// the cluster going away is the normal end of a recording
+				logger.Info("stopping recording", zap.Error(err))
+				break
+			}
+
+			outSample := RecordStatsOutput_Sample{
+				Time: sample.Time,
+			}
+			for _, node := range sample.Nodes {
+				outSample.Nodes = append(outSample.Nodes, RecordStatsOutput_Node{
+					NodeID:            node.NodeID,
+					Name:              node.Name,
+					CpuPercent:        node.CpuPercent,
+					MemUsedBytes:      node.MemUsedBytes,
+					MemLimitBytes:     node.MemLimitBytes,
+					DiskUsedBytes:     node.DiskUsedBytes,
+					ItemCount:         node.ItemCount,
+					OpsPerSec:         node.OpsPerSec,
+					DataMemUsedBytes:  node.DataMemUsedBytes,
+					DataDiskUsedBytes: node.DataDiskUsedBytes,
+				})
+			}
+
+			if format == "csv" {
+				// rows are flushed as we go so the report survives us being killed
+				err := writeRecordStatsCsv(csvWriter, &outSample)
+				if err != nil {
+					logger.Fatal("failed to write report", zap.Error(err))
+				}
+			} else {
+				samples = append(samples, outSample)
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(outFile)
+			enc.SetIndent("", "  ")
+			err := enc.Encode(samples)
+			if err != nil {
+				logger.Fatal("failed to write report", zap.Error(err))
+			}
+		}
+
+		logger.Info("stats recording written", zap.String("path", outPath))
+	},
+}
+
+func writeRecordStatsCsv(w *csv.Writer, sample *RecordStatsOutput_Sample) error {
+	sampleTime := sample.Time.Format(time.RFC3339)
+	for _, node := range sample.Nodes {
+		err := w.Write([]string{
+			sampleTime,
+			node.NodeID,
+			node.Name,
+			strconv.FormatFloat(node.CpuPercent, 'f', 2, 64),
+			strconv.FormatUint(node.MemUsedBytes, 10),
+			strconv.FormatUint(node.MemLimitBytes, 10),
+			strconv.FormatInt(node.DiskUsedBytes, 10),
+			strconv.FormatInt(node.ItemCount, 10),
+			strconv.FormatFloat(node.OpsPerSec, 'f', 2, 64),
+			strconv.FormatInt(node.DataMemUsedBytes, 10),
+			strconv.FormatInt(node.DataDiskUsedBytes, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func init() {
+	rootCmd.AddCommand(recordStatsCmd)
+
+	recordStatsCmd.Flags().Duration("interval", 5*time.Second, "How often to sample the cluster")
+	recordStatsCmd.Flags().String("format", "", "The report format, csv or json (defaults based on the file extension)")
+}
@@ -35,6 +35,8 @@ var refreshCmd = &cobra.Command{
 		if err != nil {
 			logger.Fatal("failed to remove cluster", zap.Error(err))
 		}
+
+		helper.UpdateRegisteredExpiry(ctx, cluster.GetID(), newExpiryTime)
 	},
 }
 
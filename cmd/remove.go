@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/clouddeploy"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -15,12 +19,24 @@ var removeCmd = &cobra.Command{
 		logger := helper.GetLogger()
 		ctx := helper.GetContext()
 
-		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+		deployerName, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		cloudCluster, ok := cluster.(*clouddeploy.ClusterInfo)
+		if ok && cloudCluster.EstimatedHourlyCost > 0 {
+			logger.Info("removing cluster",
+				zap.String("estimatedCost", fmt.Sprintf("$%.2f", cloudCluster.EstimatedCost())),
+				zap.Duration("uptime", time.Since(cloudCluster.CreatedAt).Round(time.Minute)))
+		}
 
-		err := deployer.RemoveCluster(ctx, cluster.GetID())
+		progressCtx, finishProgress := helper.StartProgress(ctx,
+			"removing cluster", "remove-"+deployerName)
+		err := deployer.RemoveCluster(progressCtx, cluster.GetID())
+		finishProgress(err == nil)
 		if err != nil {
 			logger.Fatal("failed to remove cluster", zap.Error(err))
 		}
+
+		helper.UnregisterCluster(ctx, cluster.GetID())
 	},
 }
 
@@ -33,6 +33,8 @@ var removeAllCmd = &cobra.Command{
 			if err != nil {
 				logger.Fatal("failed to remove all clusters", zap.Error(err))
 			}
+
+			helper.UnregisterOwnedClusters(ctx, deployerName)
 		}
 	},
 }
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -13,12 +16,37 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
+	// scripts written for cbdyncluster can invoke us under its name
+	binaryName := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if binaryName == legacyBinaryName {
+		rootCmd.SetArgs(append([]string{legacyCmd.Name()}, os.Args[1:]...))
+	}
+
+	// cobra only returns errors for invalid usage, such as unknown flags or
+	// the wrong number of arguments, as commands report their own failures
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatalf("failed to initialize command line parser: %s", err)
+		log.Printf("failed to initialize command line parser: %s", err)
+		os.Exit(ExitUsage)
 	}
 }
 
 func init() {
+	// this is assigned here rather than in the declaration of rootCmd, as
+	// checkReadOnly reads the flags of rootCmd, which would be a cycle
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		checkReadOnly(cmd)
+	}
+
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Turns on verbose logging")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only outputs the result of the command and any failures")
+	rootCmd.PersistentFlags().Bool("verbose-http", false, "Logs all HTTP requests and responses to Capella and clusters (implies --verbose)")
 	rootCmd.PersistentFlags().Bool("json", false, "Turns on JSON output for supported commands")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Disables the local cache of Capella lookups")
+	rootCmd.PersistentFlags().String("capella-env", "", "Selects the Capella environment to use instead of the configured one")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Prints the actions destructive commands would take without performing them")
+	rootCmd.PersistentFlags().Bool("no-lock", false, "Disables locking clusters while they are being modified, allowing concurrent modifications")
+	rootCmd.PersistentFlags().String("diagnostics-dir", "", "Captures a diagnostics bundle into this directory when creating or modifying a cluster fails")
+	rootCmd.PersistentFlags().String("ci-output", "", "Also emits the results of commands as CI outputs (github, jenkins, auto)")
+	rootCmd.PersistentFlags().String("ci-output-file", "", "Overrides the file CI outputs are written to")
+	rootCmd.PersistentFlags().Bool("read-only", false, "Refuses to run commands which modify clusters, in addition to the read-only config setting")
 }
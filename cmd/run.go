@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] [definition-tag | --def | --def-file] -- command [args...]",
+	Short: "Allocates a cluster, runs a command against it and then removes the cluster",
+	Long: "Allocates a cluster, runs a command against it and then removes the cluster, even if\n" +
+		"the command fails or we are interrupted.  The command is given CBDC_CLUSTER_ID,\n" +
+		"CBDC_CONNSTR, CBDC_CONNSTR_TLS, CBDC_MGMT, CBDC_MGMT_TLS, CBDC_USERNAME and\n" +
+		"CBDC_PASSWORD to connect to the cluster with, and its exit code becomes ours.",
+	Example: "run --def-file cluster.yaml -- ./run-tests.sh\nrun simple:7.6.2 -- go test ./...",
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+		config := helper.GetConfig(ctx)
+
+		defStr, _ := cmd.Flags().GetString("def")
+		defFile, _ := cmd.Flags().GetString("def-file")
+		purpose, _ := cmd.Flags().GetString("purpose")
+		expiry, _ := cmd.Flags().GetDuration("expiry")
+		expiryIsSet := cmd.Flags().Changed("expiry")
+		deployerName, _ := cmd.Flags().GetString("deployer")
+		keepOnFailure, _ := cmd.Flags().GetBool("keep-on-failure")
+
+		dashIdx := cmd.ArgsLenAtDash()
+		if dashIdx < 0 || dashIdx >= len(args) {
+			logger.Fatal("the command to run must be specified after --")
+		}
+		defArgs := args[:dashIdx]
+		childArgs := args[dashIdx:]
+
+		if len(defArgs) > 1 {
+			logger.Fatal("only a single definition tag can be specified")
+		}
+
+		simpleDefStr := ""
+		if len(defArgs) == 1 {
+			simpleDefStr = defArgs[0]
+		}
+
+		def, err := helper.FetchClusterDef(simpleDefStr, defStr, defFile)
+		if err != nil {
+			logger.Fatal("failed to get definition", zap.Error(err))
+		}
+
+		if purpose != "" {
+			def.Purpose = purpose
+		}
+		if expiryIsSet {
+			def.Expiry = expiry
+		}
+		if deployerName != "" {
+			def.Deployer = deployerName
+		}
+
+		// the default expiry still applies, in case we fail to remove the cluster
+		err = helper.GetEffectiveDefaults(ctx, "").Apply(def)
+		if err != nil {
+			logger.Fatal("failed to apply defaults", zap.Error(err))
+		}
+
+		helper.ResolveVersionNames(ctx, def)
+
+		resolvedDeployerName := def.Deployer
+		if resolvedDeployerName == "" {
+			resolvedDeployerName = config.DefaultDeployer
+		}
+
+		var deployer deployment.Deployer
+		if def.Deployer == "" {
+			deployer = helper.GetDefaultDeployer(ctx)
+		} else {
+			deployer = helper.GetDeployerByName(ctx, def.Deployer)
+		}
+
+		// SIGTERM is also caught by us from here on, so that it can be passed
+		// on to the command.  SIGINT is not passed on, as it is sent to the
+		// entire foreground process group by the terminal, so the command
+		// has already received it.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		progressCtx, finishProgress := helper.StartProgress(ctx,
+			"allocating cluster", "allocate-"+resolvedDeployerName)
+		trackedCtx, finishPartials := helper.TrackPartialClusters(progressCtx, resolvedDeployerName, deployer)
+		cluster, err := deployer.NewCluster(trackedCtx, def)
+		finishProgress(err == nil)
+		if err != nil {
+			finishPartials(err)
+			helper.FatalIfInterrupted(err)
+			logger.Fatal("cluster deployment failed", zap.Error(err))
+		}
+
+		clusterID := cluster.GetID()
+		logger.Info("cluster deployed", zap.String("cluster", clusterID))
+
+		helper.RegisterCluster(ctx, resolvedDeployerName, cluster, nil)
+
+		removeCluster := func() {
+			// we remove the cluster even if our context was cancelled
+			removeCtx := context.WithoutCancel(ctx)
+
+			progressCtx, finishProgress := helper.StartProgress(removeCtx,
+				"removing cluster", "remove-"+resolvedDeployerName)
+			err := deployer.RemoveCluster(progressCtx, clusterID)
+			finishProgress(err == nil)
+			if err != nil {
+				logger.Warn("failed to remove cluster, it will be removed once it expires",
+					zap.String("cluster", clusterID),
+					zap.Error(err))
+				return
+			}
+
+			helper.UnregisterCluster(removeCtx, clusterID)
+		}
+
+		if helper.IsInterrupted() {
+			logger.Warn("interrupted while allocating, removing cluster")
+			removeCluster()
+			helper.FatalIfInterrupted(ctx.Err())
+		}
+
+		err = deployment.RunPostProvisionHooks(ctx, deployer, &deployment.RunPostProvisionHooksOptions{
+			Logger:    logger,
+			ClusterID: clusterID,
+			Hooks:     def.PostProvision,
+		})
+		if err != nil {
+			if keepOnFailure {
+				logger.Warn("keeping cluster since its post-provision hooks failed",
+					zap.String("cluster", clusterID))
+			} else {
+				removeCluster()
+			}
+			helper.FatalIfInterrupted(err)
+			logger.Fatal("failed to run post-provision hooks", zap.Error(err))
+		}
+
+		connectInfo, err := deployment.GetConnectInfoWithCredentials(ctx, deployer, clusterID,
+			&deployment.ConnectCredentialsOptions{})
+		if err != nil {
+			logger.Warn("failed to create dedicated credentials, continuing without them",
+				zap.Error(err))
+
+			connectInfo, err = deployer.GetConnectInfo(ctx, clusterID)
+			if err != nil {
+				removeCluster()
+				logger.Fatal("failed to get connect info", zap.Error(err))
+			}
+		}
+
+		childCmd := exec.Command(childArgs[0], childArgs[1:]...)
+		childCmd.Stdin = os.Stdin
+		childCmd.Stdout = os.Stdout
+		childCmd.Stderr = os.Stderr
+		childCmd.Env = append(os.Environ(),
+			"CBDC_CLUSTER_ID="+clusterID,
+			"CBDC_CONNSTR="+connectInfo.ConnStr,
+			"CBDC_CONNSTR_TLS="+connectInfo.ConnStrTls,
+			"CBDC_MGMT="+connectInfo.Mgmt,
+			"CBDC_MGMT_TLS="+connectInfo.MgmtTls,
+			"CBDC_USERNAME="+connectInfo.Username,
+			"CBDC_PASSWORD="+connectInfo.Password)
+
+		logger.Info("running command", zap.Strings("command", childArgs))
+
+		// from here on we own the command and the teardown of the cluster,
+		// so further interrupts must not exit before the cluster is removed
+		resumeForceExit := helper.SuspendForceExit()
+		defer resumeForceExit()
+
+		err = childCmd.Start()
+		if err != nil {
+			removeCluster()
+			logger.Fatal("failed to start command", zap.Error(err))
+		}
+
+		// signals are passed on to the command, and the cluster is removed
+		// once it has exited
+		childDone := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case sig := <-signals:
+					logger.Info("passing signal to command", zap.String("signal", sig.String()))
+					_ = childCmd.Process.Signal(sig)
+				case <-childDone:
+					return
+				}
+			}
+		}()
+
+		err = childCmd.Wait()
+		close(childDone)
+
+		exitCode := ExitSuccess
+		if err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				removeCluster()
+				logger.Fatal("failed to run command", zap.Error(err))
+			}
+
+			exitCode = exitErr.ExitCode()
+			if exitCode < 0 {
+				exitCode = ExitFailure
+			}
+		}
+
+		if exitCode != ExitSuccess {
+			logger.Info("command failed", zap.Int("exitCode", exitCode))
+		}
+
+		if exitCode != ExitSuccess && keepOnFailure {
+			logger.Warn("keeping cluster since the command failed",
+				zap.String("cluster", clusterID))
+		} else {
+			removeCluster()
+		}
+
+		if exitCode != ExitSuccess {
+			exitWithCode(exitCode)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().String("def", "", "The cluster definition you wish to provision.")
+	runCmd.Flags().String("def-file", "", "The path to a file containing a cluster definition to provision.")
+	runCmd.Flags().String("purpose", "", "The purpose for allocating this cluster")
+	runCmd.Flags().Duration("expiry", 0, "The time to keep this cluster allocated for if it fails to be removed")
+	runCmd.Flags().String("deployer", "", "The name of the deployer to use")
+	runCmd.Flags().Bool("keep-on-failure", false, "Keeps the cluster when the command fails, for investigating the failure")
+}
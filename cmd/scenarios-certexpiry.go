@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ScenariosCertExpiryOutput struct {
+	CaPem    string    `json:"caPem"`
+	NotAfter time.Time `json:"notAfter"`
+	Expired  bool      `json:"expired"`
+}
+
+var scenariosCertExpiryCmd = &cobra.Command{
+	Use:   "cert-expiry",
+	Short: "Installs node certificates which expire shortly, to test clients against expired certificates",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		validity, _ := cmd.Flags().GetDuration("validity")
+		waitExpiry, _ := cmd.Flags().GetBool("wait")
+		caOutPath, _ := cmd.Flags().GetString("ca-out")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("certificate expiry scenarios are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		result, err := dockerDeployer.InstallShortLivedCertificates(ctx, cluster.GetID(), &dockerdeploy.InstallShortLivedCertificatesOptions{
+			Validity: validity,
+		})
+		if err != nil {
+			logger.Fatal("failed to install short-lived certificates", zap.Error(err))
+		}
+
+		if caOutPath != "" {
+			err := os.WriteFile(caOutPath, []byte(result.CaPem), 0644)
+			if err != nil {
+				logger.Fatal("failed to write ca certificate", zap.Error(err))
+			}
+		}
+
+		expired := false
+		if waitExpiry {
+			logger.Info("waiting for the node certificates to expire",
+				zap.Time("notAfter", result.NotAfter))
+
+			select {
+			case <-time.After(time.Until(result.NotAfter)):
+			case <-ctx.Done():
+				logger.Fatal("failed to wait for certificates to expire", zap.Error(ctx.Err()))
+			}
+
+			statuses, err := dockerDeployer.GetNodeCertificates(ctx, cluster.GetID())
+			if err != nil {
+				logger.Fatal("failed to get node certificates", zap.Error(err))
+			}
+
+			expired = true
+			for _, status := range statuses {
+				if !status.Expired {
+					logger.Error("node is not presenting an expired certificate",
+						zap.String("node", status.NodeID),
+						zap.Time("notAfter", status.NotAfter))
+					expired = false
+				}
+			}
+		}
+
+		if !outputJson {
+			fmt.Printf("Expires: %s\n", result.NotAfter.Format(time.RFC3339))
+			if waitExpiry {
+				fmt.Printf("Expired: %t\n", expired)
+			}
+			if caOutPath == "" {
+				fmt.Printf("%s", result.CaPem)
+			}
+		} else {
+			helper.OutputJson(ScenariosCertExpiryOutput{
+				CaPem:    result.CaPem,
+				NotAfter: result.NotAfter,
+				Expired:  expired,
+			})
+		}
+
+		if waitExpiry && !expired {
+			logger.Fatal("node certificates did not expire")
+		}
+	},
+}
+
+func init() {
+	scenariosCmd.AddCommand(scenariosCertExpiryCmd)
+
+	scenariosCertExpiryCmd.Flags().Duration("validity", 2*time.Minute, "How long the node certificates are valid for")
+	scenariosCertExpiryCmd.Flags().Bool("wait", false, "Waits for the certificates to expire and checks that every node presents an expired certificate")
+	scenariosCertExpiryCmd.Flags().String("ca-out", "", "Writes the CA which signed the node certificates to a file rather than printing it")
+}
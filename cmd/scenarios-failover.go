@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ScenariosFailoverOutput struct {
+	NodeID         string   `json:"nodeId"`
+	OTPNode        string   `json:"otpNode"`
+	DocsWritten    int      `json:"docsWritten"`
+	DocsFound      int      `json:"docsFound"`
+	DocsMissing    int      `json:"docsMissing"`
+	DocsMismatched int      `json:"docsMismatched"`
+	MissingKeys    []string `json:"missingKeys,omitempty"`
+}
+
+var scenariosFailoverCmd = &cobra.Command{
+	Use:   "failover",
+	Short: "Writes tracked documents, fails over a node and validates that the documents survived",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		nodeInput, _ := cmd.Flags().GetString("node")
+		numDocs, _ := cmd.Flags().GetInt("num-docs")
+		graceful, _ := cmd.Flags().GetBool("graceful")
+		recoveryType, _ := cmd.Flags().GetString("recovery")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("failover scenarios are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		nodeID := ""
+		if nodeInput != "" {
+			nodeID = helper.IdentifyNode(ctx, cluster, nodeInput).GetID()
+		}
+
+		result, err := dockerDeployer.RunFailoverScenario(ctx, cluster.GetID(), &dockerdeploy.FailoverScenarioOptions{
+			BucketName:   args[1],
+			NodeID:       nodeID,
+			NumDocs:      numDocs,
+			Graceful:     graceful,
+			RecoveryType: recoveryType,
+		})
+		if err != nil {
+			logger.Fatal("failed to run failover scenario", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Failed Over: %s (%s)\n", result.NodeID, result.OTPNode)
+			fmt.Printf("Written: %d\n", result.DocsWritten)
+			fmt.Printf("Found: %d\n", result.DocsFound)
+			fmt.Printf("Missing: %d\n", result.DocsMissing)
+			fmt.Printf("Mismatched: %d\n", result.DocsMismatched)
+			for _, key := range result.MissingKeys {
+				fmt.Printf("  %s\n", key)
+			}
+		} else {
+			helper.OutputJson(ScenariosFailoverOutput{
+				NodeID:         result.NodeID,
+				OTPNode:        result.OTPNode,
+				DocsWritten:    result.DocsWritten,
+				DocsFound:      result.DocsFound,
+				DocsMissing:    result.DocsMissing,
+				DocsMismatched: result.DocsMismatched,
+				MissingKeys:    result.MissingKeys,
+			})
+		}
+
+		if result.DocsMissing > 0 || result.DocsMismatched > 0 {
+			logger.Fatal("documents did not survive the failover",
+				zap.Int("missing", result.DocsMissing),
+				zap.Int("mismatched", result.DocsMismatched))
+		}
+	},
+}
+
+func init() {
+	scenariosCmd.AddCommand(scenariosFailoverCmd)
+
+	scenariosFailoverCmd.Flags().String("node", "", "The node to fail over, defaulting to the last data node")
+	scenariosFailoverCmd.Flags().Int("num-docs", 1000, "The number of tracked documents to write")
+	scenariosFailoverCmd.Flags().Bool("graceful", false, "Whether to use a graceful failover rather than a hard failover")
+	scenariosFailoverCmd.Flags().String("recovery", "", "Adds the node back with the given recovery type (delta or full) instead of removing it")
+}
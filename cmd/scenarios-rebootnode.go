@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ScenariosRebootNodeOutput struct {
+	NodeID              string                            `json:"nodeId"`
+	OTPNode             string                            `json:"otpNode"`
+	AutoFailoverEnabled bool                              `json:"autoFailoverEnabled"`
+	AutoFailoverTimeout string                            `json:"autoFailoverTimeout"`
+	FailedOver          bool                              `json:"failedOver"`
+	Recovered           bool                              `json:"recovered"`
+	Events              []ScenariosRebootNodeOutput_Event `json:"events"`
+}
+
+type ScenariosRebootNodeOutput_Event struct {
+	Time   time.Time `json:"time"`
+	Offset string    `json:"offset"`
+	Type   string    `json:"type"`
+}
+
+var scenariosRebootNodeCmd = &cobra.Command{
+	Use:   "reboot-node",
+	Short: "Reboots a node and reports whether it was automatically failed over",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		nodeInput, _ := cmd.Flags().GetString("node")
+		mode, _ := cmd.Flags().GetString("mode")
+		downTime, _ := cmd.Flags().GetDuration("down-time")
+		observeTimeout, _ := cmd.Flags().GetDuration("observe-timeout")
+		recover, _ := cmd.Flags().GetBool("recover")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("reboot scenarios are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		nodeID := ""
+		if nodeInput != "" {
+			nodeID = helper.IdentifyNode(ctx, cluster, nodeInput).GetID()
+		}
+
+		result, err := dockerDeployer.RunRebootNodeScenario(ctx, cluster.GetID(), &dockerdeploy.RebootNodeScenarioOptions{
+			NodeID:         nodeID,
+			Mode:           mode,
+			DownTime:       downTime,
+			ObserveTimeout: observeTimeout,
+			Recover:        recover,
+		})
+		if err != nil {
+			logger.Fatal("failed to run reboot node scenario", zap.Error(err))
+		}
+
+		formatOffset := func(t time.Time) string {
+			return fmt.Sprintf("+%.1fs", t.Sub(result.StartTime).Seconds())
+		}
+
+		if !outputJson {
+			fmt.Printf("Rebooted: %s (%s)\n", result.NodeID, result.OTPNode)
+			if result.AutoFailoverEnabled {
+				fmt.Printf("Auto-Failover: enabled (timeout %s)\n", result.AutoFailoverTimeout)
+			} else {
+				fmt.Printf("Auto-Failover: disabled\n")
+			}
+			fmt.Printf("Failed Over: %t\n", result.FailedOver)
+			fmt.Printf("Recovered: %t\n", result.Recovered)
+			fmt.Printf("Timeline:\n")
+			for _, event := range result.Events {
+				fmt.Printf("  %-8s %s\n", formatOffset(event.Time), event.Type)
+			}
+		} else {
+			out := ScenariosRebootNodeOutput{
+				NodeID:              result.NodeID,
+				OTPNode:             result.OTPNode,
+				AutoFailoverEnabled: result.AutoFailoverEnabled,
+				AutoFailoverTimeout: result.AutoFailoverTimeout.String(),
+				FailedOver:          result.FailedOver,
+				Recovered:           result.Recovered,
+				Events:              []ScenariosRebootNodeOutput_Event{},
+			}
+			for _, event := range result.Events {
+				out.Events = append(out.Events, ScenariosRebootNodeOutput_Event{
+					Time:   event.Time,
+					Offset: formatOffset(event.Time),
+					Type:   string(event.Type),
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	scenariosCmd.AddCommand(scenariosRebootNodeCmd)
+
+	scenariosRebootNodeCmd.Flags().String("node", "", "The node to reboot, defaulting to the last data node")
+	scenariosRebootNodeCmd.Flags().String("mode", dockerdeploy.RebootModeContainer, "Whether to restart the node container or the couchbase-server service within it (container or service)")
+	scenariosRebootNodeCmd.Flags().Duration("down-time", 0, "How long the node stays down before it is started again")
+	scenariosRebootNodeCmd.Flags().Duration("observe-timeout", 5*time.Minute, "How long to wait for the node to become healthy after it is started")
+	scenariosRebootNodeCmd.Flags().Bool("recover", false, "Adds the node back with delta recovery if it was failed over")
+}
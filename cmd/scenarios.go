@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var scenariosCmd = &cobra.Command{
+	Use:   "scenarios",
+	Short: "Provides built-in scenarios for testing the behaviour of clusters",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(scenariosCmd)
+}
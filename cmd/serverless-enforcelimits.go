@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var serverlessEnforceLimitsCmd = &cobra.Command{
+	Use:   "enforce-limits",
+	Short: "Controls whether the limits of a cluster are enforced or only reported",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		disable, _ := cmd.Flags().GetBool("disable")
+
+		deployer, cluster := helper.identifyServerlessCluster(ctx, args[0])
+
+		err := deployer.SetServerlessLimitEnforcement(ctx, cluster.GetID(), !disable)
+		if err != nil {
+			logger.Fatal("failed to set limit enforcement", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	serverlessCmd.AddCommand(serverlessEnforceLimitsCmd)
+
+	serverlessEnforceLimitsCmd.Flags().Bool("disable", false, "Stop enforcing limits instead")
+}
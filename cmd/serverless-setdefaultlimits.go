@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var serverlessSetDefaultLimitsCmd = &cobra.Command{
+	Use:   "set-default-limits",
+	Short: "Sets the throttle and storage limits applied to new buckets",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		limits := parseServerlessLimits(cmd)
+		if limits.IsEmpty() {
+			logger.Fatal("no limits were specified to update")
+		}
+
+		deployer, cluster := helper.identifyServerlessCluster(ctx, args[0])
+
+		err := deployer.SetDefaultServerlessLimits(ctx, cluster.GetID(), limits)
+		if err != nil {
+			logger.Fatal("failed to set default limits", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	serverlessCmd.AddCommand(serverlessSetDefaultLimitsCmd)
+
+	addServerlessLimitsFlags(serverlessSetDefaultLimitsCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var serverlessSetLimitsCmd = &cobra.Command{
+	Use:   "set-limits",
+	Short: "Sets the throttle and storage limits of a bucket",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		limits := parseServerlessLimits(cmd)
+		if limits.IsEmpty() {
+			logger.Fatal("no limits were specified to update")
+		}
+
+		deployer, cluster := helper.identifyServerlessCluster(ctx, args[0])
+
+		err := deployer.SetBucketServerlessLimits(ctx, cluster.GetID(), args[1], limits)
+		if err != nil {
+			logger.Fatal("failed to set bucket limits", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	serverlessCmd.AddCommand(serverlessSetLimitsCmd)
+
+	addServerlessLimitsFlags(serverlessSetLimitsCmd)
+}
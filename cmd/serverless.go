@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var serverlessCmd = &cobra.Command{
+	Use:   "serverless",
+	Short: "Provides the ability to manage the limits of serverless profile clusters",
+	Run:   nil,
+}
+
+// identifyServerlessCluster identifies a cluster for the serverless commands,
+// which emulate Capella serverless using docker nodes.
+func (h *CmdHelper) identifyServerlessCluster(ctx context.Context, userInput string) (*dockerdeploy.Deployer, deployment.ClusterInfo) {
+	logger := h.GetLogger()
+
+	_, deployer, cluster := h.IdentifyCluster(ctx, userInput)
+
+	dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+	if !ok {
+		logger.Fatal("serverless emulation is only supported for docker clusters",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	return dockerDeployer, cluster
+}
+
+func addServerlessLimitsFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("data-throttle", 0, "The data service throttle limit in units per second.")
+	cmd.Flags().Int("index-throttle", 0, "The index service throttle limit in units per second.")
+	cmd.Flags().Int("search-throttle", 0, "The search service throttle limit in units per second.")
+	cmd.Flags().Int("query-throttle", 0, "The query service throttle limit in units per second.")
+	cmd.Flags().Int("data-storage", 0, "The data service storage limit in GB.")
+	cmd.Flags().Int("index-storage", 0, "The index service storage limit in GB.")
+	cmd.Flags().Int("search-storage", 0, "The search service storage limit in GB.")
+}
+
+func parseServerlessLimits(cmd *cobra.Command) *clusterdef.ServerlessLimits {
+	limits := &clusterdef.ServerlessLimits{}
+	limits.DataThrottleLimit, _ = cmd.Flags().GetInt("data-throttle")
+	limits.IndexThrottleLimit, _ = cmd.Flags().GetInt("index-throttle")
+	limits.SearchThrottleLimit, _ = cmd.Flags().GetInt("search-throttle")
+	limits.QueryThrottleLimit, _ = cmd.Flags().GetInt("query-throttle")
+	limits.DataStorageLimit, _ = cmd.Flags().GetInt("data-storage")
+	limits.IndexStorageLimit, _ = cmd.Flags().GetInt("index-storage")
+	limits.SearchStorageLimit, _ = cmd.Flags().GetInt("search-storage")
+	return limits
+}
+
+func init() {
+	rootCmd.AddCommand(serverlessCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type SnapshotsListOutput []SnapshotsListOutput_Item
+
+type SnapshotsListOutput_Item struct {
+	Name          string   `json:"name"`
+	Image         string   `json:"image"`
+	ServerVersion string   `json:"server-version"`
+	Buckets       []string `json:"buckets"`
+	CreatedAt     string   `json:"created-at"`
+}
+
+var snapshotsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Lists the golden snapshots available locally",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer := helper.GetDockerDeployer(ctx)
+
+		snapshots, err := deployer.ListSnapshots(ctx)
+		if err != nil {
+			logger.Fatal("failed to list snapshots", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Snapshots:\n")
+			for _, snapshot := range snapshots {
+				fmt.Printf("  %s [Image: %s, Version: %s, Buckets: %s, Created: %s]\n",
+					snapshot.Name,
+					snapshot.ImagePath,
+					snapshot.ServerVersion,
+					strings.Join(snapshot.Buckets, ", "),
+					snapshot.CreatedAt.Format(time.RFC3339))
+			}
+		} else {
+			var out SnapshotsListOutput
+			for _, snapshot := range snapshots {
+				out = append(out, SnapshotsListOutput_Item{
+					Name:          snapshot.Name,
+					Image:         snapshot.ImagePath,
+					ServerVersion: snapshot.ServerVersion,
+					Buckets:       snapshot.Buckets,
+					CreatedAt:     snapshot.CreatedAt.Format(time.RFC3339),
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	snapshotsCmd.AddCommand(snapshotsListCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var snapshotsPublishCmd = &cobra.Command{
+	Use:   "publish [cluster] [name]",
+	Short: "Publishes the data of a cluster as a golden snapshot",
+	Long: "Backs up the buckets, data and indexes of a docker cluster into a snapshot image, which " +
+		"new clusters are started from by specifying `docker.snapshot` in their definition.  With " +
+		"--push, the snapshot is pushed to the configured snapshot registry.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		push, _ := cmd.Flags().GetBool("push")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("snapshots are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		snapshot, err := dockerDeployer.PublishSnapshot(ctx, cluster.GetID(), &dockerdeploy.PublishSnapshotOptions{
+			Name: args[1],
+			Push: push,
+		})
+		if err != nil {
+			logger.Fatal("failed to publish snapshot", zap.Error(err))
+		}
+
+		fmt.Printf("%s\n", snapshot.ImagePath)
+	},
+}
+
+func init() {
+	snapshotsCmd.AddCommand(snapshotsPublishCmd)
+
+	snapshotsPublishCmd.Flags().Bool("push", false, "Whether to push the snapshot to the snapshot registry")
+}
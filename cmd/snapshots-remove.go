@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var snapshotsRemoveCmd = &cobra.Command{
+	Use:     "remove [name]",
+	Aliases: []string{"rm"},
+	Short:   "Removes the local image of a golden snapshot",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer := helper.GetDockerDeployer(ctx)
+
+		err := deployer.RemoveSnapshot(ctx, args[0])
+		if err != nil {
+			logger.Fatal("failed to remove snapshot", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	snapshotsCmd.AddCommand(snapshotsRemoveCmd)
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var snapshotsCmd = &cobra.Command{
+	Use:     "snapshots",
+	Aliases: []string{"snapshot"},
+	Short:   "Provides the ability to publish and manage golden snapshots of docker clusters.",
+	Run:     nil,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotsCmd)
+}
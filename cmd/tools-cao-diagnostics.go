@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsCaoDiagnosticsCmd = &cobra.Command{
+	Use:   "diagnostics [cluster] [dest-path]",
+	Short: "Captures the cluster resource, events, pod states and operator logs of a cao cluster",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		caoDeployer, ok := deployer.(*caodeploy.Deployer)
+		if !ok {
+			logger.Fatal("diagnostics are only supported for cao clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		paths, err := caoDeployer.CollectDiagnostics(ctx, cluster.GetID(), args[1])
+		if err != nil {
+			logger.Fatal("failed to collect diagnostics", zap.Error(err))
+		}
+
+		for _, path := range paths {
+			fmt.Printf("%s\n", path)
+		}
+	},
+}
+
+func init() {
+	toolsCaoCmd.AddCommand(toolsCaoDiagnosticsCmd)
+}
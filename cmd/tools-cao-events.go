@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ToolsCaoEventsOutput_Item struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Count   int       `json:"count"`
+}
+
+var toolsCaoEventsCmd = &cobra.Command{
+	Use:   "events [cluster]",
+	Short: "Streams the k8s events of the namespace of a cao cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		caoDeployer, ok := deployer.(*caodeploy.Deployer)
+		if !ok {
+			logger.Fatal("events are only supported for cao clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		err := caoDeployer.WatchEvents(ctx, cluster.GetID(), func(event *caocontrol.EventInfo) {
+			if !outputJson {
+				fmt.Printf("%s %-8s %-24s %s: %s\n",
+					event.Time.Format(time.RFC3339),
+					event.Type,
+					event.Reason,
+					event.Object,
+					event.Message)
+			} else {
+				helper.OutputJson(ToolsCaoEventsOutput_Item{
+					Time:    event.Time,
+					Type:    event.Type,
+					Reason:  event.Reason,
+					Object:  event.Object,
+					Message: event.Message,
+					Count:   event.Count,
+				})
+			}
+		})
+		if err != nil {
+			logger.Fatal("failed to watch events", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	toolsCaoCmd.AddCommand(toolsCaoEventsCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/caodeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsCaoRunInClusterCmd = &cobra.Command{
+	Use:   "run-in-cluster [cluster] -- [command...]",
+	Short: "Runs a command as a k8s job next to a cao cluster and exits with its exit code",
+	Long: "Runs a command as a k8s job in the namespace of a cao cluster, streaming its output.\n" +
+		"The job is given CBDC_CLUSTER_ID, CBDC_CONNSTR, CBDC_CONNSTR_TLS, CBDC_MGMT,\n" +
+		"CBDC_USERNAME and CBDC_PASSWORD to connect to the cluster with.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		image, _ := cmd.Flags().GetString("image")
+		envStrs, _ := cmd.Flags().GetStringSlice("env")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		keep, _ := cmd.Flags().GetBool("keep")
+
+		env := make(map[string]string)
+		for _, envStr := range envStrs {
+			envName, envValue, found := strings.Cut(envStr, "=")
+			if !found {
+				logger.Fatal("environment variables must be in NAME=VALUE form",
+					zap.String("env", envStr))
+			}
+			env[envName] = envValue
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		caoDeployer, ok := deployer.(*caodeploy.Deployer)
+		if !ok {
+			logger.Fatal("running in cluster is only supported for cao clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		exitCode, err := caoDeployer.RunInCluster(ctx, cluster.GetID(), &caodeploy.RunInClusterOptions{
+			Image:   image,
+			Command: args[1:],
+			Env:     env,
+			Timeout: timeout,
+			Keep:    keep,
+		}, os.Stdout)
+		if err != nil {
+			logger.Fatal("failed to run in cluster", zap.Error(err))
+		}
+
+		if exitCode != 0 {
+			logger.Info("command failed", zap.Int("exitCode", exitCode))
+			if exitCode < 0 {
+				exitCode = ExitFailure
+			}
+			exitWithCode(exitCode)
+		}
+	},
+}
+
+func init() {
+	toolsCaoCmd.AddCommand(toolsCaoRunInClusterCmd)
+
+	toolsCaoRunInClusterCmd.Flags().String("image", "", "The image to run")
+	toolsCaoRunInClusterCmd.Flags().StringSlice("env", nil, "Extra environment variables in NAME=VALUE form")
+	toolsCaoRunInClusterCmd.Flags().Duration("timeout", 0, "How long the command may run before it is terminated")
+	toolsCaoRunInClusterCmd.Flags().Bool("keep", false, "Leave the job behind once it completes")
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ToolsConsistencyCheckOutput_Reader struct {
+	NodeID     string `json:"nodeId"`
+	Path       string `json:"path"`
+	Guaranteed bool   `json:"guaranteed"`
+	Reads      int    `json:"reads"`
+	Stale      int    `json:"stale"`
+	Missing    int    `json:"missing"`
+	MaxLag     string `json:"maxLag"`
+	FirstError string `json:"firstError,omitempty"`
+}
+
+type ToolsConsistencyCheckOutput struct {
+	WriterNodeID string                               `json:"writerNodeId"`
+	Readers      []ToolsConsistencyCheckOutput_Reader `json:"readers"`
+	Violations   int                                  `json:"violations"`
+}
+
+var toolsConsistencyCheckCmd = &cobra.Command{
+	Use:   "consistency-check [cluster] [bucket]",
+	Short: "Writes through one node and reports how stale reads through every node and service are",
+	Long: "Repeatedly writes a set of documents through one node, and after each write reads it back from " +
+		"the active and replica copies held by each data node, and through the query service of every query " +
+		"node at each scan consistency, reporting how many reads did not observe the write immediately and " +
+		"how long they took to.  Stale reads of active copies or request_plus queries are violations and make " +
+		"the command fail, while replicas and not_bounded queries are expected to be stale at times.  Query " +
+		"reads need an index node, and a temporary index is created for them.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		nodeInput, _ := cmd.Flags().GetString("via")
+		numWrites, _ := cmd.Flags().GetInt("num-writes")
+		readTimeout, _ := cmd.Flags().GetDuration("read-timeout")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("consistency checks are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		writerNodeID := ""
+		if nodeInput != "" {
+			writerNodeID = helper.IdentifyNode(ctx, cluster, nodeInput).GetID()
+		}
+
+		result, err := dockerDeployer.RunConsistencyCheck(ctx, cluster.GetID(), &dockerdeploy.ConsistencyCheckOptions{
+			BucketName:   args[1],
+			WriterNodeID: writerNodeID,
+			NumWrites:    numWrites,
+			ReadTimeout:  readTimeout,
+		})
+		if err != nil {
+			logger.Fatal("failed to run consistency check", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Writer: %s\n", result.WriterNodeID)
+			for _, reader := range result.Readers {
+				fmt.Printf("%s via %s\n", reader.Path, reader.NodeID)
+				fmt.Printf("  reads: %d\n", reader.Reads)
+				fmt.Printf("  stale: %d\n", reader.Stale)
+				fmt.Printf("  missing: %d\n", reader.Missing)
+				fmt.Printf("  max lag: %s\n", reader.MaxLag.Round(time.Millisecond))
+				if reader.FirstError != "" {
+					fmt.Printf("  first error: %s\n", reader.FirstError)
+				}
+			}
+			fmt.Printf("Violations: %d\n", result.Violations)
+		} else {
+			out := ToolsConsistencyCheckOutput{
+				WriterNodeID: result.WriterNodeID,
+				Readers:      []ToolsConsistencyCheckOutput_Reader{},
+				Violations:   result.Violations,
+			}
+			for _, reader := range result.Readers {
+				out.Readers = append(out.Readers, ToolsConsistencyCheckOutput_Reader{
+					NodeID:     reader.NodeID,
+					Path:       reader.Path,
+					Guaranteed: reader.Guaranteed,
+					Reads:      reader.Reads,
+					Stale:      reader.Stale,
+					Missing:    reader.Missing,
+					MaxLag:     reader.MaxLag.String(),
+					FirstError: reader.FirstError,
+				})
+			}
+			helper.OutputJson(out)
+		}
+
+		if result.Violations > 0 {
+			logger.Fatal("reads did not observe completed writes",
+				zap.Int("violations", result.Violations))
+		}
+	},
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsConsistencyCheckCmd)
+
+	toolsConsistencyCheckCmd.Flags().String("via", "", "The node to write through, defaulting to the first data node")
+	toolsConsistencyCheckCmd.Flags().Int("num-writes", 100, "The number of writes to read back")
+	toolsConsistencyCheckCmd.Flags().Duration("read-timeout", 10*time.Second, "How long each reader has to observe a write before it is counted as missing")
+}
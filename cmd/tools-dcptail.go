@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/dcptap"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ToolsDcpTailOutput_Event struct {
+	Type       string `json:"type"`
+	VbID       uint16 `json:"vbid"`
+	Seqno      uint64 `json:"seqno"`
+	Collection string `json:"collection"`
+	Key        string `json:"key"`
+}
+
+var toolsDcpTailCmd = &cobra.Command{
+	Use:   "dcp-tail [cluster] [bucket]",
+	Short: "Prints the mutations and deletions of a bucket in real time using DCP",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		username, _ := cmd.Flags().GetString("username")
+		password, _ := cmd.Flags().GetString("password")
+		fromStart, _ := cmd.Flags().GetBool("from-start")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		connectInfo, err := deployer.GetConnectInfo(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get connect info", zap.Error(err))
+		}
+
+		var printLock sync.Mutex
+		err = dcptap.Tail(ctx, &dcptap.TailOptions{
+			Logger:     logger,
+			ConnStr:    toolsConnStr(connectInfo),
+			Username:   username,
+			Password:   password,
+			BucketName: args[1],
+			FromStart:  fromStart,
+		}, func(event *dcptap.Event) {
+			printLock.Lock()
+			defer printLock.Unlock()
+
+			if !outputJson {
+				fmt.Printf("%-8s vb:%-4d seqno:%-8d %s %s\n",
+					event.Type,
+					event.VbID,
+					event.Seqno,
+					event.Collection,
+					event.Key)
+			} else {
+				helper.OutputJson(ToolsDcpTailOutput_Event{
+					Type:       string(event.Type),
+					VbID:       event.VbID,
+					Seqno:      event.Seqno,
+					Collection: event.Collection,
+					Key:        event.Key,
+				})
+			}
+		})
+		if err != nil {
+			logger.Fatal("failed to tail dcp", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsDcpTailCmd)
+
+	toolsDcpTailCmd.Flags().String("username", "Administrator", "The username to connect to the cluster with")
+	toolsDcpTailCmd.Flags().String("password", "password", "The password to connect to the cluster with")
+	toolsDcpTailCmd.Flags().Bool("from-start", false, "Stream all existing documents rather than only new changes")
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ToolsDurabilityCheckOutput_Outcome struct {
+	FailedNodeID string `json:"failedNodeId,omitempty"`
+	Level        string `json:"level"`
+	Succeeded    int    `json:"succeeded"`
+	Ambiguous    int    `json:"ambiguous"`
+	Impossible   int    `json:"impossible"`
+	Failed       int    `json:"failed"`
+	FirstError   string `json:"firstError,omitempty"`
+}
+
+type ToolsDurabilityCheckOutput struct {
+	Outcomes   []ToolsDurabilityCheckOutput_Outcome `json:"outcomes"`
+	LostWrites int                                  `json:"lostWrites"`
+	LostKeys   []string                             `json:"lostKeys,omitempty"`
+}
+
+var toolsDurabilityCheckCmd = &cobra.Command{
+	Use:   "durability-check [cluster] [bucket]",
+	Short: "Performs sync-writes at each durability level while each data node is paused and reports the outcomes",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		levels, _ := cmd.Flags().GetStringSlice("level")
+		numWrites, _ := cmd.Flags().GetInt("num-writes")
+		writeTimeout, _ := cmd.Flags().GetDuration("write-timeout")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("durability checks are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		result, err := dockerDeployer.RunDurabilityCheck(ctx, cluster.GetID(), &dockerdeploy.DurabilityCheckOptions{
+			BucketName:   args[1],
+			Levels:       levels,
+			NumWrites:    numWrites,
+			WriteTimeout: writeTimeout,
+		})
+		if err != nil {
+			logger.Fatal("failed to run durability check", zap.Error(err))
+		}
+
+		if !outputJson {
+			for _, outcome := range result.Outcomes {
+				if outcome.FailedNodeID == "" {
+					fmt.Printf("%s (all nodes healthy)\n", outcome.Level)
+				} else {
+					fmt.Printf("%s (%s paused)\n", outcome.Level, outcome.FailedNodeID)
+				}
+				fmt.Printf("  succeeded: %d\n", outcome.Succeeded)
+				fmt.Printf("  ambiguous: %d\n", outcome.Ambiguous)
+				fmt.Printf("  impossible: %d\n", outcome.Impossible)
+				fmt.Printf("  failed: %d\n", outcome.Failed)
+				if outcome.FirstError != "" {
+					fmt.Printf("  first error: %s\n", outcome.FirstError)
+				}
+			}
+
+			fmt.Printf("Lost Writes: %d\n", result.LostWrites)
+			for _, key := range result.LostKeys {
+				fmt.Printf("  %s\n", key)
+			}
+		} else {
+			out := ToolsDurabilityCheckOutput{
+				LostWrites: result.LostWrites,
+				LostKeys:   result.LostKeys,
+			}
+			for _, outcome := range result.Outcomes {
+				out.Outcomes = append(out.Outcomes, ToolsDurabilityCheckOutput_Outcome{
+					FailedNodeID: outcome.FailedNodeID,
+					Level:        outcome.Level,
+					Succeeded:    outcome.Succeeded,
+					Ambiguous:    outcome.Ambiguous,
+					Impossible:   outcome.Impossible,
+					Failed:       outcome.Failed,
+					FirstError:   outcome.FirstError,
+				})
+			}
+			helper.OutputJson(out)
+		}
+
+		if result.LostWrites > 0 {
+			logger.Fatal("acknowledged writes were lost",
+				zap.Int("lost", result.LostWrites))
+		}
+	},
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsDurabilityCheckCmd)
+
+	toolsDurabilityCheckCmd.Flags().StringSlice("level", nil, "The durability levels to test (none, majority, majorityAndPersistActive, persistToMajority), defaulting to all of them")
+	toolsDurabilityCheckCmd.Flags().Int("num-writes", 100, "The number of writes to perform at each level for each phase")
+	toolsDurabilityCheckCmd.Flags().Duration("write-timeout", 5*time.Second, "How long to wait for each write before counting it as ambiguous")
+}
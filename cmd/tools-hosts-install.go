@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/etchosts"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ToolsHostsInstallOutput struct {
+	Entries    []ToolsHostsInstallOutput_Entry `json:"entries"`
+	ConnStr    string                          `json:"connstr"`
+	ConnStrTls string                          `json:"connstrTls"`
+	Mgmt       string                          `json:"mgmt"`
+	MgmtTls    string                          `json:"mgmtTls"`
+}
+
+type ToolsHostsInstallOutput_Entry struct {
+	NodeID   string `json:"nodeId"`
+	Hostname string `json:"hostname"`
+	Address  string `json:"address"`
+}
+
+var toolsHostsInstallCmd = &cobra.Command{
+	Use:   "install <cluster-id>",
+	Short: "Adds hosts file entries mapping the hostnames of the nodes of a docker cluster to their addresses",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		hostsFile, _ := cmd.Flags().GetString("hosts-file")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("node hostnames are only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		info, err := dockerDeployer.GetNodeHostnames(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get node hostnames", zap.Error(err))
+		}
+
+		var entries []etchosts.Entry
+		for _, entry := range info.Entries {
+			entries = append(entries, etchosts.Entry{
+				Address:  entry.Address,
+				Hostname: entry.Hostname,
+			})
+		}
+
+		if helper.IsDryRun() {
+			logger.Info("dry-run: would install hosts file entries",
+				zap.String("hostsFile", hostsFile),
+				zap.Any("entries", entries))
+		} else {
+			err = etchosts.UpdateFile(hostsFile, func(contents string) string {
+				return etchosts.SetBlock(contents, cluster.GetID(), entries)
+			})
+			if err != nil {
+				logger.Fatal("failed to update hosts file", zap.Error(err))
+			}
+		}
+
+		if !outputJson {
+			fmt.Printf("Hosts:\n")
+			for _, entry := range info.Entries {
+				fmt.Printf("  %s -> %s\n", entry.Hostname, entry.Address)
+			}
+			fmt.Printf("Connection String: %s\n", info.ConnStr)
+			fmt.Printf("Connection String (TLS): %s\n", info.ConnStrTls)
+			fmt.Printf("Management: %s\n", info.Mgmt)
+			fmt.Printf("Management (TLS): %s\n", info.MgmtTls)
+		} else {
+			out := ToolsHostsInstallOutput{
+				ConnStr:    info.ConnStr,
+				ConnStrTls: info.ConnStrTls,
+				Mgmt:       info.Mgmt,
+				MgmtTls:    info.MgmtTls,
+			}
+			for _, entry := range info.Entries {
+				out.Entries = append(out.Entries, ToolsHostsInstallOutput_Entry{
+					NodeID:   entry.NodeID,
+					Hostname: entry.Hostname,
+					Address:  entry.Address,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	toolsHostsCmd.AddCommand(toolsHostsInstallCmd)
+
+	toolsHostsInstallCmd.Flags().String("hosts-file", etchosts.DefaultPath(), "The path of the hosts file to update")
+}
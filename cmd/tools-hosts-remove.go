@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/etchosts"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsHostsRemoveCmd = &cobra.Command{
+	Use:   "remove [cluster-id]",
+	Short: "Removes the hosts file entries of a cluster, which may already have been removed",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+
+		hostsFile, _ := cmd.Flags().GetString("hosts-file")
+		removeAll, _ := cmd.Flags().GetBool("all")
+
+		if len(args) == 0 && !removeAll {
+			logger.Fatal("a cluster id or --all must be specified")
+		}
+
+		// the entries are matched by cluster id prefix rather than by
+		// identifying the cluster, so that they can still be removed
+		// once the cluster itself is gone
+		matches := func(name string) bool {
+			return removeAll || strings.HasPrefix(name, args[0])
+		}
+
+		err := etchosts.UpdateFile(hostsFile, func(contents string) string {
+			for _, name := range etchosts.BlockNames(contents) {
+				if matches(name) {
+					if helper.IsDryRun() {
+						logger.Info("dry-run: would remove hosts file entries", zap.String("cluster", name))
+					} else {
+						logger.Info("removing hosts file entries", zap.String("cluster", name))
+					}
+				}
+			}
+
+			if helper.IsDryRun() {
+				return contents
+			}
+			return etchosts.RemoveBlocks(contents, matches)
+		})
+		if err != nil {
+			logger.Fatal("failed to update hosts file", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	toolsHostsCmd.AddCommand(toolsHostsRemoveCmd)
+
+	toolsHostsRemoveCmd.Flags().String("hosts-file", etchosts.DefaultPath(), "The path of the hosts file to update")
+	toolsHostsRemoveCmd.Flags().Bool("all", false, "Removes the entries of every cluster")
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var toolsHostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Provides access to tools for resolving the hostnames of docker nodes through the hosts file",
+	Run:   nil,
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsHostsCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsMinioDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploys MinIO on the network of a docker cluster and prints its credentials",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		buckets, _ := cmd.Flags().GetStringSlice("bucket")
+		region, _ := cmd.Flags().GetString("region")
+		accessKey, _ := cmd.Flags().GetString("access-key")
+		secretKey, _ := cmd.Flags().GetString("secret-key")
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("minio is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		info, err := dockerDeployer.DeployMinio(ctx, cluster.GetID(), &dockerdeploy.DeployMinioOptions{
+			Buckets:   buckets,
+			Region:    region,
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+		})
+		if err != nil {
+			logger.Fatal("failed to deploy minio", zap.Error(err))
+		}
+
+		outputMinioInfo(&helper, outputJson, info)
+	},
+}
+
+func init() {
+	toolsMinioCmd.AddCommand(toolsMinioDeployCmd)
+
+	toolsMinioDeployCmd.Flags().StringSlice("bucket", []string{"analytics"}, "The buckets to create")
+	toolsMinioDeployCmd.Flags().String("region", "", "The region MinIO reports, defaulting to us-east-1")
+	toolsMinioDeployCmd.Flags().String("access-key", "", "The access key, defaulting to minioadmin")
+	toolsMinioDeployCmd.Flags().String("secret-key", "", "The secret key, defaulting to minioadmin")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsMinioInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Prints the endpoint, credentials and buckets of the MinIO of a docker cluster",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("minio is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		info, err := dockerDeployer.GetMinio(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get minio", zap.Error(err))
+		}
+
+		outputMinioInfo(&helper, outputJson, info)
+	},
+}
+
+func init() {
+	toolsMinioCmd.AddCommand(toolsMinioInfoCmd)
+}
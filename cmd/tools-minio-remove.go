@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var toolsMinioRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Removes the MinIO of a docker cluster along with its data",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+		if !ok {
+			logger.Fatal("minio is only supported for docker clusters",
+				zap.Error(deployment.ErrFeatureUnsupported))
+		}
+
+		err := dockerDeployer.RemoveMinio(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to remove minio", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	toolsMinioCmd.AddCommand(toolsMinioRemoveCmd)
+}
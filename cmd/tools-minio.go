@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+)
+
+type ToolsMinioOutput struct {
+	NodeID       string   `json:"nodeId"`
+	Endpoint     string   `json:"endpoint"`
+	HostEndpoint string   `json:"hostEndpoint"`
+	Region       string   `json:"region"`
+	AccessKey    string   `json:"accessKey"`
+	SecretKey    string   `json:"secretKey"`
+	Buckets      []string `json:"buckets"`
+	BackupArgs   []string `json:"backupArgs"`
+}
+
+var toolsMinioCmd = &cobra.Command{
+	Use:   "minio",
+	Short: "Provides access to tools for S3 compatible storage alongside a docker cluster",
+	Run:   nil,
+}
+
+// minioBackupArgs are the cbbackupmgr arguments which use MinIO for the
+// object store backing an archive, which must be an s3:// URL for one of
+// its buckets.  They are only printed for running cbbackupmgr by hand.
+func minioBackupArgs(info *dockerdeploy.MinioInfo) []string {
+	return []string{
+		"--obj-endpoint", info.Endpoint,
+		"--obj-region", info.Region,
+		"--obj-access-key-id", info.AccessKey,
+		"--obj-secret-access-key", info.SecretKey,
+		"--obj-staging-dir", "/tmp/cbbackupmgr-staging",
+		"--s3-force-path-style",
+	}
+}
+
+func outputMinioInfo(helper *CmdHelper, outputJson bool, info *dockerdeploy.MinioInfo) {
+	if !outputJson {
+		fmt.Printf("Endpoint: %s\n", info.Endpoint)
+		fmt.Printf("Host Endpoint: %s\n", info.HostEndpoint)
+		fmt.Printf("Region: %s\n", info.Region)
+		fmt.Printf("Access Key: %s\n", info.AccessKey)
+		fmt.Printf("Secret Key: %s\n", info.SecretKey)
+		fmt.Printf("Buckets:\n")
+		for _, bucket := range info.Buckets {
+			fmt.Printf("  s3://%s\n", bucket)
+		}
+		fmt.Printf("Manual Backup Args: %s\n", strings.Join(minioBackupArgs(info), " "))
+	} else {
+		buckets := info.Buckets
+		if buckets == nil {
+			buckets = []string{}
+		}
+
+		helper.OutputJson(ToolsMinioOutput{
+			NodeID:       info.NodeID,
+			Endpoint:     info.Endpoint,
+			HostEndpoint: info.HostEndpoint,
+			Region:       info.Region,
+			AccessKey:    info.AccessKey,
+			SecretKey:    info.SecretKey,
+			Buckets:      buckets,
+			BackupArgs:   minioBackupArgs(info),
+		})
+	}
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsMinioCmd)
+}
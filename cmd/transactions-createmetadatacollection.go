@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var transactionsCreateMetadataCollectionCmd = &cobra.Command{
+	Use:   "create-metadata-collection [cluster] [bucket]",
+	Short: "Creates the collection which clients store transaction metadata in, if it does not exist",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		loc := parseATRLocation(cmd, args[1])
+
+		deployer, cluster := helper.identifyTransactionsCluster(ctx, args[0])
+
+		err := deployer.EnsureTransactionMetadataCollection(ctx, cluster.GetID(), loc)
+		if err != nil {
+			logger.Fatal("failed to create transaction metadata collection", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	transactionsCmd.AddCommand(transactionsCreateMetadataCollectionCmd)
+
+	transactionsCreateMetadataCollectionCmd.Flags().String("scope", "_default", "The scope to create the collection in, which is created if needed")
+	transactionsCreateMetadataCollectionCmd.Flags().String("collection", "txn-metadata", "The name of the collection to create")
+}
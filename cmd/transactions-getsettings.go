@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type TransactionsGetSettingsOutput struct {
+	Timeout               string `json:"timeout,omitempty"`
+	CleanupWindow         string `json:"cleanupWindow,omitempty"`
+	CleanupClientAttempts *bool  `json:"cleanupClientAttempts,omitempty"`
+	CleanupLostAttempts   *bool  `json:"cleanupLostAttempts,omitempty"`
+	NumATRs               int    `json:"numAtrs,omitempty"`
+}
+
+var transactionsGetSettingsCmd = &cobra.Command{
+	Use:   "get-settings [cluster]",
+	Short: "Gets the transaction settings of a cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, cluster := helper.identifyTransactionsCluster(ctx, args[0])
+
+		settings, err := deployer.GetTransactionSettings(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to get transaction settings", zap.Error(err))
+		}
+
+		out := TransactionsGetSettingsOutput{
+			CleanupClientAttempts: settings.CleanupClientAttempts,
+			CleanupLostAttempts:   settings.CleanupLostAttempts,
+			NumATRs:               settings.NumATRs,
+		}
+		if settings.Timeout > 0 {
+			out.Timeout = settings.Timeout.String()
+		}
+		if settings.CleanupWindow > 0 {
+			out.CleanupWindow = settings.CleanupWindow.String()
+		}
+
+		if !outputJson {
+			fmt.Printf("Timeout: %s\n", out.Timeout)
+			fmt.Printf("Cleanup Window: %s\n", out.CleanupWindow)
+			if out.CleanupClientAttempts != nil {
+				fmt.Printf("Cleanup Client Attempts: %t\n", *out.CleanupClientAttempts)
+			}
+			if out.CleanupLostAttempts != nil {
+				fmt.Printf("Cleanup Lost Attempts: %t\n", *out.CleanupLostAttempts)
+			}
+			fmt.Printf("Num ATRs: %d\n", out.NumATRs)
+		} else {
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	transactionsCmd.AddCommand(transactionsGetSettingsCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type TransactionsListATRsOutput []TransactionsListATRsOutput_Item
+
+type TransactionsListATRsOutput_Item struct {
+	ATRID         string    `json:"atrId"`
+	AttemptID     string    `json:"attemptId"`
+	TransactionID string    `json:"transactionId"`
+	State         string    `json:"state"`
+	StartTime     time.Time `json:"startTime"`
+	Expiry        string    `json:"expiry"`
+	Lost          bool      `json:"lost"`
+}
+
+var transactionsListATRsCmd = &cobra.Command{
+	Use:   "list-atrs [cluster] [bucket]",
+	Short: "Lists the attempts recorded in the active transaction records of a collection",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		lostOnly, _ := cmd.Flags().GetBool("lost")
+		outputJson, _ := cmd.Flags().GetBool("json")
+		loc := parseATRLocation(cmd, args[1])
+
+		deployer, cluster := helper.identifyTransactionsCluster(ctx, args[0])
+
+		entries, err := deployer.ListATREntries(ctx, cluster.GetID(), loc)
+		if err != nil {
+			logger.Fatal("failed to list active transaction records", zap.Error(err))
+		}
+
+		out := TransactionsListATRsOutput{}
+		for _, entry := range entries {
+			if lostOnly && !entry.Lost {
+				continue
+			}
+
+			out = append(out, TransactionsListATRsOutput_Item{
+				ATRID:         entry.ATRID,
+				AttemptID:     entry.AttemptID,
+				TransactionID: entry.TransactionID,
+				State:         entry.State,
+				StartTime:     entry.StartTime,
+				Expiry:        entry.Expiry.String(),
+				Lost:          entry.Lost,
+			})
+		}
+
+		if !outputJson {
+			fmt.Printf("Attempts:\n")
+			for _, item := range out {
+				lostStr := ""
+				if item.Lost {
+					lostStr = " (lost)"
+				}
+				fmt.Printf("  %s [ATR: %s, Transaction: %s]\n", item.AttemptID, item.ATRID, item.TransactionID)
+				fmt.Printf("    State: %s%s\n", item.State, lostStr)
+				fmt.Printf("    Started: %s (expires after %s)\n", item.StartTime.Format(time.RFC3339), item.Expiry)
+			}
+		} else {
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	transactionsCmd.AddCommand(transactionsListATRsCmd)
+
+	addATRLocationFlags(transactionsListATRsCmd)
+	transactionsListATRsCmd.Flags().Bool("lost", false, "Only list attempts which have been lost")
+}
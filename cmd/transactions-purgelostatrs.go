@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type TransactionsPurgeLostATRsOutput struct {
+	PurgedATRs  []string `json:"purgedAtrs"`
+	SkippedATRs int      `json:"skippedAtrs"`
+}
+
+var transactionsPurgeLostATRsCmd = &cobra.Command{
+	Use:   "purge-lost-atrs [cluster] [bucket]",
+	Short: "Removes active transaction records which only hold lost or finished attempts",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		loc := parseATRLocation(cmd, args[1])
+
+		deployer, cluster := helper.identifyTransactionsCluster(ctx, args[0])
+
+		result, err := deployer.PurgeLostATRs(ctx, cluster.GetID(), loc)
+		if err != nil {
+			logger.Fatal("failed to purge lost active transaction records", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Purged: %d\n", len(result.PurgedATRs))
+			for _, atrID := range result.PurgedATRs {
+				fmt.Printf("  %s\n", atrID)
+			}
+			fmt.Printf("Skipped: %d\n", result.SkippedATRs)
+		} else {
+			out := TransactionsPurgeLostATRsOutput{
+				PurgedATRs:  result.PurgedATRs,
+				SkippedATRs: result.SkippedATRs,
+			}
+			if out.PurgedATRs == nil {
+				out.PurgedATRs = []string{}
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	transactionsCmd.AddCommand(transactionsPurgeLostATRsCmd)
+
+	addATRLocationFlags(transactionsPurgeLostATRsCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var transactionsSetSettingsCmd = &cobra.Command{
+	Use:   "set-settings [cluster]",
+	Short: "Changes the transaction settings of a cluster, such as the cleanup window",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		settings := &dockerdeploy.TransactionSettings{}
+		settings.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		settings.CleanupWindow, _ = cmd.Flags().GetDuration("cleanup-window")
+		settings.NumATRs, _ = cmd.Flags().GetInt("num-atrs")
+		if cmd.Flags().Changed("cleanup-client-attempts") {
+			cleanupClientAttempts, _ := cmd.Flags().GetBool("cleanup-client-attempts")
+			settings.CleanupClientAttempts = &cleanupClientAttempts
+		}
+		if cmd.Flags().Changed("cleanup-lost-attempts") {
+			cleanupLostAttempts, _ := cmd.Flags().GetBool("cleanup-lost-attempts")
+			settings.CleanupLostAttempts = &cleanupLostAttempts
+		}
+
+		if settings.Timeout == 0 && settings.CleanupWindow == 0 && settings.NumATRs == 0 &&
+			settings.CleanupClientAttempts == nil && settings.CleanupLostAttempts == nil {
+			logger.Fatal("no settings were specified to update")
+		}
+
+		deployer, cluster := helper.identifyTransactionsCluster(ctx, args[0])
+
+		err := deployer.UpdateTransactionSettings(ctx, cluster.GetID(), settings)
+		if err != nil {
+			logger.Fatal("failed to update transaction settings", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	transactionsCmd.AddCommand(transactionsSetSettingsCmd)
+
+	transactionsSetSettingsCmd.Flags().Duration("timeout", 0, "The default timeout of query transactions")
+	transactionsSetSettingsCmd.Flags().Duration("cleanup-window", 0, "How often the active transaction records are checked for lost transactions")
+	transactionsSetSettingsCmd.Flags().Bool("cleanup-client-attempts", false, "Whether transactions started by query clients are cleaned up")
+	transactionsSetSettingsCmd.Flags().Bool("cleanup-lost-attempts", false, "Whether lost transactions are cleaned up")
+	transactionsSetSettingsCmd.Flags().Int("num-atrs", 0, "The number of active transaction records used by query transactions")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var transactionsCmd = &cobra.Command{
+	Use:   "transactions",
+	Short: "Provides the ability to configure transactions and manage their metadata",
+	Run:   nil,
+}
+
+// identifyTransactionsCluster identifies a cluster for the transactions
+// commands, which are currently only supported for docker clusters.
+func (h *CmdHelper) identifyTransactionsCluster(ctx context.Context, userInput string) (*dockerdeploy.Deployer, deployment.ClusterInfo) {
+	logger := h.GetLogger()
+
+	_, deployer, cluster := h.IdentifyCluster(ctx, userInput)
+
+	dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+	if !ok {
+		logger.Fatal("transactions are only supported for docker clusters",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	return dockerDeployer, cluster
+}
+
+func addATRLocationFlags(cmd *cobra.Command) {
+	cmd.Flags().String("scope", "_default", "The scope holding the transaction metadata")
+	cmd.Flags().String("collection", "_default", "The collection holding the transaction metadata")
+}
+
+func parseATRLocation(cmd *cobra.Command, bucketName string) *dockerdeploy.ATRLocation {
+	loc := &dockerdeploy.ATRLocation{
+		BucketName: bucketName,
+	}
+	loc.ScopeName, _ = cmd.Flags().GetString("scope")
+	loc.CollectionName, _ = cmd.Flags().GetString("collection")
+	return loc
+}
+
+func init() {
+	rootCmd.AddCommand(transactionsCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var udfsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Adds a new query function",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		scopeName, _ := cmd.Flags().GetString("scope")
+		params, _ := cmd.Flags().GetStringSlice("params")
+		expression, _ := cmd.Flags().GetString("expression")
+		libraryName, _ := cmd.Flags().GetString("library")
+		libraryFunction, _ := cmd.Flags().GetString("library-function")
+		replace, _ := cmd.Flags().GetBool("replace")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		err := deployer.CreateQueryFunction(ctx, cluster.GetID(), &deployment.CreateQueryFunctionOptions{
+			BucketName:      bucketName,
+			ScopeName:       scopeName,
+			Name:            args[1],
+			Params:          params,
+			Expression:      expression,
+			LibraryName:     libraryName,
+			LibraryFunction: libraryFunction,
+			Replace:         replace,
+		})
+		if err != nil {
+			logger.Fatal("failed to create query function", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	udfsCmd.AddCommand(udfsAddCmd)
+
+	udfsAddCmd.Flags().String("bucket", "", "The bucket of a scoped function")
+	udfsAddCmd.Flags().String("scope", "", "The scope of a scoped function")
+	udfsAddCmd.Flags().StringSlice("params", nil, "The names of the parameters of the function")
+	udfsAddCmd.Flags().String("expression", "", "The SQL++ expression of an inline function")
+	udfsAddCmd.Flags().String("library", "", "The javascript library implementing the function")
+	udfsAddCmd.Flags().String("library-function", "", "The function within the javascript library, defaulting to the function name")
+	udfsAddCmd.Flags().Bool("replace", false, "Replace an existing function with the same name")
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var udfsLibrariesAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Adds or replaces a javascript library from a file",
+	Args:  cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		scopeName, _ := cmd.Flags().GetString("scope")
+
+		code, err := os.ReadFile(args[2])
+		if err != nil {
+			logger.Fatal("failed to read library code", zap.Error(err))
+		}
+
+		if (bucketName == "") != (scopeName == "") {
+			logger.Fatal("scoped libraries require both a bucket and a scope")
+		}
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		err = deployer.CreateJsLibrary(ctx, cluster.GetID(), &deployment.CreateJsLibraryOptions{
+			BucketName: bucketName,
+			ScopeName:  scopeName,
+			Name:       args[1],
+			Code:       string(code),
+		})
+		if err != nil {
+			logger.Fatal("failed to create javascript library", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	udfsLibrariesCmd.AddCommand(udfsLibrariesAddCmd)
+
+	udfsLibrariesAddCmd.Flags().String("bucket", "", "The bucket of a scoped library")
+	udfsLibrariesAddCmd.Flags().String("scope", "", "The scope of a scoped library")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type UdfsLibrariesListOutput []UdfsLibrariesListOutput_Item
+
+type UdfsLibrariesListOutput_Item struct {
+	Bucket string `json:"bucket,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+}
+
+var udfsLibrariesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Lists all the javascript libraries",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		libraries, err := deployer.ListJsLibraries(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to list javascript libraries", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Libraries:\n")
+			for _, library := range libraries {
+				if library.BucketName == "" {
+					fmt.Printf("  %s\n",
+						library.Name)
+				} else {
+					fmt.Printf("  %s/%s/%s\n",
+						library.BucketName, library.ScopeName, library.Name)
+				}
+			}
+		} else {
+			var out UdfsLibrariesListOutput
+			for _, library := range libraries {
+				out = append(out, UdfsLibrariesListOutput_Item{
+					Bucket: library.BucketName,
+					Scope:  library.ScopeName,
+					Name:   library.Name,
+					Code:   library.Code,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	udfsLibrariesCmd.AddCommand(udfsLibrariesListCmd)
+}
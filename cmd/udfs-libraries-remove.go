@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var udfsLibrariesRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Aliases: []string{"rm"},
+	Short:   "Removes a javascript library",
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		scopeName, _ := cmd.Flags().GetString("scope")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		err := deployer.DeleteJsLibrary(ctx, cluster.GetID(), bucketName, scopeName, args[1])
+		if err != nil {
+			logger.Fatal("failed to delete javascript library", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	udfsLibrariesCmd.AddCommand(udfsLibrariesRemoveCmd)
+
+	udfsLibrariesRemoveCmd.Flags().String("bucket", "", "The bucket of a scoped library")
+	udfsLibrariesRemoveCmd.Flags().String("scope", "", "The scope of a scoped library")
+}
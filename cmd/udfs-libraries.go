@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var udfsLibrariesCmd = &cobra.Command{
+	Use:   "libraries",
+	Short: "Provides the ability to manipulate the javascript libraries used by query functions",
+	Run:   nil,
+}
+
+func init() {
+	udfsCmd.AddCommand(udfsLibrariesCmd)
+}
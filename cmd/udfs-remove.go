@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var udfsRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Aliases: []string{"rm"},
+	Short:   "Removes a query function",
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		bucketName, _ := cmd.Flags().GetString("bucket")
+		scopeName, _ := cmd.Flags().GetString("scope")
+
+		_, deployer, cluster := helper.IdentifyCluster(ctx, args[0])
+
+		err := deployer.DropQueryFunction(ctx, cluster.GetID(), bucketName, scopeName, args[1])
+		if err != nil {
+			logger.Fatal("failed to drop query function", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	udfsCmd.AddCommand(udfsRemoveCmd)
+
+	udfsRemoveCmd.Flags().String("bucket", "", "The bucket of a scoped function")
+	udfsRemoveCmd.Flags().String("scope", "", "The scope of a scoped function")
+}
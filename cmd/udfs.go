@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var udfsCmd = &cobra.Command{
+	Use:   "udfs",
+	Short: "Provides the ability to manipulate the query user-defined functions of a system",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(udfsCmd)
+}
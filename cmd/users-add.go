@@ -20,6 +20,7 @@ var usersAddCmd = &cobra.Command{
 		password, _ := cmd.Flags().GetString("password")
 		canRead, _ := cmd.Flags().GetBool("can-read")
 		canWrite, _ := cmd.Flags().GetBool("can-write")
+		buckets, _ := cmd.Flags().GetStringSlice("bucket")
 
 		if password == "" {
 			logger.Fatal("you must specify a password to use")
@@ -32,6 +33,7 @@ var usersAddCmd = &cobra.Command{
 			Password: password,
 			CanRead:  canRead,
 			CanWrite: canWrite,
+			Buckets:  buckets,
 		})
 		if err != nil {
 			logger.Fatal("failed to create user", zap.Error(err))
@@ -45,4 +47,5 @@ func init() {
 	usersAddCmd.Flags().String("password", "", "The password to assign to the user")
 	usersAddCmd.Flags().Bool("can-read", true, "Whether the user can read data")
 	usersAddCmd.Flags().Bool("can-write", true, "Whether the user can write data")
+	usersAddCmd.Flags().StringSlice("bucket", nil, "Restricts the data access of the user to these buckets")
 }
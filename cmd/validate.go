@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type ValidateOutput []ValidateOutput_Item
+
+type ValidateOutput_Item struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var validateCmd = &cobra.Command{
+	Use:     "validate [flags] [definition-tag | --def | --def-file]",
+	Short:   "Validates a cluster definition against a deployers constraints",
+	Example: "validate --def-file cluster.yaml --deployer cloud",
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		defStr, _ := cmd.Flags().GetString("def")
+		defFile, _ := cmd.Flags().GetString("def-file")
+		deployerName, _ := cmd.Flags().GetString("deployer")
+
+		simpleDefStr := ""
+		if len(args) >= 1 {
+			simpleDefStr = args[0]
+		}
+
+		def, err := helper.FetchClusterDef(simpleDefStr, defStr, defFile)
+		if err != nil {
+			logger.Fatal("failed to get definition", zap.Error(err))
+		}
+
+		if deployerName != "" {
+			def.Deployer = deployerName
+		}
+
+		var deployer deployment.Deployer
+		if def.Deployer == "" {
+			deployer = helper.GetDefaultDeployer(ctx)
+		} else {
+			deployer = helper.GetDeployerByName(ctx, def.Deployer)
+		}
+
+		violations, err := deployer.ValidateDefinition(ctx, def)
+		if err != nil {
+			logger.Fatal("failed to validate definition", zap.Error(err))
+		}
+
+		if !outputJson {
+			if len(violations) == 0 {
+				fmt.Printf("Definition is valid.\n")
+			} else {
+				fmt.Printf("Violations:\n")
+				for _, violation := range violations {
+					fmt.Printf("  %s: %s\n", violation.Field, violation.Message)
+				}
+			}
+		} else {
+			out := ValidateOutput{}
+			for _, violation := range violations {
+				out = append(out, ValidateOutput_Item{
+					Field:   violation.Field,
+					Message: violation.Message,
+				})
+			}
+			helper.OutputJson(out)
+		}
+
+		if len(violations) > 0 {
+			exitWithCode(ExitInvalidDefinition)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().String("def", "", "The cluster definition you wish to validate.")
+	validateCmd.Flags().String("def-file", "", "The path to a file containing a cluster definition to validate.")
+	validateCmd.Flags().String("deployer", "", "The name of the deployer to validate against")
+}
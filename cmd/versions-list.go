@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/versionlock"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type VersionsListOutput []VersionsListOutput_Item
+
+type VersionsListOutput_Item struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+var versionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the versions pinned in the versions lock file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		lockPath := helper.GetVersionsLockPath(ctx)
+
+		lock := &versionlock.Lock{}
+		if lockPath != "" {
+			var err error
+			lock, err = versionlock.Load(lockPath)
+			if err != nil {
+				logger.Fatal("failed to load versions lock", zap.Error(err))
+			}
+		}
+
+		if !outputJson {
+			if lockPath == "" {
+				fmt.Printf("No %s was found.\n", versionlock.FileName)
+				return
+			}
+
+			fmt.Printf("Pinned versions (%s):\n", lockPath)
+			for _, name := range lock.Names() {
+				fmt.Printf("  %s: %s\n", name, lock.Versions[name])
+			}
+		} else {
+			out := VersionsListOutput{}
+			for _, name := range lock.Names() {
+				out = append(out, VersionsListOutput_Item{
+					Name:    name,
+					Version: lock.Versions[name],
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	versionsCmd.AddCommand(versionsListCmd)
+
+	versionsListCmd.Flags().Bool("json", false, "Output as JSON")
+}
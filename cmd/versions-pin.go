@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/utils/versionlock"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var versionsPinCmd = &cobra.Command{
+	Use:     "pin [name] [version]",
+	Short:   "Pins a name such as stable to an exact server version",
+	Long:    "Pins a name such as stable to an exact server version in the versions lock file, which is created in the working directory if none is found.",
+	Example: "versions pin stable 7.6.2\nversions pin next 8.0.0-1234",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		name := args[0]
+		version := args[1]
+
+		lockPath := helper.GetVersionsLockPath(ctx)
+		if lockPath == "" {
+			lockPath = versionlock.FileName
+		}
+
+		lock, err := versionlock.Load(lockPath)
+		if err != nil {
+			logger.Fatal("failed to load versions lock", zap.Error(err))
+		}
+
+		oldVersion, err := lock.Pin(name, version)
+		if err != nil {
+			logger.Fatal("failed to pin version", zap.Error(err))
+		}
+
+		err = lock.Save(lockPath)
+		if err != nil {
+			logger.Fatal("failed to save versions lock", zap.Error(err))
+		}
+
+		logger.Info("pinned version",
+			zap.String("name", name),
+			zap.String("version", version),
+			zap.String("previousVersion", oldVersion),
+			zap.String("lock", lockPath))
+	},
+}
+
+func init() {
+	versionsCmd.AddCommand(versionsPinCmd)
+}
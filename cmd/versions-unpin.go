@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/utils/versionlock"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var versionsUnpinCmd = &cobra.Command{
+	Use:   "unpin [name]",
+	Short: "Removes a name from the versions lock file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		name := args[0]
+
+		lockPath := helper.GetVersionsLockPath(ctx)
+		if lockPath == "" {
+			logger.Fatal("no versions lock was found")
+		}
+
+		lock, err := versionlock.Load(lockPath)
+		if err != nil {
+			logger.Fatal("failed to load versions lock", zap.Error(err))
+		}
+
+		if !lock.Unpin(name) {
+			logger.Fatal("name is not pinned", zap.String("name", name))
+		}
+
+		err = lock.Save(lockPath)
+		if err != nil {
+			logger.Fatal("failed to save versions lock", zap.Error(err))
+		}
+
+		logger.Info("unpinned version",
+			zap.String("name", name),
+			zap.String("lock", lockPath))
+	},
+}
+
+func init() {
+	versionsCmd.AddCommand(versionsUnpinCmd)
+}
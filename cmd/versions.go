@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "Provides the ability to manage the versions pinned in the versions lock file",
+	Run:   nil,
+}
+
+func init() {
+	rootCmd.AddCommand(versionsCmd)
+}
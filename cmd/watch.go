@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type WatchOutput_Item struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Deployer  string    `json:"deployer"`
+	ClusterID string    `json:"cluster_id"`
+	NodeID    string    `json:"node_id,omitempty"`
+	State     string    `json:"state,omitempty"`
+	PrevState string    `json:"prev_state,omitempty"`
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watches clusters and outputs an event whenever one changes",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		type deployerEvent struct {
+			DeployerName string
+			Event        deployment.ClusterEvent
+		}
+
+		eventCh := make(chan deployerEvent)
+		for deployerName, deployer := range helper.GetAllDeployers(ctx) {
+			deployerName := deployerName
+
+			deployerEventCh, err := deployer.WatchClusters(ctx)
+			if err != nil {
+				logger.Fatal("failed to watch clusters",
+					zap.String("deployer", deployerName),
+					zap.Error(err))
+			}
+
+			go func() {
+				for event := range deployerEventCh {
+					eventCh <- deployerEvent{
+						DeployerName: deployerName,
+						Event:        event,
+					}
+				}
+			}()
+		}
+
+		for item := range eventCh {
+			event := item.Event
+
+			if !outputJson {
+				fmt.Printf("%s %-14s %s [Deployer: %s",
+					event.Time.Format(time.RFC3339),
+					event.Type,
+					event.ClusterID,
+					item.DeployerName)
+				if event.NodeID != "" {
+					fmt.Printf(", Node: %s", event.NodeID)
+				}
+				if event.PrevState != "" || event.State != "" {
+					fmt.Printf(", State: %s", event.State)
+				}
+				if event.PrevState != "" && event.State != "" {
+					fmt.Printf(" (was %s)", event.PrevState)
+				}
+				fmt.Printf("]\n")
+			} else {
+				helper.OutputJson(WatchOutput_Item{
+					Time:      event.Time,
+					Type:      string(event.Type),
+					Deployer:  item.DeployerName,
+					ClusterID: event.ClusterID,
+					NodeID:    event.NodeID,
+					State:     event.State,
+					PrevState: event.PrevState,
+				})
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var xdcrAddRemoteCmd = &cobra.Command{
+	Use:   "add-remote cluster target-cluster",
+	Short: "Registers another cluster as an XDCR remote of a cluster",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		name, _ := cmd.Flags().GetString("name")
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+		_, targetCluster := helper.identifyXdcrCluster(ctx, args[1])
+
+		if name == "" {
+			name = targetCluster.GetID()
+		}
+
+		err := deployer.AddXdcrRemote(ctx, cluster.GetID(), name, targetCluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to add remote", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrAddRemoteCmd)
+
+	xdcrAddRemoteCmd.Flags().String("name", "", "The name of the remote, defaults to the target cluster id.")
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type XdcrListOutput struct {
+	Remotes      []XdcrListOutput_Remote      `json:"remotes"`
+	Replications []XdcrListOutput_Replication `json:"replications"`
+}
+
+type XdcrListOutput_Remote struct {
+	Name     string `json:"name"`
+	UUID     string `json:"uuid"`
+	Hostname string `json:"hostname"`
+}
+
+type XdcrListOutput_Replication struct {
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	Source           string `json:"source"`
+	Target           string `json:"target"`
+	FilterExpression string `json:"filter_expression,omitempty"`
+}
+
+var xdcrListCmd = &cobra.Command{
+	Use:   "list cluster",
+	Short: "Lists the XDCR remotes and replications of a cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+
+		remotes, err := deployer.ListXdcrRemotes(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to list remotes", zap.Error(err))
+		}
+
+		replications, err := deployer.ListXdcrReplications(ctx, cluster.GetID())
+		if err != nil {
+			logger.Fatal("failed to list replications", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("Remotes:\n")
+			for _, remote := range remotes {
+				fmt.Printf("  %s  %s [%s]\n", remote.Name, remote.Hostname, remote.UUID)
+			}
+
+			fmt.Printf("Replications:\n")
+			for _, replication := range replications {
+				fmt.Printf("  %s [%s]\n", replication.ID, replication.Status)
+				if replication.FilterExpression != "" {
+					fmt.Printf("    filter: %s\n", replication.FilterExpression)
+				}
+			}
+		} else {
+			out := XdcrListOutput{
+				Remotes:      []XdcrListOutput_Remote{},
+				Replications: []XdcrListOutput_Replication{},
+			}
+			for _, remote := range remotes {
+				out.Remotes = append(out.Remotes, XdcrListOutput_Remote{
+					Name:     remote.Name,
+					UUID:     remote.UUID,
+					Hostname: remote.Hostname,
+				})
+			}
+			for _, replication := range replications {
+				out.Replications = append(out.Replications, XdcrListOutput_Replication{
+					ID:               replication.ID,
+					Status:           replication.Status,
+					Source:           replication.Source,
+					Target:           replication.Target,
+					FilterExpression: replication.FilterExpression,
+				})
+			}
+			helper.OutputJson(out)
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrListCmd)
+}
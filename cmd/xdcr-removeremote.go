@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var xdcrRemoveRemoteCmd = &cobra.Command{
+	Use:   "remove-remote cluster name",
+	Short: "Removes an XDCR remote from a cluster",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+
+		err := deployer.RemoveXdcrRemote(ctx, cluster.GetID(), args[1])
+		if err != nil {
+			logger.Fatal("failed to remove remote", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrRemoveRemoteCmd)
+}
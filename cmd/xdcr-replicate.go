@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type XdcrReplicateOutput struct {
+	ID string `json:"id"`
+}
+
+var xdcrReplicateCmd = &cobra.Command{
+	Use:   "replicate cluster bucket remote target-bucket",
+	Short: "Creates a replication of a bucket to a remote",
+	Args:  cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		outputJson, _ := cmd.Flags().GetBool("json")
+		paused, _ := cmd.Flags().GetBool("paused")
+
+		settings, err := parseReplicationSettings(cmd)
+		if err != nil {
+			logger.Fatal("invalid replication settings", zap.Error(err))
+		}
+		if paused {
+			settings.PauseRequested = &paused
+		}
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+
+		replicationID, err := deployer.CreateXdcrReplication(ctx, cluster.GetID(), &clustercontrol.CreateReplicationRequest{
+			FromBucket:          args[1],
+			ToCluster:           args[2],
+			ToBucket:            args[3],
+			ReplicationSettings: *settings,
+		})
+		if err != nil {
+			logger.Fatal("failed to create replication", zap.Error(err))
+		}
+
+		if !outputJson {
+			fmt.Printf("%s\n", replicationID)
+		} else {
+			helper.OutputJson(XdcrReplicateOutput{
+				ID: replicationID,
+			})
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrReplicateCmd)
+
+	addReplicationSettingsFlags(xdcrReplicateCmd)
+	xdcrReplicateCmd.Flags().Bool("paused", false, "Whether the replication is created paused.")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var xdcrResumeCmd = &cobra.Command{
+	Use:   "resume cluster replication-id",
+	Short: "Resumes a replication",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+
+		paused := false
+		err := deployer.UpdateXdcrReplication(ctx, cluster.GetID(), args[1], &clustercontrol.ReplicationSettings{
+			PauseRequested: &paused,
+		})
+		if err != nil {
+			logger.Fatal("failed to resume replication", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrResumeCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var xdcrUpdateCmd = &cobra.Command{
+	Use:   "update cluster replication-id",
+	Short: "Changes the settings of a replication",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		helper := CmdHelper{}
+		logger := helper.GetLogger()
+		ctx := helper.GetContext()
+
+		settings, err := parseReplicationSettings(cmd)
+		if err != nil {
+			logger.Fatal("invalid replication settings", zap.Error(err))
+		}
+
+		deployer, cluster := helper.identifyXdcrCluster(ctx, args[0])
+
+		err = deployer.UpdateXdcrReplication(ctx, cluster.GetID(), args[1], settings)
+		if err != nil {
+			logger.Fatal("failed to update replication", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	xdcrCmd.AddCommand(xdcrUpdateCmd)
+
+	addReplicationSettingsFlags(xdcrUpdateCmd)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/deployment/dockerdeploy"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var xdcrCmd = &cobra.Command{
+	Use:   "xdcr",
+	Short: "Provides the ability to manage XDCR replications between clusters",
+	Run:   nil,
+}
+
+// identifyXdcrCluster identifies a cluster for the xdcr commands, which are
+// currently only supported for docker clusters.
+func (h *CmdHelper) identifyXdcrCluster(ctx context.Context, userInput string) (*dockerdeploy.Deployer, deployment.ClusterInfo) {
+	logger := h.GetLogger()
+
+	_, deployer, cluster := h.IdentifyCluster(ctx, userInput)
+
+	dockerDeployer, ok := deployer.(*dockerdeploy.Deployer)
+	if !ok {
+		logger.Fatal("xdcr is only supported for docker clusters",
+			zap.Error(deployment.ErrFeatureUnsupported))
+	}
+
+	return dockerDeployer, cluster
+}
+
+func addReplicationSettingsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("filter", "", "Only replicates documents matching this filter expression.")
+	cmd.Flags().Bool("filter-expiration", false, "Whether expirations are not replicated.")
+	cmd.Flags().Bool("filter-deletion", false, "Whether deletions are not replicated.")
+	cmd.Flags().Bool("bypass-expiry", false, "Whether document expiry is removed when replicating.")
+	cmd.Flags().String("compression", "", "The compression to use (auto, none).")
+	cmd.Flags().String("priority", "", "The priority of the replication (high, medium, low).")
+	cmd.Flags().StringArray("map", nil, "Maps a source scope or collection to a target (source=target), or denies it (source=).")
+	cmd.Flags().Bool("migration", false, "Whether mappings are migration rules from the default collection.")
+	cmd.Flags().Int("checkpoint-interval", 0, "The interval between checkpoints in seconds.")
+	cmd.Flags().Int("worker-batch-size", 0, "The number of mutations in a batch.")
+	cmd.Flags().Int("doc-batch-size-kb", 0, "The size of a batch of documents in KB.")
+	cmd.Flags().Int("source-nozzles", 0, "The number of source nozzles per node.")
+	cmd.Flags().Int("target-nozzles", 0, "The number of target nozzles per node.")
+	cmd.Flags().Int("network-usage-limit", 0, "The bandwidth limit of the replication in MiB/s.")
+}
+
+// parseReplicationSettings builds replication settings from the flags which
+// were explicitly specified, leaving the others unchanged.
+func parseReplicationSettings(cmd *cobra.Command) (*clustercontrol.ReplicationSettings, error) {
+	flags := cmd.Flags()
+	settings := &clustercontrol.ReplicationSettings{}
+
+	getBoolPtr := func(name string) *bool {
+		if !flags.Changed(name) {
+			return nil
+		}
+		value, _ := flags.GetBool(name)
+		return &value
+	}
+
+	settings.FilterExpression, _ = flags.GetString("filter")
+	settings.FilterExpiration = getBoolPtr("filter-expiration")
+	settings.FilterDeletion = getBoolPtr("filter-deletion")
+	settings.FilterBypassExpiry = getBoolPtr("bypass-expiry")
+
+	compression, _ := flags.GetString("compression")
+	switch strings.ToLower(compression) {
+	case "":
+	case "auto":
+		settings.CompressionType = "Auto"
+	case "none":
+		settings.CompressionType = "None"
+	default:
+		return nil, fmt.Errorf("unexpected compression `%s`", compression)
+	}
+
+	priority, _ := flags.GetString("priority")
+	switch strings.ToLower(priority) {
+	case "":
+	case "high":
+		settings.Priority = "High"
+	case "medium":
+		settings.Priority = "Medium"
+	case "low":
+		settings.Priority = "Low"
+	default:
+		return nil, fmt.Errorf("unexpected priority `%s`", priority)
+	}
+
+	mappings, _ := flags.GetStringArray("map")
+	if len(mappings) > 0 {
+		settings.CollectionMappings = make(map[string]string)
+		for _, mapping := range mappings {
+			source, target, ok := strings.Cut(mapping, "=")
+			if !ok || source == "" {
+				return nil, fmt.Errorf("invalid mapping `%s`, expected source=target", mapping)
+			}
+			settings.CollectionMappings[source] = target
+		}
+	}
+
+	migration, _ := flags.GetBool("migration")
+	if migration {
+		settings.CollectionsMigrationMode = &migration
+	} else if len(mappings) > 0 {
+		explicitMapping := true
+		settings.CollectionsExplicitMapping = &explicitMapping
+	}
+
+	settings.CheckpointInterval, _ = flags.GetInt("checkpoint-interval")
+	settings.WorkerBatchSize, _ = flags.GetInt("worker-batch-size")
+	settings.DocBatchSizeKb, _ = flags.GetInt("doc-batch-size-kb")
+	settings.SourceNozzlePerNode, _ = flags.GetInt("source-nozzles")
+	settings.TargetNozzlePerNode, _ = flags.GetInt("target-nozzles")
+	settings.NetworkUsageLimit, _ = flags.GetInt("network-usage-limit")
+
+	if settings.CollectionsMigrationMode != nil && len(mappings) == 0 {
+		return nil, errors.New("migration requires at least one mapping")
+	}
+
+	return settings, nil
+}
+
+func init() {
+	rootCmd.AddCommand(xdcrCmd)
+}
@@ -0,0 +1,73 @@
+package deployment
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+type BucketType string
+
+const (
+	BucketTypeCouchbase BucketType = "couchbase"
+	BucketTypeEphemeral BucketType = "ephemeral"
+	BucketTypeMemcached BucketType = "memcached"
+)
+
+// evictionPolicies are the eviction policies each type of bucket supports,
+// with the default listed first.
+var evictionPolicies = map[BucketType][]string{
+	BucketTypeCouchbase: {"valueOnly", "fullEviction"},
+	BucketTypeEphemeral: {"noEviction", "nruEviction"},
+	BucketTypeMemcached: nil,
+}
+
+// BucketFeature returns the feature a type of bucket requires, if any.
+func BucketFeature(bucketType BucketType) (Feature, bool) {
+	switch bucketType {
+	case BucketTypeEphemeral:
+		return FeatureEphemeralBuckets, true
+	case BucketTypeMemcached:
+		return FeatureMemcachedBuckets, true
+	}
+	return "", false
+}
+
+// ResolveBucketOptions checks the options of a new bucket are valid for its
+// type, and fills in the default type and eviction policy.
+func ResolveBucketOptions(opts *CreateBucketOptions) (*CreateBucketOptions, error) {
+	resolved := *opts
+
+	if resolved.BucketType == "" {
+		resolved.BucketType = BucketTypeCouchbase
+	}
+
+	policies, ok := evictionPolicies[resolved.BucketType]
+	if !ok {
+		return nil, errors.Errorf("unexpected bucket type `%s`", resolved.BucketType)
+	}
+
+	if resolved.EvictionPolicy != "" && !slices.Contains(policies, resolved.EvictionPolicy) {
+		if len(policies) == 0 {
+			return nil, errors.Errorf("%s buckets do not support eviction policies", resolved.BucketType)
+		}
+		return nil, errors.Errorf("%s buckets only support the %v eviction policies", resolved.BucketType, policies)
+	}
+	if resolved.EvictionPolicy == "" && len(policies) > 0 {
+		resolved.EvictionPolicy = policies[0]
+	}
+
+	if resolved.BucketType != BucketTypeCouchbase {
+		if resolved.StorageBackend != "" {
+			return nil, errors.Errorf("%s buckets do not support storage backends", resolved.BucketType)
+		}
+		if resolved.HistoryRetentionBytes != 0 || resolved.HistoryRetentionDuration != 0 {
+			return nil, errors.Errorf("%s buckets do not support history retention", resolved.BucketType)
+		}
+	}
+
+	if resolved.BucketType == BucketTypeMemcached && resolved.NumReplicas > 0 {
+		return nil, errors.New("memcached buckets do not support replicas")
+	}
+
+	return &resolved, nil
+}
@@ -0,0 +1,39 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBucketOptions(t *testing.T) {
+	opts, err := ResolveBucketOptions(&CreateBucketOptions{Name: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, BucketTypeCouchbase, opts.BucketType)
+	assert.Equal(t, "valueOnly", opts.EvictionPolicy)
+
+	opts, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeEphemeral})
+	require.NoError(t, err)
+	assert.Equal(t, "noEviction", opts.EvictionPolicy)
+
+	opts, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeEphemeral, EvictionPolicy: "nruEviction"})
+	require.NoError(t, err)
+	assert.Equal(t, "nruEviction", opts.EvictionPolicy)
+
+	opts, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeMemcached})
+	require.NoError(t, err)
+	assert.Empty(t, opts.EvictionPolicy)
+
+	_, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeEphemeral, EvictionPolicy: "fullEviction"})
+	assert.ErrorContains(t, err, "ephemeral buckets only support the [noEviction nruEviction] eviction policies")
+
+	_, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeEphemeral, StorageBackend: "magma"})
+	assert.Error(t, err)
+
+	_, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: BucketTypeMemcached, NumReplicas: 1})
+	assert.Error(t, err)
+
+	_, err = ResolveBucketOptions(&CreateBucketOptions{BucketType: "bogus"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,491 @@
+package caodeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	BackupStrategyFullOnly             = "full_only"
+	BackupStrategyFullIncremental      = "full_incremental"
+	BackupStrategyImmediateFull        = "immediate_full"
+	BackupStrategyImmediateIncremental = "immediate_incremental"
+)
+
+type CreateBackupOptions struct {
+	Name string
+
+	// Strategy is one of the operator backup strategies, defaulting to
+	// immediate_full which takes a single full backup straight away.
+	Strategy string
+
+	// FullSchedule and IncrementalSchedule are cron schedules, which are
+	// required by the scheduled strategies.
+	FullSchedule        string
+	IncrementalSchedule string
+
+	// Size is the size of the volume the backups are stored on, such as 5Gi.
+	Size string
+
+	// BackupVersion is the operator backup image version used to enable
+	// managed backups on clusters which were allocated without them.
+	BackupVersion string
+}
+
+type CreateRestoreOptions struct {
+	Name       string
+	BackupName string
+
+	// Repo is the repository to restore from, defaulting to the repository
+	// the backup is currently writing to.
+	Repo string
+}
+
+type BackupInfo struct {
+	Name        string
+	Strategy    string
+	Running     bool
+	Failed      bool
+	Repo        string
+	LastRun     time.Time
+	LastSuccess time.Time
+	LastFailure time.Time
+	Backups     []BackupRepoInfo
+}
+
+type BackupRepoInfo struct {
+	Name         string
+	Full         string
+	Incrementals []string
+}
+
+type RestoreInfo struct {
+	Name        string
+	BackupName  string
+	Repo        string
+	Running     bool
+	Failed      bool
+	LastRun     time.Time
+	LastSuccess time.Time
+	LastFailure time.Time
+}
+
+type BackupJobInfo = caocontrol.BackupJobInfo
+
+func (d *Deployer) requireClusterNamespace(ctx context.Context, clusterID string) (string, error) {
+	namespace, err := d.getClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return "", err
+	}
+	if namespace == "" {
+		return "", fmt.Errorf("failed to find namespace for cluster `%s`", clusterID)
+	}
+
+	return namespace, nil
+}
+
+func (d *Deployer) generateBackupSpec(ctx context.Context, version string, isOpenShift bool) (map[string]interface{}, error) {
+	imagePath, err := caocontrol.GetBackupImage(ctx, version, isOpenShift)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to identify backup image")
+	}
+
+	return map[string]interface{}{
+		"managed":            true,
+		"image":              imagePath,
+		"serviceAccountName": caocontrol.BackupServiceAccountName,
+		"imagePullSecrets": []interface{}{
+			map[string]interface{}{
+				"name": caocontrol.GhcrSecretName,
+			},
+		},
+	}, nil
+}
+
+func (d *Deployer) enableBackups(ctx context.Context, namespace string, version string) error {
+	managed, err := d.client.IsCouchbaseClusterBackupManaged(ctx, namespace, CouchbaseClusterName)
+	if err != nil {
+		return err
+	}
+	if managed {
+		return nil
+	}
+
+	if version == "" {
+		return errors.New("cluster does not have managed backups enabled, a backup image version must be specified")
+	}
+
+	isOpenShift, err := d.client.IsOpenShift(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to detect whether we are using openshift")
+	}
+
+	backupSpec, err := d.generateBackupSpec(ctx, version, isOpenShift)
+	if err != nil {
+		return err
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would enable managed backups",
+			zap.String("namespace", namespace),
+			zap.Any("spec", backupSpec))
+		return nil
+	}
+
+	err = d.client.InstallBackupRbac(ctx, namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to install backup rbac")
+	}
+
+	err = d.client.EnableCouchbaseClusterBackup(ctx, namespace, CouchbaseClusterName, backupSpec)
+	if err != nil {
+		return errors.Wrap(err, "failed to enable managed backups")
+	}
+
+	return nil
+}
+
+// CreateBackup creates a CouchbaseBackup resource for the cluster, first
+// enabling operator managed backups on the cluster if necessary.
+func (d *Deployer) CreateBackup(ctx context.Context, clusterID string, opts *CreateBackupOptions) error {
+	if opts.Name == "" {
+		return errors.New("a backup name must be specified")
+	}
+
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = BackupStrategyImmediateFull
+	}
+
+	backupSpec := map[string]interface{}{
+		"strategy": strategy,
+	}
+
+	switch strategy {
+	case BackupStrategyImmediateFull, BackupStrategyImmediateIncremental:
+	case BackupStrategyFullOnly:
+		if opts.FullSchedule == "" {
+			return errors.New("the full_only strategy requires a full schedule")
+		}
+		backupSpec["full"] = map[string]interface{}{
+			"schedule": opts.FullSchedule,
+		}
+	case BackupStrategyFullIncremental:
+		if opts.FullSchedule == "" || opts.IncrementalSchedule == "" {
+			return errors.New("the full_incremental strategy requires a full and incremental schedule")
+		}
+		backupSpec["full"] = map[string]interface{}{
+			"schedule": opts.FullSchedule,
+		}
+		backupSpec["incremental"] = map[string]interface{}{
+			"schedule": opts.IncrementalSchedule,
+		}
+	default:
+		return fmt.Errorf("unknown backup strategy `%s`", strategy)
+	}
+
+	if opts.Size != "" {
+		backupSpec["size"] = opts.Size
+	}
+
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.enableBackups(ctx, namespace, opts.BackupVersion)
+	if err != nil {
+		return err
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would create couchbase backup",
+			zap.String("namespace", namespace),
+			zap.String("name", opts.Name),
+			zap.Any("spec", backupSpec))
+		return nil
+	}
+
+	err = d.client.CreateCouchbaseBackup(ctx, namespace, opts.Name, backupSpec)
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup")
+	}
+
+	return nil
+}
+
+func backupStatusTimes(status *caocontrol.CouchbaseBackupStatus) (time.Time, time.Time, time.Time) {
+	var lastRun, lastSuccess, lastFailure time.Time
+	if status.LastRun != nil {
+		lastRun = status.LastRun.Time
+	}
+	if status.LastSuccess != nil {
+		lastSuccess = status.LastSuccess.Time
+	}
+	if status.LastFailure != nil {
+		lastFailure = status.LastFailure.Time
+	}
+	return lastRun, lastSuccess, lastFailure
+}
+
+func (d *Deployer) ListBackups(ctx context.Context, clusterID string) ([]*BackupInfo, error) {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := d.client.ListCouchbaseBackups(ctx, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list backups")
+	}
+
+	var out []*BackupInfo
+	for resIdx := range resources {
+		res := &resources[resIdx]
+
+		status, err := d.client.ParseCouchbaseBackupStatus(res)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse backup status")
+		}
+
+		strategy, _, _ := unstructured.NestedString(res.Object, "spec", "strategy")
+		lastRun, lastSuccess, lastFailure := backupStatusTimes(status)
+
+		info := &BackupInfo{
+			Name:        res.GetName(),
+			Strategy:    strategy,
+			Running:     status.Running,
+			Failed:      status.Failed,
+			Repo:        status.Repo,
+			LastRun:     lastRun,
+			LastSuccess: lastSuccess,
+			LastFailure: lastFailure,
+		}
+		for _, backup := range status.Backups {
+			info.Backups = append(info.Backups, BackupRepoInfo{
+				Name:         backup.Name,
+				Full:         backup.Full,
+				Incrementals: backup.Incrementals,
+			})
+		}
+
+		out = append(out, info)
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) DeleteBackup(ctx context.Context, clusterID string, name string) error {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would delete couchbase backup",
+			zap.String("namespace", namespace),
+			zap.String("name", name))
+		return nil
+	}
+
+	err = d.client.DeleteCouchbaseBackup(ctx, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete backup")
+	}
+
+	return nil
+}
+
+// CreateRestore creates a CouchbaseBackupRestore resource which restores
+// all the backups of a repository of an existing backup into the cluster.
+func (d *Deployer) CreateRestore(ctx context.Context, clusterID string, opts *CreateRestoreOptions) error {
+	if opts.Name == "" {
+		return errors.New("a restore name must be specified")
+	}
+	if opts.BackupName == "" {
+		return errors.New("a backup name must be specified")
+	}
+
+	repo := opts.Repo
+	if repo == "" {
+		backups, err := d.ListBackups(ctx, clusterID)
+		if err != nil {
+			return err
+		}
+
+		for _, backup := range backups {
+			if backup.Name == opts.BackupName {
+				repo = backup.Repo
+			}
+		}
+		if repo == "" {
+			return fmt.Errorf("backup `%s` has no repository to restore from yet", opts.BackupName)
+		}
+	}
+
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	restoreSpec := map[string]interface{}{
+		"backup": opts.BackupName,
+		"repo":   repo,
+		"start": map[string]interface{}{
+			"str": "oldest",
+		},
+		"end": map[string]interface{}{
+			"str": "latest",
+		},
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would create couchbase backup restore",
+			zap.String("namespace", namespace),
+			zap.String("name", opts.Name),
+			zap.Any("spec", restoreSpec))
+		return nil
+	}
+
+	err = d.client.CreateCouchbaseBackupRestore(ctx, namespace, opts.Name, restoreSpec)
+	if err != nil {
+		return errors.Wrap(err, "failed to create restore")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListRestores(ctx context.Context, clusterID string) ([]*RestoreInfo, error) {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := d.client.ListCouchbaseBackupRestores(ctx, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list restores")
+	}
+
+	var out []*RestoreInfo
+	for resIdx := range resources {
+		res := &resources[resIdx]
+
+		status, err := d.client.ParseCouchbaseBackupStatus(res)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse restore status")
+		}
+
+		backupName, _, _ := unstructured.NestedString(res.Object, "spec", "backup")
+		repo, _, _ := unstructured.NestedString(res.Object, "spec", "repo")
+		lastRun, lastSuccess, lastFailure := backupStatusTimes(status)
+
+		out = append(out, &RestoreInfo{
+			Name:        res.GetName(),
+			BackupName:  backupName,
+			Repo:        repo,
+			Running:     status.Running,
+			Failed:      status.Failed,
+			LastRun:     lastRun,
+			LastSuccess: lastSuccess,
+			LastFailure: lastFailure,
+		})
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) DeleteRestore(ctx context.Context, clusterID string, name string) error {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would delete couchbase backup restore",
+			zap.String("namespace", namespace),
+			zap.String("name", name))
+		return nil
+	}
+
+	err = d.client.DeleteCouchbaseBackupRestore(ctx, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete restore")
+	}
+
+	return nil
+}
+
+// ListBackupJobs lists the jobs of the backups and restores of a cluster.
+// When ownerName is specified, only the jobs of that backup or restore are
+// returned.
+func (d *Deployer) ListBackupJobs(ctx context.Context, clusterID string, ownerName string) ([]*BackupJobInfo, error) {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := d.client.ListBackupJobs(ctx, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list backup jobs")
+	}
+
+	if ownerName == "" {
+		return jobs, nil
+	}
+
+	var out []*BackupJobInfo
+	for _, job := range jobs {
+		if job.OwnerName == ownerName {
+			out = append(out, job)
+		}
+	}
+
+	return out, nil
+}
+
+// WaitBackupJob waits for the first job of a backup or restore to complete,
+// returning an error if the job failed.
+func (d *Deployer) WaitBackupJob(ctx context.Context, clusterID string, ownerName string) (*BackupJobInfo, error) {
+	for {
+		jobs, err := d.ListBackupJobs(ctx, clusterID, ownerName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(jobs) > 0 {
+			job := jobs[0]
+			if job.Succeeded > 0 {
+				return job, nil
+			}
+			if job.Failed > 0 && job.Active == 0 {
+				return job, fmt.Errorf("job `%s` failed", job.Name)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (d *Deployer) GetBackupJobLogs(ctx context.Context, clusterID string, jobName string) (string, error) {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	logs, err := d.client.GetJobLogs(ctx, namespace, jobName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get job logs")
+	}
+
+	return logs, nil
+}
@@ -3,12 +3,14 @@ package caodeploy
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/cbdcuuid"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -23,6 +25,10 @@ const (
 type Deployer struct {
 	logger *zap.Logger
 	client *caocontrol.Controller
+	dryRun bool
+
+	gracePeriod time.Duration
+	onExpiring  deployment.ExpiringHook
 }
 
 var _ deployment.Deployer = (*Deployer)(nil)
@@ -30,12 +36,29 @@ var _ deployment.Deployer = (*Deployer)(nil)
 type NewDeployerOptions struct {
 	Logger *zap.Logger
 	Client *caocontrol.Controller
+
+	// DryRun causes destructive operations to only log the kubernetes
+	// changes they would have made rather than actually making them.
+	DryRun bool
+
+	// ExpiryGracePeriod is how long clusters are kept around after they
+	// expire before they are actually removed by Cleanup.
+	ExpiryGracePeriod time.Duration
+
+	// OnClusterExpiring is invoked during each Cleanup for every cluster
+	// which is in the expiring phase, as there is nowhere to record that a
+	// cluster was notified, so hooks must ignore repeated notifications.
+	OnClusterExpiring deployment.ExpiringHook
 }
 
 func NewDeployer(opts *NewDeployerOptions) (*Deployer, error) {
 	return &Deployer{
 		logger: opts.Logger,
 		client: opts.Client,
+		dryRun: opts.DryRun,
+
+		gracePeriod: opts.ExpiryGracePeriod,
+		onExpiring:  opts.OnClusterExpiring,
 	}, nil
 }
 
@@ -103,6 +126,11 @@ func (d *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 				}
 			}
 
+			expiryPhase := deployment.GetExpiryPhase(expiryTime, d.gracePeriod, time.Now())
+			if expiryPhase != deployment.ExpiryPhaseActive && clusterStatus == "available" {
+				clusterStatus = deployment.ClusterStateExpiring
+			}
+
 			clusters = append(clusters, &ClusterInfo{
 				ClusterID: namespace.Labels["cbdc2.cluster_id"],
 				Expiry:    expiryTime,
@@ -156,20 +184,58 @@ func (d *Deployer) generateClusterSpec(
 			return nil, errors.Wrap(err, "failed to generate cao server services list")
 		}
 
-		serversRes = append(serversRes, map[string]interface{}{
+		podSpec := map[string]interface{}{
+			"imagePullSecrets": []map[string]interface{}{
+				{
+					"name": caocontrol.GhcrSecretName,
+				},
+			},
+		}
+		if len(nodeGrp.Cao.NodeSelector) > 0 {
+			podSpec["nodeSelector"] = nodeGrp.Cao.NodeSelector
+		}
+		if len(nodeGrp.Cao.Tolerations) > 0 {
+			var tolerationsRes []map[string]interface{}
+			for _, toleration := range nodeGrp.Cao.Tolerations {
+				tolerationRes := map[string]interface{}{
+					"key":    toleration.Key,
+					"effect": toleration.Effect,
+				}
+				if toleration.Operator != "" {
+					tolerationRes["operator"] = toleration.Operator
+				}
+				if toleration.Value != "" {
+					tolerationRes["value"] = toleration.Value
+				}
+				tolerationsRes = append(tolerationsRes, tolerationRes)
+			}
+			podSpec["tolerations"] = tolerationsRes
+		}
+
+		podRes := map[string]interface{}{
+			"spec": podSpec,
+		}
+		if nodeGrp.Name != "" {
+			// the server class names are left positional so that naming a
+			// group does not cause its pods to be replaced
+			podRes["metadata"] = map[string]interface{}{
+				"labels": map[string]string{
+					"cbdc2.node_group": nodeGrp.Name,
+				},
+			}
+		}
+
+		serverRes := map[string]interface{}{
 			"size":     nodeGrp.Count,
 			"name":     fmt.Sprintf("group_%d", nodeGrpIdx),
 			"services": caoServices,
-			"pod": map[string]interface{}{
-				"spec": map[string]interface{}{
-					"imagePullSecrets": []map[string]interface{}{
-						{
-							"name": caocontrol.GhcrSecretName,
-						},
-					},
-				},
-			},
-		})
+			"pod":      podRes,
+		}
+		if len(nodeGrp.Cao.Zones) > 0 {
+			serverRes["serverGroups"] = nodeGrp.Cao.Zones
+		}
+
+		serversRes = append(serversRes, serverRes)
 	}
 
 	cngSpec := make(map[string]interface{})
@@ -201,10 +267,45 @@ func (d *Deployer) generateClusterSpec(
 		},
 		"servers": serversRes,
 	}
+	if len(def.Cao.ServerGroups) > 0 {
+		clusterSpec["serverGroups"] = def.Cao.ServerGroups
+	}
+	if def.Cao.AntiAffinity {
+		clusterSpec["antiAffinity"] = true
+	}
+	if def.Cao.BackupVersion != "" {
+		backupSpec, err := d.generateBackupSpec(ctx, def.Cao.BackupVersion, isOpenShift)
+		if err != nil {
+			return nil, err
+		}
+
+		clusterSpec["backup"] = backupSpec
+	}
 
 	return clusterSpec, nil
 }
 
+func (d *Deployer) WatchClusters(ctx context.Context) (<-chan deployment.ClusterEvent, error) {
+	triggerCh := make(chan struct{}, 1)
+	err := d.client.WatchCouchbaseClusters(ctx, func() {
+		select {
+		case triggerCh <- struct{}{}:
+		default:
+			// a relisting is already pending
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to watch couchbase clusters")
+	}
+
+	return deployment.WatchClusterChanges(ctx, &deployment.ClusterWatcherOptions{
+		Logger:       d.logger,
+		ListClusters: d.ListClusters,
+		Triggers:     triggerCh,
+		PollInterval: 1 * time.Minute,
+	})
+}
+
 func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (deployment.ClusterInfo, error) {
 	isOpenShift, err := d.client.IsOpenShift(ctx)
 	if err != nil {
@@ -213,7 +314,13 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 	if def.Columnar {
 		return nil, errors.New("columnar is not supported for caodeploy")
 	}
+
+	err = deployment.CheckNodeGroupFeatures(def.NodeGroups)
+	if err != nil {
+		return nil, err
+	}
 	clusterID := cbdcuuid.New()
+	deployment.TrackPartialCluster(ctx, clusterID.String())
 	namespace := "cbdc2-" + clusterID.String()
 
 	expiryTime := time.Time{}
@@ -230,6 +337,8 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		password = def.Cao.Password
 	}
 
+	progress.Step(ctx, "creating namespace")
+
 	err = d.client.CreateNamespace(ctx, namespace, map[string]string{
 		"cbdc2.type":       "cluster",
 		"cbdc2.cluster_id": clusterID.String(),
@@ -245,6 +354,8 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		return nil, errors.Wrap(err, "failed to install ghcr secret")
 	}
 
+	progress.Step(ctx, "installing operator")
+
 	err = d.client.InstallOperator(ctx, namespace, def.Cao.OperatorVersion, isOpenShift)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to install operator")
@@ -260,10 +371,20 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		return nil, errors.Wrap(err, "failed to generate cluster spec")
 	}
 
+	if def.Cao.BackupVersion != "" {
+		err = d.client.InstallBackupRbac(ctx, namespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to install backup rbac")
+		}
+	}
+
+	progress.Step(ctx, "creating cluster")
+
 	err = d.client.CreateCouchbaseCluster(ctx,
 		namespace, CouchbaseClusterName, nil,
 		clusterSpec)
 	if err != nil {
+		d.collectFailureDiagnostics(ctx, clusterID.String(), namespace)
 		return nil, errors.Wrap(err, "failed to create cluster resource")
 	}
 
@@ -271,6 +392,7 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 	if err != nil {
 		d.logger.Info("no cng service detected")
 	} else {
+		progress.Step(ctx, "waiting for cng service")
 		d.logger.Info("cng service detected, waiting for endpoints to be available")
 
 		err := d.client.WaitServiceHasEndpoints(ctx, namespace, CngServiceName)
@@ -285,6 +407,8 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		}
 	}
 
+	progress.Complete(ctx)
+
 	return ClusterInfo{
 		ClusterID: clusterID.String(),
 		Expiry:    time.Time{},
@@ -292,6 +416,51 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 	}, nil
 }
 
+func (d *Deployer) ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]deployment.DefinitionViolation, error) {
+	violations := deployment.ValidateCommonDefinition(def)
+
+	if def.Columnar {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "columnar",
+			Message: "columnar is not supported for cao deploy",
+		})
+	}
+
+	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
+		_, err := clusterdef.ServicesToCaoServices(nodeGrp.Services)
+		if err != nil {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   deployment.NodeGroupField(nodeGrpIdx, "services"),
+				Message: err.Error(),
+			})
+		}
+
+		// the operator only places pods in zones which are server groups
+		// of the cluster, so pinned zones must be among them
+		for _, zone := range nodeGrp.Cao.Zones {
+			if !slices.Contains(def.Cao.ServerGroups, zone) {
+				violations = append(violations, deployment.DefinitionViolation{
+					Field:   deployment.NodeGroupField(nodeGrpIdx, "cao.zones"),
+					Message: fmt.Sprintf("zone `%s` is not one of the cluster server-groups", zone),
+				})
+			}
+		}
+
+		for _, toleration := range nodeGrp.Cao.Tolerations {
+			switch toleration.Effect {
+			case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+			default:
+				violations = append(violations, deployment.DefinitionViolation{
+					Field:   deployment.NodeGroupField(nodeGrpIdx, "cao.tolerations"),
+					Message: fmt.Sprintf("unknown toleration effect `%s`", toleration.Effect),
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
 func (d *Deployer) GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error) {
 	return nil, errors.New("caodeploy does not support fetching the cluster definition")
 }
@@ -312,6 +481,11 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 		}
 	}
 
+	err = deployment.CheckNodeGroupFeatures(def.NodeGroups)
+	if err != nil {
+		return err
+	}
+
 	namespaceName, err := d.getClusterNamespace(ctx, clusterID)
 	if err != nil {
 		return err
@@ -322,8 +496,16 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 		return errors.Wrap(err, "failed to generate cluster spec")
 	}
 
+	if d.dryRun {
+		d.logger.Info("dry-run: would update couchbase cluster spec",
+			zap.String("namespace", namespaceName),
+			zap.Any("spec", clusterSpec))
+		return nil
+	}
+
 	err = d.client.UpdateCouchbaseClusterSpec(ctx, namespaceName, CouchbaseClusterName, clusterSpec)
 	if err != nil {
+		d.collectFailureDiagnostics(ctx, clusterID, namespaceName)
 		return errors.Wrap(err, "failed to update cluster spec")
 	}
 
@@ -338,6 +520,10 @@ func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID stri
 	return errors.New("caodeploy does not support cluster node removal")
 }
 
+func (d *Deployer) SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error) {
+	return "", errors.New("caodeploy does not support swapping cluster nodes")
+}
+
 func (d *Deployer) getClusterNamespace(ctx context.Context, clusterID string) (string, error) {
 	namespaces, err := d.client.ListNamespaces(ctx)
 	if err != nil {
@@ -354,6 +540,16 @@ func (d *Deployer) getClusterNamespace(ctx context.Context, clusterID string) (s
 	return namespaceName, nil
 }
 
+func (d *Deployer) deleteNamespaces(ctx context.Context, namespaces []string) error {
+	if d.dryRun {
+		d.logger.Info("dry-run: would delete namespaces",
+			zap.Strings("namespaces", namespaces))
+		return nil
+	}
+
+	return d.client.DeleteNamespaces(ctx, namespaces)
+}
+
 func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
 	namespaceName, err := d.getClusterNamespace(ctx, clusterID)
 	if err != nil {
@@ -361,7 +557,7 @@ func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
 	}
 
 	if namespaceName != "" {
-		err = d.client.DeleteNamespaces(ctx, []string{namespaceName})
+		err = d.deleteNamespaces(ctx, []string{namespaceName})
 		if err != nil {
 			return errors.Wrap(err, "failed delete namespaces")
 		}
@@ -384,7 +580,7 @@ func (d *Deployer) RemoveAll(ctx context.Context) error {
 	}
 
 	if len(clusterNames) > 0 {
-		err = d.client.DeleteNamespaces(ctx, clusterNames)
+		err = d.deleteNamespaces(ctx, clusterNames)
 		if err != nil {
 			return errors.Wrap(err, "failed delete namespaces")
 		}
@@ -591,6 +787,7 @@ func (d *Deployer) Cleanup(ctx context.Context) error {
 	}
 
 	var clusterNames []string
+	var actions []*deployment.CleanupAction
 	for _, namespace := range namespaces.Items {
 		if namespace.Labels["cbdc2.cluster_id"] != "" {
 			expiryStr := namespace.Labels["cbdc2.expiry"]
@@ -600,17 +797,46 @@ func (d *Deployer) Cleanup(ctx context.Context) error {
 				continue
 			}
 
-			if !expiryTime.IsZero() && !expiryTime.After(curTime) {
+			expiryPhase := deployment.GetExpiryPhase(expiryTime, d.gracePeriod, curTime)
+			if expiryPhase == deployment.ExpiryPhaseExpiring {
+				clusterID := namespace.Labels["cbdc2.cluster_id"]
+
+				d.logger.Warn("cluster has expired and will be removed after the grace period",
+					zap.String("cluster", clusterID),
+					zap.Time("expiry", expiryTime),
+					zap.Duration("gracePeriod", d.gracePeriod))
+
+				if d.onExpiring != nil {
+					d.onExpiring(ctx, clusterID, expiryTime)
+				}
+			} else if expiryPhase == deployment.ExpiryPhaseExpired {
 				clusterNames = append(clusterNames, namespace.Name)
+				actions = append(actions, &deployment.CleanupAction{
+					Type:      "namespace",
+					ID:        namespace.Name,
+					ClusterID: namespace.Labels["cbdc2.cluster_id"],
+					CreatedAt: namespace.CreationTimestamp.Time,
+					Expiry:    expiryTime,
+					Reason:    "expired",
+					DryRun:    d.dryRun,
+				})
 			}
 		}
 	}
 
 	if len(clusterNames) > 0 {
-		err = d.client.DeleteNamespaces(ctx, clusterNames)
+		err = d.deleteNamespaces(ctx, clusterNames)
 		if err != nil {
+			for _, action := range actions {
+				action.Error = err.Error()
+				deployment.RecordCleanupAction(ctx, action)
+			}
 			return errors.Wrap(err, "failed delete namespaces")
 		}
+
+		for _, action := range actions {
+			deployment.RecordCleanupAction(ctx, action)
+		}
 	}
 
 	return nil
@@ -675,14 +901,26 @@ func (d *Deployer) ListCollections(ctx context.Context, clusterID string, bucket
 	return nil, errors.New("caodeploy does not support getting collections")
 }
 
+func (d *Deployer) GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*deployment.CollectionManifest, error) {
+	return nil, errors.New("caodeploy does not support getting collection manifests")
+}
+
+func (d *Deployer) WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error {
+	return errors.New("caodeploy does not support waiting for collection manifests")
+}
+
 func (d *Deployer) CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
 	return errors.New("caodeploy does not support creating scopes")
 }
 
-func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
+func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.CreateCollectionOptions) error {
 	return errors.New("caodeploy does not support creating collections")
 }
 
+func (d *Deployer) UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.UpdateCollectionOptions) error {
+	return errors.New("caodeploy does not support updating collections")
+}
+
 func (d *Deployer) DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
 	return errors.New("caodeploy does not support deleting scopes")
 }
@@ -691,6 +929,34 @@ func (d *Deployer) DeleteCollection(ctx context.Context, clusterID string, bucke
 	return errors.New("caodeploy does not support deleting collections")
 }
 
+func (d *Deployer) CreateQueryFunction(ctx context.Context, clusterID string, opts *deployment.CreateQueryFunctionOptions) error {
+	return errors.New("caodeploy does not support creating query functions")
+}
+
+func (d *Deployer) DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return errors.New("caodeploy does not support dropping query functions")
+}
+
+func (d *Deployer) ListJsLibraries(ctx context.Context, clusterID string) ([]deployment.JsLibraryInfo, error) {
+	return nil, errors.New("caodeploy does not support listing javascript libraries")
+}
+
+func (d *Deployer) CreateJsLibrary(ctx context.Context, clusterID string, opts *deployment.CreateJsLibraryOptions) error {
+	return errors.New("caodeploy does not support creating javascript libraries")
+}
+
+func (d *Deployer) DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return errors.New("caodeploy does not support deleting javascript libraries")
+}
+
+func (d *Deployer) ListIndexes(ctx context.Context, clusterID string) ([]deployment.IndexInfo, error) {
+	return nil, errors.New("caodeploy does not support listing indexes")
+}
+
+func (d *Deployer) AdviseIndexes(ctx context.Context, clusterID string, statement string) (*deployment.IndexAdvice, error) {
+	return nil, errors.New("caodeploy does not support the index advisor")
+}
+
 func (d *Deployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType deployment.BlockNodeTrafficType) error {
 	return errors.New("caodeploy does not support traffic control")
 }
@@ -729,6 +995,14 @@ func (d *Deployer) UnpauseNode(ctx context.Context, clusterID string, nodeID str
 	return errors.New("caodeploy does not support node pausing")
 }
 
+func (d *Deployer) SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error {
+	return errors.New("caodeploy does not support clock skew")
+}
+
+func (d *Deployer) GetNodeVersions(ctx context.Context, clusterID string) ([]deployment.NodeVersionInfo, error) {
+	return nil, errors.New("caodeploy does not support node version reporting")
+}
+
 func (d *Deployer) RedeployCluster(ctx context.Context, clusterID string) error {
 	return errors.New("caodeploy does not support redeploy cluster")
 }
@@ -0,0 +1,61 @@
+package caodeploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// collectFailureDiagnostics captures the state of a cluster which failed to
+// become ready into a directory which outlives the cluster, so the failure
+// can still be investigated once the cluster has been cleaned up.
+func (d *Deployer) collectFailureDiagnostics(ctx context.Context, clusterID string, namespace string) {
+	// the original context may have been what failed
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 1*time.Minute)
+	defer cancel()
+
+	destPath := filepath.Join(os.TempDir(),
+		"cbdc-cao-diagnostics-"+clusterID+"-"+time.Now().Format("20060102T150405"))
+
+	_, err := d.client.CollectDiagnostics(ctx, namespace, CouchbaseClusterName, destPath)
+	if err != nil {
+		d.logger.Warn("failed to collect failure diagnostics", zap.Error(err))
+		return
+	}
+
+	d.logger.Warn("cluster failed to become ready, diagnostics were collected",
+		zap.String("cluster", clusterID),
+		zap.String("path", destPath))
+}
+
+// CollectDiagnostics captures the cluster resource, namespace events, pod
+// states and operator logs of a cluster into destPath.
+func (d *Deployer) CollectDiagnostics(ctx context.Context, clusterID string, destPath string) ([]string, error) {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := d.client.CollectDiagnostics(ctx, namespace, CouchbaseClusterName, destPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect diagnostics")
+	}
+
+	return paths, nil
+}
+
+// WatchEvents streams the k8s events of the namespace of a cluster until
+// ctx is done.
+func (d *Deployer) WatchEvents(ctx context.Context, clusterID string, onEvent func(*caocontrol.EventInfo)) error {
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return d.client.WatchEvents(ctx, namespace, onEvent)
+}
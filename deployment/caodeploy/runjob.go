@@ -0,0 +1,85 @@
+package caodeploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/caocontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/cbdcuuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type RunInClusterOptions struct {
+	Image   string
+	Command []string
+	Env     map[string]string
+	Timeout time.Duration
+	Keep    bool
+}
+
+// RunInCluster runs a command as a k8s job in the namespace of a cluster,
+// which allows tests to reach the cluster even when the k8s cluster itself
+// is not reachable from where cbdinocluster runs.  The job is given the
+// details of the cluster through CBDC_ environment variables, its output is
+// streamed to logOut and the exit code of the command is returned.
+func (d *Deployer) RunInCluster(
+	ctx context.Context,
+	clusterID string,
+	opts *RunInClusterOptions,
+	logOut io.Writer,
+) (int, error) {
+	if opts.Image == "" {
+		return -1, errors.New("an image must be specified")
+	}
+
+	namespace, err := d.requireClusterNamespace(ctx, clusterID)
+	if err != nil {
+		return -1, err
+	}
+
+	// the operator exposes all the pods of the cluster through this
+	// headless service, which also provides the DNS SRV records
+	srvHost := fmt.Sprintf("%s-srv.%s.svc", CouchbaseClusterName, namespace)
+
+	env := map[string]string{
+		"CBDC_CLUSTER_ID":  clusterID,
+		"CBDC_CONNSTR":     "couchbase://" + srvHost,
+		"CBDC_CONNSTR_TLS": "couchbases://" + srvHost,
+		"CBDC_MGMT":        fmt.Sprintf("http://%s-ui.%s.svc:8091", CouchbaseClusterName, namespace),
+	}
+	for envName, envValue := range opts.Env {
+		env[envName] = envValue
+	}
+
+	jobName := "cbdc2-run-" + cbdcuuid.New().ShortString()
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would run job",
+			zap.String("namespace", namespace),
+			zap.String("name", jobName),
+			zap.String("image", opts.Image),
+			zap.Strings("command", opts.Command))
+		return 0, nil
+	}
+
+	exitCode, err := d.client.RunJob(ctx, namespace, &caocontrol.RunJobOptions{
+		Name:    jobName,
+		Image:   opts.Image,
+		Command: opts.Command,
+		Env:     env,
+		SecretEnv: []caocontrol.JobSecretEnvVar{
+			{Name: "CBDC_USERNAME", SecretName: "cbdc2-admin-auth", SecretKey: "username"},
+			{Name: "CBDC_PASSWORD", SecretName: "cbdc2-admin-auth", SecretKey: "password"},
+		},
+		Timeout: opts.Timeout,
+		Keep:    opts.Keep,
+	}, logOut)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to run job")
+	}
+
+	return exitCode, nil
+}
@@ -0,0 +1,265 @@
+package deployment
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// CleanupAction describes a resource which was removed by a cleanup, or
+// which would have been removed during a dry-run.
+type CleanupAction struct {
+	// Source is the deployer or cleaner which performed the action.
+	Source    string    `json:"source"`
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	ClusterID string    `json:"cluster-id,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created-at,omitempty"`
+	Expiry    time.Time `json:"expiry,omitempty"`
+	Reason    string    `json:"reason"`
+	DryRun    bool      `json:"dry-run,omitempty"`
+
+	// Error is set when the resource failed to be removed.
+	Error string `json:"error,omitempty"`
+}
+
+// Age returns how old the resource was at the time t, or zero when its
+// creation time is unknown.
+func (a *CleanupAction) Age(t time.Time) time.Duration {
+	if a.CreatedAt.IsZero() {
+		return 0
+	}
+	return t.Sub(a.CreatedAt)
+}
+
+// CleanupReport collects the actions taken by cleanups, so that scheduled
+// cleanup jobs can surface what they did.
+type CleanupReport struct {
+	lock    sync.Mutex
+	actions []*CleanupAction
+}
+
+// Record adds an action to the report, filling in the source of the action
+// if it was not specified.
+func (r *CleanupReport) Record(source string, action *CleanupAction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if action.Source == "" {
+		action.Source = source
+	}
+	r.actions = append(r.actions, action)
+}
+
+// Actions returns the actions recorded so far.
+func (r *CleanupReport) Actions() []*CleanupAction {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]*CleanupAction(nil), r.actions...)
+}
+
+type cleanupReportCtxKey struct{}
+
+type cleanupReportCtxValue struct {
+	report *CleanupReport
+	source string
+}
+
+// WithCleanupReport attaches a report to a context, to which the cleanups
+// performed with that context record their actions as coming from source.
+func WithCleanupReport(ctx context.Context, report *CleanupReport, source string) context.Context {
+	return context.WithValue(ctx, cleanupReportCtxKey{}, &cleanupReportCtxValue{
+		report: report,
+		source: source,
+	})
+}
+
+// RecordCleanupAction records an action to the report attached to ctx, if
+// there is one.
+func RecordCleanupAction(ctx context.Context, action *CleanupAction) {
+	value, _ := ctx.Value(cleanupReportCtxKey{}).(*cleanupReportCtxValue)
+	if value == nil {
+		return
+	}
+
+	value.report.Record(value.source, action)
+}
+
+type CleanupReportFormat string
+
+const (
+	CleanupReportFormatJson  CleanupReportFormat = "json"
+	CleanupReportFormatCsv   CleanupReportFormat = "csv"
+	CleanupReportFormatJUnit CleanupReportFormat = "junit"
+)
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// WriteCleanupReport writes actions in the specified format.  Ages are
+// calculated relative to now.
+func WriteCleanupReport(w io.Writer, format CleanupReportFormat, actions []*CleanupAction, now time.Time) error {
+	switch format {
+	case CleanupReportFormatJson:
+		return writeCleanupReportJson(w, actions, now)
+	case CleanupReportFormatCsv:
+		return writeCleanupReportCsv(w, actions, now)
+	case CleanupReportFormatJUnit:
+		return writeCleanupReportJUnit(w, actions, now)
+	}
+	return fmt.Errorf("unsupported report format `%s`", format)
+}
+
+func writeCleanupReportJson(w io.Writer, actions []*CleanupAction, now time.Time) error {
+	type jsonAction struct {
+		*CleanupAction
+		CreatedAt string `json:"created-at,omitempty"`
+		Expiry    string `json:"expiry,omitempty"`
+		Age       string `json:"age,omitempty"`
+	}
+
+	out := []jsonAction{}
+	for _, action := range actions {
+		item := jsonAction{
+			CleanupAction: action,
+			CreatedAt:     formatReportTime(action.CreatedAt),
+			Expiry:        formatReportTime(action.Expiry),
+		}
+		if age := action.Age(now); age > 0 {
+			item.Age = age.Round(time.Second).String()
+		}
+		out = append(out, item)
+	}
+
+	outBytes, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", outBytes)
+	return err
+}
+
+func writeCleanupReportCsv(w io.Writer, actions []*CleanupAction, now time.Time) error {
+	csvWriter := csv.NewWriter(w)
+
+	err := csvWriter.Write([]string{
+		"source", "type", "id", "name", "cluster-id", "owner",
+		"created-at", "expiry", "age-seconds", "reason", "dry-run", "error",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, action := range actions {
+		ageStr := ""
+		if age := action.Age(now); age > 0 {
+			ageStr = fmt.Sprintf("%d", int64(age.Seconds()))
+		}
+
+		err := csvWriter.Write([]string{
+			action.Source,
+			action.Type,
+			action.ID,
+			action.Name,
+			action.ClusterID,
+			action.Owner,
+			formatReportTime(action.CreatedAt),
+			formatReportTime(action.Expiry),
+			ageStr,
+			action.Reason,
+			fmt.Sprintf("%t", action.DryRun),
+			action.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeCleanupReportJUnit writes each action as a test case, with actions
+// which failed being failures, so that CI dashboards show cleanup runs.
+func writeCleanupReportJUnit(w io.Writer, actions []*CleanupAction, now time.Time) error {
+	suite := junitTestSuite{
+		Name:      "cbdinocluster cleanup",
+		Tests:     len(actions),
+		Timestamp: now.Format(time.RFC3339),
+	}
+
+	for _, action := range actions {
+		name := fmt.Sprintf("remove %s %s", action.Type, action.ID)
+		if action.DryRun {
+			name = "dry-run: " + name
+		}
+
+		systemOut := fmt.Sprintf("name: %s\nowner: %s\nreason: %s\n",
+			action.Name, action.Owner, action.Reason)
+		if age := action.Age(now); age > 0 {
+			systemOut += fmt.Sprintf("age: %s\n", age.Round(time.Second))
+		}
+
+		testCase := junitTestCase{
+			ClassName: action.Source,
+			Name:      name,
+			SystemOut: systemOut,
+		}
+		if action.Error != "" {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: action.Error,
+			}
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suite)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
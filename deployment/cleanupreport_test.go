@@ -0,0 +1,65 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupReport(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// actions recorded without a report are dropped
+	RecordCleanupAction(context.Background(), &CleanupAction{ID: "dropped"})
+
+	report := &CleanupReport{}
+	ctx := WithCleanupReport(context.Background(), report, "docker")
+	RecordCleanupAction(ctx, &CleanupAction{
+		Type:      "node",
+		ID:        "node-1",
+		Name:      "node, one",
+		Owner:     "bob",
+		CreatedAt: now.Add(-2 * time.Hour),
+		Reason:    "expired",
+	})
+	RecordCleanupAction(ctx, &CleanupAction{
+		Source: "registry",
+		Type:   "cluster",
+		ID:     "cluster-1",
+		Reason: "expired",
+		Error:  "failed to remove",
+	})
+
+	actions := report.Actions()
+	require.Len(t, actions, 2)
+	require.Equal(t, "docker", actions[0].Source)
+	require.Equal(t, "registry", actions[1].Source)
+	require.Equal(t, 2*time.Hour, actions[0].Age(now))
+	require.Zero(t, actions[1].Age(now))
+
+	var jsonOut bytes.Buffer
+	err := WriteCleanupReport(&jsonOut, CleanupReportFormatJson, actions, now)
+	require.NoError(t, err)
+	require.Contains(t, jsonOut.String(), `"age":"2h0m0s"`)
+	require.Contains(t, jsonOut.String(), `"created-at":"2024-05-01T10:00:00Z"`)
+
+	var csvOut bytes.Buffer
+	err = WriteCleanupReport(&csvOut, CleanupReportFormatCsv, actions, now)
+	require.NoError(t, err)
+	csvLines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	require.Len(t, csvLines, 3)
+	require.Equal(t, `docker,node,node-1,"node, one",,bob,2024-05-01T10:00:00Z,,7200,expired,false,`, csvLines[1])
+
+	var junitOut bytes.Buffer
+	err = WriteCleanupReport(&junitOut, CleanupReportFormatJUnit, actions, now)
+	require.NoError(t, err)
+	require.Contains(t, junitOut.String(), `<testsuite name="cbdinocluster cleanup" tests="2" failures="1"`)
+	require.Contains(t, junitOut.String(), `<failure message="failed to remove"></failure>`)
+
+	err = WriteCleanupReport(&junitOut, "yaml", actions, now)
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package clouddeploy
+
+import (
+	"context"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/cbdcuuid"
+	"github.com/couchbaselabs/cbdinocluster/utils/stringclustermeta"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type AdoptClusterOptions struct {
+	// CloudClusterID is the Capella ID of the cluster to adopt.
+	CloudClusterID string
+	Expiry         time.Time
+	Purpose        string
+}
+
+// AdoptCluster takes over a cluster which was created outside of cbdinocluster.
+// We track clusters by the name of the project they live in, so the project
+// is renamed, which requires that the cluster is the only one in its project.
+func (p *Deployer) AdoptCluster(ctx context.Context, opts *AdoptClusterOptions) (deployment.ClusterInfo, error) {
+	clusters, err := p.client.ListAllClusters(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list all clusters")
+	}
+
+	var cluster *capellacontrol.ClusterInfo
+	for _, clusterResp := range clusters.Data {
+		if clusterResp.Data.Id == opts.CloudClusterID {
+			cluster = clusterResp.Data
+		}
+	}
+	if cluster == nil {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	for _, clusterResp := range clusters.Data {
+		if clusterResp.Data.Project.Id == cluster.Project.Id && clusterResp.Data.Id != cluster.Id {
+			return nil, errors.New("cannot adopt a cluster which shares its project with other clusters")
+		}
+	}
+
+	projects, err := p.client.ListProjects(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list projects")
+	}
+
+	var project *capellacontrol.ProjectInfo
+	for _, projectResp := range projects.Data {
+		if projectResp.Data.ID == cluster.Project.Id {
+			project = projectResp.Data
+		}
+	}
+	if project == nil {
+		return nil, errors.New("failed to find the project of the cluster")
+	}
+
+	existingMeta, err := stringclustermeta.Parse(project.Name)
+	if err == nil && existingMeta != nil {
+		return nil, errors.Errorf("cluster is already managed as %s", existingMeta.ID.String())
+	}
+
+	purpose := opts.Purpose
+	if purpose == "" {
+		purpose = cluster.Name
+	}
+
+	meta := &stringclustermeta.MetaData{
+		ID:      cbdcuuid.New(),
+		Expiry:  opts.Expiry,
+		Purpose: purpose,
+	}
+	newProjectName := meta.String()
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would rename project to adopt cluster",
+			zap.String("project-id", cluster.Project.Id),
+			zap.String("project-name", project.Name),
+			zap.String("new-project-name", newProjectName))
+	} else {
+		p.logger.Info("renaming project to adopt cluster",
+			zap.String("project-id", cluster.Project.Id),
+			zap.String("project-name", project.Name),
+			zap.String("new-project-name", newProjectName))
+
+		_, err = p.client.UpdateProject(ctx, p.tenantID, cluster.Project.Id, &capellacontrol.UpdateProjectRequest{
+			Name: newProjectName,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to rename project")
+		}
+	}
+
+	return &ClusterInfo{
+		ClusterID:      meta.ID.String(),
+		Type:           deployment.ClusterTypeServer,
+		CloudProjectID: cluster.Project.Id,
+		CloudClusterID: cluster.Id,
+		Region:         cluster.Provider.Region,
+		Expiry:         meta.Expiry,
+		State:          cluster.Status.State,
+		CreatedAt:      cluster.CreatedAt,
+	}, nil
+}
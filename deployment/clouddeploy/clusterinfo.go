@@ -1,9 +1,11 @@
 package clouddeploy
 
 import (
+	"strings"
 	"time"
 
 	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
 )
 
 type ClusterInfo struct {
@@ -14,6 +16,19 @@ type ClusterInfo struct {
 	Region         string
 	Expiry         time.Time
 	State          string
+	CreatedAt      time.Time
+
+	// EstimatedHourlyCost is the approximate hourly cost of the cluster,
+	// or zero if the cost could not be estimated.
+	EstimatedHourlyCost float64
+
+	// ActiveJobs are the incomplete Capella jobs of the cluster, which are
+	// only listed once PopulateActiveJobs is called.
+	ActiveJobs []*capellacontrol.ClusterJobInfo
+
+	// ServiceGroups are the groups of nodes of the cluster and the services
+	// running on them, which are not known for columnar clusters.
+	ServiceGroups []ServiceGroupInfo
 }
 
 var _ (deployment.ClusterInfo) = (*ClusterInfo)(nil)
@@ -22,7 +37,36 @@ func (i ClusterInfo) GetID() string                   { return i.ClusterID }
 func (i ClusterInfo) GetType() deployment.ClusterType { return i.Type }
 func (i ClusterInfo) GetPurpose() string              { return "" }
 func (i ClusterInfo) GetExpiry() time.Time            { return i.Expiry }
-func (i ClusterInfo) GetState() string                { return i.State }
+func (i ClusterInfo) GetState() string {
+	// Capella can report a cluster as healthy while it is running a job, so
+	// the job is used to describe what is happening to the cluster instead
+	if i.State == "healthy" && len(i.ActiveJobs) > 0 {
+		return jobTypeState(i.ActiveJobs[0].JobType)
+	}
+	return i.State
+}
 func (i ClusterInfo) GetNodes() []deployment.ClusterNodeInfo {
 	return nil
 }
+
+// EstimatedCost returns the approximate accumulated cost of the cluster.
+func (i ClusterInfo) EstimatedCost() float64 {
+	return EstimateAccumulatedCost(i.EstimatedHourlyCost, i.CreatedAt, time.Now())
+}
+
+// jobTypeState describes the state of a cluster running a job of the given
+// type, such as `scaling` for a scaleCluster job.
+func jobTypeState(jobType string) string {
+	lowerJobType := strings.ToLower(jobType)
+	switch {
+	case strings.Contains(lowerJobType, "scale"):
+		return "scaling"
+	case strings.Contains(lowerJobType, "upgrade"):
+		return "upgrading"
+	case strings.Contains(lowerJobType, "backup"):
+		return "backingUp"
+	case strings.Contains(lowerJobType, "restore"):
+		return "restoring"
+	}
+	return "busy"
+}
@@ -0,0 +1,147 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/pkg/errors"
+)
+
+// The prices below are approximate on-demand list prices in USD and are
+// only intended to give a rough idea of how expensive a configuration is.
+// They do not include the Capella markup, support costs or any discounts.
+
+var instanceHourlyPrices = map[string]float64{
+	"m5.large":        0.096,
+	"m5.xlarge":       0.192,
+	"m5.2xlarge":      0.384,
+	"m5.4xlarge":      0.768,
+	"c5.xlarge":       0.17,
+	"c5.2xlarge":      0.34,
+	"r5.xlarge":       0.252,
+	"r5.2xlarge":      0.504,
+	"Standard_D4s_v5": 0.192,
+	"Standard_D8s_v5": 0.384,
+	"Standard_E4s_v5": 0.252,
+	"n2-standard-4":   0.194,
+	"n2-standard-8":   0.388,
+	"n2-highmem-4":    0.262,
+}
+
+// used when the instance type is unknown, but the resources are
+const (
+	fallbackCpuHourlyPrice      = 0.032
+	fallbackMemoryGbHourlyPrice = 0.0043
+)
+
+var diskGbMonthlyPrices = map[string]float64{
+	"gp3":    0.08,
+	"gp2":    0.10,
+	"io2":    0.125,
+	"P6":     0.077,
+	"P10":    0.077,
+	"P15":    0.077,
+	"P20":    0.077,
+	"pd-ssd": 0.17,
+}
+
+const hoursPerMonth = 730
+
+type NodeCostSpec struct {
+	Count        int
+	InstanceType string
+	Cpu          int
+	MemoryGb     int
+	DiskType     string
+	DiskSizeGb   int
+}
+
+// EstimateHourlyCost returns the approximate hourly cost of running
+// the specified nodes.
+func EstimateHourlyCost(specs []NodeCostSpec) (float64, error) {
+	totalCost := 0.0
+	for _, spec := range specs {
+		computeCost, ok := instanceHourlyPrices[spec.InstanceType]
+		if !ok {
+			if spec.Cpu == 0 || spec.MemoryGb == 0 {
+				return 0, fmt.Errorf("no pricing information for instance type `%s`", spec.InstanceType)
+			}
+
+			computeCost = float64(spec.Cpu)*fallbackCpuHourlyPrice +
+				float64(spec.MemoryGb)*fallbackMemoryGbHourlyPrice
+		}
+
+		diskCost := 0.0
+		if spec.DiskSizeGb > 0 {
+			diskGbPrice, ok := diskGbMonthlyPrices[spec.DiskType]
+			if !ok {
+				return 0, fmt.Errorf("no pricing information for disk type `%s`", spec.DiskType)
+			}
+
+			diskCost = float64(spec.DiskSizeGb) * diskGbPrice / hoursPerMonth
+		}
+
+		totalCost += float64(spec.Count) * (computeCost + diskCost)
+	}
+
+	return totalCost, nil
+}
+
+// EstimateAccumulatedCost returns the approximate cost of running a cluster
+// with the specified hourly cost since its creation time.
+func EstimateAccumulatedCost(hourlyCost float64, createdAt time.Time, curTime time.Time) float64 {
+	if createdAt.IsZero() || curTime.Before(createdAt) {
+		return 0
+	}
+	return hourlyCost * curTime.Sub(createdAt).Hours()
+}
+
+func clusterCostSpecs(cluster *capellacontrol.ClusterInfo) []NodeCostSpec {
+	var specs []NodeCostSpec
+	for _, service := range cluster.Services {
+		specs = append(specs, NodeCostSpec{
+			Count:        service.Count,
+			InstanceType: service.Compute.Type,
+			Cpu:          service.Compute.Cpu,
+			MemoryGb:     service.Compute.MemoryInGB,
+			DiskType:     service.Disk.Type,
+			DiskSizeGb:   service.Disk.SizeInGb,
+		})
+	}
+	return specs
+}
+
+// EstimateDefinitionCost returns the approximate hourly cost of a cluster
+// definition if it were deployed by this deployer.
+func (p *Deployer) EstimateDefinitionCost(ctx context.Context, def *clusterdef.Cluster) (float64, error) {
+	if def.Columnar {
+		return 0, errors.New("cost estimation is not supported for columnar clusters")
+	}
+
+	cloudProvider := def.Cloud.CloudProvider
+	if cloudProvider == "" {
+		cloudProvider = p.defaultCloud
+	}
+
+	deploySpecs, err := p.buildDeploySpecs(ctx, cloudProvider, def.NodeGroups)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to build cluster specs")
+	}
+
+	var specs []NodeCostSpec
+	for _, deploySpec := range deploySpecs {
+		specs = append(specs, NodeCostSpec{
+			Count:        deploySpec.Count,
+			InstanceType: deploySpec.Compute.Type,
+			Cpu:          deploySpec.Compute.Cpu,
+			MemoryGb:     deploySpec.Compute.Memory,
+			DiskType:     deploySpec.Disk.Type,
+			DiskSizeGb:   deploySpec.Disk.SizeInGb,
+		})
+	}
+
+	return EstimateHourlyCost(specs)
+}
@@ -3,6 +3,7 @@ package clouddeploy
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -14,10 +15,13 @@ import (
 	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
 	"github.com/couchbaselabs/cbdinocluster/utils/cbdcuuid"
+	"github.com/couchbaselabs/cbdinocluster/utils/clusterlock"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
 	"github.com/couchbaselabs/cbdinocluster/utils/stringclustermeta"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 )
 
 type Deployer struct {
@@ -32,6 +36,13 @@ type Deployer struct {
 	defaultAzureRegion       string
 	defaultGcpRegion         string
 	uploadServerLogsHostName string
+	dryRun                   bool
+	gracePeriod              time.Duration
+	onExpiring               deployment.ExpiringHook
+	diagnosticsPath          string
+	clusterLocker            *clusterlock.Locker
+	dnsResolvers             []string
+	dnsWaitTimeout           time.Duration
 }
 
 var _ deployment.Deployer = (*Deployer)(nil)
@@ -47,6 +58,33 @@ type NewDeployerOptions struct {
 	DefaultAzureRegion       string
 	DefaultGcpRegion         string
 	UploadServerLogsHostName string
+
+	// DryRun causes destructive operations to only log the Capella API
+	// calls they would have issued rather than actually issuing them.
+	DryRun bool
+
+	// ExpiryGracePeriod is how long clusters are kept around after they
+	// expire before they are actually removed by Cleanup.
+	ExpiryGracePeriod time.Duration
+
+	// OnClusterExpiring is invoked during each Cleanup for every cluster
+	// which is in the expiring phase, as there is nowhere to record that a
+	// cluster was notified, so hooks must ignore repeated notifications.
+	OnClusterExpiring deployment.ExpiringHook
+
+	// DiagnosticsPath is a directory into which a diagnostics bundle is
+	// captured whenever creating or modifying a cluster fails.
+	DiagnosticsPath string
+
+	// ClusterLocker serializes operations which modify a cluster between
+	// concurrent invocations.  No locking is performed when it is nil.
+	ClusterLocker *clusterlock.Locker
+
+	// DnsResolvers are the DNS servers which the connection string records
+	// of new clusters must resolve consistently against before creation is
+	// complete, defaulting to the system resolver.
+	DnsResolvers   []string
+	DnsWaitTimeout time.Duration
 }
 
 func NewDeployer(opts *NewDeployerOptions) (*Deployer, error) {
@@ -65,6 +103,13 @@ func NewDeployer(opts *NewDeployerOptions) (*Deployer, error) {
 		defaultAzureRegion:       opts.DefaultAzureRegion,
 		defaultGcpRegion:         opts.DefaultGcpRegion,
 		uploadServerLogsHostName: opts.UploadServerLogsHostName,
+		dryRun:                   opts.DryRun,
+		gracePeriod:              opts.ExpiryGracePeriod,
+		onExpiring:               opts.OnClusterExpiring,
+		diagnosticsPath:          opts.DiagnosticsPath,
+		clusterLocker:            opts.ClusterLocker,
+		dnsResolvers:             opts.DnsResolvers,
+		dnsWaitTimeout:           opts.DnsWaitTimeout,
 	}, nil
 }
 
@@ -205,6 +250,16 @@ func (p *Deployer) listClusters(ctx context.Context) ([]*clusterInfo, error) {
 	return out, nil
 }
 
+// lockCluster acquires the lock for a cluster for an operation which modifies
+// it, preventing concurrent invocations from interleaving their changes.
+func (p *Deployer) lockCluster(ctx context.Context, clusterID string, operation string) (context.Context, func(), error) {
+	if p.clusterLocker == nil {
+		return ctx, func() {}, nil
+	}
+
+	return p.clusterLocker.Lock(ctx, clusterID, operation)
+}
+
 func (p *Deployer) getCluster(ctx context.Context, clusterID string) (*clusterInfo, error) {
 	clusters, err := p.listClusters(ctx)
 	if err != nil {
@@ -218,7 +273,7 @@ func (p *Deployer) getCluster(ctx context.Context, clusterID string) (*clusterIn
 		}
 	}
 	if foundCluster == nil {
-		return nil, errors.New("failed to find cluster")
+		return nil, deployment.ErrClusterNotFound
 	}
 
 	if foundCluster.IsCorrupted {
@@ -236,7 +291,10 @@ func (p *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 
 	var out []deployment.ClusterInfo
 
+	curTime := time.Now()
 	for _, cluster := range clusters {
+		isExpiring := deployment.GetExpiryPhase(cluster.Meta.Expiry, p.gracePeriod, curTime) != deployment.ExpiryPhaseActive
+
 		if cluster.IsCorrupted {
 			out = append(out, &ClusterInfo{
 				ClusterID:      cluster.Meta.ID.String(),
@@ -262,16 +320,32 @@ func (p *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 		}
 
 		if cluster.Cluster != nil {
+			state := cluster.Cluster.Status.State
+			if isExpiring && state == "healthy" {
+				state = deployment.ClusterStateExpiring
+			}
+
+			// clusters with unpriceable specs just report no cost
+			hourlyCost, _ := EstimateHourlyCost(clusterCostSpecs(cluster.Cluster))
+
 			out = append(out, &ClusterInfo{
-				ClusterID:      cluster.Meta.ID.String(),
-				Type:           deployment.ClusterTypeServer,
-				CloudProjectID: cluster.Project.ID,
-				CloudClusterID: cluster.Cluster.Id,
-				Region:         cluster.Cluster.Provider.Region,
-				Expiry:         cluster.Meta.Expiry,
-				State:          cluster.Cluster.Status.State,
+				ClusterID:           cluster.Meta.ID.String(),
+				Type:                deployment.ClusterTypeServer,
+				CloudProjectID:      cluster.Project.ID,
+				CloudClusterID:      cluster.Cluster.Id,
+				Region:              cluster.Cluster.Provider.Region,
+				Expiry:              cluster.Meta.Expiry,
+				State:               state,
+				CreatedAt:           cluster.Cluster.CreatedAt,
+				EstimatedHourlyCost: hourlyCost,
+				ServiceGroups:       clusterServiceGroups(cluster.Cluster),
 			})
 		} else if cluster.Columnar != nil {
+			state := cluster.Columnar.State
+			if isExpiring && state == "healthy" {
+				state = deployment.ClusterStateExpiring
+			}
+
 			out = append(out, &ClusterInfo{
 				ClusterID:      cluster.Meta.ID.String(),
 				Type:           deployment.ClusterTypeColumnar,
@@ -279,7 +353,7 @@ func (p *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 				CloudClusterID: cluster.Columnar.ID,
 				Region:         cluster.Columnar.Config.Region,
 				Expiry:         cluster.Meta.Expiry,
-				State:          cluster.Columnar.State,
+				State:          state,
 			})
 		}
 	}
@@ -541,9 +615,7 @@ func (p *Deployer) buildModifySpecs(
 	return specs, nil
 }
 
-func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster, clusterVersion string, serverImage string) (deployment.ClusterInfo, error) {
-	clusterID := cbdcuuid.New()
-
+func (p *Deployer) deployNewCluster(ctx context.Context, clusterID cbdcuuid.UUID, def *clusterdef.Cluster, clusterVersion string, serverImage string) (deployment.ClusterInfo, error) {
 	expiryTime := time.Time{}
 	if def.Expiry > 0 {
 		expiryTime = time.Now().Add(def.Expiry)
@@ -555,13 +627,14 @@ func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster
 	}
 	projectName := metaData.String()
 
+	progress.Step(ctx, "creating project")
 	p.logger.Debug("creating a new cloud project")
 
 	newProject, err := p.client.CreateProject(ctx, p.tenantID, &capellacontrol.CreateProjectRequest{
 		Name: projectName,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create project")
+		return nil, errors.Wrap(wrapQuotaError(err), "failed to create project")
 	}
 
 	cloudProjectID := newProject.Id
@@ -626,6 +699,7 @@ func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster
 		clusterCidr = deploymentOpts.SuggestedCidr
 	}
 
+	progress.Step(ctx, "creating cluster")
 	p.logger.Debug("creating a new cloud cluster")
 
 	clusterName := fmt.Sprintf("cbdc2_%s", clusterID)
@@ -640,7 +714,7 @@ func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 	createReq := &capellacontrol.DeployClusterRequest{
 		CIDR:        clusterCidr,
-		Description: "",
+		Description: projectName,
 		Name:        clusterName,
 		Package:     "developerPro",
 		ProjectId:   cloudProjectID,
@@ -662,11 +736,12 @@ func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 	newCluster, err := p.client.DeployCluster(ctx, p.tenantID, createReq)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create cluster")
+		return nil, errors.Wrap(wrapQuotaError(err), "failed to create cluster")
 	}
 
 	cloudClusterID := newCluster.Id
 
+	progress.Step(ctx, "waiting for cluster to become healthy")
 	p.logger.Debug("waiting for cluster creation to complete")
 
 	err = p.mgr.WaitForClusterState(ctx, p.tenantID, cloudClusterID, "healthy", false)
@@ -695,9 +770,7 @@ func (p *Deployer) deployNewCluster(ctx context.Context, def *clusterdef.Cluster
 	return thisCluster, nil
 }
 
-func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster, clusterVersion string) (deployment.ClusterInfo, error) {
-	clusterID := cbdcuuid.New()
-
+func (p *Deployer) createNewCluster(ctx context.Context, clusterID cbdcuuid.UUID, def *clusterdef.Cluster, clusterVersion string) (deployment.ClusterInfo, error) {
 	expiryTime := time.Time{}
 	if def.Expiry > 0 {
 		expiryTime = time.Now().Add(def.Expiry)
@@ -709,13 +782,14 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 	}
 	projectName := metaData.String()
 
+	progress.Step(ctx, "creating project")
 	p.logger.Debug("creating a new cloud project")
 
 	newProject, err := p.client.CreateProject(ctx, p.tenantID, &capellacontrol.CreateProjectRequest{
 		Name: projectName,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create project")
+		return nil, errors.Wrap(wrapQuotaError(err), "failed to create project")
 	}
 
 	cloudProjectID := newProject.Id
@@ -780,6 +854,7 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 		clusterCidr = deploymentOpts.SuggestedCidr
 	}
 
+	progress.Step(ctx, "creating cluster")
 	p.logger.Debug("creating a new cloud cluster")
 
 	clusterName := fmt.Sprintf("cbdc2_%s", clusterID)
@@ -795,7 +870,7 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 		createReq := &capellacontrol.CreateClusterRequest{
 			CIDR:        clusterCidr,
-			Description: "",
+			Description: projectName,
 			Name:        clusterName,
 			Plan:        "Developer Pro",
 			ProjectId:   cloudProjectID,
@@ -810,11 +885,12 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 		newCluster, err := p.client.CreateCluster(ctx, p.tenantID, createReq)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create cluster")
+			return nil, errors.Wrap(wrapQuotaError(err), "failed to create cluster")
 		}
 
 		cloudClusterID := newCluster.Id
 
+		progress.Step(ctx, "waiting for cluster to become healthy")
 		p.logger.Debug("waiting for cluster creation to complete")
 
 		err = p.mgr.WaitForClusterState(ctx, p.tenantID, cloudClusterID, "healthy", false)
@@ -861,7 +937,7 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 		createReq := &capellacontrol.CreateColumnarInstanceRequest{
 			Name:        clusterName,
-			Description: "",
+			Description: projectName,
 			Provider:    clusterProvider,
 			Region:      cloudRegion,
 			Nodes:       nodeCount,
@@ -885,11 +961,12 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 		newCluster, err := p.client.CreateColumnar(ctx, p.tenantID, cloudProjectID, createReq)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create columnar")
+			return nil, errors.Wrap(wrapQuotaError(err), "failed to create columnar")
 		}
 
 		cloudClusterID := newCluster.Id
 
+		progress.Step(ctx, "waiting for columnar to become healthy")
 		p.logger.Debug("waiting for columnar creation to complete")
 
 		err = p.mgr.WaitForClusterState(ctx, p.tenantID, cloudClusterID, "healthy", true)
@@ -920,6 +997,121 @@ func (p *Deployer) createNewCluster(ctx context.Context, def *clusterdef.Cluster
 
 }
 
+func (p *Deployer) ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]deployment.DefinitionViolation, error) {
+	violations := deployment.ValidateCommonDefinition(def)
+
+	cloudProvider := def.Cloud.CloudProvider
+	if cloudProvider == "" {
+		cloudProvider = p.defaultCloud
+	}
+	if cloudProvider != "aws" && cloudProvider != "gcp" && cloudProvider != "azure" {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "cloud.cloud-provider",
+			Message: fmt.Sprintf("unsupported cloud provider `%s`", cloudProvider),
+		})
+		return violations, nil
+	}
+
+	clusterVersion := ""
+	serverImage := ""
+	totalNodes := 0
+	kvNodes := 0
+	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
+		if nodeGrpIdx == 0 {
+			clusterVersion = nodeGrp.Version
+			serverImage = nodeGrp.Cloud.ServerImage
+		} else if clusterVersion != nodeGrp.Version || serverImage != nodeGrp.Cloud.ServerImage {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   deployment.NodeGroupField(nodeGrpIdx, "version"),
+				Message: "all node groups must have the same version and image",
+			})
+		}
+
+		totalNodes += nodeGrp.Count
+		if len(nodeGrp.Services) == 0 || slices.Contains(nodeGrp.Services, clusterdef.KvService) {
+			kvNodes += nodeGrp.Count
+		}
+	}
+
+	if def.Columnar {
+		if len(def.NodeGroups) > 1 {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   "nodes",
+				Message: "columnar clusters only support a single node group",
+			})
+		}
+
+		return violations, nil
+	}
+
+	if totalNodes > 1 && kvNodes < 3 {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "nodes",
+			Message: fmt.Sprintf("multi-node clusters require at least 3 nodes running the kv service (found %d)", kvNodes),
+		})
+	}
+
+	deploymentOpts, err := p.client.GetProviderDeploymentOptions(ctx, p.tenantID, &capellacontrol.GetProviderDeploymentOptionsRequest{
+		Provider: cloudProvider,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get deployment options")
+	}
+
+	serverVersions := deploymentOpts.ServerVersions.Versions
+	if clusterVersion != "" && serverImage == "" && len(serverVersions) > 0 {
+		if !slices.Contains(serverVersions, clusterVersion) {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   deployment.NodeGroupField(0, "version"),
+				Message: fmt.Sprintf("version `%s` is not available on %s", clusterVersion, cloudProvider),
+			})
+		}
+	}
+
+	specs, err := p.buildCreateSpecs(ctx, cloudProvider, def.NodeGroups)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build cluster specs")
+	}
+
+	for specIdx, spec := range specs {
+		if len(deploymentOpts.Provider.Compute) > 0 {
+			computeIdx := slices.IndexFunc(deploymentOpts.Provider.Compute, func(compute capellacontrol.GetProviderDeploymentOptionsResponse_Provider_Compute) bool {
+				return compute.Type == spec.Compute
+			})
+			if computeIdx < 0 {
+				violations = append(violations, deployment.DefinitionViolation{
+					Field:   deployment.NodeGroupField(specIdx, "cloud.instance-type"),
+					Message: fmt.Sprintf("instance type `%s` is not available on %s", spec.Compute, cloudProvider),
+				})
+			}
+		}
+
+		if len(deploymentOpts.Provider.Disks) > 0 {
+			diskIdx := slices.IndexFunc(deploymentOpts.Provider.Disks, func(disk capellacontrol.GetProviderDeploymentOptionsResponse_Provider_Disk) bool {
+				return disk.Type == spec.Disk.Type
+			})
+			if diskIdx < 0 {
+				violations = append(violations, deployment.DefinitionViolation{
+					Field:   deployment.NodeGroupField(specIdx, "cloud.disk-type"),
+					Message: fmt.Sprintf("disk type `%s` is not available on %s", spec.Disk.Type, cloudProvider),
+				})
+			} else {
+				disk := deploymentOpts.Provider.Disks[diskIdx]
+				if (disk.MinSizeInGb > 0 && spec.Disk.SizeInGb < disk.MinSizeInGb) ||
+					(disk.MaxSizeInGb > 0 && spec.Disk.SizeInGb > disk.MaxSizeInGb) {
+					violations = append(violations, deployment.DefinitionViolation{
+						Field: deployment.NodeGroupField(specIdx, "cloud.disk-size"),
+						Message: fmt.Sprintf("disk size %dGB is outside the allowed range for `%s` (%d-%dGB)",
+							spec.Disk.SizeInGb, spec.Disk.Type, disk.MinSizeInGb, disk.MaxSizeInGb),
+					})
+				}
+			}
+		}
+	}
+
+	return violations, nil
+}
+
 func (p *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (deployment.ClusterInfo, error) {
 	var (
 		clusterVersion = ""
@@ -937,17 +1129,73 @@ func (p *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		}
 	}
 
+	// columnar versions are numbered independently of Couchbase Server
+	if !def.Columnar {
+		err := deployment.CheckNodeGroupFeatures(def.NodeGroups)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clusterID := cbdcuuid.New()
+	deployment.TrackPartialCluster(ctx, clusterID.String())
+
 	// Deploy cluster based on presence of server image,
 	// specific Columnar images are deployed through the normal createCluster func
+	var cluster deployment.ClusterInfo
+	var err error
 	if serverImage != "" && !def.Columnar {
-		return p.deployNewCluster(ctx, def, clusterVersion, serverImage)
+		cluster, err = p.deployNewCluster(ctx, clusterID, def, clusterVersion, serverImage)
 	} else {
-		return p.createNewCluster(ctx, def, clusterVersion)
+		cluster, err = p.createNewCluster(ctx, clusterID, def, clusterVersion)
+	}
+	if err != nil {
+		err = deployment.WrapProvisionTimeout(err)
+		p.captureDiagnostics(ctx, clusterID.String(), def, err)
+		return nil, err
 	}
+
+	if !def.Columnar {
+		p.waitForClusterDns(ctx, clusterID.String())
+	}
+
+	progress.Complete(ctx)
+
+	return cluster, nil
 }
 
 func (d *Deployer) GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error) {
-	return nil, errors.New("clouddeploy does not support fetching the cluster definition")
+	clusterInfo, err := d.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if clusterInfo.Cluster == nil {
+		return nil, errors.New("clouddeploy only supports fetching the definition of server clusters")
+	}
+
+	var nodeGroups []*clusterdef.NodeGroup
+	for _, group := range clusterServiceGroups(clusterInfo.Cluster) {
+		nodeGroups = append(nodeGroups, &clusterdef.NodeGroup{
+			Count:    group.Count,
+			Version:  clusterInfo.Cluster.Config.Version,
+			Services: group.Services,
+			Cloud: clusterdef.CloudNodeGroup{
+				InstanceType: group.InstanceType,
+				DiskType:     group.DiskType,
+				DiskSize:     group.DiskSizeGB,
+				DiskIops:     group.DiskIops,
+			},
+		})
+	}
+
+	return &clusterdef.Cluster{
+		NodeGroups: nodeGroups,
+		Cloud: clusterdef.CloudCluster{
+			CloudProvider: clusterInfo.Cluster.Provider.Name,
+			Region:        clusterInfo.Cluster.Provider.Region,
+		},
+	}, nil
 }
 
 func (d *Deployer) UpdateClusterExpiry(ctx context.Context, clusterID string, newExpiryTime time.Time) error {
@@ -975,6 +1223,23 @@ func (d *Deployer) UpdateClusterExpiry(ctx context.Context, clusterID string, ne
 }
 
 func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clusterdef.Cluster) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "ModifyCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.modifyCluster(ctx, clusterID, def)
+	if err != nil {
+		err = deployment.WrapProvisionTimeout(err)
+		d.captureDiagnostics(ctx, clusterID, def, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *Deployer) modifyCluster(ctx context.Context, clusterID string, def *clusterdef.Cluster) error {
 	clusterInfo, err := d.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -988,6 +1253,15 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 			Description: clusterInfo.Columnar.Description,
 			Nodes:       def.NodeGroups[0].Count,
 		}
+
+		if d.dryRun {
+			d.logger.Info("dry-run: would update columnar specs",
+				zap.String("project-id", clusterInfo.Columnar.ProjectID),
+				zap.String("columnar-id", clusterInfo.Columnar.ID),
+				zap.Any("spec", newSpec))
+			return nil
+		}
+
 		err = d.client.UpdateColumnarSpecs(ctx, clusterInfo.Columnar.TenantID, clusterInfo.Columnar.ProjectID, clusterInfo.Columnar.ID, newSpec)
 		if err != nil {
 			return errors.Wrap(err, "failed to update specs")
@@ -1010,6 +1284,11 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 		return nil
 	}
 
+	err = deployment.CheckNodeGroupFeatures(def.NodeGroups)
+	if err != nil {
+		return err
+	}
+
 	cloudProjectID := clusterInfo.Cluster.Project.Id
 	cloudClusterID := clusterInfo.Cluster.Id
 	cloudProvider := clusterInfo.Cluster.Provider.Name
@@ -1022,9 +1301,24 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 		return errors.Wrap(err, "failed to build cluster specs")
 	}
 
-	if !isServiceEqual(clusterInfo.Cluster.Services, newSpecs) {
+	specsChanged := !isServiceEqual(clusterInfo.Cluster.Services, newSpecs)
+	if specsChanged {
+		err = checkSpecsChange(clusterInfo.Cluster.Services, newSpecs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if specsChanged && d.dryRun {
+		d.logger.Info("dry-run: would update cluster specs",
+			zap.String("project-id", cloudProjectID),
+			zap.String("cluster-id", cloudClusterID),
+			zap.Any("specs", newSpecs))
+	} else if specsChanged {
 		d.logger.Info("cluster current spec is different from the def spec")
 		d.logger.Debug("generated new specification list", zap.Any("specs", newSpecs))
+
+		progress.Step(ctx, "updating cluster specs")
 		err = d.client.UpdateClusterSpecs(
 			ctx,
 			d.tenantID,
@@ -1044,12 +1338,26 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 			return errors.Wrap(err, "failed to wait for cluster modification to begin")
 		}
 
+		progress.Step(ctx, "waiting for cluster to scale")
 		d.logger.Debug("waiting for cluster to be healthy")
 
 		err = d.mgr.WaitForClusterState(ctx, d.tenantID, cloudClusterID, "healthy", false)
 		if err != nil {
 			return errors.Wrap(err, "failed to wait for cluster to be healthy")
 		}
+
+		progress.Step(ctx, "verifying cluster topology")
+		d.logger.Debug("verifying cluster topology")
+
+		scaledInfo, err := d.getCluster(ctx, clusterID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cluster after scaling")
+		}
+
+		err = checkTopology(scaledInfo.Cluster.Services, newSpecs)
+		if err != nil {
+			return errors.Wrap(err, "cluster does not match the definition after scaling")
+		}
 	}
 
 	var (
@@ -1076,6 +1384,15 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 
 		d.logger.Info(fmt.Sprintf("Release id is: %s", releaseId))
 
+		if d.dryRun {
+			d.logger.Info("dry-run: would update server version",
+				zap.String("project-id", cloudProjectID),
+				zap.String("cluster-id", cloudClusterID),
+				zap.String("server-version", clusterVersion),
+				zap.String("server-image", serverImage))
+			return nil
+		}
+
 		err = d.client.UpdateServerVersion(ctx, d.tenantID, cloudProjectID, cloudClusterID, &capellacontrol.UpdateServerVersionRequest{
 			OverrideToken: d.overrideToken,
 			ServerImage:   serverImage,
@@ -1109,7 +1426,27 @@ func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID stri
 	return errors.New("clouddeploy does not support cluster node removal")
 }
 
+func (d *Deployer) SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error) {
+	return "", errors.New("clouddeploy does not support swapping cluster nodes")
+}
+
 func (p *Deployer) removeCluster(ctx context.Context, clusterInfo *clusterInfo) error {
+	if p.dryRun {
+		if clusterInfo.Cluster != nil {
+			p.logger.Info("dry-run: would delete cluster",
+				zap.String("project-id", clusterInfo.Cluster.Project.Id),
+				zap.String("cluster-id", clusterInfo.Cluster.Id))
+		} else if clusterInfo.Columnar != nil {
+			p.logger.Info("dry-run: would delete columnar",
+				zap.String("project-id", clusterInfo.Columnar.ProjectID),
+				zap.String("columnar-id", clusterInfo.Columnar.ID))
+		}
+		p.logger.Info("dry-run: would delete project",
+			zap.String("project-id", clusterInfo.Project.ID))
+		return nil
+	}
+
+	progress.Step(ctx, "deleting cluster")
 	p.logger.Debug("deleting the cloud cluster", zap.String("cluster-id", clusterInfo.Meta.ID.String()))
 
 	if clusterInfo.Cluster != nil {
@@ -1118,6 +1455,7 @@ func (p *Deployer) removeCluster(ctx context.Context, clusterInfo *clusterInfo)
 			return errors.Wrap(err, "failed to delete cluster")
 		}
 
+		progress.Step(ctx, "waiting for cluster deletion")
 		p.logger.Debug("waiting for cluster deletion to finish")
 
 		err = p.mgr.WaitForClusterState(ctx, p.tenantID, clusterInfo.Cluster.Id, "", false)
@@ -1130,6 +1468,7 @@ func (p *Deployer) removeCluster(ctx context.Context, clusterInfo *clusterInfo)
 			return errors.Wrap(err, "failed to delete cluster")
 		}
 
+		progress.Step(ctx, "waiting for cluster deletion")
 		p.logger.Debug("waiting for cluster deletion to finish")
 
 		err = p.mgr.WaitForClusterState(ctx, p.tenantID, clusterInfo.Columnar.ID, "", true)
@@ -1138,6 +1477,7 @@ func (p *Deployer) removeCluster(ctx context.Context, clusterInfo *clusterInfo)
 		}
 	}
 
+	progress.Step(ctx, "deleting project")
 	p.logger.Debug("deleting the cloud project")
 
 	err := p.client.DeleteProject(ctx, p.tenantID, clusterInfo.Project.ID)
@@ -1149,6 +1489,12 @@ func (p *Deployer) removeCluster(ctx context.Context, clusterInfo *clusterInfo)
 }
 
 func (p *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "RemoveCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1203,6 +1549,12 @@ func (p *Deployer) ListAllowListEntries(ctx context.Context, clusterID string) (
 }
 
 func (p *Deployer) AddAllowListEntry(ctx context.Context, clusterID string, cidr string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "AddAllowListEntry")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1232,6 +1584,12 @@ func (p *Deployer) AddAllowListEntry(ctx context.Context, clusterID string, cidr
 }
 
 func (p *Deployer) RemoveAllowListEntry(ctx context.Context, clusterID string, cidr string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "RemoveAllowListEntry")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1281,6 +1639,12 @@ func (p *Deployer) RemoveAllowListEntry(ctx context.Context, clusterID string, c
 }
 
 func (p *Deployer) EnablePrivateEndpoints(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "EnablePrivateEndpoints")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1300,6 +1664,12 @@ func (p *Deployer) EnablePrivateEndpoints(ctx context.Context, clusterID string)
 }
 
 func (p *Deployer) DisablePrivateEndpoints(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "DisablePrivateEndpoints")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1406,6 +1776,15 @@ func (p *Deployer) RemoveAll(ctx context.Context) error {
 	}
 	p.logger.Info("found clusters to remove", zap.Strings("clusters", clusterNamesToRemove))
 
+	if p.dryRun {
+		for _, cluster := range clustersToRemove {
+			p.logger.Info("dry-run: would delete cluster",
+				zap.String("project-id", cluster.Project.Id),
+				zap.String("cluster-id", cluster.Id))
+		}
+		clustersToRemove = nil
+	}
+
 	for _, cluster := range clustersToRemove {
 		p.logger.Info("removing a cluster", zap.String("cluster-id", cluster.Id))
 
@@ -1449,6 +1828,15 @@ func (p *Deployer) RemoveAll(ctx context.Context) error {
 	}
 	p.logger.Info("found columnar to remove", zap.Strings("columnar", columnarNamesToRemove))
 
+	if p.dryRun {
+		for _, columnar := range columnarsToRemove {
+			p.logger.Info("dry-run: would delete columnar",
+				zap.String("project-id", columnar.ProjectID),
+				zap.String("columnar-id", columnar.ID))
+		}
+		columnarsToRemove = nil
+	}
+
 	for _, columnar := range columnarsToRemove {
 		p.logger.Info("removing a columnar", zap.String("cluster-id", columnar.ID))
 
@@ -1492,6 +1880,14 @@ func (p *Deployer) RemoveAll(ctx context.Context) error {
 	}
 	p.logger.Info("found projects to remove", zap.Strings("projects", projectNamesToRemove))
 
+	if p.dryRun {
+		for _, project := range projectsToRemove {
+			p.logger.Info("dry-run: would delete project",
+				zap.String("project-id", project.ID))
+		}
+		projectsToRemove = nil
+	}
+
 	for _, project := range projectsToRemove {
 		p.logger.Info("removing a project", zap.String("project-id", project.ID))
 
@@ -1534,20 +1930,54 @@ func (p *Deployer) Cleanup(ctx context.Context) error {
 
 	curTime := time.Now()
 	for _, cluster := range clusters {
-		if !cluster.Meta.Expiry.IsZero() && !cluster.Meta.Expiry.After(curTime) {
+		expiryPhase := deployment.GetExpiryPhase(cluster.Meta.Expiry, p.gracePeriod, curTime)
+		if expiryPhase == deployment.ExpiryPhaseExpiring {
+			p.logger.Warn("cluster has expired and will be removed after the grace period",
+				zap.String("cluster-id", cluster.Meta.ID.String()),
+				zap.Time("expiry", cluster.Meta.Expiry),
+				zap.Duration("grace-period", p.gracePeriod))
+
+			if p.onExpiring != nil {
+				p.onExpiring(ctx, cluster.Meta.ID.String(), cluster.Meta.Expiry)
+			}
+		} else if expiryPhase == deployment.ExpiryPhaseExpired {
 			p.logger.Info("removing cluster",
 				zap.String("cluster-id", cluster.Meta.ID.String()))
 
+			action := &deployment.CleanupAction{
+				Type:      "cluster",
+				ID:        cluster.Meta.ID.String(),
+				ClusterID: cluster.Meta.ID.String(),
+				Expiry:    cluster.Meta.Expiry,
+				Reason:    "expired",
+				DryRun:    p.dryRun,
+			}
+			if cluster.Cluster != nil {
+				action.Name = cluster.Cluster.Name
+				action.CreatedAt = cluster.Cluster.CreatedAt
+			} else if cluster.Columnar != nil {
+				action.Name = cluster.Columnar.Name
+				action.CreatedAt, _ = time.Parse(time.RFC3339, cluster.Columnar.CreatedAt)
+			}
+
 			if cluster.Cluster != nil && cluster.Cluster.Status.State == "destroy_failed" {
 				p.logger.Warn("skipping due to destroy_failed state (cluster)")
+				action.Error = "skipped due to destroy_failed state"
+				deployment.RecordCleanupAction(ctx, action)
 				continue
 			}
 			if cluster.Columnar != nil && cluster.Columnar.State == "destroy_failed" {
 				p.logger.Warn("skipping due to destroy_failed state (columnar)")
+				action.Error = "skipped due to destroy_failed state"
+				deployment.RecordCleanupAction(ctx, action)
 				continue
 			}
 
-			p.removeCluster(ctx, cluster)
+			err := p.removeCluster(ctx, cluster)
+			if err != nil {
+				action.Error = err.Error()
+			}
+			deployment.RecordCleanupAction(ctx, action)
 		}
 	}
 
@@ -1620,6 +2050,12 @@ func (p *Deployer) ListUsers(ctx context.Context, clusterID string) ([]deploymen
 }
 
 func (p *Deployer) CreateUser(ctx context.Context, clusterID string, opts *deployment.CreateUserOptions) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "CreateUser")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1628,10 +2064,14 @@ func (p *Deployer) CreateUser(ctx context.Context, clusterID string, opts *deplo
 		perms := make(map[string]capellacontrol.CreateUserRequest_Permission)
 
 		if opts.CanRead {
-			perms["data_reader"] = capellacontrol.CreateUserRequest_Permission{}
+			perms["data_reader"] = capellacontrol.CreateUserRequest_Permission{
+				Buckets: opts.Buckets,
+			}
 		}
 		if opts.CanWrite {
-			perms["data_writer"] = capellacontrol.CreateUserRequest_Permission{}
+			perms["data_writer"] = capellacontrol.CreateUserRequest_Permission{
+				Buckets: opts.Buckets,
+			}
 		}
 
 		err = p.mgr.Client.CreateUser(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id, &capellacontrol.CreateUserRequest{
@@ -1673,6 +2113,12 @@ func (p *Deployer) CreateUser(ctx context.Context, clusterID string, opts *deplo
 }
 
 func (p *Deployer) DeleteUser(ctx context.Context, clusterID string, username string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "DeleteUser")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1758,6 +2204,17 @@ func (p *Deployer) ListBuckets(ctx context.Context, clusterID string) ([]deploym
 }
 
 func (p *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *deployment.CreateBucketOptions) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "CreateBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	resolvedOpts, err := deployment.ResolveBucketOptions(opts)
+	if err != nil {
+		return err
+	}
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1773,6 +2230,24 @@ func (p *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *dep
 		numReplicas = opts.NumReplicas
 	}
 
+	storageBackend := ""
+	if resolvedOpts.BucketType == deployment.BucketTypeCouchbase {
+		storageBackend = "couchstore"
+		if opts.StorageBackend != "" {
+			storageBackend = opts.StorageBackend
+		}
+	}
+
+	if opts.HistoryRetentionBytes != 0 || opts.HistoryRetentionDuration != 0 {
+		return errors.Wrap(deployment.ErrFeatureUnsupported, "clouddeploy does not support bucket history retention")
+	}
+	if resolvedOpts.BucketType == deployment.BucketTypeMemcached {
+		return errors.Wrap(deployment.ErrFeatureUnsupported, "clouddeploy does not support memcached buckets")
+	}
+	if opts.EvictionPolicy != "" {
+		return errors.Wrap(deployment.ErrFeatureUnsupported, "clouddeploy does not support bucket eviction policies")
+	}
+
 	err = p.mgr.Client.CreateBucket(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id, &capellacontrol.CreateBucketRequest{
 		BucketConflictResolution: "seqno",
 		DurabilityLevel:          "none",
@@ -1780,8 +2255,8 @@ func (p *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *dep
 		MemoryAllocationInMB:     ramQuotaMb,
 		Name:                     opts.Name,
 		Replicas:                 numReplicas,
-		StorageBackend:           "couchstore",
-		Type:                     "couchbase",
+		StorageBackend:           storageBackend,
+		Type:                     string(resolvedOpts.BucketType),
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to create bucket")
@@ -1791,6 +2266,12 @@ func (p *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *dep
 }
 
 func (p *Deployer) DeleteBucket(ctx context.Context, clusterID string, bucketName string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "DeleteBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := p.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1808,6 +2289,12 @@ func (p *Deployer) DeleteBucket(ctx context.Context, clusterID string, bucketNam
 }
 
 func (d *Deployer) LoadSampleBucket(ctx context.Context, clusterID string, bucketName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "LoadSampleBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := d.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -1909,6 +2396,12 @@ func (d *Deployer) CollectLogs(ctx context.Context, clusterID string, destPath s
 }
 
 func (d *Deployer) RedeployCluster(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RedeployCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	cluster, err := d.getCluster(ctx, clusterID)
 	if err != nil {
 		return err
@@ -2013,23 +2506,337 @@ func (p *Deployer) ExecuteQuery(ctx context.Context, clusterID string, query str
 }
 
 func (d *Deployer) ListCollections(ctx context.Context, clusterID string, bucketName string) ([]deployment.ScopeInfo, error) {
-	return nil, errors.New("clouddeploy does not support getting collections")
+	manifest, err := d.GetCollectionManifest(ctx, clusterID, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.Scopes, nil
+}
+
+func (d *Deployer) GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*deployment.CollectionManifest, error) {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestJson, err := d.mgr.Client.GetClusterCollectionManifest(ctx, clusterInfo.Cluster.Id, bucketName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch collection manifest")
+	}
+
+	return deployment.ParseCollectionManifest(manifestJson)
+}
+
+func (d *Deployer) WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// the server waits a while for the manifest to reach every node before
+		// failing, so we just keep asking until it does or we give up.
+		err := d.mgr.Client.EnsureClusterCollectionManifest(ctx, clusterInfo.Cluster.Id, bucketName, minUID)
+		if err == nil {
+			return nil
+		}
+
+		d.logger.Debug("collection manifest not yet propagated", zap.Error(err))
+
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return errors.Wrap(err, "context finished while waiting for collection manifest")
+		}
+	}
+}
+
+// getServerCluster gets a cluster which supports the cluster management
+// APIs proxied by Capella, which columnar instances do not.
+func (d *Deployer) getServerCluster(ctx context.Context, clusterID string) (*clusterInfo, error) {
+	clusterInfo, err := d.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if clusterInfo.Cluster == nil {
+		return nil, errors.Wrap(deployment.ErrFeatureUnsupported, "columnar instances do not support collection management")
+	}
+
+	return clusterInfo, nil
 }
 
 func (d *Deployer) CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
-	return errors.New("clouddeploy does not support creating scopes")
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateScope")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mgr.Client.CreateClusterScope(ctx, clusterInfo.Cluster.Id, bucketName, scopeName)
+	if err != nil {
+		return errors.Wrap(err, "failed to create scope")
+	}
+
+	return nil
 }
 
-func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
-	return errors.New("clouddeploy does not support creating collections")
+func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.CreateCollectionOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	settings := &capellacontrol.CollectionSettings{}
+	if opts != nil {
+		if opts.MaxTTL != 0 {
+			maxTTL := deployment.MaxTTLSeconds(opts.MaxTTL)
+			settings.MaxTTL = &maxTTL
+		}
+		settings.History = opts.History
+	}
+
+	err = d.mgr.Client.CreateClusterCollection(ctx, clusterInfo.Cluster.Id, bucketName, scopeName, collectionName, settings)
+	if err != nil {
+		return errors.Wrap(err, "failed to create collection")
+	}
+
+	return nil
+}
+
+func (d *Deployer) UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.UpdateCollectionOptions) error {
+	err := deployment.CheckUpdateCollectionOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "UpdateCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	settings := &capellacontrol.CollectionSettings{
+		History: opts.History,
+	}
+	if opts.MaxTTL != nil {
+		maxTTL := deployment.MaxTTLSeconds(*opts.MaxTTL)
+		settings.MaxTTL = &maxTTL
+	}
+
+	err = d.mgr.Client.UpdateClusterCollection(ctx, clusterInfo.Cluster.Id, bucketName, scopeName, collectionName, settings)
+	if err != nil {
+		return errors.Wrap(err, "failed to update collection")
+	}
+
+	return nil
 }
 
 func (d *Deployer) DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
-	return errors.New("clouddeploy does not support deleting scopes")
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteScope")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mgr.Client.DeleteClusterScope(ctx, clusterInfo.Cluster.Id, bucketName, scopeName)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete scope")
+	}
+
+	return nil
 }
 
 func (d *Deployer) DeleteCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
-	return errors.New("clouddeploy does not support deleting collections")
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mgr.Client.DeleteClusterCollection(ctx, clusterInfo.Cluster.Id, bucketName, scopeName, collectionName)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete collection")
+	}
+
+	return nil
+}
+
+// GetManagementJson fetches an endpoint of the cluster management API
+// through the Capella proxy.
+func (d *Deployer) GetManagementJson(ctx context.Context, clusterID string, path string) (json.RawMessage, error) {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.mgr.Client.GetClusterRawJson(ctx, clusterInfo.Cluster.Id, path)
+}
+
+func (d *Deployer) CreateQueryFunction(ctx context.Context, clusterID string, opts *deployment.CreateQueryFunctionOptions) error {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	statement, err := opts.Statement()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.mgr.Client.ExecuteClusterQuery(ctx, clusterInfo.Cluster.Id, statement)
+	if err != nil {
+		return errors.Wrap(err, "failed to create query function")
+	}
+
+	return nil
+}
+
+func (d *Deployer) DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	statement, err := deployment.DropQueryFunctionStatement(bucketName, scopeName, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.mgr.Client.ExecuteClusterQuery(ctx, clusterInfo.Cluster.Id, statement)
+	if err != nil {
+		return errors.Wrap(err, "failed to drop query function")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListJsLibraries(ctx context.Context, clusterID string) ([]deployment.JsLibraryInfo, error) {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	libraries, err := d.mgr.Client.ListClusterJsLibraries(ctx, clusterInfo.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list javascript libraries")
+	}
+
+	var out []deployment.JsLibraryInfo
+	for _, library := range libraries {
+		out = append(out, deployment.JsLibraryInfo{
+			BucketName: library.Bucket,
+			ScopeName:  library.Scope,
+			Name:       library.Name,
+			Code:       library.Code,
+		})
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) CreateJsLibrary(ctx context.Context, clusterID string, opts *deployment.CreateJsLibraryOptions) error {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mgr.Client.CreateClusterJsLibrary(ctx, clusterInfo.Cluster.Id,
+		opts.BucketName, opts.ScopeName, opts.Name, opts.Code)
+	if err != nil {
+		return errors.Wrap(err, "failed to create javascript library")
+	}
+
+	return nil
+}
+
+func (d *Deployer) DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mgr.Client.DeleteClusterJsLibrary(ctx, clusterInfo.Cluster.Id, bucketName, scopeName, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete javascript library")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListIndexes(ctx context.Context, clusterID string) ([]deployment.IndexInfo, error) {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := d.mgr.Client.ListClusterIndexes(ctx, clusterInfo.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list indexes")
+	}
+
+	var out []deployment.IndexInfo
+	for _, index := range indexes {
+		out = append(out, deployment.IndexInfo{
+			BucketName:     index.Bucket,
+			ScopeName:      index.Scope,
+			CollectionName: index.Collection,
+			Name:           index.Name,
+			ReplicaID:      index.ReplicaID,
+			Status:         index.Status,
+			Progress:       index.Progress,
+			Definition:     index.Definition,
+			Hosts:          index.Hosts,
+		})
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) AdviseIndexes(ctx context.Context, clusterID string, statement string) (*deployment.IndexAdvice, error) {
+	clusterInfo, err := d.getServerCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	adviseStatement, err := deployment.IndexAdviseStatement(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := d.mgr.Client.AdviseClusterIndexes(ctx, clusterInfo.Cluster.Id, adviseStatement)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run index advisor")
+	}
+
+	return deployment.ParseIndexAdvice(row)
 }
 
 func (d *Deployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType deployment.BlockNodeTrafficType) error {
@@ -2041,11 +2848,44 @@ func (d *Deployer) AllowNodeTraffic(ctx context.Context, clusterID string, nodeI
 }
 
 func (d *Deployer) ListImages(ctx context.Context) ([]deployment.Image, error) {
-	return nil, errors.New("clouddeploy does not support image listing")
+	var images []deployment.Image
+	for _, cloudProvider := range []string{"aws", "azure", "gcp"} {
+		deploymentOpts, err := d.client.GetProviderDeploymentOptions(ctx, d.tenantID, &capellacontrol.GetProviderDeploymentOptionsRequest{
+			Provider: cloudProvider,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get deployment options for %s", cloudProvider)
+		}
+
+		for _, version := range deploymentOpts.ServerVersions.Versions {
+			images = append(images, deployment.Image{
+				Source:     "capella-" + cloudProvider,
+				Name:       version,
+				SourcePath: "",
+			})
+		}
+	}
+
+	return images, nil
 }
 
 func (d *Deployer) SearchImages(ctx context.Context, version string) ([]deployment.Image, error) {
-	return nil, errors.New("clouddeploy does not support image search")
+	allImages, err := d.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []deployment.Image
+	for _, image := range allImages {
+		if !strings.Contains(image.Name, version) {
+			// ignore versions that don't match the search
+			continue
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
 }
 
 func (d *Deployer) PauseNode(ctx context.Context, clusterID string, nodeID string) error {
@@ -2055,3 +2895,11 @@ func (d *Deployer) PauseNode(ctx context.Context, clusterID string, nodeID strin
 func (d *Deployer) UnpauseNode(ctx context.Context, clusterID string, nodeID string) error {
 	return errors.New("clouddeploy does not support node pausing")
 }
+
+func (d *Deployer) SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error {
+	return errors.New("clouddeploy does not support clock skew")
+}
+
+func (d *Deployer) GetNodeVersions(ctx context.Context, clusterID string) ([]deployment.NodeVersionInfo, error) {
+	return nil, errors.New("clouddeploy does not support node version reporting")
+}
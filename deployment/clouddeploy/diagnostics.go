@@ -0,0 +1,63 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"go.uber.org/zap"
+)
+
+const diagnosticsTimeout = 2 * time.Minute
+
+// captureDiagnostics writes a diagnostics bundle for a failed deployment
+// when a diagnostics path is configured.  Capella keeps failed clusters
+// around, so we capture whatever state and jobs it still has for them.
+func (p *Deployer) captureDiagnostics(
+	ctx context.Context,
+	clusterID string,
+	def *clusterdef.Cluster,
+	cause error,
+) {
+	if p.diagnosticsPath == "" {
+		return
+	}
+
+	// the failure may have been caused by the context expiring, which must
+	// not prevent us from gathering the diagnostics.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	bundle, err := deployment.NewDiagnosticsBundle(p.logger, p.diagnosticsPath, clusterID)
+	if err != nil {
+		p.logger.Warn("failed to create diagnostics bundle", zap.Error(err))
+		return
+	}
+
+	p.logger.Info("capturing diagnostics bundle", zap.String("path", bundle.Path))
+
+	bundle.WriteFile("error.txt", []byte(fmt.Sprintf("%+v\n", cause)))
+	if def != nil {
+		bundle.WriteYaml("definition.yaml", def)
+	}
+
+	cluster, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		bundle.WriteError("cluster.json", err)
+	} else if cluster.Columnar != nil {
+		bundle.WriteJson("cluster.json", cluster.Columnar)
+	} else {
+		bundle.WriteJson("cluster.json", cluster.Cluster)
+
+		jobs, err := p.ListClusterJobs(ctx, clusterID)
+		if err != nil {
+			bundle.WriteError("jobs.json", err)
+		} else {
+			bundle.WriteJson("jobs.json", jobs)
+		}
+	}
+
+	p.logger.Info("diagnostics bundle captured", zap.String("path", bundle.Path))
+}
@@ -0,0 +1,59 @@
+package clouddeploy
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/dnswait"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"go.uber.org/zap"
+)
+
+type skipDnsWaitCtxKey struct{}
+
+// WithSkipDnsWait returns a context which causes clusters created with it to
+// be returned without waiting for their DNS records to propagate.
+func WithSkipDnsWait(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipDnsWaitCtxKey{}, true)
+}
+
+func shouldSkipDnsWait(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipDnsWaitCtxKey{}).(bool)
+	return skip
+}
+
+// waitForClusterDns waits for the SRV record of a newly created cluster to
+// propagate, since connecting to it fails until it has.  The cluster is
+// usable once the records propagate, so a timeout is only logged.
+func (p *Deployer) waitForClusterDns(ctx context.Context, clusterID string) {
+	if p.dryRun || shouldSkipDnsWait(ctx) {
+		return
+	}
+
+	clusterInfo, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		p.logger.Warn("failed to get cluster to wait for dns records", zap.Error(err))
+		return
+	}
+
+	if clusterInfo.Cluster == nil || clusterInfo.Cluster.Connect.Srv == "" {
+		return
+	}
+
+	srvHost := clusterInfo.Cluster.Connect.Srv
+
+	progress.Step(ctx, "waiting for dns records to propagate")
+	p.logger.Info("waiting for dns records to propagate",
+		zap.String("host", srvHost),
+		zap.Strings("resolvers", p.dnsResolvers))
+
+	err = dnswait.WaitForSrv(ctx, srvHost, &dnswait.WaitOptions{
+		Logger:    p.logger,
+		Resolvers: p.dnsResolvers,
+		Timeout:   p.dnsWaitTimeout,
+	})
+	if err != nil {
+		p.logger.Warn("dns records have not propagated, connecting to the cluster may fail until they do",
+			zap.String("host", srvHost),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,134 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"go.uber.org/zap"
+)
+
+const (
+	// watchPollInterval is how often clusters are listed while watching.
+	watchPollInterval = 30 * time.Second
+
+	// watchJobPollInterval is how often the jobs of clusters which are
+	// changing are checked, so that their completion is noticed quickly.
+	watchJobPollInterval = 5 * time.Second
+)
+
+// isSteadyClusterState indicates whether a cluster is in a state which
+// Capella jobs are not expected to move it out of.
+func isSteadyClusterState(state string) bool {
+	switch state {
+	case "healthy", "turnedOff", "corrupted", deployment.ClusterStateExpiring:
+		return true
+	}
+	return false
+}
+
+// clusterJobsWatcher polls the jobs of clusters which are changing and
+// triggers a relisting of the clusters whenever their jobs change.
+type clusterJobsWatcher struct {
+	deployer  *Deployer
+	triggerCh chan struct{}
+
+	lock          sync.Mutex
+	changing      []*ClusterInfo
+	jobsByCluster map[string]string
+}
+
+func (w *clusterJobsWatcher) ListClusters(ctx context.Context) ([]deployment.ClusterInfo, error) {
+	clusters, err := w.deployer.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changing []*ClusterInfo
+	for _, cluster := range clusters {
+		cloudCluster := cluster.(*ClusterInfo)
+		if cloudCluster.Type == deployment.ClusterTypeServer && !isSteadyClusterState(cloudCluster.State) {
+			changing = append(changing, cloudCluster)
+		}
+	}
+
+	w.lock.Lock()
+	w.changing = changing
+	w.lock.Unlock()
+
+	return clusters, nil
+}
+
+func (w *clusterJobsWatcher) pollJobs(ctx context.Context) {
+	w.lock.Lock()
+	changing := w.changing
+	w.lock.Unlock()
+
+	changed := false
+	for _, cluster := range changing {
+		resp, err := w.deployer.client.ListClusterJobs(
+			ctx, w.deployer.tenantID, cluster.CloudProjectID, cluster.CloudClusterID)
+		if err != nil {
+			w.deployer.logger.Debug("failed to list cluster jobs while watching",
+				zap.String("cluster", cluster.ClusterID),
+				zap.Error(err))
+			continue
+		}
+
+		var jobStates []string
+		for _, job := range resp.Data {
+			jobStates = append(jobStates,
+				fmt.Sprintf("%s:%s:%d", job.Data.ID, job.Data.CurrentStep, job.Data.CompletionPercentage))
+		}
+		jobsStr := strings.Join(jobStates, ",")
+
+		if prevJobsStr, ok := w.jobsByCluster[cluster.ClusterID]; ok && prevJobsStr != jobsStr {
+			changed = true
+		}
+		w.jobsByCluster[cluster.ClusterID] = jobsStr
+	}
+
+	if changed {
+		select {
+		case w.triggerCh <- struct{}{}:
+		default:
+			// a relisting is already pending
+		}
+	}
+}
+
+func (w *clusterJobsWatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(watchJobPollInterval):
+			w.pollJobs(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Deployer) WatchClusters(ctx context.Context) (<-chan deployment.ClusterEvent, error) {
+	jobsWatcher := &clusterJobsWatcher{
+		deployer:      p,
+		triggerCh:     make(chan struct{}, 1),
+		jobsByCluster: make(map[string]string),
+	}
+
+	eventCh, err := deployment.WatchClusterChanges(ctx, &deployment.ClusterWatcherOptions{
+		Logger:       p.logger,
+		ListClusters: jobsWatcher.ListClusters,
+		Triggers:     jobsWatcher.triggerCh,
+		PollInterval: watchPollInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go jobsWatcher.run(ctx)
+
+	return eventCh, nil
+}
@@ -2,6 +2,7 @@ package clouddeploy
 
 import (
 	"fmt"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
 	"github.com/pkg/errors"
 	"sort"
@@ -108,3 +109,94 @@ func getReleaseIdFromServerImage(serverImage string) (string, error) {
 	releaseID := fmt.Sprintf("1.0.%d", releaseNumber)
 	return releaseID, nil
 }
+
+// wrapQuotaError marks errors caused by Capella quotas as quota errors.
+func wrapQuotaError(err error) error {
+	if capellacontrol.IsQuotaError(err) {
+		return deployment.NewError(deployment.ErrQuotaExceeded, err)
+	}
+	return err
+}
+
+// specServicesKey identifies a group of nodes by the services it runs, which
+// is how the groups of a spec update are matched to the existing nodes.
+func specServicesKey(services []capellacontrol.UpdateClusterSpecsRequest_Spec_Service) string {
+	var serviceTypes []string
+	for _, service := range services {
+		serviceTypes = append(serviceTypes, service.Type)
+	}
+
+	sort.Strings(serviceTypes)
+	return strings.Join(serviceTypes, ",")
+}
+
+func findClusterService(services []capellacontrol.ClusterInfo_Service, key string) *capellacontrol.ClusterInfo_Service {
+	for i := range services {
+		if specServicesKey(convertClusterServicesToSpecServices(services[i].Services)) == key {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// checkSpecsChange rejects changes to the specs of a cluster which Capella
+// does not permit, so that they are reported before anything is modified.
+func checkSpecsChange(current []capellacontrol.ClusterInfo_Service, newSpecs []capellacontrol.UpdateClusterSpecsRequest_Spec) error {
+	for _, spec := range newSpecs {
+		key := specServicesKey(spec.Services)
+
+		currentService := findClusterService(current, key)
+		if currentService == nil {
+			continue
+		}
+
+		if spec.Disk.SizeInGb < currentService.Disk.SizeInGb {
+			return deployment.NewError(deployment.ErrInvalidDefinition,
+				fmt.Errorf("disks of the %s nodes cannot shrink from %dGB to %dGB",
+					key, currentService.Disk.SizeInGb, spec.Disk.SizeInGb))
+		}
+
+		if spec.Disk.Type != "" && currentService.Disk.Type != "" && spec.Disk.Type != currentService.Disk.Type {
+			return deployment.NewError(deployment.ErrInvalidDefinition,
+				fmt.Errorf("disk type of the %s nodes cannot change from %s to %s",
+					key, currentService.Disk.Type, spec.Disk.Type))
+		}
+	}
+
+	return nil
+}
+
+// checkTopology verifies that a cluster matches the specs it was modified
+// to.  Disks are only required to be at least the requested size, since
+// disk auto-scaling may have grown them further.
+func checkTopology(current []capellacontrol.ClusterInfo_Service, specs []capellacontrol.UpdateClusterSpecsRequest_Spec) error {
+	if len(current) != len(specs) {
+		return fmt.Errorf("cluster has %d groups of nodes but %d were requested", len(current), len(specs))
+	}
+
+	for _, spec := range specs {
+		key := specServicesKey(spec.Services)
+
+		currentService := findClusterService(current, key)
+		if currentService == nil {
+			return fmt.Errorf("cluster has no %s nodes", key)
+		}
+
+		if currentService.Count != spec.Count {
+			return fmt.Errorf("cluster has %d %s nodes but %d were requested",
+				currentService.Count, key, spec.Count)
+		}
+
+		if currentService.Compute.Type != spec.Compute.Type {
+			return fmt.Errorf("%s nodes use %s compute but %s was requested",
+				key, currentService.Compute.Type, spec.Compute.Type)
+		}
+
+		if currentService.Disk.SizeInGb < spec.Disk.SizeInGb {
+			return fmt.Errorf("%s nodes have %dGB disks but %dGB was requested",
+				key, currentService.Disk.SizeInGb, spec.Disk.SizeInGb)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package clouddeploy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/stringclustermeta"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type JanitorResourceType string
+
+const (
+	JanitorResourceCluster  JanitorResourceType = "cluster"
+	JanitorResourceColumnar JanitorResourceType = "columnar"
+	JanitorResourceProject  JanitorResourceType = "project"
+)
+
+type JanitorResource struct {
+	Type       JanitorResourceType
+	ProjectID  string
+	ResourceID string
+	Name       string
+	CreatedBy  string
+	CreatedAt  time.Time
+	Expiry     time.Time
+	Reason     string
+}
+
+func (r *JanitorResource) cleanupAction(dryRun bool, err error) *deployment.CleanupAction {
+	action := &deployment.CleanupAction{
+		Type:      string(r.Type),
+		ID:        r.ResourceID,
+		Name:      r.Name,
+		Owner:     r.CreatedBy,
+		CreatedAt: r.CreatedAt,
+		Expiry:    r.Expiry,
+		Reason:    r.Reason,
+		DryRun:    dryRun,
+	}
+	if err != nil {
+		action.Error = err.Error()
+	}
+	return action
+}
+
+type JanitorOptions struct {
+	// MaxAge causes dyncluster resources which carry no expiry meta-data to
+	// be removed once they are older than this.  Zero disables this.
+	MaxAge time.Duration
+}
+
+func parseResourceMeta(strs ...string) *stringclustermeta.MetaData {
+	for _, str := range strs {
+		meta, _ := stringclustermeta.Parse(str)
+		if meta != nil {
+			return meta
+		}
+	}
+	return nil
+}
+
+// FindJanitorResources scans the entire tenant for dyncluster resources which
+// have exceeded their expiry.  Unlike Cleanup, this does not rely on the
+// project/cluster pairing being intact, and so also finds resources left
+// behind by crashed deployments on other machines.
+//
+// The expiry is taken from the project name where available, falling back to
+// the cluster description, which carries the same meta-data.
+func (p *Deployer) FindJanitorResources(ctx context.Context, opts *JanitorOptions) ([]*JanitorResource, error) {
+	projects, err := p.client.ListProjects(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list projects")
+	}
+
+	clusters, err := p.client.ListAllClusters(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list all clusters")
+	}
+
+	columnars, err := p.client.ListAllColumnars(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list all columnars")
+	}
+
+	projectNames := make(map[string]string)
+	for _, project := range projects.Data {
+		projectNames[project.Data.ID] = project.Data.Name
+	}
+
+	curTime := time.Now()
+	isExpired := func(meta *stringclustermeta.MetaData, createdAt time.Time) (bool, time.Time, string) {
+		if meta != nil && !meta.Expiry.IsZero() {
+			phase := deployment.GetExpiryPhase(meta.Expiry, p.gracePeriod, curTime)
+			return phase == deployment.ExpiryPhaseExpired, meta.Expiry, "expired"
+		}
+
+		if opts.MaxAge > 0 && !createdAt.IsZero() && curTime.Sub(createdAt) > opts.MaxAge {
+			return true, time.Time{}, "exceeded max age"
+		}
+
+		return false, time.Time{}, ""
+	}
+
+	var out []*JanitorResource
+	liveProjects := make(map[string]bool)
+
+	for _, cluster := range clusters.Data {
+		projectName := projectNames[cluster.Data.Project.Id]
+		if !strings.HasPrefix(cluster.Data.Name, "cbdc2_") && !strings.HasPrefix(projectName, "cbdc2_") {
+			liveProjects[cluster.Data.Project.Id] = true
+			continue
+		}
+
+		meta := parseResourceMeta(projectName, cluster.Data.Description)
+		expired, expiry, reason := isExpired(meta, cluster.Data.CreatedAt)
+		if !expired {
+			liveProjects[cluster.Data.Project.Id] = true
+			continue
+		}
+
+		out = append(out, &JanitorResource{
+			Type:       JanitorResourceCluster,
+			ProjectID:  cluster.Data.Project.Id,
+			ResourceID: cluster.Data.Id,
+			Name:       cluster.Data.Name,
+			CreatedBy:  cluster.Data.CreatedBy,
+			CreatedAt:  cluster.Data.CreatedAt,
+			Expiry:     expiry,
+			Reason:     reason,
+		})
+	}
+
+	for _, columnar := range columnars.Data {
+		projectName := projectNames[columnar.Data.ProjectID]
+		if !strings.HasPrefix(columnar.Data.Name, "cbdc2_") && !strings.HasPrefix(projectName, "cbdc2_") {
+			liveProjects[columnar.Data.ProjectID] = true
+			continue
+		}
+
+		createdAt, _ := time.Parse(time.RFC3339, columnar.Data.CreatedAt)
+
+		meta := parseResourceMeta(projectName, columnar.Data.Description)
+		expired, expiry, reason := isExpired(meta, createdAt)
+		if !expired {
+			liveProjects[columnar.Data.ProjectID] = true
+			continue
+		}
+
+		out = append(out, &JanitorResource{
+			Type:       JanitorResourceColumnar,
+			ProjectID:  columnar.Data.ProjectID,
+			ResourceID: columnar.Data.ID,
+			Name:       columnar.Data.Name,
+			CreatedBy:  columnar.Data.CreatedByUser,
+			CreatedAt:  createdAt,
+			Expiry:     expiry,
+			Reason:     reason,
+		})
+	}
+
+	for _, project := range projects.Data {
+		if !strings.HasPrefix(project.Data.Name, "cbdc2_") {
+			continue
+		}
+
+		// projects are only removed once nothing live remains inside them
+		if liveProjects[project.Data.ID] {
+			continue
+		}
+
+		meta := parseResourceMeta(project.Data.Name)
+		expired, expiry, reason := isExpired(meta, project.Data.CreatedAt)
+		if !expired {
+			continue
+		}
+
+		out = append(out, &JanitorResource{
+			Type:       JanitorResourceProject,
+			ProjectID:  project.Data.ID,
+			ResourceID: project.Data.ID,
+			Name:       project.Data.Name,
+			CreatedBy:  project.Data.CreatedByUsername,
+			CreatedAt:  project.Data.CreatedAt,
+			Expiry:     expiry,
+			Reason:     reason,
+		})
+	}
+
+	return out, nil
+}
+
+// RunJanitor removes all the resources identified by FindJanitorResources,
+// returning the list of resources which were (or in dry-run mode, would
+// have been) removed.  Each resource is also recorded to the cleanup report
+// of ctx as it is removed.
+func (p *Deployer) RunJanitor(ctx context.Context, opts *JanitorOptions) ([]*JanitorResource, error) {
+	resources, err := p.FindJanitorResources(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.dryRun {
+		for _, resource := range resources {
+			p.logger.Info("dry-run: would delete "+string(resource.Type),
+				zap.String("project-id", resource.ProjectID),
+				zap.String("resource-id", resource.ResourceID),
+				zap.String("name", resource.Name))
+			deployment.RecordCleanupAction(ctx, resource.cleanupAction(true, nil))
+		}
+		return resources, nil
+	}
+
+	for _, resource := range resources {
+		switch resource.Type {
+		case JanitorResourceCluster:
+			p.logger.Info("removing a cluster", zap.String("cluster-id", resource.ResourceID))
+
+			err := p.client.DeleteCluster(ctx, p.tenantID, resource.ProjectID, resource.ResourceID)
+			if err != nil {
+				deployment.RecordCleanupAction(ctx, resource.cleanupAction(false, err))
+				return nil, errors.Wrap(err, "failed to remove cluster")
+			}
+		case JanitorResourceColumnar:
+			p.logger.Info("removing a columnar", zap.String("columnar-id", resource.ResourceID))
+
+			err := p.client.DeleteColumnar(ctx, p.tenantID, resource.ProjectID, resource.ResourceID)
+			if err != nil {
+				deployment.RecordCleanupAction(ctx, resource.cleanupAction(false, err))
+				return nil, errors.Wrap(err, "failed to remove columnar")
+			}
+		}
+	}
+
+	for _, resource := range resources {
+		switch resource.Type {
+		case JanitorResourceCluster, JanitorResourceColumnar:
+			p.logger.Info("waiting for removal to complete", zap.String("resource-id", resource.ResourceID))
+
+			isColumnar := resource.Type == JanitorResourceColumnar
+			err := p.mgr.WaitForClusterState(ctx, p.tenantID, resource.ResourceID, "", isColumnar)
+			deployment.RecordCleanupAction(ctx, resource.cleanupAction(false, err))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to wait for removal to finish")
+			}
+		}
+	}
+
+	for _, resource := range resources {
+		if resource.Type != JanitorResourceProject {
+			continue
+		}
+
+		p.logger.Info("removing a project", zap.String("project-id", resource.ResourceID))
+
+		err := p.client.DeleteProject(ctx, p.tenantID, resource.ResourceID)
+		deployment.RecordCleanupAction(ctx, resource.cleanupAction(false, err))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to remove project")
+		}
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,142 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type NodeMaintenanceAction string
+
+const (
+	NodeMaintenanceReplace NodeMaintenanceAction = "replace"
+	NodeMaintenanceRepair  NodeMaintenanceAction = "repair"
+)
+
+// how long to wait for a triggered job to appear in the jobs list
+const nodeMaintenanceJobStartTimeout = 2 * time.Minute
+
+// StartNodeMaintenance triggers a Capella-side replace or repair job for a
+// node of the cluster, identified by its hostname.  This is useful to test
+// how SDKs behave while Capella replaces nodes underneath them.
+func (p *Deployer) StartNodeMaintenance(
+	ctx context.Context,
+	clusterID string,
+	hostname string,
+	action NodeMaintenanceAction,
+) error {
+	cluster, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	if cluster.Columnar != nil {
+		return errors.New("node maintenance is not supported for columnar clusters")
+	}
+
+	if p.internalSupportToken == "" {
+		return errors.New("node maintenance requires an internal support token")
+	}
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would start node maintenance job",
+			zap.String("cluster-id", cluster.Cluster.Id),
+			zap.String("hostname", hostname),
+			zap.String("action", string(action)))
+		return nil
+	}
+
+	req := &capellacontrol.NodeMaintenanceRequest{
+		HostName: hostname,
+	}
+
+	switch action {
+	case NodeMaintenanceReplace:
+		err = p.mgr.Client.ReplaceClusterNode(ctx, cluster.Cluster.Id, p.internalSupportToken, req)
+	case NodeMaintenanceRepair:
+		err = p.mgr.Client.RepairClusterNode(ctx, cluster.Cluster.Id, p.internalSupportToken, req)
+	default:
+		return fmt.Errorf("unsupported node maintenance action `%s`", action)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to start node %s job", action)
+	}
+
+	return nil
+}
+
+// ListClusterJobs returns the jobs Capella is running, or has recently run,
+// against the cluster.
+func (p *Deployer) ListClusterJobs(ctx context.Context, clusterID string) ([]*capellacontrol.ClusterJobInfo, error) {
+	cluster, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Columnar != nil {
+		return nil, errors.New("listing jobs is not supported for columnar clusters")
+	}
+
+	resp, err := p.client.ListClusterJobs(ctx, p.tenantID, cluster.Project.ID, cluster.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list cluster jobs")
+	}
+
+	var jobs []*capellacontrol.ClusterJobInfo
+	for _, job := range resp.Data {
+		jobs = append(jobs, job.Data)
+	}
+
+	return jobs, nil
+}
+
+// PopulateActiveJobs lists the incomplete Capella jobs of a cluster into its
+// ActiveJobs, which are then reflected by its state.
+func (p *Deployer) PopulateActiveJobs(ctx context.Context, cluster *ClusterInfo) error {
+	if cluster.Type != deployment.ClusterTypeServer || cluster.CloudClusterID == "" {
+		return nil
+	}
+
+	resp, err := p.client.ListClusterJobs(ctx, p.tenantID, cluster.CloudProjectID, cluster.CloudClusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list cluster jobs")
+	}
+
+	cluster.ActiveJobs = nil
+	for _, job := range resp.Data {
+		if job.Data.CompletionPercentage < 100 {
+			cluster.ActiveJobs = append(cluster.ActiveJobs, job.Data)
+		}
+	}
+
+	return nil
+}
+
+// WaitForClusterJobs waits for all running jobs of the cluster to complete
+// and for the cluster to return to a healthy state.
+func (p *Deployer) WaitForClusterJobs(ctx context.Context, clusterID string) error {
+	cluster, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	if cluster.Columnar != nil {
+		return errors.New("waiting for jobs is not supported for columnar clusters")
+	}
+
+	err = p.mgr.WaitForClusterJobs(ctx, p.tenantID, cluster.Project.ID, cluster.Cluster.Id, nodeMaintenanceJobStartTimeout)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for cluster jobs")
+	}
+
+	p.logger.Debug("waiting for cluster to be healthy")
+
+	err = p.mgr.WaitForClusterState(ctx, p.tenantID, cluster.Cluster.Id, "healthy", false)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for cluster to be healthy")
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// NetworkPeerInfo describes a VPC peering between a cluster and another
+// network, which is an alternative to private endpoints for private
+// connectivity to a cluster.
+type NetworkPeerInfo struct {
+	ID           string
+	Name         string
+	ProviderType string
+	Cidr         string
+	Network      string
+	State        string
+	Reasoning    string
+	ProviderID   string
+	HostedZoneID string
+	CreatedAt    time.Time
+}
+
+type CreateNetworkPeerOptions struct {
+	Name string
+
+	// Cidr is the CIDR of the peered network, which must not overlap with
+	// the CIDR of the cluster.
+	Cidr string
+
+	// AccountID, VpcID and Region identify an AWS VPC, with the region
+	// defaulting to the region of the cluster.
+	AccountID string
+	VpcID     string
+	Region    string
+
+	// ProjectID, NetworkName and ServiceAccount identify a GCP network.
+	ProjectID      string
+	NetworkName    string
+	ServiceAccount string
+}
+
+// NetworkPeerRouteInfo is what is needed to finish setting up a peering
+// from the peered network.
+type NetworkPeerRouteInfo struct {
+	ProviderID   string
+	HostedZoneID string
+	Command      string
+}
+
+func networkPeerProviderType(cluster *capellacontrol.ClusterInfo) (string, error) {
+	switch cluster.Provider.Name {
+	case "hostedAWS", "aws":
+		return "aws", nil
+	case "gcp":
+		return "gcp", nil
+	}
+	return "", fmt.Errorf("network peering is not supported for `%s` clusters", cluster.Provider.Name)
+}
+
+func networkPeerInfoFromPeer(peer *capellacontrol.NetworkPeerInfo) *NetworkPeerInfo {
+	network := peer.ProviderConfig.VpcID
+	if network == "" {
+		network = peer.ProviderConfig.NetworkName
+	}
+
+	return &NetworkPeerInfo{
+		ID:           peer.ID,
+		Name:         peer.Name,
+		ProviderType: peer.ProviderType,
+		Cidr:         peer.ProviderConfig.Cidr,
+		Network:      network,
+		State:        peer.Status.State,
+		Reasoning:    peer.Status.Reasoning,
+		ProviderID:   peer.ProviderConfig.ProviderID,
+		HostedZoneID: peer.ProviderConfig.HostedZoneID,
+		CreatedAt:    peer.CreatedAt,
+	}
+}
+
+func (p *Deployer) ListNetworkPeers(ctx context.Context, clusterID string) ([]*NetworkPeerInfo, error) {
+	clusterInfo, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := p.client.ListNetworkPeers(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network peers")
+	}
+
+	var out []*NetworkPeerInfo
+	for _, peer := range peers.Data {
+		out = append(out, networkPeerInfoFromPeer(peer))
+	}
+
+	return out, nil
+}
+
+func (p *Deployer) findNetworkPeer(ctx context.Context, clusterInfo *clusterInfo, peerIDOrName string) (*capellacontrol.NetworkPeerInfo, error) {
+	peers, err := p.client.ListNetworkPeers(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network peers")
+	}
+
+	for _, peer := range peers.Data {
+		if peer.ID == peerIDOrName {
+			return peer, nil
+		}
+	}
+
+	var foundPeer *capellacontrol.NetworkPeerInfo
+	for _, peer := range peers.Data {
+		if peer.Name == peerIDOrName {
+			if foundPeer != nil {
+				return nil, fmt.Errorf("multiple network peers are named `%s`, use its id instead", peerIDOrName)
+			}
+			foundPeer = peer
+		}
+	}
+	if foundPeer == nil {
+		return nil, fmt.Errorf("failed to find network peer `%s`", peerIDOrName)
+	}
+
+	return foundPeer, nil
+}
+
+// CreateNetworkPeer requests a peering between a cluster and another network
+// and waits for the peering connection to be requested of that network.  The
+// peering must then be accepted from the peered network, see
+// GetNetworkPeerRouteInfo.
+func (p *Deployer) CreateNetworkPeer(ctx context.Context, clusterID string, opts *CreateNetworkPeerOptions) (*NetworkPeerInfo, error) {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "CreateNetworkPeer")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if opts.Name == "" {
+		return nil, errors.New("a network peer name must be specified")
+	}
+	if opts.Cidr == "" {
+		return nil, errors.New("the cidr of the peered network must be specified")
+	}
+
+	clusterInfo, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	providerType, err := networkPeerProviderType(clusterInfo.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	providerConfig := capellacontrol.NetworkPeerProviderConfig{
+		Cidr: opts.Cidr,
+	}
+	if providerType == "aws" {
+		if opts.AccountID == "" || opts.VpcID == "" {
+			return nil, errors.New("an aws account id and vpc id must be specified")
+		}
+
+		region := opts.Region
+		if region == "" {
+			region = clusterInfo.Cluster.Provider.Region
+		}
+
+		providerConfig.AccountID = opts.AccountID
+		providerConfig.VpcID = opts.VpcID
+		providerConfig.Region = region
+	} else {
+		if opts.ProjectID == "" || opts.NetworkName == "" || opts.ServiceAccount == "" {
+			return nil, errors.New("a gcp project id, network name and service account must be specified")
+		}
+
+		providerConfig.ProjectID = opts.ProjectID
+		providerConfig.NetworkName = opts.NetworkName
+		providerConfig.ServiceAccount = opts.ServiceAccount
+	}
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would create network peer",
+			zap.String("cluster", clusterID),
+			zap.String("name", opts.Name),
+			zap.Any("config", providerConfig))
+		return &NetworkPeerInfo{
+			Name:         opts.Name,
+			ProviderType: providerType,
+			Cidr:         opts.Cidr,
+		}, nil
+	}
+
+	resp, err := p.client.CreateNetworkPeer(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id,
+		&capellacontrol.CreateNetworkPeerRequest{
+			Name:           opts.Name,
+			ProviderType:   providerType,
+			ProviderConfig: providerConfig,
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create network peer")
+	}
+
+	peer, err := p.mgr.WaitForNetworkPeerRequested(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id, resp.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for network peering request")
+	}
+
+	return networkPeerInfoFromPeer(peer), nil
+}
+
+// GetNetworkPeerRouteInfo returns the peering connection and the commands
+// which accept the peering from the peered network and route to the cluster.
+func (p *Deployer) GetNetworkPeerRouteInfo(ctx context.Context, clusterID string, peerIDOrName string) (*NetworkPeerRouteInfo, error) {
+	clusterInfo, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	peer, err := p.findNetworkPeer(ctx, clusterInfo, peerIDOrName)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.GenNetworkPeerCommand(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id,
+		&capellacontrol.NetworkPeerCommandRequest{
+			ProviderType:           peer.ProviderType,
+			AccountID:              peer.ProviderConfig.AccountID,
+			VpcID:                  peer.ProviderConfig.VpcID,
+			Region:                 peer.ProviderConfig.Region,
+			Cidr:                   peer.ProviderConfig.Cidr,
+			VpcPeeringConnectionID: peer.ProviderConfig.ProviderID,
+			ProjectID:              peer.ProviderConfig.ProjectID,
+			NetworkName:            peer.ProviderConfig.NetworkName,
+			ServiceAccount:         peer.ProviderConfig.ServiceAccount,
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate network peer commands")
+	}
+
+	return &NetworkPeerRouteInfo{
+		ProviderID:   peer.ProviderConfig.ProviderID,
+		HostedZoneID: peer.ProviderConfig.HostedZoneID,
+		Command:      resp.Data.Command,
+	}, nil
+}
+
+func (p *Deployer) DeleteNetworkPeer(ctx context.Context, clusterID string, peerIDOrName string) error {
+	ctx, unlock, err := p.lockCluster(ctx, clusterID, "DeleteNetworkPeer")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clusterInfo, err := p.getCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	peer, err := p.findNetworkPeer(ctx, clusterInfo, peerIDOrName)
+	if err != nil {
+		return err
+	}
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would delete network peer",
+			zap.String("cluster", clusterID),
+			zap.String("peer-id", peer.ID))
+		return nil
+	}
+
+	err = p.client.DeleteNetworkPeer(ctx, p.tenantID, clusterInfo.Cluster.Project.Id, clusterInfo.Cluster.Id, peer.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete network peer")
+	}
+
+	return nil
+}
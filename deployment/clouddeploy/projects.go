@@ -0,0 +1,151 @@
+package clouddeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/stringclustermeta"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ProjectInfo describes a project of the Capella tenant.  Every allocated
+// cluster gets its own project which is deleted along with the cluster, for
+// these ephemeral projects ClusterID is the id of the owning cluster.
+type ProjectInfo struct {
+	ID           string
+	Name         string
+	ClusterCount int
+	CreatedAt    time.Time
+	ClusterID    string
+}
+
+func (p *Deployer) listProjects(ctx context.Context) ([]*capellacontrol.ProjectInfo, error) {
+	resp, err := p.client.ListProjects(ctx, p.tenantID, &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       1000,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list projects")
+	}
+
+	var projects []*capellacontrol.ProjectInfo
+	for _, project := range resp.Data {
+		projects = append(projects, project.Data)
+	}
+
+	return projects, nil
+}
+
+func (p *Deployer) ListProjects(ctx context.Context) ([]*ProjectInfo, error) {
+	projects, err := p.listProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*ProjectInfo
+	for _, project := range projects {
+		projectInfo := &ProjectInfo{
+			ID:           project.ID,
+			Name:         project.Name,
+			ClusterCount: project.ClusterCount,
+			CreatedAt:    project.CreatedAt,
+		}
+
+		// projects which fail to parse are simply not ours
+		meta, _ := stringclustermeta.Parse(project.Name)
+		if meta != nil {
+			projectInfo.ClusterID = meta.ID.String()
+		}
+
+		out = append(out, projectInfo)
+	}
+
+	return out, nil
+}
+
+// CreateProject creates a project which is not associated with any cluster,
+// and so is left alone by cluster removal and the janitor.
+func (p *Deployer) CreateProject(ctx context.Context, name string) (*ProjectInfo, error) {
+	if name == "" {
+		return nil, errors.New("a project name must be specified")
+	}
+
+	if meta, _ := stringclustermeta.Parse(name); meta != nil {
+		return nil, fmt.Errorf("project name `%s` is reserved for cluster projects", name)
+	}
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would create project", zap.String("name", name))
+		return &ProjectInfo{Name: name}, nil
+	}
+
+	resp, err := p.client.CreateProject(ctx, p.tenantID, &capellacontrol.CreateProjectRequest{
+		Name: name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(wrapQuotaError(err), "failed to create project")
+	}
+
+	return &ProjectInfo{
+		ID:   resp.Id,
+		Name: name,
+	}, nil
+}
+
+// DeleteProject deletes a project by its id, or otherwise by its exact
+// name.  Projects which still belong to a cluster must be removed along
+// with their cluster instead.
+func (p *Deployer) DeleteProject(ctx context.Context, projectIDOrName string) error {
+	projects, err := p.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	var foundProject *ProjectInfo
+	for _, project := range projects {
+		if project.ID == projectIDOrName {
+			foundProject = project
+		}
+	}
+	if foundProject == nil {
+		for _, project := range projects {
+			if project.Name == projectIDOrName {
+				if foundProject != nil {
+					return fmt.Errorf("multiple projects are named `%s`, use its id instead", projectIDOrName)
+				}
+				foundProject = project
+			}
+		}
+	}
+	if foundProject == nil {
+		return fmt.Errorf("failed to find project `%s`", projectIDOrName)
+	}
+
+	if foundProject.ClusterID != "" && foundProject.ClusterCount > 0 {
+		return fmt.Errorf("project `%s` belongs to cluster `%s`, remove the cluster instead",
+			foundProject.ID, foundProject.ClusterID)
+	}
+
+	if p.dryRun {
+		p.logger.Info("dry-run: would delete project",
+			zap.String("project-id", foundProject.ID),
+			zap.String("name", foundProject.Name))
+		return nil
+	}
+
+	p.logger.Debug("deleting project",
+		zap.String("project-id", foundProject.ID),
+		zap.String("name", foundProject.Name))
+
+	err = p.client.DeleteProject(ctx, p.tenantID, foundProject.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete project")
+	}
+
+	return nil
+}
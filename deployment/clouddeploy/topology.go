@@ -0,0 +1,55 @@
+package clouddeploy
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+)
+
+// ServiceGroupInfo describes a group of identical nodes of a Capella cluster,
+// the services which run on them and the memory each service is allocated on
+// every node of the group.
+type ServiceGroupInfo struct {
+	Count        int
+	Services     []clusterdef.Service
+	InstanceType string
+	Cpu          int
+	MemoryGB     int
+	DiskType     string
+	DiskSizeGB   int
+	DiskIops     int
+
+	ServiceQuotasMB map[clusterdef.Service]int
+}
+
+func clusterServiceGroups(cluster *capellacontrol.ClusterInfo) []ServiceGroupInfo {
+	var groups []ServiceGroupInfo
+	for _, spec := range cluster.Services {
+		group := ServiceGroupInfo{
+			Count:           spec.Count,
+			InstanceType:    spec.Compute.Type,
+			Cpu:             spec.Compute.Cpu,
+			MemoryGB:        spec.Compute.MemoryInGB,
+			DiskType:        spec.Disk.Type,
+			DiskSizeGB:      spec.Disk.SizeInGb,
+			DiskIops:        spec.Disk.Iops,
+			ServiceQuotasMB: make(map[clusterdef.Service]int),
+		}
+
+		for _, service := range spec.Services {
+			// services which cannot be mapped are reported using the name
+			// that Capella uses for them
+			defService, err := clusterdef.NsServiceToService(service.Type)
+			if err != nil {
+				defService = clusterdef.Service(service.Type)
+			}
+
+			group.Services = append(group.Services, defService)
+			if service.MemoryAllocationInMB > 0 {
+				group.ServiceQuotasMB[defService] = service.MemoryAllocationInMB
+			}
+		}
+
+		groups = append(groups, group)
+	}
+	return groups
+}
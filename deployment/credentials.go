@@ -0,0 +1,127 @@
+package deployment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+type ConnectCredentialsOptions struct {
+	// Buckets restricts the credentials to these buckets, an empty list
+	// grants access to every bucket.
+	Buckets []string
+
+	// ReadOnly creates credentials which cannot write data.
+	ReadOnly bool
+}
+
+const (
+	credentialsPasswordLength = 24
+
+	// Capella requires passwords to contain each of these classes of
+	// characters, so we always include at least one of each.
+	passwordLowerChars   = "abcdefghijkmnopqrstuvwxyz"
+	passwordUpperChars   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordDigitChars   = "23456789"
+	passwordSpecialChars = "#%+-.:=@_"
+)
+
+func randomChar(chars string) (byte, error) {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[idx.Int64()], nil
+}
+
+// generateCredentialsPassword generates a random password which satisfies
+// the password policies of both Couchbase Server and Capella.
+func generateCredentialsPassword() (string, error) {
+	classes := []string{
+		passwordLowerChars,
+		passwordUpperChars,
+		passwordDigitChars,
+		passwordSpecialChars,
+	}
+	allChars := passwordLowerChars + passwordUpperChars + passwordDigitChars + passwordSpecialChars
+
+	password := make([]byte, credentialsPasswordLength)
+	for charIdx := range password {
+		chars := allChars
+		if charIdx < len(classes) {
+			chars = classes[charIdx]
+		}
+
+		char, err := randomChar(chars)
+		if err != nil {
+			return "", err
+		}
+		password[charIdx] = char
+	}
+
+	// shuffle so the guaranteed classes are not always at the start
+	for charIdx := len(password) - 1; charIdx > 0; charIdx-- {
+		swapIdx, err := rand.Int(rand.Reader, big.NewInt(int64(charIdx+1)))
+		if err != nil {
+			return "", err
+		}
+		password[charIdx], password[swapIdx.Int64()] = password[swapIdx.Int64()], password[charIdx]
+	}
+
+	return string(password), nil
+}
+
+func generateCredentialsUsername() (string, error) {
+	idBytes := make([]byte, 6)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return "cbdc-" + hex.EncodeToString(idBytes), nil
+}
+
+// GetConnectInfoWithCredentials gets the connect info of a cluster along
+// with a newly created user with a random password, so that each consumer
+// of a cluster can use its own isolated credentials.  The user lives within
+// the cluster, and so goes away along with it.
+func GetConnectInfoWithCredentials(
+	ctx context.Context,
+	deployer Deployer,
+	clusterID string,
+	opts *ConnectCredentialsOptions,
+) (*ConnectInfo, error) {
+	connectInfo, err := deployer.GetConnectInfo(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := generateCredentialsUsername()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate username")
+	}
+
+	password, err := generateCredentialsPassword()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate password")
+	}
+
+	err = deployer.CreateUser(ctx, clusterID, &CreateUserOptions{
+		Username: username,
+		Password: password,
+		CanRead:  true,
+		CanWrite: !opts.ReadOnly,
+		Buckets:  opts.Buckets,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create credentials")
+	}
+
+	connectInfo.Username = username
+	connectInfo.Password = password
+
+	return connectInfo, nil
+}
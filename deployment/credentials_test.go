@@ -0,0 +1,27 @@
+package deployment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCredentialsPassword(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		password, err := generateCredentialsPassword()
+		require.NoError(t, err)
+		require.Len(t, password, credentialsPasswordLength)
+
+		for _, chars := range []string{
+			passwordLowerChars,
+			passwordUpperChars,
+			passwordDigitChars,
+			passwordSpecialChars,
+		} {
+			assert.True(t, strings.ContainsAny(password, chars),
+				"password %s is missing one of %s", password, chars)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package deployment
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
 )
 
 type ClusterType string
@@ -21,6 +24,15 @@ type ClusterNodeInfo interface {
 	GetResourceID() string
 	GetName() string
 	GetIPAddress() string
+
+	// GetOTPNode and GetNodeUUID return the identity of the node within the
+	// cluster, which are empty when they are not known.
+	GetOTPNode() string
+	GetNodeUUID() string
+
+	// GetNodeGroup returns the name of the node group the node was deployed
+	// from, which is empty when the group was unnamed.
+	GetNodeGroup() string
 }
 
 type ClusterInfo interface {
@@ -38,6 +50,16 @@ type ConnectInfo struct {
 	ConnStrCb2 string
 	Mgmt       string
 	MgmtTls    string
+
+	// SyncGateway and SyncGatewayAdmin are the websocket endpoint of the
+	// Sync Gateway database and its admin endpoint, if one is deployed.
+	SyncGateway      string
+	SyncGatewayAdmin string
+
+	// Username and Password are only populated when dedicated credentials
+	// were requested through GetConnectInfoWithCredentials.
+	Username string
+	Password string
 }
 
 type UserInfo struct {
@@ -51,6 +73,10 @@ type CreateUserOptions struct {
 	Password string
 	CanRead  bool
 	CanWrite bool
+
+	// Buckets restricts the data access of the user to these buckets rather
+	// than granting it on every bucket.
+	Buckets []string
 }
 
 type BucketInfo struct {
@@ -62,15 +88,131 @@ type CreateBucketOptions struct {
 	RamQuotaMB   int
 	FlushEnabled bool
 	NumReplicas  int
+
+	// BucketType is couchbase, ephemeral or memcached, defaulting to couchbase.
+	BucketType BucketType
+
+	// EvictionPolicy is valueOnly or fullEviction for couchbase buckets and
+	// noEviction or nruEviction for ephemeral buckets, defaulting to valueOnly
+	// and noEviction respectively.
+	EvictionPolicy string
+
+	// StorageBackend is either couchstore or magma, defaulting to couchstore.
+	StorageBackend string
+
+	// HistoryRetentionBytes and HistoryRetentionDuration bound the change
+	// history retained for collections with history enabled, which needs
+	// the magma storage backend.  Zero uses the server defaults.
+	HistoryRetentionBytes    int
+	HistoryRetentionDuration time.Duration
+}
+
+type CreateCollectionOptions struct {
+	// MaxTTL is the maximum expiry of documents in the collection.  Zero
+	// uses the expiry of the bucket, and a negative value disables expiry.
+	MaxTTL time.Duration
+
+	// History specifies whether the change history of the collection is
+	// retained, or uses the bucket default if unset.
+	History *bool
+}
+
+// MaxTTLSeconds converts a collection max TTL into the seconds used by the
+// server, where -1 disables expiry.
+func MaxTTLSeconds(maxTTL time.Duration) int {
+	if maxTTL < 0 {
+		return -1
+	}
+	return int(maxTTL / time.Second)
+}
+
+// UpdateCollectionOptions specifies the collection settings to change, with
+// unset fields left unchanged.
+type UpdateCollectionOptions struct {
+	MaxTTL  *time.Duration
+	History *bool
+}
+
+// CheckUpdateCollectionOptions checks that there is at least one collection
+// setting to update.
+func CheckUpdateCollectionOptions(opts *UpdateCollectionOptions) error {
+	if opts == nil || (opts.MaxTTL == nil && opts.History == nil) {
+		return errors.New("no collection settings to update were specified")
+	}
+	return nil
+}
+
+// CollectionManifest describes the scopes and collections of a bucket, along
+// with the uid of the manifest, which increases with every change to them.
+type CollectionManifest struct {
+	UID    uint64
+	Scopes []ScopeInfo
 }
 
 type ScopeInfo struct {
+	UID         uint64
 	Name        string
 	Collections []CollectionInfo
 }
 
 type CollectionInfo struct {
+	UID  uint64
 	Name string
+
+	// MaxTTL is zero when the bucket max TTL applies, and negative when
+	// documents in the collection never expire.
+	MaxTTL  time.Duration
+	History bool
+}
+
+// ParseManifestUID parses a uid from a collection manifest, which the server
+// reports as a hexadecimal string.
+func ParseManifestUID(uid string) (uint64, error) {
+	parsedUid, err := strconv.ParseUint(uid, 16, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid manifest uid `%s`", uid)
+	}
+	return parsedUid, nil
+}
+
+// ParseCollectionManifest converts a collection manifest as reported by the
+// server, which Capella proxies unchanged, into a CollectionManifest.
+func ParseCollectionManifest(manifestJson *clustercontrol.CollectionManifestJson) (*CollectionManifest, error) {
+	manifestUid, err := ParseManifestUID(manifestJson.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &CollectionManifest{
+		UID: manifestUid,
+	}
+	for _, scopeJson := range manifestJson.Scopes {
+		scopeUid, err := ParseManifestUID(scopeJson.UID)
+		if err != nil {
+			return nil, err
+		}
+
+		scope := ScopeInfo{
+			UID:  scopeUid,
+			Name: scopeJson.Name,
+		}
+		for _, collectionJson := range scopeJson.Collections {
+			collectionUid, err := ParseManifestUID(collectionJson.UID)
+			if err != nil {
+				return nil, err
+			}
+
+			scope.Collections = append(scope.Collections, CollectionInfo{
+				UID:     collectionUid,
+				Name:    collectionJson.Name,
+				MaxTTL:  time.Duration(collectionJson.MaxTTL) * time.Second,
+				History: collectionJson.History,
+			})
+		}
+		manifest.Scopes = append(manifest.Scopes, scope)
+	}
+
+	return manifest, nil
 }
 
 type Image struct {
@@ -89,12 +231,15 @@ const (
 
 type Deployer interface {
 	ListClusters(ctx context.Context) ([]ClusterInfo, error)
+	WatchClusters(ctx context.Context) (<-chan ClusterEvent, error)
 	NewCluster(ctx context.Context, def *clusterdef.Cluster) (ClusterInfo, error)
+	ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]DefinitionViolation, error)
 	GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error)
 	UpdateClusterExpiry(ctx context.Context, clusterID string, newExpiryTime time.Time) error
 	ModifyCluster(ctx context.Context, clusterID string, def *clusterdef.Cluster) error
 	AddNode(ctx context.Context, clusterID string) (string, error)
 	RemoveNode(ctx context.Context, clusterID string, nodeID string) error
+	SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error)
 	RemoveCluster(ctx context.Context, clusterID string) error
 	RemoveAll(ctx context.Context) error
 	Cleanup(ctx context.Context) error
@@ -110,10 +255,20 @@ type Deployer interface {
 	GetGatewayCertificate(ctx context.Context, clusterID string) (string, error)
 	ExecuteQuery(ctx context.Context, clusterID string, query string) (string, error)
 	ListCollections(ctx context.Context, clusterID string, bucketName string) ([]ScopeInfo, error)
+	GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*CollectionManifest, error)
+	WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error
 	CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error
-	CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error
+	CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *CreateCollectionOptions) error
+	UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *UpdateCollectionOptions) error
 	DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error
 	DeleteCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error
+	CreateQueryFunction(ctx context.Context, clusterID string, opts *CreateQueryFunctionOptions) error
+	DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error
+	ListJsLibraries(ctx context.Context, clusterID string) ([]JsLibraryInfo, error)
+	CreateJsLibrary(ctx context.Context, clusterID string, opts *CreateJsLibraryOptions) error
+	DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error
+	ListIndexes(ctx context.Context, clusterID string) ([]IndexInfo, error)
+	AdviseIndexes(ctx context.Context, clusterID string, statement string) (*IndexAdvice, error)
 	BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType BlockNodeTrafficType) error
 	AllowNodeTraffic(ctx context.Context, clusterID string, nodeID string) error
 	CollectLogs(ctx context.Context, clusterID string, destPath string) ([]string, error)
@@ -121,6 +276,8 @@ type Deployer interface {
 	SearchImages(ctx context.Context, version string) ([]Image, error)
 	PauseNode(ctx context.Context, clusterID string, nodeID string) error
 	UnpauseNode(ctx context.Context, clusterID string, nodeID string) error
+	SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error
+	GetNodeVersions(ctx context.Context, clusterID string) ([]NodeVersionInfo, error)
 	RedeployCluster(ctx context.Context, clusterID string) error
 	CreateCapellaLink(ctx context.Context, columnarID, linkName, clusterId, directID string) error
 	CreateS3Link(ctx context.Context, columnarID, linkName, region, endpoint, accessKey, secretKey string) error
@@ -0,0 +1,79 @@
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// DiagnosticsBundle is a directory into which deployers capture information
+// about a failed deployment for later postmortem.  Capturing is best-effort,
+// failures to write individual files are logged rather than returned so that
+// as much evidence as possible is kept.
+type DiagnosticsBundle struct {
+	Logger *zap.Logger
+	Path   string
+}
+
+// NewDiagnosticsBundle creates a new timestamped bundle directory for a
+// cluster within the specified base path.
+func NewDiagnosticsBundle(logger *zap.Logger, basePath string, clusterID string) (*DiagnosticsBundle, error) {
+	dirName := fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), clusterID)
+	bundlePath := filepath.Join(basePath, dirName)
+
+	err := os.MkdirAll(bundlePath, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create diagnostics directory")
+	}
+
+	return &DiagnosticsBundle{
+		Logger: logger,
+		Path:   bundlePath,
+	}, nil
+}
+
+func (b *DiagnosticsBundle) WriteFile(name string, data []byte) {
+	filePath := filepath.Join(b.Path, name)
+
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err == nil {
+		err = os.WriteFile(filePath, data, 0644)
+	}
+	if err != nil {
+		b.Logger.Warn("failed to write diagnostics file",
+			zap.String("path", filePath),
+			zap.Error(err))
+	}
+}
+
+func (b *DiagnosticsBundle) WriteJson(name string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		b.WriteError(name, err)
+		return
+	}
+
+	b.WriteFile(name, data)
+}
+
+func (b *DiagnosticsBundle) WriteYaml(name string, value interface{}) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		b.WriteError(name, err)
+		return
+	}
+
+	b.WriteFile(name, data)
+}
+
+// WriteError records an error, including its stack where available, in
+// place of a file which could not be captured.
+func (b *DiagnosticsBundle) WriteError(name string, err error) {
+	b.WriteFile(name+".error", []byte(fmt.Sprintf("%+v\n", err)))
+}
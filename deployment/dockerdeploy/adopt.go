@@ -0,0 +1,82 @@
+package dockerdeploy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type AdoptClusterOptions struct {
+	// Identifier is a cluster ID, or the ID or name of one of its containers.
+	Identifier string
+	Expiry     time.Time
+}
+
+// AdoptCluster takes over a cluster whose containers were created by the
+// original cbdyncluster, which labelled them the same way we do but did not
+// always store node state.  The nodes are given an expiry, so that they are
+// removed by Cleanup like any other node.
+func (d *Deployer) AdoptCluster(ctx context.Context, opts *AdoptClusterOptions) (deployment.ClusterInfo, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	clusterID := ""
+	for _, node := range nodes {
+		if node.ClusterID != opts.Identifier &&
+			!strings.HasPrefix(node.ContainerID, opts.Identifier) &&
+			node.Name != opts.Identifier {
+			continue
+		}
+
+		if clusterID != "" && clusterID != node.ClusterID {
+			return nil, errors.New("identifier matches nodes of more than one cluster")
+		}
+		clusterID = node.ClusterID
+	}
+	if clusterID == "" {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "AdoptCluster")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+
+		if d.dryRun {
+			d.logger.Info("dry-run: would set node expiry to adopt it",
+				zap.String("id", node.NodeID),
+				zap.String("container", node.ContainerID),
+				zap.Time("expiry", opts.Expiry))
+			continue
+		}
+
+		d.logger.Info("setting node expiry to adopt it",
+			zap.String("id", node.NodeID),
+			zap.String("container", node.ContainerID),
+			zap.Time("expiry", opts.Expiry))
+
+		// nodes without node state cannot be updated, so the state is
+		// written in full rather than using UpdateExpiry
+		err := d.getHost(node.HostName).Controller.WriteNodeState(ctx, node.ContainerID, &DockerNodeState{
+			Expiry: opts.Expiry,
+			Labels: node.StateLabels,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write node state")
+		}
+	}
+
+	return d.getCluster(ctx, clusterID)
+}
@@ -0,0 +1,151 @@
+package dockerdeploy
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type overriddenAlternateAddresses struct {
+	endpoint string
+	previous *clustercontrol.SetupAlternateAddressesOptions
+}
+
+// OverrideAlternateAddresses temporarily replaces the external alternate
+// addresses of the nodes of a cluster, keyed by node id.  SDKs using the
+// external network expect every node to have one, so any other nodes which
+// have none are given the address they are reachable on from this machine.
+// The returned function restores the alternate addresses the nodes had
+// before, which includes those set up for an advertise address or client
+// network.
+func (d *Deployer) OverrideAlternateAddresses(
+	ctx context.Context,
+	clusterID string,
+	nodeAddrs map[string]*clustercontrol.SetupAlternateAddressesOptions,
+) (func(ctx context.Context) error, error) {
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	var overridden []*overriddenAlternateAddresses
+	restore := func(ctx context.Context) error {
+		var firstErr error
+		for _, override := range overridden {
+			nodeCtrl := clustercontrol.NodeManager{
+				Endpoint: override.endpoint,
+			}
+
+			var err error
+			if override.previous == nil {
+				err = nodeCtrl.Controller().RemoveAlternateAddresses(ctx)
+			} else {
+				err = nodeCtrl.Controller().SetupAlternateAddresses(ctx, override.previous)
+			}
+			if err != nil {
+				d.logger.Warn("failed to restore alternate addresses",
+					zap.String("endpoint", override.endpoint),
+					zap.Error(err))
+				if firstErr == nil {
+					firstErr = errors.Wrap(err, "failed to restore alternate addresses")
+				}
+			}
+		}
+		return firstErr
+	}
+
+	for _, node := range clusterInfo.Nodes {
+		if node.OTPNode == "" {
+			// only cluster nodes have alternate addresses
+			continue
+		}
+
+		endpoint := d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts)
+		nodeCtrl := clustercontrol.NodeManager{
+			Endpoint: endpoint,
+		}
+
+		previous, err := nodeCtrl.Controller().GetLocalAlternateAddresses(ctx)
+		if err != nil {
+			restore(ctx)
+			return nil, errors.Wrap(err, "failed to get existing alternate addresses")
+		}
+
+		addrs, ok := nodeAddrs[node.NodeID]
+		if !ok {
+			if previous != nil {
+				continue
+			}
+
+			addrs, err = d.reachableAlternateAddresses(node)
+			if err != nil {
+				restore(ctx)
+				return nil, err
+			}
+		}
+
+		err = nodeCtrl.Controller().SetupAlternateAddresses(ctx, addrs)
+		if err != nil {
+			restore(ctx)
+			return nil, errors.Wrap(err, "failed to setup alternate addresses")
+		}
+
+		overridden = append(overridden, &overriddenAlternateAddresses{
+			endpoint: endpoint,
+			previous: previous,
+		})
+	}
+
+	return restore, nil
+}
+
+func (d *Deployer) reachableAlternateAddresses(node *deployedNodeInfo) (*clustercontrol.SetupAlternateAddressesOptions, error) {
+	addrs := &clustercontrol.SetupAlternateAddressesOptions{
+		Ports: make(map[string]int),
+	}
+
+	for portName, port := range clustercontrol.AlternateAddressPorts {
+		host, portStr, err := net.SplitHostPort(
+			d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, port))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse node address")
+		}
+
+		reachablePort, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse node port")
+		}
+
+		addrs.Hostname = host
+		if reachablePort != port {
+			addrs.Ports[portName] = reachablePort
+		}
+	}
+
+	return addrs, nil
+}
+
+// NodeServiceAddresses returns the host:port that each of the service ports
+// of a node of a cluster is reachable on from this machine.
+func (d *Deployer) NodeServiceAddresses(ctx context.Context, clusterID string, nodeID string) (map[int]string, error) {
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	for _, node := range clusterInfo.Nodes {
+		if node.NodeID == nodeID {
+			addrs := make(map[int]string)
+			for _, port := range clustercontrol.AlternateAddressPorts {
+				addrs[port] = d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, port)
+			}
+			return addrs, nil
+		}
+	}
+
+	return nil, errors.New("failed to find node")
+}
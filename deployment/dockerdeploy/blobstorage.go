@@ -0,0 +1,324 @@
+package dockerdeploy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	minioPort   = 9000
+	minioNodeID = "minio"
+	minioImage  = "minio/minio"
+
+	defaultMinioAccessKey    = "minioadmin"
+	defaultMinioSecretKey    = "minioadmin"
+	defaultBlobStorageRegion = "us-east-1"
+
+	defaultColumnarBucket = "columnar"
+)
+
+type DeployMinioNodeOptions struct {
+	Purpose   string
+	ClusterID string
+	Expiry    time.Duration
+	Image     *ImageRef
+
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// DeployMinioNode deploys a MinIO container which provides S3 compatible
+// blob storage for columnar clusters, including request signing, which the
+// s3mock container does not validate.
+func (c *Controller) DeployMinioNode(ctx context.Context, opts *DeployMinioNodeOptions) (*NodeInfo, error) {
+	nodeID := minioNodeID
+	logger := c.Logger.With(zap.String("nodeId", nodeID))
+
+	logger.Debug("deploying minio node")
+
+	containerName := "cbdynnode-minio-" + opts.ClusterID
+
+	exposedPorts, portBindings := c.publishPorts([]int{minioPort})
+
+	labels := map[string]string{
+		"com.couchbase.dyncluster.cluster_id": opts.ClusterID,
+		"com.couchbase.dyncluster.type":       "minio",
+		"com.couchbase.dyncluster.purpose":    opts.Purpose,
+		"com.couchbase.dyncluster.node_id":    nodeID,
+	}
+	stateLabels := c.moveSensitiveLabels(labels)
+
+	createResult, err := c.DockerCli.ContainerCreate(ctx, &container.Config{
+		Image:        opts.Image.ImagePath,
+		Cmd:          []string{"server", "/data"},
+		ExposedPorts: exposedPorts,
+		Labels:       labels,
+		Env: []string{
+			"MINIO_ROOT_USER=" + opts.AccessKey,
+			"MINIO_ROOT_PASSWORD=" + opts.SecretKey,
+			"MINIO_SITE_REGION=" + opts.Region,
+		},
+		// same effect as ntp
+		Volumes: map[string]struct{}{"/etc/localtime:/etc/localtime": {}},
+	}, &container.HostConfig{
+		AutoRemove:   true,
+		NetworkMode:  container.NetworkMode(c.NetworkName),
+		PortBindings: portBindings,
+	}, nil, nil, containerName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create container")
+	}
+
+	containerID := createResult.ID
+
+	logger.Debug("container created, starting", zap.String("container", containerID))
+
+	err = c.DockerCli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start container")
+	}
+
+	node, err := c.finishSidecarNode(ctx, containerID, opts.Expiry, stateLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("container has started, waiting for it to get ready", zap.String("address", node.IPAddress))
+
+	healthUrl := "http://" + c.NodeAddress(node.IPAddress, node.PublishedPorts, minioPort) + "/minio/health/live"
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", healthUrl, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create minio request")
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != 200 {
+				err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(ctx.Err(), "failed to wait for minio")
+			}
+
+			logger.Debug("minio not ready yet", zap.Error(err))
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		break
+	}
+
+	logger.Debug("container is ready!")
+
+	return node, nil
+}
+
+// doS3Request sends a request to the S3 REST API, signing it when
+// credentials are provided.
+func doS3Request(ctx context.Context, method string, reqUrl string, region string, creds *aws.Credentials) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create s3 request")
+	}
+
+	if creds != nil {
+		payloadHash := sha256.Sum256(nil)
+		payloadHashStr := hex.EncodeToString(payloadHash[:])
+		req.Header.Set("X-Amz-Content-Sha256", payloadHashStr)
+
+		err = v4.NewSigner().SignHTTP(ctx, *creds, req, payloadHashStr, "s3", region, time.Now())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign s3 request")
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send s3 request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read s3 response")
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-200 status code from s3 (code: %d): %s", resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// createS3Bucket creates a bucket using the S3 REST API.
+func createS3Bucket(ctx context.Context, endpoint string, bucketName string, region string, creds *aws.Credentials) error {
+	_, err := doS3Request(ctx, http.MethodPut, endpoint+"/"+bucketName+"/", region, creds)
+	return err
+}
+
+// blobStorageEnvVars returns the environment variables which provide the
+// blob storage credentials to the analytics service of the nodes.
+func blobStorageEnvVars(settings *clusterdef.AnalyticsBlobStorageSettings) map[string]string {
+	if settings.AccessKey == "" {
+		return nil
+	}
+
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     settings.AccessKey,
+		"AWS_SECRET_ACCESS_KEY": settings.SecretKey,
+	}
+}
+
+// mergeEnvVars combines the environment variables the deployer sets for the
+// nodes with those of a node group, where the node group takes precedence.
+func mergeEnvVars(baseVars map[string]string, nodeGrpVars map[string]string) map[string]string {
+	if len(baseVars) == 0 {
+		return nodeGrpVars
+	}
+
+	envVars := make(map[string]string, len(baseVars)+len(nodeGrpVars))
+	for varName, varValue := range baseVars {
+		envVars[varName] = varValue
+	}
+	for varName, varValue := range nodeGrpVars {
+		envVars[varName] = varValue
+	}
+
+	return envVars
+}
+
+// deployColumnarBlobStorage prepares the blob storage for a new columnar
+// cluster.  When no endpoint is specified, an s3mock or MinIO container is
+// deployed alongside the cluster with the bucket already created.  This
+// returns the settings to configure analytics with, along with any
+// environment variables the nodes need to authenticate to the storage.
+func (d *Deployer) deployColumnarBlobStorage(
+	ctx context.Context,
+	host *dockerHost,
+	clusterID string,
+	def *clusterdef.Cluster,
+) (*clusterdef.AnalyticsBlobStorageSettings, map[string]string, error) {
+	settings := def.Docker.Analytics.BlobStorage
+
+	if settings.Bucket == "" {
+		settings.Bucket = defaultColumnarBucket
+	}
+	if settings.Scheme == "" {
+		settings.Scheme = "s3"
+	}
+
+	if settings.Endpoint != "" {
+		d.logger.Info("using external blob storage",
+			zap.String("endpoint", settings.Endpoint),
+			zap.String("bucket", settings.Bucket))
+
+		if settings.Region == "" {
+			settings.Region = defaultBlobStorageRegion
+		}
+
+		return &settings, blobStorageEnvVars(&settings), nil
+	}
+
+	switch settings.Provider {
+	case "", clusterdef.BlobStorageProviderS3Mock:
+		progress.Step(ctx, "deploying mock s3")
+		d.logger.Info("deploying mock s3 for blob storage")
+
+		d.logger.Debug("deploying s3mock container")
+
+		node, err := host.Controller.DeployS3MockNode(ctx, clusterID, def.Expiry)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to deploy s3mock node")
+		}
+
+		d.logger.Debug("creating columnar bucket")
+
+		err = createS3Bucket(ctx,
+			"http://"+d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, 9090),
+			settings.Bucket, "", nil)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create columnar s3 bucket")
+		}
+
+		d.logger.Info("s3 mock is ready")
+
+		if settings.Region == "" {
+			settings.Region = "local"
+		}
+		settings.Endpoint = fmt.Sprintf("http://%s:9090", node.IPAddress)
+		settings.AnonymousAuth = true
+
+		return &settings, nil, nil
+
+	case clusterdef.BlobStorageProviderMinio:
+		progress.Step(ctx, "deploying minio")
+		d.logger.Info("deploying minio for blob storage")
+
+		if settings.Region == "" {
+			settings.Region = defaultBlobStorageRegion
+		}
+		if settings.AccessKey == "" {
+			settings.AccessKey = defaultMinioAccessKey
+			settings.SecretKey = defaultMinioSecretKey
+		}
+
+		image, err := host.ImageProvider.GetImageRaw(ctx, minioImage)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get minio image")
+		}
+
+		node, err := host.Controller.DeployMinioNode(ctx, &DeployMinioNodeOptions{
+			Purpose:   def.Purpose,
+			ClusterID: clusterID,
+			Expiry:    def.Expiry,
+			Image:     image,
+			Region:    settings.Region,
+			AccessKey: settings.AccessKey,
+			SecretKey: settings.SecretKey,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to deploy minio node")
+		}
+
+		d.logger.Debug("creating columnar bucket")
+
+		err = createS3Bucket(ctx,
+			"http://"+d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, minioPort),
+			settings.Bucket, settings.Region, &aws.Credentials{
+				AccessKeyID:     settings.AccessKey,
+				SecretAccessKey: settings.SecretKey,
+			})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create columnar minio bucket")
+		}
+
+		d.logger.Info("minio is ready",
+			zap.String("accessKey", settings.AccessKey))
+
+		settings.Endpoint = fmt.Sprintf("http://%s:%d", node.IPAddress, minioPort)
+		settings.AnonymousAuth = false
+
+		return &settings, blobStorageEnvVars(&settings), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported blob storage provider `%s`", settings.Provider)
+}
@@ -0,0 +1,322 @@
+package dockerdeploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"path"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const couchbaseInboxPath = "/opt/couchbase/var/lib/couchbase/inbox"
+
+// shortLivedCAValidity is how long the CA which signs the short-lived node
+// certificates is valid for, the CA itself is not meant to expire.
+const shortLivedCAValidity = 365 * 24 * time.Hour
+
+type InstallShortLivedCertificatesOptions struct {
+	// Validity is how long the node certificates are valid for.
+	Validity time.Duration
+}
+
+type InstallShortLivedCertificatesResult struct {
+	// CaPem is the CA which signed the node certificates, which clients
+	// need to trust to connect to the cluster.
+	CaPem    string
+	NotAfter time.Time
+}
+
+// NodeCertificateStatus is the certificate presented by a node on its TLS
+// management port.
+type NodeCertificateStatus struct {
+	NodeID    string
+	Address   string
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	Expired   bool
+}
+
+func newCertSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeCertPem(certBytes []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+}
+
+// InstallShortLivedCertificates replaces the certificates of all the nodes
+// of a cluster with certificates which expire after a short time, signed by
+// a newly generated CA which is added to the trusted CAs of the cluster.
+// Once they expire, clients which verify the certificates fail to connect.
+func (d *Deployer) InstallShortLivedCertificates(
+	ctx context.Context,
+	clusterID string,
+	opts *InstallShortLivedCertificatesOptions,
+) (*InstallShortLivedCertificatesResult, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "InstallShortLivedCertificates")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if opts.Validity <= 0 {
+		return nil, errors.New("certificate validity must be positive")
+	}
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	// certificates are backdated slightly to tolerate clock differences
+	// between this machine and the nodes
+	notBefore := time.Now().Add(-1 * time.Minute)
+	notAfter := time.Now().Add(opts.Validity)
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would install short-lived certificates",
+			zap.String("cluster", clusterID),
+			zap.Time("notAfter", notAfter))
+		return &InstallShortLivedCertificatesResult{
+			NotAfter: notAfter,
+		}, nil
+	}
+
+	progress.Step(ctx, "generating certificates")
+	d.logger.Info("generating certificate authority")
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ca key")
+	}
+
+	caSerial, err := newCertSerial()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ca serial")
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber: caSerial,
+		Subject: pkix.Name{
+			CommonName: "cbdinocluster short-lived CA " + clusterID,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(shortLivedCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ca certificate")
+	}
+
+	caCert, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ca certificate")
+	}
+
+	caPem := encodeCertPem(caBytes)
+
+	progress.Step(ctx, "installing node certificates")
+
+	loadedCA := false
+	for _, node := range clusterInfo.Nodes {
+		if node.OTPNode == "" {
+			continue
+		}
+
+		logger := d.logger.With(zap.String("container", node.ContainerID))
+
+		nodeKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate node key")
+		}
+
+		nodeSerial, err := newCertSerial()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate node serial")
+		}
+
+		nodeTemplate := &x509.Certificate{
+			SerialNumber: nodeSerial,
+			Subject: pkix.Name{
+				CommonName: node.IPAddress,
+			},
+			NotBefore:   notBefore,
+			NotAfter:    notAfter,
+			KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			IPAddresses: []net.IP{net.ParseIP(node.IPAddress), net.ParseIP("127.0.0.1")},
+			DNSNames:    []string{"localhost", nodeHostname(clusterID, node.NodeID)},
+		}
+
+		if node.ClientIPAddress != "" {
+			nodeTemplate.IPAddresses = append(nodeTemplate.IPAddresses, net.ParseIP(node.ClientIPAddress))
+		}
+
+		advertiseAddress := d.getHost(node.HostName).Controller.AdvertiseAddress
+		if advertiseAddress != "" {
+			if advertiseIP := net.ParseIP(advertiseAddress); advertiseIP != nil {
+				nodeTemplate.IPAddresses = append(nodeTemplate.IPAddresses, advertiseIP)
+			} else {
+				nodeTemplate.DNSNames = append(nodeTemplate.DNSNames, advertiseAddress)
+			}
+		}
+
+		nodeBytes, err := x509.CreateCertificate(rand.Reader, nodeTemplate, caCert, &nodeKey.PublicKey, caKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create node certificate")
+		}
+
+		chainPem := append(encodeCertPem(nodeBytes), caPem...)
+		keyPem := pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(nodeKey),
+		})
+
+		logger.Info("installing short-lived node certificate",
+			zap.String("otp", node.OTPNode),
+			zap.Time("notAfter", notAfter))
+
+		err = d.writeNodeInbox(ctx, node, map[string][]byte{
+			"CA/ca.pem": caPem,
+			"chain.pem": chainPem,
+			"pkey.key":  keyPem,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write node certificates")
+		}
+
+		nodeCtrl := &clustercontrol.NodeManager{
+			Endpoint: d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
+		}
+
+		// the CA is shared by the whole cluster, so only needs loading once
+		if !loadedCA {
+			err = nodeCtrl.Controller().LoadTrustedCAs(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load trusted CA")
+			}
+			loadedCA = true
+		}
+
+		err = nodeCtrl.Controller().ReloadNodeCertificate(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to reload node certificate")
+		}
+	}
+
+	return &InstallShortLivedCertificatesResult{
+		CaPem:    string(caPem),
+		NotAfter: notAfter,
+	}, nil
+}
+
+// writeNodeInbox copies files into the inbox directory of a node, which is
+// where ns_server loads certificates from.
+func (d *Deployer) writeNodeInbox(ctx context.Context, node *deployedNodeInfo, files map[string][]byte) error {
+	tarBuf := bytes.NewBuffer(nil)
+	tarFile := tar.NewWriter(tarBuf)
+	tarFile.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     "inbox/CA/",
+		Mode:     0755,
+	})
+	for fileName, fileBytes := range files {
+		tarFile.WriteHeader(&tar.Header{
+			Name: "inbox/" + fileName,
+			Mode: 0644,
+			Size: int64(len(fileBytes)),
+		})
+		tarFile.Write(fileBytes)
+	}
+	tarFile.Close()
+
+	controller := d.getHost(node.HostName).Controller
+
+	err := controller.DockerCli.CopyToContainer(ctx, node.ContainerID,
+		path.Dir(couchbaseInboxPath), tarBuf, types.CopyToContainerOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to copy files")
+	}
+
+	err = controller.execCmd(ctx, node.ContainerID,
+		[]string{"chown", "-R", "couchbase:couchbase", couchbaseInboxPath})
+	if err != nil {
+		return errors.Wrap(err, "failed to change inbox ownership")
+	}
+
+	return nil
+}
+
+// GetNodeCertificates connects to the TLS management port of each node of a
+// cluster and returns the certificate each presents.
+func (d *Deployer) GetNodeCertificates(ctx context.Context, clusterID string) ([]*NodeCertificateStatus, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var statuses []*NodeCertificateStatus
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+		if node.Type != "server-node" && node.Type != "columnar-node" {
+			continue
+		}
+
+		address := d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, 18091)
+
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+			Config: &tls.Config{
+				// we are inspecting the certificate, not trusting it
+				InsecureSkipVerify: true,
+			},
+		}
+
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to node `%s`", node.NodeID)
+		}
+
+		peerCerts := conn.(*tls.Conn).ConnectionState().PeerCertificates
+		conn.Close()
+
+		if len(peerCerts) == 0 {
+			return nil, errors.Errorf("node `%s` presented no certificate", node.NodeID)
+		}
+		cert := peerCerts[0]
+
+		statuses = append(statuses, &NodeCertificateStatus{
+			NodeID:    node.NodeID,
+			Address:   address,
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			Expired:   time.Now().After(cert.NotAfter),
+		})
+	}
+
+	return statuses, nil
+}
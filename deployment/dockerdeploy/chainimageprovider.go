@@ -0,0 +1,357 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ImageSourceOptions configures one of the sources of a chain of image
+// providers.
+type ImageSourceOptions struct {
+	// Type is one of local, ghcr, dockerhub, package or registry.
+	Type string
+
+	// PackageURL is the package url used by the package type, see
+	// PackageImageProvider.
+	PackageURL string
+
+	// RegistryURL, RegistryUsername and RegistryPassword are the image
+	// reference and credentials used by the registry type, see
+	// RegistryImageProvider.
+	RegistryURL      string
+	RegistryUsername string
+	RegistryPassword string
+
+	// Allow and Deny are glob patterns matched against the version being
+	// fetched, both with and without its build number (7.6.0 and 7.6.0-1234).
+	// When Allow is specified, only matching versions are fetched from this
+	// source.  Versions matching Deny are never fetched from this source.
+	Allow []string
+	Deny  []string
+}
+
+// DefaultImageSources are the sources images are fetched from when none are
+// configured, trying the released images on dockerhub before the internal
+// builds on ghcr.
+var DefaultImageSources = []ImageSourceOptions{
+	{Type: "dockerhub"},
+	{Type: "ghcr"},
+}
+
+type imageSource struct {
+	Name     string
+	Provider ImageProvider
+	Allow    []string
+	Deny     []string
+
+	// ServerlessTag distinguishes the serverless images built on top of the
+	// images of this source from those of other sources.
+	ServerlessTag string
+}
+
+// ChainImageProvider tries each of its sources in order until one of them
+// provides the image.
+type ChainImageProvider struct {
+	logger    *zap.Logger
+	dockerCli *client.Client
+	sources   []*imageSource
+}
+
+var _ ImageProvider = (*ChainImageProvider)(nil)
+
+type ChainImageProviderOptions struct {
+	Logger       *zap.Logger
+	DockerCli    *client.Client
+	GhcrUsername string
+	GhcrPassword string
+	Manifest     *ImageManifest
+	Sources      []ImageSourceOptions
+}
+
+func NewChainImageProvider(opts *ChainImageProviderOptions) (*ChainImageProvider, error) {
+	if len(opts.Sources) == 0 {
+		return nil, errors.New("an image provider chain must have at least one source")
+	}
+
+	var sources []*imageSource
+	for _, sourceOpts := range opts.Sources {
+		var provider ImageProvider
+		serverlessTag := sourceOpts.Type
+		switch sourceOpts.Type {
+		case "local":
+			provider = &LocalImageProvider{
+				Logger:    opts.Logger,
+				DockerCli: opts.DockerCli,
+				Manifest:  opts.Manifest,
+			}
+		case "ghcr":
+			provider = &GhcrImageProvider{
+				Logger:       opts.Logger,
+				DockerCli:    opts.DockerCli,
+				GhcrUsername: opts.GhcrUsername,
+				GhcrPassword: opts.GhcrPassword,
+				Manifest:     opts.Manifest,
+			}
+		case "dockerhub":
+			provider = &DockerHubImageProvider{
+				Logger:    opts.Logger,
+				DockerCli: opts.DockerCli,
+				Manifest:  opts.Manifest,
+			}
+			// this matches the serverless images built before image
+			// sources were configurable
+			serverlessTag = "dh"
+		case "package":
+			provider = &PackageImageProvider{
+				Logger:     opts.Logger,
+				DockerCli:  opts.DockerCli,
+				PackageURL: sourceOpts.PackageURL,
+			}
+		case "registry":
+			if sourceOpts.RegistryURL == "" {
+				return nil, errors.New("a registry image provider must have a registry url")
+			}
+
+			provider = &RegistryImageProvider{
+				Logger:      opts.Logger,
+				DockerCli:   opts.DockerCli,
+				RegistryURL: sourceOpts.RegistryURL,
+				Username:    sourceOpts.RegistryUsername,
+				Password:    sourceOpts.RegistryPassword,
+				Manifest:    opts.Manifest,
+			}
+			serverlessTag = registryServerlessTag(sourceOpts.RegistryURL)
+		default:
+			return nil, fmt.Errorf("unknown image provider type `%s`", sourceOpts.Type)
+		}
+
+		for _, patterns := range [][]string{sourceOpts.Allow, sourceOpts.Deny} {
+			for _, pattern := range patterns {
+				_, err := path.Match(pattern, "")
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid version pattern `%s`", pattern)
+				}
+			}
+		}
+
+		sources = append(sources, &imageSource{
+			Name:          sourceOpts.Type,
+			Provider:      provider,
+			Allow:         sourceOpts.Allow,
+			Deny:          sourceOpts.Deny,
+			ServerlessTag: serverlessTag,
+		})
+	}
+
+	return &ChainImageProvider{
+		logger:    opts.Logger,
+		dockerCli: opts.DockerCli,
+		sources:   sources,
+	}, nil
+}
+
+func matchesVersionPatterns(patterns []string, def *ImageDef) bool {
+	versions := []string{def.Version}
+	if def.BuildNo > 0 {
+		versions = append(versions, fmt.Sprintf("%s-%d", def.Version, def.BuildNo))
+	}
+
+	for _, pattern := range patterns {
+		for _, version := range versions {
+			// patterns were validated when the chain was created
+			matched, _ := path.Match(pattern, version)
+			if matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *imageSource) allows(def *ImageDef) bool {
+	if matchesVersionPatterns(s.Deny, def) {
+		return false
+	}
+	if len(s.Allow) > 0 && !matchesVersionPatterns(s.Allow, def) {
+		return false
+	}
+	return true
+}
+
+var rawImageVersionRegexp = regexp.MustCompile(`^(\d+\.\d+\.\d+)(?:-(\d+))?$`)
+
+// rawImageDef identifies the server version of a raw image from its tag, such
+// as couchbase/server:enterprise-7.6.0 or ghcr.io/cb-vanilla/server:7.6.0-1234,
+// returning nil for images whose tag is not a server version.
+func rawImageDef(imagePath string) *ImageDef {
+	imagePath, _, _ = strings.Cut(imagePath, "@")
+
+	// the registry host may contain a port, so the tag is only looked for
+	// after the last path separator
+	imageName := imagePath[strings.LastIndex(imagePath, "/")+1:]
+	_, tag, found := strings.Cut(imageName, ":")
+	if !found {
+		return nil
+	}
+
+	def := &ImageDef{}
+	if versionTag, ok := strings.CutPrefix(tag, "community-"); ok {
+		def.UseCommunityEdition = true
+		tag = versionTag
+	} else {
+		tag = strings.TrimPrefix(tag, "enterprise-")
+	}
+
+	matches := rawImageVersionRegexp.FindStringSubmatch(tag)
+	if matches == nil {
+		return nil
+	}
+
+	def.Version = matches[1]
+	if matches[2] != "" {
+		def.BuildNo, _ = strconv.Atoi(matches[2])
+	}
+
+	return def
+}
+
+// allowsRaw indicates whether a raw image may be fetched from this source.
+// The allow and deny lists only apply to raw images of server versions, so
+// that other images such as minio are unaffected by them.
+func (s *imageSource) allowsRaw(imagePath string) bool {
+	def := rawImageDef(imagePath)
+	if def == nil {
+		return true
+	}
+	return s.allows(def)
+}
+
+// isFatalImageError indicates that a source failing to provide an image must
+// fail the whole chain, as an untrusted image must not fall back to another
+// source.
+func isFatalImageError(err error) bool {
+	return errors.Is(err, deployment.ErrImageUntrusted)
+}
+
+func (p *ChainImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	var failures []string
+	for _, source := range p.sources {
+		if !source.allows(def) {
+			p.logger.Debug("image provider does not allow this version",
+				zap.String("provider", source.Name),
+				zap.String("version", def.Version),
+				zap.Int("build", def.BuildNo))
+			failures = append(failures, fmt.Sprintf("%s: version not allowed", source.Name))
+			continue
+		}
+
+		provider := source.Provider
+		if def.UseServerless {
+			// serverless images are built on top of the non-serverless image
+			// of the same source, and are tagged by that source so that an
+			// image built from one source is never used in place of another.
+			provider = &ServerlessImageProvider{
+				Logger:            p.logger,
+				DockerCli:         p.dockerCli,
+				BaseProviderTag:   source.ServerlessTag,
+				BaseImageProvider: source.Provider,
+			}
+		}
+
+		image, err := provider.GetImage(ctx, def)
+		if err != nil {
+			if isFatalImageError(err) {
+				return nil, err
+			}
+
+			p.logger.Debug("image provider failed to provide image",
+				zap.String("provider", source.Name),
+				zap.Error(err))
+			failures = append(failures, fmt.Sprintf("%s: %s", source.Name, err))
+			continue
+		}
+
+		p.logger.Debug("image provider provided image",
+			zap.String("provider", source.Name),
+			zap.String("image", image.ImagePath))
+		return image, nil
+	}
+
+	return nil, deployment.NewError(deployment.ErrVersionUnavailable,
+		fmt.Errorf("no image provider could provide the image (%s)", strings.Join(failures, "; ")))
+}
+
+func (p *ChainImageProvider) GetImageRaw(ctx context.Context, imagePath string) (*ImageRef, error) {
+	var failures []string
+	for _, source := range p.sources {
+		if !source.allowsRaw(imagePath) {
+			p.logger.Debug("image provider does not allow this version",
+				zap.String("provider", source.Name),
+				zap.String("image", imagePath))
+			failures = append(failures, fmt.Sprintf("%s: version not allowed", source.Name))
+			continue
+		}
+
+		image, err := source.Provider.GetImageRaw(ctx, imagePath)
+		if err != nil {
+			if isFatalImageError(err) {
+				return nil, err
+			}
+
+			p.logger.Debug("image provider failed to provide image",
+				zap.String("provider", source.Name),
+				zap.Error(err))
+			failures = append(failures, fmt.Sprintf("%s: %s", source.Name, err))
+			continue
+		}
+
+		return image, nil
+	}
+
+	return nil, deployment.NewError(deployment.ErrVersionUnavailable,
+		fmt.Errorf("no image provider could provide the image (%s)", strings.Join(failures, "; ")))
+}
+
+func (p *ChainImageProvider) ListImages(ctx context.Context) ([]deployment.Image, error) {
+	var images []deployment.Image
+	for _, source := range p.sources {
+		providerImages, err := source.Provider.ListImages(ctx)
+		if err != nil {
+			p.logger.Debug("image provider failed to list images",
+				zap.String("provider", source.Name),
+				zap.Error(err))
+			continue
+		}
+
+		images = append(images, providerImages...)
+	}
+
+	return images, nil
+}
+
+func (p *ChainImageProvider) SearchImages(ctx context.Context, version string) ([]deployment.Image, error) {
+	var images []deployment.Image
+	for _, source := range p.sources {
+		providerImages, err := source.Provider.SearchImages(ctx, version)
+		if err != nil {
+			p.logger.Debug("image provider failed to search images",
+				zap.String("provider", source.Name),
+				zap.Error(err))
+			continue
+		}
+
+		images = append(images, providerImages...)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,79 @@
+package dockerdeploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRawImageDef(t *testing.T) {
+	assert.Equal(t, &ImageDef{Version: "7.6.0"},
+		rawImageDef("couchbase/server:enterprise-7.6.0"))
+	assert.Equal(t, &ImageDef{Version: "7.6.0", UseCommunityEdition: true},
+		rawImageDef("couchbase/server:community-7.6.0"))
+	assert.Equal(t, &ImageDef{Version: "8.0.0", BuildNo: 1234},
+		rawImageDef("ghcr.io/cb-vanilla/server:8.0.0-1234"))
+	assert.Equal(t, &ImageDef{Version: "7.6.0", BuildNo: 1234},
+		rawImageDef("registry.example.com:5000/server:7.6.0-1234@sha256:abcd"))
+
+	assert.Nil(t, rawImageDef("minio/minio:RELEASE.2024-01-01T00-00-00Z"))
+	assert.Nil(t, rawImageDef("registry.example.com:5000/server"))
+	assert.Nil(t, rawImageDef("couchbase/server:latest"))
+}
+
+func TestChainImageSources(t *testing.T) {
+	provider, err := NewChainImageProvider(&ChainImageProviderOptions{
+		Logger: zap.NewNop(),
+		Sources: []ImageSourceOptions{
+			{Type: "dockerhub", Deny: []string{"6.*"}},
+			{Type: "ghcr", Allow: []string{"8.0.*"}},
+			{Type: "registry", RegistryURL: "registry.example.com/server:{edition}-{version}"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, provider.sources, 3)
+
+	dockerhub, ghcr, registry := provider.sources[0], provider.sources[1], provider.sources[2]
+
+	// serverless images keep the tags they had before sources were configurable
+	assert.Equal(t, "dh", dockerhub.ServerlessTag)
+	assert.Equal(t, "ghcr", ghcr.ServerlessTag)
+	assert.Regexp(t, "^registry-[0-9a-f]{8}$", registry.ServerlessTag)
+
+	assert.False(t, dockerhub.allowsRaw("couchbase/server:enterprise-6.6.0"))
+	assert.True(t, dockerhub.allowsRaw("couchbase/server:enterprise-7.6.0"))
+	assert.False(t, ghcr.allowsRaw("ghcr.io/cb-vanilla/server:7.6.0-1234"))
+	assert.True(t, ghcr.allowsRaw("ghcr.io/cb-vanilla/server:8.0.0-1234"))
+
+	// images which are not of a server version are unaffected by the lists
+	assert.True(t, ghcr.allowsRaw("minio/minio:latest"))
+
+	_, err = NewChainImageProvider(&ChainImageProviderOptions{
+		Logger:  zap.NewNop(),
+		Sources: []ImageSourceOptions{{Type: "registry"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRegistryImagePath(t *testing.T) {
+	provider := &RegistryImageProvider{
+		RegistryURL: "registry.example.com:5000/couchbase/server:{edition}-{version}",
+	}
+
+	imagePath, err := provider.imagePath(&ImageDef{Version: "7.6.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com:5000/couchbase/server:enterprise-7.6.0", imagePath)
+
+	_, err = provider.imagePath(&ImageDef{Version: "8.0.0", BuildNo: 1234})
+	assert.Error(t, err)
+
+	assert.Equal(t, "registry.example.com:5000/couchbase/server", provider.repository())
+	assert.Equal(t, "registry.example.com:5000", provider.registryHost())
+
+	provider.RegistryURL = "registry.example.com/server:{version}-{build}"
+	imagePath, err = provider.imagePath(&ImageDef{Version: "8.0.0", BuildNo: 1234})
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/server:8.0.0-1234", imagePath)
+}
@@ -0,0 +1,116 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The clock of a node is skewed by preloading libfaketime into every process
+// of the container via /etc/ld.so.preload.  libfaketime periodically re-reads
+// /etc/faketimerc, so once the library is loaded the skew can be changed
+// without restarting the server again.  Note that changes can take up to 10
+// seconds to be observed, due to libfaketime caching the file contents.
+const (
+	faketimeRcPath       = "/etc/faketimerc"
+	faketimePreloadPath  = "/etc/ld.so.preload"
+	faketimeFindLibCmd   = "dpkg -L libfaketime | grep '/libfaketime.so.1$' | head -n 1"
+	couchbaseServicePath = "/etc/service/couchbase-server"
+)
+
+func (c *Controller) execShell(ctx context.Context, containerID string, script string) error {
+	return c.execCmd(ctx, containerID, []string{"sh", "-c", script})
+}
+
+func (c *Controller) installFaketime(ctx context.Context, containerID string) error {
+	err := c.execShell(ctx, containerID, "dpkg -s libfaketime >/dev/null 2>&1")
+	if err == nil {
+		return nil
+	}
+
+	c.Logger.Debug("libfaketime is not installed, attempting to install")
+
+	err = c.execCmd(ctx, containerID, []string{"apt-get", "update"})
+	if err != nil {
+		return errors.Wrap(err, "failed to update apt")
+	}
+
+	err = c.execCmd(ctx, containerID, []string{"apt-get", "-y", "install", "libfaketime"})
+	if err != nil {
+		return errors.Wrap(err, "failed to install libfaketime")
+	}
+
+	return nil
+}
+
+// SetClockSkew shifts the clock observed by the server processes in the
+// container by the specified offset.  The first time this is done for a
+// container, the server is restarted so that libfaketime is loaded.
+func (c *Controller) SetClockSkew(ctx context.Context, containerID string, skew time.Duration) error {
+	logger := c.Logger.With(zap.String("container", containerID))
+	logger.Debug("setting clock skew", zap.Duration("skew", skew))
+
+	err := c.installFaketime(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	skewSecs := int64(skew.Round(time.Second) / time.Second)
+	err = c.execShell(ctx, containerID, fmt.Sprintf("echo '%+d' > %s", skewSecs, faketimeRcPath))
+	if err != nil {
+		return errors.Wrap(err, "failed to write faketime configuration")
+	}
+
+	err = c.execShell(ctx, containerID, fmt.Sprintf(
+		"grep -q libfaketime %s 2>/dev/null", faketimePreloadPath))
+	if err == nil {
+		logger.Debug("libfaketime already preloaded, skew will apply shortly")
+		return nil
+	}
+
+	logger.Debug("preloading libfaketime and restarting server")
+
+	err = c.execShell(ctx, containerID, fmt.Sprintf(
+		"lib=$(%s) && test -n \"$lib\" && echo \"$lib\" >> %s",
+		faketimeFindLibCmd, faketimePreloadPath))
+	if err != nil {
+		return errors.Wrap(err, "failed to preload libfaketime")
+	}
+
+	err = c.execCmd(ctx, containerID, []string{"sv", "restart", couchbaseServicePath})
+	if err != nil {
+		return errors.Wrap(err, "failed to restart couchbase server")
+	}
+
+	nodes, err := c.ListNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+
+	var node *NodeInfo
+	for _, listedNode := range nodes {
+		if listedNode.ContainerID == containerID {
+			node = listedNode
+		}
+	}
+	if node == nil {
+		return errors.New("failed to find node after restart")
+	}
+
+	clusterCtrl := &clustercontrol.NodeManager{
+		Endpoint: "http://" + c.NodeAddress(node.IPAddress, node.PublishedPorts, 8091),
+	}
+
+	err = clusterCtrl.WaitForOnline(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for node to restart")
+	}
+
+	logger.Debug("clock skew has been set up!")
+
+	return nil
+}
@@ -3,15 +3,41 @@ package dockerdeploy
 import (
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
 )
 
 type ClusterNodeInfo struct {
 	NodeID     string
 	IsNode     bool
 	Name       string
+	NodeGroup  string
 	ResourceID string
 	IPAddress  string
+
+	// ClientIPAddress is the address of the node on the client network,
+	// if it is attached to one.
+	ClientIPAddress string
+
+	PublishedPorts map[int]int
+	HostName       string
+	Health         string
+	ContainerID    string
+
+	// Usage is the live resource usage of the node, which is only
+	// available once populated with PopulateResourceUsage.
+	Usage *ContainerResourceUsage
+
+	// OTPNode and NodeUUID identify the node within the cluster, and are
+	// only available once populated with PopulateNodeIdentity.
+	OTPNode  string
+	NodeUUID string
+
+	// Services and ServerGroup describe where the node sits in the topology
+	// of the cluster, and are also populated with PopulateNodeIdentity.
+	Services    []clusterdef.Service
+	ServerGroup string
 }
 
 var _ (deployment.ClusterNodeInfo) = (*ClusterNodeInfo)(nil)
@@ -21,6 +47,9 @@ func (i ClusterNodeInfo) IsClusterNode() bool   { return i.IsNode }
 func (i ClusterNodeInfo) GetName() string       { return i.Name }
 func (i ClusterNodeInfo) GetResourceID() string { return i.ResourceID }
 func (i ClusterNodeInfo) GetIPAddress() string  { return i.IPAddress }
+func (i ClusterNodeInfo) GetOTPNode() string    { return i.OTPNode }
+func (i ClusterNodeInfo) GetNodeUUID() string   { return i.NodeUUID }
+func (i ClusterNodeInfo) GetNodeGroup() string  { return i.NodeGroup }
 
 type ClusterInfo struct {
 	ClusterID string
@@ -29,7 +58,12 @@ type ClusterInfo struct {
 	Owner     string
 	Purpose   string
 	Expiry    time.Time
+	State     string
 	Nodes     []*ClusterNodeInfo
+
+	// MemoryQuotas are the service memory quotas of the cluster, which are
+	// only available once populated with PopulateMemoryQuotas.
+	MemoryQuotas *clustercontrol.MemoryQuotas
 }
 
 var _ (deployment.ClusterInfo) = (*ClusterInfo)(nil)
@@ -38,7 +72,7 @@ func (i ClusterInfo) GetID() string                   { return i.ClusterID }
 func (i ClusterInfo) GetType() deployment.ClusterType { return i.Type }
 func (i ClusterInfo) GetPurpose() string              { return i.Purpose }
 func (i ClusterInfo) GetExpiry() time.Time            { return i.Expiry }
-func (i ClusterInfo) GetState() string                { return "ready" }
+func (i ClusterInfo) GetState() string                { return i.State }
 func (i ClusterInfo) GetNodes() []deployment.ClusterNodeInfo {
 	var nodes []deployment.ClusterNodeInfo
 	for _, node := range i.Nodes {
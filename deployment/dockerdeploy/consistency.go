@@ -0,0 +1,507 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/memdclient"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type ConsistencyCheckOptions struct {
+	BucketName string
+
+	// WriterNodeID is the node the writes are made through, defaulting to the
+	// first data node.
+	WriterNodeID string
+
+	// NumWrites is the number of writes made through the writer node, each of
+	// which is read back through every reader holding a copy of it.
+	NumWrites int
+
+	// ReadTimeout is how long a reader has to observe a write, after which the
+	// write is counted as never observed by that reader.
+	ReadTimeout time.Duration
+}
+
+// ConsistencyCheckReader counts how the writes were observed through one node
+// and service, where Path is `kv:active`, `kv:replica` or
+// `query:<scan consistency>`.  The kv paths read the copy of a document held
+// by the node itself, so they only read the writes to the vbuckets which the
+// node holds that copy of.
+type ConsistencyCheckReader struct {
+	NodeID string
+	Path   string
+
+	// Guaranteed is whether this path guarantees that a write is observed
+	// as soon as it has completed, so that any stale read is a violation.
+	Guaranteed bool
+
+	Reads int
+
+	// Stale counts the writes which were not observed by the first read
+	// after they completed, and Missing those which were not observed at all
+	// within the read timeout.
+	Stale   int
+	Missing int
+
+	// MaxLag is the longest time after a write completed before it was
+	// observed, of the writes which were observed.
+	MaxLag     time.Duration
+	FirstError string
+}
+
+type ConsistencyCheckResult struct {
+	WriterNodeID string
+	Readers      []*ConsistencyCheckReader
+
+	// Violations counts the stale reads of the paths which guarantee that
+	// writes are observed immediately.
+	Violations int
+}
+
+type consistencyCheckDoc struct {
+	RunID string `json:"cbdcConsistencyRun"`
+	Seq   int    `json:"seq"`
+}
+
+// consistencyCheckNumKeys is the number of documents the writes are spread
+// across, so that they land in vbuckets held by different nodes.
+const consistencyCheckNumKeys = 16
+
+// errConsistencyCopyNotHeld indicates that a reader does not hold the copy of
+// a document it reads.
+var errConsistencyCopyNotHeld = errors.New("node does not hold this copy of the document")
+
+type consistencyReader struct {
+	Info *ConsistencyCheckReader
+
+	// Read returns the sequence number of a document as seen by this
+	// reader, or -1 if the document is not visible.
+	Read func(ctx context.Context, key string) (int, error)
+
+	// Close releases the connections of the reader, if it has any.
+	Close func()
+}
+
+// consistencyKvReader reads documents directly from the data service of one
+// node, from either the active or the replica copy of their vbucket which
+// that node holds.  Reading through the management API instead would always
+// be routed to the active copy, wherever it lives.
+type consistencyKvReader struct {
+	address    string
+	bucketName string
+	replica    bool
+
+	conn    *memdclient.Conn
+	routing *memdclient.Routing
+}
+
+func (r *consistencyKvReader) connect(ctx context.Context) error {
+	conn, err := memdclient.Dial(ctx, r.address, nil)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Setup("cbdinocluster-consistency", "Administrator", "password", r.bucketName)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	// the config is fetched through the node itself, so that it identifies
+	// which of the servers the node is
+	configResp, err := conn.Request(memdclient.OpGetClusterConfig, 0, nil, nil, nil)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to fetch cluster config")
+	}
+
+	seedHost, _, _ := net.SplitHostPort(r.address)
+	routing, err := memdclient.ParseClusterConfig(configResp.Value, seedHost, false, "")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if routing.ThisServer < 0 {
+		conn.Close()
+		return errors.New("node is not serving the bucket")
+	}
+
+	r.conn = conn
+	r.routing = routing
+	return nil
+}
+
+func (r *consistencyKvReader) Read(ctx context.Context, key string) (int, error) {
+	if r.conn == nil {
+		err := r.connect(ctx)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	vbID := memdclient.VbucketForKey([]byte(key), len(r.routing.VbucketServers))
+	opcode := uint8(memdclient.OpGet)
+	if r.replica {
+		if !slices.Contains(r.routing.VbucketReplicas[vbID], r.routing.ThisServer) {
+			return -1, errConsistencyCopyNotHeld
+		}
+		opcode = memdclient.OpGetReplica
+	} else if r.routing.VbucketServers[vbID] != r.routing.ThisServer {
+		return -1, errConsistencyCopyNotHeld
+	}
+
+	// a read which does not complete in time is abandoned by closing the
+	// connection, which is then reestablished by the next read
+	conn := r.conn
+	stopClose := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	resp, err := conn.Request(opcode, vbID, nil, []byte(key), nil)
+	wasClosed := !stopClose()
+
+	var statusErr memdclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == memdclient.StatusKeyNotFound && !wasClosed {
+		return -1, nil
+	}
+
+	// any other failure, such as the vbucket having moved, also refreshes
+	// the config along with the connection
+	if err != nil || wasClosed {
+		conn.Close()
+		r.conn = nil
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	var doc consistencyCheckDoc
+	err = json.Unmarshal(resp.Value, &doc)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to parse document")
+	}
+
+	return doc.Seq, nil
+}
+
+func (r *consistencyKvReader) Close() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// RunConsistencyCheck writes a set of documents repeatedly through one node
+// and after each write reads it back from the active and replica copies held
+// by each data node, and through the query service of every query node at
+// each scan consistency, recording how long each of them takes to observe
+// the write.
+func (d *Deployer) RunConsistencyCheck(ctx context.Context, clusterID string, opts *ConsistencyCheckOptions) (*ConsistencyCheckResult, error) {
+	readTimeout := opts.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = 10 * time.Second
+	}
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	var writerNode *deployedNodeInfo
+	for _, clusterNode := range clusterInfo.Nodes {
+		if opts.WriterNodeID != "" {
+			if clusterNode.NodeID == opts.WriterNodeID {
+				writerNode = clusterNode
+				break
+			}
+		} else if slices.Contains(clusterNode.Services, clusterdef.KvService) {
+			writerNode = clusterNode
+			break
+		}
+	}
+	if writerNode == nil {
+		if opts.WriterNodeID != "" {
+			return nil, fmt.Errorf("failed to find writer node `%s`", opts.WriterNodeID)
+		}
+		return nil, errors.New("cluster has no data nodes")
+	}
+
+	var kvNodes []*deployedNodeInfo
+	var queryNodes []*deployedNodeInfo
+	hasIndexService := false
+	for _, clusterNode := range clusterInfo.Nodes {
+		if slices.Contains(clusterNode.Services, clusterdef.KvService) {
+			kvNodes = append(kvNodes, clusterNode)
+		}
+		if slices.Contains(clusterNode.Services, clusterdef.QueryService) {
+			queryNodes = append(queryNodes, clusterNode)
+		}
+		if slices.Contains(clusterNode.Services, clusterdef.IndexService) {
+			hasIndexService = true
+		}
+	}
+
+	// queries need an index to observe the scan consistency, so they are
+	// only checked when the cluster can build one
+	if !hasIndexService {
+		d.logger.Info("cluster has no index nodes, skipping query reads")
+		queryNodes = nil
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would run consistency check",
+			zap.String("bucket", opts.BucketName),
+			zap.String("writer", writerNode.NodeID),
+			zap.Int("numWrites", opts.NumWrites),
+			zap.Int("numKvNodes", len(kvNodes)),
+			zap.Int("numQueryNodes", len(queryNodes)))
+		return &ConsistencyCheckResult{
+			WriterNodeID: writerNode.NodeID,
+		}, nil
+	}
+
+	runID := uuid.NewString()
+
+	writerCtrl := &clustercontrol.Controller{
+		Endpoint: d.mgmtEndpoint(writerNode.HostName, writerNode.IPAddress, writerNode.PublishedPorts),
+	}
+
+	var readers []*consistencyReader
+
+	// every reader is closed, including those of a failed check
+	defer func() {
+		for _, reader := range readers {
+			if reader.Close != nil {
+				reader.Close()
+			}
+		}
+	}()
+
+	for _, kvNode := range kvNodes {
+		for _, replica := range []bool{false, true} {
+			kvReader := &consistencyKvReader{
+				address:    d.nodeAddress(kvNode.HostName, kvNode.IPAddress, kvNode.PublishedPorts, 11210),
+				bucketName: opts.BucketName,
+				replica:    replica,
+			}
+
+			// only the active copy is guaranteed to observe a write as soon
+			// as it completes, as replication is asynchronous
+			path := "kv:active"
+			if replica {
+				path = "kv:replica"
+			}
+
+			readers = append(readers, &consistencyReader{
+				Info: &ConsistencyCheckReader{
+					NodeID:     kvNode.NodeID,
+					Path:       path,
+					Guaranteed: !replica,
+				},
+				Read:  kvReader.Read,
+				Close: kvReader.Close,
+			})
+		}
+	}
+
+	if len(queryNodes) > 0 {
+		indexName := "cbdc_consistency_" + runID[:8]
+		queryNode := queryNodes[0]
+		queryCtrl := &clustercontrol.Controller{
+			Endpoint: "http://" + d.nodeAddress(queryNode.HostName, queryNode.IPAddress, queryNode.PublishedPorts, 8093),
+		}
+
+		progress.Step(ctx, "creating consistency check index")
+		d.logger.Info("creating consistency check index", zap.String("index", indexName))
+
+		_, err := queryCtrl.Query(ctx, &clustercontrol.QueryOptions{
+			Statement: fmt.Sprintf("CREATE INDEX `%s` ON `%s`(cbdcConsistencyRun)", indexName, opts.BucketName),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create consistency check index")
+		}
+
+		// the index is dropped even if we were cancelled, so that it does
+		// not outlive the check
+		defer func() {
+			_, err := queryCtrl.Query(context.WithoutCancel(ctx), &clustercontrol.QueryOptions{
+				Statement: fmt.Sprintf("DROP INDEX `%s` ON `%s`", indexName, opts.BucketName),
+			})
+			if err != nil {
+				d.logger.Warn("failed to drop consistency check index",
+					zap.String("index", indexName),
+					zap.Error(err))
+			}
+		}()
+
+		for _, queryNode := range queryNodes {
+			nodeCtrl := &clustercontrol.Controller{
+				Endpoint: "http://" + d.nodeAddress(queryNode.HostName, queryNode.IPAddress, queryNode.PublishedPorts, 8093),
+			}
+
+			for _, scanConsistency := range []string{
+				clustercontrol.ScanConsistencyNotBounded,
+				clustercontrol.ScanConsistencyRequestPlus,
+			} {
+				scanConsistency := scanConsistency
+				readers = append(readers, &consistencyReader{
+					Info: &ConsistencyCheckReader{
+						NodeID:     queryNode.NodeID,
+						Path:       "query:" + scanConsistency,
+						Guaranteed: scanConsistency == clustercontrol.ScanConsistencyRequestPlus,
+					},
+					Read: func(ctx context.Context, key string) (int, error) {
+						rows, err := nodeCtrl.Query(ctx, &clustercontrol.QueryOptions{
+							Statement: fmt.Sprintf(
+								"SELECT RAW d.seq FROM `%s` AS d WHERE d.cbdcConsistencyRun = $run AND META(d).id = $key",
+								opts.BucketName),
+							ScanConsistency: scanConsistency,
+							NamedArgs: map[string]interface{}{
+								"run": runID,
+								"key": key,
+							},
+						})
+						if err != nil {
+							return -1, err
+						}
+
+						if len(rows) == 0 {
+							return -1, nil
+						}
+
+						var seq int
+						err = json.Unmarshal(rows[0], &seq)
+						if err != nil {
+							return -1, errors.Wrap(err, "failed to parse query row")
+						}
+
+						return seq, nil
+					},
+				})
+			}
+		}
+	}
+
+	progress.Step(ctx, "writing and reading back documents")
+	d.logger.Info("writing and reading back documents",
+		zap.String("writer", writerNode.NodeID),
+		zap.Int("numReaders", len(readers)))
+
+	for seq := 0; seq < opts.NumWrites; seq++ {
+		docKey := fmt.Sprintf("cbdc-consistency-%s-%d", runID, seq%consistencyCheckNumKeys)
+		docBytes, _ := json.Marshal(consistencyCheckDoc{
+			RunID: runID,
+			Seq:   seq,
+		})
+
+		err := writerCtrl.UpsertDocument(ctx, opts.BucketName, docKey, docBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write document")
+		}
+
+		// the readers read concurrently so that each of them starts reading
+		// as soon as possible after the write completed
+		writtenTime := time.Now()
+
+		var waitGrp sync.WaitGroup
+		for _, reader := range readers {
+			waitGrp.Add(1)
+			go func(reader *consistencyReader) {
+				defer waitGrp.Done()
+				d.observeConsistencyWrite(ctx, reader, docKey, seq, writtenTime, readTimeout)
+			}(reader)
+		}
+		waitGrp.Wait()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	result := &ConsistencyCheckResult{
+		WriterNodeID: writerNode.NodeID,
+	}
+	for _, reader := range readers {
+		result.Readers = append(result.Readers, reader.Info)
+		if reader.Info.Guaranteed {
+			result.Violations += reader.Info.Stale
+		}
+	}
+
+	return result, nil
+}
+
+// observeConsistencyWrite reads through a reader until it observes the write
+// of seq to a document, or the read timeout elapses.
+func (d *Deployer) observeConsistencyWrite(
+	ctx context.Context,
+	reader *consistencyReader,
+	key string,
+	seq int,
+	writtenTime time.Time,
+	readTimeout time.Duration,
+) {
+	info := reader.Info
+
+	// reads which are still pending when the read timeout elapses are
+	// abandoned, so that a hung node is reported as missing the write
+	readCtx, cancel := context.WithDeadline(ctx, writtenTime.Add(readTimeout))
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		readSeq, err := reader.Read(readCtx, key)
+		if errors.Is(err, errConsistencyCopyNotHeld) {
+			return
+		}
+		if attempt == 0 {
+			info.Reads++
+		}
+		if err != nil {
+			if info.FirstError == "" {
+				info.FirstError = err.Error()
+			}
+			d.logger.Debug("consistency check read failed",
+				zap.String("node", info.NodeID),
+				zap.String("path", info.Path),
+				zap.Error(err))
+		}
+
+		if err == nil && readSeq == seq {
+			lag := time.Since(writtenTime)
+			if attempt > 0 {
+				info.Stale++
+				if lag > info.MaxLag {
+					info.MaxLag = lag
+				}
+			}
+			return
+		}
+
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-readCtx.Done():
+		}
+
+		if readCtx.Err() != nil {
+			// only the read timeout elapsing means the write was missed,
+			// rather than the whole check being cancelled
+			if ctx.Err() == nil {
+				info.Stale++
+				info.Missing++
+			}
+			return
+		}
+	}
+}
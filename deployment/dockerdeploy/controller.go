@@ -8,23 +8,75 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	units "github.com/docker/go-units"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type Controller struct {
 	Logger      *zap.Logger
 	DockerCli   *client.Client
 	NetworkName string
+
+	// ClientNetworkName is an optional second network which server nodes
+	// are attached to for client traffic, with NetworkName then only
+	// carrying the traffic between nodes.
+	ClientNetworkName string
+
+	// HostName is the name of the docker host this controller manages,
+	// which is recorded on the nodes it lists.
+	HostName string
+
+	// AdvertiseAddress is the address of the docker host as reachable from
+	// this machine.  When set, node service ports are published on the host
+	// and nodes are reached through it rather than their container IPs,
+	// which allows driving a remote docker host.
+	AdvertiseAddress string
+
+	// StateKey is the key used to decrypt node state, and to encrypt it
+	// when EncryptState is enabled.
+	StateKey     []byte
+	EncryptState bool
+}
+
+func (c *Controller) publishPorts(ports []int) (nat.PortSet, nat.PortMap) {
+	if c.AdvertiseAddress == "" {
+		return nil, nil
+	}
+
+	exposedPorts := make(nat.PortSet)
+	portBindings := make(nat.PortMap)
+	for _, port := range ports {
+		containerPort := nat.Port(fmt.Sprintf("%d/tcp", port))
+		exposedPorts[containerPort] = struct{}{}
+		// an empty host port causes docker to pick a free one
+		portBindings[containerPort] = []nat.PortBinding{{HostPort: ""}}
+	}
+	return exposedPorts, portBindings
+}
+
+// NodeAddress returns the host:port that should be used to reach a service
+// port of a node from this machine.
+func (c *Controller) NodeAddress(ipAddress string, publishedPorts map[int]int, port int) string {
+	if c.AdvertiseAddress != "" {
+		if publicPort, ok := publishedPorts[port]; ok {
+			return fmt.Sprintf("%s:%d", c.AdvertiseAddress, publicPort)
+		}
+	}
+	return fmt.Sprintf("%s:%d", ipAddress, port)
 }
 
 type NodeInfo struct {
@@ -33,12 +85,44 @@ type NodeInfo struct {
 	NodeID               string
 	ClusterID            string
 	Name                 string
+	NodeGroup            string
 	Creator              string
 	Owner                string
 	Purpose              string
 	Expiry               time.Time
+	ExpiryNotified       bool
 	IPAddress            string
+	ClientIPAddress      string
+	PublishedPorts       map[int]int
+	HostName             string
 	InitialServerVersion string
+	RuntimeOpts          *NodeRuntimeOptions
+	Health               string
+	CreatedAt            time.Time
+
+	// StateLabels are the sensitive labels stored in the node state, which
+	// must be preserved when the node state is rewritten.
+	StateLabels map[string]string
+
+	// StateErr is set when the node state exists but could not be
+	// decrypted, in which case Expiry and StateLabels are unknown.
+	StateErr error
+}
+
+// NodeRuntimeOptions holds the custom runtime options a node was deployed
+// with.  These are recorded in a label on the container so that they can
+// be recovered when regenerating the cluster definition.  Only the names of
+// the environment variables are recorded as their values may be secrets, the
+// values are instead read back from the container itself.
+type NodeRuntimeOptions struct {
+	EnvVarNames []string                  `json:"env-names,omitempty"`
+	Ulimits     []clusterdef.DockerUlimit `json:"ulimits,omitempty"`
+	Sysctls     map[string]string         `json:"sysctls,omitempty"`
+	Volumes     []string                  `json:"volumes,omitempty"`
+	Args        []string                  `json:"args,omitempty"`
+	OsTuning    bool                      `json:"os-tuning,omitempty"`
+
+	MemoryLimitMB int `json:"memory-limit,omitempty"`
 }
 
 func (c *Controller) parseContainerInfo(container types.Container) *NodeInfo {
@@ -46,18 +130,31 @@ func (c *Controller) parseContainerInfo(container types.Container) *NodeInfo {
 	nodeType := container.Labels["com.couchbase.dyncluster.type"]
 	nodeID := container.Labels["com.couchbase.dyncluster.node_id"]
 	nodeName := container.Labels["com.couchbase.dyncluster.node_name"]
+	nodeGroup := container.Labels["com.couchbase.dyncluster.node_group"]
 	creator := container.Labels["com.couchbase.dyncluster.creator"]
 	purpose := container.Labels["com.couchbase.dyncluster.purpose"]
 	initialServerVersion := container.Labels["com.couchbase.dyncluster.initial_server_version"]
+	runtimeOptsJson := container.Labels["com.couchbase.dyncluster.runtime_opts"]
 
 	// If there is no cluster ID specified, this is not a cbdyncluster container
 	if clusterID == "" {
 		return nil
 	}
 
-	var pickedNetwork *network.EndpointSettings
-	for _, network := range container.NetworkSettings.Networks {
-		pickedNetwork = network
+	// nodes attached to a client network are addressed by their cluster
+	// network address, anything else uses whichever network it is on
+	pickedNetwork := container.NetworkSettings.Networks[c.NetworkName]
+	if pickedNetwork == nil {
+		for _, network := range container.NetworkSettings.Networks {
+			pickedNetwork = network
+		}
+	}
+
+	clientIPAddress := ""
+	if c.ClientNetworkName != "" && c.ClientNetworkName != c.NetworkName {
+		if clientNetwork := container.NetworkSettings.Networks[c.ClientNetworkName]; clientNetwork != nil {
+			clientIPAddress = clientNetwork.IPAddress
+		}
 	}
 
 	// if the node type is unspecified, we default to server-node
@@ -65,18 +162,44 @@ func (c *Controller) parseContainerInfo(container types.Container) *NodeInfo {
 		nodeType = "server-node"
 	}
 
+	var runtimeOpts *NodeRuntimeOptions
+	if runtimeOptsJson != "" {
+		err := json.Unmarshal([]byte(runtimeOptsJson), &runtimeOpts)
+		if err != nil {
+			c.Logger.Debug("failed to parse node runtime options", zap.Error(err))
+		}
+	}
+
+	var publishedPorts map[int]int
+	for _, port := range container.Ports {
+		if port.PublicPort == 0 || port.Type != "tcp" {
+			continue
+		}
+		if publishedPorts == nil {
+			publishedPorts = make(map[int]int)
+		}
+		publishedPorts[int(port.PrivatePort)] = int(port.PublicPort)
+	}
+
 	return &NodeInfo{
 		ContainerID:          container.ID,
 		Type:                 nodeType,
 		NodeID:               nodeID,
 		ClusterID:            clusterID,
 		Name:                 nodeName,
+		NodeGroup:            nodeGroup,
 		Creator:              creator,
 		Owner:                "",
 		Purpose:              purpose,
 		Expiry:               time.Time{},
 		IPAddress:            pickedNetwork.IPAddress,
+		ClientIPAddress:      clientIPAddress,
+		PublishedPorts:       publishedPorts,
+		HostName:             c.HostName,
 		InitialServerVersion: initialServerVersion,
+		RuntimeOpts:          runtimeOpts,
+		Health:               parseContainerHealth(container.Status),
+		CreatedAt:            time.Unix(container.Created, 0),
 	}
 }
 
@@ -98,8 +221,30 @@ func (c *Controller) ListNodes(ctx context.Context) ([]*NodeInfo, error) {
 		node := c.parseContainerInfo(container)
 		if node != nil {
 			nodeState, err := c.ReadNodeState(ctx, node.ContainerID)
-			if err == nil && nodeState != nil {
+			if errors.Is(err, ErrNodeStateUndecryptable) {
+				// the node would otherwise silently appear to never expire
+				c.Logger.Warn("failed to decrypt node state, its expiry is unknown",
+					zap.String("container", node.ContainerID),
+					zap.Error(err))
+				node.StateErr = err
+			} else if err != nil {
+				c.Logger.Debug("failed to read node state",
+					zap.String("container", node.ContainerID),
+					zap.Error(err))
+			}
+
+			if nodeState != nil {
+				// sensitive labels may have been stored in the node state instead
+				if len(nodeState.Labels) > 0 {
+					for labelName, labelValue := range nodeState.Labels {
+						container.Labels[labelName] = labelValue
+					}
+					node = c.parseContainerInfo(container)
+				}
+
 				node.Expiry = nodeState.Expiry
+				node.ExpiryNotified = nodeState.ExpiryNotified
+				node.StateLabels = nodeState.Labels
 			}
 
 			nodes = append(nodes, node)
@@ -110,18 +255,30 @@ func (c *Controller) ListNodes(ctx context.Context) ([]*NodeInfo, error) {
 }
 
 type DockerNodeState struct {
-	Expiry time.Time
+	Expiry         time.Time
+	ExpiryNotified bool
+
+	// Labels holds the sensitive labels of the node, when they are stored
+	// in the node state rather than on the container.
+	Labels map[string]string
 }
 
 type DockerNodeStateJson struct {
-	Expiry time.Time
+	Expiry         time.Time
+	ExpiryNotified bool              `json:",omitempty"`
+	Labels         map[string]string `json:",omitempty"`
 }
 
 func (c *Controller) WriteNodeState(ctx context.Context, containerID string, state *DockerNodeState) error {
-	c.Logger.Debug("writing node state", zap.String("container", containerID), zap.Any("state", state))
+	c.Logger.Debug("writing node state",
+		zap.String("container", containerID),
+		zap.Time("expiry", state.Expiry),
+		zap.Bool("expiryNotified", state.ExpiryNotified))
 
 	jsonState := &DockerNodeStateJson{
-		Expiry: state.Expiry,
+		Expiry:         state.Expiry,
+		ExpiryNotified: state.ExpiryNotified,
+		Labels:         state.Labels,
 	}
 
 	jsonBytes, err := json.Marshal(jsonState)
@@ -129,6 +286,13 @@ func (c *Controller) WriteNodeState(ctx context.Context, containerID string, sta
 		return errors.Wrap(err, "failed to marshal dyncluster node state")
 	}
 
+	if c.EncryptState {
+		jsonBytes, err = encryptNodeState(c.StateKey, jsonBytes)
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt dyncluster node state")
+		}
+	}
+
 	tarBuf := bytes.NewBuffer(nil)
 	tarFile := tar.NewWriter(tarBuf)
 	tarFile.WriteHeader(&tar.Header{
@@ -176,6 +340,13 @@ func (c *Controller) ReadNodeState(ctx context.Context, containerID string) (*Do
 			return nil, errors.Wrap(err, "failed to read dyncluster node state data")
 		}
 
+		if isNodeStateEncrypted(stateBytes) {
+			stateBytes, err = decryptNodeState(c.StateKey, stateBytes)
+			if err != nil {
+				return nil, deployment.NewError(ErrNodeStateUndecryptable, err)
+			}
+		}
+
 		err = json.Unmarshal(stateBytes, &nodeStateJson)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to parse dyncluster node state data")
@@ -187,7 +358,9 @@ func (c *Controller) ReadNodeState(ctx context.Context, containerID string) (*Do
 	}
 
 	return &DockerNodeState{
-		Expiry: nodeStateJson.Expiry,
+		Expiry:         nodeStateJson.Expiry,
+		ExpiryNotified: nodeStateJson.ExpiryNotified,
+		Labels:         nodeStateJson.Labels,
 	}, nil
 }
 
@@ -199,8 +372,11 @@ func (c *Controller) DeployS3MockNode(ctx context.Context, clusterID string, exp
 
 	containerName := "cbdynnode-s3-" + clusterID
 
+	exposedPorts, portBindings := c.publishPorts([]int{9090})
+
 	createResult, err := c.DockerCli.ContainerCreate(context.Background(), &container.Config{
-		Image: "adobe/s3mock",
+		Image:        "adobe/s3mock",
+		ExposedPorts: exposedPorts,
 		Labels: map[string]string{
 			"com.couchbase.dyncluster.cluster_id": clusterID,
 			"com.couchbase.dyncluster.type":       "s3mock",
@@ -210,9 +386,10 @@ func (c *Controller) DeployS3MockNode(ctx context.Context, clusterID string, exp
 		// same effect as ntp
 		Volumes: map[string]struct{}{"/etc/localtime:/etc/localtime": {}},
 	}, &container.HostConfig{
-		AutoRemove:  true,
-		NetworkMode: container.NetworkMode(c.NetworkName),
-		CapAdd:      []string{"NET_ADMIN"},
+		AutoRemove:   true,
+		NetworkMode:  container.NetworkMode(c.NetworkName),
+		CapAdd:       []string{"NET_ADMIN"},
+		PortBindings: portBindings,
 		Resources: container.Resources{
 			Ulimits: []*units.Ulimit{
 				{Name: "nofile", Soft: 200000, Hard: 200000},
@@ -263,7 +440,7 @@ func (c *Controller) DeployS3MockNode(ctx context.Context, clusterID string, exp
 	logger.Debug("container has started, waiting for it to get ready", zap.String("address", node.IPAddress))
 
 	for {
-		resp, err := http.Get(fmt.Sprintf("http://%s:%d", node.IPAddress, 9090))
+		resp, err := http.Get("http://" + c.NodeAddress(node.IPAddress, node.PublishedPorts, 9090))
 		if err != nil || resp.StatusCode != 200 {
 			logger.Debug("s3mock not ready yet", zap.Error(err))
 			time.Sleep(100 * time.Millisecond)
@@ -282,10 +459,37 @@ type DeployNodeOptions struct {
 	Purpose            string
 	Expiry             time.Duration
 	ClusterID          string
+	NodeGroup          string
 	Image              *ImageRef
 	ImageServerVersion string
 	IsColumnar         bool
 	EnvVars            map[string]string
+	Ulimits            []clusterdef.DockerUlimit
+	Sysctls            map[string]string
+	Volumes            []string
+	Args               []string
+	OsTuning           bool
+	MemoryLimitMB      int
+	License            *NodeLicense
+}
+
+// ReadNodeEnvVars reads the values of the named environment variables from
+// the container of a node.
+func (c *Controller) ReadNodeEnvVars(ctx context.Context, containerID string, varNames []string) (map[string]string, error) {
+	inspect, err := c.DockerCli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to inspect container")
+	}
+
+	envVars := make(map[string]string)
+	for _, envVar := range inspect.Config.Env {
+		varName, varValue, _ := strings.Cut(envVar, "=")
+		if slices.Contains(varNames, varName) {
+			envVars[varName] = varValue
+		}
+	}
+
+	return envVars, nil
 }
 
 func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*NodeInfo, error) {
@@ -297,6 +501,9 @@ func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*N
 	containerName := "cbdynnode-" + nodeID
 
 	var envVars []string
+	if def.License != nil {
+		envVars = append(envVars, def.License.envVars()...)
+	}
 	for varName, varValue := range def.EnvVars {
 		envVars = append(envVars, fmt.Sprintf("%s=%s", varName, varValue))
 	}
@@ -306,27 +513,90 @@ func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*N
 		nodeType = "columnar-node"
 	}
 
+	ulimits := []*units.Ulimit{
+		{Name: "nofile", Soft: 200000, Hard: 200000},
+	}
+	if def.OsTuning {
+		ulimits = slices.Clone(osTuningUlimits)
+	}
+	for _, ulimit := range def.Ulimits {
+		// user specified ulimits replace any of our defaults of the same name
+		ulimits = slices.DeleteFunc(ulimits, func(existing *units.Ulimit) bool {
+			return existing.Name == ulimit.Name
+		})
+		ulimits = append(ulimits, &units.Ulimit{
+			Name: ulimit.Name,
+			Soft: ulimit.Soft,
+			Hard: ulimit.Hard,
+		})
+	}
+
+	labels := map[string]string{
+		"com.couchbase.dyncluster.cluster_id":             def.ClusterID,
+		"com.couchbase.dyncluster.type":                   nodeType,
+		"com.couchbase.dyncluster.purpose":                def.Purpose,
+		"com.couchbase.dyncluster.node_id":                nodeID,
+		"com.couchbase.dyncluster.initial_server_version": def.ImageServerVersion,
+	}
+	if def.Image.Digest != "" {
+		labels["com.couchbase.dyncluster.image_digest"] = def.Image.Digest
+	}
+	if def.NodeGroup != "" {
+		labels["com.couchbase.dyncluster.node_group"] = def.NodeGroup
+	}
+
+	envVarNames := maps.Keys(def.EnvVars)
+	slices.Sort(envVarNames)
+
+	runtimeOpts := &NodeRuntimeOptions{
+		EnvVarNames: envVarNames,
+		Ulimits:     def.Ulimits,
+		Sysctls:     def.Sysctls,
+		Volumes:     def.Volumes,
+		Args:        def.Args,
+		OsTuning:    def.OsTuning,
+
+		MemoryLimitMB: def.MemoryLimitMB,
+	}
+	runtimeOptsJson, err := json.Marshal(runtimeOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal node runtime options")
+	}
+	if string(runtimeOptsJson) != "{}" {
+		labels["com.couchbase.dyncluster.runtime_opts"] = string(runtimeOptsJson)
+	}
+
+	stateLabels := c.moveSensitiveLabels(labels)
+
+	exposedPorts, portBindings := c.publishPorts(maps.Values(clustercontrol.AlternateAddressPorts))
+
+	resources := container.Resources{
+		Ulimits: ulimits,
+	}
+	if def.MemoryLimitMB > 0 {
+		// swap is included in the limit, as nodes which swap are too slow
+		// to be useful for testing
+		resources.Memory = int64(def.MemoryLimitMB) * 1024 * 1024
+		resources.MemorySwap = resources.Memory
+	}
+
 	createResult, err := c.DockerCli.ContainerCreate(context.Background(), &container.Config{
-		Image: def.Image.ImagePath,
-		Labels: map[string]string{
-			"com.couchbase.dyncluster.cluster_id":             def.ClusterID,
-			"com.couchbase.dyncluster.type":                   nodeType,
-			"com.couchbase.dyncluster.purpose":                def.Purpose,
-			"com.couchbase.dyncluster.node_id":                nodeID,
-			"com.couchbase.dyncluster.initial_server_version": def.ImageServerVersion,
-		},
+		Image:  def.Image.ImagePath,
+		Labels: labels,
 		// same effect as ntp
-		Volumes: map[string]struct{}{"/etc/localtime:/etc/localtime": {}},
-		Env:     envVars,
+		Volumes:      map[string]struct{}{"/etc/localtime:/etc/localtime": {}},
+		Env:          envVars,
+		Cmd:          def.Args,
+		ExposedPorts: exposedPorts,
+		Healthcheck:  serverNodeHealthcheck,
 	}, &container.HostConfig{
-		AutoRemove:  true,
-		NetworkMode: container.NetworkMode(c.NetworkName),
-		CapAdd:      []string{"NET_ADMIN"},
-		Resources: container.Resources{
-			Ulimits: []*units.Ulimit{
-				{Name: "nofile", Soft: 200000, Hard: 200000},
-			},
-		},
+		AutoRemove:   true,
+		NetworkMode:  container.NetworkMode(c.NetworkName),
+		CapAdd:       []string{"NET_ADMIN"},
+		Binds:        def.Volumes,
+		Sysctls:      def.Sysctls,
+		PortBindings: portBindings,
+		Resources:    resources,
 	}, nil, nil, containerName)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create container")
@@ -334,6 +604,22 @@ func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*N
 
 	containerID := createResult.ID
 
+	if c.ClientNetworkName != "" && c.ClientNetworkName != c.NetworkName {
+		err = c.DockerCli.NetworkConnect(ctx, c.ClientNetworkName, containerID, nil)
+		if err != nil {
+			c.DockerCli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+			return nil, errors.Wrap(err, "failed to attach container to client network")
+		}
+	}
+
+	if def.License != nil && def.License.Data != nil {
+		err = c.writeLicense(ctx, containerID, def.License)
+		if err != nil {
+			c.DockerCli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+			return nil, err
+		}
+	}
+
 	logger.Debug("container created, starting", zap.String("container", containerID))
 
 	err = c.DockerCli.ContainerStart(context.Background(), containerID, types.ContainerStartOptions{})
@@ -348,6 +634,7 @@ func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*N
 
 	err = c.WriteNodeState(ctx, containerID, &DockerNodeState{
 		Expiry: expiryTime,
+		Labels: stateLabels,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed write node state")
@@ -369,13 +656,19 @@ func (c *Controller) DeployNode(ctx context.Context, def *DeployNodeOptions) (*N
 		return nil, errors.New("failed to find newly created container")
 	}
 
-	logger.Debug("container has started, waiting for it to get ready", zap.String("address", node.IPAddress))
+	if def.OsTuning {
+		tuning, err := c.applyOsTuning(ctx, containerID, ulimits)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to apply os tuning")
+		}
 
-	clusterCtrl := &clustercontrol.NodeManager{
-		Endpoint: fmt.Sprintf("http://%s:%d", node.IPAddress, 8091),
+		logger.Debug("applied os tuning", zap.Any("results", tuning.Results))
 	}
 
-	err = clusterCtrl.WaitForOnline(ctx)
+	logger.Debug("container has started, waiting for it to get ready", zap.String("address", node.IPAddress))
+
+	mgmtEndpoint := "http://" + c.NodeAddress(node.IPAddress, node.PublishedPorts, 8091)
+	err = c.waitForHealthy(ctx, containerID, mgmtEndpoint)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to wait for node readiness")
 	}
@@ -437,6 +730,7 @@ func (c *Controller) UpdateExpiry(ctx context.Context, containerID string, newEx
 	}
 
 	state.Expiry = newExpiryTime
+	state.ExpiryNotified = false
 
 	err = c.WriteNodeState(ctx, containerID, state)
 	if err != nil {
@@ -489,10 +783,16 @@ const (
 	TrafficControlAllowAll     TrafficControlType = "none"
 )
 
-func (c *Controller) SetTrafficControl(ctx context.Context, containerID string, tcType TrafficControlType) error {
+// SetTrafficControl replaces the traffic rules of a node with ones blocking
+// the given type of traffic.  When ports are specified, only traffic to and
+// from those ports is blocked, leaving the other services of the node
+// reachable.  Nodes are identified by the subnet of the cluster network, so
+// clients on a separate client network are never mistaken for nodes.
+func (c *Controller) SetTrafficControl(ctx context.Context, containerID string, tcType TrafficControlType, ports []int) error {
 	logger := c.Logger.With(zap.String("container", containerID))
 	logger.Debug("setting up traffic control",
-		zap.String("blockType", string(tcType)))
+		zap.String("blockType", string(tcType)),
+		zap.Ints("ports", ports))
 
 	netInfo, err := c.DockerCli.NetworkInspect(ctx, c.NetworkName, types.NetworkInspectOptions{})
 	if err != nil {
@@ -519,9 +819,31 @@ func (c *Controller) SetTrafficControl(ctx context.Context, containerID string,
 		return errors.Wrap(err, "failed to clear iptables")
 	}
 
+	// insertDrop inserts a rule dropping the matched packets, which is
+	// restricted to packets to or from the blocked ports if there are any.
+	// Dropping packets from the ports also blocks the responses to any
+	// connections the node makes to those ports on other nodes.
+	insertDrop := func(match []string) error {
+		if len(ports) == 0 {
+			return c.execIptables(ctx, containerID, append(append([]string{"-I", "INPUT"}, match...), "-j", "DROP"))
+		}
+
+		for _, port := range ports {
+			for _, portMatch := range []string{"--dport", "--sport"} {
+				args := append([]string{"-I", "INPUT"}, match...)
+				args = append(args, "-p", "tcp", portMatch, fmt.Sprintf("%d", port), "-j", "DROP")
+				err := c.execIptables(ctx, containerID, args)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
 	if tcType == TrafficControlBlockNodes {
 		// reject from the rest of that subnet
-		err = c.execIptables(ctx, containerID, []string{"-I", "INPUT", "-s", ipRange, "-j", "DROP"})
+		err = insertDrop([]string{"-s", ipRange})
 		if err != nil {
 			return errors.Wrap(err, "failed to create iptables rule")
 		}
@@ -533,7 +855,7 @@ func (c *Controller) SetTrafficControl(ctx context.Context, containerID string,
 		}
 	} else if tcType == TrafficControlBlockClients {
 		// block everyone else
-		err = c.execIptables(ctx, containerID, []string{"-I", "INPUT", "-j", "DROP"})
+		err = insertDrop(nil)
 		if err != nil {
 			return errors.Wrap(err, "failed to create iptables rule")
 		}
@@ -545,13 +867,13 @@ func (c *Controller) SetTrafficControl(ctx context.Context, containerID string,
 		}
 
 		// always reject from the gateway
-		err = c.execIptables(ctx, containerID, []string{"-I", "INPUT", "-s", gatewayIP, "-j", "DROP"})
+		err = insertDrop([]string{"-s", gatewayIP})
 		if err != nil {
 			return errors.Wrap(err, "failed to create iptables rule")
 		}
 	} else if tcType == TrafficControlBlockAll {
 		// block all packets
-		err = c.execIptables(ctx, containerID, []string{"-I", "INPUT", "-j", "DROP"})
+		err = insertDrop(nil)
 		if err != nil {
 			return errors.Wrap(err, "failed to create iptables rule")
 		}
@@ -0,0 +1,122 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// dataToolsImage is the image the cbexport and cbimport tools are run from.
+const dataToolsImage = "couchbase:enterprise"
+
+// The portable format is JSON lines, where each document has its key, scope
+// and collection recorded in the following extra fields.
+const (
+	dataKeyField        = "__cbdc_key"
+	dataScopeField      = "__cbdc_scope"
+	dataCollectionField = "__cbdc_collection"
+)
+
+type BucketDataOptions struct {
+	ConnStr    string
+	Username   string
+	Password   string
+	BucketName string
+}
+
+func (o *BucketDataOptions) toolArgs() []string {
+	return []string{
+		"-c", o.ConnStr,
+		"-u", o.Username,
+		"-p", o.Password,
+		"-b", o.BucketName,
+		"--no-ssl-verify",
+	}
+}
+
+// ExportBucketData exports all the documents of a bucket in the portable
+// JSON lines format using cbexport in a tool container.  Since the tool
+// container runs on the docker network, this works both for docker clusters
+// and for any cluster reachable from the docker host, such as Capella.
+func (d *Deployer) ExportBucketData(ctx context.Context, opts *BucketDataOptions, w io.Writer) error {
+	image, err := d.imageProvider.GetImageRaw(ctx, dataToolsImage)
+	if err != nil {
+		return errors.Wrap(err, "failed to get tools image")
+	}
+
+	outputPath := "/tmp/export.jsonl"
+	cmd := []string{"/opt/couchbase/bin/cbexport", "json"}
+	cmd = append(cmd, opts.toolArgs()...)
+	cmd = append(cmd,
+		"-f", "lines",
+		"-o", outputPath,
+		"--include-key", dataKeyField,
+		"--scope-field", dataScopeField,
+		"--collection-field", dataCollectionField)
+
+	output, err := d.controller.RunTool(ctx, &RunToolOptions{
+		Image:      image,
+		Cmd:        cmd,
+		OutputPath: outputPath,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to export bucket data")
+	}
+
+	_, err = w.Write(output)
+	if err != nil {
+		return errors.Wrap(err, "failed to write exported data")
+	}
+
+	return nil
+}
+
+// ImportBucketData imports documents in the portable JSON lines format into
+// a bucket using cbimport in a tool container.  Any scopes and collections
+// referenced by the data must already exist.  The cluster lock is not taken
+// as the target is a connection string, which need not be one of our
+// clusters, and importing only writes documents.
+func (d *Deployer) ImportBucketData(ctx context.Context, opts *BucketDataOptions, r io.Reader) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to read data to import")
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would import bucket data",
+			zap.String("bucket", opts.BucketName),
+			zap.Int("size", len(input)))
+		return nil
+	}
+
+	image, err := d.imageProvider.GetImageRaw(ctx, dataToolsImage)
+	if err != nil {
+		return errors.Wrap(err, "failed to get tools image")
+	}
+
+	inputPath := "/tmp/import.jsonl"
+	cmd := []string{"/opt/couchbase/bin/cbimport", "json"}
+	cmd = append(cmd, opts.toolArgs()...)
+	cmd = append(cmd,
+		"-f", "lines",
+		"-d", "file://"+inputPath,
+		"-g", fmt.Sprintf("%%%s%%", dataKeyField),
+		"--scope-collection-exp", fmt.Sprintf("%%%s%%.%%%s%%", dataScopeField, dataCollectionField),
+		"--ignore-fields", strings.Join([]string{dataKeyField, dataScopeField, dataCollectionField}, ","))
+
+	_, err = d.controller.RunTool(ctx, &RunToolOptions{
+		Image:     image,
+		Cmd:       cmd,
+		InputPath: inputPath,
+		Input:     input,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to import bucket data")
+	}
+
+	return nil
+}
@@ -6,10 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -18,8 +18,11 @@ import (
 	"github.com/couchbaselabs/cbdinocluster/clusterdef"
 	"github.com/couchbaselabs/cbdinocluster/deployment"
 	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/clusterlock"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
 	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
 	"github.com/docker/docker/client"
+	units "github.com/docker/go-units"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -33,6 +36,19 @@ type Deployer struct {
 	dockerCli     *client.Client
 	imageProvider ImageProvider
 	controller    *Controller
+	hosts         []*dockerHost
+	dryRun        bool
+	gracePeriod   time.Duration
+	onExpiring    deployment.ExpiringHook
+	acceptLicense bool
+	licenseFile   string
+
+	diagnosticsPath string
+	clusterLocker   *clusterlock.Locker
+
+	snapshotRegistry string
+	ghcrUsername     string
+	ghcrPassword     string
 }
 
 var _ deployment.Deployer = (*Deployer)(nil)
@@ -43,28 +59,150 @@ type DeployerOptions struct {
 	NetworkName  string
 	GhcrUsername string
 	GhcrPassword string
+
+	// ClientNetworkName is an optional second network which server nodes
+	// are attached to, which clients connect through while the traffic
+	// between nodes stays on NetworkName.
+	ClientNetworkName string
+
+	// AdvertiseAddress is the address of the docker host as reachable from
+	// this machine.  It must be specified when the docker host is remote and
+	// its container network is not routable from this machine.
+	AdvertiseAddress string
+
+	// ExtraHosts specifies additional docker hosts which nodes are spread
+	// across, allowing clusters larger than a single machine.
+	ExtraHosts []*HostOptions
+
+	// DryRun causes destructive operations to only log the actions
+	// they would have taken rather than actually performing them.
+	DryRun bool
+
+	// ExpiryGracePeriod is how long nodes are kept around after they
+	// expire before they are actually removed by Cleanup.
+	ExpiryGracePeriod time.Duration
+
+	// OnClusterExpiring is invoked once for each cluster which enters
+	// the expiring phase during Cleanup.
+	OnClusterExpiring deployment.ExpiringHook
+
+	// DiagnosticsPath is a directory into which a diagnostics bundle is
+	// captured whenever creating or modifying a cluster fails.
+	DiagnosticsPath string
+
+	// AcceptLicense and LicenseFile are the default license material for
+	// server nodes, used when a cluster definition does not specify any.
+	AcceptLicense bool
+	LicenseFile   string
+
+	// ImageManifest lists trusted image digests which images are pinned to
+	// and verified against.
+	ImageManifest *ImageManifest
+
+	// ImageSources is an ordered chain of sources which server images are
+	// fetched from, falling through to the next source when one cannot
+	// provide an image.  DefaultImageSources are used when it is empty.
+	ImageSources []ImageSourceOptions
+
+	// NodeStateKey is the machine key used to decrypt node state, and to
+	// encrypt it when EncryptNodeState is enabled, in which case sensitive
+	// values are also kept out of container labels.
+	NodeStateKey     []byte
+	EncryptNodeState bool
+
+	// ClusterLocker serializes operations which modify a cluster between
+	// concurrent invocations.  No locking is performed when it is nil.
+	ClusterLocker *clusterlock.Locker
+
+	// SnapshotRegistry is the image repository golden snapshots are
+	// published to and resolved from.  Snapshots are local when empty.
+	SnapshotRegistry string
 }
 
 func NewDeployer(opts *DeployerOptions) (*Deployer, error) {
-	return &Deployer{
-		logger:    opts.Logger,
-		dockerCli: opts.DockerCli,
-		imageProvider: &HybridImageProvider{
+	newHost := func(name string, dockerCli *client.Client, advertiseAddress string) (*dockerHost, error) {
+		imageSources := opts.ImageSources
+		if len(imageSources) == 0 {
+			imageSources = DefaultImageSources
+		}
+
+		imageProvider, err := NewChainImageProvider(&ChainImageProviderOptions{
 			Logger:       opts.Logger,
-			DockerCli:    opts.DockerCli,
+			DockerCli:    dockerCli,
 			GhcrUsername: opts.GhcrUsername,
 			GhcrPassword: opts.GhcrPassword,
-		},
-		controller: &Controller{
-			Logger:      opts.Logger,
-			DockerCli:   opts.DockerCli,
-			NetworkName: opts.NetworkName,
-		},
+			Manifest:     opts.ImageManifest,
+			Sources:      imageSources,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create image provider chain")
+		}
+
+		return &dockerHost{
+			Name:          name,
+			DockerCli:     dockerCli,
+			ImageProvider: imageProvider,
+			Controller: &Controller{
+				Logger:            opts.Logger.With(zap.String("host", name)),
+				DockerCli:         dockerCli,
+				NetworkName:       opts.NetworkName,
+				ClientNetworkName: opts.ClientNetworkName,
+				HostName:          name,
+				AdvertiseAddress:  advertiseAddress,
+				StateKey:          opts.NodeStateKey,
+				EncryptState:      opts.EncryptNodeState,
+			},
+		}, nil
+	}
+
+	defaultHost, err := newHost(DefaultHostName, opts.DockerCli, opts.AdvertiseAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*dockerHost{defaultHost}
+	for _, hostOpts := range opts.ExtraHosts {
+		if hostOpts.Name == "" {
+			return nil, errors.New("docker hosts must have a name")
+		}
+
+		for _, host := range hosts {
+			if host.Name == hostOpts.Name {
+				return nil, fmt.Errorf("duplicate docker host name `%s`", hostOpts.Name)
+			}
+		}
+
+		host, err := newHost(hostOpts.Name, hostOpts.DockerCli, hostOpts.AdvertiseAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return &Deployer{
+		logger:        opts.Logger,
+		dockerCli:     hosts[0].DockerCli,
+		imageProvider: hosts[0].ImageProvider,
+		controller:    hosts[0].Controller,
+		hosts:         hosts,
+		dryRun:        opts.DryRun,
+		gracePeriod:   opts.ExpiryGracePeriod,
+		onExpiring:    opts.OnClusterExpiring,
+		acceptLicense: opts.AcceptLicense,
+		licenseFile:   opts.LicenseFile,
+
+		diagnosticsPath: opts.DiagnosticsPath,
+		clusterLocker:   opts.ClusterLocker,
+
+		snapshotRegistry: opts.SnapshotRegistry,
+		ghcrUsername:     opts.GhcrUsername,
+		ghcrPassword:     opts.GhcrPassword,
 	}, nil
 }
 
 func (d *Deployer) listClusters(ctx context.Context) ([]*ClusterInfo, error) {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list nodes")
 	}
@@ -84,6 +222,7 @@ func (d *Deployer) listClusters(ctx context.Context) ([]*ClusterInfo, error) {
 		cluster := &ClusterInfo{
 			ClusterID: clusterID,
 			Type:      deployment.ClusterTypeServer,
+			State:     "ready",
 		}
 		clusters = append(clusters, cluster)
 		return cluster
@@ -108,15 +247,34 @@ func (d *Deployer) listClusters(ctx context.Context) ([]*ClusterInfo, error) {
 			IsNode:     isClusterNode,
 			NodeID:     node.NodeID,
 			Name:       node.Name,
+			NodeGroup:  node.NodeGroup,
 			IPAddress:  node.IPAddress,
+
+			ClientIPAddress: node.ClientIPAddress,
+			PublishedPorts:  node.PublishedPorts,
+			HostName:        node.HostName,
+			Health:          node.Health,
+			ContainerID:     node.ContainerID,
 		})
 
+		if node.Health == NodeHealthUnhealthy {
+			cluster.State = ClusterStateUnhealthy
+		}
+
 		// if any nodes are columnar nodes, the cluster is a columnar cluster
 		if node.Type == "columnar-node" {
 			cluster.Type = deployment.ClusterTypeColumnar
 		}
 	}
 
+	curTime := time.Now()
+	for _, cluster := range clusters {
+		expiryPhase := deployment.GetExpiryPhase(cluster.Expiry, d.gracePeriod, curTime)
+		if expiryPhase != deployment.ExpiryPhaseActive {
+			cluster.State = deployment.ClusterStateExpiring
+		}
+	}
+
 	return clusters, nil
 }
 
@@ -133,12 +291,12 @@ func (d *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 	return out, nil
 }
 
-func (d *Deployer) getImagesForNodeGrps(ctx context.Context, nodeGrps []*clusterdef.NodeGroup, isColumnar bool) ([]*ImageRef, error) {
+func (d *Deployer) getImagesForNodeGrps(ctx context.Context, imageProvider ImageProvider, nodeGrps []*clusterdef.NodeGroup, isColumnar bool) ([]*ImageRef, error) {
 	nodeGrpDefs := make([]*ImageDef, len(nodeGrps))
 	nodeGrpImages := make([]*ImageRef, len(nodeGrps))
 	for nodeGrpIdx, nodeGrp := range nodeGrps {
 		if nodeGrp.Docker.Image != "" {
-			foundImageRef, err := d.imageProvider.GetImageRaw(ctx, nodeGrp.Docker.Image)
+			foundImageRef, err := imageProvider.GetImageRaw(ctx, nodeGrp.Docker.Image)
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to get image for a node")
 			}
@@ -169,7 +327,7 @@ func (d *Deployer) getImagesForNodeGrps(ctx context.Context, nodeGrps []*cluster
 		}
 
 		if imageRef == nil {
-			foundImageRef, err := d.imageProvider.GetImage(ctx, imageDef)
+			foundImageRef, err := imageProvider.GetImage(ctx, imageDef)
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to get image for a node")
 			}
@@ -183,11 +341,268 @@ func (d *Deployer) getImagesForNodeGrps(ctx context.Context, nodeGrps []*cluster
 	return nodeGrpImages, nil
 }
 
-func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (deployment.ClusterInfo, error) {
+// estimateNodeMemoryMB estimates the memory needed by a single node of the
+// node group, based on the service quotas which will be configured for it.
+func (d *Deployer) estimateNodeMemoryMB(def *clusterdef.Cluster, nodeGrp *clusterdef.NodeGroup) int {
+	services := nodeGrp.Services
+	if def.Columnar {
+		services = []clusterdef.Service{
+			clusterdef.KvService,
+			clusterdef.AnalyticsService,
+		}
+	} else if len(services) == 0 {
+		services = []clusterdef.Service{
+			clusterdef.KvService,
+			clusterdef.IndexService,
+			clusterdef.QueryService,
+			clusterdef.SearchService,
+		}
+	}
+
+	quotaOrDefault := func(quotaMB int, defaultMB int) int {
+		if quotaMB > defaultMB {
+			return quotaMB
+		}
+		return defaultMB
+	}
+
+	memoryMB := 0
+	for _, service := range services {
+		switch service {
+		case clusterdef.KvService:
+			memoryMB += quotaOrDefault(def.Docker.KvMemoryMB, 256)
+		case clusterdef.IndexService:
+			memoryMB += quotaOrDefault(def.Docker.IndexMemoryMB, 256)
+		case clusterdef.SearchService:
+			memoryMB += quotaOrDefault(def.Docker.FtsMemoryMB, 256)
+		case clusterdef.AnalyticsService:
+			memoryMB += quotaOrDefault(def.Docker.CbasMemoryMB, 1024)
+		case clusterdef.EventingService:
+			memoryMB += quotaOrDefault(def.Docker.EventingMemoryMB, 256)
+		}
+	}
+
+	return memoryMB
+}
+
+// nodeMemoryOverheadMB is the memory used by the processes of a node beyond
+// its service quotas, such as ns_server and the query service.
+const nodeMemoryOverheadMB = 512
+
+// warnMemoryLimits warns about node groups whose memory limit leaves too
+// little room for their service quotas, as the processes of these nodes are
+// likely to be killed once they are under load.
+func (d *Deployer) warnMemoryLimits(def *clusterdef.Cluster, nodeGrps []*clusterdef.NodeGroup) {
+	for nodeGrpIdx, nodeGrp := range nodeGrps {
+		if nodeGrp.Docker.MemoryLimitMB <= 0 {
+			continue
+		}
+
+		requiredMemoryMB := d.estimateNodeMemoryMB(def, nodeGrp) + nodeMemoryOverheadMB
+		if nodeGrp.Docker.MemoryLimitMB < requiredMemoryMB {
+			d.logger.Warn("memory limit of nodes is below their estimated memory use, processes may be killed when it is exceeded",
+				zap.String("field", deployment.NodeGroupField(nodeGrpIdx, "docker.memory-limit")),
+				zap.Int("limitMB", nodeGrp.Docker.MemoryLimitMB),
+				zap.Int("estimatedMB", requiredMemoryMB))
+		}
+	}
+}
+
+// namespacedKernelSysctls are the kernel sysctls which are namespaced by
+// the ipc namespace, the remainder of kernel.* affects the entire host.
+var namespacedKernelSysctls = []string{
+	"kernel.msgmax",
+	"kernel.msgmnb",
+	"kernel.msgmni",
+	"kernel.sem",
+	"kernel.shmall",
+	"kernel.shmmax",
+	"kernel.shmmni",
+	"kernel.shm_rmid_forced",
+}
+
+// isNamespacedSysctl checks whether a sysctl is namespaced, since docker only
+// permits namespaced sysctls to be set per-container.
+func isNamespacedSysctl(name string) bool {
+	return strings.HasPrefix(name, "net.") ||
+		strings.HasPrefix(name, "fs.mqueue.") ||
+		slices.Contains(namespacedKernelSysctls, name)
+}
+
+func (d *Deployer) validateRuntimeOpts(nodeGrps []*clusterdef.NodeGroup) []deployment.DefinitionViolation {
+	var violations []deployment.DefinitionViolation
+	addViolation := func(nodeGrpIdx int, field string, message string) {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   deployment.NodeGroupField(nodeGrpIdx, field),
+			Message: message,
+		})
+	}
+
+	for nodeGrpIdx, nodeGrp := range nodeGrps {
+		for varName := range nodeGrp.Docker.EnvVars {
+			if varName == "" || strings.Contains(varName, "=") {
+				addViolation(nodeGrpIdx, "docker.env", fmt.Sprintf("invalid environment variable name `%s`", varName))
+			}
+		}
+
+		for _, ulimit := range nodeGrp.Docker.Ulimits {
+			_, err := units.ParseUlimit(fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+			if err != nil {
+				addViolation(nodeGrpIdx, "docker.ulimits", fmt.Sprintf("invalid ulimit `%s`: %s", ulimit.Name, err))
+			}
+		}
+
+		for sysctlName := range nodeGrp.Docker.Sysctls {
+			if !isNamespacedSysctl(sysctlName) {
+				addViolation(nodeGrpIdx, "docker.sysctls", fmt.Sprintf("sysctl `%s` is not namespaced", sysctlName))
+			}
+		}
+
+		for _, volume := range nodeGrp.Docker.Volumes {
+			volumeParts := strings.Split(volume, ":")
+			if len(volumeParts) < 2 || len(volumeParts) > 3 || volumeParts[0] == "" || !path.IsAbs(volumeParts[1]) {
+				addViolation(nodeGrpIdx, "docker.volumes", fmt.Sprintf("invalid volume `%s`, expected source:target[:mode]", volume))
+			}
+		}
+
+		if nodeGrp.Docker.MemoryLimitMB < 0 {
+			addViolation(nodeGrpIdx, "docker.memory-limit", "memory limit cannot be negative")
+		}
+	}
+
+	return violations
+}
+
+func (d *Deployer) ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]deployment.DefinitionViolation, error) {
+	violations := deployment.ValidateCommonDefinition(def)
+	violations = append(violations, d.validateRuntimeOpts(def.NodeGroups)...)
+
+	if def.Docker.License.File != "" {
+		if _, err := os.Stat(def.Docker.License.File); err != nil {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   "docker.license.file",
+				Message: fmt.Sprintf("cannot read license file: %s", err),
+			})
+		}
+	}
+
+	if def.Docker.Serverless != (clusterdef.ServerlessSettings{}) && !isServerlessDef(def) {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "docker.serverless",
+			Message: "serverless settings require a serverless version, such as `7.2.0-serverless`",
+		})
+	}
+
+	if def.Docker.Snapshot != "" && !strings.ContainsAny(def.Docker.Snapshot, "/:") &&
+		!snapshotNameRegexp.MatchString(def.Docker.Snapshot) {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "docker.snapshot",
+			Message: fmt.Sprintf("invalid snapshot name `%s`", def.Docker.Snapshot),
+		})
+	}
+
+	blobStorage := def.Docker.Analytics.BlobStorage
+	switch blobStorage.Provider {
+	case "", clusterdef.BlobStorageProviderS3Mock, clusterdef.BlobStorageProviderMinio:
+	default:
+		violations = append(violations, deployment.DefinitionViolation{
+			Field: "docker.analytics.blob-storage.provider",
+			Message: fmt.Sprintf("unknown blob storage provider `%s`, expected %s or %s",
+				blobStorage.Provider, clusterdef.BlobStorageProviderS3Mock, clusterdef.BlobStorageProviderMinio),
+		})
+	}
+	if blobStorage.Provider != "" && blobStorage.Endpoint != "" {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "docker.analytics.blob-storage.provider",
+			Message: "a blob storage provider cannot be combined with an endpoint",
+		})
+	}
+	if (blobStorage.AccessKey == "") != (blobStorage.SecretKey == "") {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "docker.analytics.blob-storage.access-key",
+			Message: "an access key and secret key must be specified together",
+		})
+	}
+	if blobStorage.Provider == clusterdef.BlobStorageProviderMinio &&
+		blobStorage.SecretKey != "" && len(blobStorage.SecretKey) < 8 {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "docker.analytics.blob-storage.secret-key",
+			Message: "minio requires a secret key of at least 8 characters",
+		})
+	}
+
+	hasUnknownHost := false
+	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
+		if nodeGrp.Docker.Host != "" && d.findHost(nodeGrp.Docker.Host) == nil {
+			violations = append(violations, deployment.DefinitionViolation{
+				Field: deployment.NodeGroupField(nodeGrpIdx, "docker.host"),
+				Message: fmt.Sprintf("unknown docker host `%s`, expected one of %s",
+					nodeGrp.Docker.Host, strings.Join(d.HostNames(), ", ")),
+			})
+			hasUnknownHost = true
+		}
+	}
+	if hasUnknownHost {
+		// memory can only be checked once every node can be placed
+		return violations, nil
+	}
+
+	nodeGrpHosts, err := d.placeNodes(def.NodeGroups, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to place nodes")
+	}
+
+	hostMemoryMB := make(map[*dockerHost]int)
+	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
+		// nodes with a memory limit can use up to all of it
+		nodeMemoryMB := d.estimateNodeMemoryMB(def, nodeGrp)
+		if nodeGrp.Docker.MemoryLimitMB > 0 {
+			nodeMemoryMB = nodeGrp.Docker.MemoryLimitMB
+		}
+
+		for _, host := range nodeGrpHosts[nodeGrpIdx] {
+			hostMemoryMB[host] += nodeMemoryMB
+		}
+	}
+
+	for _, host := range d.hosts {
+		requiredMemoryMB, ok := hostMemoryMB[host]
+		if !ok {
+			continue
+		}
+
+		dockerInfo, err := host.DockerCli.Info(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch docker info for host `%s`", host.Name)
+		}
+
+		availableMemoryMB := int(dockerInfo.MemTotal / 1024 / 1024)
+		if requiredMemoryMB > availableMemoryMB {
+			message := fmt.Sprintf(
+				"cluster requires at least %dMB of memory but docker only has %dMB available",
+				requiredMemoryMB, availableMemoryMB)
+			if len(d.hosts) > 1 {
+				message = fmt.Sprintf(
+					"nodes placed on docker host `%s` require at least %dMB of memory but it only has %dMB available",
+					host.Name, requiredMemoryMB, availableMemoryMB)
+			}
+
+			violations = append(violations, deployment.DefinitionViolation{
+				Field:   "nodes",
+				Message: message,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (_ deployment.ClusterInfo, retErr error) {
 	if def.Columnar {
 		for _, nodeGrp := range def.NodeGroups {
 			if len(nodeGrp.Services) != 0 {
-				return nil, errors.New("columnar clusters cannot specify services")
+				return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+					errors.New("columnar clusters cannot specify services"))
 			}
 
 			nodeGrp.Services = []clusterdef.Service{
@@ -197,71 +612,118 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		}
 	}
 
-	clusterID := uuid.NewString()
+	runtimeViolations := d.validateRuntimeOpts(def.NodeGroups)
+	if len(runtimeViolations) > 0 {
+		return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+			fmt.Errorf("invalid docker options for %s: %s",
+				runtimeViolations[0].Field, runtimeViolations[0].Message))
+	}
 
-	if def.Columnar {
-		d.logger.Info("deploying mock s3 for blob storage")
+	d.warnMemoryLimits(def, def.NodeGroups)
 
-		d.logger.Debug("deploying s3mock container")
+	versionViolations := deployment.ValidateNodeGroupVersions(def.NodeGroups)
+	if len(versionViolations) > 0 {
+		return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+			fmt.Errorf("invalid versions for %s: %s",
+				versionViolations[0].Field, versionViolations[0].Message))
+	}
 
-		node, err := d.controller.DeployS3MockNode(ctx, clusterID, def.Expiry)
+	// columnar versions are numbered independently of Couchbase Server
+	if !def.Columnar {
+		err := deployment.CheckNodeGroupFeatures(def.NodeGroups)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to deploy s3mock node")
+			return nil, err
 		}
+	}
+
+	license, err := d.resolveLicense(def.Docker.License)
+	if err != nil {
+		return nil, err
+	}
 
-		d.logger.Debug("creating columnar bucket")
+	if def.Docker.Snapshot != "" {
+		progress.Step(ctx, "gathering snapshot")
 
-		bucketName := "columnar"
-		req, err := http.NewRequest(
-			"PUT",
-			fmt.Sprintf("http://%s:9090/%s/", node.IPAddress, bucketName),
-			nil)
+		// the snapshot is checked up front on the default host, before the
+		// nodes are placed and it is fetched onto the host it is restored on
+		_, snapshot, err := d.getSnapshot(ctx, d.hosts[0], def.Docker.Snapshot)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create columnar s3 bucket request")
+			return nil, err
 		}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create columnar s3 bucket")
-		}
-		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("non-200 status code when creating columnar s3 bucket (code: %d)", resp.StatusCode)
-		}
+		d.logger.Info("cluster will be started from snapshot",
+			zap.String("snapshot", snapshot.Name),
+			zap.String("version", snapshot.ServerVersion),
+			zap.Strings("buckets", snapshot.Buckets))
+	}
 
-		d.logger.Info("s3 mock is ready")
+	clusterID := uuid.NewString()
+	deployment.TrackPartialCluster(ctx, clusterID)
+
+	nodeGrpHosts, err := d.placeNodes(def.NodeGroups, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to place nodes")
+	}
+
+	var blobStorageEnvVars map[string]string
+	if def.Columnar {
+		// the blob storage is placed with the first node, as the nodes
+		// reach it by its address on the docker network of its host
+		blobStorageHost := d.hosts[0]
+		for _, hosts := range nodeGrpHosts {
+			if len(hosts) > 0 {
+				blobStorageHost = hosts[0]
+				break
+			}
+		}
 
-		def.Docker.Analytics.BlobStorage = clusterdef.AnalyticsBlobStorageSettings{
-			Region:        "local",
-			Bucket:        "columnar",
-			Scheme:        "s3",
-			Endpoint:      fmt.Sprintf("http://%s:9090", node.IPAddress),
-			AnonymousAuth: true,
+		blobStorage, envVars, err := d.deployColumnarBlobStorage(ctx, blobStorageHost, clusterID, def)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to prepare blob storage")
 		}
+
+		def.Docker.Analytics.BlobStorage = *blobStorage
+		blobStorageEnvVars = envVars
 	}
 
+	progress.Step(ctx, "gathering node images")
 	d.logger.Info("gathering node images")
 
-	nodeGrpImages, err := d.getImagesForNodeGrps(ctx, def.NodeGroups, def.Columnar)
+	hostImages, err := d.getHostImagesForNodeGrps(ctx, def.NodeGroups, nodeGrpHosts, def.Columnar)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch images")
 	}
 
+	progress.Step(ctx, "deploying nodes")
 	d.logger.Info("deploying nodes")
 
 	nodes := make([]*NodeInfo, 0)
 	leaveNodesAfterReturn := false
 	cleanupNodes := func() {
 		if !leaveNodesAfterReturn {
+			// the nodes are removed even when we were interrupted
+			cleanupCtx := context.WithoutCancel(ctx)
+
 			for _, node := range nodes {
 				if node != nil {
-					d.controller.RemoveNode(ctx, node.ContainerID)
+					d.getHost(node.HostName).Controller.RemoveNode(cleanupCtx, node.ContainerID)
 				}
 			}
 		}
 	}
 	defer cleanupNodes()
 
+	// diagnostics are captured for any failure, whether or not the nodes are
+	// then removed, and before they are removed since this is deferred later
+	defer func() {
+		if retErr != nil {
+			retErr = deployment.WrapProvisionTimeout(retErr)
+			d.captureDiagnostics(ctx, clusterID, def, nodes, retErr)
+		}
+	}()
+
 	var nodeOpts []*DeployNodeOptions
+	var nodeHosts []*dockerHost
 	var nodeNodeGrps []*clusterdef.NodeGroup
 	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
 		// We grab the number of nodes to allocate and copy the group out
@@ -273,29 +735,41 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		for grpNodeIdx := 0; grpNodeIdx < numNodes; grpNodeIdx++ {
 			d.logger.Info("deploying", zap.Any("nodeGrp", nodeGrp))
 
-			image := nodeGrpImages[nodeGrpIdx]
+			host := nodeGrpHosts[nodeGrpIdx][grpNodeIdx]
+			image := hostImages[host][nodeGrpIdx]
 
 			deployOpts := &DeployNodeOptions{
 				Purpose:            def.Purpose,
 				ClusterID:          clusterID,
+				NodeGroup:          nodeGrp.Name,
 				Image:              image,
 				ImageServerVersion: nodeGrp.Version,
 				IsColumnar:         def.Columnar,
 				Expiry:             def.Expiry,
-				EnvVars:            nodeGrp.Docker.EnvVars,
+				EnvVars:            mergeEnvVars(blobStorageEnvVars, nodeGrp.Docker.EnvVars),
+				Ulimits:            nodeGrp.Docker.Ulimits,
+				Sysctls:            nodeGrp.Docker.Sysctls,
+				Volumes:            nodeGrp.Docker.Volumes,
+				Args:               nodeGrp.Docker.Args,
+				OsTuning:           nodeGrp.Docker.OsTuning,
+				MemoryLimitMB:      nodeGrp.Docker.MemoryLimitMB,
+				License:            license,
 			}
 
 			nodeOpts = append(nodeOpts, deployOpts)
+			nodeHosts = append(nodeHosts, host)
 			nodeNodeGrps = append(nodeNodeGrps, nodeGrp)
 		}
 	}
 
 	waitCh := make(chan error)
-	for _, deployOpts := range nodeOpts {
-		go func(deployOpts *DeployNodeOptions) {
-			d.logger.Info("deploying node", zap.Any("deployOpts", deployOpts))
+	for nodeIdx, deployOpts := range nodeOpts {
+		go func(host *dockerHost, deployOpts *DeployNodeOptions) {
+			d.logger.Info("deploying node",
+				zap.String("host", host.Name),
+				zap.Any("deployOpts", deployOpts))
 
-			node, err := d.controller.DeployNode(ctx, deployOpts)
+			node, err := host.Controller.DeployNode(ctx, deployOpts)
 			if err != nil {
 				waitCh <- errors.Wrap(err, "failed to deploy a node")
 				return
@@ -308,13 +782,15 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 
 			nodes = append(nodes, node)
 			waitCh <- nil
-		}(deployOpts)
+		}(nodeHosts[nodeIdx], deployOpts)
 	}
-	for range nodeOpts {
+	for nodeIdx := range nodeOpts {
 		err := <-waitCh
 		if err != nil {
 			return nil, err
 		}
+
+		progress.NodesReady(ctx, nodeIdx+1, len(nodeOpts))
 	}
 
 	d.logger.Info("nodes deployed", zap.String("cluster", clusterID))
@@ -352,12 +828,7 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 
 		services := nodeGrp.Services
 		if len(services) == 0 {
-			services = []clusterdef.Service{
-				clusterdef.KvService,
-				clusterdef.IndexService,
-				clusterdef.QueryService,
-				clusterdef.SearchService,
-			}
+			services = defaultNodeServices
 		}
 
 		nsServices, err := clusterdef.ServicesToNsServices(services)
@@ -369,6 +840,7 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 			Address:     node.IPAddress,
 			ServerGroup: nodeGrp.ServerGroup,
 			Services:    nsServices,
+			Endpoint:    d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
 		})
 	}
 
@@ -477,6 +949,7 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		AnalyticsSettings:     analyticsSettings,
 	}
 
+	progress.Step(ctx, "initializing cluster")
 	clusterMgr := clustercontrol.ClusterManager{
 		Logger: d.logger,
 	}
@@ -485,15 +958,135 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		return nil, errors.Wrap(err, "failed to setup cluster")
 	}
 
+	for _, node := range nodes {
+		err := d.setupAlternateAddresses(ctx, node.HostName, node.IPAddress, node.ClientIPAddress, node.PublishedPorts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isServerlessDef(def) {
+		err := d.setupServerless(ctx, clusterID, &def.Docker.Serverless)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to setup serverless settings")
+		}
+	}
+
+	if def.Docker.Snapshot != "" {
+		progress.Step(ctx, "restoring snapshot")
+		d.logger.Info("restoring snapshot", zap.String("snapshot", def.Docker.Snapshot))
+
+		err := d.restoreSnapshot(ctx, def.Docker.Snapshot, nodes[0], username, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if def.Docker.Mobile.SyncGatewayVersion != "" {
+		mobileNodes, err := d.deployMobile(ctx, clusterID, def, nodes[0], username, password)
+		nodes = append(nodes, mobileNodes...)
+		if err != nil {
+			// the cluster is unusable without the mobile stack it asked for,
+			// so the cluster and any mobile nodes which did start are removed
+			leaveNodesAfterReturn = false
+			return nil, errors.Wrap(err, "failed to deploy mobile stack")
+		}
+	}
+
+	progress.Complete(ctx)
+
 	return thisCluster, nil
 }
 
+func (d *Deployer) nodeAddress(hostName string, ipAddress string, publishedPorts map[int]int, port int) string {
+	return d.getHost(hostName).Controller.NodeAddress(ipAddress, publishedPorts, port)
+}
+
+func (d *Deployer) mgmtEndpoint(hostName string, ipAddress string, publishedPorts map[int]int) string {
+	return "http://" + d.nodeAddress(hostName, ipAddress, publishedPorts, 8091)
+}
+
+// setupAlternateAddresses registers the published ports of a node as its
+// external alternate addresses, so that SDKs connecting through the
+// advertise address with network=external are directed to reachable ports.
+// Without an advertise address, nodes attached to a client network instead
+// register their client network address.
+func (d *Deployer) setupAlternateAddresses(ctx context.Context, hostName string, ipAddress string, clientIPAddress string, publishedPorts map[int]int) error {
+	advertiseAddress := d.getHost(hostName).Controller.AdvertiseAddress
+	if advertiseAddress == "" && clientIPAddress == "" {
+		return nil
+	}
+
+	altHostname := advertiseAddress
+	altPorts := make(map[string]int)
+	if advertiseAddress != "" {
+		for portName, port := range clustercontrol.AlternateAddressPorts {
+			if publicPort, ok := publishedPorts[port]; ok {
+				altPorts[portName] = publicPort
+			}
+		}
+	} else {
+		altHostname = clientIPAddress
+	}
+
+	nodeCtrl := clustercontrol.NodeManager{
+		Endpoint: d.mgmtEndpoint(hostName, ipAddress, publishedPorts),
+	}
+	err := nodeCtrl.Controller().SetupAlternateAddresses(ctx, &clustercontrol.SetupAlternateAddressesOptions{
+		Hostname: altHostname,
+		Ports:    altPorts,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to setup alternate addresses")
+	}
+
+	return nil
+}
+
 type deployedNodeInfo struct {
-	ContainerID string
-	IPAddress   string
-	OTPNode     string
-	Version     string
-	Services    []clusterdef.Service
+	ContainerID     string
+	NodeID          string
+	NodeGroup       string
+	IPAddress       string
+	ClientIPAddress string
+	PublishedPorts  map[int]int
+	HostName        string
+	OTPNode         string
+	Version         string
+	NsVersion       string
+	Services        []clusterdef.Service
+	ServerGroup     string
+	RuntimeOpts     *NodeRuntimeOptions
+}
+
+// nodeRuntimeOpts recovers the docker options a node was deployed with from
+// its recorded runtime options, reading the values of its environment
+// variables back from its container.
+func (d *Deployer) nodeRuntimeOpts(ctx context.Context, node *deployedNodeInfo) (clusterdef.DockerNodeGroup, error) {
+	if node.RuntimeOpts == nil {
+		return clusterdef.DockerNodeGroup{}, nil
+	}
+
+	var envVars map[string]string
+	if len(node.RuntimeOpts.EnvVarNames) > 0 {
+		var err error
+		envVars, err = d.getHost(node.HostName).Controller.ReadNodeEnvVars(
+			ctx, node.ContainerID, node.RuntimeOpts.EnvVarNames)
+		if err != nil {
+			return clusterdef.DockerNodeGroup{}, errors.Wrap(err, "failed to read node environment")
+		}
+	}
+
+	return clusterdef.DockerNodeGroup{
+		EnvVars:  envVars,
+		Ulimits:  node.RuntimeOpts.Ulimits,
+		Sysctls:  node.RuntimeOpts.Sysctls,
+		Volumes:  node.RuntimeOpts.Volumes,
+		Args:     node.RuntimeOpts.Args,
+		OsTuning: node.RuntimeOpts.OsTuning,
+
+		MemoryLimitMB: node.RuntimeOpts.MemoryLimitMB,
+	}, nil
 }
 
 type deployedClusterInfo struct {
@@ -505,7 +1098,7 @@ type deployedClusterInfo struct {
 }
 
 func (d *Deployer) getClusterInfo(ctx context.Context, clusterID string) (*deployedClusterInfo, error) {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list nodes")
 	}
@@ -528,12 +1121,21 @@ func (d *Deployer) getClusterInfo(ctx context.Context, clusterID string) (*deplo
 				expiry = node.Expiry
 			}
 
+			// sidecars such as the sync gateway are not part of the cluster
+			// topology, so they are not included in the definition
+			if node.Type == "sync-gateway" || node.Type == "mobile-app" ||
+				node.Type == "s3mock" || node.Type == "minio" {
+				continue
+			}
+
 			var otpNode string
 			var services []clusterdef.Service
+			var nsVersion string
+			var serverGroup string
 
 			if node.Type == "server-node" || node.Type == "columnar-node" {
 				nodeCtrl := clustercontrol.NodeManager{
-					Endpoint: fmt.Sprintf("http://%s:8091", node.IPAddress),
+					Endpoint: d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
 				}
 				thisNodeInfo, err := nodeCtrl.Controller().GetLocalInfo(ctx)
 				if err != nil {
@@ -546,14 +1148,24 @@ func (d *Deployer) getClusterInfo(ctx context.Context, clusterID string) (*deplo
 				}
 
 				otpNode = thisNodeInfo.OTPNode
+				nsVersion = thisNodeInfo.Version
+				serverGroup = thisNodeInfo.ServerGroup
 			}
 
 			nodeInfo = append(nodeInfo, &deployedNodeInfo{
-				ContainerID: node.ContainerID,
-				IPAddress:   node.IPAddress,
-				OTPNode:     otpNode,
-				Version:     node.InitialServerVersion,
-				Services:    services,
+				ContainerID:     node.ContainerID,
+				NodeID:          node.NodeID,
+				NodeGroup:       node.NodeGroup,
+				IPAddress:       node.IPAddress,
+				ClientIPAddress: node.ClientIPAddress,
+				PublishedPorts:  node.PublishedPorts,
+				HostName:        node.HostName,
+				OTPNode:         otpNode,
+				Version:         node.InitialServerVersion,
+				NsVersion:       nsVersion,
+				Services:        services,
+				ServerGroup:     serverGroup,
+				RuntimeOpts:     node.RuntimeOpts,
 			})
 		}
 	}
@@ -567,6 +1179,41 @@ func (d *Deployer) getClusterInfo(ctx context.Context, clusterID string) (*deplo
 	}, nil
 }
 
+// nsVersionToVersion converts a version reported by ns_server, such as
+// `7.2.0-5325-enterprise`, into the form used by cluster definitions.
+func nsVersionToVersion(nsVersion string) string {
+	if strings.HasSuffix(nsVersion, "-community") {
+		return "community-" + strings.TrimSuffix(nsVersion, "-community")
+	}
+	return strings.TrimSuffix(nsVersion, "-enterprise")
+}
+
+// checkClusterFeature checks that all the nodes of a cluster support a
+// feature before it is used, so that older servers produce an actionable
+// error rather than an opaque failure from the server.
+func (d *Deployer) checkClusterFeature(ctx context.Context, clusterID string, feature deployment.Feature) error {
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster info")
+	}
+
+	// columnar versions are numbered independently of Couchbase Server
+	if clusterInfo.IsColumnar {
+		return nil
+	}
+
+	var versions []string
+	for _, node := range clusterInfo.Nodes {
+		version := node.Version
+		if version == "" && node.NsVersion != "" {
+			version = nsVersionToVersion(node.NsVersion)
+		}
+		versions = append(versions, version)
+	}
+
+	return deployment.CheckClusterFeature(versions, feature)
+}
+
 func (d *Deployer) GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error) {
 	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
 	if err != nil {
@@ -576,20 +1223,69 @@ func (d *Deployer) GetDefinition(ctx context.Context, clusterID string) (*cluste
 	var nodeGroups []*clusterdef.NodeGroup
 
 	for _, node := range clusterInfo.Nodes {
-		nodeGroups = append(nodeGroups, &clusterdef.NodeGroup{
+		// nodes deployed from a custom image have no recorded version, so
+		// we fall back to the version the node reports
+		version := node.Version
+		if version == "" {
+			version = nsVersionToVersion(node.NsVersion)
+		}
+
+		nodeGroup := &clusterdef.NodeGroup{
+			Name:     node.NodeGroup,
 			Count:    1,
-			Version:  node.Version,
+			Version:  version,
 			Services: node.Services,
-		})
+		}
+
+		nodeGroup.Docker, err = d.nodeRuntimeOpts(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+
+		// the host is only recorded when there is a choice of hosts, so
+		// that single host definitions are unchanged
+		if len(d.hosts) > 1 {
+			nodeGroup.Docker.Host = d.getHost(node.HostName).Name
+		}
+
+		nodeGroups = append(nodeGroups, nodeGroup)
 	}
 
-	return &clusterdef.Cluster{
+	def := &clusterdef.Cluster{
 		Purpose:    clusterInfo.Purpose,
 		NodeGroups: nodeGroups,
-	}, nil
+	}
+
+	// the quotas are only included when the cluster can be reached, so that
+	// the definition of a cluster which is down can still be fetched
+	if len(clusterInfo.Nodes) > 0 {
+		ctrlNode := clusterInfo.Nodes[0]
+		nodeCtrl := &clustercontrol.NodeManager{
+			Endpoint: d.mgmtEndpoint(ctrlNode.HostName, ctrlNode.IPAddress, ctrlNode.PublishedPorts),
+		}
+
+		quotas, err := nodeCtrl.Controller().GetMemoryQuotas(ctx)
+		if err != nil {
+			d.logger.Debug("failed to get memory quotas", zap.Error(err))
+		} else {
+			def.Docker.KvMemoryMB = quotas.KvMemoryQuotaMB
+			def.Docker.IndexMemoryMB = quotas.IndexMemoryQuotaMB
+			def.Docker.FtsMemoryMB = quotas.FtsMemoryQuotaMB
+			def.Docker.CbasMemoryMB = quotas.CbasMemoryQuotaMB
+			def.Docker.EventingMemoryMB = quotas.EventingMemoryQuotaMB
+		}
+	}
+
+	return def, nil
 }
 
 func (d *Deployer) UpdateClusterExpiry(ctx context.Context, clusterID string, newExpiryTime time.Time) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "UpdateClusterExpiry")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster info")
@@ -600,7 +1296,7 @@ func (d *Deployer) UpdateClusterExpiry(ctx context.Context, clusterID string, ne
 	}
 
 	for _, node := range clusterInfo.Nodes {
-		err := d.controller.UpdateExpiry(ctx, node.ContainerID, newExpiryTime)
+		err := d.getHost(node.HostName).Controller.UpdateExpiry(ctx, node.ContainerID, newExpiryTime)
 		if err != nil {
 			return errors.Wrap(err, "failed to update node expiry")
 		}
@@ -614,6 +1310,7 @@ func (d *Deployer) addRemoveNodes(
 	clusterInfo *deployedClusterInfo,
 	nodesToAdd []*clusterdef.NodeGroup,
 	nodesToRemove []*deployedNodeInfo,
+	license *NodeLicense,
 ) ([]string, error) {
 	if len(nodesToRemove) == 0 && len(nodesToAdd) == 0 {
 		return nil, nil
@@ -625,36 +1322,69 @@ func (d *Deployer) addRemoveNodes(
 		zap.String("address", ctrlNode.IPAddress))
 
 	nodeCtrl := clustercontrol.NodeManager{
-		Endpoint: fmt.Sprintf("http://%s:8091", ctrlNode.IPAddress),
+		Endpoint: d.mgmtEndpoint(ctrlNode.HostName, ctrlNode.IPAddress, ctrlNode.PublishedPorts),
 	}
 
-	d.logger.Info("gathering node images")
+	runtimeViolations := d.validateRuntimeOpts(nodesToAdd)
+	if len(runtimeViolations) > 0 {
+		return nil, deployment.NewError(deployment.ErrInvalidDefinition,
+			fmt.Errorf("invalid docker options for %s: %s",
+				runtimeViolations[0].Field, runtimeViolations[0].Message))
+	}
+
+	var existingHostNames []string
+	for _, node := range clusterInfo.Nodes {
+		if !slices.Contains(nodesToRemove, node) {
+			existingHostNames = append(existingHostNames, node.HostName)
+		}
+	}
+
+	nodesToAddHosts, err := d.placeNodes(nodesToAdd, existingHostNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to place nodes")
+	}
 
-	nodesToAddImages, err := d.getImagesForNodeGrps(ctx, nodesToAdd, clusterInfo.IsColumnar)
+	progress.Step(ctx, "gathering node images")
+	d.logger.Info("gathering node images")
+
+	hostImages, err := d.getHostImagesForNodeGrps(ctx, nodesToAdd, nodesToAddHosts, clusterInfo.IsColumnar)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch images")
 	}
 
+	progress.Step(ctx, "deploying new nodes")
 	d.logger.Info("deploying new node containers")
 
 	var deployedNodeIds []string
+	var deployedNodes []*NodeInfo
 	var setupNodeOpts []*clustercontrol.AddNodeOptions
 	for nodeGrpIdx, nodeGrp := range nodesToAdd {
-		image := nodesToAddImages[nodeGrpIdx]
+		host := nodesToAddHosts[nodeGrpIdx][0]
+		image := hostImages[host][nodeGrpIdx]
 
 		deployOpts := &DeployNodeOptions{
 			Purpose:            clusterInfo.Purpose,
 			ClusterID:          clusterInfo.ID,
+			NodeGroup:          nodeGrp.Name,
 			Image:              image,
 			ImageServerVersion: nodeGrp.Version,
 			IsColumnar:         clusterInfo.IsColumnar,
 			Expiry:             time.Until(clusterInfo.Expiry),
 			EnvVars:            nodeGrp.Docker.EnvVars,
+			Ulimits:            nodeGrp.Docker.Ulimits,
+			Sysctls:            nodeGrp.Docker.Sysctls,
+			Volumes:            nodeGrp.Docker.Volumes,
+			Args:               nodeGrp.Docker.Args,
+			OsTuning:           nodeGrp.Docker.OsTuning,
+			MemoryLimitMB:      nodeGrp.Docker.MemoryLimitMB,
+			License:            license,
 		}
 
-		d.logger.Info("deploying node", zap.Any("deployOpts", deployOpts))
+		d.logger.Info("deploying node",
+			zap.String("host", host.Name),
+			zap.Any("deployOpts", deployOpts))
 
-		node, err := d.controller.DeployNode(ctx, deployOpts)
+		node, err := host.Controller.DeployNode(ctx, deployOpts)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to deploy a node")
 		}
@@ -673,8 +1403,10 @@ func (d *Deployer) addRemoveNodes(
 		})
 
 		deployedNodeIds = append(deployedNodeIds, node.NodeID)
+		deployedNodes = append(deployedNodes, node)
 	}
 
+	progress.Step(ctx, "registering new nodes")
 	d.logger.Info("registering new nodes")
 
 	for _, addNodeOpts := range setupNodeOpts {
@@ -702,9 +1434,10 @@ func (d *Deployer) addRemoveNodes(
 		zap.String("address", ctrlNode.IPAddress))
 
 	nodeCtrl = clustercontrol.NodeManager{
-		Endpoint: fmt.Sprintf("http://%s:8091", ctrlNode.IPAddress),
+		Endpoint: d.mgmtEndpoint(ctrlNode.HostName, ctrlNode.IPAddress, ctrlNode.PublishedPorts),
 	}
 
+	progress.Step(ctx, "rebalancing")
 	d.logger.Info("initiating rebalance")
 
 	err = nodeCtrl.Rebalance(ctx, otpsToRemove)
@@ -719,17 +1452,33 @@ func (d *Deployer) addRemoveNodes(
 		return nil, errors.Wrap(err, "failed to wait for tasks to complete")
 	}
 
+	for _, node := range deployedNodes {
+		err := d.setupAlternateAddresses(ctx, node.HostName, node.IPAddress, node.ClientIPAddress, node.PublishedPorts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(nodesToRemove) > 0 {
+		progress.Step(ctx, "removing old nodes")
+	}
 	for _, node := range nodesToRemove {
 		d.logger.Info("removing node",
 			zap.String("container", node.ContainerID))
 
-		d.controller.RemoveNode(ctx, node.ContainerID)
+		d.getHost(node.HostName).Controller.RemoveNode(ctx, node.ContainerID)
 	}
 
 	return deployedNodeIds, nil
 }
 
 func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clusterdef.Cluster) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "ModifyCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster info")
@@ -739,59 +1488,175 @@ func (d *Deployer) ModifyCluster(ctx context.Context, clusterID string, def *clu
 		return errors.New("cannot modify a cluster with no nodes")
 	}
 
+	versionViolations := deployment.ValidateNodeGroupVersions(def.NodeGroups)
+	if len(versionViolations) > 0 {
+		return deployment.NewError(deployment.ErrInvalidDefinition,
+			fmt.Errorf("invalid versions for %s: %s",
+				versionViolations[0].Field, versionViolations[0].Message))
+	}
+
+	if !clusterInfo.IsColumnar {
+		err := deployment.CheckNodeGroupFeatures(def.NodeGroups)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(def.NodeGroups) > 0 {
-		nodesToRemove := clusterInfo.Nodes
-		nodesToAdd := []*clusterdef.NodeGroup{}
+		nodesToAdd, nodesToRemove := d.diffNodeGroups(clusterInfo, def.NodeGroups, nil)
 
-		// build the list of individualized nodes we need
-		for _, nodeGrp := range def.NodeGroups {
-			numNodes := nodeGrp.Count
-			nodeGrp := to.Ptr(*nodeGrp)
-			nodeGrp.Count = 1
+		d.logger.Debug("identified nodes to add",
+			zap.Any("nodes", nodesToAdd))
+		d.logger.Debug("identified nodes to remove",
+			zap.Any("nodes", nodesToRemove))
 
-			for grpNodeIdx := 0; grpNodeIdx < numNodes; grpNodeIdx++ {
-				nodesToAdd = append(nodesToAdd, nodeGrp)
-			}
+		if len(nodesToAdd) == 0 && len(nodesToRemove) == 0 {
+			d.logger.Info("cluster already matches the definition")
+			return nil
 		}
 
-		// first iterate and find any exact matches and use those
-		nodesToAdd = slices.DeleteFunc(nodesToAdd, func(nodeGrp *clusterdef.NodeGroup) bool {
-			if nodeGrp.ForceNew {
-				return false
+		if d.dryRun {
+			for _, nodeGrp := range nodesToAdd {
+				d.logger.Info("dry-run: would deploy node",
+					zap.String("version", nodeGrp.Version),
+					zap.Any("services", nodeGrp.Services))
+			}
+			for _, node := range nodesToRemove {
+				d.logger.Info("dry-run: would remove node",
+					zap.String("container", node.ContainerID),
+					zap.String("otp", node.OTPNode))
 			}
+			if len(nodesToAdd) > 0 || len(nodesToRemove) > 0 {
+				d.logger.Info("dry-run: would rebalance cluster")
+			}
+			return nil
+		}
 
-			for nodeIdx, node := range nodesToRemove {
-				if node.Version != nodeGrp.Version {
-					continue
-				}
+		license, err := d.resolveLicense(def.Docker.License)
+		if err != nil {
+			return err
+		}
+
+		deployedNodeIds, err := d.addRemoveNodes(ctx, clusterInfo, nodesToAdd, nodesToRemove, license)
+		if err != nil {
+			err = deployment.WrapProvisionTimeout(err)
+			d.captureClusterDiagnostics(ctx, clusterID, def, err)
+			return err
+		}
+
+		progress.Step(ctx, "verifying cluster topology")
+
+		newClusterInfo, err := d.getClusterInfo(ctx, clusterID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cluster info after modification")
+		}
 
-				serviceCmp := clusterdef.CompareServices(node.Services, nodeGrp.Services)
-				if serviceCmp != 0 {
-					continue
+		remainingToAdd, remainingToRemove := d.diffNodeGroups(newClusterInfo, def.NodeGroups, deployedNodeIds)
+		if len(remainingToAdd) > 0 || len(remainingToRemove) > 0 {
+			return fmt.Errorf(
+				"cluster does not match the definition after modification, %d nodes are missing and %d are unexpected",
+				len(remainingToAdd), len(remainingToRemove))
+		}
+	}
+
+	return nil
+}
+
+// defaultNodeServices are the services of nodes whose node group does not
+// specify any.
+var defaultNodeServices = []clusterdef.Service{
+	clusterdef.KvService,
+	clusterdef.IndexService,
+	clusterdef.QueryService,
+	clusterdef.SearchService,
+}
+
+// diffNodeGroups compares the nodes of a cluster against the node groups of
+// a definition, returning the nodes which need to be deployed and the
+// existing nodes which need to be removed.  Existing nodes are kept when
+// their version, services, host and server group all match, since a node
+// cannot change any of these without being replaced.  Node groups which
+// force new nodes can only be matched by the nodes in newNodeIDs, which
+// are the nodes deployed by the modification being verified.
+func (d *Deployer) diffNodeGroups(
+	clusterInfo *deployedClusterInfo,
+	nodeGrps []*clusterdef.NodeGroup,
+	newNodeIDs []string,
+) ([]*clusterdef.NodeGroup, []*deployedNodeInfo) {
+	nodesToRemove := slices.Clone(clusterInfo.Nodes)
+	nodesToAdd := []*clusterdef.NodeGroup{}
+
+	// build the list of individualized nodes we need
+	for _, nodeGrp := range nodeGrps {
+		numNodes := nodeGrp.Count
+		nodeGrp := to.Ptr(*nodeGrp)
+		nodeGrp.Count = 1
+
+		// the services are made explicit so that they are compared with
+		// those of the existing nodes, and new nodes are deployed with them
+		if len(nodeGrp.Services) == 0 {
+			if clusterInfo.IsColumnar {
+				nodeGrp.Services = []clusterdef.Service{
+					clusterdef.KvService,
+					clusterdef.AnalyticsService,
 				}
+			} else {
+				nodeGrp.Services = defaultNodeServices
+			}
+		}
+
+		for grpNodeIdx := 0; grpNodeIdx < numNodes; grpNodeIdx++ {
+			nodesToAdd = append(nodesToAdd, nodeGrp)
+		}
+	}
 
-				nodesToRemove = slices.Delete(nodesToRemove, nodeIdx, nodeIdx+1)
-				return true
+	// first iterate and find any exact matches and use those
+	nodesToAdd = slices.DeleteFunc(nodesToAdd, func(nodeGrp *clusterdef.NodeGroup) bool {
+		for nodeIdx, node := range nodesToRemove {
+			if nodeGrp.ForceNew && !slices.Contains(newNodeIDs, node.NodeID) {
+				continue
 			}
 
-			return false
-		})
+			if node.Version != nodeGrp.Version {
+				continue
+			}
 
-		d.logger.Debug("identified nodes to add",
-			zap.Any("nodes", nodesToAdd))
-		d.logger.Debug("identified nodes to remove",
-			zap.Any("nodes", nodesToRemove))
+			serviceCmp := clusterdef.CompareServices(node.Services, nodeGrp.Services)
+			if serviceCmp != 0 {
+				continue
+			}
 
-		_, err := d.addRemoveNodes(ctx, clusterInfo, nodesToAdd, nodesToRemove)
-		if err != nil {
-			return err
+			if nodeGrp.Docker.Host != "" && nodeGrp.Docker.Host != d.getHost(node.HostName).Name {
+				continue
+			}
+
+			if nodeGrp.ServerGroup != "" && nodeGrp.ServerGroup != node.ServerGroup {
+				continue
+			}
+
+			// the group of a node is fixed when it is deployed, so a named
+			// group can only reuse the nodes which were deployed from it
+			if nodeGrp.Name != "" && nodeGrp.Name != node.NodeGroup {
+				continue
+			}
+
+			nodesToRemove = slices.Delete(nodesToRemove, nodeIdx, nodeIdx+1)
+			return true
 		}
-	}
 
-	return nil
+		return false
+	})
+
+	return nodesToAdd, nodesToRemove
 }
 
 func (d *Deployer) AddNode(ctx context.Context, clusterID string) (string, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "AddNode")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
 	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get cluster info")
@@ -810,13 +1675,19 @@ func (d *Deployer) AddNode(ctx context.Context, clusterID string) (string, error
 		}
 	}
 
+	// only the default license applies, as the definition is not known
+	license, err := d.resolveLicense(clusterdef.DockerLicense{})
+	if err != nil {
+		return "", err
+	}
+
 	nodeIds, err := d.addRemoveNodes(ctx, clusterInfo, []*clusterdef.NodeGroup{
 		{
 			Count:    1,
 			Version:  nodeVersion,
 			Services: nodeServices,
 		},
-	}, nil)
+	}, nil, license)
 	if err != nil {
 		return "", err
 	}
@@ -829,6 +1700,12 @@ func (d *Deployer) AddNode(ctx context.Context, clusterID string) (string, error
 }
 
 func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RemoveNode")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster info")
@@ -851,9 +1728,16 @@ func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID stri
 		return errors.Wrap(err, "failed to find deployed node")
 	}
 
+	if d.dryRun {
+		d.logger.Info("dry-run: would remove node and rebalance",
+			zap.String("container", foundNode.ContainerID),
+			zap.String("otp", foundNode.OTPNode))
+		return nil
+	}
+
 	_, err = d.addRemoveNodes(ctx, clusterInfo, nil, []*deployedNodeInfo{
 		foundNode,
-	})
+	}, nil)
 	if err != nil {
 		return err
 	}
@@ -861,19 +1745,148 @@ func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID stri
 	return nil
 }
 
+// SwapNode replaces a node with a new node running the same services in the
+// same server group and with the same docker options, using a swap
+// rebalance which adds the new node and ejects the old one at once.
+// The new node runs the same version as the old one unless newVersion is
+// specified, allowing clusters to be upgraded one node at a time.
+func (d *Deployer) SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "SwapNode")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get cluster info")
+	}
+
+	node, err := d.getNode(ctx, clusterID, oldNodeID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get node")
+	}
+
+	var oldNode *deployedNodeInfo
+	var nodeGrps []*clusterdef.NodeGroup
+	for _, clusterNode := range clusterInfo.Nodes {
+		if clusterNode.ContainerID == node.ContainerID {
+			oldNode = clusterNode
+			continue
+		}
+
+		nodeGrps = append(nodeGrps, &clusterdef.NodeGroup{
+			Count:   1,
+			Version: clusterNode.Version,
+		})
+	}
+	if oldNode == nil {
+		return "", errors.New("failed to find deployed node")
+	}
+
+	if newVersion == "" {
+		newVersion = oldNode.Version
+	}
+	if newVersion == "" {
+		return "", errors.New("node has no recorded version, so a version must be specified")
+	}
+
+	versionViolations := deployment.ValidateNodeGroupVersions(append(nodeGrps, &clusterdef.NodeGroup{
+		Count:   1,
+		Version: newVersion,
+	}))
+	if len(versionViolations) > 0 {
+		return "", deployment.NewError(deployment.ErrInvalidDefinition,
+			fmt.Errorf("cannot swap in version `%s`: %s", newVersion, versionViolations[0].Message))
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would swap rebalance node",
+			zap.String("container", oldNode.ContainerID),
+			zap.String("otp", oldNode.OTPNode),
+			zap.String("version", newVersion))
+		return "", nil
+	}
+
+	// only the default license applies, as the definition is not known
+	license, err := d.resolveLicense(clusterdef.DockerLicense{})
+	if err != nil {
+		return "", err
+	}
+
+	newNodeGrp, err := d.swapNodeGroup(ctx, oldNode, newVersion)
+	if err != nil {
+		return "", err
+	}
+
+	nodeIds, err := d.addRemoveNodes(ctx, clusterInfo, []*clusterdef.NodeGroup{
+		newNodeGrp,
+	}, []*deployedNodeInfo{
+		oldNode,
+	}, license)
+	if err != nil {
+		return "", err
+	}
+
+	if len(nodeIds) != 1 {
+		return "", errors.New("unexpected number of node ids returned")
+	}
+
+	return nodeIds[0], nil
+}
+
+// swapNodeGroup builds the node group for the node which replaces oldNode,
+// which is placed in the same server group and on the same host, and is
+// deployed with the same docker options.
+func (d *Deployer) swapNodeGroup(ctx context.Context, oldNode *deployedNodeInfo, newVersion string) (*clusterdef.NodeGroup, error) {
+	dockerOpts, err := d.nodeRuntimeOpts(ctx, oldNode)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerOpts.Host = d.getHost(oldNode.HostName).Name
+
+	return &clusterdef.NodeGroup{
+		Name:        oldNode.NodeGroup,
+		Count:       1,
+		Version:     newVersion,
+		Services:    oldNode.Services,
+		ServerGroup: oldNode.ServerGroup,
+		Docker:      dockerOpts,
+	}, nil
+}
+
+func (d *Deployer) removeNode(ctx context.Context, node *NodeInfo) {
+	if d.dryRun {
+		d.logger.Info("dry-run: would remove node",
+			zap.String("id", node.NodeID),
+			zap.String("container", node.ContainerID))
+		return
+	}
+
+	d.logger.Info("removing node",
+		zap.String("id", node.NodeID),
+		zap.String("container", node.ContainerID))
+
+	d.getHost(node.HostName).Controller.RemoveNode(ctx, node.ContainerID)
+}
+
 func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
-	nodes, err := d.controller.ListNodes(ctx)
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RemoveCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return err
 	}
 
+	progress.Step(ctx, "removing nodes")
 	for _, node := range nodes {
 		if node.ClusterID == clusterID {
-			d.logger.Info("removing node",
-				zap.String("id", node.NodeID),
-				zap.String("container", node.ContainerID))
-
-			d.controller.RemoveNode(ctx, node.ContainerID)
+			d.removeNode(ctx, node)
 		}
 	}
 
@@ -881,17 +1894,13 @@ func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
 }
 
 func (d *Deployer) RemoveAll(ctx context.Context) error {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, node := range nodes {
-		d.logger.Info("removing node",
-			zap.String("id", node.NodeID),
-			zap.String("container", node.ContainerID))
-
-		d.controller.RemoveNode(ctx, node.ContainerID)
+		d.removeNode(ctx, node)
 	}
 
 	return nil
@@ -910,7 +1919,7 @@ func (d *Deployer) getCluster(ctx context.Context, clusterID string) (*ClusterIn
 		}
 	}
 	if thisCluster == nil {
-		return nil, errors.New("failed to find cluster")
+		return nil, deployment.ErrClusterNotFound
 	}
 
 	return thisCluster, nil
@@ -926,67 +1935,153 @@ func (d *Deployer) GetConnectInfo(ctx context.Context, clusterID string) (*deplo
 	var connstrTlsAddrs []string
 	var mgmtAddr string
 	var mgmtTlsAddr string
+	var sgwAddr string
+	var sgwAdminAddr string
+	useExternalNetwork := false
 	for _, node := range thisCluster.Nodes {
+		if node.NodeID == syncGatewayNodeID {
+			sgwAddr = d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, syncGatewayPublicPort)
+			sgwAdminAddr = d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, syncGatewayAdminPort)
+		}
+
 		if !node.IsClusterNode() {
 			continue
 		}
 
-		kvPort := 11210
-		kvTlsPort := 11207
-		mgmtPort := 8091
-		mgmtTlsPort := 18091
-
-		if kvPort == 11210 {
-			connstrAddrs = append(connstrAddrs, node.IPAddress)
-		} else {
-			connstrAddrs = append(connstrAddrs, fmt.Sprintf("%s:%d", node.IPAddress, kvPort))
+		if d.getHost(node.HostName).Controller.AdvertiseAddress != "" {
+			useExternalNetwork = true
 		}
 
-		if kvTlsPort == 11207 {
-			connstrTlsAddrs = append(connstrTlsAddrs, node.IPAddress)
-		} else {
-			connstrTlsAddrs = append(connstrTlsAddrs, fmt.Sprintf("%s:%d", node.IPAddress, kvTlsPort))
+		// clients reach nodes which are attached to a client network through
+		// it, which the nodes know as their alternate address
+		ipAddress := node.IPAddress
+		if node.ClientIPAddress != "" {
+			ipAddress = node.ClientIPAddress
+			useExternalNetwork = true
 		}
 
-		mgmtAddr = fmt.Sprintf("%s:%d", node.IPAddress, mgmtPort)
-		mgmtTlsAddr = fmt.Sprintf("%s:%d", node.IPAddress, mgmtTlsPort)
+		kvAddr := d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 11210)
+		kvTlsAddr := d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 11207)
+
+		connstrAddrs = append(connstrAddrs, strings.TrimSuffix(kvAddr, ":11210"))
+		connstrTlsAddrs = append(connstrTlsAddrs, strings.TrimSuffix(kvTlsAddr, ":11207"))
+
+		mgmtAddr = d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 8091)
+		mgmtTlsAddr = d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 18091)
 	}
 
 	connStr := fmt.Sprintf("couchbase://%s", strings.Join(connstrAddrs, ","))
 	connStrTls := fmt.Sprintf("couchbases://%s", strings.Join(connstrTlsAddrs, ","))
+	if useExternalNetwork {
+		// published ports and client network addresses are only known to
+		// the cluster as alternate addresses
+		connStr += "?network=external"
+		connStrTls += "?network=external"
+	}
 	mgmt := fmt.Sprintf("http://%s", mgmtAddr)
 	mgmtTls := fmt.Sprintf("https://%s", mgmtTlsAddr)
 
-	return &deployment.ConnectInfo{
+	connectInfo := &deployment.ConnectInfo{
 		ConnStr:    connStr,
 		ConnStrTls: connStrTls,
 		Mgmt:       mgmt,
 		MgmtTls:    mgmtTls,
-	}, nil
+	}
+	if sgwAddr != "" {
+		connectInfo.SyncGateway = fmt.Sprintf("ws://%s/%s", sgwAddr, syncGatewayDatabase)
+		connectInfo.SyncGatewayAdmin = fmt.Sprintf("http://%s", sgwAdminAddr)
+	}
+
+	return connectInfo, nil
 }
 
 func (d *Deployer) Cleanup(ctx context.Context) error {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return err
 	}
 
 	curTime := time.Now()
+
+	// a cluster which has any notified node has already been notified, which
+	// stops nodes added after the notification from notifying it again.
+	notifiedClusters := make(map[string]bool)
+	for _, node := range nodes {
+		if node.ExpiryNotified {
+			notifiedClusters[node.ClusterID] = true
+		}
+	}
+
 	for _, node := range nodes {
-		if !node.Expiry.IsZero() && !node.Expiry.After(curTime) {
-			d.logger.Info("removing node",
+		if node.StateErr != nil {
+			d.logger.Warn("skipping cleanup of node with unknown expiry",
 				zap.String("id", node.NodeID),
-				zap.String("container", node.ContainerID))
+				zap.String("container", node.ContainerID),
+				zap.Error(node.StateErr))
+			continue
+		}
 
-			d.controller.RemoveNode(ctx, node.ContainerID)
+		expiryPhase := deployment.GetExpiryPhase(node.Expiry, d.gracePeriod, curTime)
+		if expiryPhase == deployment.ExpiryPhaseExpired {
+			d.removeNode(ctx, node)
+
+			deployment.RecordCleanupAction(ctx, &deployment.CleanupAction{
+				Type:      "node",
+				ID:        node.NodeID,
+				Name:      node.Name,
+				ClusterID: node.ClusterID,
+				Owner:     node.Creator,
+				CreatedAt: node.CreatedAt,
+				Expiry:    node.Expiry,
+				Reason:    "expired",
+				DryRun:    d.dryRun,
+			})
+		} else if expiryPhase == deployment.ExpiryPhaseExpiring && !node.ExpiryNotified {
+			d.markNodeExpiring(ctx, node, notifiedClusters)
 		}
 	}
 
 	return nil
 }
 
+func (d *Deployer) markNodeExpiring(ctx context.Context, node *NodeInfo, notifiedClusters map[string]bool) {
+	if d.dryRun {
+		d.logger.Info("dry-run: would mark node as expiring",
+			zap.String("id", node.NodeID),
+			zap.String("container", node.ContainerID))
+		return
+	}
+
+	// the notification is persisted first, as notifying without it would
+	// notify again on every following cleanup
+	err := d.getHost(node.HostName).Controller.WriteNodeState(ctx, node.ContainerID, &DockerNodeState{
+		Expiry:         node.Expiry,
+		ExpiryNotified: true,
+		Labels:         node.StateLabels,
+	})
+	if err != nil {
+		d.logger.Warn("failed to mark node as expiring",
+			zap.String("id", node.NodeID),
+			zap.Error(err))
+		return
+	}
+
+	if !notifiedClusters[node.ClusterID] {
+		d.logger.Warn("cluster has expired and will be removed after the grace period",
+			zap.String("cluster", node.ClusterID),
+			zap.Time("expiry", node.Expiry),
+			zap.Duration("gracePeriod", d.gracePeriod))
+
+		if d.onExpiring != nil {
+			d.onExpiring(ctx, node.ClusterID, node.Expiry)
+		}
+
+		notifiedClusters[node.ClusterID] = true
+	}
+}
+
 func (d *Deployer) DestroyAllResources(ctx context.Context) error {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to list all nodes")
 	}
@@ -996,7 +2091,7 @@ func (d *Deployer) DestroyAllResources(ctx context.Context) error {
 			zap.String("id", node.NodeID),
 			zap.String("container", node.ContainerID))
 
-		err := d.controller.RemoveNode(ctx, node.ContainerID)
+		err := d.getHost(node.HostName).Controller.RemoveNode(ctx, node.ContainerID)
 		if err != nil {
 			return errors.Wrap(err, "failed to remove")
 		}
@@ -1005,6 +2100,16 @@ func (d *Deployer) DestroyAllResources(ctx context.Context) error {
 	return nil
 }
 
+// lockCluster acquires the lock for a cluster for an operation which modifies
+// it, preventing concurrent invocations from interleaving their changes.
+func (d *Deployer) lockCluster(ctx context.Context, clusterID string, operation string) (context.Context, func(), error) {
+	if d.clusterLocker == nil {
+		return ctx, func() {}, nil
+	}
+
+	return d.clusterLocker.Lock(ctx, clusterID, operation)
+}
+
 func (d *Deployer) getController(ctx context.Context, clusterID string) (*clustercontrol.NodeManager, error) {
 	clusterInfo, err := d.getCluster(ctx, clusterID)
 	if err != nil {
@@ -1012,7 +2117,7 @@ func (d *Deployer) getController(ctx context.Context, clusterID string) (*cluste
 	}
 
 	nodeCtrl := &clustercontrol.NodeManager{
-		Endpoint: fmt.Sprintf("http://%s:8091", clusterInfo.Nodes[0].IPAddress),
+		Endpoint: d.mgmtEndpoint(clusterInfo.Nodes[0].HostName, clusterInfo.Nodes[0].IPAddress, clusterInfo.Nodes[0].PublishedPorts),
 	}
 
 	return nodeCtrl, nil
@@ -1024,8 +2129,9 @@ func (d *Deployer) getAgent(ctx context.Context, clusterID string, bucketName st
 		return nil, errors.Wrap(err, "failed to get cluster info")
 	}
 
-	httpEndpoint := fmt.Sprintf("%s:8091", clusterInfo.Nodes[0].IPAddress)
-	memdEndpoint := fmt.Sprintf("%s:11210", clusterInfo.Nodes[0].IPAddress)
+	seedNode := clusterInfo.Nodes[0]
+	httpEndpoint := d.nodeAddress(seedNode.HostName, seedNode.IPAddress, seedNode.PublishedPorts, 8091)
+	memdEndpoint := d.nodeAddress(seedNode.HostName, seedNode.IPAddress, seedNode.PublishedPorts, 11210)
 
 	agent, err := gocbcorex.CreateAgent(ctx, gocbcorex.AgentOptions{
 		Logger:     d.logger.Named("agent"),
@@ -1086,13 +2192,37 @@ func (d *Deployer) ListUsers(ctx context.Context, clusterID string) ([]deploymen
 }
 
 func (d *Deployer) CreateUser(ctx context.Context, clusterID string, opts *deployment.CreateUserOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateUser")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	controller, err := d.getController(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster controller")
 	}
 
 	var roles []string
-	if opts.CanWrite {
+	if len(opts.Buckets) > 0 {
+		for _, bucketName := range opts.Buckets {
+			if opts.CanRead || opts.CanWrite {
+				roles = append(roles,
+					"data_reader["+bucketName+"]",
+					"views_reader["+bucketName+"]",
+					"query_select["+bucketName+"]",
+					"fts_searcher["+bucketName+"]")
+			}
+			if opts.CanWrite {
+				roles = append(roles,
+					"data_writer["+bucketName+"]",
+					"query_insert["+bucketName+"]",
+					"query_update["+bucketName+"]",
+					"query_delete["+bucketName+"]",
+					"query_manage_index["+bucketName+"]")
+			}
+		}
+	} else if opts.CanWrite {
 		roles = append(roles, "admin")
 	} else if opts.CanRead {
 		roles = append(roles,
@@ -1117,6 +2247,12 @@ func (d *Deployer) CreateUser(ctx context.Context, clusterID string, opts *deplo
 }
 
 func (d *Deployer) DeleteUser(ctx context.Context, clusterID string, username string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteUser")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	controller, err := d.getController(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster controller")
@@ -1152,6 +2288,12 @@ func (d *Deployer) ListBuckets(ctx context.Context, clusterID string) ([]deploym
 }
 
 func (d *Deployer) LoadSampleBucket(ctx context.Context, clusterID string, bucketName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "LoadSampleBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	controller, err := d.getController(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster controller")
@@ -1171,6 +2313,17 @@ func (d *Deployer) LoadSampleBucket(ctx context.Context, clusterID string, bucke
 }
 
 func (d *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *deployment.CreateBucketOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	opts, err = deployment.ResolveBucketOptions(opts)
+	if err != nil {
+		return err
+	}
+
 	controller, err := d.getController(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster controller")
@@ -1179,37 +2332,90 @@ func (d *Deployer) CreateBucket(ctx context.Context, clusterID string, opts *dep
 	ramQuotaMb := 256
 	if opts.RamQuotaMB > 0 {
 		ramQuotaMb = opts.RamQuotaMB
+	} else {
+		clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cluster info")
+		}
+
+		// clusters with memory limited nodes have small data quotas, so
+		// their buckets default to the minimum quota to fit more of them
+		for _, node := range clusterInfo.Nodes {
+			if node.RuntimeOpts != nil && node.RuntimeOpts.MemoryLimitMB > 0 {
+				ramQuotaMb = minBucketRamQuotaMB
+			}
+		}
 	}
 
-	numReplicas := 1
-	if opts.NumReplicas > 1 {
-		numReplicas = opts.NumReplicas
-	}
-
-	err = controller.Controller().CreateBucket(ctx, &clustercontrol.CreateBucketRequest{
-		Name:                   opts.Name,
-		BucketType:             "membase",
-		StorageBackend:         "couchstore",
-		AutoCompactionDefined:  false,
-		EvictionPolicy:         "valueOnly",
-		ThreadsNumber:          3,
-		ReplicaNumber:          numReplicas,
-		DurabilityMinLevel:     "none",
-		CompressionMode:        "passive",
-		MaxTTL:                 0,
-		ReplicaIndex:           0,
-		ConflictResolutionType: "seqno",
-		RamQuotaMB:             ramQuotaMb,
-		FlushEnabled:           opts.FlushEnabled,
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed to create bucket")
+	if feature, ok := deployment.BucketFeature(opts.BucketType); ok {
+		err := d.checkClusterFeature(ctx, clusterID, feature)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
-}
+	req := &clustercontrol.CreateBucketRequest{
+		Name:         opts.Name,
+		RamQuotaMB:   ramQuotaMb,
+		FlushEnabled: opts.FlushEnabled,
+	}
+
+	if opts.BucketType == deployment.BucketTypeMemcached {
+		req.BucketType = "memcached"
+	} else {
+		numReplicas := 1
+		if opts.NumReplicas > 1 {
+			numReplicas = opts.NumReplicas
+		}
+
+		autoCompactionDefined := false
+		req.AutoCompactionDefined = &autoCompactionDefined
+		req.EvictionPolicy = opts.EvictionPolicy
+		req.ThreadsNumber = 3
+		req.ReplicaNumber = &numReplicas
+		req.DurabilityMinLevel = "none"
+		req.CompressionMode = "passive"
+		req.ConflictResolutionType = "seqno"
+
+		if opts.BucketType == deployment.BucketTypeEphemeral {
+			req.BucketType = "ephemeral"
+		} else {
+			storageBackend := "couchstore"
+			if opts.StorageBackend != "" {
+				storageBackend = opts.StorageBackend
+			}
+
+			if storageBackend == "magma" {
+				err := d.checkClusterFeature(ctx, clusterID, deployment.FeatureMagma)
+				if err != nil {
+					return err
+				}
+			}
+
+			replicaIndex := 0
+			req.BucketType = "membase"
+			req.StorageBackend = storageBackend
+			req.ReplicaIndex = &replicaIndex
+			req.HistoryRetentionBytes = opts.HistoryRetentionBytes
+			req.HistoryRetentionSeconds = int(opts.HistoryRetentionDuration / time.Second)
+		}
+	}
+
+	err = controller.Controller().CreateBucket(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to create bucket")
+	}
+
+	return nil
+}
+
+func (d *Deployer) DeleteBucket(ctx context.Context, clusterID string, bucketName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteBucket")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-func (d *Deployer) DeleteBucket(ctx context.Context, clusterID string, bucketName string) error {
 	controller, err := d.getController(ctx, clusterID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster controller")
@@ -1276,37 +2482,64 @@ func (d *Deployer) ExecuteQuery(ctx context.Context, clusterID string, query str
 }
 
 func (d *Deployer) ListCollections(ctx context.Context, clusterID string, bucketName string) ([]deployment.ScopeInfo, error) {
-	agent, err := d.getAgent(ctx, clusterID, "")
+	manifest, err := d.GetCollectionManifest(ctx, clusterID, bucketName)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get cluster agent")
+		return nil, err
 	}
-	defer agent.Close()
 
-	manifest, err := agent.GetCollectionManifest(ctx, &cbmgmtx.GetCollectionManifestOptions{
-		BucketName: bucketName,
-	})
+	return manifest.Scopes, nil
+}
+
+func (d *Deployer) GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*deployment.CollectionManifest, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	manifestJson, err := controller.Controller().GetCollectionManifest(ctx, bucketName)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch collection manifest")
 	}
 
-	var scopes []deployment.ScopeInfo
-	for _, scope := range manifest.Scopes {
-		var collections []deployment.CollectionInfo
-		for _, collection := range scope.Collections {
-			collections = append(collections, deployment.CollectionInfo{
-				Name: collection.Name,
-			})
-		}
-		scopes = append(scopes, deployment.ScopeInfo{
-			Name:        scope.Name,
-			Collections: collections,
-		})
+	return deployment.ParseCollectionManifest(manifestJson)
+}
+
+func (d *Deployer) WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
 	}
 
-	return scopes, nil
+	for {
+		// the server waits a while for the manifest to reach every node before
+		// failing, so we just keep asking until it does or we give up.
+		err := controller.Controller().EnsureCollectionManifest(ctx, bucketName, minUID)
+		if err == nil {
+			return nil
+		}
+
+		d.logger.Debug("collection manifest not yet propagated", zap.Error(err))
+
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return errors.Wrap(err, "context finished while waiting for collection manifest")
+		}
+	}
 }
 
 func (d *Deployer) CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateScope")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureCollections)
+	if err != nil {
+		return err
+	}
+
 	agent, err := d.getAgent(ctx, clusterID, "")
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster agent")
@@ -1324,18 +2557,33 @@ func (d *Deployer) CreateScope(ctx context.Context, clusterID string, bucketName
 	return nil
 }
 
-func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
-	agent, err := d.getAgent(ctx, clusterID, "")
+func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.CreateCollectionOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateCollection")
 	if err != nil {
-		return errors.Wrap(err, "failed to get cluster agent")
+		return err
 	}
-	defer agent.Close()
+	defer unlock()
 
-	_, err = agent.CreateCollection(ctx, &cbmgmtx.CreateCollectionOptions{
-		BucketName:     bucketName,
-		ScopeName:      scopeName,
-		CollectionName: collectionName,
-	})
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureCollections)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	settings := &clustercontrol.CollectionSettings{}
+	if opts != nil {
+		if opts.MaxTTL != 0 {
+			maxTTL := deployment.MaxTTLSeconds(opts.MaxTTL)
+			settings.MaxTTL = &maxTTL
+		}
+		settings.History = opts.History
+	}
+
+	err = controller.Controller().CreateCollection(ctx, bucketName, scopeName, collectionName, settings)
 	if err != nil {
 		return errors.Wrap(err, "failed to create collection")
 	}
@@ -1343,7 +2591,51 @@ func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucke
 	return nil
 }
 
+func (d *Deployer) UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.UpdateCollectionOptions) error {
+	err := deployment.CheckUpdateCollectionOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "UpdateCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureCollectionUpdates)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	settings := &clustercontrol.CollectionSettings{
+		History: opts.History,
+	}
+	if opts.MaxTTL != nil {
+		maxTTL := deployment.MaxTTLSeconds(*opts.MaxTTL)
+		settings.MaxTTL = &maxTTL
+	}
+
+	err = controller.Controller().UpdateCollection(ctx, bucketName, scopeName, collectionName, settings)
+	if err != nil {
+		return errors.Wrap(err, "failed to update collection")
+	}
+
+	return nil
+}
+
 func (d *Deployer) DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteScope")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	agent, err := d.getAgent(ctx, clusterID, "")
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster agent")
@@ -1362,6 +2654,12 @@ func (d *Deployer) DeleteScope(ctx context.Context, clusterID string, bucketName
 }
 
 func (d *Deployer) DeleteCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	agent, err := d.getAgent(ctx, clusterID, "")
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster agent")
@@ -1381,7 +2679,7 @@ func (d *Deployer) DeleteCollection(ctx context.Context, clusterID string, bucke
 }
 
 func (d *Deployer) getNode(ctx context.Context, clusterID, nodeID string) (*NodeInfo, error) {
-	nodes, err := d.controller.ListNodes(ctx)
+	nodes, err := d.listNodes(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list nodes")
 	}
@@ -1399,7 +2697,256 @@ func (d *Deployer) getNode(ctx context.Context, clusterID, nodeID string) (*Node
 	return foundNode, nil
 }
 
+// PopulateNodeIdentity fetches the otpNode, node uuid, services and server
+// group of the nodes of a cluster from the nodes themselves.  Nodes which
+// cannot be reached are left without an identity.
+func (d *Deployer) PopulateNodeIdentity(ctx context.Context, cluster *ClusterInfo) {
+	var wg sync.WaitGroup
+	for _, node := range cluster.Nodes {
+		if !node.IsNode {
+			continue
+		}
+
+		wg.Add(1)
+		go func(node *ClusterNodeInfo) {
+			defer wg.Done()
+
+			nodeCtrl := clustercontrol.NodeManager{
+				Endpoint: d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
+			}
+			localInfo, err := nodeCtrl.Controller().GetLocalInfo(ctx)
+			if err != nil {
+				d.logger.Debug("failed to get node identity",
+					zap.String("container", node.ContainerID),
+					zap.Error(err))
+				return
+			}
+
+			node.OTPNode = localInfo.OTPNode
+			node.NodeUUID = localInfo.NodeUUID
+			node.Services, _ = clusterdef.NsServicesToServices(localInfo.Services)
+			node.ServerGroup = localInfo.ServerGroup
+		}(node)
+	}
+	wg.Wait()
+}
+
+// PopulateMemoryQuotas fetches the service memory quotas of a cluster from
+// the first of its nodes which can be reached.
+func (d *Deployer) PopulateMemoryQuotas(ctx context.Context, cluster *ClusterInfo) {
+	for _, node := range cluster.Nodes {
+		if !node.IsNode {
+			continue
+		}
+
+		nodeCtrl := clustercontrol.NodeManager{
+			Endpoint: d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
+		}
+		quotas, err := nodeCtrl.Controller().GetMemoryQuotas(ctx)
+		if err != nil {
+			d.logger.Debug("failed to get memory quotas",
+				zap.String("container", node.ContainerID),
+				zap.Error(err))
+			continue
+		}
+
+		cluster.MemoryQuotas = quotas
+		return
+	}
+}
+
+// GetManagementJson fetches an endpoint of the cluster management API.
+func (d *Deployer) GetManagementJson(ctx context.Context, clusterID string, path string) (json.RawMessage, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().GetRawJson(ctx, path)
+}
+
+func (d *Deployer) CreateQueryFunction(ctx context.Context, clusterID string, opts *deployment.CreateQueryFunctionOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateQueryFunction")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureQueryUDFs)
+	if err != nil {
+		return err
+	}
+
+	if opts.IsJavascript() {
+		err := d.checkClusterFeature(ctx, clusterID, deployment.FeatureJavascriptUDFs)
+		if err != nil {
+			return err
+		}
+	}
+
+	statement, err := opts.Statement()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.ExecuteQuery(ctx, clusterID, statement)
+	if err != nil {
+		return errors.Wrap(err, "failed to create query function")
+	}
+
+	return nil
+}
+
+func (d *Deployer) DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DropQueryFunction")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	statement, err := deployment.DropQueryFunctionStatement(bucketName, scopeName, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.ExecuteQuery(ctx, clusterID, statement)
+	if err != nil {
+		return errors.Wrap(err, "failed to drop query function")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListJsLibraries(ctx context.Context, clusterID string) ([]deployment.JsLibraryInfo, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	libraries, err := controller.Controller().ListJsLibraries(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list javascript libraries")
+	}
+
+	var out []deployment.JsLibraryInfo
+	for _, library := range libraries {
+		out = append(out, deployment.JsLibraryInfo{
+			BucketName: library.Bucket,
+			ScopeName:  library.Scope,
+			Name:       library.Name,
+			Code:       library.Code,
+		})
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) CreateJsLibrary(ctx context.Context, clusterID string, opts *deployment.CreateJsLibraryOptions) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateJsLibrary")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureJavascriptUDFs)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().CreateJsLibrary(ctx, opts.BucketName, opts.ScopeName, opts.Name, opts.Code)
+	if err != nil {
+		return errors.Wrap(err, "failed to create javascript library")
+	}
+
+	return nil
+}
+
+func (d *Deployer) DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeleteJsLibrary")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().DeleteJsLibrary(ctx, bucketName, scopeName, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete javascript library")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListIndexes(ctx context.Context, clusterID string) ([]deployment.IndexInfo, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	indexes, err := controller.Controller().ListIndexStatuses(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list indexes")
+	}
+
+	var out []deployment.IndexInfo
+	for _, index := range indexes {
+		out = append(out, deployment.IndexInfo{
+			BucketName:     index.Bucket,
+			ScopeName:      index.Scope,
+			CollectionName: index.Collection,
+			Name:           index.Name,
+			ReplicaID:      index.ReplicaID,
+			Status:         index.Status,
+			Progress:       index.Progress,
+			Definition:     index.Definition,
+			Hosts:          index.Hosts,
+		})
+	}
+
+	return out, nil
+}
+
+func (d *Deployer) AdviseIndexes(ctx context.Context, clusterID string, statement string) (*deployment.IndexAdvice, error) {
+	adviseStatement, err := deployment.IndexAdviseStatement(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsStr, err := d.ExecuteQuery(ctx, clusterID, adviseStatement)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run index advisor")
+	}
+
+	var rows []json.RawMessage
+	err = json.Unmarshal([]byte(resultsStr), &rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse index advisor results")
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("index advisor returned no results")
+	}
+
+	return deployment.ParseIndexAdvice(rows[0])
+}
+
 func (d *Deployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType deployment.BlockNodeTrafficType) error {
+	return d.BlockNodePortTraffic(ctx, clusterID, nodeID, blockType, nil)
+}
+
+// BlockNodePortTraffic blocks a type of traffic to a node in the same way as
+// BlockNodeTraffic, but when ports are specified, only the traffic to and
+// from those ports is blocked.
+func (d *Deployer) BlockNodePortTraffic(ctx context.Context, clusterID string, nodeID string, blockType deployment.BlockNodeTrafficType, ports []int) error {
 	node, err := d.getNode(ctx, clusterID, nodeID)
 	if err != nil {
 		return errors.Wrap(err, "failed to get node")
@@ -1414,7 +2961,16 @@ func (d *Deployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeI
 	case deployment.BlockNodeTrafficAll:
 		tcType = TrafficControlBlockAll
 	}
-	err = d.controller.SetTrafficControl(ctx, node.ContainerID, tcType)
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would block node traffic",
+			zap.String("container", node.ContainerID),
+			zap.String("type", string(blockType)),
+			zap.Ints("ports", ports))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).Controller.SetTrafficControl(ctx, node.ContainerID, tcType, ports)
 	if err != nil {
 		return errors.Wrap(err, "failed to block traffic")
 	}
@@ -1428,7 +2984,13 @@ func (d *Deployer) AllowNodeTraffic(ctx context.Context, clusterID string, nodeI
 		return errors.Wrap(err, "failed to get node")
 	}
 
-	err = d.controller.SetTrafficControl(ctx, node.ContainerID, TrafficControlAllowAll)
+	if d.dryRun {
+		d.logger.Info("dry-run: would allow node traffic",
+			zap.String("container", node.ContainerID))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).Controller.SetTrafficControl(ctx, node.ContainerID, TrafficControlAllowAll, nil)
 	if err != nil {
 		return errors.Wrap(err, "failed to allow traffic")
 	}
@@ -1447,7 +3009,7 @@ func (d *Deployer) CollectLogs(ctx context.Context, clusterID string, destPath s
 	}
 
 	nodeCtrl := clustercontrol.NodeManager{
-		Endpoint: fmt.Sprintf("http://%s:8091", clusterInfo.Nodes[0].IPAddress),
+		Endpoint: d.mgmtEndpoint(clusterInfo.Nodes[0].HostName, clusterInfo.Nodes[0].IPAddress, clusterInfo.Nodes[0].PublishedPorts),
 	}
 
 	nodeOtps, err := nodeCtrl.Controller().ListNodeOTPs(ctx)
@@ -1501,6 +3063,7 @@ func (d *Deployer) CollectLogs(ctx context.Context, clusterID string, destPath s
 			return nil, fmt.Errorf("failed to find node for ip %s", ipAddress)
 		}
 		containerId := nodeInfo.ContainerID
+		dockerCli := d.getHost(nodeInfo.HostName).DockerCli
 
 		fileName := path.Base(filePath)
 		destFilePath := path.Join(destPath, fileName)
@@ -1517,7 +3080,7 @@ func (d *Deployer) CollectLogs(ctx context.Context, clusterID string, destPath s
 				zap.String("destPath", destFilePath))
 		}
 
-		resp, _, err := d.dockerCli.CopyFromContainer(ctx, containerId, filePath)
+		resp, _, err := dockerCli.CopyFromContainer(ctx, containerId, filePath)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to copy from container")
 		}
@@ -1559,7 +3122,13 @@ func (d *Deployer) PauseNode(ctx context.Context, clusterID string, nodeID strin
 		return errors.Wrap(err, "failed to get node")
 	}
 
-	err = d.dockerCli.ContainerPause(ctx, node.ContainerID)
+	if d.dryRun {
+		d.logger.Info("dry-run: would pause container",
+			zap.String("container", node.ContainerID))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).DockerCli.ContainerPause(ctx, node.ContainerID)
 	if err != nil {
 		return errors.Wrap(err, "failed to pause container")
 	}
@@ -1573,26 +3142,128 @@ func (d *Deployer) UnpauseNode(ctx context.Context, clusterID string, nodeID str
 		return errors.Wrap(err, "failed to get node")
 	}
 
-	err = d.dockerCli.ContainerUnpause(ctx, node.ContainerID)
+	if d.dryRun {
+		d.logger.Info("dry-run: would unpause container",
+			zap.String("container", node.ContainerID))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).DockerCli.ContainerUnpause(ctx, node.ContainerID)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpause container")
 	}
 
+	err = d.WaitForBucketsReady(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (d *Deployer) RedeployCluster(ctx context.Context, clusterID string) error {
-	return errors.New("docker deploy does not support redeploy cluster")
+func (d *Deployer) SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "SetNodeClockSkew")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	node, err := d.getNode(ctx, clusterID, nodeID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get node")
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would set node clock skew",
+			zap.String("container", node.ContainerID),
+			zap.Duration("skew", skew))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).Controller.SetClockSkew(ctx, node.ContainerID, skew)
+	if err != nil {
+		return errors.Wrap(err, "failed to set clock skew")
+	}
+
+	// the server is restarted the first time the clock is skewed
+	err = d.WaitForBucketsReady(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (d *Deployer) CreateCapellaLink(ctx context.Context, columnarID, linkName, clusterId, directID string) error {
-	return errors.New("docker deploy does not support create capella link")
+func (d *Deployer) GetNodeVersions(ctx context.Context, clusterID string) ([]deployment.NodeVersionInfo, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var versions []deployment.NodeVersionInfo
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+		if node.Type != "server-node" && node.Type != "columnar-node" {
+			continue
+		}
+
+		// the version is queried from the node itself, since the container
+		// may have been upgraded or created from a different image
+		nodeCtrl := clustercontrol.NodeManager{
+			Endpoint: d.mgmtEndpoint(node.HostName, node.IPAddress, node.PublishedPorts),
+		}
+		localInfo, err := nodeCtrl.Controller().GetLocalInfo(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get version of node `%s`", node.NodeID)
+		}
+
+		versions = append(versions, deployment.NodeVersionInfo{
+			NodeID:          node.NodeID,
+			Name:            node.Name,
+			ExpectedVersion: node.InitialServerVersion,
+			RunningVersion:  nsVersionToVersion(localInfo.Version),
+		})
+	}
+	if len(versions) == 0 {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	return versions, nil
 }
 
-func (d *Deployer) CreateS3Link(ctx context.Context, columnarID, linkName, region, endpoint, accessKey, secretKey string) error {
-	return errors.New("docker deploy does not support create S3 link")
+// WaitForBucketsReady waits until the buckets of a cluster have warmed up
+// on all of its active data nodes, which is needed after nodes are restarted
+// or resumed to avoid observing transient errors from the data service.
+func (d *Deployer) WaitForBucketsReady(ctx context.Context, clusterID string) error {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	d.logger.Info("waiting for buckets to warm up")
+
+	err = controller.WaitForBucketsReady(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for buckets to warm up")
+	}
+
+	return nil
 }
 
-func (d *Deployer) DropLink(ctx context.Context, columnarID, linkName string) error {
-	return errors.New("docker deploy does not support drop link")
+func (d *Deployer) RedeployCluster(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RedeployCluster")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return errors.New("docker deploy does not support redeploy cluster")
+}
+
+// CreateCapellaLink takes no cluster lock as it is not supported and
+// modifies nothing.
+func (d *Deployer) CreateCapellaLink(ctx context.Context, columnarID, linkName, clusterId, directID string) error {
+	return errors.New("docker deploy does not support create capella link")
 }
@@ -0,0 +1,107 @@
+package dockerdeploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffNodeGroupsForceNew(t *testing.T) {
+	d := &Deployer{}
+
+	services := []clusterdef.Service{clusterdef.KvService}
+	nodeGrps := []*clusterdef.NodeGroup{
+		{Count: 2, Version: "7.2.0", Services: services, ForceNew: true},
+	}
+
+	makeCluster := func(nodeIDs ...string) *deployedClusterInfo {
+		clusterInfo := &deployedClusterInfo{}
+		for _, nodeID := range nodeIDs {
+			clusterInfo.Nodes = append(clusterInfo.Nodes, &deployedNodeInfo{
+				NodeID:   nodeID,
+				Version:  "7.2.0",
+				Services: services,
+			})
+		}
+		return clusterInfo
+	}
+
+	// existing nodes are never reused by a group forcing new nodes
+	nodesToAdd, nodesToRemove := d.diffNodeGroups(makeCluster("old1", "old2"), nodeGrps, nil)
+	assert.Len(t, nodesToAdd, 2)
+	assert.Len(t, nodesToRemove, 2)
+
+	// once modified, the nodes which were deployed satisfy the group
+	nodesToAdd, nodesToRemove = d.diffNodeGroups(makeCluster("new1", "new2"), nodeGrps,
+		[]string{"new1", "new2"})
+	assert.Empty(t, nodesToAdd)
+	assert.Empty(t, nodesToRemove)
+
+	// but an old node which was not replaced still does not
+	nodesToAdd, nodesToRemove = d.diffNodeGroups(makeCluster("old1", "new1"), nodeGrps,
+		[]string{"new1"})
+	assert.Len(t, nodesToAdd, 1)
+	if assert.Len(t, nodesToRemove, 1) {
+		assert.Equal(t, "old1", nodesToRemove[0].NodeID)
+	}
+}
+
+func TestValidateRuntimeOptsSysctls(t *testing.T) {
+	d := &Deployer{}
+
+	nodeGrps := []*clusterdef.NodeGroup{
+		{Docker: clusterdef.DockerNodeGroup{Sysctls: map[string]string{
+			"net.ipv4.tcp_keepalive_time": "60",
+			"kernel.shmmax":               "68719476736",
+			"fs.mqueue.msg_max":           "100",
+		}}},
+		{Docker: clusterdef.DockerNodeGroup{Sysctls: map[string]string{
+			"kernel.core_pattern": "/tmp/core",
+		}}},
+	}
+
+	violations := d.validateRuntimeOpts(nodeGrps)
+	assert.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Message, "kernel.core_pattern")
+}
+
+func TestSwapNodeGroupKeepsNodeOptions(t *testing.T) {
+	d := &Deployer{
+		hosts: []*dockerHost{{Name: "default"}, {Name: "remote"}},
+	}
+
+	oldNode := &deployedNodeInfo{
+		NodeGroup:   "data",
+		HostName:    "remote",
+		Version:     "7.2.0",
+		Services:    []clusterdef.Service{clusterdef.KvService},
+		ServerGroup: "Group 2",
+		RuntimeOpts: &NodeRuntimeOptions{
+			Ulimits:       []clusterdef.DockerUlimit{{Name: "nofile", Soft: 1024, Hard: 2048}},
+			Sysctls:       map[string]string{"net.ipv4.tcp_keepalive_time": "60"},
+			Volumes:       []string{"/data:/opt/couchbase/var"},
+			Args:          []string{"--debug"},
+			OsTuning:      true,
+			MemoryLimitMB: 4096,
+		},
+	}
+
+	nodeGrp, err := d.swapNodeGroup(context.Background(), oldNode, "7.6.0")
+	require.NoError(t, err)
+
+	assert.Equal(t, "data", nodeGrp.Name)
+	assert.Equal(t, 1, nodeGrp.Count)
+	assert.Equal(t, "7.6.0", nodeGrp.Version)
+	assert.Equal(t, oldNode.Services, nodeGrp.Services)
+	assert.Equal(t, "Group 2", nodeGrp.ServerGroup)
+	assert.Equal(t, "remote", nodeGrp.Docker.Host)
+	assert.Equal(t, oldNode.RuntimeOpts.Ulimits, nodeGrp.Docker.Ulimits)
+	assert.Equal(t, oldNode.RuntimeOpts.Sysctls, nodeGrp.Docker.Sysctls)
+	assert.Equal(t, oldNode.RuntimeOpts.Volumes, nodeGrp.Docker.Volumes)
+	assert.Equal(t, oldNode.RuntimeOpts.Args, nodeGrp.Docker.Args)
+	assert.True(t, nodeGrp.Docker.OsTuning)
+	assert.Equal(t, 4096, nodeGrp.Docker.MemoryLimitMB)
+}
@@ -0,0 +1,141 @@
+package dockerdeploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"go.uber.org/zap"
+)
+
+const (
+	diagnosticsTailLines = "1000"
+	diagnosticsTimeout   = 2 * time.Minute
+	nsServerLogsPath     = "/opt/couchbase/var/lib/couchbase/logs"
+)
+
+var nsServerDiagnosticsLogs = []string{
+	"babysitter.log",
+	"debug.log",
+	"error.log",
+	"info.log",
+}
+
+func (c *Controller) captureNodeDiagnostics(ctx context.Context, bundle *deployment.DiagnosticsBundle, node *NodeInfo) {
+	nodeDir := node.Name
+	if nodeDir == "" {
+		nodeDir = node.ContainerID
+	}
+
+	_, inspectJson, err := c.DockerCli.ContainerInspectWithRaw(ctx, node.ContainerID, false)
+	if err != nil {
+		bundle.WriteError(path.Join(nodeDir, "inspect.json"), err)
+	} else {
+		bundle.WriteFile(path.Join(nodeDir, "inspect.json"), inspectJson)
+	}
+
+	logsRdr, err := c.DockerCli.ContainerLogs(ctx, node.ContainerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Tail:       diagnosticsTailLines,
+	})
+	if err != nil {
+		bundle.WriteError(path.Join(nodeDir, "container.log"), err)
+	} else {
+		logsBuf := bytes.NewBuffer(nil)
+		stdcopy.StdCopy(logsBuf, logsBuf, logsRdr)
+		logsRdr.Close()
+		bundle.WriteFile(path.Join(nodeDir, "container.log"), logsBuf.Bytes())
+	}
+
+	if node.Type != "server-node" && node.Type != "columnar-node" {
+		return
+	}
+
+	for _, logName := range nsServerDiagnosticsLogs {
+		logOutput, err := dockerExecOutput(ctx, c.DockerCli, node.ContainerID, []string{
+			"tail", "-n", diagnosticsTailLines, path.Join(nsServerLogsPath, logName),
+		})
+		if err != nil {
+			bundle.WriteError(path.Join(nodeDir, logName), err)
+			continue
+		}
+
+		bundle.WriteFile(path.Join(nodeDir, logName), logOutput)
+	}
+}
+
+// captureDiagnostics writes a diagnostics bundle for a failed deployment
+// when a diagnostics path is configured.  This must be invoked before any
+// of the nodes are cleaned up, as they hold most of the evidence.
+func (d *Deployer) captureDiagnostics(
+	ctx context.Context,
+	clusterID string,
+	def *clusterdef.Cluster,
+	nodes []*NodeInfo,
+	cause error,
+) {
+	if d.diagnosticsPath == "" {
+		return
+	}
+
+	// the failure may have been caused by the context expiring, which must
+	// not prevent us from gathering the diagnostics.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	bundle, err := deployment.NewDiagnosticsBundle(d.logger, d.diagnosticsPath, clusterID)
+	if err != nil {
+		d.logger.Warn("failed to create diagnostics bundle", zap.Error(err))
+		return
+	}
+
+	d.logger.Info("capturing diagnostics bundle", zap.String("path", bundle.Path))
+
+	bundle.WriteFile("error.txt", []byte(fmt.Sprintf("%+v\n", cause)))
+	if def != nil {
+		bundle.WriteYaml("definition.yaml", def)
+	}
+
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+
+		d.getHost(node.HostName).Controller.captureNodeDiagnostics(ctx, bundle, node)
+	}
+
+	d.logger.Info("diagnostics bundle captured", zap.String("path", bundle.Path))
+}
+
+func (d *Deployer) captureClusterDiagnostics(
+	ctx context.Context,
+	clusterID string,
+	def *clusterdef.Cluster,
+	cause error,
+) {
+	if d.diagnosticsPath == "" {
+		return
+	}
+
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		d.logger.Warn("failed to list nodes for diagnostics", zap.Error(err))
+	}
+
+	var clusterNodes []*NodeInfo
+	for _, node := range nodes {
+		if node.ClusterID == clusterID {
+			clusterNodes = append(clusterNodes, node)
+		}
+	}
+
+	d.captureDiagnostics(ctx, clusterID, def, clusterNodes, cause)
+}
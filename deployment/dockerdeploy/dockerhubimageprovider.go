@@ -16,20 +16,22 @@ import (
 type DockerHubImageProvider struct {
 	Logger    *zap.Logger
 	DockerCli *client.Client
+	Manifest  *ImageManifest
 }
 
 var _ ImageProvider = (*DockerHubImageProvider)(nil)
 
-func (p *DockerHubImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+// dockerhubImagePath returns the dockerhub image for a server version.
+func dockerhubImagePath(def *ImageDef) (string, error) {
 	if def.BuildNo != 0 {
-		return nil, errors.New("cannot use dockerhub for non-ga releases")
+		return "", errors.New("cannot use dockerhub for non-ga releases")
 	}
 
 	if def.UseServerless {
-		return nil, errors.New("cannot use dockerhub for serverless releases")
+		return "", errors.New("cannot use dockerhub for serverless releases")
 	}
 	if def.UseColumnar {
-		return nil, errors.New("cannot use dockerhub for columnar releases")
+		return "", errors.New("cannot use dockerhub for columnar releases")
 	}
 
 	var serverVersion string
@@ -39,13 +41,22 @@ func (p *DockerHubImageProvider) GetImage(ctx context.Context, def *ImageDef) (*
 		serverVersion = fmt.Sprintf("enterprise-%s", def.Version)
 	}
 
-	dhImagePath := fmt.Sprintf("couchbase/server:%s", serverVersion)
+	return fmt.Sprintf("couchbase/server:%s", serverVersion), nil
+}
+
+func (p *DockerHubImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	dhImagePath, err := dockerhubImagePath(def)
+	if err != nil {
+		return nil, err
+	}
+
 	p.Logger.Debug("identified dockerhub image to pull", zap.String("image", dhImagePath))
 
 	return MultiArchImagePuller{
 		Logger:    p.Logger,
 		DockerCli: p.DockerCli,
 		ImagePath: dhImagePath,
+		Manifest:  p.Manifest,
 	}.Pull(ctx)
 }
 
@@ -54,6 +65,7 @@ func (p *DockerHubImageProvider) GetImageRaw(ctx context.Context, imagePath stri
 		Logger:    p.Logger,
 		DockerCli: p.DockerCli,
 		ImagePath: imagePath,
+		Manifest:  p.Manifest,
 	}.Pull(ctx)
 }
 
@@ -0,0 +1,269 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/couchbase/gocbcorex"
+	"github.com/couchbase/gocbcorex/memdx"
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DurabilityLevels are the durability levels a durability check can use, in
+// increasing order of strength.
+var DurabilityLevels = []string{
+	"none",
+	"majority",
+	"majorityAndPersistActive",
+	"persistToMajority",
+}
+
+var durabilityLevelsToMemdx = map[string]memdx.DurabilityLevel{
+	"majority":                 memdx.DurabilityLevelMajority,
+	"majorityAndPersistActive": memdx.DurabilityLevelMajorityAndPersistToActive,
+	"persistToMajority":        memdx.DurabilityLevelPersistToMajority,
+}
+
+// isMemdStatus indicates that an operation failed because the server
+// responded with a specific status.
+func isMemdStatus(err error, status memdx.Status) bool {
+	var serverErr memdx.ServerError
+	return errors.As(err, &serverErr) && serverErr.Status == status
+}
+
+type DurabilityCheckOptions struct {
+	BucketName string
+
+	// Levels are the durability levels to write with, defaulting to all of
+	// DurabilityLevels.
+	Levels []string
+
+	// NumWrites is the number of writes performed at each level, both before
+	// any failure is induced and while each data node is paused.
+	NumWrites int
+
+	// WriteTimeout bounds each write, after which it is counted as ambiguous.
+	WriteTimeout time.Duration
+}
+
+// DurabilityCheckOutcome counts the outcomes of the writes at one durability
+// level while one node was paused, or with all nodes healthy when FailedNodeID
+// is empty.
+type DurabilityCheckOutcome struct {
+	FailedNodeID string
+	Level        string
+	Succeeded    int
+	Ambiguous    int
+	Impossible   int
+	Failed       int
+	FirstError   string
+}
+
+type DurabilityCheckResult struct {
+	Outcomes []*DurabilityCheckOutcome
+
+	// LostWrites counts the writes which succeeded but could not be read back
+	// once all the nodes had recovered.
+	LostWrites int
+	LostKeys   []string
+}
+
+type durabilityCheckDoc struct {
+	RunID string `json:"runId"`
+	Level string `json:"level"`
+	Seq   int    `json:"seq"`
+}
+
+// RunDurabilityCheck performs sync-writes at each durability level, first with
+// all nodes healthy and then while each data node in turn is paused, counting
+// which writes succeed, are ambiguous or are rejected as impossible.  Once the
+// nodes have recovered, every write which succeeded is read back to check that
+// none were lost.
+func (d *Deployer) RunDurabilityCheck(ctx context.Context, clusterID string, opts *DurabilityCheckOptions) (*DurabilityCheckResult, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RunDurabilityCheck")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	levels := opts.Levels
+	if len(levels) == 0 {
+		levels = DurabilityLevels
+	}
+	for _, level := range levels {
+		if !slices.Contains(DurabilityLevels, level) {
+			return nil, fmt.Errorf("invalid durability level `%s`, expected one of %v", level, DurabilityLevels)
+		}
+	}
+
+	writeTimeout := opts.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = 5 * time.Second
+	}
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	var dataNodes []*deployedNodeInfo
+	for _, clusterNode := range clusterInfo.Nodes {
+		if slices.Contains(clusterNode.Services, clusterdef.KvService) {
+			dataNodes = append(dataNodes, clusterNode)
+		}
+	}
+	if len(dataNodes) == 0 {
+		return nil, errors.New("cluster has no data nodes")
+	}
+
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	nodeIDs := make(map[string]string)
+	for _, node := range nodes {
+		nodeIDs[node.ContainerID] = node.NodeID
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would run durability check",
+			zap.String("bucket", opts.BucketName),
+			zap.Strings("levels", levels),
+			zap.Int("numWrites", opts.NumWrites),
+			zap.Int("numDataNodes", len(dataNodes)))
+		return &DurabilityCheckResult{}, nil
+	}
+
+	agent, err := d.getAgent(ctx, clusterID, opts.BucketName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster agent")
+	}
+	defer agent.Close()
+
+	result := &DurabilityCheckResult{}
+
+	runID := uuid.NewString()
+	var writtenKeys []string
+	nextSeq := 0
+
+	writeAll := func(failedNodeID string) {
+		for _, level := range levels {
+			outcome := &DurabilityCheckOutcome{
+				FailedNodeID: failedNodeID,
+				Level:        level,
+			}
+
+			for i := 0; i < opts.NumWrites; i++ {
+				seq := nextSeq
+				nextSeq++
+
+				key := fmt.Sprintf("cbdc-durability-%s-%d", runID, seq)
+				docBytes, _ := json.Marshal(durabilityCheckDoc{
+					RunID: runID,
+					Level: level,
+					Seq:   seq,
+				})
+
+				writeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+				_, err := agent.Upsert(writeCtx, &gocbcorex.UpsertOptions{
+					Key:             []byte(key),
+					ScopeName:       "_default",
+					CollectionName:  "_default",
+					Value:           docBytes,
+					Flags:           0x02000006,
+					DurabilityLevel: durabilityLevelsToMemdx[level],
+				})
+				cancel()
+
+				switch {
+				case err == nil:
+					outcome.Succeeded++
+					writtenKeys = append(writtenKeys, key)
+					continue
+				case isMemdStatus(err, memdx.StatusDurabilityImpossible):
+					outcome.Impossible++
+				case isMemdStatus(err, memdx.StatusSyncWriteAmbiguous),
+					errors.Is(err, context.DeadlineExceeded):
+					outcome.Ambiguous++
+				default:
+					outcome.Failed++
+				}
+
+				if outcome.FirstError == "" {
+					outcome.FirstError = err.Error()
+				}
+			}
+
+			d.logger.Info("durability check writes completed",
+				zap.String("failedNode", failedNodeID),
+				zap.String("level", level),
+				zap.Int("succeeded", outcome.Succeeded),
+				zap.Int("ambiguous", outcome.Ambiguous),
+				zap.Int("impossible", outcome.Impossible),
+				zap.Int("failed", outcome.Failed))
+
+			result.Outcomes = append(result.Outcomes, outcome)
+		}
+	}
+
+	progress.Step(ctx, "writing with all nodes healthy")
+	writeAll("")
+
+	for _, dataNode := range dataNodes {
+		nodeID := nodeIDs[dataNode.ContainerID]
+		hostCtrl := d.getHost(dataNode.HostName)
+
+		progress.Step(ctx, fmt.Sprintf("writing with node %s paused", nodeID))
+		d.logger.Info("pausing node", zap.String("node", nodeID))
+
+		err := hostCtrl.DockerCli.ContainerPause(ctx, dataNode.ContainerID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to pause node")
+		}
+
+		writeAll(nodeID)
+
+		// the node is resumed even if we were cancelled, so that the cluster
+		// is not left with a paused node
+		err = hostCtrl.DockerCli.ContainerUnpause(context.WithoutCancel(ctx), dataNode.ContainerID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unpause node")
+		}
+
+		err = d.WaitForBucketsReady(ctx, clusterID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	progress.Step(ctx, "validating successful writes")
+	d.logger.Info("validating successful writes", zap.Int("numWrites", len(writtenKeys)))
+
+	nodeCtrl := &clustercontrol.NodeManager{
+		Endpoint: d.mgmtEndpoint(dataNodes[0].HostName, dataNodes[0].IPAddress, dataNodes[0].PublishedPorts),
+	}
+	controller := nodeCtrl.Controller()
+
+	for _, key := range writtenKeys {
+		_, err := controller.GetDocument(ctx, opts.BucketName, key)
+		if errors.Is(err, clustercontrol.ErrDocumentNotFound) {
+			result.LostWrites++
+			if len(result.LostKeys) < maxReportedMissingKeys {
+				result.LostKeys = append(result.LostKeys, key)
+			}
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read back document `%s`", key)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package dockerdeploy
+
+import (
+	"context"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"go.uber.org/zap"
+)
+
+// watchPollInterval is how often clusters are listed while watching, which
+// catches changes that docker does not produce events for, such as expiry.
+const watchPollInterval = 1 * time.Minute
+
+// watchHostEvents triggers a relisting of the clusters whenever docker
+// reports a change to one of our containers on a host.
+func (d *Deployer) watchHostEvents(ctx context.Context, host *dockerHost, triggerCh chan<- struct{}) {
+	eventCh, errCh := host.DockerCli.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("label", "com.couchbase.dyncluster.cluster_id"),
+			filters.Arg("event", "start"),
+			filters.Arg("event", "die"),
+			filters.Arg("event", "destroy"),
+			filters.Arg("event", "pause"),
+			filters.Arg("event", "unpause"),
+			filters.Arg("event", "health_status")),
+	})
+
+	for {
+		select {
+		case event := <-eventCh:
+			d.logger.Debug("received container event",
+				zap.String("host", host.Name),
+				zap.String("action", event.Action))
+
+			select {
+			case triggerCh <- struct{}{}:
+			default:
+				// a relisting is already pending
+			}
+		case err := <-errCh:
+			if ctx.Err() == nil {
+				d.logger.Warn("stopped receiving docker events, falling back to polling",
+					zap.String("host", host.Name),
+					zap.Error(err))
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Deployer) WatchClusters(ctx context.Context) (<-chan deployment.ClusterEvent, error) {
+	triggerCh := make(chan struct{}, 1)
+	for _, host := range d.hosts {
+		go d.watchHostEvents(ctx, host, triggerCh)
+	}
+
+	return deployment.WatchClusterChanges(ctx, &deployment.ClusterWatcherOptions{
+		Logger:       d.logger,
+		ListClusters: d.ListClusters,
+		Triggers:     triggerCh,
+		PollInterval: watchPollInterval,
+	})
+}
@@ -0,0 +1,254 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type FailoverScenarioOptions struct {
+	BucketName string
+
+	// NodeID is the node to fail over, when empty the last data node of the
+	// cluster is selected.
+	NodeID string
+
+	// NumDocs is the number of tracked documents written before the failover.
+	NumDocs int
+
+	Graceful bool
+
+	// RecoveryType is the recovery type used to add the node back into the
+	// cluster (`delta` or `full`).  When empty, the node is rebalanced out
+	// and removed instead.
+	RecoveryType string
+}
+
+type FailoverScenarioResult struct {
+	NodeID         string
+	OTPNode        string
+	DocsWritten    int
+	DocsFound      int
+	DocsMissing    int
+	DocsMismatched int
+	MissingKeys    []string
+}
+
+type failoverScenarioDoc struct {
+	RunID string `json:"runId"`
+	Seq   int    `json:"seq"`
+}
+
+// maxReportedMissingKeys limits the number of missing keys that are included
+// in a scenario result, the counts are always complete.
+const maxReportedMissingKeys = 100
+
+// selectScenarioNode finds the node of a cluster which a scenario acts on,
+// which is the specified node, or the last data node if none is specified.
+func (d *Deployer) selectScenarioNode(
+	ctx context.Context,
+	clusterID string,
+	clusterInfo *deployedClusterInfo,
+	nodeID string,
+) (*deployedNodeInfo, error) {
+	if nodeID != "" {
+		node, err := d.getNode(ctx, clusterID, nodeID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get node")
+		}
+
+		for _, clusterNode := range clusterInfo.Nodes {
+			if clusterNode.ContainerID == node.ContainerID {
+				return clusterNode, nil
+			}
+		}
+		return nil, errors.New("failed to find deployed node")
+	}
+
+	var dataNode *deployedNodeInfo
+	for _, clusterNode := range clusterInfo.Nodes {
+		if slices.Contains(clusterNode.Services, clusterdef.KvService) {
+			dataNode = clusterNode
+		}
+	}
+	if dataNode == nil {
+		return nil, errors.New("cluster has no data nodes")
+	}
+	return dataNode, nil
+}
+
+// RunFailoverScenario writes a set of tracked documents to a bucket, fails
+// over a node and rebalances, then reads the documents back to validate
+// that none were lost.
+func (d *Deployer) RunFailoverScenario(ctx context.Context, clusterID string, opts *FailoverScenarioOptions) (*FailoverScenarioResult, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RunFailoverScenario")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if opts.RecoveryType != "" && opts.RecoveryType != "delta" && opts.RecoveryType != "full" {
+		return nil, fmt.Errorf("invalid recovery type `%s`, expected delta or full", opts.RecoveryType)
+	}
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	if len(clusterInfo.Nodes) < 2 {
+		return nil, errors.New("failover requires a cluster with at least two nodes")
+	}
+
+	failedNode, err := d.selectScenarioNode(ctx, clusterID, clusterInfo, opts.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// all the management requests are sent to a node which survives
+	var ctrlNode *deployedNodeInfo
+	for _, clusterNode := range clusterInfo.Nodes {
+		if clusterNode != failedNode {
+			ctrlNode = clusterNode
+			break
+		}
+	}
+
+	nodeCtrl := &clustercontrol.NodeManager{
+		Endpoint: d.mgmtEndpoint(ctrlNode.HostName, ctrlNode.IPAddress, ctrlNode.PublishedPorts),
+	}
+	controller := nodeCtrl.Controller()
+
+	result := &FailoverScenarioResult{
+		NodeID:  failedNode.NodeID,
+		OTPNode: failedNode.OTPNode,
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would run failover scenario",
+			zap.String("otp", failedNode.OTPNode),
+			zap.String("bucket", opts.BucketName),
+			zap.Int("numDocs", opts.NumDocs),
+			zap.Bool("graceful", opts.Graceful),
+			zap.String("recoveryType", opts.RecoveryType))
+		return result, nil
+	}
+
+	runID := uuid.NewString()
+	docKey := func(seq int) string {
+		return fmt.Sprintf("cbdc-failover-%s-%d", runID, seq)
+	}
+
+	progress.Step(ctx, "writing tracked documents")
+	d.logger.Info("writing tracked documents",
+		zap.String("bucket", opts.BucketName),
+		zap.Int("numDocs", opts.NumDocs))
+
+	for seq := 0; seq < opts.NumDocs; seq++ {
+		docBytes, _ := json.Marshal(failoverScenarioDoc{
+			RunID: runID,
+			Seq:   seq,
+		})
+
+		err := controller.UpsertDocument(ctx, opts.BucketName, docKey(seq), docBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write document %d", seq)
+		}
+
+		result.DocsWritten++
+	}
+
+	progress.Step(ctx, "failing over node")
+	d.logger.Info("failing over node",
+		zap.String("otp", failedNode.OTPNode),
+		zap.Bool("graceful", opts.Graceful))
+
+	err = controller.Failover(ctx, &clustercontrol.FailoverOptions{
+		OTPNode:  failedNode.OTPNode,
+		Graceful: opts.Graceful,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fail over node")
+	}
+
+	err = nodeCtrl.WaitForNoRunningTasks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for failover to complete")
+	}
+
+	var ejectedNodeOtps []string
+	if opts.RecoveryType != "" {
+		d.logger.Info("setting node recovery type",
+			zap.String("otp", failedNode.OTPNode),
+			zap.String("recoveryType", opts.RecoveryType))
+
+		err := controller.SetRecoveryType(ctx, failedNode.OTPNode, opts.RecoveryType)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set recovery type")
+		}
+	} else {
+		ejectedNodeOtps = []string{failedNode.OTPNode}
+	}
+
+	progress.Step(ctx, "rebalancing")
+	d.logger.Info("initiating rebalance")
+
+	err = nodeCtrl.Rebalance(ctx, ejectedNodeOtps)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start rebalance")
+	}
+
+	err = nodeCtrl.WaitForNoRunningTasks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for rebalance to complete")
+	}
+
+	if opts.RecoveryType == "" {
+		d.logger.Info("removing failed over node",
+			zap.String("container", failedNode.ContainerID))
+
+		d.getHost(failedNode.HostName).Controller.RemoveNode(ctx, failedNode.ContainerID)
+	}
+
+	err = nodeCtrl.WaitForBucketsReady(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for buckets to warm up")
+	}
+
+	progress.Step(ctx, "validating tracked documents")
+	d.logger.Info("validating tracked documents")
+
+	for seq := 0; seq < opts.NumDocs; seq++ {
+		key := docKey(seq)
+
+		docBytes, err := controller.GetDocument(ctx, opts.BucketName, key)
+		if errors.Is(err, clustercontrol.ErrDocumentNotFound) {
+			result.DocsMissing++
+			if len(result.MissingKeys) < maxReportedMissingKeys {
+				result.MissingKeys = append(result.MissingKeys, key)
+			}
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read document %d", seq)
+		}
+
+		var doc failoverScenarioDoc
+		err = json.Unmarshal(docBytes, &doc)
+		if err != nil || doc.RunID != runID || doc.Seq != seq {
+			result.DocsMismatched++
+			continue
+		}
+
+		result.DocsFound++
+	}
+
+	return result, nil
+}
@@ -20,6 +20,7 @@ type GhcrImageProvider struct {
 	DockerCli    *client.Client
 	GhcrUsername string
 	GhcrPassword string
+	Manifest     *ImageManifest
 }
 
 var _ ImageProvider = (*GhcrImageProvider)(nil)
@@ -37,34 +38,41 @@ func (p *GhcrImageProvider) genGhcrAuthStr() string {
 	return base64.StdEncoding.EncodeToString(authConfigJson)
 }
 
-func (p *GhcrImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
-	if p.GhcrUsername == "" && p.GhcrPassword == "" {
-		return nil, errors.New("cannot use ghcr without credentials")
-	}
-
+// ghcrImagePath returns the ghcr image for a server build.
+func ghcrImagePath(def *ImageDef) (string, error) {
 	if def.UseServerless {
-		return nil, errors.New("cannot use ghcr for serverless releases")
+		return "", errors.New("cannot use ghcr for serverless releases")
 	}
 
 	if def.BuildNo == 0 {
-		return nil, errors.New("cannot use ghcr for ga releases")
+		return "", errors.New("cannot use ghcr for ga releases")
 	}
 
 	serverVersion := fmt.Sprintf("%s-%d", def.Version, def.BuildNo)
 
-	var ghcrImagePath string
 	if !def.UseColumnar {
 		if def.UseCommunityEdition {
 			serverVersion = "community-" + serverVersion
 		}
 
-		ghcrImagePath = fmt.Sprintf("ghcr.io/cb-vanilla/server:%s", serverVersion)
-	} else {
-		if def.UseCommunityEdition {
-			return nil, errors.New("cannot pull community edition of columnar")
-		}
+		return fmt.Sprintf("ghcr.io/cb-vanilla/server:%s", serverVersion), nil
+	}
+
+	if def.UseCommunityEdition {
+		return "", errors.New("cannot pull community edition of columnar")
+	}
+
+	return fmt.Sprintf("ghcr.io/cb-vanilla/couchbase-columnar:%s", serverVersion), nil
+}
 
-		ghcrImagePath = fmt.Sprintf("ghcr.io/cb-vanilla/couchbase-columnar:%s", serverVersion)
+func (p *GhcrImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	if p.GhcrUsername == "" && p.GhcrPassword == "" {
+		return nil, errors.New("cannot use ghcr without credentials")
+	}
+
+	ghcrImagePath, err := ghcrImagePath(def)
+	if err != nil {
+		return nil, err
 	}
 
 	p.Logger.Debug("identified ghcr image to pull", zap.String("image", ghcrImagePath))
@@ -74,6 +82,7 @@ func (p *GhcrImageProvider) GetImage(ctx context.Context, def *ImageDef) (*Image
 		DockerCli:    p.DockerCli,
 		RegistryAuth: p.genGhcrAuthStr(),
 		ImagePath:    ghcrImagePath,
+		Manifest:     p.Manifest,
 	}.Pull(ctx)
 }
 
@@ -87,6 +96,7 @@ func (p *GhcrImageProvider) GetImageRaw(ctx context.Context, imagePath string) (
 		DockerCli:    p.DockerCli,
 		RegistryAuth: p.genGhcrAuthStr(),
 		ImagePath:    imagePath,
+		Manifest:     p.Manifest,
 	}.Pull(ctx)
 }
 
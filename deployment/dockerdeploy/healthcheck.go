@@ -0,0 +1,108 @@
+package dockerdeploy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// serverNodeHealthcheck is the docker healthcheck for server nodes.  Docker
+// reports changes in health as events, which lets us wait for a node to be
+// ready without repeatedly polling it ourselves.  Failures during the start
+// period do not mark the node as unhealthy, but the first success marks it
+// as healthy immediately.
+var serverNodeHealthcheck = &container.HealthConfig{
+	Test:        []string{"CMD-SHELL", "curl -sf -o /dev/null http://127.0.0.1:8091/pools || exit 1"},
+	Interval:    2 * time.Second,
+	Timeout:     5 * time.Second,
+	StartPeriod: 5 * time.Minute,
+	Retries:     3,
+}
+
+// These are the health states docker reports for containers.
+const (
+	NodeHealthNone      = ""
+	NodeHealthStarting  = "starting"
+	NodeHealthHealthy   = "healthy"
+	NodeHealthUnhealthy = "unhealthy"
+)
+
+// ClusterStateUnhealthy is the state reported for clusters with any node
+// which docker reports as unhealthy.
+const ClusterStateUnhealthy = "unhealthy"
+
+// parseContainerHealth extracts the health from the status docker reports
+// when listing containers, such as `Up 2 minutes (healthy)`.
+func parseContainerHealth(status string) string {
+	switch {
+	case strings.HasSuffix(status, "(health: starting)"):
+		return NodeHealthStarting
+	case strings.HasSuffix(status, "(healthy)"):
+		return NodeHealthHealthy
+	case strings.HasSuffix(status, "(unhealthy)"):
+		return NodeHealthUnhealthy
+	}
+	return NodeHealthNone
+}
+
+// waitForHealthy waits for a container to report as healthy.  Containers
+// without a healthcheck, such as those from images which disable it, fall
+// back to polling the node until it responds.
+func (c *Controller) waitForHealthy(ctx context.Context, containerID string, mgmtEndpoint string) error {
+	// subscribe before inspecting, so that no transition is missed between the two
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventCh, errCh := c.DockerCli.Events(eventsCtx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("container", containerID),
+			filters.Arg("event", "health_status"),
+			filters.Arg("event", "die")),
+	})
+
+	inspect, err := c.DockerCli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return errors.Wrap(err, "failed to inspect container")
+	}
+
+	if inspect.State == nil || inspect.State.Health == nil {
+		c.Logger.Debug("container has no healthcheck, polling for readiness")
+
+		clusterCtrl := &clustercontrol.NodeManager{
+			Endpoint: mgmtEndpoint,
+		}
+		return clusterCtrl.WaitForOnline(ctx)
+	}
+
+	if inspect.State.Health.Status == NodeHealthHealthy {
+		return nil
+	}
+
+	for {
+		select {
+		case event := <-eventCh:
+			c.Logger.Debug("received container event", zap.String("action", event.Action))
+
+			switch event.Action {
+			case "health_status: " + NodeHealthHealthy:
+				return nil
+			case "health_status: " + NodeHealthUnhealthy:
+				return errors.New("node became unhealthy while starting")
+			case "die":
+				return errors.New("node exited while starting")
+			}
+		case err := <-errCh:
+			return errors.Wrap(err, "failed to watch container events")
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "context finished while waiting for node to become healthy")
+		}
+	}
+}
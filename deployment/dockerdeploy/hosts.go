@@ -0,0 +1,147 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// DefaultHostName is the name of the primary docker host that the deployer
+// was created with.
+const DefaultHostName = "default"
+
+// HostOptions describes an additional docker host which cluster nodes can
+// be placed on.  All hosts must share the deployer's network, either as a
+// swarm overlay network marked as attachable, or as bridge networks whose
+// subnets are routed between the hosts.
+type HostOptions struct {
+	Name             string
+	DockerCli        *client.Client
+	AdvertiseAddress string
+}
+
+type dockerHost struct {
+	Name          string
+	DockerCli     *client.Client
+	ImageProvider ImageProvider
+	Controller    *Controller
+}
+
+// HostNames returns the names of all the docker hosts nodes can be placed on.
+func (d *Deployer) HostNames() []string {
+	var names []string
+	for _, host := range d.hosts {
+		names = append(names, host.Name)
+	}
+	return names
+}
+
+func (d *Deployer) findHost(name string) *dockerHost {
+	for _, host := range d.hosts {
+		if host.Name == name {
+			return host
+		}
+	}
+	return nil
+}
+
+// getHost returns the named docker host, falling back to the primary host
+// for nodes which do not record one.
+func (d *Deployer) getHost(name string) *dockerHost {
+	host := d.findHost(name)
+	if host == nil {
+		return d.hosts[0]
+	}
+	return host
+}
+
+// listNodes lists the nodes across all of the docker hosts.
+func (d *Deployer) listNodes(ctx context.Context) ([]*NodeInfo, error) {
+	var nodes []*NodeInfo
+	for _, host := range d.hosts {
+		hostNodes, err := host.Controller.ListNodes(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list nodes on host `%s`", host.Name)
+		}
+
+		nodes = append(nodes, hostNodes...)
+	}
+	return nodes, nil
+}
+
+// placeNodes picks the docker host for each node of each node group.  Node
+// groups which name a host are placed on it, all other nodes are placed on
+// whichever host has the fewest nodes of the cluster, which spreads a new
+// cluster round-robin across the hosts.
+func (d *Deployer) placeNodes(nodeGrps []*clusterdef.NodeGroup, existingHostNames []string) ([][]*dockerHost, error) {
+	hostNodeCounts := make(map[*dockerHost]int)
+	for _, hostName := range existingHostNames {
+		hostNodeCounts[d.getHost(hostName)]++
+	}
+
+	nodeHosts := make([][]*dockerHost, len(nodeGrps))
+	for nodeGrpIdx, nodeGrp := range nodeGrps {
+		for grpNodeIdx := 0; grpNodeIdx < nodeGrp.Count; grpNodeIdx++ {
+			var pickedHost *dockerHost
+			if nodeGrp.Docker.Host != "" {
+				pickedHost = d.findHost(nodeGrp.Docker.Host)
+				if pickedHost == nil {
+					return nil, fmt.Errorf("unknown docker host `%s`", nodeGrp.Docker.Host)
+				}
+			} else {
+				for _, host := range d.hosts {
+					if pickedHost == nil || hostNodeCounts[host] < hostNodeCounts[pickedHost] {
+						pickedHost = host
+					}
+				}
+			}
+
+			hostNodeCounts[pickedHost]++
+			nodeHosts[nodeGrpIdx] = append(nodeHosts[nodeGrpIdx], pickedHost)
+		}
+	}
+
+	return nodeHosts, nil
+}
+
+// getHostImagesForNodeGrps fetches the images for the node groups onto each
+// host that nodes of those groups were placed on.  The returned slices are
+// indexed by node group, with nil entries for groups not on that host.
+func (d *Deployer) getHostImagesForNodeGrps(
+	ctx context.Context,
+	nodeGrps []*clusterdef.NodeGroup,
+	nodeHosts [][]*dockerHost,
+	isColumnar bool,
+) (map[*dockerHost][]*ImageRef, error) {
+	hostImages := make(map[*dockerHost][]*ImageRef)
+	for _, host := range d.hosts {
+		var hostNodeGrps []*clusterdef.NodeGroup
+		var hostNodeGrpIdxs []int
+		for nodeGrpIdx, nodeGrp := range nodeGrps {
+			if slices.Contains(nodeHosts[nodeGrpIdx], host) {
+				hostNodeGrps = append(hostNodeGrps, nodeGrp)
+				hostNodeGrpIdxs = append(hostNodeGrpIdxs, nodeGrpIdx)
+			}
+		}
+		if len(hostNodeGrps) == 0 {
+			continue
+		}
+
+		images, err := d.getImagesForNodeGrps(ctx, host.ImageProvider, hostNodeGrps, isColumnar)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch images on host `%s`", host.Name)
+		}
+
+		nodeGrpImages := make([]*ImageRef, len(nodeGrps))
+		for imageIdx, nodeGrpIdx := range hostNodeGrpIdxs {
+			nodeGrpImages[nodeGrpIdx] = images[imageIdx]
+		}
+		hostImages[host] = nodeGrpImages
+	}
+
+	return hostImages, nil
+}
@@ -0,0 +1,118 @@
+package dockerdeploy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ImageManifest lists the trusted digests of images.  Images listed in the
+// manifest are pulled by their digest rather than their tag, and are checked
+// against it once pulled, guaranteeing the exact image contents which are used.
+type ImageManifest struct {
+	// Strict causes images which are not listed in the manifest to be
+	// rejected, rather than being used without verification.
+	Strict bool `yaml:"strict"`
+
+	// Images maps image references, exactly as they would be pulled (such
+	// as couchbase/server:enterprise-7.2.0), to their registry digests (such
+	// as sha256:...), as shown by `docker images --digests`.
+	Images map[string]string `yaml:"images"`
+}
+
+func LoadImageManifest(path string) (*ImageManifest, error) {
+	manifestBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read image manifest")
+	}
+
+	var manifest *ImageManifest
+	err = yaml.Unmarshal(manifestBytes, &manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse image manifest")
+	}
+	if manifest == nil {
+		manifest = &ImageManifest{}
+	}
+
+	for imagePath, digest := range manifest.Images {
+		if !strings.HasPrefix(digest, "sha256:") {
+			return nil, fmt.Errorf("image manifest digest for `%s` must be of the form sha256:...", imagePath)
+		}
+	}
+
+	return manifest, nil
+}
+
+// expectedDigest returns the digest an image must have, or an empty string
+// if the image is not pinned.  References which already include a digest are
+// always pinned to it.
+func (m *ImageManifest) expectedDigest(imagePath string) string {
+	if m != nil {
+		if digest, ok := m.Images[imagePath]; ok {
+			return digest
+		}
+	}
+
+	if _, digest, found := strings.Cut(imagePath, "@"); found {
+		return digest
+	}
+
+	return ""
+}
+
+// imageRepository strips the tag and digest from an image reference,
+// taking care not to confuse a registry port with a tag.
+func imageRepository(imagePath string) string {
+	repo, _, _ := strings.Cut(imagePath, "@")
+
+	tagIdx := strings.LastIndex(repo, ":")
+	if tagIdx > strings.LastIndex(repo, "/") {
+		repo = repo[:tagIdx]
+	}
+
+	return repo
+}
+
+// PinnedImagePath returns the digest-pinned form of an image reference.
+func PinnedImagePath(imagePath string, digest string) string {
+	return imageRepository(imagePath) + "@" + digest
+}
+
+// resolve returns the reference an image should be pulled by, along with the
+// digest it is pinned to, if any.
+func (m *ImageManifest) resolve(imagePath string) (string, string, error) {
+	digest := m.expectedDigest(imagePath)
+	if digest == "" {
+		if m != nil && m.Strict {
+			return "", "", deployment.NewError(deployment.ErrImageUntrusted,
+				fmt.Errorf("image `%s` is not listed in the image manifest", imagePath))
+		}
+
+		return imagePath, "", nil
+	}
+
+	return PinnedImagePath(imagePath, digest), digest, nil
+}
+
+// verify checks that the repo digests of a pulled image include the
+// digest which it was pinned to.
+func (m *ImageManifest) verify(imagePath string, digest string, repoDigests []string) error {
+	if digest == "" {
+		return nil
+	}
+
+	for _, repoDigest := range repoDigests {
+		if strings.HasSuffix(repoDigest, "@"+digest) {
+			return nil
+		}
+	}
+
+	return deployment.NewError(deployment.ErrImageUntrusted,
+		fmt.Errorf("image `%s` does not match its trusted digest %s (found %s)",
+			imagePath, digest, strings.Join(repoDigests, ", ")))
+}
@@ -17,6 +17,11 @@ type ImageDef struct {
 
 type ImageRef struct {
 	ImagePath string
+
+	// SourcePath is the reference the image was pulled as, and Digest is
+	// the trusted digest it was pinned to, if any.
+	SourcePath string
+	Digest     string
 }
 
 type ImageProvider interface {
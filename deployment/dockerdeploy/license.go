@@ -0,0 +1,90 @@
+package dockerdeploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	licenseAcceptEnvVar = "COUCHBASE_ACCEPT_LICENSE"
+	licenseFileEnvVar   = "COUCHBASE_LICENSE_FILE"
+	licenseFilePath     = "/etc/couchbase/license"
+)
+
+// NodeLicense is the license material provided to a server node.
+type NodeLicense struct {
+	Accept bool
+
+	// Data is the contents of the license file, which is written into the
+	// container before it is started.
+	Data []byte
+}
+
+func (l *NodeLicense) envVars() []string {
+	var envVars []string
+	if l.Accept {
+		envVars = append(envVars, licenseAcceptEnvVar+"=true")
+	}
+	if l.Data != nil {
+		envVars = append(envVars, licenseFileEnvVar+"="+licenseFilePath)
+	}
+	return envVars
+}
+
+// resolveLicense combines the license settings of a cluster definition with
+// the defaults of the deployer, returning nil when there is no license.
+func (d *Deployer) resolveLicense(def clusterdef.DockerLicense) (*NodeLicense, error) {
+	accept := def.Accept || d.acceptLicense
+
+	licenseFile := def.File
+	if licenseFile == "" {
+		licenseFile = d.licenseFile
+	}
+
+	if !accept && licenseFile == "" {
+		return nil, nil
+	}
+
+	license := &NodeLicense{
+		Accept: accept,
+	}
+
+	if licenseFile != "" {
+		data, err := os.ReadFile(licenseFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read license file")
+		}
+
+		license.Data = data
+	}
+
+	return license, nil
+}
+
+// writeLicense copies the license file into a container which has been
+// created but not yet started.
+func (c *Controller) writeLicense(ctx context.Context, containerID string, license *NodeLicense) error {
+	tarBuf := bytes.NewBuffer(nil)
+	tarFile := tar.NewWriter(tarBuf)
+	tarFile.WriteHeader(&tar.Header{
+		Name: strings.TrimPrefix(licenseFilePath, "/"),
+		Mode: 0644,
+		Size: int64(len(license.Data)),
+	})
+	tarFile.Write(license.Data)
+	tarFile.Close()
+
+	err := c.DockerCli.CopyToContainer(ctx, containerID, "/", tarBuf, types.CopyToContainerOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to copy license file")
+	}
+
+	return nil
+}
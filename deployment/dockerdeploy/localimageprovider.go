@@ -0,0 +1,102 @@
+package dockerdeploy
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// LocalImageProvider only provides images which are already available on
+// the docker host, whichever source they originally came from.  It never
+// pulls or builds images.
+type LocalImageProvider struct {
+	Logger    *zap.Logger
+	DockerCli *client.Client
+	Manifest  *ImageManifest
+}
+
+var _ ImageProvider = (*LocalImageProvider)(nil)
+
+func (p *LocalImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	var imagePaths []string
+	for _, pathFn := range []func(*ImageDef) (string, error){
+		dockerhubImagePath,
+		ghcrImagePath,
+	} {
+		imagePath, err := pathFn(def)
+		if err != nil {
+			continue
+		}
+
+		imagePaths = append(imagePaths, imagePath)
+	}
+
+	for _, imagePath := range imagePaths {
+		image, err := MultiArchImagePuller{
+			Logger:    p.Logger,
+			DockerCli: p.DockerCli,
+			ImagePath: imagePath,
+			Manifest:  p.Manifest,
+		}.Find(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if image != nil {
+			p.Logger.Debug("found local image", zap.String("image", imagePath))
+			return image, nil
+		}
+	}
+
+	// images built from packages are not pulled, so they are never pinned
+	// by the manifest and are looked up by tag instead.
+	tagPath, err := packageImageTag(def)
+	if err == nil {
+		images, err := p.DockerCli.ImageList(ctx, types.ImageListOptions{
+			Filters: filters.NewArgs(filters.Arg("reference", tagPath)),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list images")
+		}
+
+		if len(images) > 0 {
+			p.Logger.Debug("found local image", zap.String("image", tagPath))
+			return &ImageRef{
+				ImagePath: tagPath,
+			}, nil
+		}
+	}
+
+	return nil, errors.New("image is not available locally")
+}
+
+func (p *LocalImageProvider) GetImageRaw(ctx context.Context, imagePath string) (*ImageRef, error) {
+	image, err := MultiArchImagePuller{
+		Logger:    p.Logger,
+		DockerCli: p.DockerCli,
+		ImagePath: imagePath,
+		Manifest:  p.Manifest,
+	}.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if image == nil {
+		return nil, errors.New("image is not available locally")
+	}
+
+	return image, nil
+}
+
+func (p *LocalImageProvider) ListImages(ctx context.Context) ([]deployment.Image, error) {
+	return []deployment.Image{}, nil
+}
+
+func (p *LocalImageProvider) SearchImages(ctx context.Context, version string) ([]deployment.Image, error) {
+	return []deployment.Image{}, nil
+}
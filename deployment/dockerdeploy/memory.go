@@ -0,0 +1,86 @@
+package dockerdeploy
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/pkg/errors"
+)
+
+// oomKillsScript prints the memory events of the cgroup of a container,
+// which include the number of processes killed for exceeding its memory
+// limit.  The cgroup v1 equivalent is used where cgroup v2 is unavailable.
+const oomKillsScript = "cat /sys/fs/cgroup/memory.events 2>/dev/null || cat /sys/fs/cgroup/memory/memory.oom_control"
+
+func parseOomKills(output string) int {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, _ := strconv.Atoi(fields[1])
+			return count
+		}
+	}
+	return 0
+}
+
+// ReadOomKills returns the number of processes of a container which were
+// killed for exceeding its memory limit.  Couchbase restarts these, so they
+// otherwise only show up as mysterious failures.
+func (c *Controller) ReadOomKills(ctx context.Context, containerID string) (int, error) {
+	output, err := dockerExecOutput(ctx, c.DockerCli, containerID, []string{"sh", "-c", oomKillsScript})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read memory events")
+	}
+
+	return parseOomKills(string(output)), nil
+}
+
+type NodeMemory struct {
+	NodeID    string
+	IPAddress string
+	LimitMB   int
+	OomKills  int
+}
+
+// GetNodeMemory returns the memory limit of each node of the cluster, and
+// how many of its processes were killed for exceeding it.
+func (d *Deployer) GetNodeMemory(ctx context.Context, clusterID string) ([]*NodeMemory, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var nodeMemories []*NodeMemory
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+		if node.Type != "server-node" && node.Type != "columnar-node" {
+			continue
+		}
+
+		oomKills, err := d.getHost(node.HostName).Controller.ReadOomKills(ctx, node.ContainerID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read oom kills for node %s", node.NodeID)
+		}
+
+		limitMB := 0
+		if node.RuntimeOpts != nil {
+			limitMB = node.RuntimeOpts.MemoryLimitMB
+		}
+
+		nodeMemories = append(nodeMemories, &NodeMemory{
+			NodeID:    node.NodeID,
+			IPAddress: node.IPAddress,
+			LimitMB:   limitMB,
+			OomKills:  oomKills,
+		})
+	}
+	if len(nodeMemories) == 0 {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	return nodeMemories, nil
+}
@@ -0,0 +1,298 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type DeployMinioOptions struct {
+	// Buckets are created once MinIO is ready.
+	Buckets []string
+
+	// Region defaults to us-east-1.
+	Region string
+
+	// AccessKey and SecretKey are the root credentials of MinIO, which
+	// default to minioadmin.
+	AccessKey string
+	SecretKey string
+}
+
+// MinioInfo describes the MinIO container of a cluster.  Endpoint is the
+// address used by the nodes on the docker network, while HostEndpoint is
+// the address reachable from this machine.
+type MinioInfo struct {
+	NodeID       string
+	ContainerID  string
+	Endpoint     string
+	HostEndpoint string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	Buckets      []string
+}
+
+type s3ListAllMyBucketsResult struct {
+	Buckets []struct {
+		Name string `xml:"Name"`
+	} `xml:"Buckets>Bucket"`
+}
+
+func (i *MinioInfo) credentials() *aws.Credentials {
+	return &aws.Credentials{
+		AccessKeyID:     i.AccessKey,
+		SecretAccessKey: i.SecretKey,
+	}
+}
+
+func (d *Deployer) findMinioNode(ctx context.Context, clusterID string) (*NodeInfo, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	for _, node := range nodes {
+		if node.ClusterID == clusterID && node.Type == "minio" {
+			return node, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// DeployMinio deploys a MinIO container on the docker network of a cluster
+// with the specified buckets already created, for use as S3 compatible
+// storage by analytics links.  If any of the buckets cannot be created, the
+// container is removed again.
+func (d *Deployer) DeployMinio(ctx context.Context, clusterID string, opts *DeployMinioOptions) (*MinioInfo, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DeployMinio")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	if len(clusterInfo.Nodes) == 0 {
+		return nil, errors.New("cluster has no nodes")
+	}
+
+	existingNode, err := d.findMinioNode(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if existingNode != nil {
+		return nil, errors.New("cluster already has a minio container")
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = defaultBlobStorageRegion
+	}
+
+	accessKey := opts.AccessKey
+	secretKey := opts.SecretKey
+	if accessKey == "" && secretKey == "" {
+		accessKey = defaultMinioAccessKey
+		secretKey = defaultMinioSecretKey
+	} else if accessKey == "" || secretKey == "" {
+		return nil, errors.New("an access key and secret key must be specified together")
+	} else if len(secretKey) < 8 {
+		return nil, errors.New("minio requires a secret key of at least 8 characters")
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would deploy minio",
+			zap.String("cluster", clusterID),
+			zap.Strings("buckets", opts.Buckets))
+		return &MinioInfo{
+			Region:    region,
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			Buckets:   opts.Buckets,
+		}, nil
+	}
+
+	// minio is placed with the first node so that it shares its network
+	host := d.getHost(clusterInfo.Nodes[0].HostName)
+
+	var expiry time.Duration
+	if !clusterInfo.Expiry.IsZero() {
+		expiry = time.Until(clusterInfo.Expiry)
+	}
+
+	progress.Step(ctx, "deploying minio")
+	d.logger.Info("deploying minio")
+
+	image, err := host.ImageProvider.GetImageRaw(ctx, minioImage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get minio image")
+	}
+
+	node, err := host.Controller.DeployMinioNode(ctx, &DeployMinioNodeOptions{
+		Purpose:   clusterInfo.Purpose,
+		ClusterID: clusterID,
+		Expiry:    expiry,
+		Image:     image,
+		Region:    region,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to deploy minio node")
+	}
+
+	info := &MinioInfo{
+		NodeID:       node.NodeID,
+		ContainerID:  node.ContainerID,
+		Endpoint:     fmt.Sprintf("http://%s:%d", node.IPAddress, minioPort),
+		HostEndpoint: "http://" + d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, minioPort),
+		Region:       region,
+		AccessKey:    accessKey,
+		SecretKey:    secretKey,
+	}
+
+	for _, bucketName := range opts.Buckets {
+		d.logger.Info("creating minio bucket", zap.String("bucket", bucketName))
+
+		err := createS3Bucket(ctx, info.HostEndpoint, bucketName, region, info.credentials())
+		if err != nil {
+			// don't leave behind a container which is missing its buckets
+			d.removeNode(context.WithoutCancel(ctx), node)
+			return nil, errors.Wrapf(err, "failed to create minio bucket `%s`", bucketName)
+		}
+
+		info.Buckets = append(info.Buckets, bucketName)
+	}
+
+	d.logger.Info("minio is ready")
+
+	return info, nil
+}
+
+// GetMinio returns the details of the MinIO container of a cluster, which
+// was deployed either by DeployMinio or as the blob storage of a columnar
+// cluster.  The credentials are recovered from the container environment.
+func (d *Deployer) GetMinio(ctx context.Context, clusterID string) (*MinioInfo, error) {
+	node, err := d.findMinioNode(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, errors.New("cluster has no minio container")
+	}
+
+	inspect, err := d.getHost(node.HostName).DockerCli.ContainerInspect(ctx, node.ContainerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to inspect minio container")
+	}
+
+	info := &MinioInfo{
+		NodeID:       node.NodeID,
+		ContainerID:  node.ContainerID,
+		Endpoint:     fmt.Sprintf("http://%s:%d", node.IPAddress, minioPort),
+		HostEndpoint: "http://" + d.nodeAddress(node.HostName, node.IPAddress, node.PublishedPorts, minioPort),
+	}
+
+	for _, envVar := range inspect.Config.Env {
+		varName, varValue, _ := strings.Cut(envVar, "=")
+		switch varName {
+		case "MINIO_ROOT_USER":
+			info.AccessKey = varValue
+		case "MINIO_ROOT_PASSWORD":
+			info.SecretKey = varValue
+		case "MINIO_SITE_REGION":
+			info.Region = varValue
+		}
+	}
+
+	respBody, err := doS3Request(ctx, http.MethodGet, info.HostEndpoint+"/", info.Region, info.credentials())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list minio buckets")
+	}
+
+	var result s3ListAllMyBucketsResult
+	err = xml.Unmarshal(respBody, &result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse minio buckets")
+	}
+
+	for _, bucket := range result.Buckets {
+		info.Buckets = append(info.Buckets, bucket.Name)
+	}
+
+	return info, nil
+}
+
+// RemoveMinio removes the MinIO container of a cluster, along with any data
+// which was stored in it.
+func (d *Deployer) RemoveMinio(ctx context.Context, clusterID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RemoveMinio")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	node, err := d.findMinioNode(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return errors.New("cluster has no minio container")
+	}
+
+	d.removeNode(ctx, node)
+
+	return nil
+}
+
+// CreateS3Link creates an analytics link to S3 compatible storage, such as
+// the MinIO container of the cluster.
+func (d *Deployer) CreateS3Link(ctx context.Context, clusterID, linkName, region, endpoint, accessKey, secretKey string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateS3Link")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return controller.Controller().CreateAnalyticsS3Link(ctx, &clustercontrol.CreateAnalyticsS3LinkOptions{
+		Name:            linkName,
+		Region:          region,
+		Endpoint:        endpoint,
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+	})
+}
+
+func (d *Deployer) DropLink(ctx context.Context, clusterID, linkName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "DropLink")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return controller.Controller().DropAnalyticsLink(ctx, "", linkName)
+}
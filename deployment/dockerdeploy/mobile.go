@@ -0,0 +1,371 @@
+package dockerdeploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	syncGatewayPublicPort = 4984
+	syncGatewayAdminPort  = 4985
+
+	// syncGatewayDatabase is the name of the database which is created on
+	// the sync gateway, and which the test app is pointed at.
+	syncGatewayDatabase = "db"
+
+	syncGatewayNodeID = "sync-gateway"
+	mobileAppNodeID   = "mobile-app"
+
+	defaultMobileBucket = "mobile"
+)
+
+type syncGatewayBootstrapJson struct {
+	Server       string `json:"server"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	UseTLSServer bool   `json:"use_tls_server"`
+}
+
+type syncGatewayApiJson struct {
+	PublicInterface string `json:"public_interface"`
+	AdminInterface  string `json:"admin_interface"`
+}
+
+type syncGatewayConfigJson struct {
+	Bootstrap syncGatewayBootstrapJson `json:"bootstrap"`
+	Api       syncGatewayApiJson       `json:"api"`
+}
+
+type DeploySyncGatewayNodeOptions struct {
+	Purpose   string
+	ClusterID string
+	Expiry    time.Duration
+	Image     *ImageRef
+
+	// ServerAddress is the address of a node of the cluster the sync gateway
+	// bootstraps from, which must be reachable on the docker network.
+	ServerAddress string
+	Username      string
+	Password      string
+	BucketName    string
+}
+
+// DeploySyncGatewayNode deploys a sync gateway container which bootstraps
+// from the cluster, and creates a database backed by the specified bucket.
+func (c *Controller) DeploySyncGatewayNode(ctx context.Context, opts *DeploySyncGatewayNodeOptions) (*NodeInfo, error) {
+	nodeID := syncGatewayNodeID
+	logger := c.Logger.With(zap.String("nodeId", nodeID))
+
+	logger.Debug("deploying sync gateway node")
+
+	configBytes, err := json.Marshal(&syncGatewayConfigJson{
+		Bootstrap: syncGatewayBootstrapJson{
+			Server:       "couchbase://" + opts.ServerAddress,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			UseTLSServer: false,
+		},
+		Api: syncGatewayApiJson{
+			PublicInterface: fmt.Sprintf(":%d", syncGatewayPublicPort),
+			AdminInterface:  fmt.Sprintf(":%d", syncGatewayAdminPort),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal sync gateway config")
+	}
+
+	containerName := "cbdynnode-sgw-" + opts.ClusterID
+
+	exposedPorts, portBindings := c.publishPorts([]int{syncGatewayPublicPort, syncGatewayAdminPort})
+
+	labels := map[string]string{
+		"com.couchbase.dyncluster.cluster_id": opts.ClusterID,
+		"com.couchbase.dyncluster.type":       "sync-gateway",
+		"com.couchbase.dyncluster.purpose":    opts.Purpose,
+		"com.couchbase.dyncluster.node_id":    nodeID,
+	}
+	stateLabels := c.moveSensitiveLabels(labels)
+
+	createResult, err := c.DockerCli.ContainerCreate(ctx, &container.Config{
+		Image:        opts.Image.ImagePath,
+		ExposedPorts: exposedPorts,
+		Labels:       labels,
+		// same effect as ntp
+		Volumes: map[string]struct{}{"/etc/localtime:/etc/localtime": {}},
+	}, &container.HostConfig{
+		AutoRemove:   true,
+		NetworkMode:  container.NetworkMode(c.NetworkName),
+		PortBindings: portBindings,
+	}, nil, nil, containerName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create container")
+	}
+
+	containerID := createResult.ID
+
+	tarBuf := bytes.NewBuffer(nil)
+	tarFile := tar.NewWriter(tarBuf)
+	tarFile.WriteHeader(&tar.Header{
+		Name: "sync_gateway/config.json",
+		Mode: 0644,
+		Size: int64(len(configBytes)),
+	})
+	tarFile.Write(configBytes)
+	tarFile.Close()
+
+	err = c.DockerCli.CopyToContainer(ctx, containerID, "/etc/", tarBuf, types.CopyToContainerOptions{})
+	if err != nil {
+		c.DockerCli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+		return nil, errors.Wrap(err, "failed to write sync gateway config")
+	}
+
+	logger.Debug("container created, starting", zap.String("container", containerID))
+
+	err = c.DockerCli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start container")
+	}
+
+	node, err := c.finishSidecarNode(ctx, containerID, opts.Expiry, stateLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("container has started, waiting for it to get ready", zap.String("address", node.IPAddress))
+
+	adminAddr := c.NodeAddress(node.IPAddress, node.PublishedPorts, syncGatewayAdminPort)
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", "http://"+adminAddr+"/", nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create sync gateway request")
+		}
+		req.SetBasicAuth(opts.Username, opts.Password)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != 200 {
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(ctx.Err(), "failed to wait for sync gateway")
+			}
+
+			logger.Debug("sync gateway not ready yet", zap.Error(err))
+			time.Sleep(500 * time.Millisecond)
+			continue
+		}
+		resp.Body.Close()
+
+		break
+	}
+
+	logger.Debug("creating sync gateway database", zap.String("bucket", opts.BucketName))
+
+	dbBytes, _ := json.Marshal(map[string]interface{}{
+		"bucket":             opts.BucketName,
+		"num_index_replicas": 0,
+	})
+
+	req, err := http.NewRequestWithContext(ctx,
+		"PUT",
+		fmt.Sprintf("http://%s/%s/", adminAddr, syncGatewayDatabase),
+		bytes.NewReader(dbBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sync gateway database request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(opts.Username, opts.Password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sync gateway database")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 && resp.StatusCode != 200 {
+		return nil, fmt.Errorf("non-success status code when creating sync gateway database (code: %d)", resp.StatusCode)
+	}
+
+	logger.Debug("container is ready!")
+
+	return node, nil
+}
+
+type DeployMobileAppNodeOptions struct {
+	Purpose   string
+	ClusterID string
+	Expiry    time.Duration
+	Image     *ImageRef
+
+	// SyncGatewayUrl is the websocket endpoint of the sync gateway database,
+	// which is passed to the app in SYNC_GATEWAY_URL.
+	SyncGatewayUrl string
+}
+
+// DeployMobileAppNode deploys a test app container which is pointed at the
+// sync gateway.  The app is expected to be able to run unattended.
+func (c *Controller) DeployMobileAppNode(ctx context.Context, opts *DeployMobileAppNodeOptions) (*NodeInfo, error) {
+	nodeID := mobileAppNodeID
+	logger := c.Logger.With(zap.String("nodeId", nodeID))
+
+	logger.Debug("deploying mobile app node")
+
+	containerName := "cbdynnode-app-" + opts.ClusterID
+
+	labels := map[string]string{
+		"com.couchbase.dyncluster.cluster_id": opts.ClusterID,
+		"com.couchbase.dyncluster.type":       "mobile-app",
+		"com.couchbase.dyncluster.purpose":    opts.Purpose,
+		"com.couchbase.dyncluster.node_id":    nodeID,
+	}
+	stateLabels := c.moveSensitiveLabels(labels)
+
+	createResult, err := c.DockerCli.ContainerCreate(ctx, &container.Config{
+		Image: opts.Image.ImagePath,
+		Env: []string{
+			"SYNC_GATEWAY_URL=" + opts.SyncGatewayUrl,
+		},
+		Labels: labels,
+	}, &container.HostConfig{
+		AutoRemove:  true,
+		NetworkMode: container.NetworkMode(c.NetworkName),
+	}, nil, nil, containerName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create container")
+	}
+
+	containerID := createResult.ID
+
+	logger.Debug("container created, starting", zap.String("container", containerID))
+
+	err = c.DockerCli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start container")
+	}
+
+	return c.finishSidecarNode(ctx, containerID, opts.Expiry, stateLabels)
+}
+
+// finishSidecarNode records the expiry and sensitive labels of a newly
+// started sidecar container and fetches its node info.
+func (c *Controller) finishSidecarNode(
+	ctx context.Context,
+	containerID string,
+	expiry time.Duration,
+	stateLabels map[string]string,
+) (*NodeInfo, error) {
+	expiryTime := time.Time{}
+	if expiry > 0 {
+		expiryTime = time.Now().Add(expiry)
+	}
+
+	err := c.WriteNodeState(ctx, containerID, &DockerNodeState{
+		Expiry: expiryTime,
+		Labels: stateLabels,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed write node state")
+	}
+
+	allNodes, err := c.ListNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	for _, node := range allNodes {
+		if node.ContainerID == containerID {
+			return node, nil
+		}
+	}
+
+	return nil, errors.New("failed to find newly created container")
+}
+
+// deployMobile deploys the sync gateway and the optional test app for a
+// newly created cluster, creating the bucket backing the database.
+func (d *Deployer) deployMobile(
+	ctx context.Context,
+	clusterID string,
+	def *clusterdef.Cluster,
+	serverNode *NodeInfo,
+	username, password string,
+) ([]*NodeInfo, error) {
+	mobile := def.Docker.Mobile
+
+	bucketName := mobile.Bucket
+	if bucketName == "" {
+		bucketName = defaultMobileBucket
+	}
+
+	progress.Step(ctx, "deploying sync gateway")
+	d.logger.Info("deploying sync gateway",
+		zap.String("version", mobile.SyncGatewayVersion),
+		zap.String("bucket", bucketName))
+
+	err := d.CreateBucket(ctx, clusterID, &deployment.CreateBucketOptions{
+		Name:       bucketName,
+		RamQuotaMB: 256,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create mobile bucket")
+	}
+
+	sgwImage, err := d.imageProvider.GetImageRaw(ctx,
+		fmt.Sprintf("couchbase/sync-gateway:%s-enterprise", mobile.SyncGatewayVersion))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get sync gateway image")
+	}
+
+	var nodes []*NodeInfo
+
+	sgwNode, err := d.controller.DeploySyncGatewayNode(ctx, &DeploySyncGatewayNodeOptions{
+		Purpose:       def.Purpose,
+		ClusterID:     clusterID,
+		Expiry:        def.Expiry,
+		Image:         sgwImage,
+		ServerAddress: serverNode.IPAddress,
+		Username:      username,
+		Password:      password,
+		BucketName:    bucketName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to deploy sync gateway node")
+	}
+	nodes = append(nodes, sgwNode)
+
+	if mobile.TestAppImage != "" {
+		d.logger.Info("deploying mobile test app", zap.String("image", mobile.TestAppImage))
+
+		appImage, err := d.imageProvider.GetImageRaw(ctx, mobile.TestAppImage)
+		if err != nil {
+			return nodes, errors.Wrap(err, "failed to get test app image")
+		}
+
+		// the app runs on the docker network, so it uses the container address
+		appNode, err := d.controller.DeployMobileAppNode(ctx, &DeployMobileAppNodeOptions{
+			Purpose:   def.Purpose,
+			ClusterID: clusterID,
+			Expiry:    def.Expiry,
+			Image:     appImage,
+			SyncGatewayUrl: fmt.Sprintf("ws://%s:%d/%s",
+				sgwNode.IPAddress, syncGatewayPublicPort, syncGatewayDatabase),
+		})
+		if err != nil {
+			return nodes, errors.Wrap(err, "failed to deploy mobile app node")
+		}
+		nodes = append(nodes, appNode)
+	}
+
+	d.logger.Info("sync gateway is ready")
+
+	return nodes, nil
+}
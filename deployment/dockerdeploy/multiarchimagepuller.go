@@ -15,65 +15,95 @@ type MultiArchImagePuller struct {
 	DockerCli    *client.Client
 	RegistryAuth string
 	ImagePath    string
+
+	// Manifest, if specified, pins images to their trusted digests.
+	Manifest *ImageManifest
 }
 
-func (p MultiArchImagePuller) Pull(ctx context.Context) (*ImageRef, error) {
+// Find looks up the image amongst the images which are already available
+// locally, returning nil if it has not been pulled yet.
+func (p MultiArchImagePuller) Find(ctx context.Context) (*ImageRef, error) {
+	pullPath, digest, err := p.Manifest.resolve(p.ImagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.findImage(ctx, pullPath, digest)
+}
+
+func (p MultiArchImagePuller) findImage(ctx context.Context, pullPath string, digest string) (*ImageRef, error) {
 	images, err := p.DockerCli.ImageList(ctx, types.ImageListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", p.ImagePath)),
+		Filters: filters.NewArgs(filters.Arg("reference", pullPath)),
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list images")
 	}
 
-	if len(images) > 0 {
-		imageId := images[0].ID
-		p.Logger.Debug("identified image", zap.String("imageId", imageId))
-		return &ImageRef{ImagePath: imageId}, nil
+	if len(images) == 0 {
+		return nil, nil
 	}
 
-	p.Logger.Debug("image is not available locally, attempting to pull")
+	imageId := images[0].ID
+	p.Logger.Debug("identified image", zap.String("imageId", imageId))
 
-	err = dockerPullAndPipe(ctx, p.Logger, p.DockerCli, p.ImagePath, types.ImagePullOptions{
-		RegistryAuth: p.RegistryAuth,
-	})
+	err = p.Manifest.verify(p.ImagePath, digest, images[0].RepoDigests)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to pull from dockerhub registry")
+		return nil, err
 	}
 
-	images, err = p.DockerCli.ImageList(ctx, types.ImageListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", p.ImagePath)),
-	})
+	return &ImageRef{
+		ImagePath:  imageId,
+		SourcePath: p.ImagePath,
+		Digest:     digest,
+	}, nil
+}
+
+func (p MultiArchImagePuller) Pull(ctx context.Context) (*ImageRef, error) {
+	pullPath, digest, err := p.Manifest.resolve(p.ImagePath)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to list images after pull")
+		return nil, err
 	}
 
-	if len(images) > 0 {
-		imageId := images[0].ID
-		p.Logger.Debug("identified image", zap.String("imageId", imageId))
-		return &ImageRef{ImagePath: imageId}, nil
+	if digest != "" {
+		p.Logger.Debug("image is pinned to a digest", zap.String("image", pullPath))
 	}
 
-	p.Logger.Debug("image is still not available locally, attempting to pull amd64 image")
+	findImage := func() (*ImageRef, error) {
+		return p.findImage(ctx, pullPath, digest)
+	}
 
-	err = dockerPullAndPipe(ctx, p.Logger, p.DockerCli, p.ImagePath, types.ImagePullOptions{
-		Platform:     "linux/amd64",
+	image, err := findImage()
+	if err != nil || image != nil {
+		return image, err
+	}
+
+	p.Logger.Debug("image is not available locally, attempting to pull")
+
+	err = dockerPullAndPipe(ctx, p.Logger, p.DockerCli, pullPath, types.ImagePullOptions{
 		RegistryAuth: p.RegistryAuth,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to pull from dockerhub registry")
 	}
 
-	images, err = p.DockerCli.ImageList(ctx, types.ImageListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", p.ImagePath)),
+	image, err = findImage()
+	if err != nil || image != nil {
+		return image, err
+	}
+
+	p.Logger.Debug("image is still not available locally, attempting to pull amd64 image")
+
+	err = dockerPullAndPipe(ctx, p.Logger, p.DockerCli, pullPath, types.ImagePullOptions{
+		Platform:     "linux/amd64",
+		RegistryAuth: p.RegistryAuth,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to list images after amd64 pull")
+		return nil, errors.Wrap(err, "failed to pull from dockerhub registry")
 	}
 
-	if len(images) > 0 {
-		imageId := images[0].ID
-		p.Logger.Debug("identified image", zap.String("imageId", imageId))
-		return &ImageRef{ImagePath: imageId}, nil
+	image, err = findImage()
+	if err != nil || image != nil {
+		return image, err
 	}
 
 	return nil, errors.New("could not find referenced image")
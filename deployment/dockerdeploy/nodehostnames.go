@@ -0,0 +1,129 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nodeHostnameDomain is the domain of the hostnames generated for nodes.  It
+// uses the .test TLD, which is reserved for testing and never resolves
+// through real DNS servers.
+const nodeHostnameDomain = "cbdino.test"
+
+func shortNodeHostnameID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// nodeHostname generates the hostname of a node, which is included in the
+// certificates of the node and can be mapped to the node in a hosts file.
+func nodeHostname(clusterID, nodeID string) string {
+	return fmt.Sprintf("%s.%s.%s",
+		shortNodeHostnameID(nodeID), shortNodeHostnameID(clusterID), nodeHostnameDomain)
+}
+
+type NodeHostnameEntry struct {
+	NodeID   string
+	Hostname string
+	Address  string
+}
+
+// NodeHostnamesInfo describes how the generated hostnames of the nodes of a
+// cluster map to the addresses they are reachable at, along with connection
+// strings which use the hostnames instead of addresses.
+type NodeHostnamesInfo struct {
+	Entries    []NodeHostnameEntry
+	ConnStr    string
+	ConnStrTls string
+	Mgmt       string
+	MgmtTls    string
+}
+
+// withHostname replaces the host of an address with a hostname, keeping its
+// port.
+func withHostname(address string, hostname string) string {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return hostname
+	}
+	return net.JoinHostPort(hostname, port)
+}
+
+// GetNodeHostnames returns the generated hostnames of the nodes of a cluster
+// and the addresses they need to resolve to, so that TLS connections which
+// verify the hostname against the node certificates can be tested without
+// any DNS infrastructure.
+func (d *Deployer) GetNodeHostnames(ctx context.Context, clusterID string) (*NodeHostnamesInfo, error) {
+	thisCluster, err := d.getCluster(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	info := &NodeHostnamesInfo{}
+
+	var connstrAddrs []string
+	var connstrTlsAddrs []string
+	var mgmtAddr string
+	var mgmtTlsAddr string
+	useExternalNetwork := false
+	for _, node := range thisCluster.Nodes {
+		if !node.IsClusterNode() {
+			continue
+		}
+
+		if d.getHost(node.HostName).Controller.AdvertiseAddress != "" {
+			useExternalNetwork = true
+		}
+
+		ipAddress := node.IPAddress
+		if node.ClientIPAddress != "" {
+			ipAddress = node.ClientIPAddress
+			useExternalNetwork = true
+		}
+
+		hostname := nodeHostname(thisCluster.ClusterID, node.NodeID)
+
+		kvAddr := d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 11210)
+		kvHost, _, _ := net.SplitHostPort(kvAddr)
+		if net.ParseIP(kvHost) == nil {
+			return nil, fmt.Errorf("node %s is reached through `%s`, which is not an ip address and cannot be mapped to a hostname",
+				node.NodeID, kvHost)
+		}
+
+		info.Entries = append(info.Entries, NodeHostnameEntry{
+			NodeID:   node.NodeID,
+			Hostname: hostname,
+			Address:  kvHost,
+		})
+
+		kvTlsAddr := d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 11207)
+		connstrAddrs = append(connstrAddrs,
+			strings.TrimSuffix(withHostname(kvAddr, hostname), ":11210"))
+		connstrTlsAddrs = append(connstrTlsAddrs,
+			strings.TrimSuffix(withHostname(kvTlsAddr, hostname), ":11207"))
+
+		mgmtAddr = withHostname(d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 8091), hostname)
+		mgmtTlsAddr = withHostname(d.nodeAddress(node.HostName, ipAddress, node.PublishedPorts, 18091), hostname)
+	}
+
+	if len(info.Entries) == 0 {
+		return nil, errors.New("cluster has no nodes")
+	}
+
+	info.ConnStr = fmt.Sprintf("couchbase://%s", strings.Join(connstrAddrs, ","))
+	info.ConnStrTls = fmt.Sprintf("couchbases://%s", strings.Join(connstrTlsAddrs, ","))
+	if useExternalNetwork {
+		info.ConnStr += "?network=external"
+		info.ConnStrTls += "?network=external"
+	}
+	info.Mgmt = fmt.Sprintf("http://%s", mgmtAddr)
+	info.MgmtTls = fmt.Sprintf("https://%s", mgmtTlsAddr)
+
+	return info, nil
+}
@@ -0,0 +1,101 @@
+package dockerdeploy
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// encryptedNodeStateMagic prefixes node state which has been encrypted,
+// distinguishing it from the plaintext json state of older versions.
+var encryptedNodeStateMagic = []byte("CBDCENC1")
+
+// ErrNodeStateUndecryptable indicates that the state of a node could not be
+// decrypted, as it was encrypted by another machine or was corrupted, so the
+// expiry of the node is unknown.
+var ErrNodeStateUndecryptable = errors.New("node state cannot be decrypted")
+
+// sensitiveLabels are the labels which are stored in the encrypted node
+// state rather than on the container when node state encryption is
+// enabled, since container labels are visible to every user of a docker host.
+var sensitiveLabels = []string{
+	"com.couchbase.dyncluster.purpose",
+	"com.couchbase.dyncluster.runtime_opts",
+}
+
+func isNodeStateEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedNodeStateMagic)
+}
+
+func encryptNodeState(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gcm")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	out := append([]byte{}, encryptedNodeStateMagic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, encryptedNodeStateMagic), nil
+}
+
+func decryptNodeState(key []byte, data []byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("node state is encrypted, but there is no state key on this machine")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gcm")
+	}
+
+	data = data[len(encryptedNodeStateMagic):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted node state is truncated")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, encryptedNodeStateMagic)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt node state, it may have been encrypted by another machine")
+	}
+
+	return plaintext, nil
+}
+
+// moveSensitiveLabels removes the sensitive labels from the labels of a
+// new container when node state encryption is enabled, returning them so
+// they can be stored in the node state instead.
+func (c *Controller) moveSensitiveLabels(labels map[string]string) map[string]string {
+	if !c.EncryptState {
+		return nil
+	}
+
+	movedLabels := make(map[string]string)
+	for _, labelName := range sensitiveLabels {
+		if labelValue, ok := labels[labelName]; ok {
+			movedLabels[labelName] = labelValue
+			delete(labels, labelName)
+		}
+	}
+
+	return movedLabels
+}
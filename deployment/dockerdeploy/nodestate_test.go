@@ -0,0 +1,38 @@
+package dockerdeploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeStateEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	plaintext := []byte(`{"Expiry":"2026-01-01T00:00:00Z"}`)
+
+	encrypted, err := encryptNodeState(key, plaintext)
+	require.NoError(t, err)
+	assert.True(t, isNodeStateEncrypted(encrypted))
+	assert.False(t, isNodeStateEncrypted(plaintext))
+	assert.NotContains(t, string(encrypted), "Expiry")
+
+	decrypted, err := decryptNodeState(key, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// state encrypted by another machine cannot be read
+	otherKey := make([]byte, 32)
+	_, err = decryptNodeState(otherKey, encrypted)
+	assert.Error(t, err)
+
+	_, err = decryptNodeState(nil, encrypted)
+	assert.Error(t, err)
+
+	_, err = decryptNodeState(key, encrypted[:len(encryptedNodeStateMagic)+4])
+	assert.Error(t, err)
+}
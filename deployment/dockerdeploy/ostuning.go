@@ -0,0 +1,217 @@
+package dockerdeploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	units "github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const osTuningStatePath = "/var/cbdyncluster/ostuning"
+
+// osTuningUlimits are the resource limits recommended for running Couchbase
+// Server, which replace our defaults when tuning.
+var osTuningUlimits = []*units.Ulimit{
+	{Name: "nofile", Soft: 200000, Hard: 200000},
+	{Name: "nproc", Soft: 10000, Hard: 10000},
+	{Name: "memlock", Soft: -1, Hard: -1},
+}
+
+// osTuningSetting is a kernel setting we try to apply from inside the node
+// container.  These are not namespaced, so writing them only succeeds when
+// the container is permitted to modify the host, which is the reason every
+// setting is recorded along with whether it was actually applied.
+type osTuningSetting struct {
+	Name  string
+	Path  string
+	Value string
+}
+
+var osTuningSettings = []osTuningSetting{
+	{Name: "thp-enabled", Path: "/sys/kernel/mm/transparent_hugepage/enabled", Value: "never"},
+	{Name: "thp-defrag", Path: "/sys/kernel/mm/transparent_hugepage/defrag", Value: "never"},
+	{Name: "swappiness", Path: "/proc/sys/vm/swappiness", Value: "1"},
+}
+
+// OsTuningResult records a single OS setting applied to a node.
+type OsTuningResult struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Applied bool   `json:"applied"`
+
+	// Actual is the value in effect, which differs from Value when the
+	// setting could not be applied.
+	Actual string `json:"actual,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type OsTuningState struct {
+	AppliedAt time.Time         `json:"appliedAt"`
+	Results   []*OsTuningResult `json:"results"`
+}
+
+func osTuningActualValue(output []byte) string {
+	// THP settings list all the options with the active one in brackets
+	value := strings.TrimSpace(string(output))
+	if start := strings.Index(value, "["); start >= 0 {
+		if end := strings.Index(value[start:], "]"); end >= 0 {
+			return value[start+1 : start+end]
+		}
+	}
+	return value
+}
+
+// applyOsTuning applies the recommended kernel settings inside a started
+// node container on a best-effort basis, and records the outcome in the
+// container so it can be reported later.
+func (c *Controller) applyOsTuning(ctx context.Context, containerID string, ulimits []*units.Ulimit) (*OsTuningState, error) {
+	logger := c.Logger.With(zap.String("container", containerID))
+
+	state := &OsTuningState{
+		AppliedAt: time.Now(),
+	}
+
+	for _, ulimit := range ulimits {
+		state.Results = append(state.Results, &OsTuningResult{
+			Name:    "ulimit-" + ulimit.Name,
+			Value:   ulimit.String(),
+			Applied: true,
+		})
+	}
+
+	for _, setting := range osTuningSettings {
+		result := &OsTuningResult{
+			Name:  setting.Name,
+			Value: setting.Value,
+		}
+
+		_, err := dockerExecOutput(ctx, c.DockerCli, containerID, []string{
+			"sh", "-c", "echo " + setting.Value + " > " + setting.Path,
+		})
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		actualOutput, err := dockerExecOutput(ctx, c.DockerCli, containerID, []string{
+			"cat", setting.Path,
+		})
+		if err == nil {
+			result.Actual = osTuningActualValue(actualOutput)
+			result.Applied = result.Actual == setting.Value
+		}
+
+		if !result.Applied {
+			logger.Debug("failed to apply os setting",
+				zap.String("name", setting.Name),
+				zap.String("actual", result.Actual),
+				zap.String("error", result.Error))
+		}
+
+		state.Results = append(state.Results, result)
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal os tuning state")
+	}
+
+	tarBuf := bytes.NewBuffer(nil)
+	tarFile := tar.NewWriter(tarBuf)
+	tarFile.WriteHeader(&tar.Header{
+		Name: "cbdyncluster/ostuning",
+		Mode: 0644,
+		Size: int64(len(stateBytes)),
+	})
+	tarFile.Write(stateBytes)
+	tarFile.Close()
+
+	err = c.DockerCli.CopyToContainer(ctx, containerID, "/var/", tarBuf, types.CopyToContainerOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write os tuning state")
+	}
+
+	return state, nil
+}
+
+// ReadOsTuning reads the OS settings which were applied to a node, returning
+// nil if the node was not deployed with OS tuning.
+func (c *Controller) ReadOsTuning(ctx context.Context, containerID string) (*OsTuningState, error) {
+	resp, _, err := c.DockerCli.CopyFromContainer(ctx, containerID, osTuningStatePath)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read os tuning state")
+	}
+	defer resp.Close()
+
+	tarRdr := tar.NewReader(resp)
+	_, err = tarRdr.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse os tuning state")
+	}
+
+	stateBytes, err := io.ReadAll(tarRdr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read os tuning state")
+	}
+
+	var state *OsTuningState
+	err = json.Unmarshal(stateBytes, &state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse os tuning state")
+	}
+
+	return state, nil
+}
+
+type NodeOsTuning struct {
+	NodeID    string
+	IPAddress string
+	State     *OsTuningState
+}
+
+// GetOsTuning returns the OS settings which were applied to each node of
+// the cluster.  Nodes deployed without OS tuning have a nil state.
+func (d *Deployer) GetOsTuning(ctx context.Context, clusterID string) ([]*NodeOsTuning, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var nodeTunings []*NodeOsTuning
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+		if node.Type != "server-node" && node.Type != "columnar-node" {
+			continue
+		}
+
+		state, err := d.getHost(node.HostName).Controller.ReadOsTuning(ctx, node.ContainerID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read os tuning for node %s", node.NodeID)
+		}
+
+		nodeTunings = append(nodeTunings, &NodeOsTuning{
+			NodeID:    node.NodeID,
+			IPAddress: node.IPAddress,
+			State:     state,
+		})
+	}
+	if len(nodeTunings) == 0 {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	return nodeTunings, nil
+}
@@ -0,0 +1,174 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultPackageURL is where release packages are downloaded from when a
+// package provider does not specify its own package url.
+const DefaultPackageURL = "https://packages.couchbase.com/releases/{version}/couchbase-server-{edition}_{version}-linux_{arch}.deb"
+
+// PackageImageProvider builds server images by installing the server
+// package onto a base image, for versions which have no published image.
+type PackageImageProvider struct {
+	Logger    *zap.Logger
+	DockerCli *client.Client
+
+	// PackageURL is the url of the package to install, where {version},
+	// {build}, {edition} and {arch} are replaced to match the image.
+	PackageURL string
+}
+
+var _ ImageProvider = (*PackageImageProvider)(nil)
+
+// packageImageTag returns the tag which images built from packages are
+// given.
+func packageImageTag(def *ImageDef) (string, error) {
+	if def.UseServerless {
+		return "", errors.New("cannot use packages for serverless releases")
+	}
+	if def.UseColumnar {
+		return "", errors.New("cannot use packages for columnar releases")
+	}
+
+	serverVersion := def.Version
+	if def.BuildNo > 0 {
+		serverVersion = fmt.Sprintf("%s-%d", def.Version, def.BuildNo)
+	}
+
+	return fmt.Sprintf("dynclst-package-server:%s-%s", imageEdition(def), serverVersion), nil
+}
+
+func imageEdition(def *ImageDef) string {
+	if def.UseCommunityEdition {
+		return "community"
+	}
+	return "enterprise"
+}
+
+func (p *PackageImageProvider) packageURL(ctx context.Context, def *ImageDef) (string, error) {
+	packageURL := p.PackageURL
+	if packageURL == "" {
+		packageURL = DefaultPackageURL
+	}
+
+	if def.BuildNo > 0 && !strings.Contains(packageURL, "{build}") {
+		return "", errors.New("cannot use a package url without a {build} for non-ga releases")
+	}
+
+	info, err := p.DockerCli.Info(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get docker host info")
+	}
+
+	var arch string
+	switch info.Architecture {
+	case "x86_64", "amd64":
+		arch = "amd64"
+	case "aarch64", "arm64":
+		arch = "arm64"
+	default:
+		return "", fmt.Errorf("unsupported docker host architecture: %s", info.Architecture)
+	}
+
+	return strings.NewReplacer(
+		"{version}", def.Version,
+		"{build}", strconv.Itoa(def.BuildNo),
+		"{edition}", imageEdition(def),
+		"{arch}", arch,
+	).Replace(packageURL), nil
+}
+
+func (p *PackageImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	fullTagPath, err := packageImageTag(def)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := p.DockerCli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", fullTagPath)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list images")
+	}
+
+	if len(images) > 0 {
+		p.Logger.Debug("found existing image with this tag")
+
+		return &ImageRef{
+			ImagePath: fullTagPath,
+		}, nil
+	}
+
+	packageURL, err := p.packageURL(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Logger.Debug("starting image build",
+		zap.String("image", fullTagPath),
+		zap.String("package", packageURL))
+
+	err = dockerBuildEmbeddedAndPipe(ctx, p.Logger, p.DockerCli, "dockerfiles/package", types.ImageBuildOptions{
+		BuildArgs: map[string]*string{
+			"PACKAGE_URL": &packageURL,
+		},
+		Labels: map[string]string{
+			"cbdyncluster": "true",
+		},
+		Tags: []string{fullTagPath},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build image")
+	}
+
+	return &ImageRef{
+		ImagePath:  fullTagPath,
+		SourcePath: packageURL,
+	}, nil
+}
+
+func (p *PackageImageProvider) GetImageRaw(ctx context.Context, imagePath string) (*ImageRef, error) {
+	return nil, errors.New("package provider does not support raw fetches")
+}
+
+func (p *PackageImageProvider) ListImages(ctx context.Context) ([]deployment.Image, error) {
+	dkrImages, err := p.DockerCli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", "dynclst-package-server")),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list images")
+	}
+
+	var images []deployment.Image
+	for _, image := range dkrImages {
+		for _, repoTag := range image.RepoTags {
+			tagParts := strings.Split(repoTag, ":")
+			if len(tagParts) != 2 {
+				return nil, fmt.Errorf("encountered unexpected image name: %s", repoTag)
+			}
+
+			images = append(images, deployment.Image{
+				Source:     "package",
+				Name:       tagParts[1],
+				SourcePath: repoTag,
+			})
+		}
+	}
+
+	return images, nil
+}
+
+func (p *PackageImageProvider) SearchImages(ctx context.Context, version string) ([]deployment.Image, error) {
+	return []deployment.Image{}, nil
+}
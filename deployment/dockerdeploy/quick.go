@@ -0,0 +1,75 @@
+package dockerdeploy
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	ProfileStandard = "standard"
+	ProfileQuick    = "quick"
+	ProfileSmall    = "small"
+)
+
+// QuickProfileDefinition collapses a cluster definition into a single
+// container running every requested service with minimal memory quotas,
+// returning whether the definition had more than one node.  The released
+// server images cannot run ns_server's cluster_run, so a single
+// "devcontainer" style node is the fastest topology we can start, typically
+// in well under 30 seconds.
+func QuickProfileDefinition(def *clusterdef.Cluster) (*clusterdef.Cluster, bool, error) {
+	if def.Columnar {
+		return nil, false, errors.New("the quick profile does not support columnar clusters")
+	}
+	if len(def.NodeGroups) == 0 {
+		return nil, false, errors.New("at least one node group must be specified")
+	}
+
+	firstGrp := def.NodeGroups[0]
+
+	var services []clusterdef.Service
+	for _, nodeGrp := range def.NodeGroups {
+		if nodeGrp.Version != firstGrp.Version {
+			return nil, false, errors.New("the quick profile does not support mixed-version clusters")
+		}
+
+		grpServices := nodeGrp.Services
+		if len(grpServices) == 0 {
+			grpServices = []clusterdef.Service{
+				clusterdef.KvService,
+				clusterdef.IndexService,
+				clusterdef.QueryService,
+				clusterdef.SearchService,
+			}
+		}
+
+		for _, service := range grpServices {
+			if !slices.Contains(services, service) {
+				services = append(services, service)
+			}
+		}
+	}
+
+	quickDef := *def
+	quickDef.NodeGroups = []*clusterdef.NodeGroup{
+		{
+			Count:    1,
+			Version:  firstGrp.Version,
+			Services: services,
+			Docker:   firstGrp.Docker,
+		},
+	}
+
+	// these are the minimum quotas server permits, every service shares
+	// the memory of the one node
+	quickDef.Docker.KvMemoryMB = 256
+	quickDef.Docker.IndexMemoryMB = 256
+	quickDef.Docker.FtsMemoryMB = 256
+	quickDef.Docker.CbasMemoryMB = 1024
+	quickDef.Docker.EventingMemoryMB = 256
+
+	collapsed := len(def.NodeGroups) > 1 || firstGrp.Count > 1
+
+	return &quickDef, collapsed, nil
+}
@@ -0,0 +1,34 @@
+package dockerdeploy
+
+import (
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickProfileDefinition(t *testing.T) {
+	def := &clusterdef.Cluster{
+		NodeGroups: []*clusterdef.NodeGroup{
+			{Count: 2, Version: "7.2.0", Services: []clusterdef.Service{clusterdef.KvService}},
+			{Count: 1, Version: "7.2.0", Services: []clusterdef.Service{clusterdef.QueryService}},
+		},
+	}
+	def.Docker.KvMemoryMB = 1536
+
+	quickDef, collapsed, err := QuickProfileDefinition(def)
+	require.NoError(t, err)
+	assert.True(t, collapsed)
+	if assert.Len(t, quickDef.NodeGroups, 1) {
+		assert.Equal(t, 1, quickDef.NodeGroups[0].Count)
+		assert.Equal(t, []clusterdef.Service{clusterdef.KvService, clusterdef.QueryService},
+			quickDef.NodeGroups[0].Services)
+	}
+	assert.Equal(t, 256, quickDef.Docker.KvMemoryMB)
+
+	// a definition which is already a single node is not collapsed
+	_, collapsed, err = QuickProfileDefinition(quickDef)
+	require.NoError(t, err)
+	assert.False(t, collapsed)
+}
@@ -0,0 +1,403 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/progress"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// RebootModeContainer restarts the container of the node, as if the
+	// machine running it was rebooted.
+	RebootModeContainer = "container"
+
+	// RebootModeService restarts the couchbase-server service within the
+	// container of the node, as if the service was restarted by an operator.
+	RebootModeService = "service"
+)
+
+type RebootNodeScenarioOptions struct {
+	// NodeID is the node to reboot, when empty the last data node of the
+	// cluster is selected.
+	NodeID string
+
+	// Mode is either RebootModeContainer or RebootModeService, defaulting
+	// to RebootModeContainer.
+	Mode string
+
+	// DownTime is how long the node stays down for before it is started
+	// again.  Containers are paused during this time, as stopping them
+	// removes them.
+	DownTime time.Duration
+
+	// ObserveTimeout is how long to wait for the node to become healthy
+	// again after it has been started.
+	ObserveTimeout time.Duration
+
+	// Recover adds the node back into the cluster with delta recovery and
+	// rebalances, if it was automatically failed over.
+	Recover bool
+}
+
+type RebootNodeEventType string
+
+const (
+	RebootNodeEventStopped            RebootNodeEventType = "stopped"
+	RebootNodeEventStarted            RebootNodeEventType = "started"
+	RebootNodeEventDownDetected       RebootNodeEventType = "down-detected"
+	RebootNodeEventFailedOver         RebootNodeEventType = "failed-over"
+	RebootNodeEventWarmup             RebootNodeEventType = "warmup"
+	RebootNodeEventHealthy            RebootNodeEventType = "healthy"
+	RebootNodeEventRebalanceStarted   RebootNodeEventType = "rebalance-started"
+	RebootNodeEventRebalanceCompleted RebootNodeEventType = "rebalance-completed"
+)
+
+type RebootNodeEvent struct {
+	Time time.Time
+	Type RebootNodeEventType
+}
+
+type RebootNodeScenarioResult struct {
+	NodeID  string
+	OTPNode string
+
+	AutoFailoverEnabled bool
+	AutoFailoverTimeout time.Duration
+
+	// FailedOver indicates that the node was automatically failed over
+	// while it was down.
+	FailedOver bool
+
+	// Recovered indicates that the node was added back into the cluster
+	// after it was failed over.
+	Recovered bool
+
+	StartTime time.Time
+	Events    []RebootNodeEvent
+}
+
+// rebootObserver polls the cluster from a surviving node, recording changes
+// in the health and membership of the rebooted node, and in rebalances.
+type rebootObserver struct {
+	logger     *zap.Logger
+	controller *clustercontrol.Controller
+	otpNode    string
+
+	lock        sync.Mutex
+	events      []RebootNodeEvent
+	status      string
+	membership  string
+	rebalancing bool
+}
+
+func (o *rebootObserver) addEvent(eventType RebootNodeEventType) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.logger.Info("observed reboot event", zap.String("event", string(eventType)))
+	o.events = append(o.events, RebootNodeEvent{
+		Time: time.Now(),
+		Type: eventType,
+	})
+}
+
+func (o *rebootObserver) poll(ctx context.Context) {
+	nodeStatuses, err := o.controller.ListNodeStatuses(ctx)
+	if err != nil {
+		o.logger.Debug("failed to poll node statuses", zap.Error(err))
+		return
+	}
+
+	for _, nodeStatus := range nodeStatuses {
+		if nodeStatus.OTPNode != o.otpNode {
+			continue
+		}
+
+		o.lock.Lock()
+		prevStatus := o.status
+		prevMembership := o.membership
+		o.status = nodeStatus.Status
+		o.membership = nodeStatus.ClusterMembership
+		o.lock.Unlock()
+
+		if nodeStatus.Status != prevStatus && prevStatus != "" {
+			switch nodeStatus.Status {
+			case "unhealthy":
+				o.addEvent(RebootNodeEventDownDetected)
+			case "warmup":
+				o.addEvent(RebootNodeEventWarmup)
+			case "healthy":
+				o.addEvent(RebootNodeEventHealthy)
+			}
+		}
+
+		if nodeStatus.ClusterMembership != prevMembership &&
+			nodeStatus.ClusterMembership == "inactiveFailed" {
+			o.addEvent(RebootNodeEventFailedOver)
+		}
+	}
+
+	tasks, err := o.controller.ListTasks(ctx)
+	if err != nil {
+		o.logger.Debug("failed to poll tasks", zap.Error(err))
+		return
+	}
+
+	rebalancing := false
+	for _, task := range tasks {
+		if task.GetType() == "rebalance" && task.GetStatus() == "running" {
+			rebalancing = true
+		}
+	}
+
+	o.lock.Lock()
+	wasRebalancing := o.rebalancing
+	o.rebalancing = rebalancing
+	o.lock.Unlock()
+
+	if rebalancing && !wasRebalancing {
+		o.addEvent(RebootNodeEventRebalanceStarted)
+	} else if !rebalancing && wasRebalancing {
+		o.addEvent(RebootNodeEventRebalanceCompleted)
+	}
+}
+
+func (o *rebootObserver) state() (string, string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.status, o.membership
+}
+
+func (o *rebootObserver) run(ctx context.Context) {
+	for {
+		o.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+// RunRebootNodeScenario reboots a node of the cluster while observing it
+// from the rest of the cluster, recording when the node was detected as down,
+// whether it was automatically failed over, and when it became healthy again.
+func (d *Deployer) RunRebootNodeScenario(ctx context.Context, clusterID string, opts *RebootNodeScenarioOptions) (*RebootNodeScenarioResult, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RunRebootNodeScenario")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = RebootModeContainer
+	}
+	if mode != RebootModeContainer && mode != RebootModeService {
+		return nil, fmt.Errorf("invalid reboot mode `%s`, expected container or service", mode)
+	}
+
+	observeTimeout := opts.ObserveTimeout
+	if observeTimeout <= 0 {
+		observeTimeout = 5 * time.Minute
+	}
+
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster info")
+	}
+
+	if len(clusterInfo.Nodes) < 2 {
+		return nil, errors.New("rebooting a node requires a cluster with at least two nodes")
+	}
+
+	rebootNode, err := d.selectScenarioNode(ctx, clusterID, clusterInfo, opts.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cluster is observed from a node which is not rebooted
+	var ctrlNode *deployedNodeInfo
+	for _, clusterNode := range clusterInfo.Nodes {
+		if clusterNode != rebootNode {
+			ctrlNode = clusterNode
+			break
+		}
+	}
+
+	nodeCtrl := &clustercontrol.NodeManager{
+		Endpoint: d.mgmtEndpoint(ctrlNode.HostName, ctrlNode.IPAddress, ctrlNode.PublishedPorts),
+	}
+	controller := nodeCtrl.Controller()
+
+	autoFailover, err := controller.GetAutoFailoverSettings(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get auto-failover settings")
+	}
+
+	result := &RebootNodeScenarioResult{
+		NodeID:              rebootNode.NodeID,
+		OTPNode:             rebootNode.OTPNode,
+		AutoFailoverEnabled: autoFailover.Enabled,
+		AutoFailoverTimeout: time.Duration(autoFailover.Timeout) * time.Second,
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would run reboot node scenario",
+			zap.String("otp", rebootNode.OTPNode),
+			zap.String("mode", mode),
+			zap.Duration("downTime", opts.DownTime))
+		return result, nil
+	}
+
+	if !autoFailover.Enabled {
+		d.logger.Warn("auto-failover is disabled, so the node will not be failed over")
+	} else if opts.DownTime < result.AutoFailoverTimeout {
+		d.logger.Info("node is down for less than the auto-failover timeout, it may not be failed over",
+			zap.Duration("downTime", opts.DownTime),
+			zap.Duration("autoFailoverTimeout", result.AutoFailoverTimeout))
+	}
+
+	observer := &rebootObserver{
+		logger:     d.logger,
+		controller: controller,
+		otpNode:    rebootNode.OTPNode,
+	}
+	observer.poll(ctx)
+
+	observeCtx, stopObserving := context.WithCancel(ctx)
+	observerDone := make(chan struct{})
+	go func() {
+		observer.run(observeCtx)
+		close(observerDone)
+	}()
+	defer func() {
+		stopObserving()
+		<-observerDone
+	}()
+
+	hostCtrl := d.getHost(rebootNode.HostName).Controller
+	result.StartTime = time.Now()
+
+	progress.Step(ctx, "stopping node")
+	d.logger.Info("stopping node",
+		zap.String("otp", rebootNode.OTPNode),
+		zap.String("mode", mode))
+
+	if mode == RebootModeService {
+		err := hostCtrl.execCmd(ctx, rebootNode.ContainerID, []string{"sv", "down", couchbaseServicePath})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to stop couchbase-server service")
+		}
+	} else if opts.DownTime > 0 {
+		err := hostCtrl.DockerCli.ContainerPause(ctx, rebootNode.ContainerID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to pause node container")
+		}
+	}
+	observer.addEvent(RebootNodeEventStopped)
+
+	if opts.DownTime > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(opts.DownTime):
+		}
+	}
+
+	// the node is always started again, even if we were cancelled while it
+	// was down, so that it is not left stopped
+	startCtx := context.WithoutCancel(ctx)
+
+	progress.Step(ctx, "starting node")
+	d.logger.Info("starting node",
+		zap.String("otp", rebootNode.OTPNode))
+
+	if mode == RebootModeService {
+		err := hostCtrl.execCmd(startCtx, rebootNode.ContainerID, []string{"sv", "up", couchbaseServicePath})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start couchbase-server service")
+		}
+	} else {
+		if opts.DownTime > 0 {
+			err := hostCtrl.DockerCli.ContainerUnpause(startCtx, rebootNode.ContainerID)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to unpause node container")
+			}
+		}
+
+		err := hostCtrl.DockerCli.ContainerRestart(startCtx, rebootNode.ContainerID, container.StopOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to restart node container")
+		}
+	}
+	observer.addEvent(RebootNodeEventStarted)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	progress.Step(ctx, "waiting for node to become healthy")
+	d.logger.Info("waiting for node to become healthy")
+
+	deadline := time.Now().Add(observeTimeout)
+	for {
+		status, membership := observer.state()
+		if status == "healthy" {
+			result.FailedOver = membership == "inactiveFailed"
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for node to become healthy")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
+	}
+
+	if result.FailedOver && opts.Recover {
+		progress.Step(ctx, "recovering node")
+		d.logger.Info("recovering failed over node",
+			zap.String("otp", rebootNode.OTPNode))
+
+		err := controller.SetRecoveryType(ctx, rebootNode.OTPNode, "delta")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set recovery type")
+		}
+
+		err = nodeCtrl.Rebalance(ctx, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start rebalance")
+		}
+
+		err = nodeCtrl.WaitForNoRunningTasks(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to wait for rebalance to complete")
+		}
+
+		// make sure the end of the rebalance is observed
+		observer.poll(ctx)
+
+		result.Recovered = true
+	}
+
+	stopObserving()
+	<-observerDone
+
+	observer.lock.Lock()
+	result.Events = observer.events
+	observer.lock.Unlock()
+
+	return result, nil
+}
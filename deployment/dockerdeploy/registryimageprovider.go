@@ -0,0 +1,168 @@
+package dockerdeploy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RegistryImageProvider pulls server images from any registry, such as an
+// internal mirror of dockerhub or ghcr.
+type RegistryImageProvider struct {
+	Logger    *zap.Logger
+	DockerCli *client.Client
+
+	// RegistryURL is the reference of the server images within the
+	// registry, where {version}, {build} and {edition} are replaced to match
+	// the image, such as registry.example.com/couchbase/server:{edition}-{version}.
+	RegistryURL string
+
+	// Username and Password are the credentials of the registry, which are
+	// not needed for anonymous pulls.
+	Username string
+	Password string
+
+	Manifest *ImageManifest
+}
+
+var _ ImageProvider = (*RegistryImageProvider)(nil)
+
+// registryServerlessTag returns the tag which distinguishes serverless images
+// built from the images of a registry, as tags cannot contain the url itself.
+func registryServerlessTag(registryURL string) string {
+	urlHash := sha256.Sum256([]byte(registryURL))
+	return "registry-" + hex.EncodeToString(urlHash[:4])
+}
+
+func (p *RegistryImageProvider) genAuthStr() string {
+	if p.Username == "" && p.Password == "" {
+		return ""
+	}
+
+	authConfigJson, _ := json.Marshal(types.AuthConfig{
+		Username: p.Username,
+		Password: p.Password,
+	})
+	return base64.StdEncoding.EncodeToString(authConfigJson)
+}
+
+// repository returns the image reference without its tag.
+func (p *RegistryImageProvider) repository() string {
+	tagIdx := strings.LastIndex(p.RegistryURL, ":")
+	if tagIdx <= strings.LastIndex(p.RegistryURL, "/") {
+		return p.RegistryURL
+	}
+	return p.RegistryURL[:tagIdx]
+}
+
+// registryHost returns the host of the registry, which images must be within
+// for the credentials of this registry to be used with them.
+func (p *RegistryImageProvider) registryHost() string {
+	registryHost, _, _ := strings.Cut(p.RegistryURL, "/")
+	return registryHost
+}
+
+func (p *RegistryImageProvider) imagePath(def *ImageDef) (string, error) {
+	if def.UseServerless {
+		return "", errors.New("cannot use a registry for serverless releases")
+	}
+	if def.UseColumnar {
+		return "", errors.New("cannot use a registry for columnar releases")
+	}
+
+	if def.BuildNo > 0 && !strings.Contains(p.RegistryURL, "{build}") {
+		return "", errors.New("cannot use a registry url without a {build} for non-ga releases")
+	}
+	if def.BuildNo == 0 && strings.Contains(p.RegistryURL, "{build}") {
+		return "", errors.New("cannot use a registry url with a {build} for ga releases")
+	}
+
+	return strings.NewReplacer(
+		"{version}", def.Version,
+		"{build}", strconv.Itoa(def.BuildNo),
+		"{edition}", imageEdition(def),
+	).Replace(p.RegistryURL), nil
+}
+
+func (p *RegistryImageProvider) GetImage(ctx context.Context, def *ImageDef) (*ImageRef, error) {
+	imagePath, err := p.imagePath(def)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Logger.Debug("identified registry image to pull", zap.String("image", imagePath))
+
+	return MultiArchImagePuller{
+		Logger:       p.Logger,
+		DockerCli:    p.DockerCli,
+		RegistryAuth: p.genAuthStr(),
+		ImagePath:    imagePath,
+		Manifest:     p.Manifest,
+	}.Pull(ctx)
+}
+
+func (p *RegistryImageProvider) GetImageRaw(ctx context.Context, imagePath string) (*ImageRef, error) {
+	// the credentials are only for this registry, so they must never be sent
+	// along with pulls of images from other registries
+	if !strings.HasPrefix(imagePath, p.registryHost()+"/") {
+		return nil, errors.New("image is not within this registry")
+	}
+
+	return MultiArchImagePuller{
+		Logger:       p.Logger,
+		DockerCli:    p.DockerCli,
+		RegistryAuth: p.genAuthStr(),
+		ImagePath:    imagePath,
+		Manifest:     p.Manifest,
+	}.Pull(ctx)
+}
+
+func (p *RegistryImageProvider) ListImages(ctx context.Context) ([]deployment.Image, error) {
+	repository := p.repository()
+	if strings.ContainsAny(repository, "{}") {
+		// the versions are part of the repository, which cannot be listed
+		return []deployment.Image{}, nil
+	}
+
+	dkrImages, err := p.DockerCli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", repository)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list images")
+	}
+
+	var images []deployment.Image
+	for _, image := range dkrImages {
+		for _, repoTag := range image.RepoTags {
+			tagIdx := strings.LastIndex(repoTag, ":")
+			if tagIdx < 0 {
+				return nil, fmt.Errorf("encountered unexpected image name: %s", repoTag)
+			}
+
+			images = append(images, deployment.Image{
+				Source:     "registry",
+				Name:       repoTag[tagIdx+1:],
+				SourcePath: repoTag,
+			})
+		}
+	}
+
+	return images, nil
+}
+
+func (p *RegistryImageProvider) SearchImages(ctx context.Context, version string) ([]deployment.Image, error) {
+	// registries have no common way to search them
+	return []deployment.Image{}, nil
+}
@@ -0,0 +1,286 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type ContainerResourceUsage struct {
+	CpuPercent    float64
+	MemUsedBytes  uint64
+	MemLimitBytes uint64
+	DiskUsedBytes int64
+
+	// CpuLimit is the number of cpus available to the container, which
+	// CpuPercent is relative to (up to CpuLimit * 100%).
+	CpuLimit float64
+
+	// VolumeUsedBytes is the size of the data volume of the node, which
+	// is not included in DiskUsedBytes.  It is left zero if it could not
+	// be determined.
+	VolumeUsedBytes int64
+}
+
+// nodeDataPath is the data directory of server nodes, which the server
+// images declare as a volume.
+const nodeDataPath = "/opt/couchbase/var"
+
+// GetResourceUsage samples the resource usage of a container.  Docker takes
+// two samples in order to calculate the cpu usage, so this blocks for about
+// a second.
+func (c *Controller) GetResourceUsage(ctx context.Context, containerID string) (*ContainerResourceUsage, error) {
+	// the stats docker reports differ between cgroup v1 and v2, so we need
+	// to know which the host uses to interpret them
+	dockerInfo, err := c.DockerCli.Info(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch docker info")
+	}
+
+	statsResp, err := c.DockerCli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get container stats")
+	}
+	defer statsResp.Body.Close()
+
+	var stats types.StatsJSON
+	err = json.NewDecoder(statsResp.Body).Decode(&stats)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode container stats")
+	}
+
+	usage := containerStatsUsage(&stats, &dockerInfo)
+
+	containerInfo, _, err := c.DockerCli.ContainerInspectWithRaw(ctx, containerID, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to inspect container")
+	}
+
+	diskUsed := int64(0)
+	if containerInfo.SizeRw != nil {
+		diskUsed = *containerInfo.SizeRw
+	}
+
+	cpuLimit := usage.CpuLimit
+	if containerInfo.HostConfig != nil {
+		if containerInfo.HostConfig.NanoCPUs > 0 {
+			cpuLimit = float64(containerInfo.HostConfig.NanoCPUs) / 1e9
+		} else if containerInfo.HostConfig.CPUQuota > 0 && containerInfo.HostConfig.CPUPeriod > 0 {
+			cpuLimit = float64(containerInfo.HostConfig.CPUQuota) / float64(containerInfo.HostConfig.CPUPeriod)
+		}
+	}
+
+	volumeUsed := int64(0)
+	for _, mount := range containerInfo.Mounts {
+		if mount.Destination != nodeDataPath {
+			continue
+		}
+
+		volumeUsed, err = c.getPathUsage(ctx, containerID, nodeDataPath)
+		if err != nil {
+			c.Logger.Debug("failed to get data volume usage", zap.Error(err))
+		}
+	}
+
+	usage.DiskUsedBytes = diskUsed
+	usage.CpuLimit = cpuLimit
+	usage.VolumeUsedBytes = volumeUsed
+	return usage, nil
+}
+
+// containerStatsUsage calculates the cpu and memory usage of a container from
+// its stats, the same way the docker cli does.
+func containerStatsUsage(stats *types.StatsJSON, dockerInfo *types.Info) *ContainerResourceUsage {
+	cgroupV2 := dockerInfo.CgroupVersion == "2"
+
+	// with cgroup v2, the per-cpu usage is not reported, so only the online
+	// cpus can be used, falling back to the cpus of the host.
+	numCpus := float64(stats.CPUStats.OnlineCPUs)
+	if numCpus == 0 && !cgroupV2 {
+		numCpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if numCpus == 0 {
+		numCpus = float64(dockerInfo.NCPU)
+	}
+
+	cpuPercent := 0.0
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		cpuPercent = cpuDelta / systemDelta * numCpus * 100
+	}
+
+	// page cache is included in the usage reported by docker, so we remove
+	// it.  cgroup v2 reports it as inactive_file, while cgroup v1 reports
+	// total_inactive_file, or only cache on older kernels.
+	var cacheKeys []string
+	if cgroupV2 {
+		cacheKeys = []string{"inactive_file"}
+	} else {
+		cacheKeys = []string{"total_inactive_file", "cache"}
+	}
+
+	memUsed := stats.MemoryStats.Usage
+	for _, cacheKey := range cacheKeys {
+		if cache, ok := stats.MemoryStats.Stats[cacheKey]; ok {
+			if cache < memUsed {
+				memUsed -= cache
+			}
+			break
+		}
+	}
+
+	// cgroup v2 reports an unlimited container as having the maximum
+	// possible limit, rather than the memory of the host.
+	memLimit := stats.MemoryStats.Limit
+	if dockerInfo.MemTotal > 0 && memLimit > uint64(dockerInfo.MemTotal) {
+		memLimit = uint64(dockerInfo.MemTotal)
+	}
+
+	return &ContainerResourceUsage{
+		CpuPercent:    cpuPercent,
+		MemUsedBytes:  memUsed,
+		MemLimitBytes: memLimit,
+		CpuLimit:      numCpus,
+	}
+}
+
+// getPathUsage returns the disk space used by a path within a container.
+// Docker does not report the size of individual volumes cheaply, so we
+// ask the container itself.
+func (c *Controller) getPathUsage(ctx context.Context, containerID string, path string) (int64, error) {
+	out, err := dockerExecOutput(ctx, c.DockerCli, containerID, []string{"du", "-sb", path})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to execute du")
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return 0, errors.New("unexpected du output")
+	}
+
+	usedBytes, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse du output")
+	}
+
+	return usedBytes, nil
+}
+
+// PopulateResourceUsage samples the resource usage of the nodes of a
+// cluster, populating their Usage.  Nodes which cannot be sampled are
+// left without usage.
+func (d *Deployer) PopulateResourceUsage(ctx context.Context, cluster *ClusterInfo) {
+	var wg sync.WaitGroup
+	for _, node := range cluster.Nodes {
+		wg.Add(1)
+		go func(node *ClusterNodeInfo) {
+			defer wg.Done()
+
+			usage, err := d.getHost(node.HostName).Controller.GetResourceUsage(ctx, node.ContainerID)
+			if err != nil {
+				d.logger.Debug("failed to get node resource usage",
+					zap.String("container", node.ContainerID),
+					zap.Error(err))
+				return
+			}
+
+			node.Usage = usage
+		}(node)
+	}
+	wg.Wait()
+}
+
+type NodeResourceUsage struct {
+	NodeID string
+	Name   string
+	ContainerResourceUsage
+
+	// The following are the basic couchbase stats of the node, which are
+	// left zero if the node could not be reached.
+	ItemCount         int64
+	OpsPerSec         float64
+	DataMemUsedBytes  int64
+	DataDiskUsedBytes int64
+}
+
+type ResourceUsageSample struct {
+	Time  time.Time
+	Nodes []*NodeResourceUsage
+}
+
+// SampleResourceUsage samples the resource usage of every node in a cluster.
+// Couchbase stats are best-effort, since the point of recording these is to
+// diagnose clusters which may be struggling.
+func (d *Deployer) SampleResourceUsage(ctx context.Context, clusterID string) (*ResourceUsageSample, error) {
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	sample := &ResourceUsageSample{
+		Time: time.Now(),
+	}
+
+	var serverNodes []*NodeInfo
+	for _, node := range nodes {
+		if node.ClusterID != clusterID {
+			continue
+		}
+		if node.Type != "server-node" && node.Type != "columnar-node" {
+			continue
+		}
+
+		usage, err := d.getHost(node.HostName).Controller.GetResourceUsage(ctx, node.ContainerID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get resource usage of node %s", node.NodeID)
+		}
+
+		serverNodes = append(serverNodes, node)
+		sample.Nodes = append(sample.Nodes, &NodeResourceUsage{
+			NodeID:                 node.NodeID,
+			Name:                   node.Name,
+			ContainerResourceUsage: *usage,
+		})
+	}
+	if len(serverNodes) == 0 {
+		return nil, deployment.ErrClusterNotFound
+	}
+
+	controller := &clustercontrol.Controller{
+		Endpoint: d.mgmtEndpoint(serverNodes[0].HostName, serverNodes[0].IPAddress, serverNodes[0].PublishedPorts),
+	}
+
+	nodeStats, err := controller.ListNodeStats(ctx)
+	if err != nil {
+		d.logger.Debug("failed to fetch couchbase stats", zap.Error(err))
+		return sample, nil
+	}
+
+	for nodeIdx, node := range serverNodes {
+		for _, stats := range nodeStats {
+			statsHost, _, _ := net.SplitHostPort(stats.Hostname)
+			if statsHost != node.IPAddress {
+				continue
+			}
+
+			usage := sample.Nodes[nodeIdx]
+			usage.ItemCount = stats.InterestingStats.CurrItems
+			usage.OpsPerSec = stats.InterestingStats.Ops
+			usage.DataMemUsedBytes = stats.InterestingStats.MemUsed
+			usage.DataDiskUsedBytes = stats.InterestingStats.CouchDocsActualDiskSize
+		}
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,65 @@
+package dockerdeploy
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContainerStats(memStats map[string]uint64) *types.StatsJSON {
+	stats := &types.StatsJSON{}
+	stats.PreCPUStats.CPUUsage.TotalUsage = 1000
+	stats.PreCPUStats.SystemUsage = 10000
+	stats.CPUStats.CPUUsage.TotalUsage = 2000
+	stats.CPUStats.SystemUsage = 20000
+	stats.MemoryStats.Usage = 1000
+	stats.MemoryStats.Limit = 1<<63 - 1
+	stats.MemoryStats.Stats = memStats
+	return stats
+}
+
+func TestContainerStatsUsageCgroupV1(t *testing.T) {
+	stats := testContainerStats(map[string]uint64{
+		"inactive_file":       50,
+		"total_inactive_file": 200,
+		"cache":               300,
+	})
+	stats.CPUStats.CPUUsage.PercpuUsage = []uint64{1000, 1000}
+
+	usage := containerStatsUsage(stats, &types.Info{
+		CgroupVersion: "1",
+		NCPU:          8,
+		MemTotal:      4096,
+	})
+	assert.Equal(t, 2.0, usage.CpuLimit)
+	assert.InDelta(t, 20.0, usage.CpuPercent, 0.001)
+	assert.Equal(t, uint64(800), usage.MemUsedBytes)
+	assert.Equal(t, uint64(4096), usage.MemLimitBytes)
+
+	// older kernels only report the cache
+	stats = testContainerStats(map[string]uint64{"cache": 300})
+	usage = containerStatsUsage(stats, &types.Info{CgroupVersion: "1"})
+	assert.Equal(t, uint64(700), usage.MemUsedBytes)
+}
+
+func TestContainerStatsUsageCgroupV2(t *testing.T) {
+	stats := testContainerStats(map[string]uint64{
+		"inactive_file": 100,
+		"file":          300,
+	})
+
+	usage := containerStatsUsage(stats, &types.Info{
+		CgroupVersion: "2",
+		NCPU:          4,
+		MemTotal:      4096,
+	})
+	assert.Equal(t, 4.0, usage.CpuLimit)
+	assert.InDelta(t, 40.0, usage.CpuPercent, 0.001)
+	assert.Equal(t, uint64(900), usage.MemUsedBytes)
+	assert.Equal(t, uint64(4096), usage.MemLimitBytes)
+
+	stats.CPUStats.OnlineCPUs = 2
+	usage = containerStatsUsage(stats, &types.Info{CgroupVersion: "2", NCPU: 4})
+	assert.Equal(t, 2.0, usage.CpuLimit)
+}
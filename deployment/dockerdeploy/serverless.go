@@ -0,0 +1,158 @@
+package dockerdeploy
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+	"github.com/pkg/errors"
+)
+
+func serverlessLimitsToNs(limits *clusterdef.ServerlessLimits) *clustercontrol.ServerlessLimits {
+	return &clustercontrol.ServerlessLimits{
+		DataThrottleLimit:   limits.DataThrottleLimit,
+		IndexThrottleLimit:  limits.IndexThrottleLimit,
+		SearchThrottleLimit: limits.SearchThrottleLimit,
+		QueryThrottleLimit:  limits.QueryThrottleLimit,
+		DataStorageLimit:    limits.DataStorageLimit,
+		IndexStorageLimit:   limits.IndexStorageLimit,
+		SearchStorageLimit:  limits.SearchStorageLimit,
+	}
+}
+
+// isServerlessDef indicates whether the nodes of a definition run the
+// serverless profile.  Mixing profiles is rejected by validation.
+func isServerlessDef(def *clusterdef.Cluster) bool {
+	for _, nodeGrp := range def.NodeGroups {
+		versionInfo, err := versionident.Identify(context.Background(), nodeGrp.Version)
+		if err == nil && versionInfo.Serverless {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Deployer) checkServerlessCluster(ctx context.Context, clusterID string) error {
+	clusterInfo, err := d.getClusterInfo(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster info")
+	}
+
+	for _, node := range clusterInfo.Nodes {
+		versionInfo, err := versionident.Identify(ctx, node.Version)
+		if err == nil && versionInfo.Serverless {
+			return nil
+		}
+	}
+
+	return deployment.NewError(deployment.ErrFeatureUnsupported,
+		errors.New("cluster is not running the serverless profile, use a version ending in -serverless"))
+}
+
+// setupServerless applies the serverless settings of a definition to a newly
+// initialized cluster.
+func (d *Deployer) setupServerless(ctx context.Context, clusterID string, settings *clusterdef.ServerlessSettings) error {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	if settings.EnforceLimits {
+		err := controller.Controller().SetEnforceLimits(ctx, true)
+		if err != nil {
+			return errors.Wrap(err, "failed to enable limit enforcement")
+		}
+	}
+
+	if !settings.DefaultLimits.IsEmpty() {
+		err := controller.Controller().UpdateServerlessSettings(ctx, serverlessLimitsToNs(&settings.DefaultLimits))
+		if err != nil {
+			return errors.Wrap(err, "failed to configure default serverless limits")
+		}
+	}
+
+	return nil
+}
+
+// SetDefaultServerlessLimits changes the limits which are applied to buckets
+// created afterwards on a serverless cluster.
+func (d *Deployer) SetDefaultServerlessLimits(ctx context.Context, clusterID string, limits *clusterdef.ServerlessLimits) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "SetDefaultServerlessLimits")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkServerlessCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().UpdateServerlessSettings(ctx, serverlessLimitsToNs(limits))
+	if err != nil {
+		return errors.Wrap(err, "failed to update default serverless limits")
+	}
+
+	return nil
+}
+
+// SetBucketServerlessLimits changes the throttle and storage limits of a
+// bucket on a serverless cluster.
+func (d *Deployer) SetBucketServerlessLimits(ctx context.Context, clusterID string, bucketName string, limits *clusterdef.ServerlessLimits) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "SetBucketServerlessLimits")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkServerlessCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().UpdateBucketLimits(ctx, bucketName, serverlessLimitsToNs(limits))
+	if err != nil {
+		return errors.Wrap(err, "failed to update bucket serverless limits")
+	}
+
+	return nil
+}
+
+// SetServerlessLimitEnforcement controls whether a serverless cluster
+// enforces its limits rather than only reporting them.
+func (d *Deployer) SetServerlessLimitEnforcement(ctx context.Context, clusterID string, enforceLimits bool) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "SetServerlessLimitEnforcement")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkServerlessCluster(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().SetEnforceLimits(ctx, enforceLimits)
+	if err != nil {
+		return errors.Wrap(err, "failed to update limit enforcement")
+	}
+
+	return nil
+}
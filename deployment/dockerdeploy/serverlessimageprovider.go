@@ -4,11 +4,9 @@ import (
 	"context"
 	"embed"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/couchbaselabs/cbdinocluster/deployment"
-	"github.com/couchbaselabs/cbdinocluster/utils/tarhelper"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
@@ -78,41 +76,9 @@ func (p *ServerlessImageProvider) GetImage(ctx context.Context, def *ImageDef) (
 		return nil, errors.Wrap(err, "failed to get base image")
 	}
 
-	p.Logger.Debug("creating temporary tar file")
-	tmpTarFile, err := os.CreateTemp("", "dynclsttar")
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create temp file to tar docker data")
-	}
-	defer tmpTarFile.Close()
-	defer os.Remove(tmpTarFile.Name())
-
-	t, err := tarhelper.NewTarBuilder(tmpTarFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create tar builder")
-	}
-
-	p.Logger.Debug("adding base data to tar image")
-	err = t.AddEmbedDir(&assetsFs, "dockerfiles/serverless", "")
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to add base data")
-	}
-
-	err = t.Close()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to close tar builder")
-	}
-
-	tmpTarFile.Close()
-
-	tmpRTarFile, err := os.Open(tmpTarFile.Name())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open tmp tar file for reading")
-	}
-	defer tmpRTarFile.Close()
-
 	p.Logger.Debug("starting image build", zap.String("image", fullTagPath))
 
-	err = dockerBuildAndPipe(ctx, p.Logger, p.DockerCli, tmpRTarFile, types.ImageBuildOptions{
+	err = dockerBuildEmbeddedAndPipe(ctx, p.Logger, p.DockerCli, "dockerfiles/serverless", types.ImageBuildOptions{
 		BuildArgs: map[string]*string{
 			"BASE_IMAGE": &baseImageRef.ImagePath,
 		},
@@ -0,0 +1,163 @@
+package dockerdeploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// serviceProcesses maps the names which can be used to identify a service
+// process to the name of its executable in /opt/couchbase/bin.  The service
+// processes are supervised by the babysitter, which restarts them shortly
+// after they exit.
+var serviceProcesses = map[string]string{
+	"memcached":         "memcached",
+	"kv":                "memcached",
+	"indexer":           "indexer",
+	"index":             "indexer",
+	"projector":         "projector",
+	"cbq-engine":        "cbq-engine",
+	"query":             "cbq-engine",
+	"n1ql":              "cbq-engine",
+	"cbft":              "cbft",
+	"search":            "cbft",
+	"fts":               "cbft",
+	"eventing-producer": "eventing-producer",
+	"eventing":          "eventing-producer",
+}
+
+// ServiceProcessName resolves a service process name, or the name of the
+// service it belongs to, to the name of the executable of the process.
+func ServiceProcessName(name string) (string, error) {
+	processName, ok := serviceProcesses[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown service process `%s`, expected one of memcached, indexer, projector, cbq-engine, cbft or eventing-producer", name)
+	}
+	return processName, nil
+}
+
+func serviceProcessPattern(processName string) string {
+	return fmt.Sprintf("^/opt/couchbase/bin/%s( |$)", processName)
+}
+
+func serviceHoldDownMarkerPath(processName string) string {
+	return fmt.Sprintf("/tmp/cbdc-holddown-%s", processName)
+}
+
+// KillServiceProcess kills a service process within the container.  When a
+// hold down duration is specified, the process continues to be killed every
+// time the babysitter restarts it until the duration has elapsed, which runs
+// in the background so that this returns immediately.
+func (c *Controller) KillServiceProcess(ctx context.Context, containerID string, processName string, holdDown time.Duration) error {
+	logger := c.Logger.With(zap.String("container", containerID))
+	logger.Debug("killing service process",
+		zap.String("process", processName),
+		zap.Duration("holdDown", holdDown))
+
+	pattern := serviceProcessPattern(processName)
+
+	err := c.execCmd(ctx, containerID, []string{"pkill", "-9", "-f", pattern})
+	if err != nil {
+		return errors.Wrapf(err, "failed to kill %s, it may not be running on this node", processName)
+	}
+
+	if holdDown <= 0 {
+		return nil
+	}
+
+	// the marker file allows a hold down to be ended early by removing it, and
+	// starting a new hold down replaces the marker of any previous one
+	markerPath := serviceHoldDownMarkerPath(processName)
+	holdDownSecs := int64(holdDown.Round(time.Second) / time.Second)
+	script := fmt.Sprintf(
+		"marker=%s; touch $marker; end=$(($(date +%%s) + %d)); "+
+			"while [ -f $marker ] && [ $(date +%%s) -lt $end ]; do pkill -9 -f '%s'; sleep 0.2; done; "+
+			"rm -f $marker",
+		markerPath, holdDownSecs, pattern)
+
+	err = dockerExecDetached(ctx, c.DockerCli, containerID, []string{"sh", "-c", script})
+	if err != nil {
+		return errors.Wrap(err, "failed to start holding down process")
+	}
+
+	return nil
+}
+
+// ReleaseServiceProcess ends any hold down of a service process within the
+// container, allowing the babysitter to restart it.
+func (c *Controller) ReleaseServiceProcess(ctx context.Context, containerID string, processName string) error {
+	c.Logger.Debug("releasing service process",
+		zap.String("container", containerID),
+		zap.String("process", processName))
+
+	err := c.execCmd(ctx, containerID, []string{"rm", "-f", serviceHoldDownMarkerPath(processName)})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove hold down marker")
+	}
+
+	return nil
+}
+
+// KillNodeServiceProcess kills a specific service process of a node, rather
+// than the whole node, optionally preventing it from being restarted for a
+// duration.
+func (d *Deployer) KillNodeServiceProcess(ctx context.Context, clusterID string, nodeID string, processName string, holdDown time.Duration) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "KillNodeServiceProcess")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	node, err := d.getNode(ctx, clusterID, nodeID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get node")
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would kill service process",
+			zap.String("container", node.ContainerID),
+			zap.String("process", processName),
+			zap.Duration("holdDown", holdDown))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).Controller.KillServiceProcess(ctx, node.ContainerID, processName, holdDown)
+	if err != nil {
+		return errors.Wrap(err, "failed to kill service process")
+	}
+
+	return nil
+}
+
+// ReleaseNodeServiceProcess ends a hold down started by KillNodeServiceProcess
+// before its duration has elapsed.
+func (d *Deployer) ReleaseNodeServiceProcess(ctx context.Context, clusterID string, nodeID string, processName string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "ReleaseNodeServiceProcess")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	node, err := d.getNode(ctx, clusterID, nodeID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get node")
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would release service process",
+			zap.String("container", node.ContainerID),
+			zap.String("process", processName))
+		return nil
+	}
+
+	err = d.getHost(node.HostName).Controller.ReleaseServiceProcess(ctx, node.ContainerID, processName)
+	if err != nil {
+		return errors.Wrap(err, "failed to release service process")
+	}
+
+	return nil
+}
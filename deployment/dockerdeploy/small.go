@@ -0,0 +1,77 @@
+package dockerdeploy
+
+import (
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// smallNodeMemoryLimitMB is the memory limit of the nodes of the small
+	// profile, which fits 3 nodes on a machine with 8GB of memory alongside
+	// the docker VM and everything else running on it.
+	smallNodeMemoryLimitMB = 1536
+
+	// minBucketRamQuotaMB is the smallest quota server permits for a bucket.
+	minBucketRamQuotaMB = 100
+)
+
+// smallProfileServices are the services which nodes of the small profile
+// are permitted to run.  The others are too memory hungry to run within
+// the memory limit of the nodes.
+var smallProfileServices = []clusterdef.Service{
+	clusterdef.KvService,
+	clusterdef.QueryService,
+	clusterdef.IndexService,
+}
+
+// SmallProfileDefinition constrains a cluster definition to a small memory
+// footprint.  Node containers are limited in memory, service quotas are at
+// their minimums and services other than data, query and index are removed,
+// returning the services which were removed.
+func SmallProfileDefinition(def *clusterdef.Cluster) (*clusterdef.Cluster, []clusterdef.Service, error) {
+	if def.Columnar {
+		return nil, nil, errors.New("the small profile does not support columnar clusters")
+	}
+	if len(def.NodeGroups) == 0 {
+		return nil, nil, errors.New("at least one node group must be specified")
+	}
+
+	var removedServices []clusterdef.Service
+
+	smallDef := *def
+	smallDef.NodeGroups = nil
+	for _, nodeGrp := range def.NodeGroups {
+		smallGrp := *nodeGrp
+
+		smallGrp.Services = nil
+		if len(nodeGrp.Services) == 0 {
+			smallGrp.Services = slices.Clone(smallProfileServices)
+		}
+		for _, service := range nodeGrp.Services {
+			if slices.Contains(smallProfileServices, service) {
+				smallGrp.Services = append(smallGrp.Services, service)
+			} else if !slices.Contains(removedServices, service) {
+				removedServices = append(removedServices, service)
+			}
+		}
+		if len(smallGrp.Services) == 0 {
+			return nil, nil, errors.New("the small profile does not support node groups without data, query or index services")
+		}
+
+		if smallGrp.Docker.MemoryLimitMB == 0 {
+			smallGrp.Docker.MemoryLimitMB = smallNodeMemoryLimitMB
+		}
+
+		smallDef.NodeGroups = append(smallDef.NodeGroups, &smallGrp)
+	}
+
+	// these are the minimum quotas server permits
+	smallDef.Docker.KvMemoryMB = 256
+	smallDef.Docker.IndexMemoryMB = 256
+	smallDef.Docker.FtsMemoryMB = 0
+	smallDef.Docker.CbasMemoryMB = 0
+	smallDef.Docker.EventingMemoryMB = 0
+
+	return &smallDef, removedServices, nil
+}
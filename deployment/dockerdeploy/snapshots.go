@@ -0,0 +1,340 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Golden snapshots are images containing a cbbackupmgr archive of a
+// cluster.  They are built from the server image of the cluster they were
+// taken from, so that the archive is always restored with a matching
+// cbbackupmgr, and are described by the following labels.
+const (
+	snapshotArchivePath = "/snapshot"
+	snapshotRepoName    = "golden"
+
+	snapshotNameLabel    = "com.couchbase.dyncluster.snapshot_name"
+	snapshotVersionLabel = "com.couchbase.dyncluster.snapshot_server_version"
+	snapshotBucketsLabel = "com.couchbase.dyncluster.snapshot_buckets"
+	snapshotCreatedLabel = "com.couchbase.dyncluster.snapshot_created"
+
+	// defaultSnapshotRegistry is the repository snapshots are tagged into
+	// when no registry is configured, which keeps them local.
+	defaultSnapshotRegistry = "cbdc-snapshots"
+)
+
+var snapshotNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+type SnapshotInfo struct {
+	Name          string
+	ImagePath     string
+	ServerVersion string
+	Buckets       []string
+	CreatedAt     time.Time
+}
+
+type PublishSnapshotOptions struct {
+	Name string
+
+	// Push pushes the snapshot image to the snapshot registry once it has
+	// been created, making it available to everyone using the registry.
+	Push bool
+}
+
+// snapshotImagePath returns the image reference of a snapshot.  Names which
+// are already image references are used as they are.
+func (d *Deployer) snapshotImagePath(name string) string {
+	if strings.ContainsAny(name, "/:") {
+		return name
+	}
+
+	registry := d.snapshotRegistry
+	if registry == "" {
+		registry = defaultSnapshotRegistry
+	}
+
+	return fmt.Sprintf("%s/%s:latest", strings.TrimSuffix(registry, "/"), name)
+}
+
+func (d *Deployer) registryAuthFor(imagePath string) string {
+	if !strings.HasPrefix(imagePath, "ghcr.io/") || d.ghcrUsername == "" {
+		return ""
+	}
+
+	authConfigJson, _ := json.Marshal(types.AuthConfig{
+		Username: d.ghcrUsername,
+		Password: d.ghcrPassword,
+	})
+	return base64.StdEncoding.EncodeToString(authConfigJson)
+}
+
+func snapshotInfoFromLabels(imagePath string, labels map[string]string) (*SnapshotInfo, error) {
+	name := labels[snapshotNameLabel]
+	if name == "" {
+		return nil, fmt.Errorf("image `%s` is not a snapshot", imagePath)
+	}
+
+	var buckets []string
+	if labels[snapshotBucketsLabel] != "" {
+		buckets = strings.Split(labels[snapshotBucketsLabel], ",")
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, labels[snapshotCreatedLabel])
+
+	return &SnapshotInfo{
+		Name:          name,
+		ImagePath:     imagePath,
+		ServerVersion: labels[snapshotVersionLabel],
+		Buckets:       buckets,
+		CreatedAt:     createdAt,
+	}, nil
+}
+
+// PublishSnapshot backs up the buckets, data and index definitions of a
+// cluster into a golden snapshot image, which new clusters can then be
+// started from by specifying the snapshot name in their definition.
+func (d *Deployer) PublishSnapshot(ctx context.Context, clusterID string, opts *PublishSnapshotOptions) (*SnapshotInfo, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "PublishSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if !snapshotNameRegexp.MatchString(opts.Name) {
+		return nil, fmt.Errorf("invalid snapshot name `%s`, names must be lowercase alphanumeric and may contain `.`, `_` or `-`", opts.Name)
+	}
+
+	nodes, err := d.listNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var seedNode *NodeInfo
+	for _, node := range nodes {
+		if node.ClusterID == clusterID && (node.Type == "server-node" || node.Type == "columnar-node") {
+			seedNode = node
+			break
+		}
+	}
+	if seedNode == nil {
+		return nil, errors.New("failed to find a server node of the cluster")
+	}
+
+	buckets, err := d.ListBuckets(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list buckets")
+	}
+
+	var bucketNames []string
+	for _, bucket := range buckets {
+		bucketNames = append(bucketNames, bucket.Name)
+	}
+	sort.Strings(bucketNames)
+
+	imagePath := d.snapshotImagePath(opts.Name)
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would publish snapshot",
+			zap.String("cluster", clusterID),
+			zap.String("image", imagePath),
+			zap.Strings("buckets", bucketNames))
+		return &SnapshotInfo{
+			Name:          opts.Name,
+			ImagePath:     imagePath,
+			ServerVersion: seedNode.InitialServerVersion,
+			Buckets:       bucketNames,
+		}, nil
+	}
+
+	versionInfo, err := versionident.Identify(ctx, seedNode.InitialServerVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to identify cluster version")
+	}
+
+	// the snapshot is committed on the host of the node being backed up, as
+	// the tool container must be able to reach it
+	host := d.getHost(seedNode.HostName)
+
+	toolImage, err := host.ImageProvider.GetImage(ctx, &ImageDef{
+		Version:             versionInfo.Version,
+		BuildNo:             versionInfo.BuildNo,
+		UseCommunityEdition: versionInfo.CommunityEdition,
+		UseServerless:       versionInfo.Serverless,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tools image")
+	}
+
+	createdAt := time.Now()
+	labels := map[string]string{
+		snapshotNameLabel:    opts.Name,
+		snapshotVersionLabel: seedNode.InitialServerVersion,
+		snapshotBucketsLabel: strings.Join(bucketNames, ","),
+		snapshotCreatedLabel: createdAt.Format(time.RFC3339),
+	}
+
+	d.logger.Info("backing up cluster into snapshot",
+		zap.String("cluster", clusterID),
+		zap.String("image", imagePath))
+
+	clusterAddr := fmt.Sprintf("http://%s:8091", seedNode.IPAddress)
+	_, err = host.Controller.RunTool(ctx, &RunToolOptions{
+		Image: toolImage,
+		Cmd: []string{"/bin/sh", "-c", strings.Join([]string{
+			fmt.Sprintf("/opt/couchbase/bin/cbbackupmgr config -a %s -r %s",
+				snapshotArchivePath, snapshotRepoName),
+			fmt.Sprintf("/opt/couchbase/bin/cbbackupmgr backup -a %s -r %s -c %s -u Administrator -p password --no-progress-bar",
+				snapshotArchivePath, snapshotRepoName, clusterAddr),
+		}, " && ")},
+		CommitImage:  imagePath,
+		CommitLabels: labels,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to back up cluster")
+	}
+
+	if opts.Push {
+		if strings.HasPrefix(imagePath, defaultSnapshotRegistry+"/") {
+			return nil, errors.New("a snapshot registry must be configured to push snapshots")
+		}
+
+		d.logger.Info("pushing snapshot", zap.String("image", imagePath))
+
+		err = dockerPushAndPipe(ctx, d.logger, host.DockerCli, imagePath, types.ImagePushOptions{
+			RegistryAuth: d.registryAuthFor(imagePath),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to push snapshot")
+		}
+	}
+
+	return &SnapshotInfo{
+		Name:          opts.Name,
+		ImagePath:     imagePath,
+		ServerVersion: seedNode.InitialServerVersion,
+		Buckets:       bucketNames,
+		CreatedAt:     createdAt,
+	}, nil
+}
+
+// ListSnapshots lists the snapshots which are available locally, which
+// includes those published from here and those pulled from the registry.
+func (d *Deployer) ListSnapshots(ctx context.Context) ([]*SnapshotInfo, error) {
+	images, err := d.dockerCli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", snapshotNameLabel)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list images")
+	}
+
+	var out []*SnapshotInfo
+	for _, image := range images {
+		for _, repoTag := range image.RepoTags {
+			info, err := snapshotInfoFromLabels(repoTag, image.Labels)
+			if err != nil {
+				d.logger.Debug("skipping invalid snapshot image", zap.Error(err))
+				continue
+			}
+
+			out = append(out, info)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ImagePath < out[j].ImagePath
+	})
+
+	return out, nil
+}
+
+// RemoveSnapshot removes the local image of a snapshot.  Snapshots which
+// were pushed remain available from the registry.
+func (d *Deployer) RemoveSnapshot(ctx context.Context, name string) error {
+	imagePath := d.snapshotImagePath(name)
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would remove snapshot", zap.String("image", imagePath))
+		return nil
+	}
+
+	_, err := d.dockerCli.ImageRemove(ctx, imagePath, types.ImageRemoveOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove snapshot image")
+	}
+
+	return nil
+}
+
+// getSnapshot finds the image of a snapshot on a docker host, pulling it from
+// the snapshot registry if it is not available there.
+func (d *Deployer) getSnapshot(ctx context.Context, host *dockerHost, name string) (*ImageRef, *SnapshotInfo, error) {
+	imagePath := d.snapshotImagePath(name)
+
+	image, err := host.ImageProvider.GetImageRaw(ctx, imagePath)
+	if err != nil {
+		return nil, nil, deployment.NewError(deployment.ErrInvalidDefinition,
+			errors.Wrapf(err, "failed to get snapshot `%s`", name))
+	}
+
+	imageInfo, _, err := host.DockerCli.ImageInspectWithRaw(ctx, image.ImagePath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to inspect snapshot image")
+	}
+
+	var labels map[string]string
+	if imageInfo.Config != nil {
+		labels = imageInfo.Config.Labels
+	}
+
+	info, err := snapshotInfoFromLabels(imagePath, labels)
+	if err != nil {
+		return nil, nil, deployment.NewError(deployment.ErrInvalidDefinition, err)
+	}
+
+	return image, info, nil
+}
+
+// restoreSnapshot restores the buckets, data and index definitions of a
+// snapshot into an initialized cluster.  The restore runs on the host of the
+// node it restores through, so the snapshot is fetched onto that host.
+func (d *Deployer) restoreSnapshot(ctx context.Context, name string, node *NodeInfo, username, password string) error {
+	host := d.getHost(node.HostName)
+
+	image, _, err := d.getSnapshot(ctx, host, name)
+	if err != nil {
+		return err
+	}
+
+	clusterAddr := fmt.Sprintf("http://%s:8091", node.IPAddress)
+	_, err = host.Controller.RunTool(ctx, &RunToolOptions{
+		Image: image,
+		Cmd: []string{
+			"/opt/couchbase/bin/cbbackupmgr", "restore",
+			"-a", snapshotArchivePath,
+			"-r", snapshotRepoName,
+			"-c", clusterAddr,
+			"-u", username,
+			"-p", password,
+			"--auto-create-buckets",
+			"--no-progress-bar",
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to restore snapshot")
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package dockerdeploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type RunToolOptions struct {
+	Image *ImageRef
+	Cmd   []string
+
+	// InputPath and Input optionally specify a file which is written into
+	// the container before the tool is started.
+	InputPath string
+	Input     []byte
+
+	// OutputPath optionally specifies a file which is read back out of the
+	// container once the tool has completed.
+	OutputPath string
+
+	// CommitImage optionally specifies an image reference which the tool
+	// container is committed as once the tool has completed, with the
+	// labels of CommitLabels.
+	CommitImage  string
+	CommitLabels map[string]string
+}
+
+// RunTool runs a short-lived container on the deployer network to execute a
+// single tool command, returning the contents of the output file.  The tool
+// containers are not labelled, so they never appear as nodes.
+func (c *Controller) RunTool(ctx context.Context, opts *RunToolOptions) ([]byte, error) {
+	logger := c.Logger.With(zap.Strings("cmd", opts.Cmd))
+	logger.Debug("running tool container")
+
+	createResult, err := c.DockerCli.ContainerCreate(ctx, &container.Config{
+		Image:      opts.Image.ImagePath,
+		Entrypoint: opts.Cmd[:1],
+		Cmd:        opts.Cmd[1:],
+	}, &container.HostConfig{
+		NetworkMode: container.NetworkMode(c.NetworkName),
+	}, nil, nil, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create tool container")
+	}
+
+	containerID := createResult.ID
+	defer func() {
+		err := c.DockerCli.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{
+			Force: true,
+		})
+		if err != nil {
+			logger.Debug("failed to remove tool container", zap.Error(err))
+		}
+	}()
+
+	if opts.InputPath != "" {
+		tarBuf := bytes.NewBuffer(nil)
+		tarFile := tar.NewWriter(tarBuf)
+		tarFile.WriteHeader(&tar.Header{
+			Name: path.Base(opts.InputPath),
+			Mode: 0644,
+			Size: int64(len(opts.Input)),
+		})
+		tarFile.Write(opts.Input)
+		tarFile.Close()
+
+		err = c.DockerCli.CopyToContainer(ctx, containerID, path.Dir(opts.InputPath), tarBuf, types.CopyToContainerOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to write tool input")
+		}
+	}
+
+	statusCh, errCh := c.DockerCli.ContainerWait(ctx, containerID, container.WaitConditionNextExit)
+
+	err = c.DockerCli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start tool container")
+	}
+
+	var exitCode int64
+	select {
+	case err := <-errCh:
+		return nil, errors.Wrap(err, "failed to wait for tool container")
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	if exitCode != 0 {
+		return nil, fmt.Errorf("tool failed (exit code: %d): %s",
+			exitCode, c.toolOutput(ctx, containerID))
+	}
+
+	logger.Debug("tool completed", zap.String("output", c.toolOutput(ctx, containerID)))
+
+	if opts.CommitImage != "" {
+		_, err := c.DockerCli.ContainerCommit(ctx, containerID, types.ContainerCommitOptions{
+			Reference: opts.CommitImage,
+			Config: &container.Config{
+				Labels: opts.CommitLabels,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to commit tool container")
+		}
+
+		logger.Debug("committed tool container", zap.String("image", opts.CommitImage))
+	}
+
+	if opts.OutputPath == "" {
+		return nil, nil
+	}
+
+	resp, _, err := c.DockerCli.CopyFromContainer(ctx, containerID, opts.OutputPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read tool output")
+	}
+	defer resp.Close()
+
+	tarRdr := tar.NewReader(resp)
+	_, err = tarRdr.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse tool output")
+	}
+
+	output, err := io.ReadAll(tarRdr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read tool output")
+	}
+
+	return output, nil
+}
+
+func (c *Controller) toolOutput(ctx context.Context, containerID string) string {
+	logsRdr, err := c.DockerCli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return ""
+	}
+	defer logsRdr.Close()
+
+	logsBuf := bytes.NewBuffer(nil)
+	stdcopy.StdCopy(logsBuf, logsBuf, logsRdr)
+	return strings.TrimSpace(logsBuf.String())
+}
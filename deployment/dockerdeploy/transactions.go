@@ -0,0 +1,407 @@
+package dockerdeploy
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/couchbase/gocbcorex"
+	"github.com/couchbaselabs/cbdinocluster/deployment"
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// TransactionSettings are the cluster-wide transaction settings of the query
+// service, which also control the cleanup of lost transactions.  Zero and nil
+// fields keep their current values when updating.
+type TransactionSettings struct {
+	Timeout               time.Duration
+	CleanupWindow         time.Duration
+	CleanupClientAttempts *bool
+	CleanupLostAttempts   *bool
+	NumATRs               int
+}
+
+// ATREntry is a single transaction attempt recorded in an active transaction
+// record (ATR).
+type ATREntry struct {
+	ATRID         string
+	AttemptID     string
+	TransactionID string
+	State         string
+	StartTime     time.Time
+	Expiry        time.Duration
+
+	// Lost indicates that the attempt was neither completed nor rolled back
+	// before it expired, so it is waiting to be cleaned up.
+	Lost bool
+}
+
+type ATRLocation struct {
+	BucketName     string
+	ScopeName      string
+	CollectionName string
+}
+
+type PurgeLostATRsResult struct {
+	PurgedATRs []string
+
+	// SkippedATRs counts the records which were left in place because they
+	// still hold attempts that have not expired.
+	SkippedATRs int
+}
+
+type atrAttemptJson struct {
+	TransactionID string `json:"tid"`
+	State         string `json:"st"`
+	StartCas      string `json:"tst"`
+	ExpiryMs      int64  `json:"exp"`
+}
+
+type atrRowJson struct {
+	ID       string                    `json:"id"`
+	Attempts map[string]atrAttemptJson `json:"attempts"`
+}
+
+// isFinishedATRState indicates whether an attempt in the given state has
+// completed or rolled back, and so no longer needs cleaning up.
+func isFinishedATRState(state string) bool {
+	return state == "COMPLETED" || state == "ROLLED_BACK"
+}
+
+// atrKeyPrefix is the prefix of the keys of active transaction records.
+const atrKeyPrefix = "_txn:atr-"
+
+// parseATRCas parses the start time of an attempt, which the server stores
+// as the hex encoding of the little-endian CAS of the record mutation.
+func parseATRCas(value string) (time.Time, error) {
+	casBytes, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(casBytes) != 8 {
+		return time.Time{}, fmt.Errorf("invalid attempt start time `%s`", value)
+	}
+
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(casBytes))), nil
+}
+
+func atrKeyspace(loc *ATRLocation) string {
+	scopeName := loc.ScopeName
+	if scopeName == "" {
+		scopeName = "_default"
+	}
+	collectionName := loc.CollectionName
+	if collectionName == "" {
+		collectionName = "_default"
+	}
+
+	return fmt.Sprintf("`%s`.`%s`.`%s`", loc.BucketName, scopeName, collectionName)
+}
+
+func (d *Deployer) GetTransactionSettings(ctx context.Context, clusterID string) (*TransactionSettings, error) {
+	err := d.checkClusterFeature(ctx, clusterID, deployment.FeatureTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	nsSettings, err := controller.Controller().GetQueryTransactionSettings(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get query settings")
+	}
+
+	settings := &TransactionSettings{
+		CleanupClientAttempts: nsSettings.CleanupClientAttempts,
+		CleanupLostAttempts:   nsSettings.CleanupLostAttempts,
+		NumATRs:               nsSettings.NumAtrs,
+	}
+
+	if nsSettings.TxTimeout != "" {
+		settings.Timeout, err = time.ParseDuration(nsSettings.TxTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse transaction timeout")
+		}
+	}
+
+	if nsSettings.CleanupWindow != "" {
+		settings.CleanupWindow, err = time.ParseDuration(nsSettings.CleanupWindow)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse cleanup window")
+		}
+	}
+
+	return settings, nil
+}
+
+// UpdateTransactionSettings changes the transaction settings of the query
+// service.  Lowering the cleanup window lets tests observe the cleanup of
+// lost transactions without waiting for the default of a minute.
+func (d *Deployer) UpdateTransactionSettings(ctx context.Context, clusterID string, settings *TransactionSettings) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "UpdateTransactionSettings")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureTransactions)
+	if err != nil {
+		return err
+	}
+
+	nsSettings := &clustercontrol.QueryTransactionSettings{
+		CleanupClientAttempts: settings.CleanupClientAttempts,
+		CleanupLostAttempts:   settings.CleanupLostAttempts,
+		NumAtrs:               settings.NumATRs,
+	}
+	if settings.Timeout > 0 {
+		nsSettings.TxTimeout = settings.Timeout.String()
+	}
+	if settings.CleanupWindow > 0 {
+		nsSettings.CleanupWindow = settings.CleanupWindow.String()
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would update transaction settings",
+			zap.Any("settings", nsSettings))
+		return nil
+	}
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	err = controller.Controller().UpdateQueryTransactionSettings(ctx, nsSettings)
+	if err != nil {
+		return errors.Wrap(err, "failed to update query settings")
+	}
+
+	return nil
+}
+
+// EnsureTransactionMetadataCollection creates the scope and collection which
+// clients are configured to store their ATRs and client records in, if they
+// do not already exist.
+func (d *Deployer) EnsureTransactionMetadataCollection(ctx context.Context, clusterID string, loc *ATRLocation) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "EnsureTransactionMetadataCollection")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	scopes, err := d.ListCollections(ctx, clusterID, loc.BucketName)
+	if err != nil {
+		return errors.Wrap(err, "failed to list collections")
+	}
+
+	var scope *deployment.ScopeInfo
+	for i := range scopes {
+		if scopes[i].Name == loc.ScopeName {
+			scope = &scopes[i]
+		}
+	}
+
+	if scope != nil {
+		for _, collection := range scope.Collections {
+			if collection.Name == loc.CollectionName {
+				d.logger.Info("transaction metadata collection already exists")
+				return nil
+			}
+		}
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would create transaction metadata collection",
+			zap.String("bucket", loc.BucketName),
+			zap.String("scope", loc.ScopeName),
+			zap.String("collection", loc.CollectionName))
+		return nil
+	}
+
+	if scope == nil {
+		err := d.CreateScope(ctx, clusterID, loc.BucketName, loc.ScopeName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.CreateCollection(ctx, clusterID, loc.BucketName, loc.ScopeName, loc.CollectionName, &deployment.CreateCollectionOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Deployer) queryATRs(ctx context.Context, agent *gocbcorex.Agent, loc *ATRLocation) ([]atrRowJson, error) {
+	results, err := agent.Query(ctx, &gocbcorex.QueryOptions{
+		Statement: fmt.Sprintf(
+			"SELECT META().id AS id, META().xattrs.attempts AS attempts FROM %s WHERE META().id LIKE \"%s%%\"",
+			atrKeyspace(loc), atrKeyPrefix),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query active transaction records")
+	}
+
+	var rows []atrRowJson
+	for results.HasMoreRows() {
+		rowBytes, err := results.ReadRow()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read row")
+		}
+
+		var row atrRowJson
+		err = json.Unmarshal(rowBytes, &row)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse active transaction record")
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func atrRowEntries(row *atrRowJson, now time.Time) ([]ATREntry, error) {
+	var entries []ATREntry
+	for attemptID, attempt := range row.Attempts {
+		startTime, err := parseATRCas(attempt.StartCas)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse attempt `%s` of `%s`", attemptID, row.ID)
+		}
+
+		expiry := time.Duration(attempt.ExpiryMs) * time.Millisecond
+
+		entries = append(entries, ATREntry{
+			ATRID:         row.ID,
+			AttemptID:     attemptID,
+			TransactionID: attempt.TransactionID,
+			State:         attempt.State,
+			StartTime:     startTime,
+			Expiry:        expiry,
+			Lost:          !isFinishedATRState(attempt.State) && now.After(startTime.Add(expiry)),
+		})
+	}
+
+	return entries, nil
+}
+
+// ListATREntries lists the attempts recorded in the active transaction
+// records of a collection.  This uses a query, so the collection needs a
+// primary index unless the cluster supports sequential scans.
+func (d *Deployer) ListATREntries(ctx context.Context, clusterID string, loc *ATRLocation) ([]ATREntry, error) {
+	err := d.checkClusterFeature(ctx, clusterID, deployment.FeatureTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := d.getAgent(ctx, clusterID, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster agent")
+	}
+	defer agent.Close()
+
+	rows, err := d.queryATRs(ctx, agent, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []ATREntry
+	for _, row := range rows {
+		rowEntries, err := atrRowEntries(&row, now)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, rowEntries...)
+	}
+
+	return entries, nil
+}
+
+// PurgeLostATRs removes the active transaction records of a collection which
+// only hold lost or finished attempts, so tests can start from a clean slate
+// without waiting for the cleanup window.  Records with attempts which have
+// not yet expired are left alone, as they may belong to running transactions.
+func (d *Deployer) PurgeLostATRs(ctx context.Context, clusterID string, loc *ATRLocation) (*PurgeLostATRsResult, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "PurgeLostATRs")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = d.checkClusterFeature(ctx, clusterID, deployment.FeatureTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := d.getAgent(ctx, clusterID, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster agent")
+	}
+	defer agent.Close()
+
+	rows, err := d.queryATRs(ctx, agent, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PurgeLostATRsResult{}
+
+	now := time.Now()
+	for _, row := range rows {
+		entries, err := atrRowEntries(&row, now)
+		if err != nil {
+			return nil, err
+		}
+
+		hasLiveAttempts := false
+		for _, entry := range entries {
+			if !isFinishedATRState(entry.State) && !entry.Lost {
+				hasLiveAttempts = true
+			}
+		}
+
+		if hasLiveAttempts {
+			result.SkippedATRs++
+			continue
+		}
+
+		result.PurgedATRs = append(result.PurgedATRs, row.ID)
+	}
+
+	if len(result.PurgedATRs) == 0 {
+		return result, nil
+	}
+
+	if d.dryRun {
+		d.logger.Info("dry-run: would purge active transaction records",
+			zap.Strings("atrs", result.PurgedATRs))
+		return result, nil
+	}
+
+	keysBytes, _ := json.Marshal(result.PurgedATRs)
+	results, err := agent.Query(ctx, &gocbcorex.QueryOptions{
+		Statement: fmt.Sprintf("DELETE FROM %s USE KEYS %s", atrKeyspace(loc), keysBytes),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete active transaction records")
+	}
+
+	for results.HasMoreRows() {
+		_, err := results.ReadRow()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read row")
+		}
+	}
+
+	return result, nil
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 
+	"github.com/couchbaselabs/cbdinocluster/utils/tarhelper"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
@@ -50,6 +52,44 @@ func dockerBuildAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Cli
 	return nil
 }
 
+// dockerBuildEmbeddedAndPipe builds an image using one of the embedded
+// dockerfiles directories as the build context.
+func dockerBuildEmbeddedAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Client, embedDir string, options types.ImageBuildOptions) error {
+	logger.Debug("creating temporary tar file")
+	tmpTarFile, err := os.CreateTemp("", "dynclsttar")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp file to tar docker data")
+	}
+	defer tmpTarFile.Close()
+	defer os.Remove(tmpTarFile.Name())
+
+	t, err := tarhelper.NewTarBuilder(tmpTarFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to create tar builder")
+	}
+
+	logger.Debug("adding base data to tar image")
+	err = t.AddEmbedDir(&assetsFs, embedDir, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to add base data")
+	}
+
+	err = t.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to close tar builder")
+	}
+
+	tmpTarFile.Close()
+
+	tmpRTarFile, err := os.Open(tmpTarFile.Name())
+	if err != nil {
+		return errors.Wrap(err, "failed to open tmp tar file for reading")
+	}
+	defer tmpRTarFile.Close()
+
+	return dockerBuildAndPipe(ctx, logger, cli, tmpRTarFile, options)
+}
+
 func dockerPullAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Client, refStr string, options types.ImagePullOptions) error {
 	pullResp, err := cli.ImagePull(ctx, refStr, options)
 	if err != nil {
@@ -80,6 +120,42 @@ func dockerPullAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Clie
 	return nil
 }
 
+func dockerPushAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Client, refStr string, options types.ImagePushOptions) error {
+	pushResp, err := cli.ImagePush(ctx, refStr, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to push image")
+	}
+	defer pushResp.Close()
+
+	dec := json.NewDecoder(pushResp)
+
+	for dec.More() {
+		var streamMsg struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		err := dec.Decode(&streamMsg)
+		if err != nil {
+			return errors.Wrap(err, "json decode failure while reading push progress")
+		}
+
+		// failures of the push are only reported within the stream
+		if streamMsg.Error != "" {
+			return fmt.Errorf("failed to push image: %s", streamMsg.Error)
+		}
+
+		switch streamMsg.Status {
+		case "Waiting":
+		case "Pushing":
+		case "Preparing":
+		default:
+			logger.Debug("docker push output", zap.String("text", streamMsg.Status))
+		}
+	}
+
+	return nil
+}
+
 func dockerExecAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Client, containerID string, cmd []string) error {
 	execID, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
 		AttachStdout: true,
@@ -116,3 +192,60 @@ func dockerExecAndPipe(ctx context.Context, logger *zap.Logger, cli *client.Clie
 
 	return nil
 }
+
+// dockerExecDetached starts a process in a container without waiting for it
+// to exit, for processes which need to outlive the command that started them.
+func dockerExecDetached(ctx context.Context, cli *client.Client, containerID string, cmd []string) error {
+	execID, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Detach: true,
+		Cmd:    cmd,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create exec")
+	}
+
+	err = cli.ContainerExecStart(ctx, execID.ID, types.ExecStartCheck{
+		Detach: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to start exec")
+	}
+
+	return nil
+}
+
+func dockerExecOutput(ctx context.Context, cli *client.Client, containerID string, cmd []string) ([]byte, error) {
+	execID, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create exec")
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{
+		Tty: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start exec")
+	}
+	defer resp.Close()
+
+	output, err := io.ReadAll(resp.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read exec output")
+	}
+
+	res, err := cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to inspect exec")
+	}
+
+	if res.ExitCode != 0 {
+		return output, fmt.Errorf("failed to execute process (exit code: %d)", res.ExitCode)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,136 @@
+package dockerdeploy
+
+import (
+	"context"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/pkg/errors"
+)
+
+// AddXdcrRemote registers another cluster as an XDCR remote of a cluster,
+// so replications can be created to it.  The remote is reached through its
+// address on the docker network, as the replication is performed by the
+// nodes themselves.
+func (d *Deployer) AddXdcrRemote(ctx context.Context, clusterID string, name string, targetClusterID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "AddXdcrRemote")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	targetCluster, err := d.getCluster(ctx, targetClusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get target cluster info")
+	}
+
+	var targetAddress string
+	for _, node := range targetCluster.Nodes {
+		if node.IsClusterNode() {
+			targetAddress = node.IPAddress
+			break
+		}
+	}
+	if targetAddress == "" {
+		return errors.New("target cluster has no server nodes")
+	}
+
+	err = controller.Controller().CreateRemoteCluster(ctx, &clustercontrol.CreateRemoteClusterRequest{
+		Name:     name,
+		Hostname: targetAddress,
+		Username: "Administrator",
+		Password: "password",
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create remote cluster reference")
+	}
+
+	return nil
+}
+
+func (d *Deployer) ListXdcrRemotes(ctx context.Context, clusterID string) ([]clustercontrol.RemoteClusterInfo, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().ListRemoteClusters(ctx)
+}
+
+func (d *Deployer) RemoveXdcrRemote(ctx context.Context, clusterID string, name string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RemoveXdcrRemote")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().DeleteRemoteCluster(ctx, name)
+}
+
+func (d *Deployer) ListXdcrReplications(ctx context.Context, clusterID string) ([]clustercontrol.ReplicationInfo, error) {
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().ListReplications(ctx)
+}
+
+func (d *Deployer) CreateXdcrReplication(ctx context.Context, clusterID string, req *clustercontrol.CreateReplicationRequest) (string, error) {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "CreateXdcrReplication")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	resp, err := controller.Controller().CreateReplication(ctx, req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create replication")
+	}
+
+	return resp.ID, nil
+}
+
+func (d *Deployer) UpdateXdcrReplication(ctx context.Context, clusterID string, replicationID string, settings *clustercontrol.ReplicationSettings) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "UpdateXdcrReplication")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().UpdateReplicationSettings(ctx, replicationID, settings)
+}
+
+func (d *Deployer) RemoveXdcrReplication(ctx context.Context, clusterID string, replicationID string) error {
+	ctx, unlock, err := d.lockCluster(ctx, clusterID, "RemoveXdcrReplication")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	controller, err := d.getController(ctx, clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster controller")
+	}
+
+	return controller.Controller().DeleteReplication(ctx, replicationID)
+}
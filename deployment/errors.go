@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// The following are the kinds of errors which deployers report, allowing
+// callers to branch on them using errors.Is rather than matching against
+// the error messages.
+var (
+	ErrClusterNotFound    = errors.New("cluster not found")
+	ErrVersionUnavailable = errors.New("version unavailable")
+	ErrQuotaExceeded      = errors.New("quota exceeded")
+	ErrProvisionTimeout   = errors.New("provisioning timed out")
+	ErrInvalidDefinition  = errors.New("invalid definition")
+	ErrFeatureUnsupported = errors.New("feature unsupported")
+	ErrImageUntrusted     = errors.New("image untrusted")
+)
+
+// Error associates one of the error kinds above with its underlying cause.
+// errors.Is matches against both the kind and the cause, and errors.As can
+// still be used to extract the cause.
+type Error struct {
+	Kind  error
+	Cause error
+}
+
+var _ error = (*Error)(nil)
+
+func NewError(kind error, cause error) error {
+	return &Error{
+		Kind:  kind,
+		Cause: cause,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Cause)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WrapProvisionTimeout marks errors caused by the context deadline passing
+// while provisioning as provision timeouts.
+func WrapProvisionTimeout(err error) error {
+	if err == nil || errors.Is(err, ErrProvisionTimeout) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewError(ErrProvisionTimeout, err)
+	}
+
+	return err
+}
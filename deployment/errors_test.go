@@ -0,0 +1,30 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKinds(t *testing.T) {
+	cause := errors.New("no such image")
+	err := pkgerrors.Wrap(NewError(ErrVersionUnavailable, cause), "failed to fetch images")
+
+	assert.ErrorIs(t, err, ErrVersionUnavailable)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrClusterNotFound)
+	assert.EqualError(t, err, "failed to fetch images: version unavailable: no such image")
+}
+
+func TestWrapProvisionTimeout(t *testing.T) {
+	err := WrapProvisionTimeout(pkgerrors.Wrap(context.DeadlineExceeded, "failed to wait"))
+	assert.ErrorIs(t, err, ErrProvisionTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// other errors are left alone
+	otherErr := errors.New("something else")
+	assert.Same(t, otherErr, WrapProvisionTimeout(otherErr))
+}
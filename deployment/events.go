@@ -0,0 +1,197 @@
+package deployment
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ClusterEventType string
+
+const (
+	ClusterEventAdded        ClusterEventType = "added"
+	ClusterEventRemoved      ClusterEventType = "removed"
+	ClusterEventStateChanged ClusterEventType = "state-changed"
+	ClusterEventNodeAdded    ClusterEventType = "node-added"
+	ClusterEventNodeRemoved  ClusterEventType = "node-removed"
+)
+
+// ClusterEvent describes a change to a cluster observed by a deployer.
+type ClusterEvent struct {
+	Type      ClusterEventType
+	Time      time.Time
+	ClusterID string
+
+	// NodeID is the node which was added or removed for node events.
+	NodeID string
+
+	// State is the new state of the cluster, and PrevState is its state
+	// prior to the change.  Both are empty for removed clusters.
+	State     string
+	PrevState string
+
+	// Cluster is the latest information about the cluster, which is nil
+	// for removed clusters.
+	Cluster ClusterInfo
+}
+
+type ClusterWatcherOptions struct {
+	Logger       *zap.Logger
+	ListClusters func(ctx context.Context) ([]ClusterInfo, error)
+
+	// Triggers signals that clusters may have changed and should be listed
+	// again, such as when the deployer receives events from its backend.
+	Triggers <-chan struct{}
+
+	// PollInterval is how often clusters are listed when no triggers are
+	// received, catching changes which do not produce triggers.
+	PollInterval time.Duration
+}
+
+// WatchClusterChanges implements cluster watching for deployers by listing
+// clusters whenever they may have changed and emitting events for any
+// differences.  The clusters which exist when watching starts are emitted
+// as added events.  The returned channel is closed once ctx is done.
+func WatchClusterChanges(ctx context.Context, opts *ClusterWatcherOptions) (<-chan ClusterEvent, error) {
+	clusters, err := opts.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	eventCh := make(chan ClusterEvent, 16)
+
+	go func() {
+		defer close(eventCh)
+
+		var prev []ClusterInfo
+		for {
+			for _, event := range DiffClusters(prev, clusters, time.Now()) {
+				select {
+				case eventCh <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = clusters
+
+			select {
+			case <-opts.Triggers:
+			case <-time.After(opts.PollInterval):
+			case <-ctx.Done():
+				return
+			}
+
+			newClusters, err := opts.ListClusters(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				// we keep the previous clusters, so changes are caught next time
+				opts.Logger.Debug("failed to list clusters while watching", zap.Error(err))
+				continue
+			}
+			clusters = newClusters
+		}
+	}()
+
+	return eventCh, nil
+}
+
+func clusterNodeKey(node ClusterNodeInfo) string {
+	if node.GetID() != "" {
+		return node.GetID()
+	}
+	return node.GetResourceID()
+}
+
+// DiffClusters returns the events describing the changes between two
+// listings of clusters.
+func DiffClusters(prev, cur []ClusterInfo, now time.Time) []ClusterEvent {
+	prevByID := make(map[string]ClusterInfo)
+	for _, cluster := range prev {
+		prevByID[cluster.GetID()] = cluster
+	}
+
+	curByID := make(map[string]ClusterInfo)
+	for _, cluster := range cur {
+		curByID[cluster.GetID()] = cluster
+	}
+
+	var events []ClusterEvent
+
+	for _, cluster := range cur {
+		prevCluster, ok := prevByID[cluster.GetID()]
+		if !ok {
+			events = append(events, ClusterEvent{
+				Type:      ClusterEventAdded,
+				Time:      now,
+				ClusterID: cluster.GetID(),
+				State:     cluster.GetState(),
+				Cluster:   cluster,
+			})
+			continue
+		}
+
+		if prevCluster.GetState() != cluster.GetState() {
+			events = append(events, ClusterEvent{
+				Type:      ClusterEventStateChanged,
+				Time:      now,
+				ClusterID: cluster.GetID(),
+				State:     cluster.GetState(),
+				PrevState: prevCluster.GetState(),
+				Cluster:   cluster,
+			})
+		}
+
+		prevNodes := make(map[string]bool)
+		for _, node := range prevCluster.GetNodes() {
+			prevNodes[clusterNodeKey(node)] = true
+		}
+
+		curNodes := make(map[string]bool)
+		for _, node := range cluster.GetNodes() {
+			nodeKey := clusterNodeKey(node)
+			curNodes[nodeKey] = true
+
+			if !prevNodes[nodeKey] {
+				events = append(events, ClusterEvent{
+					Type:      ClusterEventNodeAdded,
+					Time:      now,
+					ClusterID: cluster.GetID(),
+					NodeID:    nodeKey,
+					State:     cluster.GetState(),
+					Cluster:   cluster,
+				})
+			}
+		}
+
+		for _, node := range prevCluster.GetNodes() {
+			nodeKey := clusterNodeKey(node)
+			if !curNodes[nodeKey] {
+				events = append(events, ClusterEvent{
+					Type:      ClusterEventNodeRemoved,
+					Time:      now,
+					ClusterID: cluster.GetID(),
+					NodeID:    nodeKey,
+					State:     cluster.GetState(),
+					Cluster:   cluster,
+				})
+			}
+		}
+	}
+
+	for _, cluster := range prev {
+		if _, ok := curByID[cluster.GetID()]; !ok {
+			events = append(events, ClusterEvent{
+				Type:      ClusterEventRemoved,
+				Time:      now,
+				ClusterID: cluster.GetID(),
+				PrevState: cluster.GetState(),
+			})
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNodeInfo struct{ id string }
+
+func (i testNodeInfo) GetID() string         { return i.id }
+func (i testNodeInfo) IsClusterNode() bool   { return true }
+func (i testNodeInfo) GetResourceID() string { return "" }
+func (i testNodeInfo) GetName() string       { return "" }
+func (i testNodeInfo) GetIPAddress() string  { return "" }
+func (i testNodeInfo) GetOTPNode() string    { return "" }
+func (i testNodeInfo) GetNodeUUID() string   { return "" }
+func (i testNodeInfo) GetNodeGroup() string  { return "" }
+
+type testClusterInfo struct {
+	id    string
+	state string
+	nodes []string
+}
+
+func (i testClusterInfo) GetID() string        { return i.id }
+func (i testClusterInfo) GetType() ClusterType { return ClusterTypeServer }
+func (i testClusterInfo) GetPurpose() string   { return "" }
+func (i testClusterInfo) GetExpiry() time.Time { return time.Time{} }
+func (i testClusterInfo) GetState() string     { return i.state }
+func (i testClusterInfo) GetNodes() []ClusterNodeInfo {
+	var nodes []ClusterNodeInfo
+	for _, id := range i.nodes {
+		nodes = append(nodes, testNodeInfo{id})
+	}
+	return nodes
+}
+
+func TestDiffClusters(t *testing.T) {
+	prev := []ClusterInfo{
+		testClusterInfo{"a", "ready", []string{"a1", "a2"}},
+		testClusterInfo{"b", "ready", []string{"b1"}},
+	}
+	cur := []ClusterInfo{
+		testClusterInfo{"a", "unhealthy", []string{"a1", "a3"}},
+		testClusterInfo{"c", "deploying", nil},
+	}
+
+	var got []string
+	for _, event := range DiffClusters(prev, cur, time.Now()) {
+		got = append(got, string(event.Type)+":"+event.ClusterID+":"+event.NodeID+":"+event.State)
+	}
+
+	expected := []string{
+		"state-changed:a::unhealthy",
+		"node-added:a:a3:unhealthy",
+		"node-removed:a:a2:unhealthy",
+		"added:c::deploying",
+		"removed:b::",
+	}
+	assert.Equal(t, expected, got)
+
+	// unchanged clusters produce no events
+	assert.Empty(t, DiffClusters(cur, cur, time.Now()))
+}
@@ -0,0 +1,38 @@
+package deployment
+
+import (
+	"context"
+	"time"
+)
+
+// ClusterStateExpiring is the state reported for clusters which have passed
+// their expiry time, but are still within their grace period.
+const ClusterStateExpiring = "expiring"
+
+type ExpiryPhase int
+
+const (
+	ExpiryPhaseActive ExpiryPhase = iota
+	ExpiryPhaseExpiring
+	ExpiryPhaseExpired
+)
+
+// GetExpiryPhase identifies where a resource with the specified expiry is within
+// the two-phase expiry model.  Resources are first marked as expiring once their
+// expiry passes, and are only considered expired (and thus reapable) once the
+// grace period has also elapsed.
+func GetExpiryPhase(expiry time.Time, gracePeriod time.Duration, curTime time.Time) ExpiryPhase {
+	if expiry.IsZero() || expiry.After(curTime) {
+		return ExpiryPhaseActive
+	}
+
+	if expiry.Add(gracePeriod).After(curTime) {
+		return ExpiryPhaseExpiring
+	}
+
+	return ExpiryPhaseExpired
+}
+
+// ExpiringHook is invoked by a deployers cleanup when it observes a cluster
+// which has entered the expiring phase.
+type ExpiringHook func(ctx context.Context, clusterID string, expiry time.Time)
@@ -0,0 +1,70 @@
+package deployment
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExpiryNotifications records which clusters have already been notified of
+// their expiry, so deployers which have nowhere to store this alongside the
+// cluster itself do not notify again on every cleanup.
+type ExpiryNotifications struct {
+	Path string
+
+	// GracePeriod is how long after their expiry clusters are removed, after
+	// which their records are no longer needed.
+	GracePeriod time.Duration
+}
+
+func (n *ExpiryNotifications) load() map[string]time.Time {
+	notified := make(map[string]time.Time)
+
+	notifiedBytes, err := os.ReadFile(n.Path)
+	if err != nil {
+		return notified
+	}
+
+	// a corrupt record only causes notifications to be repeated, so errors
+	// are ignored
+	json.Unmarshal(notifiedBytes, &notified)
+	return notified
+}
+
+// Mark records that a cluster has been notified of the specified expiry, and
+// returns whether it had not already been.  Clusters whose expiry changes are
+// notified again once the new expiry passes.
+func (n *ExpiryNotifications) Mark(clusterID string, expiry time.Time, curTime time.Time) (bool, error) {
+	notified := n.load()
+
+	if prevExpiry, ok := notified[clusterID]; ok && prevExpiry.Equal(expiry) {
+		return false, nil
+	}
+	notified[clusterID] = expiry
+
+	for notifiedClusterID, notifiedExpiry := range notified {
+		if GetExpiryPhase(notifiedExpiry, n.GracePeriod, curTime) == ExpiryPhaseExpired {
+			delete(notified, notifiedClusterID)
+		}
+	}
+
+	notifiedBytes, err := json.Marshal(notified)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to marshal expiry notifications")
+	}
+
+	err = os.MkdirAll(filepath.Dir(n.Path), 0700)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create expiry notifications directory")
+	}
+
+	err = os.WriteFile(n.Path, notifiedBytes, 0600)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to write expiry notifications")
+	}
+
+	return true, nil
+}
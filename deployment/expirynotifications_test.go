@@ -0,0 +1,39 @@
+package deployment
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryNotifications(t *testing.T) {
+	notifications := &ExpiryNotifications{
+		Path:        filepath.Join(t.TempDir(), "cbdinocluster", "expiry-notified.json"),
+		GracePeriod: 1 * time.Hour,
+	}
+
+	curTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiry := curTime.Add(-10 * time.Minute)
+
+	marked, err := notifications.Mark("cluster-a", expiry, curTime)
+	require.NoError(t, err)
+	assert.True(t, marked)
+
+	marked, err = notifications.Mark("cluster-a", expiry, curTime)
+	require.NoError(t, err)
+	assert.False(t, marked)
+
+	// an extended cluster is notified again once its new expiry passes
+	marked, err = notifications.Mark("cluster-a", expiry.Add(5*time.Minute), curTime)
+	require.NoError(t, err)
+	assert.True(t, marked)
+
+	// records of removed clusters are dropped
+	marked, err = notifications.Mark("cluster-b", curTime.Add(2*time.Hour), curTime.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, marked)
+	assert.NotContains(t, notifications.load(), "cluster-a")
+}
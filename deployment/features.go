@@ -0,0 +1,292 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+	"golang.org/x/exp/slices"
+	"golang.org/x/mod/semver"
+)
+
+// Feature is a capability of Couchbase Server which is only available in
+// some versions or editions.
+type Feature string
+
+const (
+	FeatureCollections    Feature = "collections"
+	FeatureMagma          Feature = "magma"
+	FeatureAnalytics      Feature = "analytics"
+	FeatureEventing       Feature = "eventing"
+	FeatureBackupService  Feature = "backup-service"
+	FeatureQueryUDFs      Feature = "query-udfs"
+	FeatureJavascriptUDFs Feature = "javascript-udfs"
+	FeatureServerGroups   Feature = "server-groups"
+	FeatureTransactions   Feature = "transactions"
+
+	FeatureCollectionUpdates Feature = "collection-updates"
+
+	FeatureEphemeralBuckets Feature = "ephemeral-buckets"
+	FeatureMemcachedBuckets Feature = "memcached-buckets"
+)
+
+type FeatureRequirement struct {
+	// Description is used in errors, describing what needs the feature.
+	Description string
+
+	MinVersion     string
+	EnterpriseOnly bool
+
+	// MaxVersion is the first version which no longer supports the feature,
+	// if it has been removed.
+	MaxVersion string
+
+	// Alternative suggests what to do instead, if there is an alternative
+	// other than using a newer version.
+	Alternative string
+}
+
+// FeatureMatrix lists the versions and editions of Couchbase Server which
+// support each feature.  Columnar clusters are versioned independently of
+// Couchbase Server and are not checked against it.
+var FeatureMatrix = map[Feature]FeatureRequirement{
+	FeatureCollections: {
+		Description: "scopes and collections",
+		MinVersion:  "7.0.0",
+		Alternative: "use the _default scope and collection",
+	},
+	FeatureMagma: {
+		Description:    "the magma storage backend",
+		MinVersion:     "7.1.0",
+		EnterpriseOnly: true,
+		Alternative:    "use the couchstore storage backend",
+	},
+	FeatureAnalytics: {
+		Description:    "the analytics service",
+		MinVersion:     "6.0.0",
+		EnterpriseOnly: true,
+	},
+	FeatureEventing: {
+		Description:    "the eventing service",
+		MinVersion:     "5.5.0",
+		EnterpriseOnly: true,
+	},
+	FeatureBackupService: {
+		Description:    "the backup service",
+		MinVersion:     "7.0.0",
+		EnterpriseOnly: true,
+	},
+	FeatureQueryUDFs: {
+		Description: "query user-defined functions",
+		MinVersion:  "7.0.0",
+	},
+	FeatureJavascriptUDFs: {
+		Description:    "javascript user-defined functions",
+		MinVersion:     "7.0.0",
+		EnterpriseOnly: true,
+		Alternative:    "use an inline SQL++ function",
+	},
+	FeatureServerGroups: {
+		Description:    "server groups",
+		MinVersion:     "5.0.0",
+		EnterpriseOnly: true,
+	},
+	FeatureTransactions: {
+		Description: "query transaction settings",
+		MinVersion:  "7.0.0",
+	},
+	FeatureCollectionUpdates: {
+		Description: "updating collection settings",
+		MinVersion:  "7.6.0",
+		Alternative: "drop and recreate the collection with the new settings",
+	},
+	FeatureEphemeralBuckets: {
+		Description: "ephemeral buckets",
+		MinVersion:  "5.0.0",
+	},
+	FeatureMemcachedBuckets: {
+		Description: "memcached buckets",
+		MinVersion:  "1.8.0",
+		MaxVersion:  "8.0.0",
+		Alternative: "use an ephemeral bucket",
+	},
+}
+
+// communityTopologies are the only combinations of services which Community
+// Edition permits a node to run, as it does not support multi-dimensional
+// scaling.
+var communityTopologies = [][]clusterdef.Service{
+	{clusterdef.KvService},
+	{clusterdef.KvService, clusterdef.IndexService, clusterdef.QueryService},
+	{clusterdef.KvService, clusterdef.IndexService, clusterdef.QueryService, clusterdef.SearchService},
+}
+
+func isCommunityTopology(services []clusterdef.Service) bool {
+	// an empty service list implies the default services
+	if len(services) == 0 {
+		return true
+	}
+
+	for _, topology := range communityTopologies {
+		if len(topology) != len(services) {
+			continue
+		}
+
+		matches := true
+		for _, service := range services {
+			if !slices.Contains(topology, service) {
+				matches = false
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+var serviceFeatures = map[clusterdef.Service]Feature{
+	clusterdef.AnalyticsService: FeatureAnalytics,
+	clusterdef.EventingService:  FeatureEventing,
+	clusterdef.BackupService:    FeatureBackupService,
+}
+
+// unsupportedFeatureReason returns an explanation of why a version does not
+// support a feature, or an empty string if it is supported.
+func unsupportedFeatureReason(version string, feature Feature) (string, error) {
+	req, ok := FeatureMatrix[feature]
+	if !ok {
+		return "", fmt.Errorf("unknown feature `%s`", feature)
+	}
+
+	parsedVersion, err := versionident.Identify(context.Background(), version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version `%s`: %s", version, err)
+	}
+
+	var reason string
+	if req.EnterpriseOnly && parsedVersion.CommunityEdition {
+		reason = fmt.Sprintf("%s requires Enterprise Edition, but version `%s` is Community Edition",
+			req.Description, version)
+	} else if semver.Compare("v"+parsedVersion.Version, "v"+req.MinVersion) < 0 {
+		reason = fmt.Sprintf("%s requires Couchbase Server %s or later, but version `%s` is in use",
+			req.Description, req.MinVersion, version)
+		if req.Alternative != "" {
+			reason += ", " + req.Alternative + " or use a newer version"
+		}
+	} else if req.MaxVersion != "" && semver.Compare("v"+parsedVersion.Version, "v"+req.MaxVersion) >= 0 {
+		reason = fmt.Sprintf("%s were removed in Couchbase Server %s, but version `%s` is in use",
+			req.Description, req.MaxVersion, version)
+		if req.Alternative != "" {
+			reason += ", " + req.Alternative + " or use an older version"
+		}
+	} else {
+		return "", nil
+	}
+
+	return reason, nil
+}
+
+// CheckFeature checks whether a version, in the form used by cluster
+// definitions, supports a feature.  The returned error explains what to
+// change and is of kind ErrFeatureUnsupported.
+func CheckFeature(version string, feature Feature) error {
+	reason, err := unsupportedFeatureReason(version, feature)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		return NewError(ErrFeatureUnsupported, errors.New(reason))
+	}
+
+	return nil
+}
+
+// CheckClusterFeature checks that every node of a cluster supports a
+// feature, given the versions of its nodes.  Nodes without a known version
+// are assumed to support it.
+func CheckClusterFeature(versions []string, feature Feature) error {
+	for _, version := range versions {
+		if version == "" {
+			continue
+		}
+
+		err := CheckFeature(version, feature)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateNodeGroupFeatures checks the services of each node group are
+// supported by the version that node group will run.
+func ValidateNodeGroupFeatures(nodeGrps []*clusterdef.NodeGroup) []DefinitionViolation {
+	var violations []DefinitionViolation
+
+	for nodeGrpIdx, nodeGrp := range nodeGrps {
+		// node groups with a custom image have no version we can check
+		if nodeGrp.Version == "" || nodeGrp.Docker.Image != "" {
+			continue
+		}
+
+		hasServiceViolation := false
+		for _, service := range nodeGrp.Services {
+			feature, ok := serviceFeatures[service]
+			if !ok {
+				continue
+			}
+
+			// invalid versions are already reported by ValidateNodeGroupVersions
+			reason, err := unsupportedFeatureReason(nodeGrp.Version, feature)
+			if err == nil && reason != "" {
+				violations = append(violations, DefinitionViolation{
+					Field:   NodeGroupField(nodeGrpIdx, "services"),
+					Message: reason,
+				})
+				hasServiceViolation = true
+			}
+		}
+
+		if nodeGrp.ServerGroup != "" {
+			reason, err := unsupportedFeatureReason(nodeGrp.Version, FeatureServerGroups)
+			if err == nil && reason != "" {
+				violations = append(violations, DefinitionViolation{
+					Field:   NodeGroupField(nodeGrpIdx, "server-group"),
+					Message: reason,
+				})
+			}
+		}
+
+		// services unavailable in community edition are reported above
+		version, err := versionident.Identify(context.Background(), nodeGrp.Version)
+		if err == nil && version.CommunityEdition && !hasServiceViolation && !isCommunityTopology(nodeGrp.Services) {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "services"),
+				Message: "Community Edition nodes can only run kv, kv+index+n1ql or kv+index+n1ql+fts",
+			})
+		}
+	}
+
+	return violations
+}
+
+// CheckNodeGroupFeatures is the equivalent of ValidateNodeGroupFeatures for
+// deployers, reporting the first violation as an error of kind
+// ErrFeatureUnsupported so that definitions which were not validated
+// beforehand fail before anything is deployed.
+func CheckNodeGroupFeatures(nodeGrps []*clusterdef.NodeGroup) error {
+	violations := ValidateNodeGroupFeatures(nodeGrps)
+	if len(violations) > 0 {
+		return NewError(ErrFeatureUnsupported,
+			fmt.Errorf("unsupported features for %s: %s",
+				violations[0].Field, violations[0].Message))
+	}
+
+	return nil
+}
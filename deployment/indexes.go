@@ -0,0 +1,98 @@
+package deployment
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IndexInfo describes a GSI index, with one entry for each replica of the
+// index.
+type IndexInfo struct {
+	BucketName     string
+	ScopeName      string
+	CollectionName string
+	Name           string
+	ReplicaID      int
+	Status         string
+	Progress       int
+	Definition     string
+	Hosts          []string
+}
+
+// IndexAdvice is the output of the index advisor for a statement, with each
+// index given as the statement which creates it.
+type IndexAdvice struct {
+	CurrentIndexes     []string
+	RecommendedIndexes []string
+	CoveringIndexes    []string
+}
+
+// IndexAdviseStatement builds the statement which asks the index advisor
+// about a query statement.
+func IndexAdviseStatement(statement string) (string, error) {
+	statement = strings.TrimSpace(statement)
+	statement = strings.TrimSuffix(statement, ";")
+	if statement == "" {
+		return "", errors.New("a statement to advise on must be specified")
+	}
+
+	if strings.HasPrefix(strings.ToUpper(statement), "ADVISE ") {
+		return statement, nil
+	}
+	return "ADVISE " + statement, nil
+}
+
+type indexAdviceJson_Index struct {
+	IndexStatement string `json:"index_statement"`
+}
+
+type indexAdviceJson struct {
+	Advice struct {
+		AdviseInfo struct {
+			CurrentIndexes     []indexAdviceJson_Index `json:"current_indexes"`
+			RecommendedIndexes json.RawMessage         `json:"recommended_indexes"`
+		} `json:"adviseinfo"`
+	} `json:"advice"`
+}
+
+func indexStatements(indexes []indexAdviceJson_Index) []string {
+	var statements []string
+	for _, index := range indexes {
+		statements = append(statements, index.IndexStatement)
+	}
+	return statements
+}
+
+// ParseIndexAdvice parses the result row of an ADVISE statement.
+func ParseIndexAdvice(row json.RawMessage) (*IndexAdvice, error) {
+	var resp indexAdviceJson
+	err := json.Unmarshal(row, &resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse index advice")
+	}
+
+	adviseInfo := resp.Advice.AdviseInfo
+	advice := &IndexAdvice{
+		CurrentIndexes: indexStatements(adviseInfo.CurrentIndexes),
+	}
+
+	// when there is nothing to recommend, the advisor provides a message
+	// rather than an object
+	var recommended struct {
+		Indexes         []indexAdviceJson_Index `json:"indexes"`
+		CoveringIndexes []indexAdviceJson_Index `json:"covering_indexes"`
+	}
+	if len(adviseInfo.RecommendedIndexes) > 0 && adviseInfo.RecommendedIndexes[0] == '{' {
+		err := json.Unmarshal(adviseInfo.RecommendedIndexes, &recommended)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse recommended indexes")
+		}
+	}
+
+	advice.RecommendedIndexes = indexStatements(recommended.Indexes)
+	advice.CoveringIndexes = indexStatements(recommended.CoveringIndexes)
+
+	return advice, nil
+}
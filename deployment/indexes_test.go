@@ -0,0 +1,60 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexAdviseStatement(t *testing.T) {
+	statement, err := IndexAdviseStatement(" SELECT * FROM `travel-sample` WHERE city = \"Paris\"; ")
+	require.NoError(t, err)
+	assert.Equal(t, "ADVISE SELECT * FROM `travel-sample` WHERE city = \"Paris\"", statement)
+
+	// advise statements are left alone
+	statement, err = IndexAdviseStatement("advise SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "advise SELECT 1", statement)
+
+	_, err = IndexAdviseStatement(";")
+	assert.Error(t, err)
+}
+
+func TestParseIndexAdvice(t *testing.T) {
+	advice, err := ParseIndexAdvice([]byte(`{
+		"#operator": "Advise",
+		"advice": {
+			"#operator": "IndexAdvice",
+			"adviseinfo": {
+				"current_indexes": [
+					{"index_statement": "CREATE PRIMARY INDEX def_primary ON ` + "`travel-sample`" + `"}
+				],
+				"recommended_indexes": {
+					"covering_indexes": [
+						{"index_statement": "CREATE INDEX adv_city_name ON ` + "`travel-sample`" + `(city,name)"}
+					],
+					"indexes": [
+						{"index_statement": "CREATE INDEX adv_city ON ` + "`travel-sample`" + `(city)"}
+					]
+				}
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CREATE PRIMARY INDEX def_primary ON `travel-sample`"}, advice.CurrentIndexes)
+	assert.Equal(t, []string{"CREATE INDEX adv_city ON `travel-sample`(city)"}, advice.RecommendedIndexes)
+	assert.Equal(t, []string{"CREATE INDEX adv_city_name ON `travel-sample`(city,name)"}, advice.CoveringIndexes)
+
+	advice, err = ParseIndexAdvice([]byte(`{
+		"advice": {
+			"adviseinfo": {
+				"current_indexes": [],
+				"recommended_indexes": "No index recommendation at this time."
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Empty(t, advice.RecommendedIndexes)
+	assert.Empty(t, advice.CoveringIndexes)
+}
@@ -0,0 +1,499 @@
+package deployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+)
+
+// DeployerCall describes a single call to a Deployer which is being
+// intercepted.  Results is only populated once the call has been invoked.
+type DeployerCall struct {
+	Method    string
+	Args      []interface{}
+	Results   []interface{}
+	StartTime time.Time
+}
+
+// DeployerInvoker performs the intercepted call, or the next interceptor.
+type DeployerInvoker func(ctx context.Context) error
+
+// DeployerInterceptor is invoked around every call to a Deployer, and must
+// call invoke to actually perform it.  Interceptors can act before and after
+// the call, replace the context, delay the call or replace its error.
+type DeployerInterceptor func(ctx context.Context, call *DeployerCall, invoke DeployerInvoker) error
+
+// DeployerHooks is a DeployerInterceptor for the common case of simply
+// observing calls before and after they are performed.
+type DeployerHooks struct {
+	Before func(ctx context.Context, call *DeployerCall)
+	After  func(ctx context.Context, call *DeployerCall, err error)
+}
+
+func (h *DeployerHooks) Intercept(ctx context.Context, call *DeployerCall, invoke DeployerInvoker) error {
+	if h.Before != nil {
+		h.Before(ctx, call)
+	}
+
+	err := invoke(ctx)
+
+	if h.After != nil {
+		h.After(ctx, call, err)
+	}
+
+	return err
+}
+
+// InterceptedDeployer wraps a Deployer, passing every call through a chain
+// of interceptors.  This allows test frameworks to record provisioning
+// timelines or inject delays without wrapping every method themselves.
+type InterceptedDeployer struct {
+	deployer     Deployer
+	interceptors []DeployerInterceptor
+}
+
+var _ Deployer = (*InterceptedDeployer)(nil)
+
+// NewInterceptedDeployer wraps a deployer with interceptors, which are
+// invoked in the order they are specified, the first being outermost.
+func NewInterceptedDeployer(deployer Deployer, interceptors ...DeployerInterceptor) *InterceptedDeployer {
+	return &InterceptedDeployer{
+		deployer:     deployer,
+		interceptors: interceptors,
+	}
+}
+
+// Unwrap returns the underlying deployer, which is needed to use the
+// deployer specific functionality which is not part of Deployer.
+func (i *InterceptedDeployer) Unwrap() Deployer {
+	return i.deployer
+}
+
+func (i *InterceptedDeployer) intercept(
+	ctx context.Context,
+	method string,
+	args []interface{},
+	fn func(ctx context.Context) ([]interface{}, error),
+) error {
+	call := &DeployerCall{
+		Method:    method,
+		Args:      args,
+		StartTime: time.Now(),
+	}
+
+	var invoke func(ctx context.Context, idx int) error
+	invoke = func(ctx context.Context, idx int) error {
+		if idx < len(i.interceptors) {
+			return i.interceptors[idx](ctx, call, func(ctx context.Context) error {
+				return invoke(ctx, idx+1)
+			})
+		}
+
+		results, err := fn(ctx)
+		call.Results = results
+		return err
+	}
+
+	return invoke(ctx, 0)
+}
+
+func (i *InterceptedDeployer) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
+	var result []ClusterInfo
+	err := i.intercept(ctx, "ListClusters", nil, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListClusters(ctx)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) WatchClusters(ctx context.Context) (<-chan ClusterEvent, error) {
+	var result <-chan ClusterEvent
+	err := i.intercept(ctx, "WatchClusters", nil, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.WatchClusters(ctx)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (ClusterInfo, error) {
+	var result ClusterInfo
+	err := i.intercept(ctx, "NewCluster", []interface{}{def}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.NewCluster(ctx, def)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]DefinitionViolation, error) {
+	var result []DefinitionViolation
+	err := i.intercept(ctx, "ValidateDefinition", []interface{}{def}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ValidateDefinition(ctx, def)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error) {
+	var result *clusterdef.Cluster
+	err := i.intercept(ctx, "GetDefinition", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetDefinition(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) UpdateClusterExpiry(ctx context.Context, clusterID string, newExpiryTime time.Time) error {
+	return i.intercept(ctx, "UpdateClusterExpiry", []interface{}{clusterID, newExpiryTime}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.UpdateClusterExpiry(ctx, clusterID, newExpiryTime)
+	})
+}
+
+func (i *InterceptedDeployer) ModifyCluster(ctx context.Context, clusterID string, def *clusterdef.Cluster) error {
+	return i.intercept(ctx, "ModifyCluster", []interface{}{clusterID, def}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.ModifyCluster(ctx, clusterID, def)
+	})
+}
+
+func (i *InterceptedDeployer) AddNode(ctx context.Context, clusterID string) (string, error) {
+	var result string
+	err := i.intercept(ctx, "AddNode", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.AddNode(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error) {
+	var result string
+	err := i.intercept(ctx, "SwapNode", []interface{}{clusterID, oldNodeID, newVersion}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.SwapNode(ctx, clusterID, oldNodeID, newVersion)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) RemoveNode(ctx context.Context, clusterID string, nodeID string) error {
+	return i.intercept(ctx, "RemoveNode", []interface{}{clusterID, nodeID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.RemoveNode(ctx, clusterID, nodeID)
+	})
+}
+
+func (i *InterceptedDeployer) RemoveCluster(ctx context.Context, clusterID string) error {
+	return i.intercept(ctx, "RemoveCluster", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.RemoveCluster(ctx, clusterID)
+	})
+}
+
+func (i *InterceptedDeployer) RemoveAll(ctx context.Context) error {
+	return i.intercept(ctx, "RemoveAll", nil, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.RemoveAll(ctx)
+	})
+}
+
+func (i *InterceptedDeployer) Cleanup(ctx context.Context) error {
+	return i.intercept(ctx, "Cleanup", nil, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.Cleanup(ctx)
+	})
+}
+
+func (i *InterceptedDeployer) GetConnectInfo(ctx context.Context, clusterID string) (*ConnectInfo, error) {
+	var result *ConnectInfo
+	err := i.intercept(ctx, "GetConnectInfo", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetConnectInfo(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) ListUsers(ctx context.Context, clusterID string) ([]UserInfo, error) {
+	var result []UserInfo
+	err := i.intercept(ctx, "ListUsers", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListUsers(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) CreateUser(ctx context.Context, clusterID string, opts *CreateUserOptions) error {
+	return i.intercept(ctx, "CreateUser", []interface{}{clusterID, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateUser(ctx, clusterID, opts)
+	})
+}
+
+func (i *InterceptedDeployer) DeleteUser(ctx context.Context, clusterID string, username string) error {
+	return i.intercept(ctx, "DeleteUser", []interface{}{clusterID, username}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DeleteUser(ctx, clusterID, username)
+	})
+}
+
+func (i *InterceptedDeployer) ListBuckets(ctx context.Context, clusterID string) ([]BucketInfo, error) {
+	var result []BucketInfo
+	err := i.intercept(ctx, "ListBuckets", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListBuckets(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) CreateBucket(ctx context.Context, clusterID string, opts *CreateBucketOptions) error {
+	return i.intercept(ctx, "CreateBucket", []interface{}{clusterID, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateBucket(ctx, clusterID, opts)
+	})
+}
+
+func (i *InterceptedDeployer) DeleteBucket(ctx context.Context, clusterID string, bucketName string) error {
+	return i.intercept(ctx, "DeleteBucket", []interface{}{clusterID, bucketName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DeleteBucket(ctx, clusterID, bucketName)
+	})
+}
+
+func (i *InterceptedDeployer) LoadSampleBucket(ctx context.Context, clusterID string, bucketName string) error {
+	return i.intercept(ctx, "LoadSampleBucket", []interface{}{clusterID, bucketName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.LoadSampleBucket(ctx, clusterID, bucketName)
+	})
+}
+
+func (i *InterceptedDeployer) GetCertificate(ctx context.Context, clusterID string) (string, error) {
+	var result string
+	err := i.intercept(ctx, "GetCertificate", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetCertificate(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) GetGatewayCertificate(ctx context.Context, clusterID string) (string, error) {
+	var result string
+	err := i.intercept(ctx, "GetGatewayCertificate", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetGatewayCertificate(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) ExecuteQuery(ctx context.Context, clusterID string, query string) (string, error) {
+	var result string
+	err := i.intercept(ctx, "ExecuteQuery", []interface{}{clusterID, query}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ExecuteQuery(ctx, clusterID, query)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) ListCollections(ctx context.Context, clusterID string, bucketName string) ([]ScopeInfo, error) {
+	var result []ScopeInfo
+	err := i.intercept(ctx, "ListCollections", []interface{}{clusterID, bucketName}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListCollections(ctx, clusterID, bucketName)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*CollectionManifest, error) {
+	var result *CollectionManifest
+	err := i.intercept(ctx, "GetCollectionManifest", []interface{}{clusterID, bucketName}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetCollectionManifest(ctx, clusterID, bucketName)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error {
+	return i.intercept(ctx, "WaitForCollectionManifest", []interface{}{clusterID, bucketName, minUID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.WaitForCollectionManifest(ctx, clusterID, bucketName, minUID)
+	})
+}
+
+func (i *InterceptedDeployer) CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
+	return i.intercept(ctx, "CreateScope", []interface{}{clusterID, bucketName, scopeName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateScope(ctx, clusterID, bucketName, scopeName)
+	})
+}
+
+func (i *InterceptedDeployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *CreateCollectionOptions) error {
+	return i.intercept(ctx, "CreateCollection", []interface{}{clusterID, bucketName, scopeName, collectionName, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateCollection(ctx, clusterID, bucketName, scopeName, collectionName, opts)
+	})
+}
+
+func (i *InterceptedDeployer) UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *UpdateCollectionOptions) error {
+	return i.intercept(ctx, "UpdateCollection", []interface{}{clusterID, bucketName, scopeName, collectionName, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.UpdateCollection(ctx, clusterID, bucketName, scopeName, collectionName, opts)
+	})
+}
+
+func (i *InterceptedDeployer) DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
+	return i.intercept(ctx, "DeleteScope", []interface{}{clusterID, bucketName, scopeName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DeleteScope(ctx, clusterID, bucketName, scopeName)
+	})
+}
+
+func (i *InterceptedDeployer) DeleteCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
+	return i.intercept(ctx, "DeleteCollection", []interface{}{clusterID, bucketName, scopeName, collectionName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DeleteCollection(ctx, clusterID, bucketName, scopeName, collectionName)
+	})
+}
+
+func (i *InterceptedDeployer) CreateQueryFunction(ctx context.Context, clusterID string, opts *CreateQueryFunctionOptions) error {
+	return i.intercept(ctx, "CreateQueryFunction", []interface{}{clusterID, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateQueryFunction(ctx, clusterID, opts)
+	})
+}
+
+func (i *InterceptedDeployer) DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return i.intercept(ctx, "DropQueryFunction", []interface{}{clusterID, bucketName, scopeName, name}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DropQueryFunction(ctx, clusterID, bucketName, scopeName, name)
+	})
+}
+
+func (i *InterceptedDeployer) ListJsLibraries(ctx context.Context, clusterID string) ([]JsLibraryInfo, error) {
+	var result []JsLibraryInfo
+	err := i.intercept(ctx, "ListJsLibraries", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListJsLibraries(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) CreateJsLibrary(ctx context.Context, clusterID string, opts *CreateJsLibraryOptions) error {
+	return i.intercept(ctx, "CreateJsLibrary", []interface{}{clusterID, opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateJsLibrary(ctx, clusterID, opts)
+	})
+}
+
+func (i *InterceptedDeployer) DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return i.intercept(ctx, "DeleteJsLibrary", []interface{}{clusterID, bucketName, scopeName, name}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DeleteJsLibrary(ctx, clusterID, bucketName, scopeName, name)
+	})
+}
+
+func (i *InterceptedDeployer) ListIndexes(ctx context.Context, clusterID string) ([]IndexInfo, error) {
+	var result []IndexInfo
+	err := i.intercept(ctx, "ListIndexes", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListIndexes(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) AdviseIndexes(ctx context.Context, clusterID string, statement string) (*IndexAdvice, error) {
+	var result *IndexAdvice
+	err := i.intercept(ctx, "AdviseIndexes", []interface{}{clusterID, statement}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.AdviseIndexes(ctx, clusterID, statement)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType BlockNodeTrafficType) error {
+	return i.intercept(ctx, "BlockNodeTraffic", []interface{}{clusterID, nodeID, blockType}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.BlockNodeTraffic(ctx, clusterID, nodeID, blockType)
+	})
+}
+
+func (i *InterceptedDeployer) AllowNodeTraffic(ctx context.Context, clusterID string, nodeID string) error {
+	return i.intercept(ctx, "AllowNodeTraffic", []interface{}{clusterID, nodeID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.AllowNodeTraffic(ctx, clusterID, nodeID)
+	})
+}
+
+func (i *InterceptedDeployer) CollectLogs(ctx context.Context, clusterID string, destPath string) ([]string, error) {
+	var result []string
+	err := i.intercept(ctx, "CollectLogs", []interface{}{clusterID, destPath}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.CollectLogs(ctx, clusterID, destPath)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) ListImages(ctx context.Context) ([]Image, error) {
+	var result []Image
+	err := i.intercept(ctx, "ListImages", nil, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.ListImages(ctx)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) SearchImages(ctx context.Context, version string) ([]Image, error) {
+	var result []Image
+	err := i.intercept(ctx, "SearchImages", []interface{}{version}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.SearchImages(ctx, version)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) PauseNode(ctx context.Context, clusterID string, nodeID string) error {
+	return i.intercept(ctx, "PauseNode", []interface{}{clusterID, nodeID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.PauseNode(ctx, clusterID, nodeID)
+	})
+}
+
+func (i *InterceptedDeployer) UnpauseNode(ctx context.Context, clusterID string, nodeID string) error {
+	return i.intercept(ctx, "UnpauseNode", []interface{}{clusterID, nodeID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.UnpauseNode(ctx, clusterID, nodeID)
+	})
+}
+
+func (i *InterceptedDeployer) SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error {
+	return i.intercept(ctx, "SetNodeClockSkew", []interface{}{clusterID, nodeID, skew}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.SetNodeClockSkew(ctx, clusterID, nodeID, skew)
+	})
+}
+
+func (i *InterceptedDeployer) GetNodeVersions(ctx context.Context, clusterID string) ([]NodeVersionInfo, error) {
+	var result []NodeVersionInfo
+	err := i.intercept(ctx, "GetNodeVersions", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		var err error
+		result, err = i.deployer.GetNodeVersions(ctx, clusterID)
+		return []interface{}{result}, err
+	})
+	return result, err
+}
+
+func (i *InterceptedDeployer) RedeployCluster(ctx context.Context, clusterID string) error {
+	return i.intercept(ctx, "RedeployCluster", []interface{}{clusterID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.RedeployCluster(ctx, clusterID)
+	})
+}
+
+func (i *InterceptedDeployer) CreateCapellaLink(ctx context.Context, columnarID, linkName, clusterId, directID string) error {
+	return i.intercept(ctx, "CreateCapellaLink", []interface{}{columnarID, linkName, clusterId, directID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateCapellaLink(ctx, columnarID, linkName, clusterId, directID)
+	})
+}
+
+func (i *InterceptedDeployer) CreateS3Link(ctx context.Context, columnarID, linkName, region, endpoint, accessKey, secretKey string) error {
+	return i.intercept(ctx, "CreateS3Link", []interface{}{columnarID, linkName, region, endpoint, accessKey, secretKey}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.CreateS3Link(ctx, columnarID, linkName, region, endpoint, accessKey, secretKey)
+	})
+}
+
+func (i *InterceptedDeployer) DropLink(ctx context.Context, columnarID, linkName string) error {
+	return i.intercept(ctx, "DropLink", []interface{}{columnarID, linkName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, i.deployer.DropLink(ctx, columnarID, linkName)
+	})
+}
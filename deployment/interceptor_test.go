@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeployer struct {
+	Deployer
+	clusters []ClusterInfo
+}
+
+func (d *fakeDeployer) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
+	return d.clusters, nil
+}
+
+func (d *fakeDeployer) RemoveCluster(ctx context.Context, clusterID string) error {
+	return ErrClusterNotFound
+}
+
+func TestInterceptedDeployer(t *testing.T) {
+	deployer := &fakeDeployer{
+		clusters: []ClusterInfo{testClusterInfo{"a", "ready", nil}},
+	}
+
+	var order []string
+	var calls []*DeployerCall
+	var afterErr error
+	hooks := &DeployerHooks{
+		Before: func(ctx context.Context, call *DeployerCall) {
+			order = append(order, "before:"+call.Method)
+		},
+		After: func(ctx context.Context, call *DeployerCall, err error) {
+			order = append(order, "after:"+call.Method)
+			calls = append(calls, call)
+			afterErr = err
+		},
+	}
+	outer := func(ctx context.Context, call *DeployerCall, invoke DeployerInvoker) error {
+		order = append(order, "outer:"+call.Method)
+		return invoke(ctx)
+	}
+
+	intercepted := NewInterceptedDeployer(deployer, outer, hooks.Intercept)
+
+	clusters, err := intercepted.ListClusters(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, clusters, 1)
+	require.Len(t, calls, 1)
+	assert.Len(t, calls[0].Results, 1)
+
+	// errors pass through the interceptors
+	err = intercepted.RemoveCluster(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrClusterNotFound)
+	assert.ErrorIs(t, afterErr, ErrClusterNotFound)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "a", calls[1].Args[0])
+
+	assert.Equal(t, []string{
+		"outer:ListClusters", "before:ListClusters", "after:ListClusters",
+		"outer:RemoveCluster", "before:RemoveCluster", "after:RemoveCluster",
+	}, order)
+}
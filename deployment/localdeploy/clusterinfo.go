@@ -16,6 +16,9 @@ func (i ClusterNodeInfo) IsClusterNode() bool   { return true }
 func (i ClusterNodeInfo) GetName() string       { return "" }
 func (i ClusterNodeInfo) GetResourceID() string { return "" }
 func (i ClusterNodeInfo) GetIPAddress() string  { return "127.0.0.1" }
+func (i ClusterNodeInfo) GetOTPNode() string    { return "" }
+func (i ClusterNodeInfo) GetNodeUUID() string   { return "" }
+func (i ClusterNodeInfo) GetNodeGroup() string  { return "" }
 
 type ClusterInfo struct {
 }
@@ -12,14 +12,30 @@ import (
 )
 
 type Deployer struct {
-	Logger *zap.Logger
+	logger *zap.Logger
+	dryRun bool
 }
 
 var _ deployment.Deployer = (*Deployer)(nil)
 
+type NewDeployerOptions struct {
+	Logger *zap.Logger
+
+	// DryRun causes destructive operations to only log the changes they
+	// would have made to the local installation rather than making them.
+	DryRun bool
+}
+
+func NewDeployer(opts *NewDeployerOptions) (*Deployer, error) {
+	return &Deployer{
+		logger: opts.Logger,
+		dryRun: opts.DryRun,
+	}, nil
+}
+
 func (d *Deployer) controller() *OsxController {
 	return &OsxController{
-		Logger: d.Logger,
+		Logger: d.logger,
 	}
 }
 
@@ -37,6 +53,14 @@ func (d *Deployer) ListClusters(ctx context.Context) ([]deployment.ClusterInfo,
 	return out, nil
 }
 
+func (d *Deployer) WatchClusters(ctx context.Context) (<-chan deployment.ClusterEvent, error) {
+	return deployment.WatchClusterChanges(ctx, &deployment.ClusterWatcherOptions{
+		Logger:       d.logger,
+		ListClusters: d.ListClusters,
+		PollInterval: 10 * time.Second,
+	})
+}
+
 func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (deployment.ClusterInfo, error) {
 	if len(def.NodeGroups) != 1 || def.NodeGroups[0].Count != 1 {
 		return nil, errors.New("local deployment only supports a single node")
@@ -45,6 +69,11 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		return nil, errors.New("columnar is not supported for local deploy")
 	}
 
+	err := deployment.CheckNodeGroupFeatures(def.NodeGroups)
+	if err != nil {
+		return nil, err
+	}
+
 	nodeGrp := def.NodeGroups[0]
 
 	versionInfo, err := versionident.Identify(ctx, nodeGrp.Version)
@@ -52,6 +81,13 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 		return nil, errors.Wrap(err, "failed to identify version")
 	}
 
+	if d.dryRun {
+		// starting replaces any existing installation
+		d.logger.Info("dry-run: would replace the local couchbase server installation",
+			zap.String("version", versionInfo.Version))
+		return nil, nil
+	}
+
 	err = d.controller().Start(ctx, &ServerDef{
 		Version:             versionInfo.Version,
 		BuildNo:             versionInfo.BuildNo,
@@ -65,6 +101,25 @@ func (d *Deployer) NewCluster(ctx context.Context, def *clusterdef.Cluster) (dep
 	return nil, nil
 }
 
+func (d *Deployer) ValidateDefinition(ctx context.Context, def *clusterdef.Cluster) ([]deployment.DefinitionViolation, error) {
+	violations := deployment.ValidateCommonDefinition(def)
+
+	if len(def.NodeGroups) != 1 || def.NodeGroups[0].Count != 1 {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "nodes",
+			Message: "local deployment only supports a single node",
+		})
+	}
+	if def.Columnar {
+		violations = append(violations, deployment.DefinitionViolation{
+			Field:   "columnar",
+			Message: "columnar is not supported for local deploy",
+		})
+	}
+
+	return violations, nil
+}
+
 func (d *Deployer) GetDefinition(ctx context.Context, clusterID string) (*clusterdef.Cluster, error) {
 	return nil, errors.New("localdeploy does not support fetching the cluster definition")
 }
@@ -85,11 +140,24 @@ func (d *Deployer) RemoveNode(ctx context.Context, clusterID string, nodeID stri
 	return errors.New("localdeploy does not support cluster node removal")
 }
 
+func (d *Deployer) SwapNode(ctx context.Context, clusterID string, oldNodeID string, newVersion string) (string, error) {
+	return "", errors.New("localdeploy does not support swapping cluster nodes")
+}
+
 func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
 	if clusterID != "a" {
 		return errors.New("invalid cluster-id")
 	}
 
+	return d.stop(ctx)
+}
+
+func (d *Deployer) stop(ctx context.Context) error {
+	if d.dryRun {
+		d.logger.Info("dry-run: would stop local couchbase server")
+		return nil
+	}
+
 	err := d.controller().Stop(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to stop cluster")
@@ -99,12 +167,7 @@ func (d *Deployer) RemoveCluster(ctx context.Context, clusterID string) error {
 }
 
 func (d *Deployer) RemoveAll(ctx context.Context) error {
-	err := d.controller().Stop(ctx)
-	if err != nil {
-		return errors.Wrap(err, "failed to stop cluster")
-	}
-
-	return nil
+	return d.stop(ctx)
 }
 
 func (d *Deployer) Cleanup(ctx context.Context) error {
@@ -160,14 +223,26 @@ func (d *Deployer) ListCollections(ctx context.Context, clusterID string, bucket
 	return nil, errors.New("localdeploy does not support getting collections")
 }
 
+func (d *Deployer) GetCollectionManifest(ctx context.Context, clusterID string, bucketName string) (*deployment.CollectionManifest, error) {
+	return nil, errors.New("localdeploy does not support getting collection manifests")
+}
+
+func (d *Deployer) WaitForCollectionManifest(ctx context.Context, clusterID string, bucketName string, minUID uint64) error {
+	return errors.New("localdeploy does not support waiting for collection manifests")
+}
+
 func (d *Deployer) CreateScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
 	return errors.New("localdeploy does not support creating scopes")
 }
 
-func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string) error {
+func (d *Deployer) CreateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.CreateCollectionOptions) error {
 	return errors.New("localdeploy does not support creating collections")
 }
 
+func (d *Deployer) UpdateCollection(ctx context.Context, clusterID string, bucketName, scopeName, collectionName string, opts *deployment.UpdateCollectionOptions) error {
+	return errors.New("localdeploy does not support updating collections")
+}
+
 func (d *Deployer) DeleteScope(ctx context.Context, clusterID string, bucketName, scopeName string) error {
 	return errors.New("localdeploy does not support deleting scopes")
 }
@@ -176,6 +251,34 @@ func (d *Deployer) DeleteCollection(ctx context.Context, clusterID string, bucke
 	return errors.New("localdeploy does not support deleting collections")
 }
 
+func (d *Deployer) CreateQueryFunction(ctx context.Context, clusterID string, opts *deployment.CreateQueryFunctionOptions) error {
+	return errors.New("localdeploy does not support creating query functions")
+}
+
+func (d *Deployer) DropQueryFunction(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return errors.New("localdeploy does not support dropping query functions")
+}
+
+func (d *Deployer) ListJsLibraries(ctx context.Context, clusterID string) ([]deployment.JsLibraryInfo, error) {
+	return nil, errors.New("localdeploy does not support listing javascript libraries")
+}
+
+func (d *Deployer) CreateJsLibrary(ctx context.Context, clusterID string, opts *deployment.CreateJsLibraryOptions) error {
+	return errors.New("localdeploy does not support creating javascript libraries")
+}
+
+func (d *Deployer) DeleteJsLibrary(ctx context.Context, clusterID string, bucketName, scopeName, name string) error {
+	return errors.New("localdeploy does not support deleting javascript libraries")
+}
+
+func (d *Deployer) ListIndexes(ctx context.Context, clusterID string) ([]deployment.IndexInfo, error) {
+	return nil, errors.New("localdeploy does not support listing indexes")
+}
+
+func (d *Deployer) AdviseIndexes(ctx context.Context, clusterID string, statement string) (*deployment.IndexAdvice, error) {
+	return nil, errors.New("localdeploy does not support the index advisor")
+}
+
 func (d *Deployer) BlockNodeTraffic(ctx context.Context, clusterID string, nodeID string, blockType deployment.BlockNodeTrafficType) error {
 	return errors.New("localdeploy does not support traffic control")
 }
@@ -204,6 +307,14 @@ func (d *Deployer) UnpauseNode(ctx context.Context, clusterID string, nodeID str
 	return errors.New("localdeploy does not support node pausing")
 }
 
+func (d *Deployer) SetNodeClockSkew(ctx context.Context, clusterID string, nodeID string, skew time.Duration) error {
+	return errors.New("localdeploy does not support clock skew")
+}
+
+func (d *Deployer) GetNodeVersions(ctx context.Context, clusterID string) ([]deployment.NodeVersionInfo, error) {
+	return nil, errors.New("localdeploy does not support node version reporting")
+}
+
 func (d *Deployer) LoadSampleBucket(ctx context.Context, clusterID string, bucketName string) error {
 	return errors.New("localdeploy does not support loading sample buckets")
 }
@@ -0,0 +1,53 @@
+package deployment
+
+import (
+	"context"
+	"sync"
+)
+
+// PartialClusters tracks the clusters which an operation has started to
+// create, so that they can be removed if the operation is interrupted
+// before the clusters are complete.
+type PartialClusters struct {
+	lock       sync.Mutex
+	clusterIDs []string
+}
+
+// ClusterIDs returns the IDs of the clusters which have been tracked.
+func (p *PartialClusters) ClusterIDs() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]string(nil), p.clusterIDs...)
+}
+
+func (p *PartialClusters) add(clusterID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, existingID := range p.clusterIDs {
+		if existingID == clusterID {
+			return
+		}
+	}
+	p.clusterIDs = append(p.clusterIDs, clusterID)
+}
+
+type partialClustersCtxKey struct{}
+
+// WithPartialClusters attaches a tracker of partially created clusters to
+// a context.
+func WithPartialClusters(ctx context.Context, partials *PartialClusters) context.Context {
+	return context.WithValue(ctx, partialClustersCtxKey{}, partials)
+}
+
+// TrackPartialCluster is called by deployers once they have picked the ID
+// of a cluster they are creating, and before creating any of its resources.
+func TrackPartialCluster(ctx context.Context, clusterID string) {
+	partials, _ := ctx.Value(partialClustersCtxKey{}).(*PartialClusters)
+	if partials == nil {
+		return
+	}
+
+	partials.add(clusterID)
+}
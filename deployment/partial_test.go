@@ -0,0 +1,21 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialClusters(t *testing.T) {
+	// tracking without a tracker does nothing
+	TrackPartialCluster(context.Background(), "ignored")
+
+	partials := &PartialClusters{}
+	ctx := WithPartialClusters(context.Background(), partials)
+	TrackPartialCluster(ctx, "a")
+	TrackPartialCluster(ctx, "b")
+	TrackPartialCluster(ctx, "a")
+
+	require.Equal(t, []string{"a", "b"}, partials.ClusterIDs())
+}
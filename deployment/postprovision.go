@@ -0,0 +1,156 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type RunPostProvisionHooksOptions struct {
+	Logger    *zap.Logger
+	ClusterID string
+	Hooks     []clusterdef.PostProvisionHook
+
+	// ScriptOutput receives the output of scripts, and defaults to stderr
+	// so that the output of our own commands is not mixed with it.
+	ScriptOutput io.Writer
+}
+
+func postProvisionHookName(hookIdx int, hook *clusterdef.PostProvisionHook) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	return fmt.Sprintf("post-provision[%d]", hookIdx)
+}
+
+// RunPostProvisionHooks runs the post-provision hooks of a definition
+// against a newly allocated cluster, stopping at the first which fails.
+func RunPostProvisionHooks(ctx context.Context, deployer Deployer, opts *RunPostProvisionHooksOptions) error {
+	if len(opts.Hooks) == 0 {
+		return nil
+	}
+
+	scriptOutput := opts.ScriptOutput
+	if scriptOutput == nil {
+		scriptOutput = os.Stderr
+	}
+
+	// dedicated credentials are only needed for the hooks which connect
+	// to the cluster themselves
+	var connectInfo *ConnectInfo
+	getConnectInfo := func() (*ConnectInfo, error) {
+		if connectInfo != nil {
+			return connectInfo, nil
+		}
+
+		info, err := GetConnectInfoWithCredentials(ctx, deployer, opts.ClusterID,
+			&ConnectCredentialsOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get connect info")
+		}
+
+		connectInfo = info
+		return connectInfo, nil
+	}
+
+	for hookIdx := range opts.Hooks {
+		hook := &opts.Hooks[hookIdx]
+		hookName := postProvisionHookName(hookIdx, hook)
+
+		opts.Logger.Info("running post-provision hook", zap.String("hook", hookName))
+
+		var err error
+		if hook.Query != "" {
+			_, err = deployer.ExecuteQuery(ctx, opts.ClusterID, hook.Query)
+		} else if hook.Rest != nil {
+			var info *ConnectInfo
+			info, err = getConnectInfo()
+			if err == nil {
+				err = runPostProvisionRest(ctx, info, hook.Rest)
+			}
+		} else if hook.Script != "" {
+			var info *ConnectInfo
+			info, err = getConnectInfo()
+			if err == nil {
+				err = runPostProvisionScript(ctx, opts.ClusterID, info, hook.Script, scriptOutput)
+			}
+		} else {
+			err = errors.New("hook has nothing to run")
+		}
+		if err != nil {
+			return errors.Wrapf(err, "post-provision hook `%s` failed", hookName)
+		}
+	}
+
+	return nil
+}
+
+func runPostProvisionRest(ctx context.Context, connectInfo *ConnectInfo, rest *clusterdef.PostProvisionRest) error {
+	if connectInfo.Mgmt == "" {
+		return errors.New("cluster has no management endpoint to make requests to")
+	}
+
+	method := rest.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	contentType := rest.ContentType
+	if contentType == "" && rest.Body != "" {
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	reqUrl := strings.TrimSuffix(connectInfo.Mgmt, "/") + "/" + strings.TrimPrefix(rest.Path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, strings.NewReader(rest.Body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	req.SetBasicAuth(connectInfo.Username, connectInfo.Password)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to make request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+func runPostProvisionScript(ctx context.Context, clusterID string, connectInfo *ConnectInfo, script string, output io.Writer) error {
+	scriptCmd := exec.CommandContext(ctx, "sh", "-c", script)
+	scriptCmd.Stdout = output
+	scriptCmd.Stderr = output
+	scriptCmd.Env = append(os.Environ(),
+		"CBDC_CLUSTER_ID="+clusterID,
+		"CBDC_CONNSTR="+connectInfo.ConnStr,
+		"CBDC_CONNSTR_TLS="+connectInfo.ConnStrTls,
+		"CBDC_MGMT="+connectInfo.Mgmt,
+		"CBDC_MGMT_TLS="+connectInfo.MgmtTls,
+		"CBDC_USERNAME="+connectInfo.Username,
+		"CBDC_PASSWORD="+connectInfo.Password)
+
+	err := scriptCmd.Run()
+	if err != nil {
+		return errors.Wrap(err, "script failed")
+	}
+
+	return nil
+}
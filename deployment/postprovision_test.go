@@ -0,0 +1,112 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type postProvisionDeployer struct {
+	Deployer
+	mgmt    string
+	queries []string
+	users   []string
+}
+
+func (d *postProvisionDeployer) GetConnectInfo(ctx context.Context, clusterID string) (*ConnectInfo, error) {
+	return &ConnectInfo{
+		ConnStr: "couchbase://127.0.0.1",
+		Mgmt:    d.mgmt,
+	}, nil
+}
+
+func (d *postProvisionDeployer) CreateUser(ctx context.Context, clusterID string, opts *CreateUserOptions) error {
+	d.users = append(d.users, opts.Username)
+	return nil
+}
+
+func (d *postProvisionDeployer) ExecuteQuery(ctx context.Context, clusterID string, query string) (string, error) {
+	d.queries = append(d.queries, query)
+	return "[]", nil
+}
+
+func TestRunPostProvisionHooks(t *testing.T) {
+	var restRequests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		restRequests = append(restRequests, r.Method+" "+r.URL.Path+" "+string(body)+" "+password)
+
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &postProvisionDeployer{mgmt: server.URL}
+	var scriptOutput bytes.Buffer
+
+	err := RunPostProvisionHooks(context.Background(), deployer, &RunPostProvisionHooksOptions{
+		Logger:    zap.NewNop(),
+		ClusterID: "abc",
+		Hooks: []clusterdef.PostProvisionHook{
+			{Query: "CREATE PRIMARY INDEX ON default"},
+			{Rest: &clusterdef.PostProvisionRest{Path: "/settings/indexes", Body: "storageMode=plasma"}},
+			{Script: "echo $CBDC_CLUSTER_ID $CBDC_CONNSTR"},
+		},
+		ScriptOutput: &scriptOutput,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"CREATE PRIMARY INDEX ON default"}, deployer.queries)
+	// credentials are only created once for all of the hooks
+	assert.Len(t, deployer.users, 1)
+	if assert.Len(t, restRequests, 1) {
+		assert.True(t, strings.HasPrefix(restRequests[0], "POST /settings/indexes storageMode=plasma "))
+		// the request was authenticated with a password
+		assert.False(t, strings.HasSuffix(restRequests[0], " "))
+	}
+	assert.Equal(t, "abc couchbase://127.0.0.1\n", scriptOutput.String())
+
+	err = RunPostProvisionHooks(context.Background(), deployer, &RunPostProvisionHooksOptions{
+		Logger:    zap.NewNop(),
+		ClusterID: "abc",
+		Hooks: []clusterdef.PostProvisionHook{
+			{Name: "broken", Rest: &clusterdef.PostProvisionRest{Method: "GET", Path: "/fail"}},
+			{Query: "SELECT 1"},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "`broken`")
+	assert.Contains(t, err.Error(), "nope")
+	// hooks after a failure are not run
+	assert.Len(t, deployer.queries, 1)
+}
+
+func TestValidatePostProvisionHooks(t *testing.T) {
+	violations := ValidatePostProvisionHooks([]clusterdef.PostProvisionHook{
+		{Query: "SELECT 1"},
+		{},
+		{Query: "SELECT 1", Script: "true"},
+		{Rest: &clusterdef.PostProvisionRest{Path: "settings"}},
+	})
+
+	var fields []string
+	for _, violation := range violations {
+		fields = append(fields, violation.Field)
+	}
+	assert.Equal(t, []string{
+		"post-provision[1]",
+		"post-provision[2]",
+		"post-provision[3].rest.path",
+	}, fields)
+}
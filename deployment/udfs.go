@@ -0,0 +1,132 @@
+package deployment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CreateQueryFunctionOptions describes a query user-defined function.  The
+// function is global unless a bucket and scope are specified, and is either
+// an inline SQL++ expression or a function from a javascript library.
+type CreateQueryFunctionOptions struct {
+	BucketName string
+	ScopeName  string
+	Name       string
+	Params     []string
+
+	Expression string
+
+	LibraryName     string
+	LibraryFunction string
+
+	// Replace replaces any existing function with the same name.
+	Replace bool
+}
+
+// IsJavascript indicates whether the function is implemented by a
+// javascript library rather than an inline expression.
+func (o *CreateQueryFunctionOptions) IsJavascript() bool {
+	return o.LibraryName != ""
+}
+
+// Statement builds the SQL++ statement which creates the function.
+func (o *CreateQueryFunctionOptions) Statement() (string, error) {
+	if o.Name == "" {
+		return "", errors.New("a function name must be specified")
+	}
+
+	var params []string
+	for _, param := range o.Params {
+		params = append(params, quoteQueryIdentifier(param))
+	}
+
+	var body string
+	if o.IsJavascript() {
+		if o.Expression != "" {
+			return "", errors.New("a function cannot have both an expression and a library")
+		}
+
+		libraryFunction := o.LibraryFunction
+		if libraryFunction == "" {
+			libraryFunction = o.Name
+		}
+
+		library := o.LibraryName
+		if o.BucketName != "" {
+			library = fmt.Sprintf("%s/%s/%s", o.BucketName, o.ScopeName, o.LibraryName)
+		}
+
+		body = fmt.Sprintf("LANGUAGE JAVASCRIPT AS %s AT %s",
+			quoteQueryString(libraryFunction), quoteQueryString(library))
+	} else {
+		if o.Expression == "" {
+			return "", errors.New("a function must have either an expression or a library")
+		}
+
+		body = fmt.Sprintf("{ %s }", o.Expression)
+	}
+
+	verb := "CREATE"
+	if o.Replace {
+		verb = "CREATE OR REPLACE"
+	}
+
+	functionName, err := queryFunctionName(o.BucketName, o.ScopeName, o.Name)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s FUNCTION %s(%s) %s",
+		verb, functionName, strings.Join(params, ", "), body), nil
+}
+
+// DropQueryFunctionStatement builds the SQL++ statement which removes a
+// function, which is global unless a bucket and scope are specified.
+func DropQueryFunctionStatement(bucketName, scopeName, name string) (string, error) {
+	functionName, err := queryFunctionName(bucketName, scopeName, name)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("DROP FUNCTION %s", functionName), nil
+}
+
+func queryFunctionName(bucketName, scopeName, name string) (string, error) {
+	if bucketName == "" && scopeName == "" {
+		return quoteQueryIdentifier(name), nil
+	}
+	if bucketName == "" || scopeName == "" {
+		return "", errors.New("scoped functions require both a bucket and a scope")
+	}
+
+	return fmt.Sprintf("default:%s.%s.%s",
+		quoteQueryIdentifier(bucketName),
+		quoteQueryIdentifier(scopeName),
+		quoteQueryIdentifier(name)), nil
+}
+
+func quoteQueryIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteQueryString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// JsLibraryInfo describes a javascript library used by query functions,
+// which is global unless it has a bucket and scope.
+type JsLibraryInfo struct {
+	BucketName string
+	ScopeName  string
+	Name       string
+	Code       string
+}
+
+type CreateJsLibraryOptions struct {
+	BucketName string
+	ScopeName  string
+	Name       string
+	Code       string
+}
@@ -0,0 +1,54 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFunctionStatements(t *testing.T) {
+	tests := []struct {
+		opts     CreateQueryFunctionOptions
+		expected string
+	}{
+		{
+			CreateQueryFunctionOptions{
+				Name:       "celsius",
+				Params:     []string{"degrees"},
+				Expression: "(degrees - 32) * 5/9",
+			},
+			"CREATE FUNCTION `celsius`(`degrees`) { (degrees - 32) * 5/9 }",
+		},
+		{
+			CreateQueryFunctionOptions{
+				BucketName:  "travel-sample",
+				ScopeName:   "inventory",
+				Name:        "add",
+				Params:      []string{"a", "b"},
+				LibraryName: "math",
+				Replace:     true,
+			},
+			"CREATE OR REPLACE FUNCTION default:`travel-sample`.`inventory`.`add`(`a`, `b`) " +
+				"LANGUAGE JAVASCRIPT AS \"add\" AT \"travel-sample/inventory/math\"",
+		},
+	}
+
+	for _, test := range tests {
+		statement, err := test.opts.Statement()
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, statement)
+	}
+
+	// a function needs a body
+	_, err := (&CreateQueryFunctionOptions{Name: "empty"}).Statement()
+	assert.Error(t, err)
+
+	statement, err := DropQueryFunctionStatement("", "", "celsius")
+	require.NoError(t, err)
+	assert.Equal(t, "DROP FUNCTION `celsius`", statement)
+
+	// a bucket cannot be specified without a scope
+	_, err = DropQueryFunctionStatement("travel-sample", "", "add")
+	assert.Error(t, err)
+}
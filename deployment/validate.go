@@ -0,0 +1,221 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+	"golang.org/x/exp/slices"
+)
+
+type DefinitionViolation struct {
+	Field   string
+	Message string
+}
+
+func NodeGroupField(nodeGrpIdx int, field string) string {
+	if field == "" {
+		return fmt.Sprintf("nodes[%d]", nodeGrpIdx)
+	}
+	return fmt.Sprintf("nodes[%d].%s", nodeGrpIdx, field)
+}
+
+var knownServices = []clusterdef.Service{
+	clusterdef.KvService,
+	clusterdef.QueryService,
+	clusterdef.IndexService,
+	clusterdef.SearchService,
+	clusterdef.AnalyticsService,
+	clusterdef.EventingService,
+	clusterdef.BackupService,
+}
+
+// nodeGroupNameRegexp matches the names which are valid as both container
+// and pod label values.
+var nodeGroupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]{0,61}[a-zA-Z0-9])?$`)
+
+// ValidateCommonDefinition performs the checks which apply to a cluster
+// definition regardless of which deployer will eventually deploy it.
+func ValidateCommonDefinition(def *clusterdef.Cluster) []DefinitionViolation {
+	var violations []DefinitionViolation
+
+	if len(def.NodeGroups) == 0 {
+		violations = append(violations, DefinitionViolation{
+			Field:   "nodes",
+			Message: "at least one node group must be specified",
+		})
+		return violations
+	}
+
+	hasKvService := false
+	for nodeGrpIdx, nodeGrp := range def.NodeGroups {
+		if nodeGrp.Count < 1 {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "count"),
+				Message: "node groups must contain at least one node",
+			})
+		}
+
+		if nodeGrp.Name != "" && !nodeGroupNameRegexp.MatchString(nodeGrp.Name) {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "name"),
+				Message: "node group names must be at most 63 letters, digits, `-`, `_` or `.`, starting and ending with a letter or digit",
+			})
+		}
+
+		if def.Columnar && len(nodeGrp.Services) > 0 {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "services"),
+				Message: "columnar clusters cannot specify services",
+			})
+		}
+
+		// an empty service list implies the default services, which include kv
+		if len(nodeGrp.Services) == 0 || slices.Contains(nodeGrp.Services, clusterdef.KvService) {
+			hasKvService = true
+		}
+
+		for _, service := range nodeGrp.Services {
+			if !slices.Contains(knownServices, service) {
+				violations = append(violations, DefinitionViolation{
+					Field:   NodeGroupField(nodeGrpIdx, "services"),
+					Message: fmt.Sprintf("unknown service `%s`", service),
+				})
+			}
+		}
+	}
+
+	if !def.Columnar && !hasKvService {
+		violations = append(violations, DefinitionViolation{
+			Field:   "nodes",
+			Message: "at least one node must run the kv service",
+		})
+	}
+
+	violations = append(violations, ValidateNodeGroupVersions(def.NodeGroups)...)
+	violations = append(violations, ValidatePostProvisionHooks(def.PostProvision)...)
+
+	// columnar versions are numbered independently of Couchbase Server
+	if !def.Columnar {
+		violations = append(violations, ValidateNodeGroupFeatures(def.NodeGroups)...)
+	}
+
+	return violations
+}
+
+// ValidateNodeGroupVersions checks that the versions of the node groups can
+// be combined into a single cluster.  Mixed-version clusters are permitted,
+// as they occur midway through an upgrade, but only across versions which
+// can be upgraded between directly.
+func ValidateNodeGroupVersions(nodeGrps []*clusterdef.NodeGroup) []DefinitionViolation {
+	var violations []DefinitionViolation
+
+	var firstVersion *versionident.Version
+	var firstNodeGrpIdx int
+	minMajor, maxMajor := -1, -1
+	for nodeGrpIdx, nodeGrp := range nodeGrps {
+		// node groups with a custom image have no version we can check
+		if nodeGrp.Version == "" || nodeGrp.Docker.Image != "" {
+			continue
+		}
+
+		version, err := versionident.Identify(context.Background(), nodeGrp.Version)
+		if err != nil {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "version"),
+				Message: fmt.Sprintf("invalid version `%s`: %s", nodeGrp.Version, err),
+			})
+			continue
+		}
+
+		major, err := strconv.Atoi(strings.Split(version.Version, ".")[0])
+		if err != nil {
+			violations = append(violations, DefinitionViolation{
+				Field:   NodeGroupField(nodeGrpIdx, "version"),
+				Message: fmt.Sprintf("invalid major version in `%s`", nodeGrp.Version),
+			})
+			continue
+		}
+
+		if firstVersion == nil {
+			firstVersion = version
+			firstNodeGrpIdx = nodeGrpIdx
+			minMajor, maxMajor = major, major
+			continue
+		}
+
+		if version.CommunityEdition != firstVersion.CommunityEdition {
+			violations = append(violations, DefinitionViolation{
+				Field: NodeGroupField(nodeGrpIdx, "version"),
+				Message: fmt.Sprintf("cannot mix community and enterprise editions (`%s` and `%s`)",
+					nodeGrps[firstNodeGrpIdx].Version, nodeGrp.Version),
+			})
+		}
+		if version.Serverless != firstVersion.Serverless {
+			violations = append(violations, DefinitionViolation{
+				Field: NodeGroupField(nodeGrpIdx, "version"),
+				Message: fmt.Sprintf("cannot mix serverless and non-serverless versions (`%s` and `%s`)",
+					nodeGrps[firstNodeGrpIdx].Version, nodeGrp.Version),
+			})
+		}
+
+		if major < minMajor {
+			minMajor = major
+		}
+		if major > maxMajor {
+			maxMajor = major
+		}
+	}
+
+	if maxMajor-minMajor > 1 {
+		violations = append(violations, DefinitionViolation{
+			Field: "nodes",
+			Message: fmt.Sprintf("mixed-version clusters can only span adjacent major versions (found %d.x to %d.x)",
+				minMajor, maxMajor),
+		})
+	}
+
+	return violations
+}
+
+// ValidatePostProvisionHooks checks that each post-provision hook specifies
+// exactly one thing to run.
+func ValidatePostProvisionHooks(hooks []clusterdef.PostProvisionHook) []DefinitionViolation {
+	var violations []DefinitionViolation
+
+	for hookIdx, hook := range hooks {
+		field := fmt.Sprintf("post-provision[%d]", hookIdx)
+
+		numActions := 0
+		if hook.Query != "" {
+			numActions++
+		}
+		if hook.Rest != nil {
+			numActions++
+		}
+		if hook.Script != "" {
+			numActions++
+		}
+
+		if numActions != 1 {
+			violations = append(violations, DefinitionViolation{
+				Field:   field,
+				Message: "exactly one of query, rest or script must be specified",
+			})
+			continue
+		}
+
+		if hook.Rest != nil && !strings.HasPrefix(hook.Rest.Path, "/") {
+			violations = append(violations, DefinitionViolation{
+				Field:   field + ".rest.path",
+				Message: "rest paths must start with /",
+			})
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,89 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/clusterdef"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNodeGroupVersions(t *testing.T) {
+	checkVersions := func(versions []string, numViolations int) {
+		var nodeGrps []*clusterdef.NodeGroup
+		for _, version := range versions {
+			nodeGrps = append(nodeGrps, &clusterdef.NodeGroup{Count: 1, Version: version})
+		}
+
+		violations := ValidateNodeGroupVersions(nodeGrps)
+		assert.Len(t, violations, numViolations, "versions: %v", versions)
+	}
+
+	checkVersions([]string{"7.2.0"}, 0)
+	checkVersions([]string{"7.1.4", "7.2.0-5325"}, 0)
+	checkVersions([]string{"6.6.5", "7.2.0"}, 0)
+	checkVersions([]string{"5.5.0", "7.2.0"}, 1)
+	checkVersions([]string{"7.2.0", "community-7.2.0"}, 1)
+	checkVersions([]string{"7.2.0", "bogus-7.2.0"}, 1)
+	checkVersions([]string{"7.2.0", ""}, 0)
+}
+
+func TestValidateNodeGroupNames(t *testing.T) {
+	checkName := func(name string, numViolations int) {
+		violations := ValidateCommonDefinition(&clusterdef.Cluster{
+			NodeGroups: []*clusterdef.NodeGroup{
+				{Name: name, Count: 1},
+			},
+		})
+		assert.Len(t, violations, numViolations, "name: %s", name)
+	}
+
+	checkName("", 0)
+	checkName("analytics", 0)
+	checkName("data-1.east_a", 0)
+	checkName("-analytics", 1)
+	checkName("group:analytics", 1)
+	checkName("two words", 1)
+}
+
+func TestCheckFeature(t *testing.T) {
+	assert.NoError(t, CheckFeature("7.1.0", FeatureMagma))
+	assert.NoError(t, CheckFeature("7.6.2-3721", FeatureMagma))
+	assert.ErrorIs(t, CheckFeature("7.0.5", FeatureMagma), ErrFeatureUnsupported)
+	assert.ErrorIs(t, CheckFeature("community-7.2.0", FeatureMagma), ErrFeatureUnsupported)
+	assert.ErrorIs(t, CheckFeature("6.6.5", FeatureCollections), ErrFeatureUnsupported)
+	assert.NoError(t, CheckFeature("7.6.2", FeatureMemcachedBuckets))
+	assert.ErrorContains(t, CheckFeature("8.0.0", FeatureMemcachedBuckets),
+		"memcached buckets were removed in Couchbase Server 8.0.0, but version `8.0.0` is in use, use an ephemeral bucket or use an older version")
+	assert.ErrorIs(t, CheckFeature("4.6.5", FeatureEphemeralBuckets), ErrFeatureUnsupported)
+	assert.ErrorIs(t, CheckFeature("6.6.5", FeatureTransactions), ErrFeatureUnsupported)
+
+	err := CheckClusterFeature([]string{"7.2.0", "6.6.5", ""}, FeatureCollections)
+	assert.ErrorContains(t, err, "scopes and collections requires Couchbase Server 7.0.0 or later")
+
+	violations := ValidateNodeGroupFeatures([]*clusterdef.NodeGroup{
+		{Count: 1, Version: "6.6.5", Services: []clusterdef.Service{clusterdef.KvService, clusterdef.BackupService}},
+		{Count: 1, Version: "community-7.2.0", Services: []clusterdef.Service{clusterdef.EventingService}},
+	})
+	assert.Len(t, violations, 2)
+
+	violations = ValidateNodeGroupFeatures([]*clusterdef.NodeGroup{
+		{Count: 1, Version: "community-7.2.0"},
+		{Count: 1, Version: "community-7.2.0", Services: []clusterdef.Service{clusterdef.QueryService, clusterdef.KvService, clusterdef.IndexService}},
+		{Count: 1, Version: "community-7.2.0", Services: []clusterdef.Service{clusterdef.QueryService}},
+		{Count: 1, Version: "community-7.2.0", ServerGroup: "group_2"},
+		{Count: 1, Version: "7.2.0", ServerGroup: "group_2", Services: []clusterdef.Service{clusterdef.QueryService}},
+	})
+	if assert.Len(t, violations, 2) {
+		assert.Equal(t, "nodes[2].services", violations[0].Field)
+		assert.Equal(t, "nodes[3].server-group", violations[1].Field)
+	}
+
+	err = CheckNodeGroupFeatures([]*clusterdef.NodeGroup{
+		{Count: 1, Version: "community-7.2.0", Services: []clusterdef.Service{clusterdef.QueryService}},
+	})
+	assert.ErrorIs(t, err, ErrFeatureUnsupported)
+	assert.ErrorContains(t, err, "nodes[0].services")
+	assert.NoError(t, CheckNodeGroupFeatures([]*clusterdef.NodeGroup{
+		{Count: 1, Version: "7.2.0", Services: []clusterdef.Service{clusterdef.QueryService}},
+	}))
+}
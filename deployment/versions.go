@@ -0,0 +1,95 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+)
+
+// NodeVersionInfo describes the server version a node is actually running,
+// alongside the version it was deployed with.  Versions take the form used
+// by cluster definitions, such as `7.6.2-3721` or `community-7.6.2-3721`.
+type NodeVersionInfo struct {
+	NodeID string
+	Name   string
+
+	// ExpectedVersion is the version the node was deployed with, which is
+	// empty when it was not recorded.
+	ExpectedVersion string
+
+	// RunningVersion is the version the node reports it is running.
+	RunningVersion string
+}
+
+// Drift describes how the running version of a node differs from the version
+// it was deployed with, or returns an empty string if it does not.
+func (i *NodeVersionInfo) Drift() string {
+	return CheckVersionDrift(i.ExpectedVersion, i.RunningVersion)
+}
+
+// CheckVersionDrift describes how a running version differs from an expected
+// version, or returns an empty string if it does not.  Expected versions may
+// omit the patch version or build number, in which case any are accepted.
+func CheckVersionDrift(expected string, running string) string {
+	if expected == "" {
+		return ""
+	}
+
+	expectedVersion, err := versionident.Identify(context.Background(), expected)
+	if err != nil {
+		if expected != running {
+			return fmt.Sprintf("running %s, deployed with %s", running, expected)
+		}
+		return ""
+	}
+
+	runningVersion, err := versionident.Identify(context.Background(), running)
+	if err != nil {
+		return fmt.Sprintf("running unrecognized version %s, deployed with %s", running, expected)
+	}
+
+	if expectedVersion.CommunityEdition != runningVersion.CommunityEdition {
+		return fmt.Sprintf("running %s edition, deployed with %s edition",
+			editionName(runningVersion.CommunityEdition),
+			editionName(expectedVersion.CommunityEdition))
+	}
+
+	if !isVersionPrefix(expectedVersion.Version, runningVersion.Version) {
+		return fmt.Sprintf("running %s, deployed with %s",
+			runningVersion.Version, expectedVersion.Version)
+	}
+
+	if expectedVersion.BuildNo != 0 && expectedVersion.BuildNo != runningVersion.BuildNo {
+		return fmt.Sprintf("running build %d, deployed with build %d",
+			runningVersion.BuildNo, expectedVersion.BuildNo)
+	}
+
+	return ""
+}
+
+func editionName(communityEdition bool) string {
+	if communityEdition {
+		return "community"
+	}
+	return "enterprise"
+}
+
+// isVersionPrefix checks that the components of one version number, such as
+// `7.6`, are a prefix of the components of another, such as `7.6.2`.
+func isVersionPrefix(prefix string, version string) bool {
+	prefixParts := strings.Split(prefix, ".")
+	versionParts := strings.Split(version, ".")
+	if len(prefixParts) > len(versionParts) {
+		return false
+	}
+
+	for partIdx, prefixPart := range prefixParts {
+		if versionParts[partIdx] != prefixPart {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,29 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckVersionDrift(t *testing.T) {
+	assert.Empty(t, CheckVersionDrift("", "7.6.2-3721"))
+	assert.Empty(t, CheckVersionDrift("7.6.2", "7.6.2-3721"))
+	assert.Empty(t, CheckVersionDrift("7.6", "7.6.2-3721"))
+	assert.Empty(t, CheckVersionDrift("7.6.2-3721", "7.6.2-3721"))
+	assert.Empty(t, CheckVersionDrift("community-7.6.2", "community-7.6.2-3721"))
+
+	assert.Equal(t, "running 7.6.3, deployed with 7.6.2",
+		CheckVersionDrift("7.6.2", "7.6.3-4200"))
+	assert.Equal(t, "running 7.6.2, deployed with 7.6.20",
+		CheckVersionDrift("7.6.20", "7.6.2-3721"))
+	assert.Equal(t, "running build 3800, deployed with build 3721",
+		CheckVersionDrift("7.6.2-3721", "7.6.2-3800"))
+	assert.Equal(t, "running community edition, deployed with enterprise edition",
+		CheckVersionDrift("7.6.2", "community-7.6.2-3721"))
+	assert.Equal(t, "running unrecognized version bogus, deployed with 7.6.2",
+		CheckVersionDrift("7.6.2", "bogus"))
+
+	info := &NodeVersionInfo{ExpectedVersion: "7.2.0", RunningVersion: "7.2.4-7070"}
+	assert.Equal(t, "running 7.2.4, deployed with 7.2.0", info.Drift())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/couchbaselabs/cbdinocluster/utils/resourcetags"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -16,6 +17,15 @@ type PrivateEndpointsController struct {
 	Logger      *zap.Logger
 	Region      string
 	Credentials aws.Credentials
+
+	// GracePeriod is how long tagged resources are kept after they expire
+	// before cleanup removes them.
+	GracePeriod time.Duration
+
+	// ClusterExists is used to check that the cluster an expired resource
+	// was created for has been removed before cleanup removes the resource.
+	// Expired resources are kept if this is not specified.
+	ClusterExists resourcetags.ClusterExistsFunc
 }
 
 func (c *PrivateEndpointsController) ec2Client() *ec2.Client {
@@ -31,6 +41,8 @@ type CreateVPCEndpointOptions struct {
 	ClusterID   string
 	ServiceName string
 	InstanceID  string
+	Owner       string
+	Expiry      time.Time
 }
 
 type CreateVPCEndpointResult struct {
@@ -60,6 +72,24 @@ func (c *PrivateEndpointsController) CreateVPCEndpoint(ctx context.Context, opts
 	vpcID := *instance.VpcId
 	subnetID := *instance.SubnetId
 
+	tags := []types.Tag{
+		{
+			Key:   aws.String("Name"),
+			Value: aws.String("cbdc2_" + opts.ClusterID),
+		},
+	}
+	resTags := (&resourcetags.Metadata{
+		ClusterID: opts.ClusterID,
+		Owner:     opts.Owner,
+		Expiry:    opts.Expiry,
+	}).Tags()
+	for tagName, tagValue := range resTags {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(tagName),
+			Value: aws.String(tagValue),
+		})
+	}
+
 	vpcEpResp, err := ec2Client.CreateVpcEndpoint(ctx, &ec2.CreateVpcEndpointInput{
 		ServiceName:     aws.String(opts.ServiceName),
 		VpcId:           aws.String(vpcID),
@@ -68,16 +98,7 @@ func (c *PrivateEndpointsController) CreateVPCEndpoint(ctx context.Context, opts
 		TagSpecifications: []types.TagSpecification{
 			{
 				ResourceType: types.ResourceTypeVpcEndpoint,
-				Tags: []types.Tag{
-					{
-						Key:   aws.String("Name"),
-						Value: aws.String("cbdc2_" + opts.ClusterID),
-					},
-					{
-						Key:   aws.String("Cbdc2ClusterId"),
-						Value: aws.String(opts.ClusterID),
-					},
-				},
+				Tags:         tags,
 			},
 		},
 	})
@@ -166,9 +187,10 @@ func (c *PrivateEndpointsController) EnableVPCEndpointPrivateDNS(ctx context.Con
 }
 
 // cleanupVpcEndpoints lists all the vpc endpoints in the account and then removes any
-// that are tagged with Cbdc2ClusterId and which are rejected or failed.
+// that are tagged with Cbdc2ClusterId and which are rejected, failed or abandoned.
 func (c *PrivateEndpointsController) cleanupVpcEndpoints(ctx context.Context) error {
 	ec2Client := c.ec2Client()
+	curTime := time.Now()
 
 	endpoints, err := ec2Client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{})
 	if err != nil {
@@ -178,21 +200,34 @@ func (c *PrivateEndpointsController) cleanupVpcEndpoints(ctx context.Context) er
 	var endpointIdsToRemove []string
 
 	for _, endpoint := range endpoints.VpcEndpoints {
-		hasTag := func(tagName string) bool {
-			for _, tag := range endpoint.Tags {
-				if *tag.Key == tagName {
-					return true
-				}
-			}
-			return false
+		tags := make(map[string]string)
+		for _, tag := range endpoint.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 		}
 
-		if !hasTag("Cbdc2ClusterId") {
+		meta, err := resourcetags.Parse(tags)
+		if err != nil {
+			c.Logger.Warn("failed to parse vpc endpoint tags",
+				zap.String("endpoint-id", *endpoint.VpcEndpointId),
+				zap.Error(err))
+			continue
+		}
+		if meta == nil {
+			// this is not a cbdc managed endpoint
 			continue
 		}
 
 		if endpoint.State != "rejected" && endpoint.State != "failed" {
-			continue
+			isAbandoned, err := meta.IsAbandoned(ctx, curTime, c.GracePeriod, c.ClusterExists)
+			if err != nil {
+				c.Logger.Warn("failed to check if vpc endpoint is abandoned",
+					zap.String("endpoint-id", *endpoint.VpcEndpointId),
+					zap.Error(err))
+				continue
+			}
+			if !isAbandoned {
+				continue
+			}
 		}
 
 		endpointIdsToRemove = append(endpointIdsToRemove, *endpoint.VpcEndpointId)
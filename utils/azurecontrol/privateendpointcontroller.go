@@ -12,6 +12,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"github.com/couchbaselabs/cbdinocluster/utils/resourcetags"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -24,12 +25,41 @@ type PrivateEndpointsController struct {
 	Creds  azcore.TokenCredential
 	SubID  string
 	RgName string
+
+	// GracePeriod is how long tagged resources are kept after they expire
+	// before cleanup removes them.
+	GracePeriod time.Duration
+
+	// ClusterExists is used to check that the cluster an expired resource
+	// was created for has been removed before cleanup removes the resource.
+	// Expired resources are kept if this is not specified.
+	ClusterExists resourcetags.ClusterExistsFunc
+}
+
+func azureTags(tags map[string]string) map[string]*string {
+	out := make(map[string]*string)
+	for tagName, tagValue := range tags {
+		out[tagName] = to.Ptr(tagValue)
+	}
+	return out
+}
+
+func parseAzureTags(tags map[string]*string) (*resourcetags.Metadata, error) {
+	in := make(map[string]string)
+	for tagName, tagValue := range tags {
+		if tagValue != nil {
+			in[tagName] = *tagValue
+		}
+	}
+	return resourcetags.Parse(in)
 }
 
 type CreateVPCEndpointOptions struct {
 	ClusterID    string
 	ServiceID    string
 	VmResourceID string
+	Owner        string
+	Expiry       time.Time
 }
 
 type CreateVPCEndpointResult struct {
@@ -112,9 +142,11 @@ func (c *PrivateEndpointsController) CreateVPCEndpoint(ctx context.Context, opts
 
 	createPoller, err := peClient.BeginCreateOrUpdate(ctx, rgName, peName, armnetwork.PrivateEndpoint{
 		Location: to.Ptr(c.Region),
-		Tags: map[string]*string{
-			"Cbdc2ClusterId": to.Ptr(opts.ClusterID),
-		},
+		Tags: azureTags((&resourcetags.Metadata{
+			ClusterID: opts.ClusterID,
+			Owner:     opts.Owner,
+			Expiry:    opts.Expiry,
+		}).Tags()),
 		Properties: &armnetwork.PrivateEndpointProperties{
 			ManualPrivateLinkServiceConnections: []*armnetwork.PrivateLinkServiceConnection{
 				{
@@ -150,6 +182,8 @@ type EnableVPCEndpointPrivateDNSOptions struct {
 	ClusterID    string
 	PeResourceID string
 	DnsName      string
+	Owner        string
+	Expiry       time.Time
 }
 
 func (c *PrivateEndpointsController) EnableVPCEndpointPrivateDNS(ctx context.Context, opts *EnableVPCEndpointPrivateDNSOptions) error {
@@ -213,12 +247,16 @@ func (c *PrivateEndpointsController) EnableVPCEndpointPrivateDNS(ctx context.Con
 
 	c.Logger.Debug("creating private dns zone")
 
+	pdnsTags := (&resourcetags.Metadata{
+		ClusterID: opts.ClusterID,
+		Owner:     opts.Owner,
+		Expiry:    opts.Expiry,
+	}).Tags()
+	pdnsTags["AssociatedPrivateEndpoint"] = peName
+
 	pdnsPoller, err := pdnsClient.BeginCreateOrUpdate(ctx, rgName, opts.DnsName, armprivatedns.PrivateZone{
-		Location: to.Ptr("global"),
-		Tags: map[string]*string{
-			"Cbdc2ClusterId":            to.Ptr(opts.ClusterID),
-			"AssociatedPrivateEndpoint": to.Ptr(peName),
-		},
+		Location:   to.Ptr("global"),
+		Tags:       azureTags(pdnsTags),
 		Properties: &armprivatedns.PrivateZoneProperties{},
 	}, nil)
 	if err != nil {
@@ -295,6 +333,7 @@ func (c *PrivateEndpointsController) cleanupPrivateEndpoints(ctx context.Context
 	}
 
 	var endpointNamesToRemove []string
+	curTime := time.Now()
 
 	pePager := peClient.NewListPager(c.RgName, nil)
 	for pePager.More() {
@@ -306,8 +345,14 @@ func (c *PrivateEndpointsController) cleanupPrivateEndpoints(ctx context.Context
 		for _, peLink := range page.Value {
 			c.Logger.Info("pelink", zap.Any("link", peLink))
 
-			clusterIdPtr := peLink.Tags["Cbdc2ClusterId"]
-			if clusterIdPtr == nil || *clusterIdPtr == "" {
+			meta, err := parseAzureTags(peLink.Tags)
+			if err != nil {
+				c.Logger.Warn("failed to parse private endpoint tags",
+					zap.String("pe-name", *peLink.Name),
+					zap.Error(err))
+				continue
+			}
+			if meta == nil {
 				// this is not a cbdc managed link
 				continue
 			}
@@ -316,8 +361,17 @@ func (c *PrivateEndpointsController) cleanupPrivateEndpoints(ctx context.Context
 			peConnState := peConnection.Properties.PrivateLinkServiceConnectionState
 
 			if *peConnState.Status != "Rejected" && *peConnState.Status != "Disconnected" {
-				// this connection is still active
-				continue
+				isAbandoned, err := meta.IsAbandoned(ctx, curTime, c.GracePeriod, c.ClusterExists)
+				if err != nil {
+					c.Logger.Warn("failed to check if private endpoint is abandoned",
+						zap.String("pe-name", *peLink.Name),
+						zap.Error(err))
+					continue
+				}
+				if !isAbandoned {
+					// this connection is still active
+					continue
+				}
 			}
 
 			endpointNamesToRemove = append(endpointNamesToRemove, *peLink.Name)
@@ -373,6 +427,7 @@ func (c *PrivateEndpointsController) cleanupPrivateDns(ctx context.Context) erro
 	}
 
 	var zonesToRemove []string
+	curTime := time.Now()
 
 	pdnsClient, err := armprivatedns.NewPrivateZonesClient(c.SubID, c.Creds, nil)
 	if err != nil {
@@ -387,23 +442,39 @@ func (c *PrivateEndpointsController) cleanupPrivateDns(ctx context.Context) erro
 		}
 
 		for _, pdnsZone := range pdnsList.Value {
-			clusterIdPtr := pdnsZone.Tags["Cbdc2ClusterId"]
-			if clusterIdPtr == nil || *clusterIdPtr == "" {
+			meta, err := parseAzureTags(pdnsZone.Tags)
+			if err != nil {
+				c.Logger.Warn("failed to parse private dns zone tags",
+					zap.String("zone-name", *pdnsZone.Name),
+					zap.Error(err))
+				continue
+			}
+			if meta == nil {
 				// this is not a cbdc managed dns zone
 				continue
 			}
 
-			peNamePtr := pdnsZone.Tags["AssociatedPrivateEndpoint"]
-			if peNamePtr == nil || *peNamePtr == "" {
-				// this is missing a link, so we can't clean it up
+			isAbandoned, err := meta.IsAbandoned(ctx, curTime, c.GracePeriod, c.ClusterExists)
+			if err != nil {
+				c.Logger.Warn("failed to check if private dns zone is abandoned",
+					zap.String("zone-name", *pdnsZone.Name),
+					zap.Error(err))
 				continue
 			}
 
-			peName := *peNamePtr
+			if !isAbandoned {
+				peNamePtr := pdnsZone.Tags["AssociatedPrivateEndpoint"]
+				if peNamePtr == nil || *peNamePtr == "" {
+					// this is missing a link, so we can't clean it up
+					continue
+				}
 
-			if slices.Contains(validPeNames, peName) {
-				// the associated private endpoint is still available
-				continue
+				peName := *peNamePtr
+
+				if slices.Contains(validPeNames, peName) {
+					// the associated private endpoint is still available
+					continue
+				}
 			}
 
 			zonesToRemove = append(zonesToRemove, *pdnsZone.Name)
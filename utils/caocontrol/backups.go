@@ -0,0 +1,368 @@
+package caocontrol
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// BackupServiceAccountName is the service account the operator runs the
+	// backup jobs as, which is created by InstallBackupRbac.
+	BackupServiceAccountName = "couchbase-backup"
+
+	CouchbaseBackupKind        = "CouchbaseBackup"
+	CouchbaseBackupRestoreKind = "CouchbaseBackupRestore"
+)
+
+var (
+	couchbaseBackupsGvr = schema.GroupVersionResource{
+		Group:    "couchbase.com",
+		Version:  "v2",
+		Resource: "couchbasebackups",
+	}
+	couchbaseBackupRestoresGvr = schema.GroupVersionResource{
+		Group:    "couchbase.com",
+		Version:  "v2",
+		Resource: "couchbasebackuprestores",
+	}
+)
+
+// CouchbaseBackupStatus is the status the operator reports for both backup
+// and restore resources, fields which do not apply are left empty.
+type CouchbaseBackupStatus struct {
+	Running      bool         `json:"running,omitempty"`
+	Failed       bool         `json:"failed,omitempty"`
+	Job          string       `json:"job,omitempty"`
+	CronJob      string       `json:"cronjob,omitempty"`
+	Archive      string       `json:"archive,omitempty"`
+	Repo         string       `json:"repo,omitempty"`
+	CapacityUsed string       `json:"capacityUsed,omitempty"`
+	LastRun      *metav1.Time `json:"lastRun,omitempty"`
+	LastSuccess  *metav1.Time `json:"lastSuccess,omitempty"`
+	LastFailure  *metav1.Time `json:"lastFailure,omitempty"`
+	Backups      []struct {
+		Name         string   `json:"name"`
+		Full         string   `json:"full,omitempty"`
+		Incrementals []string `json:"incrementals,omitempty"`
+	} `json:"backups,omitempty"`
+}
+
+// BackupJobInfo describes a job which the operator started to perform a
+// backup or restore.
+type BackupJobInfo struct {
+	Name           string
+	OwnerKind      string
+	OwnerName      string
+	StartTime      time.Time
+	CompletionTime time.Time
+	Active         int
+	Succeeded      int
+	Failed         int
+}
+
+// InstallBackupRbac creates the service account and role which the
+// operator needs to run backup jobs in the namespace.
+func (c *Controller) InstallBackupRbac(ctx context.Context, namespace string) error {
+	if namespace == "" {
+		return errors.New("namespace must be specified")
+	}
+
+	c.logger.Info("installing backup rbac", zap.String("namespace", namespace))
+
+	err := c.caoExecAndPipe(ctx, c.logger, []string{
+		"create",
+		"backup",
+		"--namespace", namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup rbac")
+	}
+
+	return nil
+}
+
+// EnableCouchbaseClusterBackup turns on operator managed backups for an
+// existing couchbase cluster, leaving the rest of its spec alone.
+func (c *Controller) EnableCouchbaseClusterBackup(
+	ctx context.Context,
+	namespace string,
+	name string,
+	backupSpec map[string]interface{},
+) error {
+	cluster, err := c.GetCouchbaseCluster(ctx, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch existing resource")
+	}
+
+	err = unstructured.SetNestedField(cluster.Object, backupSpec, "spec", "backup")
+	if err != nil {
+		return errors.Wrap(err, "failed to set backup spec")
+	}
+
+	err = c.updateUnstructuredResource(ctx, namespace, cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster")
+	}
+
+	return nil
+}
+
+// IsCouchbaseClusterBackupManaged returns whether the operator manages
+// backups of the couchbase cluster.
+func (c *Controller) IsCouchbaseClusterBackupManaged(ctx context.Context, namespace string, name string) (bool, error) {
+	cluster, err := c.GetCouchbaseCluster(ctx, namespace, name)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get couchbase cluster")
+	}
+
+	managed, _, err := unstructured.NestedBool(cluster.Object, "spec", "backup", "managed")
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read backup spec")
+	}
+
+	return managed, nil
+}
+
+func (c *Controller) ParseCouchbaseBackupStatus(
+	res *unstructured.Unstructured,
+) (*CouchbaseBackupStatus, error) {
+	statusObj := res.Object["status"]
+
+	statusBytes, err := json.Marshal(statusObj)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal to status object")
+	}
+
+	var out CouchbaseBackupStatus
+	err = json.Unmarshal(statusBytes, &out)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal to status object")
+	}
+
+	return &out, nil
+}
+
+func (c *Controller) CreateCouchbaseBackup(ctx context.Context, namespace string, name string, spec interface{}) error {
+	c.logger.Info("creating couchbase backup",
+		zap.String("namespace", namespace),
+		zap.String("name", name))
+
+	err := c.createUnstructuredResource(ctx, namespace, &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "couchbase.com/v2",
+			"kind":       CouchbaseBackupKind,
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": spec,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup")
+	}
+
+	return nil
+}
+
+func (c *Controller) CreateCouchbaseBackupRestore(ctx context.Context, namespace string, name string, spec interface{}) error {
+	c.logger.Info("creating couchbase backup restore",
+		zap.String("namespace", namespace),
+		zap.String("name", name))
+
+	err := c.createUnstructuredResource(ctx, namespace, &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "couchbase.com/v2",
+			"kind":       CouchbaseBackupRestoreKind,
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": spec,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup restore")
+	}
+
+	return nil
+}
+
+func (c *Controller) listResources(
+	ctx context.Context,
+	namespace string,
+	gvr schema.GroupVersionResource,
+) ([]unstructured.Unstructured, error) {
+	dyna, err := dynamic.NewForConfig(c.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	list, err := dyna.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list resources")
+	}
+
+	return list.Items, nil
+}
+
+func (c *Controller) deleteResource(
+	ctx context.Context,
+	namespace string,
+	gvr schema.GroupVersionResource,
+	name string,
+) error {
+	dyna, err := dynamic.NewForConfig(c.restConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	// the jobs the operator started are cleaned up along with the resource
+	propagation := metav1.DeletePropagationBackground
+	err = dyna.Resource(gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to delete resource")
+	}
+
+	return nil
+}
+
+func (c *Controller) ListCouchbaseBackups(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	return c.listResources(ctx, namespace, couchbaseBackupsGvr)
+}
+
+func (c *Controller) ListCouchbaseBackupRestores(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	return c.listResources(ctx, namespace, couchbaseBackupRestoresGvr)
+}
+
+func (c *Controller) DeleteCouchbaseBackup(ctx context.Context, namespace string, name string) error {
+	return c.deleteResource(ctx, namespace, couchbaseBackupsGvr, name)
+}
+
+func (c *Controller) DeleteCouchbaseBackupRestore(ctx context.Context, namespace string, name string) error {
+	return c.deleteResource(ctx, namespace, couchbaseBackupRestoresGvr, name)
+}
+
+// ListBackupJobs lists the jobs of the namespace which belong to backup or
+// restore resources, either directly or through the cronjob of a scheduled
+// backup.  Jobs are sorted by their start time.
+func (c *Controller) ListBackupJobs(ctx context.Context, namespace string) ([]*BackupJobInfo, error) {
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	cronJobs, err := kubes.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list cronjobs")
+	}
+
+	jobs, err := kubes.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list jobs")
+	}
+
+	findBackupOwner := func(refs []metav1.OwnerReference) *metav1.OwnerReference {
+		for refIdx, ref := range refs {
+			if ref.Kind == CouchbaseBackupKind || ref.Kind == CouchbaseBackupRestoreKind {
+				return &refs[refIdx]
+			}
+		}
+		return nil
+	}
+
+	cronJobOwners := make(map[string]*metav1.OwnerReference)
+	for _, cronJob := range cronJobs.Items {
+		owner := findBackupOwner(cronJob.OwnerReferences)
+		if owner != nil {
+			cronJobOwners[cronJob.Name] = owner
+		}
+	}
+
+	var out []*BackupJobInfo
+	for _, job := range jobs.Items {
+		owner := findBackupOwner(job.OwnerReferences)
+		if owner == nil {
+			for _, ref := range job.OwnerReferences {
+				if ref.Kind == "CronJob" && cronJobOwners[ref.Name] != nil {
+					owner = cronJobOwners[ref.Name]
+				}
+			}
+		}
+		if owner == nil {
+			continue
+		}
+
+		out = append(out, backupJobInfoFromJob(&job, owner))
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].StartTime.Before(out[j].StartTime)
+	})
+
+	return out, nil
+}
+
+func backupJobInfoFromJob(job *batchv1.Job, owner *metav1.OwnerReference) *BackupJobInfo {
+	info := &BackupJobInfo{
+		Name:      job.Name,
+		OwnerKind: owner.Kind,
+		OwnerName: owner.Name,
+		Active:    int(job.Status.Active),
+		Succeeded: int(job.Status.Succeeded),
+		Failed:    int(job.Status.Failed),
+	}
+	if job.Status.StartTime != nil {
+		info.StartTime = job.Status.StartTime.Time
+	}
+	if job.Status.CompletionTime != nil {
+		info.CompletionTime = job.Status.CompletionTime.Time
+	}
+	return info
+}
+
+// GetJobLogs returns the logs of the most recently started pod of a job.
+func (c *Controller) GetJobLogs(ctx context.Context, namespace string, jobName string) (string, error) {
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	pods, err := kubes.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list job pods")
+	}
+
+	if len(pods.Items) == 0 {
+		return "", errors.New("job has no pods")
+	}
+
+	latestPod := pods.Items[0]
+	for _, pod := range pods.Items {
+		if pod.CreationTimestamp.After(latestPod.CreationTimestamp.Time) {
+			latestPod = pod
+		}
+	}
+
+	logBytes, err := kubes.CoreV1().Pods(namespace).GetLogs(latestPod.Name, &corev1.PodLogOptions{}).Do(ctx).Raw()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get pod logs")
+	}
+
+	return string(logBytes), nil
+}
@@ -21,8 +21,10 @@ import (
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/ptr"
@@ -819,6 +821,35 @@ func (c *Controller) GetCouchbaseCluster(
 	return cluster, nil
 }
 
+// WatchCouchbaseClusters starts an informer which invokes onChange whenever
+// a couchbase cluster resource is added, updated or deleted, until ctx is done.
+func (c *Controller) WatchCouchbaseClusters(ctx context.Context, onChange func()) error {
+	dyna, err := dynamic.NewForConfig(c.restConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(dyna, 0)
+	informer := factory.ForResource(schema.GroupVersionResource{
+		Group:    "couchbase.com",
+		Version:  "v2",
+		Resource: "couchbaseclusters",
+	}).Informer()
+
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { onChange() },
+		UpdateFunc: func(oldObj, newObj interface{}) { onChange() },
+		DeleteFunc: func(obj interface{}) { onChange() },
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add informer event handler")
+	}
+
+	factory.Start(ctx.Done())
+
+	return nil
+}
+
 func (c *Controller) clusterSizeFromClusterSpec(spec interface{}) (int, error) {
 	var structuredSpec struct {
 		Servers []struct {
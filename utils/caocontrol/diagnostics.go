@@ -0,0 +1,282 @@
+package caocontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EventInfo is a k8s event which occurred within a namespace.
+type EventInfo struct {
+	Time    time.Time
+	Type    string
+	Reason  string
+	Object  string
+	Message string
+	Count   int
+}
+
+func eventInfoFromEvent(event *corev1.Event) *EventInfo {
+	eventTime := event.LastTimestamp.Time
+	if eventTime.IsZero() {
+		eventTime = event.EventTime.Time
+	}
+	if eventTime.IsZero() {
+		eventTime = event.CreationTimestamp.Time
+	}
+
+	return &EventInfo{
+		Time:    eventTime,
+		Type:    event.Type,
+		Reason:  event.Reason,
+		Object:  strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name,
+		Message: event.Message,
+		Count:   int(event.Count),
+	}
+}
+
+// ListEvents lists the events of a namespace, sorted by when they occurred.
+func (c *Controller) ListEvents(ctx context.Context, namespace string) ([]*EventInfo, error) {
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	events, err := kubes.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list events")
+	}
+
+	var out []*EventInfo
+	for eventIdx := range events.Items {
+		out = append(out, eventInfoFromEvent(&events.Items[eventIdx]))
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+
+	return out, nil
+}
+
+// WatchEvents invokes onEvent for each of the existing events of a namespace,
+// followed by every event which is added or updated, until ctx is done.
+func (c *Controller) WatchEvents(ctx context.Context, namespace string, onEvent func(*EventInfo)) error {
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	events, err := kubes.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list events")
+	}
+
+	var existing []*EventInfo
+	for eventIdx := range events.Items {
+		existing = append(existing, eventInfoFromEvent(&events.Items[eventIdx]))
+	}
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[i].Time.Before(existing[j].Time)
+	})
+	for _, event := range existing {
+		onEvent(event)
+	}
+
+	resourceVersion := events.ResourceVersion
+	for {
+		watcher, err := kubes.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to watch events")
+		}
+
+		for watchEvent := range watcher.ResultChan() {
+			if watchEvent.Type != watch.Added && watchEvent.Type != watch.Modified {
+				continue
+			}
+
+			event, ok := watchEvent.Object.(*corev1.Event)
+			if !ok {
+				continue
+			}
+
+			resourceVersion = event.ResourceVersion
+			onEvent(eventInfoFromEvent(event))
+		}
+
+		watcher.Stop()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the api server closes watches periodically, so we resume from
+		// the last event we saw
+		c.logger.Debug("event watch closed, restarting",
+			zap.String("namespace", namespace))
+	}
+}
+
+// CollectDiagnostics writes the couchbase cluster resource, the events and
+// pod states of the namespace and the operator logs into destPath.  This is
+// best-effort, with failures to collect individual items being recorded in
+// their place, and returns the paths of the files written.
+func (c *Controller) CollectDiagnostics(
+	ctx context.Context,
+	namespace string,
+	clusterName string,
+	destPath string,
+) ([]string, error) {
+	if namespace == "" {
+		return nil, errors.New("namespace must be specified")
+	}
+
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	err = os.MkdirAll(destPath, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create diagnostics directory")
+	}
+
+	var writtenPaths []string
+	writeFile := func(fileName string, data []byte, collectErr error) {
+		if collectErr != nil {
+			c.logger.Warn("failed to collect diagnostics item",
+				zap.String("item", fileName),
+				zap.Error(collectErr))
+			data = []byte(fmt.Sprintf("failed to collect: %s\n", collectErr))
+		}
+
+		filePath := filepath.Join(destPath, fileName)
+		err := os.WriteFile(filePath, data, 0644)
+		if err != nil {
+			c.logger.Warn("failed to write diagnostics item",
+				zap.String("path", filePath),
+				zap.Error(err))
+			return
+		}
+
+		writtenPaths = append(writtenPaths, filePath)
+	}
+
+	c.logger.Info("collecting diagnostics",
+		zap.String("namespace", namespace),
+		zap.String("path", destPath))
+
+	clusterBytes, err := func() ([]byte, error) {
+		cluster, err := c.GetCouchbaseCluster(ctx, namespace, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(cluster.Object, "", "  ")
+	}()
+	writeFile("cluster.json", clusterBytes, err)
+
+	eventsBytes, err := func() ([]byte, error) {
+		events, err := c.ListEvents(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		var sb strings.Builder
+		for _, event := range events {
+			fmt.Fprintf(&sb, "%s %-8s %-24s %s (x%d): %s\n",
+				event.Time.Format(time.RFC3339),
+				event.Type,
+				event.Reason,
+				event.Object,
+				event.Count,
+				event.Message)
+		}
+		return []byte(sb.String()), nil
+	}()
+	writeFile("events.txt", eventsBytes, err)
+
+	podsBytes, err := func() ([]byte, error) {
+		pods, err := kubes.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		var sb strings.Builder
+		for _, pod := range pods.Items {
+			fmt.Fprintf(&sb, "%s [Phase: %s, Node: %s]\n",
+				pod.Name,
+				pod.Status.Phase,
+				pod.Spec.NodeName)
+			for _, cond := range pod.Status.Conditions {
+				fmt.Fprintf(&sb, "  Condition %s=%s %s %s\n",
+					cond.Type, cond.Status, cond.Reason, cond.Message)
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				fmt.Fprintf(&sb, "  Container %s [Ready: %t, Restarts: %d]\n",
+					status.Name, status.Ready, status.RestartCount)
+				if status.State.Waiting != nil {
+					fmt.Fprintf(&sb, "    Waiting: %s %s\n",
+						status.State.Waiting.Reason, status.State.Waiting.Message)
+				}
+				if status.State.Terminated != nil {
+					fmt.Fprintf(&sb, "    Terminated: %s %s\n",
+						status.State.Terminated.Reason, status.State.Terminated.Message)
+				}
+			}
+		}
+		return []byte(sb.String()), nil
+	}()
+	writeFile("pods.txt", podsBytes, err)
+
+	operatorLogBytes, err := func() ([]byte, error) {
+		deployment, err := kubes.AppsV1().Deployments(namespace).Get(ctx, DefaultOperatorName, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get operator deployment")
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse operator selector")
+		}
+
+		pods, err := kubes.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list operator pods")
+		}
+
+		var logs []byte
+		for _, pod := range pods.Items {
+			podLogs, err := kubes.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).Do(ctx).Raw()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get logs of pod `%s`", pod.Name)
+			}
+
+			logs = append(logs, []byte(fmt.Sprintf("==> %s <==\n", pod.Name))...)
+			logs = append(logs, podLogs...)
+		}
+		return logs, nil
+	}()
+	writeFile("operator.log", operatorLogBytes, err)
+
+	return writtenPaths, nil
+}
@@ -128,3 +128,27 @@ func GetServerImage(ctx context.Context, version string, needRhcc bool) (string,
 
 	return image, nil
 }
+
+func GetBackupImage(ctx context.Context, version string, needRhcc bool) (string, error) {
+	if version[0] == '@' {
+		return version[1:], nil
+	}
+
+	version, buildNo, err := parseSimpleVersion(ctx, version)
+	if err != nil {
+		return "", err
+	}
+
+	image := ""
+	if buildNo == 0 {
+		image = fmt.Sprintf("couchbase/operator-backup:%s", version)
+	} else {
+		if !needRhcc {
+			image = fmt.Sprintf("ghcr.io/cb-vanilla/operator-backup:%s-%d", version, buildNo)
+		} else {
+			image = fmt.Sprintf("ghcr.io/cb-rhcc/operator-backup:%s-%d", version, buildNo)
+		}
+	}
+
+	return image, nil
+}
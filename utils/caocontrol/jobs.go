@@ -0,0 +1,221 @@
+package caocontrol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type JobSecretEnvVar struct {
+	Name       string
+	SecretName string
+	SecretKey  string
+}
+
+type RunJobOptions struct {
+	Name    string
+	Image   string
+	Command []string
+	Env     map[string]string
+
+	// SecretEnv are environment variables whose values are read from
+	// secrets of the namespace.
+	SecretEnv []JobSecretEnvVar
+
+	// Timeout is how long the job may run before k8s terminates it.
+	Timeout time.Duration
+
+	// Keep leaves the job and its pod behind once it has completed.
+	Keep bool
+}
+
+// startupFailureReasons are the reasons a container waits for which it will
+// not recover from without intervention.
+var startupFailureReasons = []string{
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+}
+
+// RunJob runs a single container to completion as a job within a namespace,
+// streaming its output to logOut, and returns the exit code of the container.
+func (c *Controller) RunJob(ctx context.Context, namespace string, opts *RunJobOptions, logOut io.Writer) (int, error) {
+	kubes, err := kubernetes.NewForConfig(c.restConfig)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	var envVars []corev1.EnvVar
+	for envName, envValue := range opts.Env {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  envName,
+			Value: envValue,
+		})
+	}
+	sort.Slice(envVars, func(i, j int) bool {
+		return envVars[i].Name < envVars[j].Name
+	})
+	for _, secretEnv := range opts.SecretEnv {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: secretEnv.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: secretEnv.SecretName,
+					},
+					Key: secretEnv.SecretKey,
+				},
+			},
+		})
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: opts.Name,
+			Labels: map[string]string{
+				"cbdc2.type": "job",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// the job is run exactly once so that its exit code is meaningful
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: GhcrSecretName},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    "main",
+							Image:   opts.Image,
+							Command: opts.Command,
+							Env:     envVars,
+						},
+					},
+				},
+			},
+		},
+	}
+	if opts.Timeout > 0 {
+		job.Spec.ActiveDeadlineSeconds = ptr.To(int64(opts.Timeout.Seconds()))
+	}
+
+	c.logger.Info("creating job",
+		zap.String("namespace", namespace),
+		zap.String("name", opts.Name),
+		zap.String("image", opts.Image))
+
+	_, err = kubes.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to create job")
+	}
+
+	if !opts.Keep {
+		defer func() {
+			// the job is removed even when we were interrupted
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+			defer cancel()
+
+			err := kubes.BatchV1().Jobs(namespace).Delete(cleanupCtx, opts.Name, metav1.DeleteOptions{
+				PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+			})
+			if err != nil {
+				c.logger.Warn("failed to delete job", zap.Error(err))
+			}
+		}()
+	}
+
+	podName := ""
+	err = waitForFunc(ctx, func(ctx context.Context) (bool, error) {
+		pods, err := kubes.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "job-name=" + opts.Name,
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to list job pods")
+		}
+
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodPending {
+				podName = pod.Name
+				return true, nil
+			}
+
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.State.Waiting == nil {
+					continue
+				}
+				for _, reason := range startupFailureReasons {
+					if status.State.Waiting.Reason == reason {
+						return false, fmt.Errorf("job container failed to start: %s: %s",
+							status.State.Waiting.Reason, status.State.Waiting.Message)
+					}
+				}
+			}
+		}
+
+		return false, nil
+	}, 10*time.Minute)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to wait for job to start")
+	}
+
+	logStream, err := kubes.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Follow: true,
+	}).Stream(ctx)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to stream job logs")
+	}
+
+	_, err = io.Copy(logOut, logStream)
+	logStream.Close()
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+
+		c.logger.Warn("job log stream ended unexpectedly", zap.Error(err))
+	}
+
+	exitCode := -1
+	err = waitForFunc(ctx, func(ctx context.Context) (bool, error) {
+		pod, err := kubes.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get job pod")
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				exitCode = int(status.State.Terminated.ExitCode)
+				return true, nil
+			}
+		}
+
+		// a pod killed for exceeding the deadline may have no container status
+		if pod.Status.Phase == corev1.PodFailed {
+			return true, nil
+		}
+
+		return false, nil
+	}, 1*time.Minute)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to wait for job to complete")
+	}
+
+	c.logger.Info("job completed", zap.Int("exitCode", exitCode))
+
+	return exitCode, nil
+}
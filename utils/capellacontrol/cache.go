@@ -0,0 +1,105 @@
+package capellacontrol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResponseCache is a local file-backed cache of Capella responses which
+// rarely change, shared between invocations to avoid repeating the same
+// lookups every time.  Any mutating request clears the whole cache, so
+// the cache only risks missing changes made by other clients, for which
+// the TTL is kept short.
+type ResponseCache struct {
+	Path string
+	TTL  time.Duration
+}
+
+type responseCacheEntry struct {
+	Expiry time.Time       `json:"expiry"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func (c *ResponseCache) entryPath(key string) string {
+	keyHash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Path, hex.EncodeToString(keyHash[:])+".json")
+}
+
+// Get fetches a cached response, returning false if there is no unexpired
+// response cached for the key.
+func (c *ResponseCache) Get(key string, out interface{}) bool {
+	entryBytes, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return false
+	}
+
+	var entry responseCacheEntry
+	err = json.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		return false
+	}
+
+	if time.Now().After(entry.Expiry) {
+		return false
+	}
+
+	err = json.Unmarshal(entry.Data, out)
+	return err == nil
+}
+
+func (c *ResponseCache) Put(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache data")
+	}
+
+	entryBytes, err := json.Marshal(responseCacheEntry{
+		Expiry: time.Now().Add(c.TTL),
+		Data:   data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache entry")
+	}
+
+	err = os.MkdirAll(c.Path, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+
+	// entries are written to a temporary file and renamed so that
+	// concurrent invocations never observe a partially written entry.
+	tmpFile, err := os.CreateTemp(c.Path, "entry-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create cache entry")
+	}
+
+	_, err = tmpFile.Write(entryBytes)
+	tmpFile.Close()
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "failed to write cache entry")
+	}
+
+	err = os.Rename(tmpFile.Name(), c.entryPath(key))
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrap(err, "failed to store cache entry")
+	}
+
+	return nil
+}
+
+func (c *ResponseCache) Clear() error {
+	err := os.RemoveAll(c.Path)
+	if err != nil {
+		return errors.Wrap(err, "failed to clear cache")
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package capellacontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := &ResponseCache{
+		Path: t.TempDir(),
+		TTL:  time.Minute,
+	}
+
+	var out []string
+	require.False(t, cache.Get("projects", &out))
+
+	require.NoError(t, cache.Put("projects", []string{"a", "b"}))
+	require.True(t, cache.Get("projects", &out))
+	require.Equal(t, []string{"a", "b"}, out)
+
+	require.NoError(t, cache.Clear())
+	require.False(t, cache.Get("projects", &out))
+
+	expiredCache := &ResponseCache{
+		Path: t.TempDir(),
+		TTL:  -time.Second,
+	}
+	require.NoError(t, expiredCache.Put("projects", []string{"a"}))
+	require.False(t, expiredCache.Get("projects", &out))
+}
@@ -0,0 +1,147 @@
+package capellacontrol_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/httpreplay"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// The contract tests replay fixtures recorded against a real Capella tenant,
+// validating how the controller builds its requests and parses responses.
+// Setting CAPELLA_RECORD re-records the fixtures using the CAPELLA_ENDPOINT,
+// CAPELLA_USER, CAPELLA_PASS and CAPELLA_OID environment variables.
+
+const contractTenantID = "test-tenant"
+
+func newContractController(t *testing.T, fixtureName string) *capellacontrol.Controller {
+	ctx := context.Background()
+	logger, _ := zap.NewDevelopment()
+	fixturePath := filepath.Join("testdata", fixtureName+".json")
+
+	if os.Getenv("CAPELLA_RECORD") != "" {
+		endpoint := os.Getenv("CAPELLA_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "https://api.cloud.couchbase.com"
+		}
+
+		recorder := httpreplay.NewRecorder(nil)
+		recorder.Replacements = map[string]string{
+			os.Getenv("CAPELLA_OID"): contractTenantID,
+		}
+
+		t.Cleanup(func() {
+			// logins are not part of the contract, replay uses token credentials
+			fixture := &httpreplay.Fixture{}
+			for _, interaction := range recorder.Fixture().Interactions {
+				if interaction.Path != "/sessions" {
+					fixture.Interactions = append(fixture.Interactions, interaction)
+				}
+			}
+
+			err := fixture.Save(fixturePath)
+			require.NoError(t, err)
+		})
+
+		ctrl, err := capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
+			Logger:     logger,
+			HttpClient: &http.Client{Transport: recorder},
+			Endpoint:   endpoint,
+			Auth: &capellacontrol.BasicCredentials{
+				Username: os.Getenv("CAPELLA_USER"),
+				Password: os.Getenv("CAPELLA_PASS"),
+			},
+		})
+		require.NoError(t, err)
+
+		return ctrl
+	}
+
+	fixture, err := httpreplay.LoadFixture(fixturePath)
+	require.NoError(t, err)
+
+	replayer := httpreplay.NewReplayer(fixture)
+	t.Cleanup(func() {
+		require.Empty(t, replayer.Unused(), "fixture contains requests which were not made")
+	})
+
+	ctrl, err := capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
+		Logger:     logger,
+		HttpClient: &http.Client{Transport: replayer},
+		Endpoint:   "https://capella.invalid",
+		Auth: &capellacontrol.TokenCredentials{
+			AccessKey: "access-key",
+			SecretKey: "secret-key",
+		},
+	})
+	require.NoError(t, err)
+
+	return ctrl
+}
+
+func contractTenant() string {
+	if os.Getenv("CAPELLA_RECORD") != "" {
+		return os.Getenv("CAPELLA_OID")
+	}
+	return contractTenantID
+}
+
+func TestContractListProjects(t *testing.T) {
+	ctx := context.Background()
+	ctrl := newContractController(t, "list_projects")
+
+	resp, err := ctrl.ListProjects(ctx, contractTenant(), &capellacontrol.PaginatedRequest{
+		Page:          1,
+		PerPage:       100,
+		SortBy:        "name",
+		SortDirection: "asc",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Cursor)
+	require.Equal(t, 1, resp.Cursor.Pages.Page)
+	require.Len(t, resp.Data, 2)
+	require.Equal(t, "cbdc2_project_a", resp.Data[0].Data.Name)
+	require.Equal(t, "8f2c6a1e-0b5d-4c1b-9a43-1f5f2f9a0d11", resp.Data[0].Data.ID)
+	require.Equal(t, 3, resp.Data[0].Data.ClusterCount)
+}
+
+func TestContractCreateProject(t *testing.T) {
+	ctx := context.Background()
+	ctrl := newContractController(t, "create_project")
+
+	resp, err := ctrl.CreateProject(ctx, contractTenant(), &capellacontrol.CreateProjectRequest{
+		Name: "cbdc2_contract_test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "4d7e0c52-6f3a-4b8e-b1d2-7c9e5a3f8b20", resp.Id)
+}
+
+func TestContractCreateProjectQuota(t *testing.T) {
+	ctx := context.Background()
+	ctrl := newContractController(t, "create_project_quota")
+
+	_, err := ctrl.CreateProject(ctx, contractTenant(), &capellacontrol.CreateProjectRequest{
+		Name: "cbdc2_contract_quota",
+	})
+	require.Error(t, err)
+	require.True(t, capellacontrol.IsQuotaError(err))
+}
+
+func TestContractListClusterJobs(t *testing.T) {
+	ctx := context.Background()
+	ctrl := newContractController(t, "list_cluster_jobs")
+
+	resp, err := ctrl.ListClusterJobs(ctx, contractTenant(),
+		"8f2c6a1e-0b5d-4c1b-9a43-1f5f2f9a0d11",
+		"b3a9d6e4-2c71-4f0e-8d15-6a2e9c4b7f03")
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	require.Equal(t, "deployCluster", resp.Data[0].Data.JobType)
+	require.Equal(t, 40, resp.Data[0].Data.CompletionPercentage)
+}
@@ -5,15 +5,21 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/httpdebug"
 	"github.com/google/go-querystring/query"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -48,6 +54,8 @@ type Controller struct {
 	httpClient *http.Client
 	endpoint   string
 	auth       Credentials
+	cache      *ResponseCache
+	limiter    *RateLimiter
 }
 
 type ControllerOptions struct {
@@ -55,6 +63,73 @@ type ControllerOptions struct {
 	HttpClient *http.Client
 	Endpoint   string
 	Auth       Credentials
+
+	// LogHttp enables logging of all requests and responses at debug
+	// level, with any credentials redacted.
+	LogHttp bool
+
+	// Cache optionally specifies a cache for lookups which rarely change,
+	// such as the project list and deployment options.
+	Cache *ResponseCache
+
+	// RateLimiter optionally limits the requests sent by the controller,
+	// and can be shared between controllers using the same tenant.
+	RateLimiter *RateLimiter
+
+	// ProxyUrl specifies a proxy to send requests through.  By default,
+	// the proxy is taken from the HTTPS_PROXY environment variable.
+	ProxyUrl string
+
+	// CaBundlePath specifies a file of PEM certificates which are trusted
+	// in addition to the system roots, such as those of a corporate proxy
+	// or of a pre-production control plane.
+	CaBundlePath string
+
+	// TLSSkipVerify disables verification of the control plane certificate.
+	TLSSkipVerify bool
+}
+
+// newHttpClient builds the http client for the proxy and TLS options.
+func newHttpClient(opts *ControllerOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyUrl != "" {
+		proxyUrl, err := url.Parse(opts.ProxyUrl)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse proxy url")
+		}
+
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if opts.CaBundlePath != "" || opts.TLSSkipVerify {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: opts.TLSSkipVerify,
+		}
+
+		if opts.CaBundlePath != "" {
+			caBytes, err := os.ReadFile(opts.CaBundlePath)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read ca bundle")
+			}
+
+			caPool, err := x509.SystemCertPool()
+			if err != nil {
+				caPool = x509.NewCertPool()
+			}
+
+			if !caPool.AppendCertsFromPEM(caBytes) {
+				return nil, errors.New("failed to parse ca bundle")
+			}
+			tlsConfig.RootCAs = caPool
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
 }
 
 func NewController(ctx context.Context, opts *ControllerOptions) (*Controller, error) {
@@ -63,10 +138,27 @@ func NewController(ctx context.Context, opts *ControllerOptions) (*Controller, e
 	}
 
 	httpClient := opts.HttpClient
+	if opts.ProxyUrl != "" || opts.CaBundlePath != "" || opts.TLSSkipVerify {
+		if httpClient != nil {
+			return nil, errors.New("proxy and tls options cannot be used with a custom http client")
+		}
+
+		newClient, err := newHttpClient(opts)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = newClient
+	}
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 
+	if opts.LogHttp {
+		loggedClient := *httpClient
+		loggedClient.Transport = httpdebug.NewTransport(opts.Logger, httpClient.Transport)
+		httpClient = &loggedClient
+	}
+
 	switch opts.Auth.(type) {
 	case *BasicCredentials:
 	case *TokenCredentials:
@@ -79,6 +171,8 @@ func NewController(ctx context.Context, opts *ControllerOptions) (*Controller, e
 		httpClient: httpClient,
 		endpoint:   opts.Endpoint,
 		auth:       opts.Auth,
+		cache:      opts.Cache,
+		limiter:    opts.RateLimiter,
 	}, nil
 }
 
@@ -111,11 +205,29 @@ func (e requestError) Unwrap() error {
 	return e.Cause
 }
 
+// IsQuotaError checks whether an error is Capella rejecting a request due
+// to the tenant having reached one of its resource quotas.
+func IsQuotaError(err error) bool {
+	var capellaErr *capellaError
+	if !errors.As(err, &capellaErr) {
+		return false
+	}
+
+	errText := strings.ToLower(capellaErr.ErrorName + " " + capellaErr.ErrorType + " " + capellaErr.Message)
+	return strings.Contains(errText, "quota")
+}
+
 func (c *Controller) doReq(
 	ctx context.Context,
 	req *http.Request,
 	out interface{},
 ) error {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "failed to execute auth request")
@@ -124,6 +236,11 @@ func (c *Controller) doReq(
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// slow down every request sharing the limiter, not just this one
+			c.limiter.Backoff(throttledBackoff(resp))
+		}
+
 		bytes, _ := io.ReadAll(resp.Body)
 
 		var parsedErr capellaError
@@ -147,7 +264,22 @@ func (c *Controller) doReq(
 	return nil
 }
 
+// maxThrottledRetries is how many times a request which Capella throttled
+// is retried, independent of the retries allowed for other failures.
+const maxThrottledRetries = 5
+
+// throttledBackoff determines how long to pause requests after Capella
+// has throttled us, preferring the period it asks for.
+func throttledBackoff(resp *http.Response) time.Duration {
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err == nil && retryAfter > 0 {
+		return time.Duration(retryAfter) * time.Second
+	}
+	return 2 * time.Second
+}
+
 func (c *Controller) doRetriableReq(ctx context.Context, makeReq func() (*http.Request, error), maxRetries int, out interface{}) error {
+	throttledRetries := 0
 	for retryNum := 0; ; retryNum++ {
 		req, err := makeReq()
 		if err != nil {
@@ -181,6 +313,24 @@ func (c *Controller) doRetriableReq(ctx context.Context, makeReq func() (*http.R
 				}
 			}
 
+			// Throttled requests were never processed, so they are safe to
+			// retry even when other failures are not.  The limiter delays the
+			// retry until the throttling is likely to have passed.
+			var reqErr *requestError
+			if errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusTooManyRequests &&
+				throttledRetries < maxThrottledRetries {
+				throttledRetries++
+				c.logger.Debug("request was throttled, retrying",
+					zap.Int("throttledRetries", throttledRetries))
+
+				if c.limiter == nil {
+					time.Sleep(2 * time.Second)
+				}
+
+				retryNum--
+				continue
+			}
+
 			if retryNum == maxRetries {
 				c.logger.Debug("request failed, exhausted retries",
 					zap.Error(err),
@@ -211,20 +361,53 @@ func (c *Controller) doBasicReq(
 	body interface{},
 	out interface{},
 ) error {
+	if body == nil {
+		return c.doBasicEncodedReq(ctx, allowRetries, method, path, "", nil, out)
+	}
+
 	encodedBody, err := json.Marshal(body)
 	if err != nil {
 		return errors.Wrap(err, "failed to encode request body")
 	}
 
+	return c.doBasicEncodedReq(ctx, allowRetries, method, path, "application/json", encodedBody, out)
+}
+
+// doBasicFormReq performs a request with a form encoded body, which is
+// needed for requests proxied to the cluster management API.
+func (c *Controller) doBasicFormReq(
+	ctx context.Context,
+	allowRetries bool,
+	method string,
+	path string,
+	form url.Values,
+	out interface{},
+) error {
+	return c.doBasicEncodedReq(ctx, allowRetries, method, path,
+		"application/x-www-form-urlencoded", []byte(form.Encode()), out)
+}
+
+func (c *Controller) doBasicEncodedReq(
+	ctx context.Context,
+	allowRetries bool,
+	method string,
+	path string,
+	contentType string,
+	encodedBody []byte,
+	out interface{},
+) error {
 	maxRetries := 10
 	if !allowRetries {
 		maxRetries = 0
 	}
 
+	if method != http.MethodGet {
+		c.clearCache()
+	}
+
 	return c.doRetriableReq(ctx, func() (*http.Request, error) {
 		var bodyRdr io.Reader
-		if body != nil {
-
+		if encodedBody != nil {
 			bodyRdr = bytes.NewReader(encodedBody)
 		}
 
@@ -234,7 +417,7 @@ func (c *Controller) doBasicReq(
 		}
 
 		if bodyRdr != nil {
-			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Content-Type", contentType)
 		}
 
 		switch auth := c.auth.(type) {
@@ -268,6 +451,60 @@ func (c *Controller) doBasicReq(
 	}, maxRetries, out)
 }
 
+// cacheKey identifies a request within the cache.  The credentials are part
+// of the key since they determine what resources are visible.
+func (c *Controller) cacheKey(path string) string {
+	identity := ""
+	switch auth := c.auth.(type) {
+	case *BasicCredentials:
+		identity = auth.Username
+	case *TokenCredentials:
+		identity = auth.AccessKey
+	}
+
+	return c.endpoint + "|" + identity + "|" + path
+}
+
+func (c *Controller) clearCache() {
+	if c.cache == nil {
+		return
+	}
+
+	err := c.cache.Clear()
+	if err != nil {
+		c.logger.Debug("failed to clear response cache", zap.Error(err))
+	}
+}
+
+// doCachedGet performs a GET request, using the cache where possible.
+func (c *Controller) doCachedGet(
+	ctx context.Context,
+	path string,
+	out interface{},
+) error {
+	if c.cache == nil {
+		return c.doBasicReq(ctx, false, http.MethodGet, path, nil, out)
+	}
+
+	cacheKey := c.cacheKey(path)
+	if c.cache.Get(cacheKey, out) {
+		c.logger.Debug("using cached response", zap.String("path", path))
+		return nil
+	}
+
+	err := c.doBasicReq(ctx, false, http.MethodGet, path, nil, out)
+	if err != nil {
+		return err
+	}
+
+	err = c.cache.Put(cacheKey, out)
+	if err != nil {
+		c.logger.Debug("failed to cache response", zap.Error(err))
+	}
+
+	return nil
+}
+
 func (c *Controller) doTokenRequest(
 	ctx context.Context,
 	method string,
@@ -297,6 +534,10 @@ func (c *Controller) doTokenRequest(
 	}
 	req.Header.Add("Authorization", "Bearer "+token)
 
+	if method != http.MethodGet {
+		c.clearCache()
+	}
+
 	err = c.doReq(ctx, req, out)
 
 	return err
@@ -369,6 +610,34 @@ type PagedResourceResponse[T any] PagedResponse[Resource[T]]
 
 type ResourceResponse[T any] Resource[T]
 
+type OrganizationInfo struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ModifiedAt  time.Time `json:"modifiedAt"`
+}
+
+type ListOrganizationsResponse PagedResourceResponse[*OrganizationInfo]
+
+// ListOrganizations lists the organizations (tenants) which are available
+// to the credentials being used.
+func (c *Controller) ListOrganizations(
+	ctx context.Context,
+	req *PaginatedRequest,
+) (*ListOrganizationsResponse, error) {
+	resp := &ListOrganizationsResponse{}
+
+	form, _ := query.Values(req)
+	path := fmt.Sprintf("/v2/organizations?%s", form.Encode())
+	err := c.doCachedGet(ctx, path, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 type ProjectInfo struct {
 	ClusterCount       int       `json:"clusterCount"`
 	CreatedAt          time.Time `json:"createdAt"`
@@ -444,7 +713,7 @@ func (c *Controller) ListProjects(
 
 	form, _ := query.Values(req)
 	path := fmt.Sprintf("/v2/organizations/%s/projects?%s", tenantID, form.Encode())
-	err := c.doBasicReq(ctx, false, "GET", path, nil, &resp)
+	err := c.doCachedGet(ctx, path, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -938,7 +1207,9 @@ type GetProviderDeploymentOptionsResponse struct {
 
 type GetProviderDeploymentOptionsResponse_Provider struct {
 	AutoExpansion GetProviderDeploymentOptionsResponse_Provider_AutoExpansion `json:"autoExpansion"`
+	Compute       []GetProviderDeploymentOptionsResponse_Provider_Compute     `json:"compute"`
 	DisplayName   string                                                      `json:"displayName"`
+	Disks         []GetProviderDeploymentOptionsResponse_Provider_Disk        `json:"disks"`
 	// eligibility
 	Key string `json:"key"`
 	// regions
@@ -949,6 +1220,18 @@ type GetProviderDeploymentOptionsResponse_Provider_AutoExpansion struct {
 	Enabled bool `json:"enabled"`
 }
 
+type GetProviderDeploymentOptionsResponse_Provider_Compute struct {
+	Type       string `json:"type"`
+	Cpu        int    `json:"cpu"`
+	MemoryInGb int    `json:"memoryInGb"`
+}
+
+type GetProviderDeploymentOptionsResponse_Provider_Disk struct {
+	Type        string `json:"type"`
+	MinSizeInGb int    `json:"minSizeInGb"`
+	MaxSizeInGb int    `json:"maxSizeInGb"`
+}
+
 type GetProviderDeploymentOptionsResponse_ServerVersions struct {
 	DefaultVersion string   `json:"defaultVersion"`
 	Versions       []string `json:"versions"`
@@ -963,7 +1246,7 @@ func (c *Controller) GetProviderDeploymentOptions(
 
 	form, _ := query.Values(req)
 	path := fmt.Sprintf("/v2/organizations/%s/clusters/deployment-options?%s", tenantID, form.Encode())
-	err := c.doBasicReq(ctx, false, "GET", path, nil, &resp)
+	err := c.doCachedGet(ctx, path, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -1218,6 +1501,129 @@ func (c *Controller) AcceptPrivateEndpointLink(
 	return err
 }
 
+type NetworkPeerProviderConfig struct {
+	// AWS peers use AccountID, VpcID and Region, while GCP peers use
+	// ProjectID, NetworkName and ServiceAccount.
+	AccountID      string `json:"accountId,omitempty"`
+	VpcID          string `json:"vpcId,omitempty"`
+	Region         string `json:"region,omitempty"`
+	ProjectID      string `json:"projectId,omitempty"`
+	NetworkName    string `json:"networkName,omitempty"`
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	Cidr           string `json:"cidr"`
+
+	// ProviderID is the id of the peering connection assigned by the cloud
+	// provider, and HostedZoneID is the private zone holding the DNS records
+	// of the cluster, both are populated once the peering is requested.
+	ProviderID   string `json:"providerId,omitempty"`
+	HostedZoneID string `json:"hostedZoneId,omitempty"`
+}
+
+type NetworkPeerStatus struct {
+	State     string `json:"state"` // pending, pendingAcceptance, complete, failed...
+	Reasoning string `json:"reasoning"`
+}
+
+type NetworkPeerInfo struct {
+	ID             string                    `json:"id"`
+	Name           string                    `json:"name"`
+	ProviderType   string                    `json:"providerType"`
+	ProviderConfig NetworkPeerProviderConfig `json:"providerConfig"`
+	Status         NetworkPeerStatus         `json:"status"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+}
+
+type ListNetworkPeersResponse PagedResponse[*NetworkPeerInfo]
+
+func (c *Controller) ListNetworkPeers(
+	ctx context.Context,
+	tenantID, projectID, clusterID string,
+) (*ListNetworkPeersResponse, error) {
+	resp := &ListNetworkPeersResponse{}
+
+	path := fmt.Sprintf("/v2/organizations/%s/projects/%s/clusters/%s/networkpeers", tenantID, projectID, clusterID)
+	err := c.doBasicReq(ctx, false, "GET", path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, err
+}
+
+type CreateNetworkPeerRequest struct {
+	Name           string                    `json:"name"`
+	ProviderType   string                    `json:"providerType"` // aws, gcp
+	ProviderConfig NetworkPeerProviderConfig `json:"providerConfig"`
+}
+
+type CreateNetworkPeerResponse struct {
+	Id string `json:"id"`
+}
+
+func (c *Controller) CreateNetworkPeer(
+	ctx context.Context,
+	tenantID, projectID, clusterID string,
+	req *CreateNetworkPeerRequest,
+) (*CreateNetworkPeerResponse, error) {
+	resp := &CreateNetworkPeerResponse{}
+
+	path := fmt.Sprintf("/v2/organizations/%s/projects/%s/clusters/%s/networkpeers", tenantID, projectID, clusterID)
+	err := c.doBasicReq(ctx, false, "POST", path, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, err
+}
+
+func (c *Controller) DeleteNetworkPeer(
+	ctx context.Context,
+	tenantID, projectID, clusterID, peerID string,
+) error {
+	path := fmt.Sprintf("/v2/organizations/%s/projects/%s/clusters/%s/networkpeers/%s", tenantID, projectID, clusterID, peerID)
+	err := c.doBasicReq(ctx, false, "DELETE", path, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type NetworkPeerCommandRequest struct {
+	ProviderType           string `json:"providerType"`
+	AccountID              string `json:"accountId,omitempty"`
+	VpcID                  string `json:"vpcId,omitempty"`
+	Region                 string `json:"region,omitempty"`
+	Cidr                   string `json:"cidr,omitempty"`
+	VpcPeeringConnectionID string `json:"vpcPeeringConnectionId,omitempty"`
+	ProjectID              string `json:"projectId,omitempty"`
+	NetworkName            string `json:"networkName,omitempty"`
+	ServiceAccount         string `json:"serviceAccount,omitempty"`
+}
+
+type NetworkPeerCommandInfo struct {
+	Command string `json:"command"`
+}
+
+// GenNetworkPeerCommand generates the commands which need to be run against
+// the peered network to accept the peering and route to the cluster, this
+// is what the UI presents once a peering has been requested.
+func (c *Controller) GenNetworkPeerCommand(
+	ctx context.Context,
+	tenantID, projectID, clusterID string,
+	req *NetworkPeerCommandRequest,
+) (*ResourceResponse[NetworkPeerCommandInfo], error) {
+	resp := &ResourceResponse[NetworkPeerCommandInfo]{}
+
+	path := fmt.Sprintf("/v2/organizations/%s/projects/%s/clusters/%s/networkpeers/networkpeercommand", tenantID, projectID, clusterID)
+	err := c.doBasicReq(ctx, false, "POST", path, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, err
+}
+
 type UserInfo struct {
 	ID          string                         `json:"ID"`
 	Name        string                         `json:"name"`
@@ -1433,7 +1839,7 @@ type CreateBucketRequest struct {
 	MemoryAllocationInMB     int    `json:"memoryAllocationInMb"`
 	Name                     string `json:"name"`
 	Replicas                 int    `json:"replicas"`
-	StorageBackend           string `json:"storageBackend"`
+	StorageBackend           string `json:"storageBackend,omitempty"`
 	// timeToLive
 	Type string `json:"type"`
 }
@@ -1493,6 +1899,248 @@ func (c *Controller) GetTrustedCAs(
 	return resp, err
 }
 
+// CollectionSettings are the settings of a collection, which are managed
+// through the cluster management API proxied by Capella.  Unset fields use
+// the defaults of the bucket, or are left unchanged when updating.
+type CollectionSettings struct {
+	MaxTTL  *int  `url:"maxTTL,omitempty"`
+	History *bool `url:"history,omitempty"`
+}
+
+// GetClusterRawJson fetches an arbitrary endpoint of the cluster management
+// API through the Capella proxy.
+func (c *Controller) GetClusterRawJson(
+	ctx context.Context,
+	clusterID, path string,
+) (json.RawMessage, error) {
+	var resp json.RawMessage
+
+	err := c.doBasicReq(ctx, false, "GET", fmt.Sprintf("/v2/databases/%s/proxy%s", clusterID, path), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// CollectionManifestJson is the manifest reported by the server, which is
+// proxied unchanged.
+type CollectionManifestJson = clustercontrol.CollectionManifestJson
+
+func (c *Controller) GetClusterCollectionManifest(
+	ctx context.Context,
+	clusterID, bucketName string,
+) (*CollectionManifestJson, error) {
+	resp := &CollectionManifestJson{}
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes",
+		clusterID, url.PathEscape(bucketName))
+	err := c.doBasicReq(ctx, false, "GET", path, nil, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// EnsureClusterCollectionManifest waits for every node to have a collection
+// manifest with at least the specified uid, failing if the server gives up
+// waiting before then.
+func (c *Controller) EnsureClusterCollectionManifest(
+	ctx context.Context,
+	clusterID, bucketName string,
+	manifestUid uint64,
+) error {
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes/@ensureManifest/%x",
+		clusterID, url.PathEscape(bucketName), manifestUid)
+	return c.doBasicFormReq(ctx, false, "POST", path, url.Values{}, nil)
+}
+
+type QueryResponse struct {
+	Status  string            `json:"status"`
+	Results []json.RawMessage `json:"results"`
+}
+
+// ExecuteClusterQuery executes a SQL++ statement against a cluster, through
+// the query service proxy of the cluster management API.
+func (c *Controller) ExecuteClusterQuery(
+	ctx context.Context,
+	clusterID string,
+	statement string,
+) (*QueryResponse, error) {
+	resp := &QueryResponse{}
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/_p/query/query/service", clusterID)
+	err := c.doBasicFormReq(ctx, false, "POST", path, url.Values{
+		"statement": []string{statement},
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ClusterIndexStatusJson is the status of a single replica of a GSI index.
+type ClusterIndexStatusJson struct {
+	Name       string   `json:"indexName"`
+	Bucket     string   `json:"bucket"`
+	Scope      string   `json:"scope"`
+	Collection string   `json:"collection"`
+	ReplicaID  int      `json:"replicaId"`
+	Status     string   `json:"status"`
+	Progress   int      `json:"progress"`
+	Definition string   `json:"definition"`
+	Hosts      []string `json:"hosts"`
+}
+
+// ListClusterIndexes lists the GSI indexes of a cluster through the index
+// status endpoint of the cluster management API, which does not require the
+// nodes of the cluster to be reachable.
+func (c *Controller) ListClusterIndexes(
+	ctx context.Context,
+	clusterID string,
+) ([]ClusterIndexStatusJson, error) {
+	var resp struct {
+		Indexes []ClusterIndexStatusJson `json:"indexes"`
+	}
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/indexStatus", clusterID)
+	err := c.doBasicReq(ctx, false, "GET", path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Indexes, nil
+}
+
+// AdviseClusterIndexes runs the index advisor for a statement through the
+// query service proxy, returning the advice row.  The statement must already
+// be an ADVISE statement.
+func (c *Controller) AdviseClusterIndexes(
+	ctx context.Context,
+	clusterID string,
+	adviseStatement string,
+) (json.RawMessage, error) {
+	resp, err := c.ExecuteClusterQuery(ctx, clusterID, adviseStatement)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Results) == 0 {
+		return nil, errors.New("index advisor returned no results")
+	}
+
+	return resp.Results[0], nil
+}
+
+type JsLibraryJson struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Scope  string `json:"scope"`
+	Code   string `json:"code"`
+}
+
+func clusterJsLibraryPath(clusterID, bucketName, scopeName, name string) string {
+	path := fmt.Sprintf("/v2/databases/%s/proxy/_p/query/evaluator/v1/libraries/%s",
+		clusterID, url.PathEscape(name))
+	if bucketName != "" {
+		path += "?" + url.Values{
+			"bucket": []string{bucketName},
+			"scope":  []string{scopeName},
+		}.Encode()
+	}
+	return path
+}
+
+func (c *Controller) ListClusterJsLibraries(
+	ctx context.Context,
+	clusterID string,
+) ([]JsLibraryJson, error) {
+	var resp []JsLibraryJson
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/_p/query/evaluator/v1/libraries", clusterID)
+	err := c.doBasicReq(ctx, false, "GET", path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// CreateClusterJsLibrary creates or replaces a javascript library, which is
+// global unless a bucket and scope are specified.
+func (c *Controller) CreateClusterJsLibrary(
+	ctx context.Context,
+	clusterID, bucketName, scopeName, name, code string,
+) error {
+	path := clusterJsLibraryPath(clusterID, bucketName, scopeName, name)
+	return c.doBasicEncodedReq(ctx, false, "POST", path, "application/json", []byte(code), nil)
+}
+
+func (c *Controller) DeleteClusterJsLibrary(
+	ctx context.Context,
+	clusterID, bucketName, scopeName, name string,
+) error {
+	path := clusterJsLibraryPath(clusterID, bucketName, scopeName, name)
+	return c.doBasicReq(ctx, false, "DELETE", path, nil, nil)
+}
+
+func (c *Controller) CreateClusterScope(
+	ctx context.Context,
+	clusterID, bucketName, scopeName string,
+) error {
+	form := url.Values{}
+	form.Set("name", scopeName)
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes",
+		clusterID, url.PathEscape(bucketName))
+	return c.doBasicFormReq(ctx, false, "POST", path, form, nil)
+}
+
+func (c *Controller) DeleteClusterScope(
+	ctx context.Context,
+	clusterID, bucketName, scopeName string,
+) error {
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes/%s",
+		clusterID, url.PathEscape(bucketName), url.PathEscape(scopeName))
+	return c.doBasicReq(ctx, false, "DELETE", path, nil, nil)
+}
+
+func (c *Controller) CreateClusterCollection(
+	ctx context.Context,
+	clusterID, bucketName, scopeName, collectionName string,
+	settings *CollectionSettings,
+) error {
+	form, _ := query.Values(settings)
+	form.Set("name", collectionName)
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes/%s/collections",
+		clusterID, url.PathEscape(bucketName), url.PathEscape(scopeName))
+	return c.doBasicFormReq(ctx, false, "POST", path, form, nil)
+}
+
+func (c *Controller) UpdateClusterCollection(
+	ctx context.Context,
+	clusterID, bucketName, scopeName, collectionName string,
+	settings *CollectionSettings,
+) error {
+	form, _ := query.Values(settings)
+
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes/%s/collections/%s",
+		clusterID, url.PathEscape(bucketName), url.PathEscape(scopeName), url.PathEscape(collectionName))
+	return c.doBasicFormReq(ctx, false, "PATCH", path, form, nil)
+}
+
+func (c *Controller) DeleteClusterCollection(
+	ctx context.Context,
+	clusterID, bucketName, scopeName, collectionName string,
+) error {
+	path := fmt.Sprintf("/v2/databases/%s/proxy/pools/default/buckets/%s/scopes/%s/collections/%s",
+		clusterID, url.PathEscape(bucketName), url.PathEscape(scopeName), url.PathEscape(collectionName))
+	return c.doBasicReq(ctx, false, "DELETE", path, nil, nil)
+}
+
 func (c *Controller) GetTrustedCAsColumnar(
 	ctx context.Context,
 	tenantID, projectID, clusterID string,
@@ -1602,6 +2250,38 @@ func (c *Controller) RedeployCluster(
 	return err
 }
 
+type NodeMaintenanceRequest struct {
+	HostName string `json:"hostname"`
+}
+
+// ReplaceClusterNode triggers a job which replaces the specified node of the
+// cluster with a newly provisioned one.  This uses the internal support api.
+func (c *Controller) ReplaceClusterNode(
+	ctx context.Context,
+	clusterID string,
+	internalSupportToken string,
+	req *NodeMaintenanceRequest,
+) error {
+	path := fmt.Sprintf("/internal/support/clusters/%s/nodes/replace", clusterID)
+	err := c.doTokenRequest(ctx, "POST", path, internalSupportToken, req, nil)
+
+	return err
+}
+
+// RepairClusterNode triggers a job which repairs the specified node of the
+// cluster in place.  This uses the internal support api.
+func (c *Controller) RepairClusterNode(
+	ctx context.Context,
+	clusterID string,
+	internalSupportToken string,
+	req *NodeMaintenanceRequest,
+) error {
+	path := fmt.Sprintf("/internal/support/clusters/%s/nodes/repair", clusterID)
+	err := c.doTokenRequest(ctx, "POST", path, internalSupportToken, req, nil)
+
+	return err
+}
+
 type LoadColumnarSampleBucketRequest struct {
 	SampleName string `url:"sampleName"`
 }
@@ -2,7 +2,11 @@ package capellacontrol_test
 
 import (
 	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/couchbaselabs/cbdinocluster/utils/capellacontrol"
@@ -62,3 +66,44 @@ func TestMultipleControllers(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestControllerCaBundle(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewDevelopment()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	caBundlePath := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caBundlePath, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0644)
+	require.NoError(t, err)
+
+	auth := &capellacontrol.TokenCredentials{AccessKey: "access", SecretKey: "secret"}
+
+	ctrl, err := capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
+		Logger:   logger,
+		Endpoint: server.URL,
+		Auth:     auth,
+	})
+	require.NoError(t, err)
+
+	_, err = ctrl.ListClusterJobs(ctx, "tenant", "project", "cluster")
+	require.Error(t, err)
+
+	ctrl, err = capellacontrol.NewController(ctx, &capellacontrol.ControllerOptions{
+		Logger:       logger,
+		Endpoint:     server.URL,
+		Auth:         auth,
+		CaBundlePath: caBundlePath,
+	})
+	require.NoError(t, err)
+
+	_, err = ctrl.ListClusterJobs(ctx, "tenant", "project", "cluster")
+	require.NoError(t, err)
+}
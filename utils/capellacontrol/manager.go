@@ -240,3 +240,95 @@ func (m *Manager) WaitForServerLogsCollected(
 		return perNode, nil
 	}
 }
+
+// WaitForClusterJobs waits until the cluster has no incomplete jobs, logging
+// the progress of the running jobs as it goes.  Jobs can take a moment to be
+// listed after being triggered, so when no job has been seen yet, this keeps
+// waiting for up to startTimeout before assuming the job already completed.
+func (m *Manager) WaitForClusterJobs(
+	ctx context.Context,
+	tenantID, projectID, clusterID string,
+	startTimeout time.Duration,
+) error {
+	startTime := time.Now()
+	seenJob := false
+
+	for {
+		resp, err := m.Client.ListClusterJobs(ctx, tenantID, projectID, clusterID)
+		if err != nil {
+			return errors.Wrap(err, "failed to list cluster jobs")
+		}
+
+		var runningJobs []*ClusterJobInfo
+		for _, job := range resp.Data {
+			if job.Data.CompletionPercentage < 100 {
+				runningJobs = append(runningJobs, job.Data)
+			}
+		}
+
+		if len(runningJobs) == 0 {
+			if seenJob || time.Since(startTime) > startTimeout {
+				return nil
+			}
+
+			m.Logger.Info("waiting for cluster job to start...")
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		seenJob = true
+		for _, job := range runningJobs {
+			m.Logger.Info("waiting for cluster job...",
+				zap.String("type", job.JobType),
+				zap.Int("progress", job.CompletionPercentage),
+				zap.String("step", job.CurrentStep))
+		}
+
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// WaitForNetworkPeerRequested waits for Capella to request a network peering
+// from the peered network, which is when the peering connection id becomes
+// available.
+func (m *Manager) WaitForNetworkPeerRequested(
+	ctx context.Context,
+	tenantID, projectID, clusterID string,
+	peerID string,
+) (*NetworkPeerInfo, error) {
+	for {
+		peers, err := m.Client.ListNetworkPeers(ctx, tenantID, projectID, clusterID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list network peers")
+		}
+
+		var foundPeer *NetworkPeerInfo
+		for _, peer := range peers.Data {
+			if peer.ID == peerID {
+				foundPeer = peer
+			}
+		}
+
+		if foundPeer == nil {
+			return nil, fmt.Errorf("network peer '%s' disappeared", peerID)
+		}
+
+		if foundPeer.Status.State == "failed" {
+			return nil, fmt.Errorf("network peer failed: %s", foundPeer.Status.Reasoning)
+		}
+
+		if foundPeer.ProviderConfig.ProviderID != "" {
+			return foundPeer, nil
+		}
+
+		m.Logger.Info("waiting for network peering to be requested...",
+			zap.String("peer-id", peerID),
+			zap.String("state", foundPeer.Status.State))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package capellacontrol
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultRequestsPerSecond     = 5
+	DefaultRequestBurst          = 10
+	DefaultMaxConcurrentRequests = 8
+)
+
+type RateLimiterOptions struct {
+	// RequestsPerSecond is the sustained rate that requests are sent at,
+	// or unlimited if zero.
+	RequestsPerSecond float64
+
+	// Burst is how many requests can be sent at once before being limited
+	// to RequestsPerSecond.  Defaults to 1.
+	Burst int
+
+	// MaxConcurrent caps the number of requests in flight at once, or is
+	// unlimited if zero.
+	MaxConcurrent int
+}
+
+// RateLimiter limits the rate of requests to the Capella control plane
+// using a token bucket, and optionally the number of requests in flight.
+// Capella throttles tenants which send too many requests, and parallel CI
+// jobs sharing a tenant otherwise trip it unpredictably.  A RateLimiter
+// can be shared by multiple controllers.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	slots chan struct{}
+
+	lock         sync.Mutex
+	tokens       float64
+	lastRefill   time.Time
+	blockedUntil time.Time
+}
+
+func NewRateLimiter(opts *RateLimiterOptions) *RateLimiter {
+	burst := opts.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	var slots chan struct{}
+	if opts.MaxConcurrent > 0 {
+		slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+
+	return &RateLimiter{
+		rate:       opts.RequestsPerSecond,
+		burst:      float64(burst),
+		slots:      slots,
+		tokens:     float64(burst),
+		lastRefill: time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket, returning how long the caller
+// must wait before the token may be used.  Tokens can be borrowed from
+// the future, which queues concurrent callers in order.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var wait time.Duration
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.lastRefill).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.lastRefill = now
+
+		l.tokens--
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+	if blockedWait := l.blockedUntil.Sub(now); blockedWait > wait {
+		wait = blockedWait
+	}
+
+	return wait
+}
+
+func (l *RateLimiter) unreserve() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.rate > 0 {
+		l.tokens++
+	}
+}
+
+// Acquire waits until a request may be sent, returning a function which
+// must be called once the request has completed.
+func (l *RateLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	wait := l.reserve(time.Now())
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.unreserve()
+			return nil, errors.Wrap(ctx.Err(), "context finished while waiting for rate limiter")
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "context finished while waiting for request slot")
+	}
+
+	var releaseOnce sync.Once
+	return func() {
+		releaseOnce.Do(func() { <-l.slots })
+	}, nil
+}
+
+// Backoff pauses all requests through the limiter for a period, which is
+// used when Capella reports that we are being throttled.
+func (l *RateLimiter) Backoff(period time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	blockedUntil := time.Now().Add(period)
+	if blockedUntil.After(l.blockedUntil) {
+		l.blockedUntil = blockedUntil
+	}
+}
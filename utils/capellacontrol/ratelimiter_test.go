@@ -0,0 +1,42 @@
+package capellacontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	limiter := NewRateLimiter(&RateLimiterOptions{
+		RequestsPerSecond: 20,
+		Burst:             2,
+		MaxConcurrent:     1,
+	})
+
+	startTime := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := limiter.Acquire(ctx)
+		require.NoError(t, err)
+		release()
+	}
+	// 2 requests use the burst, the other 2 wait 50ms each
+	require.GreaterOrEqual(t, time.Since(startTime), 90*time.Millisecond)
+
+	release, err := limiter.Acquire(ctx)
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(timeoutCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+
+	var nilLimiter *RateLimiter
+	release, err = nilLimiter.Acquire(ctx)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,205 @@
+// Package cioutput emits the results of commands in the native forms of CI
+// systems, so that pipelines can consume them without parsing our output.
+package cioutput
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Mode string
+
+const (
+	ModeNone    Mode = ""
+	ModeAuto    Mode = "auto"
+	ModeGitHub  Mode = "github"
+	ModeJenkins Mode = "jenkins"
+)
+
+// DefaultJenkinsPropertiesFile is the file outputs are written to for
+// Jenkins, relative to the workspace, which pipelines can load using
+// readProperties.
+const DefaultJenkinsPropertiesFile = "cbdinocluster.properties"
+
+// Detect identifies the CI system we are running within.
+func Detect() Mode {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		return ModeGitHub
+	}
+	if os.Getenv("JENKINS_URL") != "" {
+		return ModeJenkins
+	}
+	return ModeNone
+}
+
+// Output is a named result of a command.
+type Output struct {
+	Name  string
+	Value string
+
+	// Secret outputs are masked from the logs of the CI system where
+	// it supports doing so.
+	Secret bool
+}
+
+type WriterOptions struct {
+	Mode Mode
+
+	// Commands is where workflow commands are written, which defaults to
+	// stderr.  GitHub reads them from both streams, and keeping them off
+	// stdout leaves it for the output of the command, such as its json or
+	// the cluster id being captured by a script.
+	Commands io.Writer
+
+	// OutputPath overrides the file outputs are written to.  GitHub
+	// defaults to GITHUB_OUTPUT, and Jenkins defaults to a properties file
+	// within WORKSPACE.
+	OutputPath string
+}
+
+type Writer struct {
+	mode       Mode
+	commands   io.Writer
+	outputPath string
+}
+
+func NewWriter(opts *WriterOptions) (*Writer, error) {
+	mode := opts.Mode
+	if mode == ModeAuto {
+		mode = Detect()
+		if mode == ModeNone {
+			return nil, errors.New("failed to detect a supported CI system")
+		}
+	}
+
+	outputPath := opts.OutputPath
+	switch mode {
+	case ModeGitHub:
+		if outputPath == "" {
+			outputPath = os.Getenv("GITHUB_OUTPUT")
+		}
+		if outputPath == "" {
+			return nil, errors.New("GITHUB_OUTPUT is not set, an output file must be specified")
+		}
+	case ModeJenkins:
+		if outputPath == "" {
+			outputPath = filepath.Join(os.Getenv("WORKSPACE"), DefaultJenkinsPropertiesFile)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ci output mode `%s`", opts.Mode)
+	}
+
+	commands := opts.Commands
+	if commands == nil {
+		commands = os.Stderr
+	}
+
+	return &Writer{
+		mode:       mode,
+		commands:   commands,
+		outputPath: outputPath,
+	}, nil
+}
+
+func (w *Writer) Mode() Mode {
+	return w.mode
+}
+
+// Mask prevents a secret from appearing in the logs of the CI system.  This
+// is only supported by GitHub.
+func (w *Writer) Mask(secret string) {
+	if secret == "" || w.mode != ModeGitHub {
+		return
+	}
+
+	fmt.Fprintf(w.commands, "::add-mask::%s\n", secret)
+}
+
+// StartGroup starts a collapsible group of log lines.  This is only
+// supported by GitHub.
+func (w *Writer) StartGroup(title string) {
+	if w.mode != ModeGitHub {
+		return
+	}
+
+	fmt.Fprintf(w.commands, "::group::%s\n", title)
+}
+
+// EndGroup ends the group started by StartGroup.
+func (w *Writer) EndGroup() {
+	if w.mode != ModeGitHub {
+		return
+	}
+
+	fmt.Fprintf(w.commands, "::endgroup::\n")
+}
+
+// SetOutputs makes outputs available to the following steps of the
+// pipeline, masking any secret outputs first.
+func (w *Writer) SetOutputs(outputs []Output) error {
+	for _, output := range outputs {
+		if output.Secret {
+			w.Mask(output.Value)
+		}
+	}
+
+	var sb strings.Builder
+	for _, output := range outputs {
+		switch w.mode {
+		case ModeGitHub:
+			sb.WriteString(formatGitHubOutput(output.Name, output.Value))
+		case ModeJenkins:
+			sb.WriteString(formatJenkinsProperty(output.Name, output.Value))
+		}
+	}
+
+	// outputs are appended, so that the outputs of multiple commands run
+	// by the same step are all kept
+	file, err := os.OpenFile(w.outputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open output file")
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(sb.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to write outputs")
+	}
+
+	return nil
+}
+
+func formatGitHubOutput(name, value string) string {
+	if !strings.ContainsAny(value, "\r\n") {
+		return fmt.Sprintf("%s=%s\n", name, value)
+	}
+
+	// multi-line values must use a delimiter which cannot appear in them
+	delimBytes := make([]byte, 8)
+	_, _ = rand.Read(delimBytes)
+	delim := "ghadelimiter_" + hex.EncodeToString(delimBytes)
+
+	return fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delim, value, delim)
+}
+
+var jenkinsPropertyEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"=", `\=`,
+	":", `\:`,
+)
+
+func formatJenkinsProperty(name, value string) string {
+	return fmt.Sprintf("%s=%s\n",
+		jenkinsPropertyEscaper.Replace(name),
+		jenkinsPropertyEscaper.Replace(value))
+}
@@ -0,0 +1,101 @@
+package cioutput
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubOutputs(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "github_output")
+	var commands bytes.Buffer
+
+	w, err := NewWriter(&WriterOptions{
+		Mode:       ModeGitHub,
+		Commands:   &commands,
+		OutputPath: outputPath,
+	})
+	require.NoError(t, err)
+
+	w.StartGroup("allocating cluster")
+	w.EndGroup()
+
+	err = w.SetOutputs([]Output{
+		{Name: "cluster_id", Value: "abc"},
+		{Name: "password", Value: "hunter2", Secret: true},
+		{Name: "notes", Value: "line1\nline2"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t,
+		"::group::allocating cluster\n::endgroup::\n::add-mask::hunter2\n",
+		commands.String())
+
+	outputBytes, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(outputBytes), "\n")
+	require.Equal(t, "cluster_id=abc", lines[0])
+	require.Equal(t, "password=hunter2", lines[1])
+	require.True(t, strings.HasPrefix(lines[2], "notes<<ghadelimiter_"))
+	require.Equal(t, "line1", lines[3])
+	require.Equal(t, "line2", lines[4])
+	require.Equal(t, strings.TrimPrefix(lines[2], "notes<<"), lines[5])
+}
+
+func TestJenkinsOutputs(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out.properties")
+	var commands bytes.Buffer
+
+	w, err := NewWriter(&WriterOptions{
+		Mode:       ModeJenkins,
+		Commands:   &commands,
+		OutputPath: outputPath,
+	})
+	require.NoError(t, err)
+
+	w.StartGroup("ignored")
+	err = w.SetOutputs([]Output{
+		{Name: "connstr", Value: "couchbase://10.0.0.1"},
+		{Name: "password", Value: "a=b", Secret: true},
+	})
+	require.NoError(t, err)
+
+	// jenkins has no workflow commands to write
+	require.Empty(t, commands.String())
+
+	outputBytes, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	require.Equal(t, "connstr=couchbase\\://10.0.0.1\npassword=a\\=b\n", string(outputBytes))
+}
+
+func TestAutoMode(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("JENKINS_URL", "")
+
+	_, err := NewWriter(&WriterOptions{Mode: ModeAuto})
+	require.Error(t, err)
+
+	t.Setenv("JENKINS_URL", "https://jenkins.example.com")
+	t.Setenv("WORKSPACE", "/workspace")
+
+	w, err := NewWriter(&WriterOptions{Mode: ModeAuto})
+	require.NoError(t, err)
+	require.Equal(t, ModeJenkins, w.Mode())
+	require.Equal(t, "/workspace/"+DefaultJenkinsPropertiesFile, w.outputPath)
+}
+
+func TestCommandsDefaultToStderr(t *testing.T) {
+	w, err := NewWriter(&WriterOptions{
+		Mode:       ModeGitHub,
+		OutputPath: filepath.Join(t.TempDir(), "github_output"),
+	})
+	require.NoError(t, err)
+
+	// stdout is left for the output of the command
+	require.Equal(t, os.Stderr, w.commands)
+}
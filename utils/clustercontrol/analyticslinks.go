@@ -0,0 +1,53 @@
+package clustercontrol
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// The analytics service is reached through the ns_server proxy, which avoids
+// needing to know which nodes are running it.
+const analyticsProxyPath = "/_p/cbas"
+
+type CreateAnalyticsS3LinkOptions struct {
+	// Scope is the analytics scope (dataverse) of the link, which defaults
+	// to `Default`.
+	Scope string
+	Name  string
+
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+func analyticsLinkPath(scope, name string) string {
+	if scope == "" {
+		scope = "Default"
+	}
+
+	return fmt.Sprintf("%s/analytics/link/%s/%s",
+		analyticsProxyPath,
+		url.PathEscape(scope),
+		url.PathEscape(name))
+}
+
+// CreateAnalyticsS3Link creates an external analytics link to an S3 bucket,
+// or to S3 compatible storage when an endpoint is specified.
+func (c *Controller) CreateAnalyticsS3Link(ctx context.Context, opts *CreateAnalyticsS3LinkOptions) error {
+	form := make(url.Values)
+	form.Add("type", "s3")
+	form.Add("region", opts.Region)
+	form.Add("accessKeyId", opts.AccessKeyID)
+	form.Add("secretAccessKey", opts.SecretAccessKey)
+	if opts.Endpoint != "" {
+		form.Add("serviceEndpoint", opts.Endpoint)
+	}
+
+	return c.doFormPost(ctx, analyticsLinkPath(opts.Scope, opts.Name), form, false, nil)
+}
+
+func (c *Controller) DropAnalyticsLink(ctx context.Context, scope, name string) error {
+	return c.doDelete(ctx, analyticsLinkPath(scope, name), nil)
+}
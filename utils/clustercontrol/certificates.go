@@ -0,0 +1,19 @@
+package clustercontrol
+
+import (
+	"context"
+	"net/url"
+)
+
+// LoadTrustedCAs loads the CA certificates which have been placed in the
+// inbox/CA directory of the node into the trusted CAs of the cluster.
+func (c *Controller) LoadTrustedCAs(ctx context.Context) error {
+	return c.doFormPost(ctx, "/node/controller/loadTrustedCAs", make(url.Values), false, nil)
+}
+
+// ReloadNodeCertificate replaces the certificate of the node with the
+// chain.pem and pkey.key which have been placed in its inbox directory.
+// This must be sent to the node whose certificate is being replaced.
+func (c *Controller) ReloadNodeCertificate(ctx context.Context) error {
+	return c.doFormPost(ctx, "/node/controller/reloadCertificate", make(url.Values), false, nil)
+}
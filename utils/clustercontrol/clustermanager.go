@@ -16,6 +16,10 @@ type SetupNewClusterNodeOptions struct {
 	Address     string
 	ServerGroup string
 	Services    []string
+
+	// Endpoint optionally overrides the management endpoint used to reach
+	// the node, for when Address is not directly reachable.
+	Endpoint string
 }
 
 type SetupNewClusterOptions struct {
@@ -38,6 +42,9 @@ func (m *ClusterManager) SetupNewCluster(ctx context.Context, opts *SetupNewClus
 	firstNodeAddress := firstNode.Address
 
 	firstNodeEndpoint := fmt.Sprintf("http://%s:%d", firstNodeAddress, 8091)
+	if firstNode.Endpoint != "" {
+		firstNodeEndpoint = firstNode.Endpoint
+	}
 	firstNodeMgr := &NodeManager{
 		Endpoint: firstNodeEndpoint,
 	}
@@ -11,16 +11,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/couchbaselabs/cbdinocluster/utils/httpdebug"
 	"github.com/google/go-querystring/query"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 type Controller struct {
 	Endpoint string
 }
 
+// httpTransport is the transport used for all requests to cluster nodes.
+var httpTransport http.RoundTripper = http.DefaultTransport
+
+// EnableHttpLogging enables logging of all requests made to cluster nodes
+// and their responses at debug level, with any credentials redacted.
+func EnableHttpLogging(logger *zap.Logger) {
+	httpTransport = httpdebug.NewTransport(logger, http.DefaultTransport)
+}
+
 func (c *Controller) doReq(ctx context.Context, req *http.Request, out interface{}) error {
-	client := &http.Client{}
+	client := &http.Client{
+		Transport: httpTransport,
+	}
 
 	req.SetBasicAuth("Administrator", "password")
 
@@ -152,6 +165,37 @@ func (c *Controller) doJsonPost(ctx context.Context, path string, data any, allo
 	return c.doJsonReq(ctx, http.MethodPost, path, data, allowRetries, out)
 }
 
+// GetRawJson fetches an arbitrary management endpoint without retrying,
+// which is used by tools that inspect endpoints that may not exist.
+func (c *Controller) GetRawJson(ctx context.Context, path string) (json.RawMessage, error) {
+	var resp json.RawMessage
+	err := c.doRetriableReq(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+path, nil)
+	}, 0, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type PoolsInfo struct {
+	IsEnterprise          bool   `json:"isEnterprise"`
+	ImplementationVersion string `json:"implementationVersion"`
+}
+
+// GetPoolsInfo returns information about the node which is available before
+// it has been initialized.
+func (c *Controller) GetPoolsInfo(ctx context.Context) (*PoolsInfo, error) {
+	resp := &PoolsInfo{}
+	err := c.doGet(ctx, "/pools", resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 func (c *Controller) Ping(ctx context.Context) error {
 	return c.doRetriableReq(ctx, func() (*http.Request, error) {
 		return http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+"/pools", nil)
@@ -242,6 +286,81 @@ func (c *Controller) DisableUnusedExternalListeners(ctx context.Context) error {
 	return c.doFormPost(ctx, "/node/controller/disableUnusedExternalListeners", url.Values{}, true, nil)
 }
 
+// AlternateAddressPorts are the service ports of a node which can be given
+// an alternate address, keyed by the name ns_server uses for them.
+var AlternateAddressPorts = map[string]int{
+	"mgmt":              8091,
+	"mgmtSSL":           18091,
+	"capi":              8092,
+	"capiSSL":           18092,
+	"n1ql":              8093,
+	"n1qlSSL":           18093,
+	"fts":               8094,
+	"ftsSSL":            18094,
+	"cbas":              8095,
+	"cbasSSL":           18095,
+	"kv":                11210,
+	"kvSSL":             11207,
+	"eventingAdminPort": 8096,
+}
+
+type SetupAlternateAddressesOptions struct {
+	Hostname string
+
+	// Ports maps ns_server port names (mgmt, kv, n1qlSSL, ...) to the
+	// externally reachable port for that service.
+	Ports map[string]int
+}
+
+func (c *Controller) SetupAlternateAddresses(ctx context.Context, opts *SetupAlternateAddressesOptions) error {
+	form := make(url.Values)
+	form.Add("hostname", opts.Hostname)
+	for portName, port := range opts.Ports {
+		form.Add(portName, fmt.Sprintf("%d", port))
+	}
+	return c.doFormPut(ctx, "/node/controller/setupAlternateAddresses/external", form, true, nil)
+}
+
+// GetLocalAlternateAddresses returns the external alternate addresses of the
+// node, or nil if it has none.
+func (c *Controller) GetLocalAlternateAddresses(ctx context.Context) (*SetupAlternateAddressesOptions, error) {
+	var resp struct {
+		Nodes []struct {
+			ThisNode           bool `json:"thisNode"`
+			AlternateAddresses struct {
+				External *struct {
+					Hostname string         `json:"hostname"`
+					Ports    map[string]int `json:"ports"`
+				} `json:"external"`
+			} `json:"alternateAddresses"`
+		} `json:"nodes"`
+	}
+	err := c.doGet(ctx, "/pools/default", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range resp.Nodes {
+		if node.ThisNode {
+			external := node.AlternateAddresses.External
+			if external == nil {
+				return nil, nil
+			}
+
+			return &SetupAlternateAddressesOptions{
+				Hostname: external.Hostname,
+				Ports:    external.Ports,
+			}, nil
+		}
+	}
+
+	return nil, errors.New("no node was marked as this node")
+}
+
+func (c *Controller) RemoveAlternateAddresses(ctx context.Context) error {
+	return c.doDelete(ctx, "/node/controller/setupAlternateAddresses/external", nil)
+}
+
 type UpdateIndexSettingsOptions struct {
 	StorageMode string
 }
@@ -380,8 +499,10 @@ func (c *Controller) addServerGroup(ctx context.Context, groupName string) (*ser
 }
 
 func (c *Controller) getAddNodePath(ctx context.Context, groupName string) (string, error) {
+	// the server group apis are only available in enterprise edition, so we
+	// avoid them entirely when no server group is needed.
 	if groupName == "" {
-		return "/pools/default/serverGroups/0/addNode", nil
+		return "/controller/addNode", nil
 	}
 
 	groups, err := c.getServerGroups(ctx)
@@ -418,15 +539,25 @@ func (c *Controller) AddNode(ctx context.Context, opts *AddNodeOptions) error {
 
 type LocalInfo struct {
 	OTPNode  string
+	NodeUUID string
 	Services []string
+
+	// Version is the version reported by the node, which takes the form
+	// `7.2.0-5325-enterprise`.
+	Version string
+
+	ServerGroup string
 }
 
 func (c *Controller) GetLocalInfo(ctx context.Context) (*LocalInfo, error) {
 	var resp struct {
 		Nodes []struct {
-			ThisNode bool     `json:"thisNode"`
-			OTPNode  string   `json:"otpNode"`
-			Services []string `json:"services"`
+			ThisNode    bool     `json:"thisNode"`
+			OTPNode     string   `json:"otpNode"`
+			NodeUUID    string   `json:"nodeUUID"`
+			Services    []string `json:"services"`
+			Version     string   `json:"version"`
+			ServerGroup string   `json:"serverGroup"`
 		} `json:"nodes"`
 	}
 	err := c.doGet(ctx, "/pools/default", &resp)
@@ -437,8 +568,11 @@ func (c *Controller) GetLocalInfo(ctx context.Context) (*LocalInfo, error) {
 	for _, node := range resp.Nodes {
 		if node.ThisNode {
 			return &LocalInfo{
-				OTPNode:  node.OTPNode,
-				Services: node.Services,
+				OTPNode:     node.OTPNode,
+				NodeUUID:    node.NodeUUID,
+				Services:    node.Services,
+				Version:     node.Version,
+				ServerGroup: node.ServerGroup,
 			}, nil
 		}
 	}
@@ -465,6 +599,90 @@ func (c *Controller) ListNodeOTPs(ctx context.Context) ([]string, error) {
 	return nodeOtps, nil
 }
 
+type BucketNodeStatus struct {
+	Hostname string `json:"hostname"`
+
+	// Status is `warmup` while the bucket is being loaded on the node, then
+	// `healthy` once it is ready to serve operations.
+	Status string `json:"status"`
+}
+
+type BucketStatus struct {
+	Name  string             `json:"name"`
+	Nodes []BucketNodeStatus `json:"nodes"`
+}
+
+func (c *Controller) ListBucketStatuses(ctx context.Context) ([]BucketStatus, error) {
+	var resp []BucketStatus
+	err := c.doGet(ctx, "/pools/default/buckets", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetBucketStatus fetches the status of a single bucket, where the status of
+// each node reflects whether the bucket has warmed up on that node.
+func (c *Controller) GetBucketStatus(ctx context.Context, bucketName string) (*BucketStatus, error) {
+	var resp BucketStatus
+	err := c.doGet(ctx, "/pools/default/buckets/"+url.PathEscape(bucketName), &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+type NodeStats struct {
+	Hostname string `json:"hostname"`
+
+	SystemStats struct {
+		CpuUtilizationRate float64 `json:"cpu_utilization_rate"`
+		MemTotal           int64   `json:"mem_total"`
+		MemFree            int64   `json:"mem_free"`
+	} `json:"systemStats"`
+
+	InterestingStats struct {
+		CurrItems               int64   `json:"curr_items"`
+		Ops                     float64 `json:"ops"`
+		MemUsed                 int64   `json:"mem_used"`
+		CouchDocsActualDiskSize int64   `json:"couch_docs_actual_disk_size"`
+	} `json:"interestingStats"`
+}
+
+func (c *Controller) ListNodeStats(ctx context.Context) ([]NodeStats, error) {
+	var resp struct {
+		Nodes []NodeStats `json:"nodes"`
+	}
+	err := c.doGet(ctx, "/pools/default", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Nodes, nil
+}
+
+// MemoryQuotas are the per-node memory quotas of each service, which apply
+// to every node running the service.
+type MemoryQuotas struct {
+	KvMemoryQuotaMB       int `json:"memoryQuota"`
+	IndexMemoryQuotaMB    int `json:"indexMemoryQuota"`
+	FtsMemoryQuotaMB      int `json:"ftsMemoryQuota"`
+	CbasMemoryQuotaMB     int `json:"cbasMemoryQuota"`
+	EventingMemoryQuotaMB int `json:"eventingMemoryQuota"`
+}
+
+func (c *Controller) GetMemoryQuotas(ctx context.Context) (*MemoryQuotas, error) {
+	var resp MemoryQuotas
+	err := c.doGet(ctx, "/pools/default", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
 type BeginRebalanceOptions struct {
 	KnownNodeOTPs   []string
 	EjectedNodeOTPs []string
@@ -667,20 +885,26 @@ func (c *Controller) ListBuckets(ctx context.Context) ([]ListBucketsResponse_Buc
 }
 
 type CreateBucketRequest struct {
-	Name                   string `url:"name"`
-	BucketType             string `url:"bucketType"`
-	StorageBackend         string `url:"storageBackend"`
-	AutoCompactionDefined  bool   `url:"autoCompactionDefined"`
-	EvictionPolicy         string `url:"evictionPolicy"`
-	ThreadsNumber          int    `url:"threadsNumber"`
-	ReplicaNumber          int    `url:"replicaNumber"`
-	DurabilityMinLevel     string `url:"durabilityMinLevel"`
-	CompressionMode        string `url:"compressionMode"`
-	MaxTTL                 int    `url:"maxTTL"`
-	ReplicaIndex           int    `url:"replicaIndex"`
-	ConflictResolutionType string `url:"conflictResolutionType"`
-	RamQuotaMB             int    `url:"ramQuotaMB"`
-	FlushEnabled           bool   `url:"flushEnabled,int"`
+	Name         string `url:"name"`
+	BucketType   string `url:"bucketType"`
+	RamQuotaMB   int    `url:"ramQuotaMB"`
+	FlushEnabled bool   `url:"flushEnabled,int"`
+
+	// The following only apply to couchbase and ephemeral buckets, and are
+	// omitted when unset, since memcached buckets reject them.
+	StorageBackend         string `url:"storageBackend,omitempty"`
+	AutoCompactionDefined  *bool  `url:"autoCompactionDefined,omitempty"`
+	EvictionPolicy         string `url:"evictionPolicy,omitempty"`
+	ThreadsNumber          int    `url:"threadsNumber,omitempty"`
+	ReplicaNumber          *int   `url:"replicaNumber,omitempty"`
+	DurabilityMinLevel     string `url:"durabilityMinLevel,omitempty"`
+	CompressionMode        string `url:"compressionMode,omitempty"`
+	MaxTTL                 int    `url:"maxTTL,omitempty"`
+	ReplicaIndex           *int   `url:"replicaIndex,omitempty"`
+	ConflictResolutionType string `url:"conflictResolutionType,omitempty"`
+
+	HistoryRetentionBytes   int `url:"historyRetentionBytes,omitempty"`
+	HistoryRetentionSeconds int `url:"historyRetentionSeconds,omitempty"`
 }
 
 func (c *Controller) CreateBucket(ctx context.Context, req *CreateBucketRequest) error {
@@ -704,6 +928,69 @@ func (c *Controller) DeleteBucket(ctx context.Context, bucketName string) error
 	return nil
 }
 
+type CollectionManifestJson struct {
+	UID    string                         `json:"uid"`
+	Scopes []CollectionManifestJson_Scope `json:"scopes"`
+}
+
+type CollectionManifestJson_Scope struct {
+	UID         string                              `json:"uid"`
+	Name        string                              `json:"name"`
+	Collections []CollectionManifestJson_Collection `json:"collections"`
+}
+
+type CollectionManifestJson_Collection struct {
+	UID     string `json:"uid"`
+	Name    string `json:"name"`
+	MaxTTL  int    `json:"maxTTL"`
+	History bool   `json:"history"`
+}
+
+func (c *Controller) GetCollectionManifest(ctx context.Context, bucketName string) (*CollectionManifestJson, error) {
+	resp := &CollectionManifestJson{}
+
+	path := fmt.Sprintf("/pools/default/buckets/%s/scopes", url.PathEscape(bucketName))
+	err := c.doGet(ctx, path, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// EnsureCollectionManifest waits for every node to have a collection
+// manifest with at least the specified uid, failing if the server gives up
+// waiting before then.
+func (c *Controller) EnsureCollectionManifest(ctx context.Context, bucketName string, manifestUid uint64) error {
+	path := fmt.Sprintf("/pools/default/buckets/%s/scopes/@ensureManifest/%x",
+		url.PathEscape(bucketName), manifestUid)
+	return c.doFormPost(ctx, path, url.Values{}, false, nil)
+}
+
+// CollectionSettings are the settings of a collection.  Unset fields use the
+// defaults of the bucket, or are left unchanged when updating.
+type CollectionSettings struct {
+	MaxTTL  *int  `url:"maxTTL,omitempty"`
+	History *bool `url:"history,omitempty"`
+}
+
+func (c *Controller) CreateCollection(ctx context.Context, bucketName, scopeName, collectionName string, settings *CollectionSettings) error {
+	form, _ := query.Values(settings)
+	form.Set("name", collectionName)
+
+	path := fmt.Sprintf("/pools/default/buckets/%s/scopes/%s/collections",
+		url.PathEscape(bucketName), url.PathEscape(scopeName))
+	return c.doFormPost(ctx, path, form, false, nil)
+}
+
+func (c *Controller) UpdateCollection(ctx context.Context, bucketName, scopeName, collectionName string, settings *CollectionSettings) error {
+	form, _ := query.Values(settings)
+
+	path := fmt.Sprintf("/pools/default/buckets/%s/scopes/%s/collections/%s",
+		url.PathEscape(bucketName), url.PathEscape(scopeName), url.PathEscape(collectionName))
+	return c.doFormReq(ctx, http.MethodPatch, path, form, true, nil)
+}
+
 func (c *Controller) LoadSampleBucket(ctx context.Context, bucketName string) error {
 	samples := []string{
 		bucketName,
@@ -0,0 +1,74 @@
+package clustercontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+// JsonDocumentFlags are the flags the SDKs use for JSON documents.
+const JsonDocumentFlags = 0x02000006
+
+func documentPath(bucketName string, key string) string {
+	return fmt.Sprintf("/pools/default/buckets/%s/docs/%s",
+		url.PathEscape(bucketName),
+		url.PathEscape(key))
+}
+
+// UpsertDocument writes a JSON document to the default collection of a
+// bucket.  This goes through the management API and is only intended for
+// writing small numbers of documents, such as in tests of the cluster.
+func (c *Controller) UpsertDocument(ctx context.Context, bucketName string, key string, value json.RawMessage) error {
+	form := make(url.Values)
+	form.Add("value", string(value))
+	form.Add("flags", fmt.Sprintf("%d", JsonDocumentFlags))
+
+	return c.doFormPost(ctx, documentPath(bucketName, key), form, true, nil)
+}
+
+// GetDocument reads a JSON document from the default collection of a bucket,
+// returning ErrDocumentNotFound if the document does not exist.
+func (c *Controller) GetDocument(ctx context.Context, bucketName string, key string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+documentPath(bucketName, key), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+
+	req.SetBasicAuth("Administrator", "password")
+
+	client := &http.Client{
+		Transport: httpTransport,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrDocumentNotFound
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bytes, _ := io.ReadAll(resp.Body)
+
+		return nil, fmt.Errorf("non-200 status code encountered: %d %s", resp.StatusCode, bytes)
+	}
+
+	var doc struct {
+		Json json.RawMessage `json:"json"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode response")
+	}
+
+	return doc.Json, nil
+}
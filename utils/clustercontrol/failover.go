@@ -0,0 +1,75 @@
+package clustercontrol
+
+import (
+	"context"
+	"net/url"
+)
+
+type FailoverOptions struct {
+	OTPNode string
+
+	// Graceful moves the active vbuckets off of the node before it is failed
+	// over, rather than promoting the replicas on the other nodes.
+	Graceful bool
+}
+
+func (c *Controller) Failover(ctx context.Context, opts *FailoverOptions) error {
+	form := make(url.Values)
+	form.Add("otpNode", opts.OTPNode)
+
+	if opts.Graceful {
+		return c.doFormPost(ctx, "/controller/startGracefulFailover", form, false, nil)
+	}
+
+	return c.doFormPost(ctx, "/controller/failOver", form, false, nil)
+}
+
+// SetRecoveryType marks a failed over node to be added back to the cluster
+// by the next rebalance.  The recovery type is either `delta` or `full`.
+func (c *Controller) SetRecoveryType(ctx context.Context, otpNode string, recoveryType string) error {
+	form := make(url.Values)
+	form.Add("otpNode", otpNode)
+	form.Add("recoveryType", recoveryType)
+
+	return c.doFormPost(ctx, "/controller/setRecoveryType", form, true, nil)
+}
+
+type AutoFailoverSettings struct {
+	Enabled  bool `json:"enabled"`
+	Timeout  int  `json:"timeout"`
+	Count    int  `json:"count"`
+	MaxCount int  `json:"maxCount"`
+}
+
+func (c *Controller) GetAutoFailoverSettings(ctx context.Context) (*AutoFailoverSettings, error) {
+	var resp AutoFailoverSettings
+	err := c.doGet(ctx, "/settings/autoFailover", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// NodeStatus is the health and membership of a node, as seen by the node
+// which is asked.  Status is `healthy`, `unhealthy` or `warmup`, and
+// ClusterMembership is `active`, `inactiveAdded` or `inactiveFailed`.
+type NodeStatus struct {
+	OTPNode           string   `json:"otpNode"`
+	Hostname          string   `json:"hostname"`
+	Status            string   `json:"status"`
+	ClusterMembership string   `json:"clusterMembership"`
+	Services          []string `json:"services"`
+}
+
+func (c *Controller) ListNodeStatuses(ctx context.Context) ([]NodeStatus, error) {
+	var resp struct {
+		Nodes []NodeStatus `json:"nodes"`
+	}
+	err := c.doGet(ctx, "/pools/default", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Nodes, nil
+}
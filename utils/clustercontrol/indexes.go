@@ -0,0 +1,31 @@
+package clustercontrol
+
+import (
+	"context"
+)
+
+// IndexStatusJson is the status of a single replica of a GSI index, as
+// reported by the management API of the cluster.
+type IndexStatusJson struct {
+	Name       string   `json:"indexName"`
+	Bucket     string   `json:"bucket"`
+	Scope      string   `json:"scope"`
+	Collection string   `json:"collection"`
+	ReplicaID  int      `json:"replicaId"`
+	Status     string   `json:"status"`
+	Progress   int      `json:"progress"`
+	Definition string   `json:"definition"`
+	Hosts      []string `json:"hosts"`
+}
+
+func (c *Controller) ListIndexStatuses(ctx context.Context) ([]IndexStatusJson, error) {
+	var resp struct {
+		Indexes []IndexStatusJson `json:"indexes"`
+	}
+	err := c.doGet(ctx, "/indexStatus", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Indexes, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 )
 
 type NodeManager struct {
@@ -38,6 +39,81 @@ func (m *NodeManager) WaitForOnline(ctx context.Context) error {
 	return nil
 }
 
+// bucketWarmupTimeout bounds how long WaitForBucketsReady waits when the
+// context has no deadline of its own.
+const bucketWarmupTimeout = 5 * time.Minute
+
+// WaitForBucketsReady waits until every bucket has finished warming up on
+// every active data node, which happens after the data service on a node is
+// restarted.  Until then, operations against the bucket can fail with
+// transient errors.  Nodes which are down, paused or failed over are not
+// waited for, as their buckets cannot warm up until they return.
+func (m *NodeManager) WaitForBucketsReady(ctx context.Context) error {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bucketWarmupTimeout)
+		defer cancel()
+	}
+
+	for {
+		allReady, err := m.bucketsReady(ctx)
+		if err != nil {
+			return err
+		}
+
+		if allReady {
+			break
+		}
+
+		select {
+		case <-time.After(1 * time.Second):
+			// continue
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "context finished while waiting for buckets to warm up")
+		}
+	}
+
+	return nil
+}
+
+func (m *NodeManager) bucketsReady(ctx context.Context) (bool, error) {
+	c := m.Controller()
+
+	nodes, err := c.ListNodeStatuses(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list node statuses")
+	}
+
+	var kvNodes []string
+	for _, node := range nodes {
+		if node.ClusterMembership == "active" &&
+			node.Status != "unhealthy" &&
+			slices.Contains(node.Services, "kv") {
+			kvNodes = append(kvNodes, node.Hostname)
+		}
+	}
+
+	buckets, err := c.ListBucketStatuses(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list buckets")
+	}
+
+	for _, bucket := range buckets {
+		bucketStatus, err := c.GetBucketStatus(ctx, bucket.Name)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get status of bucket `%s`", bucket.Name)
+		}
+
+		for _, node := range bucketStatus.Nodes {
+			if slices.Contains(kvNodes, node.Hostname) && node.Status != "healthy" {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
 type AnalyticsSettings struct {
 	BlobStorageRegion        string
 	BlobStoragePrefix        string
@@ -57,8 +133,8 @@ type SetupOneNodeClusterOptions struct {
 	Username string
 	Password string
 
-	Services    []string
-	ServerGroup string
+	Services          []string
+	ServerGroup       string
 	AnalyticsSettings AnalyticsSettings
 }
 
@@ -78,7 +154,27 @@ func (m *NodeManager) SetupOneNodeCluster(ctx context.Context, opts *SetupOneNod
 		}
 	*/
 
-	err := c.UpdateDefaultPool(ctx, &UpdateDefaultPoolOptions{
+	poolsInfo, err := c.GetPoolsInfo(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to identify server edition")
+	}
+
+	// Community Edition does not support analytics, eventing or server groups,
+	// so we fail early with a clear error rather than partway through setup.
+	isEnterprise := poolsInfo.IsEnterprise
+	if !isEnterprise {
+		if opts.CbasMemoryQuotaMB > 0 || opts.AnalyticsSettings.BlobStorageBucket != "" {
+			return errors.New("analytics is not supported by community edition")
+		}
+		if opts.EventingMemoryQuotaMB > 0 {
+			return errors.New("eventing is not supported by community edition")
+		}
+		if opts.ServerGroup != "" {
+			return errors.New("server groups are not supported by community edition")
+		}
+	}
+
+	err = c.UpdateDefaultPool(ctx, &UpdateDefaultPoolOptions{
 		ClusterName:           "test-cluster",
 		KvMemoryQuotaMB:       opts.KvMemoryQuotaMB,
 		IndexMemoryQuotaMB:    opts.IndexMemoryQuotaMB,
@@ -130,16 +226,23 @@ func (m *NodeManager) SetupOneNodeCluster(ctx context.Context, opts *SetupOneNod
 		return errors.Wrap(err, "failed to disable unused external listeners")
 	}
 
+	// plasma is only available in enterprise edition, and community edition
+	// only supports forestdb.  Some older enterprise versions also lack
+	// plasma, so we still fall back to forestdb if it is rejected.
+	indexStorageMode := "plasma"
+	if !isEnterprise {
+		indexStorageMode = "forestdb"
+	}
 	err = c.UpdateIndexSettings(ctx, &UpdateIndexSettingsOptions{
-		StorageMode: "plasma",
+		StorageMode: indexStorageMode,
 	})
-	if err != nil {
+	if err != nil && indexStorageMode != "forestdb" {
 		err = c.UpdateIndexSettings(ctx, &UpdateIndexSettingsOptions{
 			StorageMode: "forestdb",
 		})
-		if err != nil {
-			return errors.Wrap(err, "failed to setup net config")
-		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to configure index storage mode")
 	}
 
 	err = c.UpdateWebSettings(ctx, &UpdateWebSettingsOptions{
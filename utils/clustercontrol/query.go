@@ -0,0 +1,49 @@
+package clustercontrol
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Scan consistencies supported by the query service.
+const (
+	ScanConsistencyNotBounded  = "not_bounded"
+	ScanConsistencyRequestPlus = "request_plus"
+)
+
+type QueryOptions struct {
+	Statement       string
+	ScanConsistency string
+
+	// NamedArgs are the values of the named parameters of the statement,
+	// keyed by their name without the leading `$`.
+	NamedArgs map[string]interface{}
+}
+
+type queryResponseJson struct {
+	Results []json.RawMessage `json:"results"`
+}
+
+// Query executes a statement against the query service of a single node,
+// which unlike the other methods requires the controller endpoint to be the
+// query service endpoint of that node (port 8093) rather than the management
+// endpoint, so that the node which serves the query is known.
+func (c *Controller) Query(ctx context.Context, opts *QueryOptions) ([]json.RawMessage, error) {
+	req := map[string]interface{}{
+		"statement": opts.Statement,
+	}
+	if opts.ScanConsistency != "" {
+		req["scan_consistency"] = opts.ScanConsistency
+	}
+	for argName, argValue := range opts.NamedArgs {
+		req["$"+argName] = argValue
+	}
+
+	var resp queryResponseJson
+	err := c.doJsonPost(ctx, "/query/service", req, false, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Results, nil
+}
@@ -0,0 +1,43 @@
+package clustercontrol
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+)
+
+// ServerlessLimits are the throttle and storage limits of the services of a
+// bucket, which are only available on nodes running the serverless profile.
+// Unset fields keep their current values.
+type ServerlessLimits struct {
+	DataThrottleLimit   int `url:"dataThrottleLimit,omitempty"`
+	IndexThrottleLimit  int `url:"indexThrottleLimit,omitempty"`
+	SearchThrottleLimit int `url:"searchThrottleLimit,omitempty"`
+	QueryThrottleLimit  int `url:"queryThrottleLimit,omitempty"`
+	DataStorageLimit    int `url:"dataStorageLimit,omitempty"`
+	IndexStorageLimit   int `url:"indexStorageLimit,omitempty"`
+	SearchStorageLimit  int `url:"searchStorageLimit,omitempty"`
+}
+
+// UpdateServerlessSettings changes the default limits applied to buckets
+// which are created afterwards.
+func (c *Controller) UpdateServerlessSettings(ctx context.Context, limits *ServerlessLimits) error {
+	form, _ := query.Values(limits)
+	return c.doFormPost(ctx, "/settings/serverless", form, true, nil)
+}
+
+func (c *Controller) UpdateBucketLimits(ctx context.Context, bucketName string, limits *ServerlessLimits) error {
+	form, _ := query.Values(limits)
+	path := fmt.Sprintf("/pools/default/buckets/%s", url.PathEscape(bucketName))
+	return c.doFormPost(ctx, path, form, true, nil)
+}
+
+// SetEnforceLimits controls whether the cluster enforces the limits of its
+// buckets and users, rather than only reporting them.
+func (c *Controller) SetEnforceLimits(ctx context.Context, enforceLimits bool) error {
+	form := make(url.Values)
+	form.Add("enforceLimits", fmt.Sprintf("%t", enforceLimits))
+	return c.doFormPost(ctx, "/internalSettings", form, true, nil)
+}
@@ -0,0 +1,32 @@
+package clustercontrol
+
+import (
+	"context"
+
+	"github.com/google/go-querystring/query"
+)
+
+// QueryTransactionSettings are the transaction settings of the query
+// service.  Unset fields keep their current values.
+type QueryTransactionSettings struct {
+	TxTimeout             string `url:"queryTxTimeout,omitempty" json:"queryTxTimeout,omitempty"`
+	CleanupWindow         string `url:"queryCleanupWindow,omitempty" json:"queryCleanupWindow,omitempty"`
+	CleanupClientAttempts *bool  `url:"queryCleanupClientAttempts,omitempty" json:"queryCleanupClientAttempts,omitempty"`
+	CleanupLostAttempts   *bool  `url:"queryCleanupLostAttempts,omitempty" json:"queryCleanupLostAttempts,omitempty"`
+	NumAtrs               int    `url:"queryNumAtrs,omitempty" json:"queryNumAtrs,omitempty"`
+}
+
+func (c *Controller) GetQueryTransactionSettings(ctx context.Context) (*QueryTransactionSettings, error) {
+	var resp QueryTransactionSettings
+	err := c.doGet(ctx, "/settings/querySettings", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *Controller) UpdateQueryTransactionSettings(ctx context.Context, settings *QueryTransactionSettings) error {
+	form, _ := query.Values(settings)
+	return c.doFormPost(ctx, "/settings/querySettings", form, true, nil)
+}
@@ -0,0 +1,61 @@
+package clustercontrol
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The query service is reached through the ns_server proxy, which avoids
+// needing to know which nodes are running it.
+const queryProxyPath = "/_p/query"
+
+type JsLibraryJson struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Scope  string `json:"scope"`
+	Code   string `json:"code"`
+}
+
+func jsLibraryPath(bucketName, scopeName, name string) string {
+	path := fmt.Sprintf("%s/evaluator/v1/libraries/%s", queryProxyPath, url.PathEscape(name))
+	if bucketName != "" {
+		path += "?" + url.Values{
+			"bucket": []string{bucketName},
+			"scope":  []string{scopeName},
+		}.Encode()
+	}
+	return path
+}
+
+func (c *Controller) ListJsLibraries(ctx context.Context) ([]JsLibraryJson, error) {
+	var resp []JsLibraryJson
+	err := c.doGet(ctx, queryProxyPath+"/evaluator/v1/libraries", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// CreateJsLibrary creates or replaces a javascript library, which is global
+// unless a bucket and scope are specified.
+func (c *Controller) CreateJsLibrary(ctx context.Context, bucketName, scopeName, name, code string) error {
+	path := jsLibraryPath(bucketName, scopeName, name)
+	return c.doRetriableReq(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+path, strings.NewReader(code))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Content-Type", "application/json")
+
+		return req, nil
+	}, 0, nil)
+}
+
+func (c *Controller) DeleteJsLibrary(ctx context.Context, bucketName, scopeName, name string) error {
+	return c.doDelete(ctx, jsLibraryPath(bucketName, scopeName, name), nil)
+}
@@ -0,0 +1,226 @@
+package clustercontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+	"github.com/pkg/errors"
+)
+
+type CreateRemoteClusterRequest struct {
+	Name     string `url:"name"`
+	Hostname string `url:"hostname"`
+	Username string `url:"username"`
+	Password string `url:"password"`
+}
+
+func (c *Controller) CreateRemoteCluster(ctx context.Context, req *CreateRemoteClusterRequest) error {
+	form, _ := query.Values(req)
+	return c.doFormPost(ctx, "/pools/default/remoteClusters", form, false, nil)
+}
+
+type RemoteClusterInfo struct {
+	Name     string `json:"name"`
+	UUID     string `json:"uuid"`
+	Hostname string `json:"hostname"`
+	Username string `json:"username"`
+	Deleted  bool   `json:"deleted"`
+}
+
+func (c *Controller) ListRemoteClusters(ctx context.Context) ([]RemoteClusterInfo, error) {
+	var resp []RemoteClusterInfo
+	err := c.doGet(ctx, "/pools/default/remoteClusters", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []RemoteClusterInfo
+	for _, remote := range resp {
+		if !remote.Deleted {
+			remotes = append(remotes, remote)
+		}
+	}
+
+	return remotes, nil
+}
+
+func (c *Controller) DeleteRemoteCluster(ctx context.Context, name string) error {
+	path := fmt.Sprintf("/pools/default/remoteClusters/%s", url.PathEscape(name))
+	return c.doDelete(ctx, path, nil)
+}
+
+// ReplicationSettings are the settings of a replication which can be
+// specified when creating it, or changed afterwards.  Unset fields keep
+// their default or current values.
+type ReplicationSettings struct {
+	// FilterExpression only replicates documents matching the expression.
+	FilterExpression   string `url:"filterExpression,omitempty"`
+	FilterExpiration   *bool  `url:"filterExpiration,omitempty"`
+	FilterDeletion     *bool  `url:"filterDeletion,omitempty"`
+	FilterBypassExpiry *bool  `url:"filterBypassExpiry,omitempty"`
+
+	// CompressionType is one of Auto or None.
+	CompressionType string `url:"compressionType,omitempty"`
+
+	// Priority is one of High, Medium or Low.
+	Priority string `url:"priority,omitempty"`
+
+	CollectionsExplicitMapping *bool `url:"collectionsExplicitMapping,omitempty"`
+	CollectionsMigrationMode   *bool `url:"collectionsMigrationMode,omitempty"`
+
+	// CollectionMappings maps source scopes, collections or migration
+	// filters to their targets, where an empty target denies replication.
+	CollectionMappings map[string]string `url:"-"`
+
+	CheckpointInterval  int `url:"checkpointInterval,omitempty"`
+	WorkerBatchSize     int `url:"workerBatchSize,omitempty"`
+	DocBatchSizeKb      int `url:"docBatchSizeKb,omitempty"`
+	SourceNozzlePerNode int `url:"sourceNozzlePerNode,omitempty"`
+	TargetNozzlePerNode int `url:"targetNozzlePerNode,omitempty"`
+	NetworkUsageLimit   int `url:"networkUsageLimit,omitempty"`
+
+	PauseRequested *bool `url:"pauseRequested,omitempty"`
+}
+
+func (s *ReplicationSettings) encode(form url.Values) error {
+	settingsForm, _ := query.Values(s)
+	for key, values := range settingsForm {
+		form[key] = values
+	}
+
+	if len(s.CollectionMappings) > 0 {
+		rules := make(map[string]*string)
+		for source, target := range s.CollectionMappings {
+			if target == "" {
+				rules[source] = nil
+			} else {
+				target := target
+				rules[source] = &target
+			}
+		}
+
+		rulesJson, err := json.Marshal(rules)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode collection mappings")
+		}
+		form.Set("colMappingRules", string(rulesJson))
+	}
+
+	return nil
+}
+
+type CreateReplicationRequest struct {
+	FromBucket string
+	ToCluster  string
+	ToBucket   string
+
+	ReplicationSettings
+}
+
+type CreateReplicationResponse struct {
+	ID string `json:"id"`
+}
+
+func (c *Controller) CreateReplication(ctx context.Context, req *CreateReplicationRequest) (*CreateReplicationResponse, error) {
+	form := make(url.Values)
+	err := req.ReplicationSettings.encode(form)
+	if err != nil {
+		return nil, err
+	}
+
+	form.Set("fromBucket", req.FromBucket)
+	form.Set("toCluster", req.ToCluster)
+	form.Set("toBucket", req.ToBucket)
+	form.Set("replicationType", "continuous")
+
+	resp := &CreateReplicationResponse{}
+	err = c.doFormPost(ctx, "/controller/createReplication", form, false, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Controller) UpdateReplicationSettings(ctx context.Context, replicationID string, settings *ReplicationSettings) error {
+	form := make(url.Values)
+	err := settings.encode(form)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/settings/replications/%s", url.PathEscape(replicationID))
+	return c.doFormPost(ctx, path, form, true, nil)
+}
+
+func (c *Controller) PauseReplication(ctx context.Context, replicationID string) error {
+	pause := true
+	return c.UpdateReplicationSettings(ctx, replicationID, &ReplicationSettings{
+		PauseRequested: &pause,
+	})
+}
+
+func (c *Controller) ResumeReplication(ctx context.Context, replicationID string) error {
+	pause := false
+	return c.UpdateReplicationSettings(ctx, replicationID, &ReplicationSettings{
+		PauseRequested: &pause,
+	})
+}
+
+// GetReplicationSettings returns all the settings of a replication, as
+// reported by the cluster.
+func (c *Controller) GetReplicationSettings(ctx context.Context, replicationID string) (map[string]interface{}, error) {
+	var resp map[string]interface{}
+
+	path := fmt.Sprintf("/settings/replications/%s", url.PathEscape(replicationID))
+	err := c.doGet(ctx, path, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Controller) DeleteReplication(ctx context.Context, replicationID string) error {
+	path := fmt.Sprintf("/controller/cancelXDCR/%s", url.PathEscape(replicationID))
+	return c.doDelete(ctx, path, nil)
+}
+
+type ReplicationInfo struct {
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	Source           string `json:"source"`
+	Target           string `json:"target"`
+	FilterExpression string `json:"filterExpression"`
+}
+
+// ListReplications lists the replications from this cluster, which are
+// reported as xdcr tasks.
+func (c *Controller) ListReplications(ctx context.Context) ([]ReplicationInfo, error) {
+	var resp []json.RawMessage
+	err := c.doGet(ctx, "/pools/default/tasks", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var replications []ReplicationInfo
+	for _, taskJson := range resp {
+		var task struct {
+			Type string `json:"type"`
+			ReplicationInfo
+		}
+		err := json.Unmarshal(taskJson, &task)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal task")
+		}
+
+		if task.Type == "xdcr" {
+			replications = append(replications, task.ReplicationInfo)
+		}
+	}
+
+	return replications, nil
+}
@@ -0,0 +1,311 @@
+package clusterlock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultStaleAfter is how long a lock can go without being refreshed by its
+// holder before it is considered abandoned and taken over.
+const DefaultStaleAfter = 1 * time.Minute
+
+// lockPollInterval is how often a waiting caller retries a held lock.
+const lockPollInterval = 500 * time.Millisecond
+
+// Locker serializes operations against the same cluster between processes
+// on this machine, using a lock file per cluster.  Holders periodically
+// refresh their lock file so that the locks of processes which crashed or
+// were killed are recovered once they become stale.
+type Locker struct {
+	logger     *zap.Logger
+	path       string
+	staleAfter time.Duration
+}
+
+type LockerOptions struct {
+	Logger *zap.Logger
+
+	// Path is the directory the lock files are stored in.
+	Path string
+
+	// StaleAfter defaults to DefaultStaleAfter.
+	StaleAfter time.Duration
+}
+
+func NewLocker(opts *LockerOptions) (*Locker, error) {
+	if opts.Path == "" {
+		return nil, errors.New("a lock path must be specified")
+	}
+
+	staleAfter := opts.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+
+	return &Locker{
+		logger:     opts.Logger,
+		path:       opts.Path,
+		staleAfter: staleAfter,
+	}, nil
+}
+
+type lockInfo struct {
+	Token     string    `json:"token"`
+	Pid       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	Operation string    `json:"operation"`
+	Acquired  time.Time `json:"acquired"`
+}
+
+type heldLocksKey struct{}
+
+// isHeld indicates whether the context belongs to an operation which already
+// holds the lock for a cluster, which makes locking re-entrant for deployer
+// operations which are implemented using other operations.
+func isHeld(ctx context.Context, clusterID string) bool {
+	heldLocks, _ := ctx.Value(heldLocksKey{}).(map[string]struct{})
+	_, ok := heldLocks[clusterID]
+	return ok
+}
+
+func withHeld(ctx context.Context, clusterID string) context.Context {
+	oldHeldLocks, _ := ctx.Value(heldLocksKey{}).(map[string]struct{})
+
+	heldLocks := make(map[string]struct{}, len(oldHeldLocks)+1)
+	for heldClusterID := range oldHeldLocks {
+		heldLocks[heldClusterID] = struct{}{}
+	}
+	heldLocks[clusterID] = struct{}{}
+
+	return context.WithValue(ctx, heldLocksKey{}, heldLocks)
+}
+
+func (l *Locker) lockPath(clusterID string) string {
+	return filepath.Join(l.path, clusterID+".lock")
+}
+
+func (l *Locker) readLock(lockPath string) (*lockInfo, time.Time, error) {
+	stat, err := os.Stat(lockPath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	lockBytes, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// a lock which is still being written reads as empty, this is treated
+	// as a valid lock with unknown details.
+	info := &lockInfo{}
+	_ = json.Unmarshal(lockBytes, info)
+
+	return info, stat.ModTime(), nil
+}
+
+func (l *Locker) tryLock(lockPath string, info *lockInfo) (bool, error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+
+		return false, errors.Wrap(err, "failed to create lock file")
+	}
+
+	lockBytes, _ := json.Marshal(info)
+	_, err = file.Write(lockBytes)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(lockPath)
+		return false, errors.Wrap(err, "failed to write lock file")
+	}
+
+	return true, nil
+}
+
+// takeOverStaleLock removes a lock which was found to be stale.  Every waiter
+// can find the same lock stale, so they race to create a takeover marker for
+// that lock and only the winner removes it.  The winner then checks the lock
+// is still the one which was found to be stale, as by then it may have been
+// replaced by a waiter which took it over earlier, or refreshed by a holder
+// which had stalled.
+func (l *Locker) takeOverStaleLock(lockPath string, staleInfo *lockInfo, staleRefreshed time.Time, info *lockInfo) error {
+	markerPath := lockPath + ".takeover-" + staleInfo.Token
+
+	acquired, err := l.tryLock(markerPath, info)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		// a marker is only held briefly, so a stale one was left behind by
+		// a waiter which died while taking over the lock
+		stat, err := os.Stat(markerPath)
+		if err == nil && time.Since(stat.ModTime()) > l.staleAfter {
+			os.Remove(markerPath)
+		}
+		return nil
+	}
+	defer os.Remove(markerPath)
+
+	heldInfo, refreshed, err := l.readLock(lockPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read lock file")
+	}
+
+	if heldInfo.Token != staleInfo.Token || !refreshed.Equal(staleRefreshed) {
+		return nil
+	}
+
+	err = os.Remove(lockPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to remove stale lock file")
+	}
+
+	return nil
+}
+
+// Lock acquires the lock for a cluster, waiting until it is released by any
+// other holder.  The returned context must be used for the operations
+// performed while holding the lock, and the returned function releases it.
+func (l *Locker) Lock(ctx context.Context, clusterID string, operation string) (context.Context, func(), error) {
+	if isHeld(ctx, clusterID) {
+		return ctx, func() {}, nil
+	}
+
+	err := os.MkdirAll(l.path, 0755)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create lock directory")
+	}
+
+	tokenBytes := make([]byte, 16)
+	_, _ = rand.Read(tokenBytes)
+
+	hostname, _ := os.Hostname()
+
+	info := &lockInfo{
+		Token:     hex.EncodeToString(tokenBytes),
+		Pid:       os.Getpid(),
+		Hostname:  hostname,
+		Operation: operation,
+		Acquired:  time.Now(),
+	}
+
+	lockPath := l.lockPath(clusterID)
+	loggedWait := false
+	for {
+		acquired, err := l.tryLock(lockPath, info)
+		if err != nil {
+			return nil, nil, err
+		}
+		if acquired {
+			break
+		}
+
+		heldInfo, refreshed, err := l.readLock(lockPath)
+		if errors.Is(err, os.ErrNotExist) {
+			// released between our attempt and reading it
+			continue
+		} else if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read lock file")
+		}
+
+		if time.Since(refreshed) > l.staleAfter {
+			l.logger.Warn("recovering stale cluster lock",
+				zap.String("cluster", clusterID),
+				zap.Int("pid", heldInfo.Pid),
+				zap.String("hostname", heldInfo.Hostname),
+				zap.String("operation", heldInfo.Operation),
+				zap.Time("refreshed", refreshed))
+
+			err := l.takeOverStaleLock(lockPath, heldInfo, refreshed, info)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			continue
+		}
+
+		if !loggedWait {
+			l.logger.Info("waiting for another operation on the cluster to complete",
+				zap.String("cluster", clusterID),
+				zap.Int("pid", heldInfo.Pid),
+				zap.String("operation", heldInfo.Operation))
+			loggedWait = true
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return nil, nil, errors.Wrap(ctx.Err(), fmt.Sprintf("failed to acquire lock for cluster `%s`", clusterID))
+		}
+	}
+
+	l.logger.Debug("acquired cluster lock",
+		zap.String("cluster", clusterID),
+		zap.String("operation", operation))
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+
+		ticker := time.NewTicker(l.staleAfter / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now()
+				err := os.Chtimes(lockPath, now, now)
+				if err != nil {
+					l.logger.Warn("failed to refresh cluster lock",
+						zap.String("cluster", clusterID),
+						zap.Error(err))
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	unlock := func() {
+		close(stopCh)
+		<-doneCh
+
+		// only remove the lock if it is still ours, in case it was
+		// recovered by another process after we stalled
+		heldInfo, _, err := l.readLock(lockPath)
+		if err != nil || heldInfo.Token != info.Token {
+			l.logger.Warn("cluster lock was lost before it was released",
+				zap.String("cluster", clusterID))
+			return
+		}
+
+		err = os.Remove(lockPath)
+		if err != nil {
+			l.logger.Warn("failed to release cluster lock",
+				zap.String("cluster", clusterID),
+				zap.Error(err))
+		}
+
+		l.logger.Debug("released cluster lock",
+			zap.String("cluster", clusterID))
+	}
+
+	return withHeld(ctx, clusterID), unlock, nil
+}
@@ -0,0 +1,180 @@
+package clusterlock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestLocker(t *testing.T, staleAfter time.Duration) *Locker {
+	locker, err := NewLocker(&LockerOptions{
+		Logger:     zap.NewNop(),
+		Path:       t.TempDir(),
+		StaleAfter: staleAfter,
+	})
+	require.NoError(t, err)
+	return locker
+}
+
+func TestLockExcludes(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+	ctx := context.Background()
+
+	_, unlock, err := locker.Lock(ctx, "cluster-a", "first")
+	require.NoError(t, err)
+
+	// other clusters are not affected
+	_, unlockB, err := locker.Lock(ctx, "cluster-b", "other")
+	require.NoError(t, err)
+	unlockB()
+
+	acquiredCh := make(chan struct{})
+	go func() {
+		_, unlock, err := locker.Lock(ctx, "cluster-a", "second")
+		require.NoError(t, err)
+		close(acquiredCh)
+		unlock()
+	}()
+
+	select {
+	case <-acquiredCh:
+		t.Fatal("lock was acquired while it was held")
+	case <-time.After(2 * lockPollInterval):
+	}
+
+	unlock()
+
+	select {
+	case <-acquiredCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("lock was not acquired after it was released")
+	}
+}
+
+func TestLockReentrant(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+
+	lockedCtx, unlock, err := locker.Lock(context.Background(), "cluster-a", "outer")
+	require.NoError(t, err)
+	defer unlock()
+
+	_, innerUnlock, err := locker.Lock(lockedCtx, "cluster-a", "inner")
+	require.NoError(t, err)
+	innerUnlock()
+
+	// the inner release must not release the outer lock
+	_, err = os.Stat(locker.lockPath("cluster-a"))
+	require.NoError(t, err)
+}
+
+func TestLockTimeout(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+
+	_, unlock, err := locker.Lock(context.Background(), "cluster-a", "first")
+	require.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+	defer cancel()
+
+	_, _, err = locker.Lock(ctx, "cluster-a", "second")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLockRecoversStale(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+
+	// emulate a lock left behind by a process which was killed
+	lockPath := filepath.Join(locker.path, "cluster-a.lock")
+	require.NoError(t, os.MkdirAll(locker.path, 0755))
+	require.NoError(t, os.WriteFile(lockPath, []byte(`{"token":"dead","pid":1}`), 0644))
+	staleTime := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, unlock, err := locker.Lock(ctx, "cluster-a", "recover")
+	require.NoError(t, err)
+	unlock()
+
+	_, err = os.Stat(lockPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLockRecoversStaleConcurrently(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+
+	lockPath := filepath.Join(locker.path, "cluster-a.lock")
+	require.NoError(t, os.MkdirAll(locker.path, 0755))
+	require.NoError(t, os.WriteFile(lockPath, []byte(`{"token":"dead","pid":1}`), 0644))
+	staleTime := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// every waiter finds the same lock stale at once, but only one of them
+	// may remove it, or a later one would remove the lock an earlier one took
+	var numHolders, maxHolders atomic.Int32
+	var waitGrp sync.WaitGroup
+	for waiterIdx := 0; waiterIdx < 8; waiterIdx++ {
+		waitGrp.Add(1)
+		go func() {
+			defer waitGrp.Done()
+
+			_, unlock, err := locker.Lock(ctx, "cluster-a", "recover")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			holders := numHolders.Add(1)
+			for {
+				seenMax := maxHolders.Load()
+				if holders <= seenMax || maxHolders.CompareAndSwap(seenMax, holders) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			numHolders.Add(-1)
+
+			unlock()
+		}()
+	}
+	waitGrp.Wait()
+
+	require.Equal(t, int32(1), maxHolders.Load())
+}
+
+func TestLockTakeOverChecksLockIsUnchanged(t *testing.T) {
+	locker := newTestLocker(t, time.Minute)
+
+	lockPath := filepath.Join(locker.path, "cluster-a.lock")
+	require.NoError(t, os.MkdirAll(locker.path, 0755))
+	require.NoError(t, os.WriteFile(lockPath, []byte(`{"token":"dead","pid":1}`), 0644))
+	staleTime := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+
+	// a waiter finds the lock stale, but another takes it over first
+	staleInfo, staleRefreshed, err := locker.readLock(lockPath)
+	require.NoError(t, err)
+
+	_, unlock, err := locker.Lock(context.Background(), "cluster-a", "recover")
+	require.NoError(t, err)
+	defer unlock()
+
+	err = locker.takeOverStaleLock(lockPath, staleInfo, staleRefreshed, &lockInfo{Token: "late"})
+	require.NoError(t, err)
+
+	heldInfo, _, err := locker.readLock(lockPath)
+	require.NoError(t, err)
+	require.NotEqual(t, "dead", heldInfo.Token)
+}
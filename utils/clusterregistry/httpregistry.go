@@ -0,0 +1,138 @@
+package clusterregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// HttpRegistry stores registry entries in a simple HTTP service.  The
+// service is expected to implement the following endpoints, accepting and
+// returning entries as JSON:
+//
+//	GET    /clusters       lists all entries
+//	PUT    /clusters/{id}  registers an entry
+//	DELETE /clusters/{id}  unregisters an entry
+type HttpRegistry struct {
+	logger     *zap.Logger
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+var _ Registry = (*HttpRegistry)(nil)
+
+type HttpRegistryOptions struct {
+	Logger   *zap.Logger
+	Endpoint string
+
+	// Token is sent as a bearer token with every request, if specified.
+	Token string
+
+	HttpClient *http.Client
+}
+
+func NewHttpRegistry(opts *HttpRegistryOptions) (*HttpRegistry, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("an endpoint must be specified")
+	}
+
+	httpClient := opts.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &HttpRegistry{
+		logger:     opts.Logger,
+		endpoint:   strings.TrimSuffix(opts.Endpoint, "/"),
+		token:      opts.Token,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (r *HttpRegistry) doRequest(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal request body")
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint+path, reqBody)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	r.logger.Debug("sending registry request",
+		zap.String("method", method),
+		zap.String("path", path))
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("registry returned unexpected status %d: %s", resp.StatusCode, respBody)
+	}
+
+	if out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse response")
+		}
+	}
+
+	return nil
+}
+
+func (r *HttpRegistry) Register(ctx context.Context, entry *Entry) error {
+	err := r.doRequest(ctx, http.MethodPut, "/clusters/"+url.PathEscape(entry.ClusterID), entry, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to register cluster")
+	}
+
+	return nil
+}
+
+func (r *HttpRegistry) Unregister(ctx context.Context, clusterID string) error {
+	err := r.doRequest(ctx, http.MethodDelete, "/clusters/"+url.PathEscape(clusterID), nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to unregister cluster")
+	}
+
+	return nil
+}
+
+func (r *HttpRegistry) List(ctx context.Context) ([]*Entry, error) {
+	var entries []*Entry
+	err := r.doRequest(ctx, http.MethodGet, "/clusters", nil, &entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list registered clusters")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,41 @@
+package clusterregistry
+
+import (
+	"context"
+	"time"
+)
+
+// Entry describes a cluster which has been registered in a shared registry,
+// allowing clusters to be seen and cleaned up from other machines.
+type Entry struct {
+	ClusterID string    `json:"cluster_id"`
+	Deployer  string    `json:"deployer"`
+	Owner     string    `json:"owner"`
+	Purpose   string    `json:"purpose,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Expiry    time.Time `json:"expiry"`
+	ConnStr   string    `json:"connstr,omitempty"`
+	Mgmt      string    `json:"mgmt,omitempty"`
+}
+
+// IsExpired returns whether the cluster has been expired for longer than
+// the grace period.  Clusters without an expiry never expire.
+func (e *Entry) IsExpired(now time.Time, gracePeriod time.Duration) bool {
+	if e.Expiry.IsZero() {
+		return false
+	}
+	return now.After(e.Expiry.Add(gracePeriod))
+}
+
+// Registry is a store of cluster allocations shared between machines.
+type Registry interface {
+	// Register adds an entry to the registry, replacing any existing entry
+	// for the same cluster.
+	Register(ctx context.Context, entry *Entry) error
+
+	// Unregister removes the entry for a cluster.  Removing a cluster which
+	// is not registered is not an error.
+	Unregister(ctx context.Context, clusterID string) error
+
+	List(ctx context.Context) ([]*Entry, error)
+}
@@ -0,0 +1,131 @@
+package clusterregistry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newFakeStore returns a server storing request bodies by path, which is
+// enough to emulate both the http registry service and S3.
+func newFakeStore(t *testing.T, listPath string, list func(objects map[string]string, r *http.Request) string) *httptest.Server {
+	var lock sync.Mutex
+	objects := make(map[string]string)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == listPath:
+			fmt.Fprint(w, list(objects, r))
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(body)
+		case r.Method == http.MethodGet:
+			object, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, object)
+		case r.Method == http.MethodDelete:
+			if _, ok := objects[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(objects, r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testRegistry(t *testing.T, reg Registry) {
+	ctx := context.Background()
+	expiry := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, reg.Register(ctx, &Entry{ClusterID: "c1", Deployer: "docker", Owner: "alice@host1", Expiry: expiry}))
+	require.NoError(t, reg.Register(ctx, &Entry{ClusterID: "c2", Deployer: "cloud", Owner: "bob@host2"}))
+
+	entries, err := reg.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ClusterID < entries[j].ClusterID })
+	assert.Equal(t, "alice@host1", entries[0].Owner)
+	assert.True(t, entries[0].Expiry.Equal(expiry))
+	assert.Equal(t, "cloud", entries[1].Deployer)
+
+	require.NoError(t, reg.Unregister(ctx, "c1"))
+	require.NoError(t, reg.Unregister(ctx, "missing"))
+
+	entries, err = reg.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "c2", entries[0].ClusterID)
+}
+
+func TestHttpRegistry(t *testing.T) {
+	srv := newFakeStore(t, "/clusters", func(objects map[string]string, r *http.Request) string {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var items []string
+		for _, object := range objects {
+			items = append(items, object)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	})
+
+	reg, err := NewHttpRegistry(&HttpRegistryOptions{
+		Logger:   zap.NewNop(),
+		Endpoint: srv.URL + "/",
+		Token:    "secret",
+	})
+	require.NoError(t, err)
+
+	testRegistry(t, reg)
+}
+
+func TestS3Registry(t *testing.T) {
+	srv := newFakeStore(t, "/my-bucket/", func(objects map[string]string, r *http.Request) string {
+		assert.Equal(t, "2", r.URL.Query().Get("list-type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKID/")
+
+		prefix := r.URL.Query().Get("prefix")
+		result := "<ListBucketResult>"
+		for path := range objects {
+			key := strings.TrimPrefix(path, "/my-bucket/")
+			if strings.HasPrefix(key, prefix) {
+				result += "<Contents><Key>" + key + "</Key></Contents>"
+			}
+		}
+		return result + "<IsTruncated>false</IsTruncated></ListBucketResult>"
+	})
+
+	reg, err := NewS3Registry(&S3RegistryOptions{
+		Logger:   zap.NewNop(),
+		Bucket:   "my-bucket",
+		Prefix:   "clusters/",
+		Region:   "us-east-1",
+		Endpoint: srv.URL,
+		Credentials: aws.Credentials{
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "SECRET",
+		},
+	})
+	require.NoError(t, err)
+
+	testRegistry(t, reg)
+}
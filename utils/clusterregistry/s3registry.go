@@ -0,0 +1,235 @@
+package clusterregistry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// S3Registry stores each registry entry as a JSON object in an S3 bucket.
+// Requests are made directly against the S3 REST API so that any S3
+// compatible store can be used by specifying an endpoint.
+type S3Registry struct {
+	logger      *zap.Logger
+	endpoint    string
+	bucket      string
+	prefix      string
+	region      string
+	credentials aws.Credentials
+	httpClient  *http.Client
+	signer      *v4.Signer
+}
+
+var _ Registry = (*S3Registry)(nil)
+
+type S3RegistryOptions struct {
+	Logger      *zap.Logger
+	Bucket      string
+	Prefix      string
+	Region      string
+	Credentials aws.Credentials
+
+	// Endpoint overrides the S3 endpoint, for use with S3 compatible
+	// stores.  Requests always use path-style addressing.
+	Endpoint string
+
+	HttpClient *http.Client
+}
+
+func NewS3Registry(opts *S3RegistryOptions) (*S3Registry, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("a bucket must be specified")
+	}
+	if opts.Region == "" {
+		return nil, errors.New("a region must be specified")
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+
+	httpClient := opts.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &S3Registry{
+		logger:      opts.Logger,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		bucket:      opts.Bucket,
+		prefix:      opts.Prefix,
+		region:      opts.Region,
+		credentials: opts.Credentials,
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+	}, nil
+}
+
+func (r *S3Registry) objectKey(clusterID string) string {
+	return r.prefix + clusterID + ".json"
+}
+
+func (r *S3Registry) doRequest(ctx context.Context, method string, key string, query url.Values, body []byte) ([]byte, int, error) {
+	reqUrl := r.endpoint + "/" + r.bucket + "/" + key
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to create request")
+	}
+
+	payloadHash := sha256.Sum256(body)
+	payloadHashStr := hex.EncodeToString(payloadHash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHashStr)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	err = r.signer.SignHTTP(ctx, r.credentials, req, payloadHashStr, "s3", r.region, time.Now(),
+		func(opts *v4.SignerOptions) {
+			// S3 expects object keys to only be escaped once
+			opts.DisableURIPathEscaping = true
+		})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to sign request")
+	}
+
+	r.logger.Debug("sending s3 registry request",
+		zap.String("method", method),
+		zap.String("key", key))
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to read response")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, resp.StatusCode,
+			fmt.Errorf("s3 returned unexpected status %d: %s", resp.StatusCode, respBody)
+	}
+
+	return respBody, resp.StatusCode, nil
+}
+
+func (r *S3Registry) Register(ctx context.Context, entry *Entry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal entry")
+	}
+
+	_, _, err = r.doRequest(ctx, http.MethodPut, r.objectKey(entry.ClusterID), nil, entryBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to register cluster")
+	}
+
+	return nil
+}
+
+func (r *S3Registry) Unregister(ctx context.Context, clusterID string) error {
+	_, statusCode, err := r.doRequest(ctx, http.MethodDelete, r.objectKey(clusterID), nil, nil)
+	if err != nil && statusCode != http.StatusNotFound {
+		return errors.Wrap(err, "failed to unregister cluster")
+	}
+
+	return nil
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (r *S3Registry) listKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", r.prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		respBody, _, err := r.doRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListBucketResult
+		err = xml.Unmarshal(respBody, &result)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse list response")
+		}
+
+		for _, content := range result.Contents {
+			if strings.HasSuffix(content.Key, ".json") {
+				keys = append(keys, content.Key)
+			}
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	return keys, nil
+}
+
+func (r *S3Registry) List(ctx context.Context) ([]*Entry, error) {
+	keys, err := r.listKeys(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list registered clusters")
+	}
+
+	entries := make([]*Entry, 0, len(keys))
+	for _, key := range keys {
+		entryBytes, statusCode, err := r.doRequest(ctx, http.MethodGet, key, nil, nil)
+		if err != nil {
+			// the entry may have been unregistered since we listed it
+			if statusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, errors.Wrap(err, "failed to read registered cluster")
+		}
+
+		var entry *Entry
+		err = json.Unmarshal(entryBytes, &entry)
+		if err != nil {
+			r.logger.Warn("ignoring invalid registry entry",
+				zap.String("key", key),
+				zap.Error(err))
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,49 @@
+package dcptap
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+type collectionsManifestJson struct {
+	Scopes []struct {
+		UID         string `json:"uid"`
+		Name        string `json:"name"`
+		Collections []struct {
+			UID  string `json:"uid"`
+			Name string `json:"name"`
+		} `json:"collections"`
+	} `json:"scopes"`
+}
+
+// parseCollectionsManifest builds the names of the scopes and collections,
+// keyed by their ids.
+func parseCollectionsManifest(manifestBytes []byte) (map[uint32]string, map[uint32]string, error) {
+	var manifest collectionsManifestJson
+	err := json.Unmarshal(manifestBytes, &manifest)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse collections manifest")
+	}
+
+	scopeNames := make(map[uint32]string)
+	collectionNames := make(map[uint32]string)
+	for _, scope := range manifest.Scopes {
+		scopeID, err := strconv.ParseUint(scope.UID, 16, 32)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid scope uid")
+		}
+		scopeNames[uint32(scopeID)] = scope.Name
+
+		for _, collection := range scope.Collections {
+			collectionID, err := strconv.ParseUint(collection.UID, 16, 32)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "invalid collection uid")
+			}
+			collectionNames[uint32(collectionID)] = scope.Name + "." + collection.Name
+		}
+	}
+
+	return scopeNames, collectionNames, nil
+}
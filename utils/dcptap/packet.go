@@ -0,0 +1,36 @@
+package dcptap
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	opGetAllVbSeqnos    = 0x48
+	opDcpOpen           = 0x50
+	opDcpStreamReq      = 0x53
+	opDcpGetFailoverLog = 0x54
+	opDcpStreamEnd      = 0x55
+	opDcpSnapshotMarker = 0x56
+	opDcpMutation       = 0x57
+	opDcpDeletion       = 0x58
+	opDcpExpiration     = 0x59
+	opDcpNoop           = 0x5c
+	opDcpSystemEvent    = 0x5f
+)
+
+const (
+	statusRollback = 0x23
+)
+
+// decodeCollectionKey splits a collection-aware key into its leb128 encoded
+// collection id and the actual document key.
+func decodeCollectionKey(key []byte) (uint32, []byte, error) {
+	var collectionID uint32
+	for i := 0; i < len(key) && i < 5; i++ {
+		collectionID |= uint32(key[i]&0x7f) << (7 * i)
+		if key[i]&0x80 == 0 {
+			return collectionID, key[i+1:], nil
+		}
+	}
+	return 0, nil, errors.New("invalid leb128 collection id")
+}
@@ -0,0 +1,37 @@
+package dcptap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/memdclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	key := append([]byte{0x88, 0x01}, []byte("doc-1")...)
+	buf := bytes.NewBuffer(nil)
+	err := memdclient.WritePacket(buf, &memdclient.Packet{
+		Magic:   memdclient.MagicReq,
+		Opcode:  opDcpMutation,
+		Vbucket: 12,
+		Opaque:  99,
+		Extras:  []byte{1, 2, 3},
+		Key:     key,
+		Value:   []byte(`{"a":1}`),
+	})
+	require.NoError(t, err)
+
+	pak, err := memdclient.ReadPacket(buf)
+	require.NoError(t, err)
+	require.Equal(t, uint8(opDcpMutation), pak.Opcode)
+	require.Equal(t, uint16(12), pak.Vbucket)
+	require.Equal(t, uint32(99), pak.Opaque)
+	require.Equal(t, []byte{1, 2, 3}, pak.Extras)
+	require.Equal(t, []byte(`{"a":1}`), pak.Value)
+
+	collectionID, docKey, err := decodeCollectionKey(pak.Key)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x88), collectionID)
+	require.Equal(t, "doc-1", string(docKey))
+}
@@ -0,0 +1,353 @@
+package dcptap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/memdclient"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// dcpAgentName is the name our connections identify themselves with.
+const dcpAgentName = "cbdinocluster-dcptap"
+
+type EventType string
+
+const (
+	EventTypeMutation EventType = "mutation"
+	EventTypeDeletion EventType = "deletion"
+)
+
+type Event struct {
+	Type         EventType
+	VbID         uint16
+	Seqno        uint64
+	CollectionID uint32
+	Collection   string
+	Key          string
+}
+
+type TailOptions struct {
+	Logger     *zap.Logger
+	ConnStr    string
+	Username   string
+	Password   string
+	BucketName string
+
+	// FromStart streams all existing documents rather than only the changes
+	// made after tailing started.
+	FromStart bool
+}
+
+// Tail opens DCP streams for every vbucket of the bucket and invokes the
+// handler for each mutation and deletion until the context is cancelled.
+// The handler may be invoked concurrently for different nodes.
+func Tail(ctx context.Context, opts *TailOptions, handler func(*Event)) error {
+	connStrInfo, err := memdclient.ParseConnStr(opts.ConnStr)
+	if err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if connStrInfo.UseTLS {
+		// this is a debugging tool, so we do not verify certificates
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	var seedConn *memdclient.Conn
+	var seedHost string
+	for _, address := range connStrInfo.Addresses {
+		conn, err := memdclient.Dial(ctx, address, tlsConfig)
+		if err != nil {
+			opts.Logger.Debug("failed to connect to seed node", zap.Error(err))
+			continue
+		}
+
+		seedConn = conn
+		seedHost, _, _ = net.SplitHostPort(address)
+		break
+	}
+	if seedConn == nil {
+		return errors.New("failed to connect to any seed node")
+	}
+	defer seedConn.Close()
+
+	err = seedConn.Setup(dcpAgentName, opts.Username, opts.Password, opts.BucketName, memdclient.FeatureCollections)
+	if err != nil {
+		return err
+	}
+
+	configResp, err := seedConn.Request(memdclient.OpGetClusterConfig, 0, nil, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch cluster config")
+	}
+
+	routing, err := memdclient.ParseClusterConfig(configResp.Value, seedHost, connStrInfo.UseTLS, connStrInfo.Network)
+	if err != nil {
+		return err
+	}
+
+	manifestResp, err := seedConn.Request(memdclient.OpGetCollectionsManifest, 0, nil, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch collections manifest")
+	}
+
+	scopeNames, collectionNames, err := parseCollectionsManifest(manifestResp.Value)
+	if err != nil {
+		return err
+	}
+
+	tailer := &bucketTailer{
+		opts:            opts,
+		tlsConfig:       tlsConfig,
+		handler:         handler,
+		scopeNames:      scopeNames,
+		collectionNames: collectionNames,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var waitGrp sync.WaitGroup
+	errCh := make(chan error, len(routing.Addresses))
+	for serverIdx, address := range routing.Addresses {
+		vbIDs := routing.ActiveVbuckets[serverIdx]
+		if len(vbIDs) == 0 {
+			continue
+		}
+
+		waitGrp.Add(1)
+		go func(address string, vbIDs []uint16) {
+			defer waitGrp.Done()
+
+			err := tailer.tailNode(ctx, address, vbIDs)
+			if err != nil && ctx.Err() == nil {
+				errCh <- errors.Wrapf(err, "failed to tail node %s", address)
+				cancel()
+			}
+		}(address, vbIDs)
+	}
+	waitGrp.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+type bucketTailer struct {
+	opts      *TailOptions
+	tlsConfig *tls.Config
+	handler   func(*Event)
+
+	lock            sync.Mutex
+	scopeNames      map[uint32]string
+	collectionNames map[uint32]string
+}
+
+func (t *bucketTailer) collectionName(collectionID uint32) string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if name, ok := t.collectionNames[collectionID]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", collectionID)
+}
+
+func (t *bucketTailer) tailNode(ctx context.Context, address string, vbIDs []uint16) error {
+	logger := t.opts.Logger.With(zap.String("address", address))
+
+	conn, err := memdclient.Dial(ctx, address, t.tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	err = conn.Setup(dcpAgentName, t.opts.Username, t.opts.Password, t.opts.BucketName, memdclient.FeatureCollections)
+	if err != nil {
+		return err
+	}
+
+	// DCP_OPEN with the producer flag
+	openExtras := make([]byte, 8)
+	binary.BigEndian.PutUint32(openExtras[4:], 0x01)
+	_, err = conn.Request(opDcpOpen, 0, openExtras, []byte("cbdinocluster-dcptail-"+uuid.NewString()), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to open dcp connection")
+	}
+
+	vbSeqnos := make(map[uint16]uint64)
+	if !t.opts.FromStart {
+		stateExtras := make([]byte, 4)
+		binary.BigEndian.PutUint32(stateExtras, 1) // active vbuckets only
+		seqnosResp, err := conn.Request(opGetAllVbSeqnos, 0, stateExtras, nil, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch vbucket seqnos")
+		}
+
+		for pos := 0; pos+10 <= len(seqnosResp.Value); pos += 10 {
+			vbID := binary.BigEndian.Uint16(seqnosResp.Value[pos:])
+			vbSeqnos[vbID] = binary.BigEndian.Uint64(seqnosResp.Value[pos+2:])
+		}
+	}
+
+	vbUuids := make(map[uint16]uint64)
+	for _, vbID := range vbIDs {
+		if vbSeqnos[vbID] == 0 {
+			continue
+		}
+
+		failoverResp, err := conn.Request(opDcpGetFailoverLog, vbID, nil, nil, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch failover log")
+		}
+		if len(failoverResp.Value) >= 16 {
+			vbUuids[vbID] = binary.BigEndian.Uint64(failoverResp.Value)
+		}
+	}
+
+	// streams are opened asynchronously, the responses being handled by
+	// the main read loop below along with the dcp messages
+	streamOpaques := make(map[uint32]uint16)
+	openStream := func(vbID uint16, startSeqno uint64, vbUuid uint64) error {
+		opaque := conn.NextOpaque()
+		streamOpaques[opaque] = vbID
+
+		extras := make([]byte, 48)
+		binary.BigEndian.PutUint64(extras[8:], startSeqno)
+		binary.BigEndian.PutUint64(extras[16:], 0xffffffffffffffff)
+		binary.BigEndian.PutUint64(extras[24:], vbUuid)
+		binary.BigEndian.PutUint64(extras[32:], startSeqno)
+		binary.BigEndian.PutUint64(extras[40:], startSeqno)
+
+		return conn.Send(&memdclient.Packet{
+			Magic:   memdclient.MagicReq,
+			Opcode:  opDcpStreamReq,
+			Vbucket: vbID,
+			Opaque:  opaque,
+			Extras:  extras,
+		})
+	}
+
+	for _, vbID := range vbIDs {
+		err := openStream(vbID, vbSeqnos[vbID], vbUuids[vbID])
+		if err != nil {
+			return errors.Wrap(err, "failed to request stream")
+		}
+	}
+
+	logger.Debug("opened dcp streams", zap.Int("numVbuckets", len(vbIDs)))
+
+	for {
+		pak, err := conn.Read()
+		if err != nil {
+			return errors.Wrap(err, "failed to read dcp message")
+		}
+
+		if pak.Magic == memdclient.MagicRes {
+			if pak.Opcode != opDcpStreamReq {
+				continue
+			}
+
+			vbID := streamOpaques[pak.Opaque]
+			switch pak.Status() {
+			case memdclient.StatusSuccess:
+			case statusRollback:
+				rollbackSeqno := uint64(0)
+				if len(pak.Value) >= 8 {
+					rollbackSeqno = binary.BigEndian.Uint64(pak.Value)
+				}
+
+				logger.Debug("stream rollback requested",
+					zap.Uint16("vbID", vbID),
+					zap.Uint64("seqno", rollbackSeqno))
+
+				err := openStream(vbID, rollbackSeqno, vbUuids[vbID])
+				if err != nil {
+					return errors.Wrap(err, "failed to request stream after rollback")
+				}
+			default:
+				return memdclient.StatusError{Opcode: pak.Opcode, Status: pak.Status()}
+			}
+			continue
+		}
+
+		switch pak.Opcode {
+		case opDcpMutation, opDcpDeletion, opDcpExpiration:
+			t.handleDocument(pak)
+		case opDcpSystemEvent:
+			t.handleSystemEvent(pak)
+		case opDcpNoop:
+			err := conn.Send(&memdclient.Packet{
+				Magic:  memdclient.MagicRes,
+				Opcode: opDcpNoop,
+				Opaque: pak.Opaque,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to respond to noop")
+			}
+		case opDcpStreamEnd:
+			logger.Debug("stream ended", zap.Uint16("vbID", pak.Vbucket))
+		}
+	}
+}
+
+func (t *bucketTailer) handleDocument(pak *memdclient.Packet) {
+	if len(pak.Extras) < 8 {
+		return
+	}
+
+	collectionID, key, err := decodeCollectionKey(pak.Key)
+	if err != nil {
+		t.opts.Logger.Debug("failed to decode document key", zap.Error(err))
+		return
+	}
+
+	eventType := EventTypeDeletion
+	if pak.Opcode == opDcpMutation {
+		eventType = EventTypeMutation
+	}
+
+	t.handler(&Event{
+		Type:         eventType,
+		VbID:         pak.Vbucket,
+		Seqno:        binary.BigEndian.Uint64(pak.Extras),
+		CollectionID: collectionID,
+		Collection:   t.collectionName(collectionID),
+		Key:          string(key),
+	})
+}
+
+func (t *bucketTailer) handleSystemEvent(pak *memdclient.Packet) {
+	// we only track created collections so new ones can be named, for
+	// which the value holds the manifest uid, scope id and collection id
+	if len(pak.Extras) < 12 || binary.BigEndian.Uint32(pak.Extras[8:]) != 0 || len(pak.Value) < 16 {
+		return
+	}
+
+	scopeID := binary.BigEndian.Uint32(pak.Value[8:])
+	collectionID := binary.BigEndian.Uint32(pak.Value[12:])
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	scopeName, ok := t.scopeNames[scopeID]
+	if !ok {
+		scopeName = fmt.Sprintf("0x%x", scopeID)
+	}
+	t.collectionNames[collectionID] = scopeName + "." + string(pak.Key)
+}
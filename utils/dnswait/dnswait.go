@@ -0,0 +1,160 @@
+package dnswait
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout is how long to wait for records to propagate when no
+// timeout is specified.
+const DefaultTimeout = 10 * time.Minute
+
+// pollInterval is how often the records are resolved again while waiting.
+const pollInterval = 5 * time.Second
+
+type WaitOptions struct {
+	Logger *zap.Logger
+
+	// Resolvers are the addresses of the DNS servers to check the records
+	// against, with the port defaulting to 53.  The system resolver is used
+	// when none are specified.
+	Resolvers []string
+
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// resolverAddress adds the default DNS port to a resolver address which does
+// not specify one.
+func resolverAddress(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+}
+
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	addr = resolverAddress(addr)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// resolveSrv resolves the couchbases SRV records of a host and the addresses
+// of each of their targets, returning them as sorted `target:port` entries.
+func resolveSrv(ctx context.Context, resolver *net.Resolver, srvHost string) ([]string, error) {
+	_, srvs, err := resolver.LookupSRV(ctx, "couchbases", "tcp", srvHost)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve SRV records")
+	}
+
+	var records []string
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+
+		addrs, err := resolver.LookupHost(ctx, target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve SRV target `%s`", target)
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("SRV target `%s` has no addresses", target)
+		}
+
+		records = append(records, fmt.Sprintf("%s:%d", target, srv.Port))
+	}
+
+	slices.Sort(records)
+	return records, nil
+}
+
+// recordsConsistent indicates whether every resolver returned the same,
+// non-empty set of records.
+func recordsConsistent(results [][]string) bool {
+	if len(results) == 0 {
+		return false
+	}
+
+	for _, records := range results {
+		if len(records) == 0 || !slices.Equal(records, results[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WaitForSrv waits until the couchbases SRV records of a host, and the
+// address records of their targets, resolve consistently against all of the
+// resolvers.  Newly provisioned Capella clusters are unreachable through
+// their connection strings until this is the case.
+func WaitForSrv(ctx context.Context, srvHost string, opts *WaitOptions) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	resolverAddrs := opts.Resolvers
+	if len(resolverAddrs) == 0 {
+		resolverAddrs = []string{""}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		var results [][]string
+		lastErr = nil
+
+		for _, resolverAddr := range resolverAddrs {
+			records, err := resolveSrv(ctx, newResolver(resolverAddr), srvHost)
+			if err != nil {
+				lastErr = errors.Wrapf(err, "resolver `%s`", resolverAddr)
+				break
+			}
+
+			results = append(results, records)
+		}
+
+		if lastErr == nil {
+			if recordsConsistent(results) {
+				logger.Debug("dns records are consistent",
+					zap.String("host", srvHost),
+					zap.Strings("records", results[0]))
+				return nil
+			}
+
+			lastErr = fmt.Errorf("resolvers returned different records: %v", results)
+		}
+
+		logger.Debug("waiting for dns records to propagate",
+			zap.String("host", srvHost),
+			zap.Error(lastErr))
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return errors.Wrapf(lastErr, "timed out waiting for dns records of `%s`", srvHost)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package dnswait
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolverAddress(t *testing.T) {
+	assert.Equal(t, "8.8.8.8:53", resolverAddress("8.8.8.8"))
+	assert.Equal(t, "8.8.8.8:5353", resolverAddress("8.8.8.8:5353"))
+	assert.Equal(t, "[2001:4860:4860::8888]:53", resolverAddress("2001:4860:4860::8888"))
+	assert.Equal(t, "[2001:4860:4860::8888]:53", resolverAddress("[2001:4860:4860::8888]"))
+}
+
+func TestRecordsConsistent(t *testing.T) {
+	assert.False(t, recordsConsistent(nil))
+	assert.False(t, recordsConsistent([][]string{{}}))
+	assert.True(t, recordsConsistent([][]string{{"a:11207", "b:11207"}}))
+	assert.True(t, recordsConsistent([][]string{
+		{"a:11207", "b:11207"},
+		{"a:11207", "b:11207"},
+	}))
+	assert.False(t, recordsConsistent([][]string{
+		{"a:11207", "b:11207"},
+		{"a:11207"},
+	}))
+}
@@ -0,0 +1,146 @@
+package dockerhost
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Endpoint describes how to connect to a docker daemon.
+type Endpoint struct {
+	Host          string
+	SkipTLSVerify bool
+
+	// CACertPath, CertPath and KeyPath are only populated when the
+	// endpoint has TLS material associated with it.
+	CACertPath string
+	CertPath   string
+	KeyPath    string
+}
+
+func (e *Endpoint) HasTLS() bool {
+	return e.CACertPath != "" || e.CertPath != ""
+}
+
+type contextMetaJson struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host          string `json:"Host"`
+		SkipTLSVerify bool   `json:"SkipTLSVerify"`
+	} `json:"Endpoints"`
+}
+
+func dockerConfigDir() (string, error) {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return configDir, nil
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find user home path")
+	}
+
+	return filepath.Join(homePath, ".docker"), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ResolveContext reads the endpoint of a named docker context from the
+// docker cli configuration directory, the same way `docker --context` does.
+func ResolveContext(name string) (*Endpoint, error) {
+	configDir, err := dockerConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	nameHash := sha256.Sum256([]byte(name))
+	contextDir := hex.EncodeToString(nameHash[:])
+
+	metaBytes, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", contextDir, "meta.json"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read docker context `%s`", name)
+	}
+
+	var meta contextMetaJson
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse docker context `%s`", name)
+	}
+
+	dockerEndpoint, ok := meta.Endpoints["docker"]
+	if !ok || dockerEndpoint.Host == "" {
+		return nil, errors.Errorf("docker context `%s` has no docker endpoint", name)
+	}
+
+	endpoint := &Endpoint{
+		Host:          dockerEndpoint.Host,
+		SkipTLSVerify: dockerEndpoint.SkipTLSVerify,
+	}
+
+	tlsDir := filepath.Join(configDir, "contexts", "tls", contextDir, "docker")
+	if fileExists(filepath.Join(tlsDir, "ca.pem")) {
+		endpoint.CACertPath = filepath.Join(tlsDir, "ca.pem")
+	}
+	if fileExists(filepath.Join(tlsDir, "cert.pem")) && fileExists(filepath.Join(tlsDir, "key.pem")) {
+		endpoint.CertPath = filepath.Join(tlsDir, "cert.pem")
+		endpoint.KeyPath = filepath.Join(tlsDir, "key.pem")
+	}
+
+	return endpoint, nil
+}
+
+// EndpointFromCertPath builds an endpoint for a host using the standard
+// DOCKER_CERT_PATH layout of ca.pem, cert.pem and key.pem.
+func EndpointFromCertPath(host string, certPath string, skipTLSVerify bool) *Endpoint {
+	endpoint := &Endpoint{
+		Host:          host,
+		SkipTLSVerify: skipTLSVerify,
+	}
+
+	if certPath != "" {
+		endpoint.CACertPath = filepath.Join(certPath, "ca.pem")
+		endpoint.CertPath = filepath.Join(certPath, "cert.pem")
+		endpoint.KeyPath = filepath.Join(certPath, "key.pem")
+	}
+
+	return endpoint
+}
+
+// TLSConfig builds the client TLS configuration for the endpoint.
+func (e *Endpoint) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: e.SkipTLSVerify,
+	}
+
+	if e.CACertPath != "" {
+		caBytes, err := os.ReadFile(e.CACertPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read docker ca certificate")
+		}
+
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caBytes) {
+			return nil, errors.New("failed to parse docker ca certificate")
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if e.CertPath != "" {
+		cert, err := tls.LoadX509KeyPair(e.CertPath, e.KeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load docker client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,125 @@
+package dockerhost
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewSSHDialer returns a dialer which connects to the docker daemon on a
+// remote machine by running `docker system dial-stdio` over ssh, which is
+// the same mechanism the docker cli uses for ssh:// hosts.
+func NewSSHDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	hostUrl, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ssh host")
+	}
+	if hostUrl.Scheme != "ssh" {
+		return nil, errors.New("ssh hosts must use the ssh:// scheme")
+	}
+	if hostUrl.Hostname() == "" {
+		return nil, errors.New("ssh hosts must specify a hostname")
+	}
+
+	var sshArgs []string
+	if hostUrl.User != nil {
+		sshArgs = append(sshArgs, "-l", hostUrl.User.Username())
+	}
+	if hostUrl.Port() != "" {
+		sshArgs = append(sshArgs, "-p", hostUrl.Port())
+	}
+	sshArgs = append(sshArgs, "--", hostUrl.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// the command must outlive the dial context, so it is not bound to it
+		cmd := exec.Command("ssh", sshArgs...)
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create ssh stdin pipe")
+		}
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create ssh stdout pipe")
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start ssh")
+		}
+
+		return &commandConn{
+			cmd:        cmd,
+			stdin:      stdin,
+			stdout:     stdout,
+			remoteAddr: hostUrl.Hostname(),
+		}, nil
+	}, nil
+}
+
+// IsSSHHost reports whether the docker host uses the ssh:// scheme.
+func IsSSHHost(host string) bool {
+	return strings.HasPrefix(host, "ssh://")
+}
+
+// HostAddress returns the network address of a remote docker host, or an
+// empty string for local unix socket or named pipe hosts.
+func HostAddress(host string) string {
+	hostUrl, err := url.Parse(host)
+	if err != nil {
+		return ""
+	}
+
+	switch hostUrl.Scheme {
+	case "tcp", "ssh", "http", "https":
+		return hostUrl.Hostname()
+	}
+	return ""
+}
+
+type commandConn struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     io.ReadCloser
+	remoteAddr string
+
+	closeOnce sync.Once
+}
+
+var _ net.Conn = (*commandConn)(nil)
+
+func (c *commandConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *commandConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return commandAddr("local") }
+func (c *commandConn) RemoteAddr() net.Addr { return commandAddr(c.remoteAddr) }
+
+// deadlines are not supported over a pipe, so we ignore them
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
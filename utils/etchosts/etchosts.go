@@ -0,0 +1,117 @@
+package etchosts
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	blockBeginPrefix = "# cbdinocluster begin "
+	blockEndPrefix   = "# cbdinocluster end "
+)
+
+// DefaultPath returns the path of the hosts file of this machine.
+func DefaultPath() string {
+	if runtime.GOOS == "windows" {
+		return `C:\Windows\System32\drivers\etc\hosts`
+	}
+	return "/etc/hosts"
+}
+
+type Entry struct {
+	Address  string
+	Hostname string
+}
+
+// SetBlock replaces the block of entries with the specified name in the
+// contents of a hosts file, appending the block if it does not exist yet.
+func SetBlock(contents string, name string, entries []Entry) string {
+	contents = RemoveBlocks(contents, func(blockName string) bool {
+		return blockName == name
+	})
+
+	var block strings.Builder
+	block.WriteString(blockBeginPrefix + name + "\n")
+	for _, entry := range entries {
+		block.WriteString(fmt.Sprintf("%s\t%s\n", entry.Address, entry.Hostname))
+	}
+	block.WriteString(blockEndPrefix + name + "\n")
+
+	if contents != "" && !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	return contents + block.String()
+}
+
+// RemoveBlocks removes every block of entries whose name matches from the
+// contents of a hosts file, leaving all other lines untouched.
+func RemoveBlocks(contents string, matches func(name string) bool) string {
+	lines := strings.SplitAfter(contents, "\n")
+
+	var out strings.Builder
+	removingName := ""
+	for _, line := range lines {
+		trimmedLine := strings.TrimRight(line, "\r\n")
+
+		if removingName != "" {
+			if trimmedLine == blockEndPrefix+removingName {
+				removingName = ""
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmedLine, blockBeginPrefix) {
+			name := strings.TrimPrefix(trimmedLine, blockBeginPrefix)
+			if matches(name) {
+				removingName = name
+				continue
+			}
+		}
+
+		out.WriteString(line)
+	}
+
+	return out.String()
+}
+
+// BlockNames returns the names of the blocks of entries in the contents of
+// a hosts file.
+func BlockNames(contents string) []string {
+	var names []string
+	for _, line := range strings.Split(contents, "\n") {
+		trimmedLine := strings.TrimRight(line, "\r")
+		if strings.HasPrefix(trimmedLine, blockBeginPrefix) {
+			names = append(names, strings.TrimPrefix(trimmedLine, blockBeginPrefix))
+		}
+	}
+	return names
+}
+
+// UpdateFile rewrites a hosts file in place.  The file is not replaced, as
+// it is commonly a bind mount or a symlink managed by something else.
+func UpdateFile(path string, update func(contents string) string) error {
+	contentsBytes, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read hosts file")
+	}
+
+	contents := string(contentsBytes)
+	newContents := update(contents)
+	if newContents == contents {
+		return nil
+	}
+
+	err = os.WriteFile(path, []byte(newContents), 0644)
+	if err != nil {
+		if os.IsPermission(err) {
+			return errors.Wrap(err, "failed to write hosts file, this usually requires running as root")
+		}
+		return errors.Wrap(err, "failed to write hosts file")
+	}
+
+	return nil
+}
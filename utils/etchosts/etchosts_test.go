@@ -0,0 +1,54 @@
+package etchosts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocks(t *testing.T) {
+	original := "127.0.0.1\tlocalhost\n::1\tlocalhost"
+
+	contents := SetBlock(original, "aaaa", []Entry{
+		{Address: "172.17.0.2", Hostname: "node1.aaaa.test"},
+		{Address: "172.17.0.3", Hostname: "node2.aaaa.test"},
+	})
+	contents = SetBlock(contents, "bbbb", []Entry{
+		{Address: "172.17.0.4", Hostname: "node1.bbbb.test"},
+	})
+	assert.Equal(t, "127.0.0.1\tlocalhost\n::1\tlocalhost\n"+
+		"# cbdinocluster begin aaaa\n"+
+		"172.17.0.2\tnode1.aaaa.test\n"+
+		"172.17.0.3\tnode2.aaaa.test\n"+
+		"# cbdinocluster end aaaa\n"+
+		"# cbdinocluster begin bbbb\n"+
+		"172.17.0.4\tnode1.bbbb.test\n"+
+		"# cbdinocluster end bbbb\n", contents)
+	assert.Equal(t, []string{"aaaa", "bbbb"}, BlockNames(contents))
+
+	// replacing a block moves it to the end rather than duplicating it
+	contents = SetBlock(contents, "aaaa", []Entry{
+		{Address: "172.17.0.5", Hostname: "node3.aaaa.test"},
+	})
+	assert.Equal(t, []string{"bbbb", "aaaa"}, BlockNames(contents))
+
+	contents = RemoveBlocks(contents, func(name string) bool { return true })
+	assert.Equal(t, original+"\n", contents)
+}
+
+func TestUpdateFile(t *testing.T) {
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644))
+
+	err := UpdateFile(hostsPath, func(contents string) string {
+		return SetBlock(contents, "aaaa", []Entry{{Address: "10.0.0.1", Hostname: "a.test"}})
+	})
+	require.NoError(t, err)
+
+	contentsBytes, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aaaa"}, BlockNames(string(contentsBytes)))
+}
@@ -0,0 +1,259 @@
+package faultproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type FaultType string
+
+const (
+	FaultTypeNone  FaultType = "none"
+	FaultTypeDelay FaultType = "delay"
+	FaultTypeDrop  FaultType = "drop"
+	FaultTypeReset FaultType = "reset"
+)
+
+type Fault struct {
+	Type  FaultType     `json:"type"`
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+func (f Fault) Validate() error {
+	switch f.Type {
+	case FaultTypeNone, FaultTypeDrop, FaultTypeReset:
+		return nil
+	case FaultTypeDelay:
+		if f.Delay <= 0 {
+			return errors.New("delay faults require a positive delay")
+		}
+		return nil
+	}
+	return errors.Errorf("unknown fault type `%s`", f.Type)
+}
+
+type ProxyOptions struct {
+	Logger        *zap.Logger
+	ListenAddress string
+	TargetAddress string
+}
+
+// Proxy is a TCP proxy which forwards connections to a single target
+// address, applying whatever fault is currently configured to the traffic.
+// Faults can be changed at any time and apply to both new and existing
+// connections.
+type Proxy struct {
+	logger        *zap.Logger
+	listenAddress string
+	targetAddress string
+
+	lock     sync.Mutex
+	fault    Fault
+	conns    map[net.Conn]struct{}
+	listener net.Listener
+}
+
+func NewProxy(opts *ProxyOptions) *Proxy {
+	return &Proxy{
+		logger:        opts.Logger,
+		listenAddress: opts.ListenAddress,
+		targetAddress: opts.TargetAddress,
+		fault:         Fault{Type: FaultTypeNone},
+		conns:         make(map[net.Conn]struct{}),
+	}
+}
+
+func (p *Proxy) ListenAddress() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.listener != nil {
+		return p.listener.Addr().String()
+	}
+	return p.listenAddress
+}
+
+func (p *Proxy) TargetAddress() string {
+	return p.targetAddress
+}
+
+func (p *Proxy) GetFault() Fault {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.fault
+}
+
+func (p *Proxy) SetFault(fault Fault) error {
+	err := fault.Validate()
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	p.fault = fault
+
+	// a reset applies immediately to all existing connections as well
+	var connsToReset []net.Conn
+	if fault.Type == FaultTypeReset {
+		for conn := range p.conns {
+			connsToReset = append(connsToReset, conn)
+		}
+	}
+	p.lock.Unlock()
+
+	p.logger.Info("proxy fault updated",
+		zap.String("listen", p.listenAddress),
+		zap.String("target", p.targetAddress),
+		zap.String("type", string(fault.Type)),
+		zap.Duration("delay", fault.Delay))
+
+	for _, conn := range connsToReset {
+		resetConn(conn)
+	}
+
+	return nil
+}
+
+func (p *Proxy) Listen() error {
+	listener, err := net.Listen("tcp", p.listenAddress)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	p.lock.Lock()
+	p.listener = listener
+	p.lock.Unlock()
+
+	return nil
+}
+
+// Run accepts connections until the context is cancelled.  Listen will be
+// invoked automatically if it has not already been called.
+func (p *Proxy) Run(ctx context.Context) error {
+	p.lock.Lock()
+	listener := p.listener
+	p.lock.Unlock()
+
+	if listener == nil {
+		err := p.Listen()
+		if err != nil {
+			return err
+		}
+
+		p.lock.Lock()
+		listener = p.listener
+		p.lock.Unlock()
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to accept connection")
+		}
+
+		go p.handleConn(ctx, conn)
+	}
+}
+
+func (p *Proxy) trackConn(conn net.Conn) {
+	p.lock.Lock()
+	p.conns[conn] = struct{}{}
+	p.lock.Unlock()
+}
+
+func (p *Proxy) untrackConn(conn net.Conn) {
+	p.lock.Lock()
+	delete(p.conns, conn)
+	p.lock.Unlock()
+}
+
+func (p *Proxy) handleConn(ctx context.Context, clientConn net.Conn) {
+	if p.GetFault().Type == FaultTypeReset {
+		resetConn(clientConn)
+		return
+	}
+
+	p.trackConn(clientConn)
+	defer p.untrackConn(clientConn)
+	defer clientConn.Close()
+
+	dialer := &net.Dialer{}
+	targetConn, err := dialer.DialContext(ctx, "tcp", p.targetAddress)
+	if err != nil {
+		p.logger.Debug("failed to connect to proxy target",
+			zap.String("target", p.targetAddress),
+			zap.Error(err))
+		return
+	}
+
+	p.trackConn(targetConn)
+	defer p.untrackConn(targetConn)
+	defer targetConn.Close()
+
+	waitCh := make(chan struct{}, 2)
+	go func() {
+		p.pipe(targetConn, clientConn)
+		waitCh <- struct{}{}
+	}()
+	go func() {
+		p.pipe(clientConn, targetConn)
+		waitCh <- struct{}{}
+	}()
+
+	// once either side closes, we tear down the whole connection
+	<-waitCh
+}
+
+func (p *Proxy) pipe(dst net.Conn, src net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			fault := p.GetFault()
+			switch fault.Type {
+			case FaultTypeDelay:
+				time.Sleep(fault.Delay)
+			case FaultTypeDrop:
+				// the data is silently discarded
+				continue
+			case FaultTypeReset:
+				resetConn(src)
+				resetConn(dst)
+				return
+			}
+
+			_, werr := dst.Write(buf[:n])
+			if werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				p.logger.Debug("proxy connection closed", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+func resetConn(conn net.Conn) {
+	// setting a zero linger causes the close to send a RST rather than FIN
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
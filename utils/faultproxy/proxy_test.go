@@ -0,0 +1,87 @@
+package faultproxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				rdr := bufio.NewReader(conn)
+				for {
+					line, err := rdr.ReadString('\n')
+					if err != nil {
+						return
+					}
+					conn.Write([]byte(line))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestProxyFaults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	proxy := NewProxy(&ProxyOptions{
+		Logger:        zap.NewNop(),
+		ListenAddress: "127.0.0.1:0",
+		TargetAddress: startEchoServer(t),
+	})
+	require.NoError(t, proxy.Listen())
+	go proxy.Run(ctx)
+
+	conn, err := net.Dial("tcp", proxy.ListenAddress())
+	require.NoError(t, err)
+	defer conn.Close()
+	rdr := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	line, err := rdr.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", line)
+
+	require.NoError(t, proxy.SetFault(Fault{Type: FaultTypeDelay, Delay: 200 * time.Millisecond}))
+
+	startTime := time.Now()
+	_, err = conn.Write([]byte("slow\n"))
+	require.NoError(t, err)
+	line, err = rdr.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "slow\n", line)
+	require.GreaterOrEqual(t, time.Since(startTime), 200*time.Millisecond)
+
+	require.NoError(t, proxy.SetFault(Fault{Type: FaultTypeReset}))
+
+	_, err = net.Dial("tcp", proxy.ListenAddress())
+	if err == nil {
+		// the connection may be accepted before being reset, in which case
+		// the reset is observed on the first read instead.
+		_, err = rdr.ReadString('\n')
+	}
+	require.Error(t, err)
+
+	require.Error(t, proxy.SetFault(Fault{Type: "bogus"}))
+}
@@ -0,0 +1,101 @@
+package faultproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+type ProxyInfo struct {
+	Name          string `json:"name"`
+	ListenAddress string `json:"listen"`
+	TargetAddress string `json:"target"`
+	Fault         Fault  `json:"fault"`
+}
+
+// ControlServer exposes an HTTP api which allows the faults of a set of
+// named proxies to be inspected and changed at runtime.
+//
+//	GET /proxies            lists all proxies
+//	PUT /proxies/{name}     sets the fault for a proxy (JSON Fault body)
+type ControlServer struct {
+	lock    sync.Mutex
+	proxies map[string]*Proxy
+}
+
+func (s *ControlServer) AddProxy(name string, proxy *Proxy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.proxies == nil {
+		s.proxies = make(map[string]*Proxy)
+	}
+	s.proxies[name] = proxy
+}
+
+func (s *ControlServer) ListProxies() []ProxyInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := maps.Keys(s.proxies)
+	slices.Sort(names)
+
+	var out []ProxyInfo
+	for _, name := range names {
+		proxy := s.proxies[name]
+		out = append(out, ProxyInfo{
+			Name:          name,
+			ListenAddress: proxy.ListenAddress(),
+			TargetAddress: proxy.TargetAddress(),
+			Fault:         proxy.GetFault(),
+		})
+	}
+	return out
+}
+
+func (s *ControlServer) getProxy(name string) *Proxy {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.proxies[name]
+}
+
+func (s *ControlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	pathParts := strings.Split(path, "/")
+
+	if len(pathParts) == 1 && pathParts[0] == "proxies" && r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.ListProxies())
+		return
+	}
+
+	if len(pathParts) == 2 && pathParts[0] == "proxies" && r.Method == http.MethodPut {
+		proxy := s.getProxy(pathParts[1])
+		if proxy == nil {
+			http.Error(w, "proxy not found", http.StatusNotFound)
+			return
+		}
+
+		var fault Fault
+		err := json.NewDecoder(r.Body).Decode(&fault)
+		if err != nil {
+			http.Error(w, "invalid fault: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = proxy.SetFault(fault)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Error(w, "not found", http.StatusNotFound)
+}
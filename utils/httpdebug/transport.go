@@ -0,0 +1,200 @@
+package httpdebug
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	redactedValue = "<redacted>"
+
+	// maxLoggedBodyLen is the maximum number of bytes of a body that are
+	// logged, bodies larger than this are truncated.
+	maxLoggedBodyLen = 16 * 1024
+)
+
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+var sensitiveKeyRegexp = regexp.MustCompile(`(?i)(password|passwd|secret|token|apikey|api_key|accesskey|access_key|credential|jwt|bearer|authorization)`)
+
+// Transport wraps another http.RoundTripper, logging the full requests and
+// responses which pass through it at debug level.  Authorization headers and
+// any fields which look like they contain credentials are redacted.
+type Transport struct {
+	Logger *zap.Logger
+	Base   http.RoundTripper
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func NewTransport(logger *zap.Logger, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		Logger: logger,
+		Base:   base,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Logger.Debug("http request",
+		zap.String("method", req.Method),
+		zap.String("url", RedactUrl(req.URL)),
+		zap.Any("headers", RedactHeaders(req.Header)),
+		zap.String("body", RedactBody(req.Header.Get("Content-Type"), reqBody)))
+
+	stime := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	etime := time.Now()
+	if err != nil {
+		t.Logger.Debug("http request failed",
+			zap.String("method", req.Method),
+			zap.String("url", RedactUrl(req.URL)),
+			zap.Duration("duration", etime.Sub(stime)),
+			zap.Error(err))
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	t.Logger.Debug("http response",
+		zap.String("method", req.Method),
+		zap.String("url", RedactUrl(req.URL)),
+		zap.Int("status", resp.StatusCode),
+		zap.Duration("duration", etime.Sub(stime)),
+		zap.Any("headers", RedactHeaders(resp.Header)),
+		zap.String("body", RedactBody(resp.Header.Get("Content-Type"), respBody)))
+
+	return resp, nil
+}
+
+// readBody reads a body in its entirety and then replaces it with a reader
+// over the read bytes so it can still be consumed as normal.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	bodyBytes, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(bodyBytes))
+	return bodyBytes, nil
+}
+
+func RedactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for header := range redacted {
+		if slices.Contains(sensitiveHeaders, header) || sensitiveKeyRegexp.MatchString(header) {
+			redacted[header] = []string{redactedValue}
+		}
+	}
+	return redacted
+}
+
+func RedactUrl(reqUrl *url.URL) string {
+	redactedUrl := *reqUrl
+	if redactedUrl.User != nil {
+		redactedUrl.User = url.User(redactedUrl.User.Username())
+	}
+	redactedUrl.RawQuery = redactValues(reqUrl.Query()).Encode()
+	return redactedUrl.String()
+}
+
+func redactValues(values url.Values) url.Values {
+	for key := range values {
+		if sensitiveKeyRegexp.MatchString(key) {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return values
+}
+
+func redactJson(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range typedValue {
+			if sensitiveKeyRegexp.MatchString(key) {
+				typedValue[key] = redactedValue
+			} else {
+				typedValue[key] = redactJson(fieldValue)
+			}
+		}
+	case []interface{}:
+		for idx, itemValue := range typedValue {
+			typedValue[idx] = redactJson(itemValue)
+		}
+	}
+	return value
+}
+
+// RedactBody redacts any credential-like fields from JSON and form bodies.
+// Other bodies cannot be safely redacted, so only their length is logged.
+func RedactBody(contentType string, body []byte) string {
+	redacted := RedactFullBody(contentType, body)
+	if len(redacted) > maxLoggedBodyLen {
+		redacted = redacted[:maxLoggedBodyLen] + "...<truncated>"
+	}
+	return redacted
+}
+
+// RedactFullBody is RedactBody without truncating large bodies.
+func RedactFullBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var redacted string
+	switch {
+	case strings.Contains(contentType, "application/json") || json.Valid(body):
+		var parsed interface{}
+		err := json.Unmarshal(body, &parsed)
+		if err != nil {
+			return "<unparseable json body>"
+		}
+
+		redactedBytes, _ := json.Marshal(redactJson(parsed))
+		redacted = string(redactedBytes)
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "<unparseable form body>"
+		}
+
+		redacted = redactValues(values).Encode()
+	case strings.HasPrefix(contentType, "text/"):
+		redacted = string(body)
+	default:
+		return "<binary body>"
+	}
+
+	return redacted
+}
@@ -0,0 +1,255 @@
+// Package httpreplay records the requests and responses of an http client
+// into fixture files, and replays them later, allowing clients of REST APIs
+// to be tested without access to the real service.
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/httpdebug"
+	"github.com/pkg/errors"
+)
+
+// Interaction is a single recorded request and its response.  Requests are
+// identified by their method and their path and query, since the endpoint
+// differs between recording and replay.  Credentials are redacted from both
+// the request and response.
+type Interaction struct {
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	RequestBody  string      `json:"request_body,omitempty"`
+	StatusCode   int         `json:"status_code"`
+	Headers      http.Header `json:"headers,omitempty"`
+	ResponseBody string      `json:"response_body,omitempty"`
+}
+
+type Fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	fixtureBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fixture")
+	}
+
+	var fixture *Fixture
+	err = json.Unmarshal(fixtureBytes, &fixture)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse fixture")
+	}
+
+	return fixture, nil
+}
+
+func (f *Fixture) Save(path string) error {
+	fixtureBytes, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal fixture")
+	}
+
+	err = os.WriteFile(path, append(fixtureBytes, '\n'), 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write fixture")
+	}
+
+	return nil
+}
+
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	bodyBytes, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(bodyBytes))
+	return bodyBytes, nil
+}
+
+// requestPath returns the path and query of a request, with the query
+// redacted and normalized so that recorded and replayed requests match.
+func requestPath(req *http.Request) string {
+	reqUrl, err := url.Parse(httpdebug.RedactUrl(req.URL))
+	if err != nil {
+		reqUrl = req.URL
+	}
+
+	if reqUrl.RawQuery == "" {
+		return reqUrl.Path
+	}
+	return reqUrl.Path + "?" + reqUrl.RawQuery
+}
+
+// Recorder wraps another http.RoundTripper, recording every interaction
+// which passes through it.
+type Recorder struct {
+	Base http.RoundTripper
+
+	// Replacements are substituted into recorded interactions, allowing
+	// identifiers of the account used for recording, such as tenant IDs,
+	// to be replaced with the stable values used by tests.
+	Replacements map[string]string
+
+	lock    sync.Mutex
+	fixture Fixture
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+func NewRecorder(base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Recorder{
+		Base: base,
+	}
+}
+
+func (r *Recorder) sanitize(value string) string {
+	for oldValue, newValue := range r.Replacements {
+		value = strings.ReplaceAll(value, oldValue, newValue)
+	}
+	return value
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	headers := http.Header{}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+
+	interaction := &Interaction{
+		Method:       req.Method,
+		Path:         r.sanitize(requestPath(req)),
+		RequestBody:  r.sanitize(httpdebug.RedactFullBody(req.Header.Get("Content-Type"), reqBody)),
+		StatusCode:   resp.StatusCode,
+		Headers:      headers,
+		ResponseBody: r.sanitize(httpdebug.RedactFullBody(resp.Header.Get("Content-Type"), respBody)),
+	}
+
+	r.lock.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+	r.lock.Unlock()
+
+	return resp, nil
+}
+
+// Fixture returns the interactions recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return &Fixture{
+		Interactions: append([]*Interaction{}, r.fixture.Interactions...),
+	}
+}
+
+// Replayer is an http.RoundTripper which responds to requests using the
+// interactions of a fixture rather than performing them.  Each interaction
+// is used at most once, in the order they were recorded, and requests which
+// do not match any remaining interaction fail.
+type Replayer struct {
+	lock         sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+var _ http.RoundTripper = (*Replayer)(nil)
+
+func NewReplayer(fixture *Fixture) *Replayer {
+	return &Replayer{
+		interactions: fixture.Interactions,
+		used:         make([]bool, len(fixture.Interactions)),
+	}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	path := requestPath(req)
+	redactedBody := httpdebug.RedactFullBody(req.Header.Get("Content-Type"), reqBody)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var mismatches []string
+	for idx, interaction := range r.interactions {
+		if r.used[idx] || interaction.Method != req.Method || interaction.Path != path {
+			continue
+		}
+
+		if interaction.RequestBody != redactedBody {
+			mismatches = append(mismatches, fmt.Sprintf("expected body %s but got %s",
+				interaction.RequestBody, redactedBody))
+			continue
+		}
+
+		r.used[idx] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.ResponseBody)),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("no recorded interaction matches %s %s: %s",
+			req.Method, path, strings.Join(mismatches, "; "))
+	}
+	return nil, fmt.Errorf("no recorded interaction matches %s %s", req.Method, path)
+}
+
+// Unused returns the interactions which have not been replayed, which
+// usually indicates a request the client no longer makes.
+func (r *Replayer) Unused() []*Interaction {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var unused []*Interaction
+	for idx, interaction := range r.interactions {
+		if !r.used[idx] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
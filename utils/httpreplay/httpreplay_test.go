@@ -0,0 +1,60 @@
+package httpreplay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tenantId":"real-tenant","token":"secret"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	recorder.Replacements = map[string]string{"real-tenant": "test-tenant"}
+	client := &http.Client{Transport: recorder}
+
+	resp, err := client.Post(server.URL+"/real-tenant/items?password=hunter2&page=1",
+		"application/json", strings.NewReader(`{"name":"a"}`))
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Contains(t, string(respBody), "secret")
+
+	fixturePath := filepath.Join(t.TempDir(), "fixture.json")
+	require.NoError(t, recorder.Fixture().Save(fixturePath))
+
+	fixture, err := LoadFixture(fixturePath)
+	require.NoError(t, err)
+	require.Len(t, fixture.Interactions, 1)
+	require.Equal(t, "/test-tenant/items?page=1&password=%3Credacted%3E", fixture.Interactions[0].Path)
+	require.NotContains(t, fixture.Interactions[0].ResponseBody, "secret")
+
+	replayer := NewReplayer(fixture)
+	client = &http.Client{Transport: replayer}
+
+	_, err = client.Post("http://replay/test-tenant/items?password=other&page=1",
+		"application/json", strings.NewReader(`{"name":"b"}`))
+	require.ErrorContains(t, err, "expected body")
+
+	resp, err = client.Post("http://replay/test-tenant/items?page=1&password=other",
+		"application/json", strings.NewReader(`{"name":"a"}`))
+	require.NoError(t, err)
+	respBody, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(respBody), "test-tenant")
+	require.Empty(t, replayer.Unused())
+
+	_, err = client.Post("http://replay/test-tenant/items?page=1",
+		"application/json", strings.NewReader(`{"name":"a"}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,469 @@
+package kvbench
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/clustercontrol"
+	"github.com/couchbaselabs/cbdinocluster/utils/memdclient"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// agentName is the name our connections identify themselves with.
+const agentName = "cbdinocluster-kvbench"
+
+type OpType string
+
+const (
+	OpTypeGet   OpType = "get"
+	OpTypeSet   OpType = "set"
+	OpTypeQuery OpType = "query"
+)
+
+type Options struct {
+	Logger     *zap.Logger
+	ConnStr    string
+	Username   string
+	Password   string
+	BucketName string
+
+	// Duration is how long the load is run for, not including loading the
+	// documents which are read.
+	Duration time.Duration
+
+	// Workers is the number of operations which are run concurrently.
+	Workers int
+
+	// NumKeys is the number of documents which are read and written, and
+	// DocSize the approximate size of each of them in bytes.
+	NumKeys int
+	DocSize int
+
+	// ReadRatio is the fraction of kv operations which are gets rather than
+	// sets, and QueryRatio the fraction of all operations which are queries.
+	ReadRatio  float64
+	QueryRatio float64
+
+	// ConnectTimeout is how long to keep retrying the initial connection,
+	// as newly created credentials can take a while to become usable.
+	ConnectTimeout time.Duration
+}
+
+type OpResult struct {
+	Type       OpType
+	Errors     int
+	FirstError string
+	Latencies  *Histogram
+}
+
+type Result struct {
+	Duration time.Duration
+	Ops      []*OpResult
+}
+
+type benchDoc struct {
+	Key     string `json:"key"`
+	Padding string `json:"padding"`
+}
+
+type bencher struct {
+	opts        *Options
+	tlsConfig   *tls.Config
+	useTLS      bool
+	routing     *memdclient.Routing
+	httpClient  *http.Client
+	docBytesFor func(key string) []byte
+}
+
+// Run loads the documents and then runs a mixed load of gets, sets and
+// queries against the bucket for the duration, recording the latency of
+// every operation.
+func Run(ctx context.Context, opts *Options) (*Result, error) {
+	if opts.Workers <= 0 {
+		return nil, errors.New("at least one worker is required")
+	}
+	if opts.NumKeys <= 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = 1 * time.Minute
+	}
+
+	connStrInfo, err := memdclient.ParseConnStr(opts.ConnStr)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bencher{
+		opts:   opts,
+		useTLS: connStrInfo.UseTLS,
+	}
+	if connStrInfo.UseTLS {
+		// this is a benchmarking tool, so we do not verify certificates
+		b.tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	b.httpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:     b.tlsConfig,
+			MaxIdleConnsPerHost: opts.Workers,
+		},
+	}
+
+	padding := strings.Repeat("x", max(opts.DocSize-32, 0))
+	b.docBytesFor = func(key string) []byte {
+		docBytes, _ := json.Marshal(benchDoc{Key: key, Padding: padding})
+		return docBytes
+	}
+
+	err = b.fetchRouting(ctx, connStrInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	queryRatio := opts.QueryRatio
+	if queryRatio > 0 && len(b.routing.QueryAddresses) == 0 {
+		opts.Logger.Warn("cluster has no query nodes, only running kv operations")
+		queryRatio = 0
+	}
+
+	opts.Logger.Info("loading documents", zap.Int("numKeys", opts.NumKeys))
+
+	err = b.runWorkers(ctx, func(workerIdx int, conns *workerConns) error {
+		for keyIdx := workerIdx; keyIdx < opts.NumKeys; keyIdx += opts.Workers {
+			err := b.set(ctx, conns, benchKey(keyIdx))
+			if err != nil {
+				return errors.Wrap(err, "failed to load document")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Logger.Info("running load",
+		zap.Duration("duration", opts.Duration),
+		zap.Int("workers", opts.Workers))
+
+	var resultsLock sync.Mutex
+	results := make(map[OpType]*OpResult)
+	for _, opType := range []OpType{OpTypeGet, OpTypeSet, OpTypeQuery} {
+		results[opType] = &OpResult{
+			Type:      opType,
+			Latencies: &Histogram{},
+		}
+	}
+
+	startTime := time.Now()
+	deadline := startTime.Add(opts.Duration)
+
+	err = b.runWorkers(ctx, func(workerIdx int, conns *workerConns) error {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(workerIdx)))
+
+		workerResults := make(map[OpType]*OpResult)
+		for opType := range results {
+			workerResults[opType] = &OpResult{
+				Type:      opType,
+				Latencies: &Histogram{},
+			}
+		}
+
+		for time.Now().Before(deadline) && ctx.Err() == nil {
+			key := benchKey(rnd.Intn(opts.NumKeys))
+
+			var opType OpType
+			if rnd.Float64() < queryRatio {
+				opType = OpTypeQuery
+			} else if rnd.Float64() < opts.ReadRatio {
+				opType = OpTypeGet
+			} else {
+				opType = OpTypeSet
+			}
+
+			opStart := time.Now()
+			var err error
+			switch opType {
+			case OpTypeGet:
+				err = b.get(ctx, conns, key)
+			case OpTypeSet:
+				err = b.set(ctx, conns, key)
+			case OpTypeQuery:
+				err = b.query(ctx, rnd, key)
+			}
+			latency := time.Since(opStart)
+
+			opResult := workerResults[opType]
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+
+				opResult.Errors++
+				if opResult.FirstError == "" {
+					opResult.FirstError = err.Error()
+				}
+
+				// connections are reestablished after errors, as the
+				// response to the failed request may still be pending
+				conns.Reset()
+				continue
+			}
+
+			opResult.Latencies.Record(latency)
+		}
+
+		resultsLock.Lock()
+		defer resultsLock.Unlock()
+
+		for opType, workerResult := range workerResults {
+			result := results[opType]
+			result.Latencies.Merge(workerResult.Latencies)
+			result.Errors += workerResult.Errors
+			if result.FirstError == "" {
+				result.FirstError = workerResult.FirstError
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	benchResult := &Result{
+		Duration: time.Since(startTime),
+	}
+	for _, opType := range []OpType{OpTypeGet, OpTypeSet, OpTypeQuery} {
+		result := results[opType]
+		if result.Latencies.Count() == 0 && result.Errors == 0 {
+			continue
+		}
+		benchResult.Ops = append(benchResult.Ops, result)
+	}
+
+	return benchResult, nil
+}
+
+func benchKey(keyIdx int) string {
+	return fmt.Sprintf("cbdc-bench-%d", keyIdx)
+}
+
+// fetchRouting connects to the first reachable seed node and fetches the
+// routing of the bucket from it.
+func (b *bencher) fetchRouting(ctx context.Context, connStrInfo *memdclient.ConnStrInfo) error {
+	connectCtx, cancel := context.WithTimeout(ctx, b.opts.ConnectTimeout)
+	defer cancel()
+
+	for {
+		var lastErr error
+		for _, address := range connStrInfo.Addresses {
+			conn, err := b.dial(connectCtx, address)
+			if err != nil {
+				b.opts.Logger.Debug("failed to connect to seed node",
+					zap.String("address", address),
+					zap.Error(err))
+				lastErr = err
+				continue
+			}
+
+			configResp, err := conn.Request(memdclient.OpGetClusterConfig, 0, nil, nil, nil)
+			conn.Close()
+			if err != nil {
+				return errors.Wrap(err, "failed to fetch cluster config")
+			}
+
+			seedHost, _, _ := net.SplitHostPort(address)
+			b.routing, err = memdclient.ParseClusterConfig(configResp.Value, seedHost, connStrInfo.UseTLS, connStrInfo.Network)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-connectCtx.Done():
+			return errors.Wrap(lastErr, "failed to connect to any seed node")
+		}
+	}
+}
+
+func (b *bencher) dial(ctx context.Context, address string) (*memdclient.Conn, error) {
+	conn, err := memdclient.Dial(ctx, address, b.tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Setup(agentName, b.opts.Username, b.opts.Password, b.opts.BucketName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// workerConns holds the connections of a single worker to each server, which
+// are established on first use.  They are locked as they are also closed when
+// the worker is cancelled.
+type workerConns struct {
+	b     *bencher
+	lock  sync.Mutex
+	conns []*memdclient.Conn
+}
+
+func (c *workerConns) Get(ctx context.Context, serverIdx int) (*memdclient.Conn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conns[serverIdx] != nil {
+		return c.conns[serverIdx], nil
+	}
+
+	conn, err := c.b.dial(ctx, c.b.routing.Addresses[serverIdx])
+	if err != nil {
+		return nil, err
+	}
+
+	c.conns[serverIdx] = conn
+	return conn, nil
+}
+
+func (c *workerConns) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for serverIdx, conn := range c.conns {
+		if conn != nil {
+			conn.Close()
+			c.conns[serverIdx] = nil
+		}
+	}
+}
+
+// runWorkers runs fn on each of the workers concurrently, returning the
+// first error any of them failed with.
+func (b *bencher) runWorkers(ctx context.Context, fn func(workerIdx int, conns *workerConns) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var waitGrp sync.WaitGroup
+	errCh := make(chan error, b.opts.Workers)
+	for workerIdx := 0; workerIdx < b.opts.Workers; workerIdx++ {
+		waitGrp.Add(1)
+		go func(workerIdx int) {
+			defer waitGrp.Done()
+
+			conns := &workerConns{
+				b:     b,
+				conns: make([]*memdclient.Conn, len(b.routing.Addresses)),
+			}
+			defer conns.Reset()
+
+			// blocked requests are unblocked by closing their connections
+			// once we are cancelled
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			go func() {
+				select {
+				case <-ctx.Done():
+					conns.Reset()
+				case <-stopCh:
+				}
+			}()
+
+			err := fn(workerIdx, conns)
+			if err != nil {
+				errCh <- err
+				cancel()
+			}
+		}(workerIdx)
+	}
+	waitGrp.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+func (b *bencher) get(ctx context.Context, conns *workerConns, key string) error {
+	serverIdx, vbID := b.routing.ServerForKey([]byte(key))
+	conn, err := conns.Get(ctx, serverIdx)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Request(memdclient.OpGet, vbID, nil, []byte(key), nil)
+	return err
+}
+
+func (b *bencher) set(ctx context.Context, conns *workerConns, key string) error {
+	serverIdx, vbID := b.routing.ServerForKey([]byte(key))
+	conn, err := conns.Get(ctx, serverIdx)
+	if err != nil {
+		return err
+	}
+
+	// the extras hold the flags followed by the expiry
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras, clustercontrol.JsonDocumentFlags)
+
+	_, err = conn.Request(memdclient.OpSet, vbID, extras, []byte(key), b.docBytesFor(key))
+	return err
+}
+
+func (b *bencher) query(ctx context.Context, rnd *rand.Rand, key string) error {
+	scheme := "http"
+	if b.useTLS {
+		scheme = "https"
+	}
+	address := b.routing.QueryAddresses[rnd.Intn(len(b.routing.QueryAddresses))]
+
+	// USE KEYS fetches the document directly, so that no index is needed
+	reqBytes, _ := json.Marshal(map[string]interface{}{
+		"statement": fmt.Sprintf("SELECT META(d).id FROM `%s` AS d USE KEYS $key", b.opts.BucketName),
+		"$key":      key,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		scheme+"://"+address+"/query/service", bytes.NewReader(reqBytes))
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+
+	req.SetBasicAuth(b.opts.Username, b.opts.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute query")
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read query response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("query failed with status %d: %s", resp.StatusCode, respBytes)
+	}
+
+	return nil
+}
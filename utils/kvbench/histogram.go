@@ -0,0 +1,144 @@
+package kvbench
+
+import (
+	"math"
+	"time"
+)
+
+// bucketsPerDoubling is the number of histogram buckets between each power of
+// two, which bounds the error of a percentile to about 19%.
+const bucketsPerDoubling = 4
+
+// Histogram records latencies in buckets which grow exponentially from one
+// microsecond, so that both fast and slow operations are recorded with the
+// same relative precision in a small fixed amount of memory.
+type Histogram struct {
+	counts []int
+	count  int
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+type HistogramBucket struct {
+	// UpperBound is the exclusive upper bound of the latencies in the bucket.
+	UpperBound time.Duration
+	Count      int
+}
+
+func bucketIndex(latency time.Duration) int {
+	micros := latency.Microseconds()
+	if micros < 1 {
+		return 0
+	}
+	return int(math.Log2(float64(micros))*bucketsPerDoubling) + 1
+}
+
+func bucketUpperBound(bucketIdx int) time.Duration {
+	return time.Duration(math.Pow(2, float64(bucketIdx)/bucketsPerDoubling) * float64(time.Microsecond))
+}
+
+func (h *Histogram) Record(latency time.Duration) {
+	bucketIdx := bucketIndex(latency)
+	for len(h.counts) <= bucketIdx {
+		h.counts = append(h.counts, 0)
+	}
+	h.counts[bucketIdx]++
+
+	if h.count == 0 || latency < h.min {
+		h.min = latency
+	}
+	if latency > h.max {
+		h.max = latency
+	}
+	h.count++
+	h.sum += latency
+}
+
+// Merge adds all the latencies recorded by another histogram to this one.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+
+	for len(h.counts) < len(other.counts) {
+		h.counts = append(h.counts, 0)
+	}
+	for bucketIdx, count := range other.counts {
+		h.counts[bucketIdx] += count
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+func (h *Histogram) Count() int {
+	return h.count
+}
+
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Percentile returns the latency which the given percentage (0-100) of the
+// recorded latencies are below, rounded up to the bound of its bucket.
+func (h *Histogram) Percentile(percentile float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	threshold := int(math.Ceil(float64(h.count) * percentile / 100))
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	seen := 0
+	for bucketIdx, count := range h.counts {
+		seen += count
+		if seen >= threshold {
+			// the bound of the bucket can exceed the largest latency which
+			// was actually recorded in it
+			upperBound := bucketUpperBound(bucketIdx)
+			if upperBound > h.max {
+				return h.max
+			}
+			return upperBound
+		}
+	}
+
+	return h.max
+}
+
+// Buckets returns the buckets which any latencies were recorded in, in
+// increasing order of latency.
+func (h *Histogram) Buckets() []HistogramBucket {
+	var buckets []HistogramBucket
+	for bucketIdx, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+
+		buckets = append(buckets, HistogramBucket{
+			UpperBound: bucketUpperBound(bucketIdx),
+			Count:      count,
+		})
+	}
+	return buckets
+}
@@ -0,0 +1,47 @@
+package kvbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	var hist Histogram
+	require.Equal(t, time.Duration(0), hist.Percentile(50))
+
+	for i := 1; i <= 100; i++ {
+		hist.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	require.Equal(t, 100, hist.Count())
+	require.Equal(t, 1*time.Millisecond, hist.Min())
+	require.Equal(t, 100*time.Millisecond, hist.Max())
+	require.Equal(t, 50500*time.Microsecond, hist.Mean())
+
+	// percentiles are only accurate to the width of their bucket
+	p50 := hist.Percentile(50)
+	require.GreaterOrEqual(t, p50, 50*time.Millisecond)
+	require.Less(t, p50, 60*time.Millisecond)
+	require.Equal(t, 100*time.Millisecond, hist.Percentile(100))
+
+	bucketsTotal := 0
+	for _, bucket := range hist.Buckets() {
+		bucketsTotal += bucket.Count
+	}
+	require.Equal(t, 100, bucketsTotal)
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var hist, other Histogram
+	hist.Record(2 * time.Millisecond)
+	other.Record(500 * time.Microsecond)
+	other.Record(0)
+
+	hist.Merge(&other)
+	require.Equal(t, 3, hist.Count())
+	require.Equal(t, time.Duration(0), hist.Min())
+	require.Equal(t, 2*time.Millisecond, hist.Max())
+	require.Equal(t, time.Microsecond, hist.Percentile(1))
+}
@@ -0,0 +1,109 @@
+// Package logging lets the deployers, which log through zap, be embedded in
+// applications which use a different logging stack.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSlogLogger returns a zap logger which forwards everything logged to it
+// to a slog handler, for passing to the deployers and controllers in place of
+// the zap logger the CLI uses.
+func NewSlogLogger(handler slog.Handler) *zap.Logger {
+	return zap.New(NewSlogCore(handler))
+}
+
+// NewSlogCore returns a zap core which forwards entries to a slog handler.
+// Which levels are logged is decided by the handler.
+func NewSlogCore(handler slog.Handler) zapcore.Core {
+	return &slogCore{
+		handler: handler,
+	}
+}
+
+type slogCore struct {
+	handler slog.Handler
+	fields  []zapcore.Field
+}
+
+var _ zapcore.Core = (*slogCore)(nil)
+
+func slogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// fieldsToAttrs converts zap fields to slog attributes by encoding them as a
+// map, so that every zap field type is supported.  Attributes are sorted by
+// key since the map loses the order of the fields.
+func fieldsToAttrs(fields []zapcore.Field) []slog.Attr {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	keys := make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, enc.Fields[key]))
+	}
+
+	return attrs
+}
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	return &slogCore{
+		handler: c.handler,
+		fields:  append(append([]zapcore.Field(nil), c.fields...), fields...),
+	}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	record := slog.NewRecord(ent.Time, slogLevel(ent.Level), ent.Message, 0)
+
+	if ent.LoggerName != "" {
+		record.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+
+	allFields := append(append([]zapcore.Field(nil), c.fields...), fields...)
+	record.AddAttrs(fieldsToAttrs(allFields)...)
+
+	if ent.Stack != "" {
+		record.AddAttrs(slog.String("stacktrace", ent.Stack))
+	}
+
+	return c.handler.Handle(context.Background(), record)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	})
+
+	logger := NewSlogLogger(handler).Named("deployer").With(zap.String("cluster", "abc"))
+
+	logger.Debug("not logged")
+	logger.Warn("deploying node",
+		zap.Int("node", 2),
+		zap.Error(errors.New("failed")))
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "deploying node", record["msg"])
+	assert.Equal(t, "deployer", record["logger"])
+	assert.Equal(t, "abc", record["cluster"])
+	assert.Equal(t, float64(2), record["node"])
+	assert.Equal(t, "failed", record["error"])
+}
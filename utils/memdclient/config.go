@@ -0,0 +1,195 @@
+package memdclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type clusterConfigNodeJson struct {
+	Hostname           string         `json:"hostname"`
+	ThisNode           bool           `json:"thisNode"`
+	Services           map[string]int `json:"services"`
+	AlternateAddresses map[string]struct {
+		Hostname string         `json:"hostname"`
+		Ports    map[string]int `json:"ports"`
+	} `json:"alternateAddresses"`
+}
+
+type clusterConfigJson struct {
+	NodesExt         []clusterConfigNodeJson `json:"nodesExt"`
+	VBucketServerMap struct {
+		ServerList []string `json:"serverList"`
+		VBucketMap [][]int  `json:"vBucketMap"`
+	} `json:"vBucketServerMap"`
+}
+
+type Routing struct {
+	// Addresses holds the address to connect to for each server.
+	Addresses []string
+
+	// ActiveVbuckets holds the vbuckets active on each server.
+	ActiveVbuckets [][]uint16
+
+	// VbucketServers holds the index of the server each vbucket is active
+	// on, indexed by the vbucket id.
+	VbucketServers []int
+
+	// VbucketReplicas holds the indexes of the servers holding a replica of
+	// each vbucket, indexed by the vbucket id.
+	VbucketReplicas [][]int
+
+	// ThisServer is the index of the server the config was fetched from, or
+	// -1 if that node is not one of the servers of the bucket.
+	ThisServer int
+
+	// QueryAddresses holds the address of the query service of each node
+	// which is running it.
+	QueryAddresses []string
+}
+
+// ServerForKey returns the index of the server the vbucket of a key is
+// active on, along with that vbucket.
+func (r *Routing) ServerForKey(key []byte) (int, uint16) {
+	vbID := VbucketForKey(key, len(r.VbucketServers))
+	return r.VbucketServers[vbID], vbID
+}
+
+// VbucketForKey returns the vbucket a key belongs to, using the same hash
+// as the SDKs.
+func VbucketForKey(key []byte, numVbuckets int) uint16 {
+	crc := crc32.ChecksumIEEE(key)
+	return uint16((crc >> 16 & 0x7fff) % uint32(numVbuckets))
+}
+
+// nodeServiceAddress returns the address to use for a service of a node,
+// which is empty if the node does not run the service.
+func nodeServiceAddress(node *clusterConfigNodeJson, hostname string, service string, network string) (string, error) {
+	host := hostname
+	port := node.Services[service]
+	if network != "" && network != "default" {
+		altAddr, ok := node.AlternateAddresses[network]
+		if !ok {
+			return "", fmt.Errorf("node `%s` has no `%s` alternate address", hostname, network)
+		}
+
+		host = altAddr.Hostname
+		if altPort, ok := altAddr.Ports[service]; ok {
+			port = altPort
+		}
+	}
+
+	if port == 0 {
+		return "", nil
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// ParseClusterConfig parses a bucket config into the kv address to use for
+// each server, and the vbuckets which are active on them.  The seed host is
+// substituted for the $HOST placeholder used by the server.
+func ParseClusterConfig(configBytes []byte, seedHost string, useTLS bool, network string) (*Routing, error) {
+	configBytes = []byte(strings.ReplaceAll(string(configBytes), "$HOST", seedHost))
+
+	var config clusterConfigJson
+	err := json.Unmarshal(configBytes, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse cluster config")
+	}
+
+	kvService := "kv"
+	queryService := "n1ql"
+	if useTLS {
+		kvService = "kvSSL"
+		queryService = "n1qlSSL"
+	}
+
+	routing := &Routing{
+		ThisServer: -1,
+	}
+	for _, server := range config.VBucketServerMap.ServerList {
+		serverHost, serverPort, err := net.SplitHostPort(server)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid server address `%s`", server)
+		}
+
+		address := ""
+		for nodeIdx := range config.NodesExt {
+			node := &config.NodesExt[nodeIdx]
+			hostname := node.Hostname
+			if hostname == "" {
+				hostname = seedHost
+			}
+			if hostname != serverHost || strconv.Itoa(node.Services["kv"]) != serverPort {
+				continue
+			}
+
+			address, err = nodeServiceAddress(node, hostname, kvService, network)
+			if err != nil {
+				return nil, err
+			}
+
+			if address == "" {
+				return nil, fmt.Errorf("node `%s` does not expose the %s port", hostname, kvService)
+			}
+
+			if node.ThisNode {
+				routing.ThisServer = len(routing.Addresses)
+			}
+		}
+		if address == "" {
+			return nil, fmt.Errorf("failed to find node for server `%s`", server)
+		}
+
+		routing.Addresses = append(routing.Addresses, address)
+		routing.ActiveVbuckets = append(routing.ActiveVbuckets, nil)
+	}
+
+	for vbID, vbServers := range config.VBucketServerMap.VBucketMap {
+		if len(vbServers) == 0 || vbServers[0] < 0 || vbServers[0] >= len(routing.Addresses) {
+			return nil, fmt.Errorf("vbucket %d has no active server", vbID)
+		}
+
+		serverIdx := vbServers[0]
+		routing.ActiveVbuckets[serverIdx] = append(routing.ActiveVbuckets[serverIdx], uint16(vbID))
+		routing.VbucketServers = append(routing.VbucketServers, serverIdx)
+
+		// replicas which have not been assigned a server yet are -1
+		var replicaIdxs []int
+		for _, replicaIdx := range vbServers[1:] {
+			if replicaIdx >= 0 && replicaIdx < len(routing.Addresses) {
+				replicaIdxs = append(replicaIdxs, replicaIdx)
+			}
+		}
+		routing.VbucketReplicas = append(routing.VbucketReplicas, replicaIdxs)
+	}
+
+	for nodeIdx := range config.NodesExt {
+		node := &config.NodesExt[nodeIdx]
+		if node.Services["n1ql"] == 0 {
+			continue
+		}
+
+		hostname := node.Hostname
+		if hostname == "" {
+			hostname = seedHost
+		}
+
+		address, err := nodeServiceAddress(node, hostname, queryService, network)
+		if err != nil {
+			return nil, err
+		}
+
+		if address != "" {
+			routing.QueryAddresses = append(routing.QueryAddresses, address)
+		}
+	}
+
+	return routing, nil
+}
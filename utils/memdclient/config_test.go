@@ -0,0 +1,43 @@
+package memdclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClusterConfig(t *testing.T) {
+	config := []byte(`{
+		"nodesExt": [
+			{"hostname": "10.0.0.2", "services": {"kv": 11210, "kvSSL": 11207, "n1ql": 8093, "n1qlSSL": 18093},
+			 "alternateAddresses": {"external": {"hostname": "example.com", "ports": {"kv": 31210}}}},
+			{"thisNode": true, "services": {"kv": 11210, "kvSSL": 11207}}
+		],
+		"vBucketServerMap": {
+			"serverList": ["10.0.0.2:11210", "$HOST:11210"],
+			"vBucketMap": [[0, 1], [1, 0], [1, 0]]
+		}
+	}`)
+
+	routing, err := ParseClusterConfig(config, "10.0.0.3", false, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2:11210", "10.0.0.3:11210"}, routing.Addresses)
+	require.Equal(t, [][]uint16{{0}, {1, 2}}, routing.ActiveVbuckets)
+	require.Equal(t, []int{0, 1, 1}, routing.VbucketServers)
+	require.Equal(t, [][]int{{1}, {0}, {0}}, routing.VbucketReplicas)
+	require.Equal(t, 1, routing.ThisServer)
+	require.Equal(t, []string{"10.0.0.2:8093"}, routing.QueryAddresses)
+
+	routing, err = ParseClusterConfig(config, "10.0.0.3", true, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2:11207", "10.0.0.3:11207"}, routing.Addresses)
+	require.Equal(t, []string{"10.0.0.2:18093"}, routing.QueryAddresses)
+
+	_, err = ParseClusterConfig(config, "10.0.0.3", false, "external")
+	require.Error(t, err)
+}
+
+func TestVbucketForKey(t *testing.T) {
+	require.Equal(t, uint16(656), VbucketForKey([]byte("key"), 1024))
+	require.Less(t, VbucketForKey([]byte("key"), 64), uint16(64))
+}
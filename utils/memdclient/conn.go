@@ -0,0 +1,142 @@
+package memdclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Conn is a minimal memcached binary protocol connection, which only
+// implements what our own tools need.  It is not safe for concurrent use.
+type Conn struct {
+	conn   net.Conn
+	rdr    *bufio.Reader
+	opaque uint32
+}
+
+func Dial(ctx context.Context, address string, tlsConfig *tls.Config) (*Conn, error) {
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", address)
+	}
+
+	return &Conn{
+		conn: conn,
+		rdr:  bufio.NewReader(conn),
+	}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// NextOpaque allocates the opaque for a request which is sent with Send.
+func (c *Conn) NextOpaque() uint32 {
+	c.opaque++
+	return c.opaque
+}
+
+func (c *Conn) Send(pak *Packet) error {
+	return WritePacket(c.conn, pak)
+}
+
+func (c *Conn) Read() (*Packet, error) {
+	return ReadPacket(c.rdr)
+}
+
+// Request synchronously performs a request.  This can only be used while
+// no other requests are outstanding, such as before any DCP streams are
+// opened, since responses are otherwise interleaved with other messages.
+func (c *Conn) Request(opcode uint8, vbID uint16, extras, key, value []byte) (*Packet, error) {
+	opaque := c.NextOpaque()
+
+	err := c.Send(&Packet{
+		Magic:   MagicReq,
+		Opcode:  opcode,
+		Vbucket: vbID,
+		Opaque:  opaque,
+		Extras:  extras,
+		Key:     key,
+		Value:   value,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+
+	for {
+		resp, err := c.Read()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read response")
+		}
+
+		if resp.Magic != MagicRes || resp.Opaque != opaque {
+			// ignore anything which is not the response we are waiting for
+			continue
+		}
+
+		if resp.Status() != StatusSuccess {
+			return resp, StatusError{Opcode: opcode, Status: resp.Status()}
+		}
+
+		return resp, nil
+	}
+}
+
+func (c *Conn) Authenticate(username, password string) error {
+	_, err := c.Request(OpSaslAuth, 0, nil, []byte("PLAIN"),
+		[]byte("\x00"+username+"\x00"+password))
+	if err != nil {
+		return errors.Wrap(err, "failed to authenticate")
+	}
+	return nil
+}
+
+func (c *Conn) Hello(agentName string, features ...uint16) error {
+	value := make([]byte, 2*len(features))
+	for featureIdx, feature := range features {
+		binary.BigEndian.PutUint16(value[featureIdx*2:], feature)
+	}
+
+	_, err := c.Request(OpHello, 0, nil, []byte(agentName), value)
+	if err != nil {
+		return errors.Wrap(err, "failed to negotiate features")
+	}
+	return nil
+}
+
+func (c *Conn) SelectBucket(bucketName string) error {
+	_, err := c.Request(OpSelectBucket, 0, nil, []byte(bucketName), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to select bucket")
+	}
+	return nil
+}
+
+// Setup negotiates the features, authenticates the connection and selects
+// the bucket.
+func (c *Conn) Setup(agentName, username, password, bucketName string, features ...uint16) error {
+	err := c.Hello(agentName, features...)
+	if err != nil {
+		return err
+	}
+
+	err = c.Authenticate(username, password)
+	if err != nil {
+		return err
+	}
+
+	return c.SelectBucket(bucketName)
+}
@@ -0,0 +1,73 @@
+package memdclient
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type ConnStrInfo struct {
+	Addresses []string
+	UseTLS    bool
+	Network   string
+}
+
+// ParseConnStr parses a couchbase:// or couchbases:// connection string into
+// the kv addresses to seed from.  A single host without a port is resolved
+// via DNS SRV first, as is done for Capella clusters.
+func ParseConnStr(connStr string) (*ConnStrInfo, error) {
+	parsedUrl, err := url.Parse(connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse connection string")
+	}
+
+	info := &ConnStrInfo{
+		Network: parsedUrl.Query().Get("network"),
+	}
+
+	defaultPort := 11210
+	srvService := "couchbase"
+	switch parsedUrl.Scheme {
+	case "couchbase":
+	case "couchbases":
+		info.UseTLS = true
+		defaultPort = 11207
+		srvService = "couchbases"
+	default:
+		return nil, fmt.Errorf("unsupported connection string scheme `%s`", parsedUrl.Scheme)
+	}
+
+	hosts := strings.Split(parsedUrl.Host, ",")
+	if len(hosts) == 1 && !strings.Contains(hosts[0], ":") {
+		_, srvAddrs, err := net.LookupSRV(srvService, "tcp", hosts[0])
+		if err == nil && len(srvAddrs) > 0 {
+			for _, srvAddr := range srvAddrs {
+				info.Addresses = append(info.Addresses, net.JoinHostPort(
+					strings.TrimSuffix(srvAddr.Target, "."),
+					strconv.Itoa(int(srvAddr.Port))))
+			}
+			return info, nil
+		}
+	}
+
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(defaultPort))
+		}
+		info.Addresses = append(info.Addresses, host)
+	}
+
+	if len(info.Addresses) == 0 {
+		return nil, errors.New("connection string contains no hosts")
+	}
+
+	return info, nil
+}
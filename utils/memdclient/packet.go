@@ -0,0 +1,124 @@
+package memdclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MagicReq = 0x80
+	MagicRes = 0x81
+)
+
+const (
+	OpGet                    = 0x00
+	OpSet                    = 0x01
+	OpSaslAuth               = 0x21
+	OpHello                  = 0x1f
+	OpSelectBucket           = 0x89
+	OpGetReplica             = 0x83
+	OpGetClusterConfig       = 0xb5
+	OpGetCollectionsManifest = 0xba
+)
+
+const (
+	StatusSuccess     = 0x00
+	StatusKeyNotFound = 0x01
+)
+
+const (
+	FeatureCollections = 0x12
+)
+
+const headerLen = 24
+
+type Packet struct {
+	Magic    uint8
+	Opcode   uint8
+	Datatype uint8
+
+	// Vbucket holds the vbucket for requests and the status for responses.
+	Vbucket uint16
+	Opaque  uint32
+	Cas     uint64
+	Extras  []byte
+	Key     []byte
+	Value   []byte
+}
+
+func (p *Packet) Status() uint16 {
+	return p.Vbucket
+}
+
+type StatusError struct {
+	Opcode uint8
+	Status uint16
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("memcached operation 0x%02x failed with status 0x%04x", e.Opcode, e.Status)
+}
+
+func WritePacket(w io.Writer, pak *Packet) error {
+	bodyLen := len(pak.Extras) + len(pak.Key) + len(pak.Value)
+	buf := make([]byte, headerLen+bodyLen)
+
+	buf[0] = pak.Magic
+	buf[1] = pak.Opcode
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(pak.Key)))
+	buf[4] = uint8(len(pak.Extras))
+	buf[5] = pak.Datatype
+	binary.BigEndian.PutUint16(buf[6:], pak.Vbucket)
+	binary.BigEndian.PutUint32(buf[8:], uint32(bodyLen))
+	binary.BigEndian.PutUint32(buf[12:], pak.Opaque)
+	binary.BigEndian.PutUint64(buf[16:], pak.Cas)
+
+	pos := headerLen
+	pos += copy(buf[pos:], pak.Extras)
+	pos += copy(buf[pos:], pak.Key)
+	copy(buf[pos:], pak.Value)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func ReadPacket(r io.Reader) (*Packet, error) {
+	header := make([]byte, headerLen)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := header[0]
+	if magic != MagicReq && magic != MagicRes {
+		return nil, fmt.Errorf("unexpected packet magic 0x%02x", magic)
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:]))
+	extrasLen := int(header[4])
+	bodyLen := int(binary.BigEndian.Uint32(header[8:]))
+	if keyLen+extrasLen > bodyLen {
+		return nil, errors.New("invalid packet lengths")
+	}
+
+	body := make([]byte, bodyLen)
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Packet{
+		Magic:    magic,
+		Opcode:   header[1],
+		Datatype: header[5],
+		Vbucket:  binary.BigEndian.Uint16(header[6:]),
+		Opaque:   binary.BigEndian.Uint32(header[12:]),
+		Cas:      binary.BigEndian.Uint64(header[16:]),
+		Extras:   body[:extrasLen],
+		Key:      body[extrasLen : extrasLen+keyLen],
+		Value:    body[extrasLen+keyLen:],
+	}, nil
+}
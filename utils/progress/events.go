@@ -0,0 +1,110 @@
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventPhaseStarted   EventType = "phase-started"
+	EventPhaseCompleted EventType = "phase-completed"
+	EventNodesReady     EventType = "nodes-ready"
+)
+
+// Event describes the progress of an operation, for callers embedding the
+// deployers which render their own progress rather than using a Reporter.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Phase is the step which started or completed, and for node events the
+	// step the nodes became ready during.
+	Phase string
+
+	// NodesReady and NodesTotal are only set for EventNodesReady.
+	NodesReady int
+	NodesTotal int
+}
+
+// EventHandler is invoked synchronously from within the operation, possibly
+// from multiple goroutines one at a time, and must not block.
+type EventHandler func(Event)
+
+type eventSink struct {
+	lock    sync.Mutex
+	handler EventHandler
+	phase   string
+}
+
+func (s *eventSink) emit(evt Event) {
+	evt.Time = time.Now()
+	s.handler(evt)
+}
+
+func (s *eventSink) startPhase(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.phase != "" {
+		s.emit(Event{Type: EventPhaseCompleted, Phase: s.phase})
+	}
+
+	s.phase = name
+	s.emit(Event{Type: EventPhaseStarted, Phase: name})
+}
+
+func (s *eventSink) complete() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.phase != "" {
+		s.emit(Event{Type: EventPhaseCompleted, Phase: s.phase})
+	}
+
+	s.phase = ""
+}
+
+func (s *eventSink) nodesReady(ready int, total int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.emit(Event{
+		Type:       EventNodesReady,
+		Phase:      s.phase,
+		NodesReady: ready,
+		NodesTotal: total,
+	})
+}
+
+type eventSinkCtxKey struct{}
+
+// WithEventHandler attaches a handler to the context which receives typed
+// events for the steps of an operation, alongside any Reporter.
+func WithEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventSinkCtxKey{}, &eventSink{
+		handler: handler,
+	})
+}
+
+func getEventSink(ctx context.Context) *eventSink {
+	sink, _ := ctx.Value(eventSinkCtxKey{}).(*eventSink)
+	return sink
+}
+
+// NodesReady reports how many of the nodes of the operation are ready.
+func NodesReady(ctx context.Context, ready int, total int) {
+	if sink := getEventSink(ctx); sink != nil {
+		sink.nodesReady(ready, total)
+	}
+}
+
+// Complete reports that the last step of an operation has completed.  Each
+// other step is completed by the start of the step which follows it.
+func Complete(ctx context.Context) {
+	if sink := getEventSink(ctx); sink != nil {
+		sink.complete()
+	}
+}
@@ -0,0 +1,38 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	var events []Event
+	ctx := WithEventHandler(context.Background(), func(evt Event) {
+		assert.False(t, evt.Time.IsZero())
+		evt.Time = time.Time{}
+		events = append(events, evt)
+	})
+
+	Step(ctx, "deploying nodes")
+	NodesReady(ctx, 1, 2)
+	NodesReady(ctx, 2, 2)
+	Step(ctx, "initializing cluster")
+	Complete(ctx)
+	Complete(ctx)
+
+	assert.Equal(t, []Event{
+		{Type: EventPhaseStarted, Phase: "deploying nodes"},
+		{Type: EventNodesReady, Phase: "deploying nodes", NodesReady: 1, NodesTotal: 2},
+		{Type: EventNodesReady, Phase: "deploying nodes", NodesReady: 2, NodesTotal: 2},
+		{Type: EventPhaseCompleted, Phase: "deploying nodes"},
+		{Type: EventPhaseStarted, Phase: "initializing cluster"},
+		{Type: EventPhaseCompleted, Phase: "initializing cluster"},
+	}, events)
+
+	// events on a context without a handler are ignored
+	NodesReady(context.Background(), 1, 1)
+	Complete(context.Background())
+}
@@ -0,0 +1,63 @@
+package progress
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// History records how long operations previously took, which is used to
+// estimate how long the same operation will take next time.
+type History struct {
+	Path string
+}
+
+func (h *History) load() map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+
+	historyBytes, err := os.ReadFile(h.Path)
+	if err != nil {
+		return durations
+	}
+
+	// a corrupt history only loses the estimates, so errors are ignored
+	json.Unmarshal(historyBytes, &durations)
+	return durations
+}
+
+// Expected returns the expected duration of an operation, or zero if the
+// operation has not completed before.
+func (h *History) Expected(key string) time.Duration {
+	return h.load()[key]
+}
+
+// Record records the duration of a successful operation.  The estimate is
+// averaged with the previous one to smooth out unusually slow runs.
+func (h *History) Record(key string, duration time.Duration) error {
+	durations := h.load()
+
+	if prevDuration, ok := durations[key]; ok {
+		duration = (prevDuration + duration) / 2
+	}
+	durations[key] = duration
+
+	historyBytes, err := json.Marshal(durations)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal progress history")
+	}
+
+	err = os.MkdirAll(filepath.Dir(h.Path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create progress history directory")
+	}
+
+	err = os.WriteFile(h.Path, historyBytes, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write progress history")
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+type ReporterOptions struct {
+	Logger *zap.Logger
+	Writer *Writer
+	Title  string
+
+	// Expected is how long the operation is expected to take, which is used
+	// to estimate the time remaining.  Zero disables the estimate.
+	Expected time.Duration
+
+	// LogInterval is how often a line is logged to indicate we are still
+	// working when the output is not a terminal.  Defaults to 30s.
+	LogInterval time.Duration
+}
+
+// Reporter reports the progress of a long running operation.  On terminals
+// a spinner is shown along with the current step, and otherwise the current
+// step is logged periodically so it is clear the operation has not hung.
+type Reporter struct {
+	logger      *zap.Logger
+	writer      *Writer
+	title       string
+	expected    time.Duration
+	logInterval time.Duration
+	startTime   time.Time
+
+	lock     sync.Mutex
+	numSteps int
+	stepName string
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewReporter(opts *ReporterOptions) *Reporter {
+	logInterval := opts.LogInterval
+	if logInterval <= 0 {
+		logInterval = 30 * time.Second
+	}
+
+	r := &Reporter{
+		logger:      opts.Logger,
+		writer:      opts.Writer,
+		title:       opts.Title,
+		expected:    opts.Expected,
+		logInterval: logInterval,
+		startTime:   time.Now(),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+// Step marks the start of the next step of the operation.
+func (r *Reporter) Step(name string) {
+	r.lock.Lock()
+	r.numSteps++
+	r.stepName = name
+	r.lock.Unlock()
+
+	r.logger.Debug("progress step",
+		zap.String("operation", r.title),
+		zap.String("step", name))
+}
+
+// Finish stops reporting progress and returns how long the operation took.
+func (r *Reporter) Finish() time.Duration {
+	close(r.stopCh)
+	<-r.doneCh
+
+	r.writer.SetStatus("")
+
+	return time.Since(r.startTime)
+}
+
+func (r *Reporter) remaining(elapsed time.Duration) time.Duration {
+	if r.expected <= 0 || elapsed >= r.expected {
+		return 0
+	}
+	return r.expected - elapsed
+}
+
+func (r *Reporter) describe(elapsed time.Duration) string {
+	r.lock.Lock()
+	numSteps := r.numSteps
+	stepName := r.stepName
+	r.lock.Unlock()
+
+	desc := r.title
+	if stepName != "" {
+		desc = fmt.Sprintf("%s [%d] %s", r.title, numSteps, stepName)
+	}
+
+	desc += fmt.Sprintf(" (%s elapsed", formatDuration(elapsed))
+	if remaining := r.remaining(elapsed); remaining > 0 {
+		desc += fmt.Sprintf(", ~%s left", formatDuration(remaining))
+	}
+	desc += ")"
+
+	return desc
+}
+
+func (r *Reporter) run() {
+	defer close(r.doneCh)
+
+	if r.writer.IsTTY() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		frameIdx := 0
+		for {
+			elapsed := time.Since(r.startTime)
+			r.writer.SetStatus(spinnerFrames[frameIdx%len(spinnerFrames)] + " " + r.describe(elapsed))
+			frameIdx++
+
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+
+	ticker := time.NewTicker(r.logInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.logger.Info("still working: " + r.describe(time.Since(r.startTime)))
+		}
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	} else if d >= time.Minute {
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+type reporterCtxKey struct{}
+
+// WithReporter attaches a reporter to the context, so that deployers can
+// report the steps of an operation without it being threaded through.
+func WithReporter(ctx context.Context, r *Reporter) context.Context {
+	return context.WithValue(ctx, reporterCtxKey{}, r)
+}
+
+// Step reports the start of the next step of the operation in progress, if
+// progress is being reported for the context.
+func Step(ctx context.Context, name string) {
+	r, _ := ctx.Value(reporterCtxKey{}).(*Reporter)
+	if r != nil {
+		r.Step(name)
+	}
+
+	if sink := getEventSink(ctx); sink != nil {
+		sink.startPhase(name)
+	}
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReporterDescribe(t *testing.T) {
+	r := NewReporter(&ReporterOptions{
+		Logger:   zap.NewNop(),
+		Writer:   NewWriter(&bytes.Buffer{}),
+		Title:    "allocating",
+		Expected: 5 * time.Minute,
+	})
+	defer r.Finish()
+
+	assert.Equal(t, "allocating (1m05s elapsed, ~3m55s left)", r.describe(65*time.Second))
+
+	ctx := WithReporter(context.Background(), r)
+	Step(ctx, "deploying nodes")
+	assert.Equal(t, "allocating [1] deploying nodes (6m00s elapsed)", r.describe(6*time.Minute))
+
+	// steps on a context without a reporter are ignored
+	Step(context.Background(), "ignored")
+}
+
+func TestWriterNonTTY(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+
+	w.SetStatus("working")
+	w.Write([]byte("log line\n"))
+
+	assert.Equal(t, "log line\n", buf.String())
+}
+
+func TestHistory(t *testing.T) {
+	h := &History{Path: filepath.Join(t.TempDir(), "history.json")}
+
+	assert.Equal(t, time.Duration(0), h.Expected("allocate"))
+
+	require.NoError(t, h.Record("allocate", 2*time.Minute))
+	assert.Equal(t, 2*time.Minute, h.Expected("allocate"))
+
+	require.NoError(t, h.Record("allocate", 4*time.Minute))
+	assert.Equal(t, 3*time.Minute, h.Expected("allocate"))
+}
@@ -0,0 +1,84 @@
+package progress
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer wraps an output stream so that a status line can be kept at the
+// bottom of a terminal while other output, such as log lines, is written
+// above it.  When the output is not a terminal, status lines are ignored.
+type Writer struct {
+	lock   sync.Mutex
+	out    io.Writer
+	isTTY  bool
+	status string
+}
+
+// Stderr is the writer used for both logging and progress reporting, so
+// that the two can be interleaved.
+var Stderr = NewWriter(os.Stderr)
+
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{
+		out:   out,
+		isTTY: isTerminal(out),
+	}
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+func (w *Writer) IsTTY() bool {
+	return w.isTTY
+}
+
+func (w *Writer) clearStatus() {
+	if w.status != "" {
+		io.WriteString(w.out, "\r\033[K")
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.clearStatus()
+	n, err := w.out.Write(p)
+	if w.status != "" {
+		io.WriteString(w.out, w.status)
+	}
+	return n, err
+}
+
+func (w *Writer) Sync() error {
+	if syncer, ok := w.out.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// SetStatus replaces the status line, an empty status removing it.
+func (w *Writer) SetStatus(status string) {
+	if !w.isTTY {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.clearStatus()
+	w.status = status
+	io.WriteString(w.out, status)
+}
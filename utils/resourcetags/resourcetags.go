@@ -0,0 +1,99 @@
+package resourcetags
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// These are the tags which are attached to cloud provider resources which are
+// created on behalf of a cluster (such as private endpoints or dns zones), so
+// that they can be found and removed later even if the run which created them
+// was interrupted.
+const (
+	ClusterIDTag = "Cbdc2ClusterId"
+	OwnerTag     = "Cbdc2Owner"
+	ExpiryTag    = "Cbdc2Expiry"
+)
+
+type Metadata struct {
+	ClusterID string
+	Owner     string
+	Expiry    time.Time
+}
+
+// Tags returns the set of tags which describe the metadata.  Empty fields are
+// not included.
+func (m *Metadata) Tags() map[string]string {
+	tags := map[string]string{
+		ClusterIDTag: m.ClusterID,
+	}
+	if m.Owner != "" {
+		tags[OwnerTag] = m.Owner
+	}
+	if !m.Expiry.IsZero() {
+		tags[ExpiryTag] = m.Expiry.UTC().Format(time.RFC3339)
+	}
+	return tags
+}
+
+// IsExpired returns whether the resource has been expired for longer than
+// the grace period.  Resources without an expiry never expire.
+func (m *Metadata) IsExpired(now time.Time, gracePeriod time.Duration) bool {
+	if m.Expiry.IsZero() {
+		return false
+	}
+	return now.After(m.Expiry.Add(gracePeriod))
+}
+
+// ClusterExistsFunc reports whether the cluster which a resource was created
+// for still exists.
+type ClusterExistsFunc func(ctx context.Context, clusterID string) (bool, error)
+
+// IsAbandoned returns whether the resource has expired and the cluster it was
+// created for no longer exists.  The expiry of a cluster can be extended after
+// its resources were tagged, so expiry alone is not enough to remove them, and
+// without a way to check for the cluster they are never considered abandoned.
+func (m *Metadata) IsAbandoned(
+	ctx context.Context,
+	now time.Time,
+	gracePeriod time.Duration,
+	clusterExists ClusterExistsFunc,
+) (bool, error) {
+	if clusterExists == nil || !m.IsExpired(now, gracePeriod) {
+		return false, nil
+	}
+
+	exists, err := clusterExists(ctx, m.ClusterID)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check if the cluster exists")
+	}
+
+	return !exists, nil
+}
+
+// Parse reads the metadata from the tags of a resource, returning nil if the
+// resource is not one which was created by cbdc.
+func Parse(tags map[string]string) (*Metadata, error) {
+	clusterID := tags[ClusterIDTag]
+	if clusterID == "" {
+		return nil, nil
+	}
+
+	meta := &Metadata{
+		ClusterID: clusterID,
+		Owner:     tags[OwnerTag],
+	}
+
+	if expiryStr := tags[ExpiryTag]; expiryStr != "" {
+		expiry, err := time.Parse(time.RFC3339, expiryStr)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse expiry tag")
+		}
+
+		meta.Expiry = expiry
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,95 @@
+package resourcetags
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagsRoundTrip(t *testing.T) {
+	expiry := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	meta := &Metadata{
+		ClusterID: "c1",
+		Owner:     "brett@devbox",
+		Expiry:    expiry,
+	}
+
+	tags := meta.Tags()
+	assert.Equal(t, map[string]string{
+		ClusterIDTag: "c1",
+		OwnerTag:     "brett@devbox",
+		ExpiryTag:    "2026-03-01T12:00:00Z",
+	}, tags)
+
+	parsed, err := Parse(tags)
+	require.NoError(t, err)
+	assert.Equal(t, meta, parsed)
+}
+
+func TestParseUnmanaged(t *testing.T) {
+	meta, err := Parse(map[string]string{"Name": "something"})
+	require.NoError(t, err)
+	assert.Nil(t, meta)
+
+	meta, err = Parse(map[string]string{ClusterIDTag: "c1"})
+	require.NoError(t, err)
+	assert.Equal(t, &Metadata{ClusterID: "c1"}, meta)
+
+	_, err = Parse(map[string]string{ClusterIDTag: "c1", ExpiryTag: "tomorrow"})
+	assert.Error(t, err)
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	meta := &Metadata{ClusterID: "c1"}
+	assert.False(t, meta.IsExpired(now, 0))
+
+	meta.Expiry = now.Add(-30 * time.Minute)
+	assert.True(t, meta.IsExpired(now, 0))
+	assert.False(t, meta.IsExpired(now, time.Hour))
+}
+
+func TestIsAbandoned(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var checkedClusterID string
+	clusterExists := false
+	existsFn := func(ctx context.Context, clusterID string) (bool, error) {
+		checkedClusterID = clusterID
+		return clusterExists, nil
+	}
+
+	meta := &Metadata{ClusterID: "c1", Expiry: now.Add(time.Hour)}
+	isAbandoned, err := meta.IsAbandoned(ctx, now, 0, existsFn)
+	require.NoError(t, err)
+	assert.False(t, isAbandoned)
+	assert.Empty(t, checkedClusterID)
+
+	meta.Expiry = now.Add(-time.Hour)
+	isAbandoned, err = meta.IsAbandoned(ctx, now, 0, existsFn)
+	require.NoError(t, err)
+	assert.True(t, isAbandoned)
+	assert.Equal(t, "c1", checkedClusterID)
+
+	// the cluster may have had its expiry extended since it was tagged
+	clusterExists = true
+	isAbandoned, err = meta.IsAbandoned(ctx, now, 0, existsFn)
+	require.NoError(t, err)
+	assert.False(t, isAbandoned)
+
+	// without a way to check for the cluster, nothing is abandoned
+	isAbandoned, err = meta.IsAbandoned(ctx, now, 0, nil)
+	require.NoError(t, err)
+	assert.False(t, isAbandoned)
+
+	_, err = meta.IsAbandoned(ctx, now, 0, func(ctx context.Context, clusterID string) (bool, error) {
+		return false, errors.New("listing failed")
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,281 @@
+// Package settingsdump captures a normalized dump of the settings of a
+// cluster from its management API, and compares dumps against each other.
+// Values which naturally differ between otherwise identically configured
+// clusters, such as uuids, statistics and node addresses, are removed so
+// that comparisons only show meaningful differences.
+package settingsdump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Fetcher performs a GET request against the management API of a cluster,
+// returning the raw JSON response.
+type Fetcher func(ctx context.Context, path string) (json.RawMessage, error)
+
+// Dump is a normalized dump of the settings of a cluster, divided into
+// sections which are each named after the endpoint they were read from.
+type Dump struct {
+	Sections map[string]interface{} `json:"sections"`
+
+	// Unavailable lists the sections which could not be read, which is
+	// usually because the cluster does not run the relevant service.
+	Unavailable map[string]string `json:"unavailable,omitempty"`
+}
+
+// settingsPaths are the endpoints captured into their own sections, in
+// addition to the pool and bucket configurations.
+var settingsPaths = []string{
+	"/settings/autoFailover",
+	"/settings/autoCompaction",
+	"/settings/autoReprovision",
+	"/settings/indexes",
+	"/settings/querySettings",
+	"/settings/analytics",
+	"/settings/security",
+	"/settings/rebalance",
+	"/settings/retryRebalance",
+	"/settings/replications",
+	"/settings/stats",
+	"/internalSettings",
+	"/api/v1/config",
+	"/pools/default/serverGroups",
+}
+
+// volatileKeys are removed from every section at any depth.
+var volatileKeys = map[string]bool{
+	"uuid":                  true,
+	"rev":                   true,
+	"revEpoch":              true,
+	"etag":                  true,
+	"basicStats":            true,
+	"stats":                 true,
+	"counters":              true,
+	"storageTotals":         true,
+	"nodeStatuses":          true,
+	"tasks":                 true,
+	"alerts":                true,
+	"balanced":              true,
+	"rebalanceStatus":       true,
+	"vBucketServerMap":      true,
+	"controllers":           true,
+	"ddocs":                 true,
+	"nodes":                 true,
+	"buckets":               true,
+	"remoteClusters":        true,
+	"bucketCapabilitiesVer": true,
+}
+
+func isVolatileKey(key string) bool {
+	if volatileKeys[key] {
+		return true
+	}
+
+	// links to other endpoints include uuids and add nothing to a dump
+	lowerKey := strings.ToLower(key)
+	return strings.HasSuffix(lowerKey, "uri") || strings.HasSuffix(lowerKey, "url")
+}
+
+func normalize(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for key, subValue := range typedValue {
+			if isVolatileKey(key) {
+				continue
+			}
+			out[key] = normalize(subValue)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(typedValue))
+		for idx, subValue := range typedValue {
+			out[idx] = normalize(subValue)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// normalizeNodes reduces the nodes of the pool to the settings which can be
+// compared between clusters, ordered independently of their addresses.
+func normalizeNodes(nodes []interface{}) []interface{} {
+	var out []interface{}
+	for _, node := range nodes {
+		nodeMap, ok := node.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		normalizedNode := make(map[string]interface{})
+		for _, key := range []string{"services", "version", "clusterMembership", "recoveryType", "serverGroup", "os"} {
+			if value, ok := nodeMap[key]; ok {
+				normalizedNode[key] = value
+			}
+		}
+		if services, ok := normalizedNode["services"].([]interface{}); ok {
+			sort.Slice(services, func(i, j int) bool {
+				return fmt.Sprint(services[i]) < fmt.Sprint(services[j])
+			})
+		}
+
+		out = append(out, normalizedNode)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		iBytes, _ := json.Marshal(out[i])
+		jBytes, _ := json.Marshal(out[j])
+		return string(iBytes) < string(jBytes)
+	})
+
+	return out
+}
+
+// Capture reads the settings of a cluster into a normalized dump.
+func Capture(ctx context.Context, fetch Fetcher) (*Dump, error) {
+	dump := &Dump{
+		Sections:    make(map[string]interface{}),
+		Unavailable: make(map[string]string),
+	}
+
+	var pool map[string]interface{}
+	poolJson, err := fetch(ctx, "/pools/default")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch pool configuration")
+	}
+	err = json.Unmarshal(poolJson, &pool)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pool configuration")
+	}
+
+	poolSection := normalize(pool).(map[string]interface{})
+	if nodes, ok := pool["nodes"].([]interface{}); ok {
+		poolSection["nodes"] = normalizeNodes(nodes)
+	}
+	dump.Sections["/pools/default"] = poolSection
+
+	var buckets []map[string]interface{}
+	bucketsJson, err := fetch(ctx, "/pools/default/buckets")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch bucket configurations")
+	}
+	err = json.Unmarshal(bucketsJson, &buckets)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse bucket configurations")
+	}
+
+	for _, bucket := range buckets {
+		bucketName, _ := bucket["name"].(string)
+		dump.Sections["/pools/default/buckets/"+bucketName] = normalize(bucket)
+	}
+
+	for _, path := range settingsPaths {
+		sectionJson, err := fetch(ctx, path)
+		if err != nil {
+			dump.Unavailable[path] = err.Error()
+			continue
+		}
+
+		var section interface{}
+		err = json.Unmarshal(sectionJson, &section)
+		if err != nil {
+			dump.Unavailable[path] = errors.Wrap(err, "failed to parse response").Error()
+			continue
+		}
+
+		dump.Sections[path] = normalize(section)
+	}
+
+	return dump, nil
+}
+
+func Load(path string) (*Dump, error) {
+	dumpBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read settings dump")
+	}
+
+	var dump *Dump
+	err = json.Unmarshal(dumpBytes, &dump)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse settings dump")
+	}
+
+	if dump == nil || dump.Sections == nil {
+		return nil, errors.New("file is not a settings dump")
+	}
+
+	return dump, nil
+}
+
+// Difference is a setting which differs between two dumps.  The values are
+// JSON encoded, and are empty when the setting is missing from that dump.
+type Difference struct {
+	Path  string `json:"path"`
+	Left  string `json:"left,omitempty"`
+	Right string `json:"right,omitempty"`
+}
+
+func flatten(prefix string, value interface{}, out map[string]string) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, subValue := range typedValue {
+			flatten(prefix+"."+key, subValue, out)
+		}
+	case []interface{}:
+		for idx, subValue := range typedValue {
+			flatten(fmt.Sprintf("%s[%d]", prefix, idx), subValue, out)
+		}
+	default:
+		valueBytes, _ := json.Marshal(value)
+		out[prefix] = string(valueBytes)
+	}
+}
+
+func (d *Dump) flatten() map[string]string {
+	out := make(map[string]string)
+	for sectionName, section := range d.Sections {
+		flatten(sectionName, section, out)
+	}
+	return out
+}
+
+// Diff compares two dumps, returning the differences ordered by path.
+func Diff(left, right *Dump) []Difference {
+	leftValues := left.flatten()
+	rightValues := right.flatten()
+
+	var diffs []Difference
+	for path, leftValue := range leftValues {
+		rightValue := rightValues[path]
+		if leftValue != rightValue {
+			diffs = append(diffs, Difference{
+				Path:  path,
+				Left:  leftValue,
+				Right: rightValue,
+			})
+		}
+	}
+	for path, rightValue := range rightValues {
+		if _, ok := leftValues[path]; !ok {
+			diffs = append(diffs, Difference{
+				Path:  path,
+				Right: rightValue,
+			})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs
+}
@@ -0,0 +1,64 @@
+package settingsdump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newFetcher(responses map[string]string) Fetcher {
+	return func(ctx context.Context, path string) (json.RawMessage, error) {
+		response, ok := responses[path]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return json.RawMessage(response), nil
+	}
+}
+
+func TestCaptureAndDiff(t *testing.T) {
+	ctx := context.Background()
+
+	left, err := Capture(ctx, newFetcher(map[string]string{
+		"/pools/default": `{"memoryQuota":256,"rebalanceStatus":"none",` +
+			`"nodes":[{"hostname":"10.0.0.2:8091","services":["n1ql","kv"],"version":"7.2.0"},` +
+			`{"hostname":"10.0.0.1:8091","services":["kv"],"version":"7.2.0"}]}`,
+		"/pools/default/buckets": `[{"name":"default","uuid":"abc","ramQuota":100,"streamingUri":"/x"}]`,
+		"/settings/autoFailover": `{"enabled":true,"timeout":120}`,
+	}))
+	require.NoError(t, err)
+
+	right, err := Capture(ctx, newFetcher(map[string]string{
+		"/pools/default": `{"memoryQuota":256,"rebalanceStatus":"running",` +
+			`"nodes":[{"hostname":"10.0.1.1:8091","services":["kv"],"version":"7.2.0"},` +
+			`{"hostname":"10.0.1.2:8091","services":["kv","n1ql"],"version":"7.2.0"}]}`,
+		"/pools/default/buckets": `[{"name":"default","uuid":"def","ramQuota":200}]`,
+		"/settings/autoFailover": `{"enabled":true,"timeout":30}`,
+		"/settings/indexes":      `{"storageMode":"plasma"}`,
+	}))
+	require.NoError(t, err)
+
+	require.Contains(t, left.Unavailable, "/settings/indexes")
+
+	require.Equal(t, []Difference{
+		{
+			Path:  "/pools/default/buckets/default.ramQuota",
+			Left:  "100",
+			Right: "200",
+		},
+		{
+			Path:  "/settings/autoFailover.timeout",
+			Left:  "120",
+			Right: "30",
+		},
+		{
+			Path:  "/settings/indexes.storageMode",
+			Right: `"plasma"`,
+		},
+	}, Diff(left, right))
+
+	require.Empty(t, Diff(left, left))
+}
@@ -0,0 +1,161 @@
+package versionlock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/couchbaselabs/cbdinocluster/utils/versionident"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the versions lock file, which is found by looking
+// in the working directory and each of its parents.
+const FileName = "cbdc-versions.lock"
+
+// Lock maps friendly version names (such as stable or next) to the exact
+// server versions they currently refer to, allowing a fleet of CI jobs to
+// move between versions by changing a single file.
+type Lock struct {
+	Versions map[string]string `yaml:"versions"`
+}
+
+// Find looks for a versions lock file in dir and each of its parents,
+// returning an empty path if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve directory")
+	}
+
+	for {
+		lockPath := filepath.Join(dir, FileName)
+		_, err := os.Stat(lockPath)
+		if err == nil {
+			return lockPath, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrap(err, "failed to check for versions lock")
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return "", nil
+		}
+		dir = parentDir
+	}
+}
+
+// Load reads a versions lock file.  A file which does not exist yet is
+// treated as an empty lock.
+func Load(path string) (*Lock, error) {
+	lockBytes, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Lock{}, nil
+		}
+		return nil, errors.Wrap(err, "failed to read versions lock")
+	}
+
+	var lock Lock
+	err = yaml.Unmarshal(lockBytes, &lock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse versions lock")
+	}
+
+	return &lock, nil
+}
+
+// Save writes the versions lock file.  The file is replaced atomically so
+// that jobs reading it concurrently never observe a partial write.
+func (l *Lock) Save(path string) error {
+	lockBytes, err := yaml.Marshal(l)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal versions lock")
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), FileName+".*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary versions lock")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(lockBytes)
+	if err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "failed to write temporary versions lock")
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to close temporary versions lock")
+	}
+
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to set versions lock permissions")
+	}
+
+	err = os.Rename(tmpFile.Name(), path)
+	if err != nil {
+		return errors.Wrap(err, "failed to replace versions lock")
+	}
+
+	return nil
+}
+
+// Names returns the names of all the pinned versions, sorted.
+func (l *Lock) Names() []string {
+	var names []string
+	for name := range l.Versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the version a name is pinned to.  Versions which are not
+// pinned names are returned unchanged.
+func (l *Lock) Resolve(version string) string {
+	if pinned, ok := l.Versions[version]; ok {
+		return pinned
+	}
+	return version
+}
+
+// Pin points a name at an exact server version, returning the version it
+// previously referred to, if any.
+func (l *Lock) Pin(name string, version string) (string, error) {
+	if name == "" {
+		return "", errors.New("a name must be specified")
+	}
+	if strings.ContainsAny(name, ".:") {
+		// names must never be mistaken for versions or definition tags
+		return "", errors.New("names cannot contain dots or colons")
+	}
+
+	_, err := versionident.Identify(context.Background(), version)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid version")
+	}
+
+	if l.Versions == nil {
+		l.Versions = make(map[string]string)
+	}
+
+	oldVersion := l.Versions[name]
+	l.Versions[name] = version
+	return oldVersion, nil
+}
+
+// Unpin removes a name from the lock, returning whether it was pinned.
+func (l *Lock) Unpin(name string) bool {
+	if _, ok := l.Versions[name]; !ok {
+		return false
+	}
+
+	delete(l.Versions, name)
+	return true
+}
@@ -0,0 +1,68 @@
+package versionlock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinAndResolve(t *testing.T) {
+	lock := &Lock{}
+
+	oldVersion, err := lock.Pin("stable", "7.6.2")
+	require.NoError(t, err)
+	assert.Equal(t, "", oldVersion)
+
+	oldVersion, err = lock.Pin("stable", "7.6.3")
+	require.NoError(t, err)
+	assert.Equal(t, "7.6.2", oldVersion)
+
+	_, err = lock.Pin("next", "8.0.0-1234")
+	require.NoError(t, err)
+
+	assert.Equal(t, "7.6.3", lock.Resolve("stable"))
+	assert.Equal(t, "8.0.0-1234", lock.Resolve("next"))
+	assert.Equal(t, "7.2.0", lock.Resolve("7.2.0"))
+	assert.Equal(t, []string{"next", "stable"}, lock.Names())
+
+	_, err = lock.Pin("7.6", "7.6.2")
+	assert.Error(t, err)
+	_, err = lock.Pin("stable", "latest")
+	assert.Error(t, err)
+
+	assert.True(t, lock.Unpin("next"))
+	assert.False(t, lock.Unpin("next"))
+	assert.Equal(t, "next", lock.Resolve("next"))
+}
+
+func TestSaveLoadFind(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, FileName)
+
+	lock, err := Load(lockPath)
+	require.NoError(t, err)
+	assert.Empty(t, lock.Versions)
+
+	_, err = lock.Pin("stable", "7.6.2")
+	require.NoError(t, err)
+	require.NoError(t, lock.Save(lockPath))
+
+	loaded, err := Load(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"stable": "7.6.2"}, loaded.Versions)
+
+	subDir := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(subDir, 0755))
+
+	foundPath, err := Find(subDir)
+	require.NoError(t, err)
+	assert.Equal(t, lockPath, foundPath)
+
+	// no temporary files should be left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}